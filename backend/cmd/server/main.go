@@ -4,16 +4,21 @@ package main
 
 import (
 	"log"
+	"net"
 	"net/http"
 	"os"
 
 	"gantry/internal/api"
+	"gantry/internal/grpcapi"
 	"gantry/internal/server"
+	"gantry/internal/version"
 )
 
 func main() {
 	// Print banner
 	printBanner()
+	build := version.Get()
+	log.Printf("Gantry %s (commit %s, built %s, %s)", build.Version, build.Commit, build.BuildDate, build.GoVersion)
 
 	// Create server from environment variables
 	srv, err := server.NewServerFromEnv()
@@ -32,6 +37,16 @@ func main() {
 		port = "8080"
 	}
 
+	// Serve the gRPC API alongside, when a port is given for it
+	if grpcPort := os.Getenv("GRPC_PORT"); grpcPort != "" {
+		lis, err := net.Listen("tcp", ":"+grpcPort)
+		if err != nil {
+			log.Fatalf("GRPC_PORT: %v", err)
+		}
+		log.Printf("gRPC server starting on port %s", grpcPort)
+		go func() { log.Fatal(grpcapi.NewServer(srv).Serve(lis)) }()
+	}
+
 	// Start server
 	log.Printf("Server starting on port %s", port)
 	log.Fatal(http.ListenAndServe(":"+port, router))
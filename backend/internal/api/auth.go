@@ -0,0 +1,344 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gantry/internal/auth"
+	"gantry/internal/server"
+	"gantry/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// userKey is the request context key of the signed-in user's name
+type userKey struct{}
+
+// credentials is the body of registering and signing in
+type credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// HandleRegister creates a user
+func (h *Handler) HandleRegister(w http.ResponseWriter, r *http.Request) {
+	var req credentials
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.server.Register(req.Username, req.Password)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, server.ErrAuthDisabled):
+			status = http.StatusNotFound
+		case errors.Is(err, server.ErrInvalidUser):
+			status = http.StatusBadRequest
+		case errors.Is(err, server.ErrRegistrationClosed):
+			status = http.StatusForbidden
+		case errors.Is(err, storage.ErrUserExists):
+			status = http.StatusConflict
+		}
+		http.Error(w, fmt.Sprintf("Failed to register: %v", err), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// HandleLogin signs a user in, returning their tokens
+func (h *Handler) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	var req credentials
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.server.Login(req.Username, req.Password)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, server.ErrAuthDisabled):
+			status = http.StatusNotFound
+		case errors.Is(err, server.ErrInvalidCredentials):
+			status = http.StatusUnauthorized
+		}
+		http.Error(w, fmt.Sprintf("Failed to sign in: %v", err), status)
+		return
+	}
+	writeTokens(w, tokens)
+}
+
+// HandleRefresh exchanges a refresh token for new tokens
+func (h *Handler) HandleRefresh(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "Request body must hold the refresh_token", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.server.RefreshSession(req.RefreshToken)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, server.ErrAuthDisabled):
+			status = http.StatusNotFound
+		case errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, auth.ErrExpiredToken):
+			status = http.StatusUnauthorized
+		}
+		http.Error(w, fmt.Sprintf("Failed to refresh session: %v", err), status)
+		return
+	}
+	writeTokens(w, tokens)
+}
+
+// writeTokens writes a pair of tokens, which mustn't be cached
+func writeTokens(w http.ResponseWriter, tokens auth.TokenPair) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(tokens); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// HandleGetCurrentUser returns the signed-in user
+func (h *Handler) HandleGetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	username, ok := r.Context().Value(userKey{}).(string)
+	if !ok {
+		http.Error(w, "Authentication is not enabled", http.StatusNotFound)
+		return
+	}
+
+	user, err := h.server.GetUser(username)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrUserNotFound) {
+			status = http.StatusUnauthorized
+		}
+		http.Error(w, fmt.Sprintf("Failed to get user: %v", err), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// require lets a request through to handler only when the signed-in
+// user's role has a permission, recording it in the audit trail. Without
+// authentication every request is let through.
+func (h *Handler) require(permission string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := r.Context().Value(userKey{}).(string)
+		if !ok {
+			handler(w, r)
+			return
+		}
+
+		if err := h.server.Authorize(username, permission, r.Method+" "+r.URL.Path); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, server.ErrForbidden) {
+				status = http.StatusForbidden
+			}
+			http.Error(w, fmt.Sprintf("Not allowed: %v", err), status)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// HandleListUsers lists every user
+func (h *Handler) HandleListUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.server.ListUsers()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list users: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(users); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// HandleSetUserRole gives a user another role
+func (h *Handler) HandleSetUserRole(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.server.SetUserRole(username, req.Role)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, server.ErrInvalidRole):
+			status = http.StatusBadRequest
+		case errors.Is(err, storage.ErrUserNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, server.ErrLastAdmin):
+			status = http.StatusConflict
+		}
+		http.Error(w, fmt.Sprintf("Failed to set role: %v", err), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// HandleListAuditEntries lists the audit trail, newest first
+func (h *Handler) HandleListAuditEntries(w http.ResponseWriter, r *http.Request) {
+	opts, err := listOptions(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid query: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	entries, total, err := h.server.ListAuditEntries(opts)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list audit entries: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// HandleListSecrets lists the names of the secrets, never their values
+func (h *Handler) HandleListSecrets(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.server.SecretNames()); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// HandleSetSecret stores a secret
+func (h *Handler) HandleSetSecret(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	var req struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.server.SetSecret(name, req.Value); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, server.ErrInvalidSecret):
+			status = http.StatusBadRequest
+		case errors.Is(err, server.ErrSecretsReadOnly):
+			status = http.StatusNotImplemented
+		}
+		http.Error(w, fmt.Sprintf("Failed to set secret: %v", err), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{
+		"message": "Secret set",
+		"name":    name,
+	}); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// HandleDeleteSecret removes a secret
+func (h *Handler) HandleDeleteSecret(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	if err := h.server.DeleteSecret(name); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, server.ErrSecretNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, server.ErrSecretsReadOnly):
+			status = http.StatusNotImplemented
+		}
+		http.Error(w, fmt.Sprintf("Failed to delete secret: %v", err), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{
+		"message": "Secret deleted",
+		"name":    name,
+	}); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// publicPaths are the API paths that don't need signing in
+var publicPaths = map[string]bool{
+	"/api/auth/register": true,
+	"/api/auth/login":    true,
+	"/api/auth/refresh":  true,
+	"/api/openapi.json":  true,
+	"/api/openapi.yaml":  true,
+	"/docs":              true,
+}
+
+// webhooksPath is the prefix of the webhook paths, which don't need signing
+// in either, as deliveries are signed
+const webhooksPath = "/api/webhooks/"
+
+// AuthMiddleware refuses requests without a valid access token when the
+// server has authentication enabled, and records whose token it is in the
+// request's context. The token is sent as "Authorization: Bearer <token>",
+// or as the access_token query parameter by clients that can't set
+// headers, such as browser WebSockets and EventSources.
+func (h *Handler) AuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.server.AuthEnabled() || publicPaths[r.URL.Path] || strings.HasPrefix(r.URL.Path, webhooksPath) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			token = r.URL.Query().Get("access_token")
+		}
+		if token == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+
+		username, err := h.server.Authenticate(token)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, fmt.Sprintf("Authentication failed: %v", err), http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, username)))
+	})
+}
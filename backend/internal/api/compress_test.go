@@ -0,0 +1,239 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		want           bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"GZIP", true},
+		{"deflate, gzip", true},
+		{"gzip;q=0.5", true},
+		{"gzip; q=0", false},
+		{"gzip;q=0, *", false},
+		{"*", true},
+		{"*;q=0", false},
+		{"br, *;q=0.1", true},
+		{"deflate, br", false},
+		{"identity", false},
+	}
+	for _, tt := range tests {
+		if got := acceptsGzip(tt.acceptEncoding); got != tt.want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", tt.acceptEncoding, got, tt.want)
+		}
+	}
+}
+
+// compress serves a request for handler through CompressMiddleware
+func compress(handler http.HandlerFunc, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	CompressMiddleware(handler).ServeHTTP(rec, req)
+	return rec
+}
+
+func gzipRequest() *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/runs", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	return req
+}
+
+func TestCompressMiddleware_RoundTrip(t *testing.T) {
+	body := bytes.Repeat([]byte(`{"status":"success"},`), 100)
+	rec := compress(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Header().Set("ETag", `"abc"`)
+		w.Write(body)
+	}, gzipRequest())
+
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected the response to be gzipped, got Content-Encoding %q", rec.Header().Get("Content-Encoding"))
+	}
+	if rec.Header().Get("Content-Length") != "" {
+		t.Errorf("Expected Content-Length to be removed, got %q", rec.Header().Get("Content-Length"))
+	}
+	if rec.Header().Get("ETag") != `W/"abc"` {
+		t.Errorf(`Expected ETag W/"abc", got %q`, rec.Header().Get("ETag"))
+	}
+	if rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("Expected Vary: Accept-Encoding, got %q", rec.Header().Get("Vary"))
+	}
+	if rec.Body.Len() >= len(body) {
+		t.Errorf("Expected the body to shrink from %d bytes, got %d", len(body), rec.Body.Len())
+	}
+
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("Failed to read gzipped body: %v", err)
+	}
+	got, err := io.ReadAll(zr)
+	if err != nil || !bytes.Equal(got, body) {
+		t.Errorf("Expected the body to decompress to what was written, got %q (%v)", got, err)
+	}
+
+	// A weak ETag stays as it is
+	rec = compress(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", `W/"abc"`)
+		w.Write(body)
+	}, gzipRequest())
+	if rec.Header().Get("ETag") != `W/"abc"` {
+		t.Errorf(`Expected ETag W/"abc", got %q`, rec.Header().Get("ETag"))
+	}
+}
+
+func TestCompressMiddleware_Passthrough(t *testing.T) {
+	body := []byte("data: {}\n\n")
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  int
+		body    string
+	}{
+		{
+			name: "event stream",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Write(body)
+			},
+			status: http.StatusOK,
+			body:   string(body),
+		},
+		{
+			name: "no content",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusNoContent)
+			},
+			status: http.StatusNoContent,
+		},
+		{
+			name: "not modified",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusNotModified)
+			},
+			status: http.StatusNotModified,
+		},
+		{
+			name: "already encoded",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Content-Encoding", "br")
+				w.Write(body)
+			},
+			status: http.StatusOK,
+			body:   string(body),
+		},
+		{
+			name: "not compressible",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/zip")
+				w.Write(body)
+			},
+			status: http.StatusOK,
+			body:   string(body),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := compress(tt.handler, gzipRequest())
+			if rec.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, rec.Code)
+			}
+			if encoding := rec.Header().Get("Content-Encoding"); encoding == "gzip" {
+				t.Errorf("Expected the response not to be gzipped")
+			}
+			if rec.Body.String() != tt.body {
+				t.Errorf("Expected body %q, got %q", tt.body, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestCompressMiddleware_Skipped(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(*compressWriter); ok {
+			t.Error("Expected the handler to be given the plain writer")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	}
+
+	rec := compress(handler, httptest.NewRequest(http.MethodGet, "/api/runs", nil))
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != "{}" {
+		t.Errorf("Expected a plain response without Accept-Encoding, got %q", rec.Body.String())
+	}
+	if rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("Expected Vary: Accept-Encoding, got %q", rec.Header().Get("Vary"))
+	}
+
+	req := gzipRequest()
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	rec = compress(handler, req)
+	if rec.Header().Get("Content-Encoding") != "" || rec.Header().Get("Vary") != "" {
+		t.Errorf("Expected WebSocket upgrades to be left alone, got headers %v", rec.Header())
+	}
+}
+
+func TestCompressMiddleware_Flush(t *testing.T) {
+	first := []byte(`{"line":1}` + "\n")
+	proceed := make(chan struct{})
+	ts := httptest.NewServer(CompressMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(first)
+		w.(http.Flusher).Flush()
+		<-proceed
+		w.Write([]byte(`{"line":2}` + "\n"))
+	})))
+	defer ts.Close()
+	defer close(proceed)
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected a gzipped response, got Content-Encoding %q", resp.Header.Get("Content-Encoding"))
+	}
+
+	// The flushed line arrives while the handler is still waiting
+	read := make(chan []byte, 1)
+	go func() {
+		zr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			read <- nil
+			return
+		}
+		got := make([]byte, len(first))
+		if _, err := io.ReadFull(zr, got); err != nil {
+			read <- nil
+			return
+		}
+		read <- got
+	}()
+	select {
+	case got := <-read:
+		if !bytes.Equal(got, first) {
+			t.Errorf("Expected %q to be flushed, got %q", first, got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the flushed line before the response ended")
+	}
+}
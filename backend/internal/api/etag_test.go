@@ -0,0 +1,206 @@
+package api
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gantry/internal/models"
+	"gantry/internal/server"
+)
+
+const etagTestWorkflow = "name: Poll\njobs:\n  wait:\n    runs-on: ubuntu\n    steps:\n      - name: Wait\n        run: sleep %s\n"
+
+// newTestRoutes returns the routes of a server that keeps everything in
+// memory and runs jobs on this host
+func newTestRoutes(t *testing.T) (*server.Server, http.Handler) {
+	t.Helper()
+
+	srv, err := server.NewServer(&server.Config{
+		StorageType:  "memory",
+		ExecutorType: "shell",
+		ArtifactsDir: t.TempDir(),
+		CacheDir:     t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	return srv, SetupRoutes(NewHandler(srv))
+}
+
+// triggerRun saves a workflow whose job sleeps for seconds and triggers it
+func triggerRun(t *testing.T, srv *server.Server, seconds string) string {
+	t.Helper()
+
+	if _, err := srv.ParseAndSaveWorkflow([]byte(fmt.Sprintf(etagTestWorkflow, seconds)), ""); err != nil {
+		t.Fatalf("Failed to save workflow: %v", err)
+	}
+	run, err := srv.TriggerWorkflow(context.Background(), "Poll", server.TriggerOptions{})
+	if err != nil {
+		t.Fatalf("Failed to trigger workflow: %v", err)
+	}
+	return run.ID
+}
+
+// waitForRun waits for a run to be as done accepts it
+func waitForRun(t *testing.T, srv *server.Server, runID string, done func(run *models.WorkflowRun) bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		run, err := srv.GetRun(runID)
+		if err != nil {
+			t.Fatalf("Failed to get run: %v", err)
+		}
+		if done(run) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Run %s did not get as expected", runID)
+}
+
+// finished accepts runs that are no longer going
+func finished(run *models.WorkflowRun) bool {
+	return run.Status != "pending" && run.Status != "queued" && run.Status != "running"
+}
+
+// jobRunning accepts runs whose job has started
+func jobRunning(run *models.WorkflowRun) bool {
+	return run.Jobs["wait"].Status == "running"
+}
+
+// requestRun requests a run with the given headers
+func requestRun(routes http.Handler, runID string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/runs/"+runID, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	routes.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestETagMatches(t *testing.T) {
+	const etag = `"abc"`
+	tests := []struct {
+		ifNoneMatch string
+		want        bool
+	}{
+		{`"abc"`, true},
+		{`W/"abc"`, true},
+		{`"other", "abc"`, true},
+		{`"other",W/"abc"`, true},
+		{`*`, true},
+		{`"other"`, false},
+		{`"ab"`, false},
+		{``, false},
+	}
+	for _, tt := range tests {
+		if got := etagMatches(tt.ifNoneMatch, etag); got != tt.want {
+			t.Errorf("etagMatches(%q, %q) = %v, want %v", tt.ifNoneMatch, etag, got, tt.want)
+		}
+		if got := etagMatches(tt.ifNoneMatch, "W/"+etag); got != tt.want {
+			t.Errorf("etagMatches(%q, W/%q) = %v, want %v", tt.ifNoneMatch, etag, got, tt.want)
+		}
+	}
+}
+
+func TestHandleGetRun_NotModified(t *testing.T) {
+	srv, routes := newTestRoutes(t)
+	runID := triggerRun(t, srv, "0")
+	waitForRun(t, srv, runID, finished)
+
+	first := requestRun(routes, runID, nil)
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" || strings.HasPrefix(etag, "W/") {
+		t.Fatalf("Expected 200 with a strong ETag, got %d with %q", first.Code, etag)
+	}
+	if first.Header().Get("Cache-Control") != "no-cache" {
+		t.Errorf("Expected Cache-Control: no-cache, got %q", first.Header().Get("Cache-Control"))
+	}
+
+	for _, ifNoneMatch := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		rec := requestRun(routes, runID, map[string]string{"If-None-Match": ifNoneMatch})
+		if rec.Code != http.StatusNotModified {
+			t.Errorf("Expected 304 for If-None-Match %s, got %d", ifNoneMatch, rec.Code)
+		}
+		if rec.Body.Len() != 0 {
+			t.Errorf("Expected no body for If-None-Match %s, got %q", ifNoneMatch, rec.Body.String())
+		}
+		if got := rec.Header().Get("ETag"); got != etag {
+			t.Errorf("Expected ETag %s for If-None-Match %s, got %s", etag, ifNoneMatch, got)
+		}
+	}
+
+	if rec := requestRun(routes, runID, map[string]string{"If-None-Match": `"other"`}); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 for another ETag, got %d", rec.Code)
+	}
+}
+
+func TestHandleGetRun_Changed(t *testing.T) {
+	srv, routes := newTestRoutes(t)
+	runID := triggerRun(t, srv, "3")
+	waitForRun(t, srv, runID, jobRunning)
+
+	before := requestRun(routes, runID, nil)
+	etag := before.Header().Get("ETag")
+	if before.Code != http.StatusOK || etag == "" {
+		t.Fatalf("Expected 200 with an ETag, got %d with %q", before.Code, etag)
+	}
+
+	// Cancelling marks the run cancelling at once
+	if err := srv.CancelRun(runID); err != nil {
+		t.Fatalf("Failed to cancel run: %v", err)
+	}
+
+	after := requestRun(routes, runID, map[string]string{"If-None-Match": etag})
+	if after.Code != http.StatusOK || !strings.Contains(after.Body.String(), `"status":"cancelling"`) {
+		t.Fatalf("Expected 200 with the cancelling run, got %d: %s", after.Code, after.Body.String())
+	}
+	if changed := after.Header().Get("ETag"); changed == "" || changed == etag {
+		t.Errorf("Expected a new ETag, got %q (was %q)", changed, etag)
+	}
+}
+
+func TestHandleGetRun_NotModifiedGzipped(t *testing.T) {
+	srv, routes := newTestRoutes(t)
+	runID := triggerRun(t, srv, "0")
+	waitForRun(t, srv, runID, finished)
+
+	plain := requestRun(routes, runID, nil)
+	gzipped := requestRun(routes, runID, map[string]string{"Accept-Encoding": "gzip"})
+	etag := gzipped.Header().Get("ETag")
+	if gzipped.Code != http.StatusOK || gzipped.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected a gzipped 200, got %d with Content-Encoding %q", gzipped.Code, gzipped.Header().Get("Content-Encoding"))
+	}
+	if etag != "W/"+plain.Header().Get("ETag") {
+		t.Errorf("Expected the weak form of %s, got %s", plain.Header().Get("ETag"), etag)
+	}
+
+	zr, err := gzip.NewReader(gzipped.Body)
+	if err != nil {
+		t.Fatalf("Failed to read gzipped body: %v", err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil || string(body) != plain.Body.String() {
+		t.Errorf("Expected the gzipped body to be the plain one, got %q (%v)", body, err)
+	}
+
+	// The weak ETag matches whether or not the poll is gzipped again
+	for _, acceptEncoding := range []string{"gzip", ""} {
+		rec := requestRun(routes, runID, map[string]string{"If-None-Match": etag, "Accept-Encoding": acceptEncoding})
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Errorf("Expected an empty 304 with Accept-Encoding %q, got %d: %q", acceptEncoding, rec.Code, rec.Body.String())
+		}
+		if rec.Header().Get("Content-Encoding") != "" {
+			t.Errorf("Expected a 304 not to be encoded, got %q", rec.Header().Get("Content-Encoding"))
+		}
+	}
+}
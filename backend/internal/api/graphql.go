@@ -0,0 +1,292 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"gantry/internal/graphql"
+
+	"github.com/gorilla/websocket"
+)
+
+// graphqlProtocol is the WebSocket subprotocol GraphQL subscriptions are
+// served over, that of the graphql-ws library
+const graphqlProtocol = "graphql-transport-ws"
+
+// graphqlInitTimeout is how long a GraphQL WebSocket may stay open before
+// the client initialises it
+const graphqlInitTimeout = 10 * time.Second
+
+// Close codes of the graphql-transport-ws protocol
+const (
+	graphqlCloseBadMessage   = 4400
+	graphqlCloseUnauthorized = 4401
+	graphqlCloseInitTimeout  = 4408
+	graphqlCloseDuplicateID  = 4409
+	graphqlCloseTooManyInits = 4429
+)
+
+// graphqlUpgrader accepts GraphQL WebSockets from any origin, as the API's
+// CORS policy does
+var graphqlUpgrader = websocket.Upgrader{
+	CheckOrigin:  func(r *http.Request) bool { return true },
+	Subprotocols: []string{graphqlProtocol},
+}
+
+// HandleGraphQL executes a GraphQL query sent as a JSON body or, for GET
+// requests, in the query, operationName and variables query parameters.
+// GET requests upgrading to a WebSocket subscribe instead; see
+// handleGraphQLWS.
+func (h *Handler) HandleGraphQL(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		h.handleGraphQLWS(w, r)
+		return
+	}
+
+	var req graphql.Request
+	if r.Method == http.MethodGet {
+		query := r.URL.Query()
+		req.Query = query.Get("query")
+		req.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				http.Error(w, fmt.Sprintf("Invalid variables: %v", err), http.StatusBadRequest)
+				return
+			}
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Query == "" {
+		http.Error(w, "Request must have a query", http.StatusBadRequest)
+		return
+	}
+	if graphql.OperationType(req) == graphql.OperationSubscription {
+		http.Error(w, "Subscriptions need a WebSocket", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.graphql.Execute(r.Context(), req)); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// HandleGraphQLSchema returns the GraphQL schema in the schema definition
+// language
+func (h *Handler) HandleGraphQLSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if _, err := fmt.Fprint(w, h.graphql.String()); err != nil {
+		log.Printf("failed to send schema: %v", err)
+	}
+}
+
+// graphqlMessage is a message of the graphql-transport-ws protocol
+type graphqlMessage struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// graphqlConn is a GraphQL WebSocket and the operations running on it
+type graphqlConn struct {
+	conn *websocket.Conn
+
+	writeMu sync.Mutex // serializes writes, which operations make concurrently
+
+	mu         sync.Mutex
+	operations map[string]context.CancelFunc // by the client's ID
+}
+
+// send writes a message to the client
+func (c *graphqlConn) send(typ, id string, payload interface{}) error {
+	msg := graphqlMessage{Type: typ, ID: id}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		msg.Payload = data
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(logsWriteTimeout))
+	return c.conn.WriteJSON(msg)
+}
+
+// close ends the connection with a close code and reason
+func (c *graphqlConn) close(code int, reason string) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_ = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason),
+		time.Now().Add(logsWriteTimeout))
+}
+
+// handleGraphQLWS runs GraphQL operations, usually subscriptions, over a
+// WebSocket speaking the graphql-transport-ws protocol: the client sends
+// connection_init and then a subscribe message per operation, and gets a
+// next message per result, then complete.
+func (h *Handler) handleGraphQLWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := graphqlUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader already responded with the error
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	c := &graphqlConn{conn: conn, operations: make(map[string]context.CancelFunc)}
+	if conn.Subprotocol() != graphqlProtocol {
+		c.close(websocket.CloseProtocolError, "unsupported subprotocol")
+		return
+	}
+
+	// The client must initialise the connection before it times out
+	initialised := make(chan struct{})
+	go func() {
+		select {
+		case <-initialised:
+		case <-ctx.Done():
+		case <-time.After(graphqlInitTimeout):
+			c.close(graphqlCloseInitTimeout, "Connection initialisation timeout")
+			cancel()
+			conn.Close()
+		}
+	}()
+
+	go func() {
+		ping := time.NewTicker(logsPingInterval)
+		defer ping.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ping.C:
+				c.writeMu.Lock()
+				err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(logsWriteTimeout))
+				c.writeMu.Unlock()
+				if err != nil {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	var operations sync.WaitGroup
+	defer operations.Wait()
+	defer cancel()
+
+	acked := false
+	for {
+		var msg graphqlMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			if _, ok := err.(*websocket.CloseError); !ok && ctx.Err() == nil {
+				c.close(graphqlCloseBadMessage, "Invalid message")
+			}
+			return
+		}
+
+		switch msg.Type {
+		case "connection_init":
+			if acked {
+				c.close(graphqlCloseTooManyInits, "Too many initialisation requests")
+				return
+			}
+			acked = true
+			close(initialised)
+			if err := c.send("connection_ack", "", nil); err != nil {
+				return
+			}
+		case "ping":
+			if err := c.send("pong", "", nil); err != nil {
+				return
+			}
+		case "pong":
+		case "subscribe":
+			if !acked {
+				c.close(graphqlCloseUnauthorized, "Unauthorized")
+				return
+			}
+			var req graphql.Request
+			if msg.ID == "" || json.Unmarshal(msg.Payload, &req) != nil {
+				c.close(graphqlCloseBadMessage, "Invalid subscribe message")
+				return
+			}
+			opCtx, ok := c.start(ctx, msg.ID)
+			if !ok {
+				c.close(graphqlCloseDuplicateID, fmt.Sprintf("Subscriber for %s already exists", msg.ID))
+				return
+			}
+			operations.Add(1)
+			go func() {
+				defer operations.Done()
+				h.runGraphQLOperation(opCtx, c, msg.ID, req)
+			}()
+		case "complete":
+			c.stop(msg.ID)
+		default:
+			c.close(graphqlCloseBadMessage, fmt.Sprintf("Unknown message type '%s'", msg.Type))
+			return
+		}
+	}
+}
+
+// start records an operation the client started, returning the context it
+// runs in, or false if the client already runs one with its ID
+func (c *graphqlConn) start(ctx context.Context, id string) (context.Context, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.operations[id]; ok {
+		return nil, false
+	}
+	opCtx, cancel := context.WithCancel(ctx)
+	c.operations[id] = cancel
+	return opCtx, true
+}
+
+// stop ends an operation, reporting whether it was still running
+func (c *graphqlConn) stop(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cancel, ok := c.operations[id]
+	if ok {
+		cancel()
+		delete(c.operations, id)
+	}
+	return ok
+}
+
+// runGraphQLOperation sends the results of an operation until it ends,
+// then completes it unless the client already did
+func (h *Handler) runGraphQLOperation(ctx context.Context, c *graphqlConn, id string, req graphql.Request) {
+	responses, errResp := h.graphql.Subscribe(ctx, req)
+	if errResp != nil {
+		if c.stop(id) {
+			_ = c.send("error", id, errResp.Errors)
+		}
+		return
+	}
+
+	for resp := range responses {
+		if ctx.Err() != nil {
+			break
+		}
+		if err := c.send("next", id, resp); err != nil {
+			c.stop(id)
+			return
+		}
+	}
+	if c.stop(id) {
+		_ = c.send("complete", id, nil)
+	}
+}
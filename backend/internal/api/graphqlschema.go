@@ -0,0 +1,481 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"gantry/internal/graphql"
+	"gantry/internal/models"
+	"gantry/internal/server"
+	"gantry/internal/storage"
+)
+
+// timeScalar is a point in time, as an RFC 3339 string like the REST API's
+var timeScalar = &graphql.Scalar{
+	Name:        "Time",
+	Description: "An RFC 3339 timestamp",
+	Serialize: func(v interface{}) (interface{}, error) {
+		switch t := v.(type) {
+		case time.Time:
+			return t.Format(time.RFC3339Nano), nil
+		case *time.Time:
+			return t.Format(time.RFC3339Nano), nil
+		}
+		return nil, fmt.Errorf("Time can't represent %T", v)
+	},
+	ParseValue: func(v interface{}) (interface{}, error) {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("Time must be an RFC 3339 string")
+		}
+		return time.Parse(time.RFC3339, s)
+	},
+}
+
+// graphqlJob is a job of a run, which runs keep by name
+type graphqlJob struct {
+	name string
+	job  models.Job
+}
+
+// graphqlEntry is an entry of a string map, such as a job's outputs
+type graphqlEntry struct {
+	Name  string
+	Value string
+}
+
+// newGraphQLSchema creates the schema of the GraphQL API: workflows, their
+// runs, the runs' jobs and the jobs' steps, and a subscription to changes
+// to runs. Like run responses, it leaves out what jobs printed.
+func newGraphQLSchema(srv *server.Server) (*graphql.Schema, error) {
+	nonNull := func(t graphql.Type) graphql.Type { return &graphql.NonNull{Of: t} }
+	listOf := func(t graphql.Type) graphql.Type { return nonNull(&graphql.List{Of: nonNull(t)}) }
+	pageArgs := []*graphql.Argument{
+		{Name: "limit", Type: graphql.Int, Description: "Most items returned; every item after offset without it"},
+		{Name: "offset", Type: graphql.Int, Description: "Items skipped from the start of the listing"},
+	}
+	runFilterArgs := []*graphql.Argument{
+		{Name: "status", Type: &graphql.List{Of: nonNull(graphql.String)}, Description: "Only runs with one of these statuses"},
+		{Name: "branch", Type: graphql.String, Description: "Only runs triggered for this branch"},
+		{Name: "since", Type: graphql.String, Description: "Only runs started at or after this RFC 3339 time, or this long ago, e.g. 24h"},
+		{Name: "until", Type: graphql.String, Description: "Only runs started before this RFC 3339 time, or this long ago"},
+	}
+
+	entry := &graphql.Object{Name: "Entry", Description: "A named value", Fields: []*graphql.Field{
+		{Name: "name", Type: nonNull(graphql.String), Resolve: entryField(func(e graphqlEntry) interface{} { return e.Name })},
+		{Name: "value", Type: nonNull(graphql.String), Resolve: entryField(func(e graphqlEntry) interface{} { return e.Value })},
+	}}
+
+	commit := &graphql.Object{Name: "Commit", Description: "The commit a run was triggered for", Fields: []*graphql.Field{
+		{Name: "message", Type: graphql.String, Resolve: commitField(func(c *models.CommitInfo) interface{} { return optional(c.Message) })},
+		{Name: "author", Type: graphql.String, Resolve: commitField(func(c *models.CommitInfo) interface{} { return optional(c.Author) })},
+		{Name: "url", Type: graphql.String, Resolve: commitField(func(c *models.CommitInfo) interface{} { return optional(c.URL) })},
+		{Name: "timestamp", Type: timeScalar, Resolve: commitField(func(c *models.CommitInfo) interface{} { return optionalTime(c.Timestamp) })},
+	}}
+
+	trigger := &graphql.Object{Name: "Trigger", Description: "The event that started a run", Fields: []*graphql.Field{
+		{Name: "event", Type: nonNull(graphql.String), Resolve: triggerField(func(t *models.TriggerInfo) interface{} { return t.Event })},
+		{Name: "action", Type: graphql.String, Resolve: triggerField(func(t *models.TriggerInfo) interface{} { return optional(t.Action) })},
+		{Name: "actor", Type: graphql.String, Resolve: triggerField(func(t *models.TriggerInfo) interface{} { return optional(t.Actor) })},
+		{Name: "branch", Type: graphql.String, Resolve: triggerField(func(t *models.TriggerInfo) interface{} { return optional(t.Branch) })},
+		{Name: "command", Type: graphql.String, Resolve: triggerField(func(t *models.TriggerInfo) interface{} { return optional(t.Command) })},
+		{Name: "pullRequest", Type: graphql.Int, Resolve: triggerField(func(t *models.TriggerInfo) interface{} {
+			if t.PullRequest == 0 {
+				return nil
+			}
+			return t.PullRequest
+		})},
+		{Name: "repository", Type: graphql.String, Resolve: triggerField(func(t *models.TriggerInfo) interface{} { return optional(t.Repository) })},
+		{Name: "sha", Type: graphql.String, Resolve: triggerField(func(t *models.TriggerInfo) interface{} { return optional(t.SHA) })},
+		{Name: "commit", Type: commit, Description: "The commit sha names, when the event's webhook described it",
+			Resolve: triggerField(func(t *models.TriggerInfo) interface{} { return t.Commit })},
+	}}
+
+	attempt := &graphql.Object{Name: "StepAttempt", Description: "An attempt of a retried step", Fields: []*graphql.Field{
+		{Name: "success", Type: nonNull(graphql.Boolean), Resolve: attemptField(func(a models.StepAttempt) interface{} { return a.Success })},
+		{Name: "startedAt", Type: nonNull(timeScalar), Resolve: attemptField(func(a models.StepAttempt) interface{} { return a.StartedAt })},
+		{Name: "endedAt", Type: nonNull(timeScalar), Resolve: attemptField(func(a models.StepAttempt) interface{} { return a.EndedAt })},
+		{Name: "exitCode", Type: graphql.Int, Resolve: attemptField(func(a models.StepAttempt) interface{} { return optionalInt(a.ExitCode) })},
+	}}
+
+	step := &graphql.Object{Name: "Step", Description: "A step of a job", Fields: []*graphql.Field{
+		{Name: "id", Type: graphql.String, Resolve: stepField(func(s models.Step) interface{} { return optional(s.ID) })},
+		{Name: "name", Type: nonNull(graphql.String), Resolve: stepField(func(s models.Step) interface{} { return s.Name })},
+		{Name: "status", Type: graphql.String, Resolve: stepField(func(s models.Step) interface{} { return optional(s.Status) })},
+		{Name: "startedAt", Type: timeScalar, Resolve: stepField(func(s models.Step) interface{} { return optionalTime(s.StartedAt) })},
+		{Name: "endedAt", Type: timeScalar, Resolve: stepField(func(s models.Step) interface{} { return s.EndedAt })},
+		{Name: "exitCode", Type: graphql.Int, Resolve: stepField(func(s models.Step) interface{} { return optionalInt(s.ExitCode) })},
+		{Name: "failureReason", Type: graphql.String, Resolve: stepField(func(s models.Step) interface{} { return optional(s.FailureReason) })},
+		{Name: "attempts", Type: listOf(attempt), Resolve: stepField(func(s models.Step) interface{} {
+			return append([]models.StepAttempt{}, s.Attempts...)
+		})},
+	}}
+
+	job := &graphql.Object{Name: "Job", Description: "A job of a run", Fields: []*graphql.Field{
+		{Name: "name", Type: nonNull(graphql.String), Resolve: jobField(func(j graphqlJob) interface{} { return j.name })},
+		{Name: "status", Type: nonNull(graphql.String), Resolve: jobField(func(j graphqlJob) interface{} { return j.job.Status })},
+		{Name: "runsOn", Type: graphql.String, Resolve: jobField(func(j graphqlJob) interface{} { return optional(j.job.RunsOn) })},
+		{Name: "needs", Type: listOf(graphql.String), Resolve: jobField(func(j graphqlJob) interface{} {
+			return append([]string{}, j.job.Needs...)
+		})},
+		{Name: "environment", Type: graphql.String, Resolve: jobField(func(j graphqlJob) interface{} { return optional(j.job.Environment) })},
+		{Name: "call", Type: graphql.String, Description: "The job whose uses: this job was expanded from",
+			Resolve: jobField(func(j graphqlJob) interface{} { return optional(j.job.Call) })},
+		{Name: "startedAt", Type: timeScalar, Resolve: jobField(func(j graphqlJob) interface{} { return optionalTime(j.job.StartedAt) })},
+		{Name: "endedAt", Type: timeScalar, Resolve: jobField(func(j graphqlJob) interface{} { return j.job.EndedAt })},
+		{Name: "failureReason", Type: graphql.String, Resolve: jobField(func(j graphqlJob) interface{} { return optional(j.job.FailureReason) })},
+		{Name: "failedStep", Type: graphql.String, Resolve: jobField(func(j graphqlJob) interface{} { return optional(j.job.FailedStep) })},
+		{Name: "exitCode", Type: graphql.Int, Resolve: jobField(func(j graphqlJob) interface{} { return optionalInt(j.job.ExitCode) })},
+		{Name: "matrix", Type: listOf(entry), Resolve: jobField(func(j graphqlJob) interface{} { return entries(j.job.Matrix) })},
+		{Name: "outputs", Type: listOf(entry), Resolve: jobField(func(j graphqlJob) interface{} { return entries(j.job.Outputs) })},
+		{Name: "steps", Type: listOf(step), Resolve: jobField(func(j graphqlJob) interface{} {
+			return append([]models.Step{}, j.job.Steps...)
+		})},
+	}}
+
+	workflow := &graphql.Object{Name: "Workflow", Description: "A stored workflow"}
+	run := &graphql.Object{Name: "Run", Description: "A run of a workflow"}
+	runList := &graphql.Object{Name: "RunList", Description: "A page of runs, newest first", Fields: []*graphql.Field{
+		{Name: "total", Type: nonNull(graphql.Int), Description: "How many runs match, on every page"},
+		{Name: "items", Type: listOf(run)},
+	}}
+	workflowList := &graphql.Object{Name: "WorkflowList", Description: "A page of workflows, by name", Fields: []*graphql.Field{
+		{Name: "total", Type: nonNull(graphql.Int), Description: "How many workflows there are"},
+		{Name: "items", Type: listOf(workflow)},
+	}}
+
+	workflow.Fields = []*graphql.Field{
+		{Name: "id", Type: nonNull(graphql.ID), Resolve: workflowField(func(wf *models.Workflow) interface{} { return wf.ID })},
+		{Name: "name", Type: nonNull(graphql.String), Resolve: workflowField(func(wf *models.Workflow) interface{} { return wf.Name })},
+		{Name: "jobs", Type: listOf(graphql.String), Description: "The names of the workflow's jobs, in order",
+			Resolve: workflowField(func(wf *models.Workflow) interface{} { return append([]string{}, wf.JobOrder...) })},
+		{Name: "variables", Type: listOf(entry), Resolve: workflowField(func(wf *models.Workflow) interface{} { return entries(wf.Variables) })},
+		{
+			Name:        "runs",
+			Description: "The workflow's runs, including those from before it was renamed",
+			Type:        nonNull(runList),
+			Args:        append(append([]*graphql.Argument{}, runFilterArgs...), pageArgs...),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return listRuns(srv, p.Args, p.Source.(*models.Workflow).ID)
+			},
+		},
+	}
+
+	run.Fields = []*graphql.Field{
+		{Name: "id", Type: nonNull(graphql.ID), Resolve: runField(func(r *models.WorkflowRun) interface{} { return r.ID })},
+		{Name: "workflowId", Type: nonNull(graphql.ID), Resolve: runField(func(r *models.WorkflowRun) interface{} { return r.WorkflowID })},
+		{Name: "workflowName", Type: nonNull(graphql.String), Description: "The workflow's name when the run started",
+			Resolve: runField(func(r *models.WorkflowRun) interface{} { return r.WorkflowName })},
+		{
+			Name:        "workflow",
+			Description: "The run's workflow as it is now, or null once it is deleted",
+			Type:        workflow,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				wf, err := srv.GetWorkflow(p.Source.(*models.WorkflowRun).WorkflowID)
+				if err != nil {
+					return nil, nil
+				}
+				return wf, nil
+			},
+		},
+		{Name: "status", Type: nonNull(graphql.String), Resolve: runField(func(r *models.WorkflowRun) interface{} { return r.Status })},
+		{Name: "startedAt", Type: nonNull(timeScalar), Resolve: runField(func(r *models.WorkflowRun) interface{} { return r.StartedAt })},
+		{Name: "completedAt", Type: timeScalar, Resolve: runField(func(r *models.WorkflowRun) interface{} { return r.CompletedAt })},
+		{Name: "rerunOf", Type: graphql.ID, Description: "The run this one re-ran",
+			Resolve: runField(func(r *models.WorkflowRun) interface{} { return optional(r.RerunOf) })},
+		{Name: "trigger", Type: trigger, Resolve: runField(func(r *models.WorkflowRun) interface{} { return r.Trigger })},
+		{Name: "variables", Type: listOf(entry), Resolve: runField(func(r *models.WorkflowRun) interface{} { return entries(r.Variables) })},
+		{Name: "env", Type: listOf(entry), Description: "Overrides of the workflow's env the run was triggered with",
+			Resolve: runField(func(r *models.WorkflowRun) interface{} { return entries(r.Env) })},
+		{Name: "labels", Type: listOf(entry), Resolve: runField(func(r *models.WorkflowRun) interface{} { return entries(r.Labels) })},
+		{Name: "jobs", Type: listOf(job), Description: "The run's jobs, in the order they were declared",
+			Resolve: runField(func(r *models.WorkflowRun) interface{} { return runJobs(r) })},
+		{
+			Name: "job",
+			Type: job,
+			Args: []*graphql.Argument{{Name: "name", Type: nonNull(graphql.String)}},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				name := p.Args["name"].(string)
+				j, ok := p.Source.(*models.WorkflowRun).GetJob(name)
+				if !ok {
+					return nil, nil
+				}
+				return graphqlJob{name: name, job: j}, nil
+			},
+		},
+	}
+
+	runEvent := &graphql.Object{Name: "RunEvent", Description: "A change to a run", Fields: []*graphql.Field{
+		{Name: "type", Type: nonNull(graphql.String), Description: "run_started, run_status, job_status or run_completed",
+			Resolve: eventField(func(ev server.RunEvent) interface{} { return ev.Type })},
+		{Name: "runId", Type: nonNull(graphql.ID), Resolve: eventField(func(ev server.RunEvent) interface{} { return ev.RunID })},
+		{Name: "workflow", Type: nonNull(graphql.String), Resolve: eventField(func(ev server.RunEvent) interface{} { return ev.Workflow })},
+		{Name: "job", Type: graphql.String, Description: "The job of job_status events",
+			Resolve: eventField(func(ev server.RunEvent) interface{} { return optional(ev.Job) })},
+		{Name: "status", Type: nonNull(graphql.String), Resolve: eventField(func(ev server.RunEvent) interface{} { return ev.Status })},
+		{Name: "time", Type: nonNull(timeScalar), Resolve: eventField(func(ev server.RunEvent) interface{} { return ev.Time })},
+		{
+			Name:        "run",
+			Description: "The run as it is when the event is sent, or null once it is deleted",
+			Type:        run,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return getRun(srv, p.Source.(server.RunEvent).RunID), nil
+			},
+		},
+	}}
+
+	query := &graphql.Object{Name: "Query", Fields: []*graphql.Field{
+		{
+			Name:        "workflows",
+			Description: "A page of the workflows",
+			Type:        nonNull(workflowList),
+			Args:        pageArgs,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				opts, err := graphqlListOptions(p.Args)
+				if err != nil {
+					return nil, err
+				}
+				workflows, total, err := srv.ListWorkflows(opts)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{"total": total, "items": workflows}, nil
+			},
+		},
+		{
+			Name:        "workflow",
+			Description: "A workflow by ID or name, or null if there is none",
+			Type:        workflow,
+			Args:        []*graphql.Argument{{Name: "id", Type: nonNull(graphql.ID), Description: "The workflow's ID or name"}},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				wf, err := srv.GetWorkflow(p.Args["id"].(string))
+				if err != nil {
+					return nil, nil
+				}
+				return wf, nil
+			},
+		},
+		{
+			Name:        "runs",
+			Description: "A page of the runs the filters match",
+			Type:        nonNull(runList),
+			Args: append(append([]*graphql.Argument{
+				{Name: "workflow", Type: graphql.String, Description: "Only runs of this workflow, by ID or name"},
+			}, runFilterArgs...), pageArgs...),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				workflowID, _ := p.Args["workflow"].(string)
+				return listRuns(srv, p.Args, workflowID)
+			},
+		},
+		{
+			Name:        "run",
+			Description: "A run by ID, or null if there is none",
+			Type:        run,
+			Args:        []*graphql.Argument{{Name: "id", Type: nonNull(graphql.ID)}},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return getRun(srv, p.Args["id"].(string)), nil
+			},
+		},
+	}}
+
+	subscription := &graphql.Object{Name: "Subscription", Fields: []*graphql.Field{
+		{
+			Name: "runEvents",
+			Description: "Changes to every run from now on or, given runId, the run as it is and then its\n" +
+				"changes until it completes. Subscribers that fall behind are completed early.",
+			Type: nonNull(runEvent),
+			Args: []*graphql.Argument{{Name: "runId", Type: graphql.ID}},
+			Subscribe: func(p graphql.ResolveParams) (<-chan interface{}, error) {
+				runID, _ := p.Args["runId"].(string)
+				return subscribeRunEvents(p.Context, srv, runID)
+			},
+		},
+	}}
+
+	return graphql.NewSchema(query, subscription)
+}
+
+// subscribeRunEvents passes on the events of a run, or of every run when
+// runID is empty, as the server-sent event streams do
+func subscribeRunEvents(ctx context.Context, srv *server.Server, runID string) (<-chan interface{}, error) {
+	// Subscribe before reading the run, so no change falls between them
+	sub := srv.SubscribeRunEvents(runID)
+
+	var snapshot []server.RunEvent
+	if runID != "" {
+		run, err := srv.GetRun(runID)
+		if err != nil {
+			sub.Close()
+			return nil, fmt.Errorf("run '%s' not found", runID)
+		}
+		snapshot = server.RunSnapshot(run)
+	}
+
+	events := make(chan interface{})
+	go func() {
+		defer close(events)
+		defer sub.Close()
+
+		send := func(ev server.RunEvent) bool {
+			select {
+			case events <- ev:
+				return runID == "" || ev.Type != server.RunEventCompleted
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for _, ev := range snapshot {
+			if !send(ev) {
+				return
+			}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-sub.Events:
+				if !ok || !send(ev) {
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// listRuns returns the page of runs of a runs field's arguments, of a
+// workflow if workflowID is set
+func listRuns(srv *server.Server, args map[string]interface{}, workflowID string) (interface{}, error) {
+	opts, err := graphqlListOptions(args)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := storage.RunFilter{WorkflowID: workflowID}
+	filter.Branch, _ = args["branch"].(string)
+	statuses, _ := args["status"].([]interface{})
+	for _, status := range statuses {
+		filter.Statuses = append(filter.Statuses, status.(string))
+	}
+	now := time.Now()
+	since, _ := args["since"].(string)
+	if filter.Since, err = queryTime(since, now); err != nil {
+		return nil, fmt.Errorf("since: %w", err)
+	}
+	until, _ := args["until"].(string)
+	if filter.Until, err = queryTime(until, now); err != nil {
+		return nil, fmt.Errorf("until: %w", err)
+	}
+
+	runs, total, err := srv.ListRuns(filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"total": total, "items": runSummaries(runs)}, nil
+}
+
+// graphqlListOptions reads the page a listing field asks for from its limit
+// and offset arguments
+func graphqlListOptions(args map[string]interface{}) (storage.ListOptions, error) {
+	var opts storage.ListOptions
+	opts.Limit, _ = args["limit"].(int)
+	opts.Offset, _ = args["offset"].(int)
+	if opts.Limit < 0 || opts.Offset < 0 {
+		return opts, fmt.Errorf("limit and offset must not be negative")
+	}
+	return opts, nil
+}
+
+// getRun returns the summary of a run, or nil if it doesn't exist
+func getRun(srv *server.Server, id string) *models.WorkflowRun {
+	run, err := srv.GetRun(id)
+	if err != nil {
+		return nil
+	}
+	return run.Summary()
+}
+
+// runJobs returns the jobs of a run in the order they were declared, then
+// any others by name
+func runJobs(run *models.WorkflowRun) []graphqlJob {
+	names := run.JobNames()
+	jobs := make([]graphqlJob, len(names))
+	for i, name := range names {
+		jobs[i] = graphqlJob{name: name, job: run.Jobs[name]}
+	}
+	return jobs
+}
+
+// entries returns a string map as entries sorted by name
+func entries(m map[string]string) []graphqlEntry {
+	list := make([]graphqlEntry, 0, len(m))
+	for name, value := range m {
+		list = append(list, graphqlEntry{Name: name, Value: value})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// optional returns s, or nil when it is empty so the field is null
+func optional(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// optionalInt returns n, or nil when it is zero so the field is null
+func optionalInt(n int) interface{} {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
+
+// optionalTime returns t, or nil when it is unset so the field is null
+func optionalTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+// The resolvers of fields read from their objects
+
+func workflowField(get func(*models.Workflow) interface{}) func(graphql.ResolveParams) (interface{}, error) {
+	return func(p graphql.ResolveParams) (interface{}, error) { return get(p.Source.(*models.Workflow)), nil }
+}
+
+func runField(get func(*models.WorkflowRun) interface{}) func(graphql.ResolveParams) (interface{}, error) {
+	return func(p graphql.ResolveParams) (interface{}, error) { return get(p.Source.(*models.WorkflowRun)), nil }
+}
+
+func triggerField(get func(*models.TriggerInfo) interface{}) func(graphql.ResolveParams) (interface{}, error) {
+	return func(p graphql.ResolveParams) (interface{}, error) { return get(p.Source.(*models.TriggerInfo)), nil }
+}
+
+func commitField(get func(*models.CommitInfo) interface{}) func(graphql.ResolveParams) (interface{}, error) {
+	return func(p graphql.ResolveParams) (interface{}, error) { return get(p.Source.(*models.CommitInfo)), nil }
+}
+
+func jobField(get func(graphqlJob) interface{}) func(graphql.ResolveParams) (interface{}, error) {
+	return func(p graphql.ResolveParams) (interface{}, error) { return get(p.Source.(graphqlJob)), nil }
+}
+
+func stepField(get func(models.Step) interface{}) func(graphql.ResolveParams) (interface{}, error) {
+	return func(p graphql.ResolveParams) (interface{}, error) { return get(p.Source.(models.Step)), nil }
+}
+
+func attemptField(get func(models.StepAttempt) interface{}) func(graphql.ResolveParams) (interface{}, error) {
+	return func(p graphql.ResolveParams) (interface{}, error) { return get(p.Source.(models.StepAttempt)), nil }
+}
+
+func entryField(get func(graphqlEntry) interface{}) func(graphql.ResolveParams) (interface{}, error) {
+	return func(p graphql.ResolveParams) (interface{}, error) { return get(p.Source.(graphqlEntry)), nil }
+}
+
+func eventField(get func(server.RunEvent) interface{}) func(graphql.ResolveParams) (interface{}, error) {
+	return func(p graphql.ResolveParams) (interface{}, error) { return get(p.Source.(server.RunEvent)), nil }
+}
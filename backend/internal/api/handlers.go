@@ -2,26 +2,46 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"gantry/internal/artifacts"
+	"gantry/internal/graphql"
+	"gantry/internal/importer"
+	"gantry/internal/models"
+	"gantry/internal/parser"
 	"gantry/internal/server"
+	"gantry/internal/storage"
 
 	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
 )
 
 // Handler manages HTTP requests
 type Handler struct {
-	server *server.Server
+	server  *server.Server
+	graphql *graphql.Schema
 }
 
 // NewHandler creates a new API handler
 func NewHandler(srv *server.Server) *Handler {
+	schema, err := newGraphQLSchema(srv)
+	if err != nil {
+		// The schema is fixed, so this is a bug rather than bad input
+		panic(fmt.Sprintf("invalid GraphQL schema: %v", err))
+	}
 	return &Handler{
-		server: srv,
+		server:  srv,
+		graphql: schema,
 	}
 }
 
@@ -33,45 +53,333 @@ func (h *Handler) HandleUploadWorkflow(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	wf, err := h.server.ParseAndSaveWorkflow(body)
+	username, _ := r.Context().Value(userKey{}).(string)
+	wf, err := h.server.ParseAndSaveWorkflow(body, username)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to parse workflow: %v", err), http.StatusBadRequest)
+		writeValidationErrors(w, "Failed to parse workflow", err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 
-	if err := json.NewEncoder(w).Encode(map[string]string{
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
 		"message": "Workflow uploaded successfully",
+		"id":      wf.ID,
 		"name":    wf.Name,
+		"version": wf.Version,
 	}); err != nil {
 		log.Printf("failed to encode response: %v", err)
 	}
 }
 
-// HandleListWorkflows handles listing workflows
-func (h *Handler) HandleListWorkflows(w http.ResponseWriter, _ *http.Request) {
-	workflows, err := h.server.ListWorkflows()
+// HandleBulkUploadWorkflows saves several workflows, all or none. The body
+// is a JSON array of YAML documents, or multipart/form-data with a file per
+// workflow.
+func (h *Handler) HandleBulkUploadWorkflows(w http.ResponseWriter, r *http.Request) {
+	docs, err := bulkDocuments(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	username, _ := r.Context().Value(userKey{}).(string)
+	result, err := h.server.SaveWorkflows(docs, username)
+	if err != nil && !errors.Is(err, server.ErrInvalidBulk) {
+		http.Error(w, fmt.Sprintf("Failed to upload workflows: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// bulkDocuments reads the workflows of a bulk upload, named by their file
+// name, or their index in a JSON array
+func bulkDocuments(r *http.Request) ([]server.BulkDocument, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, fmt.Errorf("expected application/json or multipart/form-data: %w", err)
+	}
+
+	switch mediaType {
+	case "application/json":
+		var sources []string
+		if err := json.NewDecoder(r.Body).Decode(&sources); err != nil {
+			return nil, err
+		}
+		docs := make([]server.BulkDocument, len(sources))
+		for i, source := range sources {
+			docs[i] = server.BulkDocument{File: fmt.Sprintf("[%d]", i), Data: []byte(source)}
+		}
+		return docs, nil
+
+	case "multipart/form-data":
+		reader, err := r.MultipartReader()
+		if err != nil {
+			return nil, err
+		}
+		var docs []server.BulkDocument
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				return docs, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			if part.FileName() == "" {
+				continue
+			}
+			data, err := io.ReadAll(part)
+			if err != nil {
+				return nil, err
+			}
+			docs = append(docs, server.BulkDocument{File: part.FileName(), Data: data})
+		}
+	}
+	return nil, fmt.Errorf("expected application/json or multipart/form-data, got %s", mediaType)
+}
+
+// HandleUpdateWorkflow replaces a workflow with a new version, keeping its
+// ID and runs
+func (h *Handler) HandleUpdateWorkflow(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	username, _ := r.Context().Value(userKey{}).(string)
+	wf, err := h.server.UpdateWorkflow(name, body, username)
+	switch {
+	case errors.Is(err, server.ErrWorkflowNotFound):
+		http.Error(w, fmt.Sprintf("Failed to update workflow: %v", err), http.StatusNotFound)
+		return
+	case errors.Is(err, storage.ErrWorkflowExists):
+		http.Error(w, fmt.Sprintf("Failed to update workflow: %v", err), http.StatusConflict)
+		return
+	case err != nil:
+		writeValidationErrors(w, "Failed to parse workflow", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Workflow updated successfully",
+		"id":      wf.ID,
+		"name":    wf.Name,
+		"version": wf.Version,
+	}); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// HandleGetWorkflowYAML returns the YAML a workflow was uploaded as, with
+// its comments and formatting, or that of an earlier version named by the
+// version query parameter
+func (h *Handler) HandleGetWorkflowYAML(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	version := 0
+	if v := r.URL.Query().Get("version"); v != "" {
+		var err error
+		if version, err = strconv.Atoi(v); err != nil || version < 1 {
+			http.Error(w, fmt.Sprintf("Invalid version: %q", v), http.StatusBadRequest)
+			return
+		}
+	}
+
+	source, err := h.server.WorkflowSource(name, version)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, server.ErrWorkflowNotFound) || errors.Is(err, server.ErrNoSource) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, fmt.Sprintf("Failed to export workflow: %v", err), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	if _, err := io.WriteString(w, source); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
+
+// HandleListWorkflowVersions lists the saved versions of a workflow, newest
+// first
+func (h *Handler) HandleListWorkflowVersions(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	revisions, err := h.server.ListWorkflowRevisions(name)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, server.ErrWorkflowNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, fmt.Sprintf("Failed to list workflow versions: %v", err), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(revisions); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// HandleValidateWorkflow checks a workflow without saving it. Problems are
+// reported in the response body, so an invalid workflow still gets a 200.
+func (h *Handler) HandleValidateWorkflow(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(h.server.ValidateWorkflow(body)); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// writeValidationErrors responds with 400 and the list of problems err
+// describes, each with its line, column and field path when known
+func writeValidationErrors(w http.ResponseWriter, message string, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":  fmt.Sprintf("%s: %v", message, err),
+		"errors": parser.AsValidationErrors(err),
+	}); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// HandleImportWorkflow translates and saves a workflow file of another CI
+// system, named by the format query parameter. The name parameter names the
+// saved workflow.
+func (h *Handler) HandleImportWorkflow(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = importer.FormatGitHub
+	}
+
+	username, _ := r.Context().Value(userKey{}).(string)
+	result, err := h.server.ImportWorkflow(format, body, importer.Options{Name: r.URL.Query().Get("name")}, username)
+	if errors.Is(err, importer.ErrUnsupportedFormat) || errors.Is(err, importer.ErrInvalidSource) {
+		http.Error(w, fmt.Sprintf("Failed to import workflow: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeValidationErrors(w, "Imported workflow is invalid", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// HandleListWorkflows handles listing workflows, a page at a time when the
+// request sets limit or offset
+func (h *Handler) HandleListWorkflows(w http.ResponseWriter, r *http.Request) {
+	opts, err := listOptions(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid query: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	workflows, total, err := h.server.ListWorkflows(opts)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to list workflows: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
 
 	if err := json.NewEncoder(w).Encode(workflows); err != nil {
 		log.Printf("failed to encode response: %v", err)
 	}
 }
 
+// listOptions reads the page a listing request asks for from its limit and
+// offset query parameters
+func listOptions(r *http.Request) (storage.ListOptions, error) {
+	var opts storage.ListOptions
+	for name, value := range map[string]*int{"limit": &opts.Limit, "offset": &opts.Offset} {
+		param := r.URL.Query().Get(name)
+		if param == "" {
+			continue
+		}
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 0 {
+			return opts, fmt.Errorf("%s must be a non-negative integer, got '%s'", name, param)
+		}
+		*value = n
+	}
+	return opts, nil
+}
+
 // HandleTriggerWorkflow handles workflow trigger requests
 func (h *Handler) HandleTriggerWorkflow(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	name := vars["name"]
 
-	run, err := h.server.TriggerWorkflow(r.Context(), name)
+	// The body is optional; it only carries workflow_dispatch inputs,
+	// variable and env overrides, the branch to run for and labels for the
+	// run
+	var req struct {
+		Inputs    map[string]interface{} `json:"inputs"`
+		Variables map[string]string      `json:"variables"`
+		Branch    string                 `json:"branch"`
+		Env       map[string]string      `json:"env"`
+		Labels    map[string]string      `json:"labels"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	run, err := h.server.TriggerWorkflow(r.Context(), name, server.TriggerOptions{
+		Inputs:    req.Inputs,
+		Variables: req.Variables,
+		Branch:    req.Branch,
+		Env:       req.Env,
+		Labels:    req.Labels,
+	})
+	if errors.Is(err, server.ErrSkipped) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]string{"status": "skipped", "reason": err.Error()}); err != nil {
+			log.Printf("failed to encode response: %v", err)
+		}
+		return
+	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to trigger workflow: %v", err), http.StatusInternalServerError)
+		status := http.StatusInternalServerError
+		if errors.Is(err, server.ErrInvalidInputs) || errors.Is(err, server.ErrInvalidVariables) ||
+			errors.Is(err, server.ErrInvalidEnv) || errors.Is(err, server.ErrInvalidLabels) ||
+			errors.Is(err, server.ErrInvalidCall) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, fmt.Sprintf("Failed to trigger workflow: %v", err), status)
 		return
 	}
 
@@ -82,6 +390,31 @@ func (h *Handler) HandleTriggerWorkflow(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// HandleEvent handles inbound trigger events, such as comment commands
+func (h *Handler) HandleEvent(w http.ResponseWriter, r *http.Request) {
+	var ev server.Event
+	if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid event: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	runs, err := h.server.DispatchEvent(r.Context(), ev)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, server.ErrInvalidEvent) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, fmt.Sprintf("Failed to dispatch event: %v", err), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(runs); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
 // HandleGetRun handles get run details requests
 func (h *Handler) HandleGetRun(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -93,27 +426,203 @@ func (h *Handler) HandleGetRun(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The dashboard polls runs, so unchanged ones are answered with 304
+	writeJSONWithETag(w, r, run.Summary())
+}
+
+// runSummaries returns the summaries of runs, which leave out what their
+// jobs printed
+func runSummaries(runs []*models.WorkflowRun) []*models.WorkflowRun {
+	summaries := make([]*models.WorkflowRun, len(runs))
+	for i, run := range runs {
+		summaries[i] = run.Summary()
+	}
+	return summaries
+}
+
+// HandleCancelRun cancels a running run
+func (h *Handler) HandleCancelRun(w http.ResponseWriter, r *http.Request) {
+	runID := mux.Vars(r)["id"]
+
+	if _, err := h.server.GetRun(runID); err != nil {
+		http.Error(w, "Run not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.server.CancelRun(runID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, server.ErrNotRunning) {
+			status = http.StatusConflict
+		}
+		http.Error(w, fmt.Sprintf("Failed to cancel run: %v", err), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{
+		"message": "Run cancelled",
+		"run_id":  runID,
+	}); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// HandleRerunRun starts a new run of a run's workflow, as it ran
+func (h *Handler) HandleRerunRun(w http.ResponseWriter, r *http.Request) {
+	runID := mux.Vars(r)["id"]
+
+	if _, err := h.server.GetRun(runID); err != nil {
+		http.Error(w, "Run not found", http.StatusNotFound)
+		return
+	}
+
+	run, err := h.server.RerunRun(r.Context(), runID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, server.ErrInvalidCall) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, fmt.Sprintf("Failed to re-run: %v", err), status)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(run); err != nil {
 		log.Printf("failed to encode response: %v", err)
 	}
 }
 
-// HandleListRuns handles listing all runs
-func (h *Handler) HandleListRuns(w http.ResponseWriter, _ *http.Request) {
-	runs, err := h.server.ListRuns()
+// HandleDeleteRun deletes a run and its artifacts
+func (h *Handler) HandleDeleteRun(w http.ResponseWriter, r *http.Request) {
+	runID := mux.Vars(r)["id"]
+
+	if _, err := h.server.GetRun(runID); err != nil {
+		http.Error(w, "Run not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.server.DeleteRun(runID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, server.ErrRunActive) {
+			status = http.StatusConflict
+		}
+		http.Error(w, fmt.Sprintf("Failed to delete run: %v", err), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{
+		"message": "Run deleted",
+		"run_id":  runID,
+	}); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// HandleDeleteRuns deletes the runs the request's filters match, which it
+// must set so that no request deletes every run by accident
+func (h *Handler) HandleDeleteRuns(w http.ResponseWriter, r *http.Request) {
+	filter, err := runFilter(r, time.Now())
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to list runs: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Invalid query: %v", err), http.StatusBadRequest)
+		return
+	}
+	if filter.WorkflowID == "" && len(filter.Statuses) == 0 && filter.Branch == "" &&
+		filter.Since.IsZero() && filter.Until.IsZero() {
+		http.Error(w, "Set at least one of workflow, status, branch, since or until", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := h.server.DeleteRuns(filter)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, server.ErrWorkflowNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, fmt.Sprintf("Failed to delete runs: %v", err), status)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Runs deleted",
+		"deleted": deleted,
+	}); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
 
-	if err := json.NewEncoder(w).Encode(runs); err != nil {
+// HandleListRuns handles listing runs, newest first, a page at a time when
+// the request sets limit or offset and only those its filters match
+func (h *Handler) HandleListRuns(w http.ResponseWriter, r *http.Request) {
+	opts, err := listOptions(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid query: %v", err), http.StatusBadRequest)
+		return
+	}
+	filter, err := runFilter(r, time.Now())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid query: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	runs, total, err := h.server.ListRuns(filter, opts)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, server.ErrWorkflowNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, fmt.Sprintf("Failed to list runs: %v", err), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+	if err := json.NewEncoder(w).Encode(runSummaries(runs)); err != nil {
 		log.Printf("failed to encode response: %v", err)
 	}
 }
 
+// runFilter reads the runs a listing request asks for from its query: the
+// workflow, comma-separated statuses, the branch, and a range of start times
+func runFilter(r *http.Request, now time.Time) (storage.RunFilter, error) {
+	query := r.URL.Query()
+	filter := storage.RunFilter{
+		WorkflowID: query.Get("workflow"),
+		Branch:     query.Get("branch"),
+	}
+	for _, status := range strings.Split(query.Get("status"), ",") {
+		if status = strings.TrimSpace(status); status != "" {
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+
+	var err error
+	if filter.Since, err = queryTime(query.Get("since"), now); err != nil {
+		return filter, fmt.Errorf("since: %w", err)
+	}
+	if filter.Until, err = queryTime(query.Get("until"), now); err != nil {
+		return filter, fmt.Errorf("until: %w", err)
+	}
+	return filter, nil
+}
+
+// queryTime parses an RFC 3339 time, or a duration such as 24h meaning that
+// long before now. An empty value is the zero time.
+func queryTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("'%s' is neither an RFC 3339 time nor a duration", value)
+}
+
 // HandleDeleteWorkflow handles workflow deletion
 func (h *Handler) HandleDeleteWorkflow(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -133,6 +642,41 @@ func (h *Handler) HandleDeleteWorkflow(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// HandleRenameWorkflow handles renaming a workflow, which keeps its ID and runs
+func (h *Handler) HandleRenameWorkflow(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	username, _ := r.Context().Value(userKey{}).(string)
+	wf, err := h.server.RenameWorkflow(name, req.Name, username)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, server.ErrWorkflowNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, server.ErrInvalidName):
+			status = http.StatusBadRequest
+		case errors.Is(err, storage.ErrWorkflowExists):
+			status = http.StatusConflict
+		}
+		http.Error(w, fmt.Sprintf("Failed to rename workflow: %v", err), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(wf); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
 // HandleGetWorkflowStats handles getting workflow statistics
 func (h *Handler) HandleGetWorkflowStats(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -162,7 +706,386 @@ func (h *Handler) HandleGetWorkflowRuns(w http.ResponseWriter, r *http.Request)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(runs); err != nil {
+	if err := json.NewEncoder(w).Encode(runSummaries(runs)); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// HandleGetWorkflowImages reports the pre-pull progress of a workflow's images
+func (h *Handler) HandleGetWorkflowImages(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.server.ImagePulls(name)); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// HandleListArtifacts handles listing the artifacts of a run
+func (h *Handler) HandleListArtifacts(w http.ResponseWriter, r *http.Request) {
+	runID := mux.Vars(r)["id"]
+
+	if _, err := h.server.GetRun(runID); err != nil {
+		http.Error(w, "Run not found", http.StatusNotFound)
+		return
+	}
+
+	list, err := h.server.ListArtifacts(runID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list artifacts: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(list); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// HandleDownloadArtifact streams an artifact's tar archive
+func (h *Handler) HandleDownloadArtifact(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	runID, name := vars["id"], vars["name"]
+
+	if _, err := h.server.GetRun(runID); err != nil {
+		http.Error(w, "Run not found", http.StatusNotFound)
+		return
+	}
+
+	archive, err := h.server.OpenArtifact(runID, name)
+	if err != nil {
+		if errors.Is(err, artifacts.ErrNotFound) {
+			http.Error(w, "Artifact not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to open artifact: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer archive.Close()
+
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".tar"))
+	if _, err := io.Copy(w, archive); err != nil {
+		log.Printf("failed to send artifact: %v", err)
+	}
+}
+
+// HandleApproveJob lets a reviewer approve a job waiting for its environment
+func (h *Handler) HandleApproveJob(w http.ResponseWriter, r *http.Request) {
+	h.reviewJob(w, r, true)
+}
+
+// HandleRejectJob lets a reviewer reject a job waiting for its environment
+func (h *Handler) HandleRejectJob(w http.ResponseWriter, r *http.Request) {
+	h.reviewJob(w, r, false)
+}
+
+// reviewJob records a reviewer's decision on a waiting job
+func (h *Handler) reviewJob(w http.ResponseWriter, r *http.Request, approve bool) {
+	vars := mux.Vars(r)
+	runID, jobName := vars["id"], vars["job"]
+
+	var req struct {
+		Reviewer string `json:"reviewer"`
+	}
+	if username, ok := r.Context().Value(userKey{}).(string); ok {
+		// Signed-in users review as themselves, whoever the body names
+		req.Reviewer = username
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Reviewer == "" {
+		http.Error(w, "Request body must name the reviewer", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.server.GetRun(runID); err != nil {
+		http.Error(w, "Run not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.server.ReviewJob(runID, jobName, req.Reviewer, approve); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, server.ErrNotWaiting):
+			status = http.StatusConflict
+		case errors.Is(err, server.ErrNotReviewer):
+			status = http.StatusForbidden
+		}
+		http.Error(w, fmt.Sprintf("Failed to review job: %v", err), status)
+		return
+	}
+
+	decision := "rejected"
+	if approve {
+		decision = "approved"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{
+		"message": fmt.Sprintf("Job %s", decision),
+		"job":     jobName,
+	}); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// HandleGetJobLogs returns what a job has printed so far, which run
+// responses leave out
+func (h *Handler) HandleGetJobLogs(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	runID, jobName := vars["id"], vars["job"]
+
+	if _, err := h.server.GetRun(runID); err != nil {
+		http.Error(w, "Run not found", http.StatusNotFound)
+		return
+	}
+
+	jobLog, err := h.server.GetJobLog(runID, jobName)
+	if err != nil {
+		if errors.Is(err, server.ErrJobNotFound) {
+			http.Error(w, "Job not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to get job logs: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(jobLog); err != nil {
 		log.Printf("failed to encode response: %v", err)
 	}
 }
+
+// HandleDownloadLogs sends every job's logs of a run as an attachment: one
+// plain-text file, or a zip of a file per job with ?format=zip
+func (h *Handler) HandleDownloadLogs(w http.ResponseWriter, r *http.Request) {
+	runID := mux.Vars(r)["id"]
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "text" && format != "zip" {
+		http.Error(w, fmt.Sprintf("Unknown format '%s' (expected text or zip)", format), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.server.GetRun(runID); err != nil {
+		http.Error(w, "Run not found", http.StatusNotFound)
+		return
+	}
+
+	logs, err := h.server.RunLogs(runID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get logs: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	write, contentType, filename := server.WriteLogsText, "text/plain; charset=utf-8", runID+"-logs.txt"
+	if format == "zip" {
+		write, contentType, filename = server.WriteLogsZip, "application/zip", runID+"-logs.zip"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if err := write(w, logs); err != nil {
+		log.Printf("failed to send logs: %v", err)
+	}
+}
+
+// logsUpgrader accepts WebSocket connections from any origin, as the API's
+// CORS policy does
+var logsUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// logsWriteTimeout is how long sending a message to a log follower may take
+const logsWriteTimeout = 10 * time.Second
+
+// logsPingInterval is how often idle log followers are pinged, so proxies
+// keep their connections open
+const logsPingInterval = 30 * time.Second
+
+// logsMessage is what follows of a job's logs: a chunk of output, or the
+// status the job ended with, sent last
+type logsMessage struct {
+	Output string `json:"output,omitempty"`
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// HandleJobLogsWS streams a job's output over a WebSocket as it runs: what
+// it has printed so far, then each line it prints, then its status once it
+// finishes
+func (h *Handler) HandleJobLogsWS(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	runID, jobName := vars["id"], vars["job"]
+
+	if _, err := h.server.GetRun(runID); err != nil {
+		http.Error(w, "Run not found", http.StatusNotFound)
+		return
+	}
+
+	conn, err := logsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader already responded with the error
+		return
+	}
+	defer conn.Close()
+
+	// The client sends nothing but control frames; reading them notices
+	// when it goes away
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	closeCode, reason := streamJobLogs(ctx, conn, h.server, runID, jobName)
+	_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(closeCode, reason),
+		time.Now().Add(logsWriteTimeout))
+}
+
+// streamJobLogs sends a job's logs until it finishes, returning how to close
+// the connection
+func streamJobLogs(ctx context.Context, conn *websocket.Conn, srv *server.Server, runID, jobName string) (int, string) {
+	send := func(msg logsMessage) error {
+		_ = conn.SetWriteDeadline(time.Now().Add(logsWriteTimeout))
+		return conn.WriteJSON(msg)
+	}
+
+	logs, err := srv.FollowJobLogs(ctx, runID, jobName)
+	if err != nil {
+		if ctx.Err() != nil {
+			return websocket.CloseGoingAway, ""
+		}
+		_ = send(logsMessage{Error: err.Error()})
+		return websocket.ClosePolicyViolation, "job not found"
+	}
+	defer logs.Close()
+
+	if logs.Output != "" {
+		if err := send(logsMessage{Output: logs.Output}); err != nil {
+			return websocket.CloseGoingAway, ""
+		}
+	}
+
+	ping := time.NewTicker(logsPingInterval)
+	defer ping.Stop()
+	for done := false; !done; {
+		select {
+		case <-ctx.Done():
+			return websocket.CloseGoingAway, ""
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(logsWriteTimeout)); err != nil {
+				return websocket.CloseGoingAway, ""
+			}
+		case output, ok := <-logs.Lines:
+			if !ok {
+				done = true
+				break
+			}
+			if err := send(logsMessage{Output: output}); err != nil {
+				return websocket.CloseGoingAway, ""
+			}
+		}
+	}
+
+	if logs.Dropped() {
+		return websocket.CloseTryAgainLater, "fell behind the job's output"
+	}
+	job, err := srv.WaitForJob(ctx, runID, jobName)
+	if err != nil {
+		return websocket.CloseGoingAway, ""
+	}
+	_ = send(logsMessage{Status: job.Status})
+	return websocket.CloseNormalClosure, ""
+}
+
+// eventsPingInterval is how often idle event streams get a comment, so
+// proxies keep their connections open
+const eventsPingInterval = 30 * time.Second
+
+// HandleRunEvents streams the events of every run as Server-Sent Events
+func (h *Handler) HandleRunEvents(w http.ResponseWriter, r *http.Request) {
+	h.streamRunEvents(w, r, "")
+}
+
+// HandleRunEventsForRun streams the events of one run as Server-Sent
+// Events, starting with its current state, until it completes
+func (h *Handler) HandleRunEventsForRun(w http.ResponseWriter, r *http.Request) {
+	runID := mux.Vars(r)["id"]
+	if _, err := h.server.GetRun(runID); err != nil {
+		http.Error(w, "Run not found", http.StatusNotFound)
+		return
+	}
+	h.streamRunEvents(w, r, runID)
+}
+
+// streamRunEvents writes run events to the response as they happen
+func (h *Handler) streamRunEvents(w http.ResponseWriter, r *http.Request, runID string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// Subscribe before reading the run, so no change falls between them
+	sub := h.server.SubscribeRunEvents(runID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	send := func(ev server.RunEvent) bool {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			log.Printf("failed to encode event: %v", err)
+			return true
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data); err != nil {
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+
+	if runID != "" {
+		run, err := h.server.GetRun(runID)
+		if err != nil {
+			return
+		}
+		for _, ev := range server.RunSnapshot(run) {
+			if !send(ev) {
+				return
+			}
+		}
+		if run.CompletedAt != nil {
+			return
+		}
+	}
+
+	ping := time.NewTicker(eventsPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ping.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case ev, ok := <-sub.Events:
+			// A subscriber that fell behind is disconnected; EventSource
+			// clients reconnect on their own
+			if !ok || !send(ev) {
+				return
+			}
+			if runID != "" && ev.Type == server.RunEventCompleted {
+				return
+			}
+		}
+	}
+}
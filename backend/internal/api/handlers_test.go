@@ -0,0 +1,52 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gantry/internal/models"
+)
+
+func TestHandleTriggerWorkflow_Labels(t *testing.T) {
+	srv, routes := newTestRoutes(t)
+	if _, err := srv.ParseAndSaveWorkflow([]byte(fmt.Sprintf(etagTestWorkflow, "0")), ""); err != nil {
+		t.Fatalf("Failed to save workflow: %v", err)
+	}
+
+	trigger := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/workflows/Poll/trigger", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := trigger(`{"labels": {"team": "web", "release/channel": "beta"}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var triggered models.WorkflowRun
+	if err := json.NewDecoder(rec.Body).Decode(&triggered); err != nil {
+		t.Fatalf("Failed to decode run: %v", err)
+	}
+	if triggered.Labels["team"] != "web" {
+		t.Errorf("Expected the triggered run to have its labels, got %v", triggered.Labels)
+	}
+	waitForRun(t, srv, triggered.ID, finished)
+
+	rec = requestRun(routes, triggered.ID, nil)
+	var run models.WorkflowRun
+	if err := json.NewDecoder(rec.Body).Decode(&run); err != nil {
+		t.Fatalf("Failed to decode run: %v", err)
+	}
+	if run.Labels["team"] != "web" || run.Labels["release/channel"] != "beta" {
+		t.Errorf("Expected the run's labels to be returned, got %v", run.Labels)
+	}
+
+	if rec := trigger(`{"labels": {"-team": "web"}}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid label, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// openAPISpec is the OpenAPI 3 document describing the API
+//
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// openAPIJSON is openAPISpec converted to JSON, which most client
+// generators and Swagger UI read
+var openAPIJSON = sync.OnceValues(func() ([]byte, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(openAPISpec, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+	return json.Marshal(doc)
+})
+
+// HandleOpenAPI serves the OpenAPI document, as JSON or, at openapi.yaml,
+// as written
+func (h *Handler) HandleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/api/openapi.yaml" {
+		w.Header().Set("Content-Type", "application/yaml")
+		if _, err := w.Write(openAPISpec); err != nil {
+			log.Printf("failed to write response: %v", err)
+		}
+		return
+	}
+
+	spec, err := openAPIJSON()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(spec); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
+
+// swaggerUIPage renders the OpenAPI document with Swagger UI, loaded from
+// a CDN so the server doesn't have to bundle it
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Gantry API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: "/api/openapi.json", dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`
+
+// HandleDocs serves Swagger UI for exploring and trying out the API
+func (h *Handler) HandleDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := w.Write([]byte(swaggerUIPage)); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
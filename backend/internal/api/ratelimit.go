@@ -0,0 +1,49 @@
+package api
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// rateLimit refuses a request with 429 Too Many Requests when its client
+// has used up its share of requests. Signed-in users are limited by name,
+// whichever address they call from, and others by address.
+func (h *Handler) rateLimit(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wait, ok := h.server.AllowRequest(h.client(r))
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "Too many requests, try again later", http.StatusTooManyRequests)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// client names who made a request, for rate limiting
+func (h *Handler) client(r *http.Request) string {
+	if username, ok := r.Context().Value(userKey{}).(string); ok {
+		return "user:" + username
+	}
+
+	if h.server.TrustProxyHeaders() {
+		if ip := r.Header.Get("X-Real-IP"); ip != "" {
+			return "ip:" + strings.TrimSpace(ip)
+		}
+		// The proxy appends the address it was called from, so the last in
+		// the list is the only one the client can't make up
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			addrs := strings.Split(forwarded, ",")
+			return "ip:" + strings.TrimSpace(addrs[len(addrs)-1])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
@@ -3,6 +3,8 @@ package api
 import (
 	"net/http"
 
+	"gantry/internal/auth"
+
 	"github.com/gorilla/mux"
 )
 
@@ -10,20 +12,80 @@ import (
 func SetupRoutes(h *Handler) http.Handler {
 	r := mux.NewRouter()
 
+	// API documentation and the running version
+	r.HandleFunc("/api/openapi.json", h.HandleOpenAPI).Methods("GET")
+	r.HandleFunc("/api/openapi.yaml", h.HandleOpenAPI).Methods("GET")
+	r.HandleFunc("/docs", h.HandleDocs).Methods("GET")
+	r.HandleFunc("/api/version", h.require(auth.PermViewRuns, h.HandleVersion)).Methods("GET")
+
+	// Auth routes
+	r.HandleFunc("/api/auth/register", h.HandleRegister).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/auth/login", h.HandleLogin).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/auth/refresh", h.HandleRefresh).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/auth/me", h.HandleGetCurrentUser).Methods("GET")
+
 	// Workflow routes
-	r.HandleFunc("/api/workflows", h.HandleUploadWorkflow).Methods("POST", "OPTIONS")
-	r.HandleFunc("/api/workflows", h.HandleListWorkflows).Methods("GET")
-	r.HandleFunc("/api/workflows/{name}", h.HandleDeleteWorkflow).Methods("DELETE", "OPTIONS")
-	r.HandleFunc("/api/workflows/{name}/trigger", h.HandleTriggerWorkflow).Methods("POST", "OPTIONS")
-	r.HandleFunc("/api/workflows/{name}/stats", h.HandleGetWorkflowStats).Methods("GET")
-	r.HandleFunc("/api/workflows/{name}/runs", h.HandleGetWorkflowRuns).Methods("GET")
+	r.HandleFunc("/api/workflows", h.require(auth.PermEditWorkflows, h.rateLimit(h.HandleUploadWorkflow))).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/workflows", h.require(auth.PermViewRuns, h.HandleListWorkflows)).Methods("GET")
+	r.HandleFunc("/api/workflows/validate", h.require(auth.PermViewRuns, h.HandleValidateWorkflow)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/workflows/bulk", h.require(auth.PermEditWorkflows, h.rateLimit(h.HandleBulkUploadWorkflows))).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/workflows/import", h.require(auth.PermEditWorkflows, h.rateLimit(h.HandleImportWorkflow))).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/workflows/{name}", h.require(auth.PermEditWorkflows, h.rateLimit(h.HandleUpdateWorkflow))).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/api/workflows/{name}", h.require(auth.PermEditWorkflows, h.HandleDeleteWorkflow)).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/api/workflows/{name}/trigger", h.require(auth.PermTriggerRuns, h.rateLimit(h.HandleTriggerWorkflow))).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/workflows/{name}/rename", h.require(auth.PermEditWorkflows, h.HandleRenameWorkflow)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/workflows/{name}/yaml", h.require(auth.PermViewRuns, h.HandleGetWorkflowYAML)).Methods("GET")
+	r.HandleFunc("/api/workflows/{name}/versions", h.require(auth.PermViewRuns, h.HandleListWorkflowVersions)).Methods("GET")
+	r.HandleFunc("/api/workflows/{name}/stats", h.require(auth.PermViewRuns, h.HandleGetWorkflowStats)).Methods("GET")
+	r.HandleFunc("/api/workflows/{name}/runs", h.require(auth.PermViewRuns, h.HandleGetWorkflowRuns)).Methods("GET")
+	r.HandleFunc("/api/workflows/{name}/images", h.require(auth.PermViewRuns, h.HandleGetWorkflowImages)).Methods("GET")
+
+	// Event routes
+	r.HandleFunc("/api/events", h.require(auth.PermTriggerRuns, h.rateLimit(h.HandleEvent))).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/events", h.require(auth.PermViewRuns, h.HandleRunEvents)).Methods("GET")
+
+	// Webhook routes; deliveries are signed rather than signed in
+	r.HandleFunc("/api/webhooks/github", h.rateLimit(h.HandleGitHubWebhook)).Methods("POST")
+	r.HandleFunc("/api/webhooks/gitlab", h.rateLimit(h.HandleGitLabWebhook)).Methods("POST")
+	r.HandleFunc("/api/webhooks/workflows/{id}", h.rateLimit(h.HandleWorkflowWebhook)).Methods("POST")
+
+	// Outgoing webhook routes
+	r.HandleFunc("/api/hooks", h.require(auth.PermEditWorkflows, h.HandleListHooks)).Methods("GET")
+	r.HandleFunc("/api/hooks", h.require(auth.PermEditWorkflows, h.HandleCreateHook)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/hooks/failures", h.require(auth.PermEditWorkflows, h.HandleListHookFailures)).Methods("GET")
+	r.HandleFunc("/api/hooks/{id}", h.require(auth.PermEditWorkflows, h.HandleDeleteHook)).Methods("DELETE", "OPTIONS")
 
 	// Run routes
-	r.HandleFunc("/api/runs", h.HandleListRuns).Methods("GET")
-	r.HandleFunc("/api/runs/{id}", h.HandleGetRun).Methods("GET")
+	r.HandleFunc("/api/runs", h.require(auth.PermViewRuns, h.HandleListRuns)).Methods("GET")
+	r.HandleFunc("/api/runs", h.require(auth.PermManageRuns, h.HandleDeleteRuns)).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/api/runs/{id}", h.require(auth.PermViewRuns, h.HandleGetRun)).Methods("GET")
+	r.HandleFunc("/api/runs/{id}", h.require(auth.PermManageRuns, h.HandleDeleteRun)).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/api/runs/{id}/cancel", h.require(auth.PermManageRuns, h.HandleCancelRun)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/runs/{id}/events", h.require(auth.PermViewRuns, h.HandleRunEventsForRun)).Methods("GET")
+	r.HandleFunc("/api/runs/{id}/rerun", h.require(auth.PermTriggerRuns, h.rateLimit(h.HandleRerunRun))).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/runs/{id}/logs/download", h.require(auth.PermViewLogs, h.HandleDownloadLogs)).Methods("GET")
+	r.HandleFunc("/api/runs/{id}/artifacts", h.require(auth.PermViewRuns, h.HandleListArtifacts)).Methods("GET")
+	r.HandleFunc("/api/runs/{id}/artifacts/{name}", h.require(auth.PermViewLogs, h.HandleDownloadArtifact)).Methods("GET")
+	// Job names of called workflows contain slashes
+	r.HandleFunc("/api/runs/{id}/jobs/{job:.+}/approve", h.require(auth.PermManageRuns, h.HandleApproveJob)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/runs/{id}/jobs/{job:.+}/reject", h.require(auth.PermManageRuns, h.HandleRejectJob)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/runs/{id}/jobs/{job:.+}/logs", h.require(auth.PermViewLogs, h.HandleGetJobLogs)).Methods("GET")
+	r.HandleFunc("/api/runs/{id}/jobs/{job:.+}/logs/ws", h.require(auth.PermViewLogs, h.HandleJobLogsWS)).Methods("GET")
+
+	// GraphQL routes; subscriptions upgrade GET requests to WebSockets
+	r.HandleFunc("/api/graphql", h.require(auth.PermViewRuns, h.HandleGraphQL)).Methods("GET", "POST", "OPTIONS")
+	r.HandleFunc("/api/graphql/schema", h.require(auth.PermViewRuns, h.HandleGraphQLSchema)).Methods("GET")
+
+	// Admin routes
+	r.HandleFunc("/api/users", h.require(auth.PermManageUsers, h.HandleListUsers)).Methods("GET")
+	r.HandleFunc("/api/users/{username}/role", h.require(auth.PermManageUsers, h.HandleSetUserRole)).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/api/audit", h.require(auth.PermManageUsers, h.HandleListAuditEntries)).Methods("GET")
+	r.HandleFunc("/api/secrets", h.require(auth.PermManageSecrets, h.HandleListSecrets)).Methods("GET")
+	r.HandleFunc("/api/secrets/{name}", h.require(auth.PermManageSecrets, h.HandleSetSecret)).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/api/secrets/{name}", h.require(auth.PermManageSecrets, h.HandleDeleteSecret)).Methods("DELETE", "OPTIONS")
 
 	// Apply middleware
-	return CORSMiddleware(r)
+	return CORSMiddleware(CompressMiddleware(h.AuthMiddleware(r)))
 }
 
 // CORSMiddleware handles CORS
@@ -31,7 +93,8 @@ func CORSMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-None-Match")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Total-Count, Retry-After, ETag")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -0,0 +1,25 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"gantry/internal/version"
+)
+
+// HandleVersion describes the running build and the backends it uses, so
+// operators can tell what is deployed
+func (h *Handler) HandleVersion(w http.ResponseWriter, r *http.Request) {
+	executorType, storageType := h.server.Backends()
+	resp := struct {
+		version.Info
+		Executor string `json:"executor"`
+		Storage  string `json:"storage"`
+	}{version.Get(), executorType, storageType}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
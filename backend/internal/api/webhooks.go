@@ -0,0 +1,241 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"gantry/internal/models"
+	"gantry/internal/server"
+	"gantry/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// HandleGitHubWebhook starts the workflows a GitHub webhook delivery
+// triggers. Deliveries are signed with the webhook's secret rather than
+// signed in, and those that trigger nothing, such as pings, are
+// acknowledged with the reason.
+func (h *Handler) HandleGitHubWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.server.VerifyGitHubSignature(body, r.Header.Get("X-Hub-Signature-256")); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, server.ErrWebhookDisabled):
+			status = http.StatusNotFound
+		case errors.Is(err, server.ErrInvalidSignature):
+			status = http.StatusUnauthorized
+		}
+		http.Error(w, fmt.Sprintf("Failed to verify webhook: %v", err), status)
+		return
+	}
+
+	ev, err := server.ParseGitHubEvent(r.Header.Get("X-GitHub-Event"), body)
+	if err != nil {
+		if errors.Is(err, server.ErrIgnoredEvent) {
+			writeIgnored(w, err)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Invalid webhook: %v", err), http.StatusBadRequest)
+		return
+	}
+	h.dispatchWebhook(w, r, ev)
+}
+
+// HandleGitLabWebhook starts the workflows a GitLab webhook delivery
+// triggers, checking its secret token rather than a signed-in user
+func (h *Handler) HandleGitLabWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.server.VerifyGitLabToken(r.Header.Get("X-Gitlab-Token")); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, server.ErrWebhookDisabled):
+			status = http.StatusNotFound
+		case errors.Is(err, server.ErrInvalidSignature):
+			status = http.StatusUnauthorized
+		}
+		http.Error(w, fmt.Sprintf("Failed to verify webhook: %v", err), status)
+		return
+	}
+
+	ev, err := server.ParseGitLabEvent(r.Header.Get("X-Gitlab-Event"), body)
+	if err != nil {
+		if errors.Is(err, server.ErrIgnoredEvent) {
+			writeIgnored(w, err)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Invalid webhook: %v", err), http.StatusBadRequest)
+		return
+	}
+	h.dispatchWebhook(w, r, ev)
+}
+
+// HandleWorkflowWebhook starts a run of the workflow a delivery to its own
+// webhook is for, signed with the secret its webhook trigger names. Jobs
+// read the delivered JSON as gantry.payload.
+func (h *Handler) HandleWorkflowWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	run, err := h.server.TriggerWebhook(r.Context(), mux.Vars(r)["id"], body, r.Header.Get("X-Gantry-Signature-256"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, server.ErrWebhookDisabled):
+			status = http.StatusNotFound
+		case errors.Is(err, server.ErrInvalidSignature):
+			status = http.StatusUnauthorized
+		case errors.Is(err, server.ErrInvalidEvent), errors.Is(err, server.ErrInvalidCall):
+			status = http.StatusBadRequest
+		}
+		http.Error(w, fmt.Sprintf("Failed to trigger workflow: %v", err), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(runSummaries([]*models.WorkflowRun{run})); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// writeIgnored acknowledges a webhook delivery that triggers nothing
+func writeIgnored(w http.ResponseWriter, reason error) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{"status": "ignored", "reason": reason.Error()}); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// dispatchWebhook starts the workflows a webhook's event triggers and
+// responds with their runs
+func (h *Handler) dispatchWebhook(w http.ResponseWriter, r *http.Request, ev server.Event) {
+	runs, err := h.server.DispatchEvent(r.Context(), ev)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, server.ErrInvalidEvent) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, fmt.Sprintf("Failed to dispatch event: %v", err), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(runSummaries(runs)); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// HandleListHooks lists the outgoing webhooks, without their secrets
+func (h *Handler) HandleListHooks(w http.ResponseWriter, r *http.Request) {
+	hooks, err := h.server.ListHooks()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list hooks: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(hooks); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// HandleCreateHook registers an outgoing webhook, responding with it and
+// its secret
+func (h *Handler) HandleCreateHook(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		URL      string   `json:"url"`
+		Workflow string   `json:"workflow"`
+		Events   []string `json:"events"`
+		Secret   string   `json:"secret"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	username, _ := r.Context().Value(userKey{}).(string)
+	hook, err := h.server.CreateHook(server.HookOptions{
+		URL:       req.URL,
+		Workflow:  req.Workflow,
+		Events:    req.Events,
+		Secret:    req.Secret,
+		CreatedBy: username,
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, server.ErrInvalidHook):
+			status = http.StatusBadRequest
+		case errors.Is(err, server.ErrWorkflowNotFound):
+			status = http.StatusNotFound
+		}
+		http.Error(w, fmt.Sprintf("Failed to create hook: %v", err), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(hook); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// HandleDeleteHook removes an outgoing webhook
+func (h *Handler) HandleDeleteHook(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if err := h.server.DeleteHook(id); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrHookNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, fmt.Sprintf("Failed to delete hook: %v", err), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{
+		"message": "Hook deleted",
+		"id":      id,
+	}); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// HandleListHookFailures lists the deliveries to outgoing webhooks that
+// failed every attempt, newest first
+func (h *Handler) HandleListHookFailures(w http.ResponseWriter, r *http.Request) {
+	opts, err := listOptions(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid query: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	failures, total, err := h.server.ListHookFailures(opts)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list hook failures: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if err := json.NewEncoder(w).Encode(failures); err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
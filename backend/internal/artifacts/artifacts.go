@@ -0,0 +1,160 @@
+// Package artifacts provides the server-side store for files uploaded by jobs
+package artifacts
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when a run has no artifact with the given name
+var ErrNotFound = errors.New("artifact not found")
+
+// archiveExt is appended to the file each artifact is stored in
+const archiveExt = ".tar"
+
+// namePattern restricts artifact names (and run IDs) to safe file names
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Artifact describes a stored artifact
+type Artifact struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Store keeps artifacts as tar archives, scoped to the run that uploaded them
+type Store interface {
+	Save(runID, name string, archive io.Reader) (*Artifact, error)
+	Open(runID, name string) (io.ReadCloser, error)
+	List(runID string) ([]Artifact, error)
+	Delete(runID string) error
+}
+
+// ValidateName checks that name can be used as an artifact name
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid artifact name '%s' (use letters, digits, '.', '_' and '-')", name)
+	}
+	return nil
+}
+
+// FileStore implements a store backed by a directory, one subdirectory per run
+type FileStore struct {
+	root string
+}
+
+// NewFileStore creates a store rooted at dir, creating it if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create artifacts directory: %w", err)
+	}
+	return &FileStore{root: dir}, nil
+}
+
+// Save stores an archive under name, replacing any artifact of the same name
+// uploaded earlier in the run
+func (s *FileStore) Save(runID, name string, archive io.Reader) (*Artifact, error) {
+	path, err := s.path(runID, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create run directory: %w", err)
+	}
+
+	// Write to a temporary file first so readers never see a partial archive
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+name+"-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create artifact: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	size, err := io.Copy(tmp, archive)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write artifact: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, fmt.Errorf("failed to save artifact: %w", err)
+	}
+
+	return &Artifact{Name: name, Size: size, CreatedAt: time.Now()}, nil
+}
+
+// Open returns the archive of an artifact
+func (s *FileStore) Open(runID, name string) (io.ReadCloser, error) {
+	path, err := s.path(runID, name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: '%s'", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open artifact: %w", err)
+	}
+	return f, nil
+}
+
+// List returns the artifacts of a run, sorted by name
+func (s *FileStore) List(runID string) ([]Artifact, error) {
+	if err := ValidateName(runID); err != nil {
+		return nil, fmt.Errorf("invalid run ID '%s'", runID)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(s.root, runID))
+	if errors.Is(err, fs.ErrNotExist) {
+		return []Artifact{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+
+	artifacts := make([]Artifact, 0, len(entries))
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), archiveExt)
+		if !ok || entry.IsDir() || ValidateName(name) != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat artifact: %w", err)
+		}
+		artifacts = append(artifacts, Artifact{Name: name, Size: info.Size(), CreatedAt: info.ModTime()})
+	}
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].Name < artifacts[j].Name })
+	return artifacts, nil
+}
+
+// Delete removes every artifact of a run
+func (s *FileStore) Delete(runID string) error {
+	if err := ValidateName(runID); err != nil {
+		return fmt.Errorf("invalid run ID '%s'", runID)
+	}
+	if err := os.RemoveAll(filepath.Join(s.root, runID)); err != nil {
+		return fmt.Errorf("failed to delete artifacts: %w", err)
+	}
+	return nil
+}
+
+// path returns the file an artifact is stored in
+func (s *FileStore) path(runID, name string) (string, error) {
+	if err := ValidateName(runID); err != nil {
+		return "", fmt.Errorf("invalid run ID '%s'", runID)
+	}
+	if err := ValidateName(name); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, runID, name+archiveExt), nil
+}
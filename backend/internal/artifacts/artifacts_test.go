@@ -0,0 +1,116 @@
+package artifacts
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestFileStore_SaveAndOpen(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	artifact, err := store.Save("run-1", "binary", strings.NewReader("archive"))
+	if err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	if artifact.Name != "binary" || artifact.Size != int64(len("archive")) {
+		t.Errorf("Unexpected artifact: %+v", artifact)
+	}
+
+	// Saving again replaces the artifact
+	if _, err := store.Save("run-1", "binary", strings.NewReader("rebuilt")); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	reader, err := store.Open("run-1", "binary")
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Failed to read artifact: %v", err)
+	}
+	if string(data) != "rebuilt" {
+		t.Errorf("Expected 'rebuilt', got '%s'", data)
+	}
+
+	if _, err := store.Open("run-2", "binary"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected artifacts to be scoped to their run, got %v", err)
+	}
+}
+
+func TestFileStore_List(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	list, err := store.List("run-1")
+	if err != nil || len(list) != 0 {
+		t.Fatalf("Expected no artifacts, got %v (%v)", list, err)
+	}
+
+	for _, name := range []string{"logs", "coverage.out"} {
+		if _, err := store.Save("run-1", name, strings.NewReader(name)); err != nil {
+			t.Fatalf("Save returned error: %v", err)
+		}
+	}
+
+	list, err = store.List("run-1")
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if len(list) != 2 || list[0].Name != "coverage.out" || list[1].Name != "logs" {
+		t.Errorf("Expected [coverage.out logs], got %+v", list)
+	}
+}
+
+func TestFileStore_Delete(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	for _, runID := range []string{"run-1", "run-2"} {
+		if _, err := store.Save(runID, "binary", strings.NewReader("archive")); err != nil {
+			t.Fatalf("Save returned error: %v", err)
+		}
+	}
+	if err := store.Delete("run-1"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+
+	if list, err := store.List("run-1"); err != nil || len(list) != 0 {
+		t.Errorf("Expected the run's artifacts to be deleted, got %v (%v)", list, err)
+	}
+	if list, err := store.List("run-2"); err != nil || len(list) != 1 {
+		t.Errorf("Expected other runs' artifacts to be kept, got %v (%v)", list, err)
+	}
+
+	// Deleting a run without artifacts does nothing
+	if err := store.Delete("run-3"); err != nil {
+		t.Errorf("Delete returned error: %v", err)
+	}
+}
+
+func TestFileStore_RejectsInvalidNames(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	for _, name := range []string{"", "../escape", "a/b", ".hidden"} {
+		if _, err := store.Save("run-1", name, strings.NewReader("x")); err == nil {
+			t.Errorf("Expected error for name '%s', got nil", name)
+		}
+	}
+	if _, err := store.List("../run"); err == nil {
+		t.Error("Expected error for invalid run ID, got nil")
+	}
+}
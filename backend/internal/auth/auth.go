@@ -0,0 +1,156 @@
+// Package auth provides password hashing and the signed tokens that keep
+// users signed in to the API
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Errors of verifying tokens
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token expired")
+)
+
+// Kinds of tokens
+const (
+	AccessToken  = "access"  // authenticates API requests
+	RefreshToken = "refresh" // is exchanged for a new pair of tokens
+)
+
+// MinSecretLength is the shortest secret tokens may be signed with
+const MinSecretLength = 32
+
+// HashPassword hashes a password with bcrypt. Bcrypt ignores what comes
+// after the first 72 bytes, so longer passwords are refused.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password is the one hash was made from
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// TokenPair is what signing in gives: an access token for requests, and a
+// longer-lived refresh token to get a new pair before it expires
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"` // seconds until the access token expires
+}
+
+// Claims are what a token says: whose it is, what kind, and when it was
+// issued and expires, in seconds since the epoch
+type Claims struct {
+	Subject   string `json:"sub"`
+	Type      string `json:"typ"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Tokens issues and verifies JWTs signed with HMAC-SHA256
+type Tokens struct {
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	now        func() time.Time
+}
+
+// jwtHeader is the header of every token Tokens issues
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// NewTokens creates an issuer of tokens signed with secret, whose access
+// and refresh tokens last accessTTL and refreshTTL
+func NewTokens(secret string, accessTTL, refreshTTL time.Duration) (*Tokens, error) {
+	if len(secret) < MinSecretLength {
+		return nil, fmt.Errorf("secret must be at least %d bytes", MinSecretLength)
+	}
+	if accessTTL <= 0 || refreshTTL <= 0 {
+		return nil, fmt.Errorf("token lifetimes must be positive")
+	}
+	return &Tokens{secret: []byte(secret), accessTTL: accessTTL, refreshTTL: refreshTTL, now: time.Now}, nil
+}
+
+// Issue signs a new pair of tokens for a user
+func (t *Tokens) Issue(username string) (TokenPair, error) {
+	access, err := t.sign(username, AccessToken, t.accessTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	refresh, err := t.sign(username, RefreshToken, t.refreshTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(t.accessTTL.Seconds()),
+	}, nil
+}
+
+// sign creates a token of a kind for a user, lasting ttl
+func (t *Tokens) sign(username, typ string, ttl time.Duration) (string, error) {
+	now := t.now()
+	payload, err := json.Marshal(Claims{
+		Subject:   username,
+		Type:      typ,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode token: %w", err)
+	}
+	unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + t.signature(unsigned), nil
+}
+
+// signature returns the encoded HMAC of a token's header and payload
+func (t *Tokens) signature(unsigned string) string {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks that a token was issued by t, is of kind typ and hasn't
+// expired, and returns its claims
+func (t *Tokens) Verify(token, typ string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return Claims{}, ErrInvalidToken
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(t.signature(parts[0]+"."+parts[1]))) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Subject == "" {
+		return Claims{}, ErrInvalidToken
+	}
+	if claims.Type != typ {
+		return Claims{}, fmt.Errorf("%w: not of type %s", ErrInvalidToken, typ)
+	}
+	if t.now().Unix() >= claims.ExpiresAt {
+		return Claims{}, ErrExpiredToken
+	}
+	return claims, nil
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("HashPassword returned error: %v", err)
+	}
+	if hash == "correct horse" || !CheckPassword(hash, "correct horse") {
+		t.Error("Expected the hash to match its password")
+	}
+	if CheckPassword(hash, "wrong horse") {
+		t.Error("Expected the hash not to match another password")
+	}
+}
+
+func TestNewTokens_RejectsShortSecret(t *testing.T) {
+	if _, err := NewTokens("short", time.Minute, time.Hour); err == nil {
+		t.Error("Expected error for a short secret, got nil")
+	}
+}
+
+func TestTokens_IssueAndVerify(t *testing.T) {
+	tokens, err := NewTokens(testSecret, 15*time.Minute, time.Hour)
+	if err != nil {
+		t.Fatalf("NewTokens returned error: %v", err)
+	}
+
+	pair, err := tokens.Issue("alice")
+	if err != nil {
+		t.Fatalf("Issue returned error: %v", err)
+	}
+	if pair.TokenType != "Bearer" || pair.ExpiresIn != 900 {
+		t.Errorf("Unexpected token pair: %+v", pair)
+	}
+
+	claims, err := tokens.Verify(pair.AccessToken, AccessToken)
+	if err != nil || claims.Subject != "alice" {
+		t.Errorf("Expected the access token to verify as alice's, got %+v (%v)", claims, err)
+	}
+	if _, err := tokens.Verify(pair.RefreshToken, RefreshToken); err != nil {
+		t.Errorf("Expected the refresh token to verify, got %v", err)
+	}
+
+	// Tokens only verify as their own kind
+	if _, err := tokens.Verify(pair.RefreshToken, AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected a refresh token not to pass as an access token, got %v", err)
+	}
+}
+
+func TestTokens_Verify_Rejects(t *testing.T) {
+	tokens, _ := NewTokens(testSecret, time.Minute, time.Hour)
+	pair, _ := tokens.Issue("alice")
+	other, _ := NewTokens(strings.Repeat("x", MinSecretLength), time.Minute, time.Hour)
+
+	parts := strings.Split(pair.AccessToken, ".")
+	forged, _ := other.Issue("mallory")
+	tampered := parts[0] + "." + strings.Split(forged.AccessToken, ".")[1] + "." + parts[2]
+
+	for name, token := range map[string]string{
+		"empty":          "",
+		"malformed":      "not.a-token",
+		"other secret":   forged.AccessToken,
+		"tampered claim": tampered,
+	} {
+		if _, err := tokens.Verify(token, AccessToken); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: expected ErrInvalidToken, got %v", name, err)
+		}
+	}
+}
+
+func TestTokens_Verify_Expired(t *testing.T) {
+	tokens, _ := NewTokens(testSecret, time.Minute, time.Hour)
+	issued := time.Now()
+	tokens.now = func() time.Time { return issued }
+	pair, _ := tokens.Issue("alice")
+
+	tokens.now = func() time.Time { return issued.Add(2 * time.Minute) }
+	if _, err := tokens.Verify(pair.AccessToken, AccessToken); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("Expected ErrExpiredToken, got %v", err)
+	}
+	if _, err := tokens.Verify(pair.RefreshToken, RefreshToken); err != nil {
+		t.Errorf("Expected the refresh token to outlive the access token, got %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package auth
+
+// Roles of users, from least to most trusted
+const (
+	RoleViewer     = "viewer"     // sees workflows, runs and their logs
+	RoleMaintainer = "maintainer" // also changes workflows and starts and stops runs
+	RoleAdmin      = "admin"      // also manages users and secrets
+)
+
+// Permissions of roles
+const (
+	PermViewRuns      = "runs:view"      // workflows, runs and their status
+	PermViewLogs      = "logs:view"      // job output and artifacts
+	PermEditWorkflows = "workflows:edit" // uploading, renaming and deleting workflows; managing outgoing webhooks
+	PermTriggerRuns   = "runs:trigger"   // triggering, re-running and dispatching events
+	PermManageRuns    = "runs:manage"    // cancelling, deleting and reviewing runs
+	PermManageSecrets = "secrets:manage" // listing, setting and deleting secrets
+	PermManageUsers   = "users:manage"   // listing users, setting roles and reading the audit trail
+)
+
+// rolePermissions are the permissions of each role
+var rolePermissions = map[string][]string{
+	RoleViewer:     {PermViewRuns, PermViewLogs},
+	RoleMaintainer: {PermViewRuns, PermViewLogs, PermEditWorkflows, PermTriggerRuns, PermManageRuns},
+	RoleAdmin: {PermViewRuns, PermViewLogs, PermEditWorkflows, PermTriggerRuns, PermManageRuns,
+		PermManageSecrets, PermManageUsers},
+}
+
+// ValidRole reports whether role is one of the roles
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// Allows reports whether role has a permission
+func Allows(role, permission string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
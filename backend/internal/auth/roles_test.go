@@ -0,0 +1,39 @@
+package auth
+
+import "testing"
+
+func TestAllows(t *testing.T) {
+	tests := []struct {
+		role       string
+		permission string
+		want       bool
+	}{
+		{RoleViewer, PermViewRuns, true},
+		{RoleViewer, PermViewLogs, true},
+		{RoleViewer, PermTriggerRuns, false},
+		{RoleMaintainer, PermEditWorkflows, true},
+		{RoleMaintainer, PermManageRuns, true},
+		{RoleMaintainer, PermManageSecrets, false},
+		{RoleAdmin, PermManageSecrets, true},
+		{RoleAdmin, PermManageUsers, true},
+		{"", PermViewRuns, false},
+		{"owner", PermViewRuns, false},
+	}
+
+	for _, tt := range tests {
+		if got := Allows(tt.role, tt.permission); got != tt.want {
+			t.Errorf("Allows(%q, %q) = %v, want %v", tt.role, tt.permission, got, tt.want)
+		}
+	}
+}
+
+func TestValidRole(t *testing.T) {
+	for _, role := range []string{RoleViewer, RoleMaintainer, RoleAdmin} {
+		if !ValidRole(role) {
+			t.Errorf("Expected %q to be a valid role", role)
+		}
+	}
+	if ValidRole("owner") {
+		t.Error("Expected \"owner\" not to be a valid role")
+	}
+}
@@ -0,0 +1,184 @@
+// Package cache provides the server-side store for dependency caches that
+// cache steps restore and save between runs
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when no cache entry matches the requested keys
+var ErrNotFound = errors.New("cache entry not found")
+
+// MaxKeyLength caps the length of a cache key
+const MaxKeyLength = 512
+
+// File extensions of an entry's archive and of the file holding its key
+const (
+	archiveExt = ".tar"
+	keyExt     = ".key"
+)
+
+// Entry describes a stored cache entry
+type Entry struct {
+	Key       string    `json:"key"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Store keeps cache entries as tar archives, by key
+type Store interface {
+	Save(key string, archive io.Reader) (*Entry, error)
+
+	// Restore opens the entry stored under key or, failing that, the most
+	// recently saved entry whose key starts with one of restoreKeys, tried
+	// in order
+	Restore(key string, restoreKeys []string) (*Entry, io.ReadCloser, error)
+}
+
+// ValidateKey checks that key can be used as a cache key
+func ValidateKey(key string) error {
+	if strings.TrimSpace(key) == "" {
+		return fmt.Errorf("cache key must not be empty")
+	}
+	if len(key) > MaxKeyLength {
+		return fmt.Errorf("cache key is longer than %d characters", MaxKeyLength)
+	}
+	return nil
+}
+
+// FileStore implements a store backed by a directory. Entries are stored
+// under the hash of their key, next to a file recording the key itself.
+type FileStore struct {
+	root string
+	mu   sync.Mutex // serializes saves of an entry's key and archive
+}
+
+// NewFileStore creates a store rooted at dir, creating it if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &FileStore{root: dir}, nil
+}
+
+// Save stores an archive under key, replacing any entry with the same key
+func (s *FileStore) Save(key string, archive io.Reader) (*Entry, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+	base := s.base(key)
+
+	// Write to a temporary file first so restores never see a partial archive
+	tmp, err := os.CreateTemp(s.root, ".entry-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache entry: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	size, err := io.Copy(tmp, archive)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write cache entry: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.WriteFile(base+keyExt, []byte(key), 0o644); err != nil {
+		return nil, fmt.Errorf("failed to save cache key: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), base+archiveExt); err != nil {
+		return nil, fmt.Errorf("failed to save cache entry: %w", err)
+	}
+
+	return &Entry{Key: key, Size: size, CreatedAt: time.Now()}, nil
+}
+
+// Restore opens the entry matching key or one of restoreKeys
+func (s *FileStore) Restore(key string, restoreKeys []string) (*Entry, io.ReadCloser, error) {
+	entry, reader, err := s.open(key)
+	if err == nil || !errors.Is(err, ErrNotFound) {
+		return entry, reader, err
+	}
+
+	if len(restoreKeys) == 0 {
+		return nil, nil, ErrNotFound
+	}
+	entries, err := s.entries()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, prefix := range restoreKeys {
+		var newest *Entry
+		for i, e := range entries {
+			if strings.HasPrefix(e.Key, prefix) && (newest == nil || e.CreatedAt.After(newest.CreatedAt)) {
+				newest = &entries[i]
+			}
+		}
+		if newest != nil {
+			return s.open(newest.Key)
+		}
+	}
+	return nil, nil, ErrNotFound
+}
+
+// open opens the entry stored under exactly key
+func (s *FileStore) open(key string) (*Entry, io.ReadCloser, error) {
+	f, err := os.Open(s.base(key) + archiveExt)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open cache entry: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, fmt.Errorf("failed to stat cache entry: %w", err)
+	}
+	return &Entry{Key: key, Size: info.Size(), CreatedAt: info.ModTime()}, f, nil
+}
+
+// entries lists every stored entry
+func (s *FileStore) entries() ([]Entry, error) {
+	files, err := os.ReadDir(s.root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cache entries: %w", err)
+	}
+
+	var entries []Entry
+	for _, file := range files {
+		base, ok := strings.CutSuffix(file.Name(), archiveExt)
+		if !ok || file.IsDir() {
+			continue
+		}
+		key, err := os.ReadFile(filepath.Join(s.root, base+keyExt))
+		if err != nil {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, Entry{Key: string(key), Size: info.Size(), CreatedAt: info.ModTime()})
+	}
+	return entries, nil
+}
+
+// base returns the path, without extension, of the files of an entry
+func (s *FileStore) base(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.root, hex.EncodeToString(sum[:]))
+}
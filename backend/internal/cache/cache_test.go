@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func restoreString(t *testing.T, store Store, key string, restoreKeys ...string) (string, string) {
+	t.Helper()
+
+	entry, reader, err := store.Restore(key, restoreKeys)
+	if err != nil {
+		t.Fatalf("Restore returned error: %v", err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Failed to read cache entry: %v", err)
+	}
+	return entry.Key, string(data)
+}
+
+func TestFileStore_RestoresExactKey(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	if _, err := store.Save("npm-abc", strings.NewReader("modules")); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	key, data := restoreString(t, store, "npm-abc", "npm-")
+	if key != "npm-abc" || data != "modules" {
+		t.Errorf("Expected npm-abc/modules, got %s/%s", key, data)
+	}
+
+	if _, _, err := store.Restore("npm-def", nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound without restore keys, got %v", err)
+	}
+}
+
+func TestFileStore_RestoresNewestPrefixMatch(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	for _, key := range []string{"go-linux-old", "go-linux-new", "go-darwin"} {
+		if _, err := store.Save(key, strings.NewReader(key)); err != nil {
+			t.Fatalf("Save returned error: %v", err)
+		}
+	}
+	// Make the timestamps deterministic
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(store.base("go-linux-old")+archiveExt, old, old); err != nil {
+		t.Fatalf("Failed to set entry time: %v", err)
+	}
+
+	key, _ := restoreString(t, store, "go-linux-123", "go-linux-", "go-")
+	if key != "go-linux-new" {
+		t.Errorf("Expected newest entry for the first matching prefix, got %s", key)
+	}
+
+	if _, _, err := store.Restore("node-1", []string{"node-"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestValidateKey(t *testing.T) {
+	if err := ValidateKey("npm-linux-abc123"); err != nil {
+		t.Errorf("Expected valid key, got %v", err)
+	}
+	if err := ValidateKey(" "); err == nil {
+		t.Error("Expected error for empty key, got nil")
+	}
+	if err := ValidateKey(strings.Repeat("k", MaxKeyLength+1)); err == nil {
+		t.Error("Expected error for long key, got nil")
+	}
+}
@@ -0,0 +1,117 @@
+// Package capture keeps the output of jobs within a size limit, trimming
+// the middle of output that outgrows it
+package capture
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Buffer keeps what is written to it, up to a limit in bytes. Once more is
+// written, only the start and end of the output are kept, each up to half
+// the limit, with a marker between them saying how much was left out. A
+// zero limit keeps everything. A Buffer isn't safe for concurrent writes.
+type Buffer struct {
+	limit int64
+	size  int64 // bytes written in total
+
+	head strings.Builder
+	tail []byte // a ring of the last bytes written once head is full
+	next int    // where the ring's next byte goes, and so where it starts
+}
+
+// NewBuffer creates a buffer keeping up to limit bytes
+func NewBuffer(limit int64) *Buffer {
+	return &Buffer{limit: limit}
+}
+
+// Write records p, dropping what falls between the first and last halves of
+// the limit
+func (b *Buffer) Write(p []byte) (int, error) {
+	n := len(p)
+	b.size += int64(n)
+	if b.limit <= 0 {
+		b.head.Write(p)
+		return n, nil
+	}
+
+	headLimit := int(b.limit / 2)
+	if room := headLimit - b.head.Len(); room > 0 {
+		room = min(room, len(p))
+		b.head.Write(p[:room])
+		p = p[room:]
+	}
+
+	tailLimit := int(b.limit) - headLimit
+	if len(p) >= tailLimit {
+		b.tail = append(b.tail[:0], p[len(p)-tailLimit:]...)
+		b.next = 0
+		return n, nil
+	}
+	for len(p) > 0 {
+		if len(b.tail) < tailLimit {
+			take := min(tailLimit-len(b.tail), len(p))
+			b.tail = append(b.tail, p[:take]...)
+			p = p[take:]
+			b.next = len(b.tail) % tailLimit
+			continue
+		}
+		copied := copy(b.tail[b.next:], p)
+		p = p[copied:]
+		b.next = (b.next + copied) % tailLimit
+	}
+	return n, nil
+}
+
+// WriteString records s as Write does
+func (b *Buffer) WriteString(s string) (int, error) {
+	return b.Write([]byte(s))
+}
+
+// Size returns how many bytes were written, including any left out
+func (b *Buffer) Size() int64 {
+	return b.size
+}
+
+// Truncated reports whether output was left out
+func (b *Buffer) Truncated() bool {
+	return int64(b.head.Len()+len(b.tail)) < b.size
+}
+
+// Split reports whether the output kept has outgrown its start, so String
+// has to copy it to join the start and end
+func (b *Buffer) Split() bool {
+	return len(b.tail) > 0
+}
+
+// String returns the output kept. Output that was trimmed is cut at line
+// boundaries where the kept start and end hold one, so no line is shown in
+// part, and a ruling says how many bytes were left out between them.
+func (b *Buffer) String() string {
+	if !b.Split() {
+		return b.head.String()
+	}
+	tail := append(append([]byte(nil), b.tail[b.next:]...), b.tail[:b.next]...)
+	if !b.Truncated() {
+		return b.head.String() + string(tail)
+	}
+
+	head := []byte(b.head.String())
+	if end := bytes.LastIndexByte(head, '\n'); end >= 0 {
+		head = head[:end+1]
+	}
+	if start := bytes.IndexByte(tail, '\n'); start >= 0 && start < len(tail)-1 {
+		tail = tail[start+1:]
+	}
+	omitted := b.size - int64(len(head)+len(tail))
+
+	var out bytes.Buffer
+	out.Write(head)
+	if len(head) > 0 && head[len(head)-1] != '\n' {
+		out.WriteByte('\n')
+	}
+	fmt.Fprintf(&out, "=== Output truncated: %d of %d bytes left out ===\n", omitted, b.size)
+	out.Write(tail)
+	return out.String()
+}
@@ -0,0 +1,86 @@
+package capture
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestBuffer_KeepsOutputWithinLimit(t *testing.T) {
+	b := NewBuffer(100)
+	fmt.Fprint(b, "line 1\nline 2\n")
+
+	if b.Truncated() || b.String() != "line 1\nline 2\n" || b.Size() != 14 {
+		t.Errorf("Expected output within the limit to be kept whole, got %q (%d bytes)", b.String(), b.Size())
+	}
+}
+
+func TestBuffer_TruncatesMiddle(t *testing.T) {
+	b := NewBuffer(40)
+	for i := 1; i <= 100; i++ {
+		fmt.Fprintf(b, "line %d\n", i)
+	}
+
+	got := b.String()
+	if !b.Truncated() {
+		t.Fatal("Expected output to be truncated")
+	}
+	if !strings.HasPrefix(got, "line 1\nline 2\n===") {
+		t.Errorf("Expected the first whole lines to be kept, got %q", got)
+	}
+	if !strings.HasSuffix(got, "===\nline 99\nline 100\n") {
+		t.Errorf("Expected the last whole lines to be kept, got %q", got)
+	}
+	size := b.Size()
+	want := fmt.Sprintf("=== Output truncated: %d of %d bytes left out ===\n", size-int64(len("line 1\nline 2\nline 99\nline 100\n")), size)
+	if !strings.Contains(got, want) {
+		t.Errorf("Expected marker %q, got %q", want, got)
+	}
+}
+
+func TestBuffer_KeepsTailAcrossWrites(t *testing.T) {
+	b := NewBuffer(8)
+	for _, s := range []string{"abcd", "efgh", "ijk", "lmnopq", "rs"} {
+		fmt.Fprint(b, s)
+	}
+
+	// Without newlines the halves are kept as they are
+	want := "abcd\n=== Output truncated: 11 of 19 bytes left out ===\npqrs"
+	if got := b.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestBuffer_LargeWrite(t *testing.T) {
+	b := NewBuffer(10)
+	fmt.Fprint(b, strings.Repeat("x", 1000)+"tail!")
+
+	if got := b.String(); !strings.HasPrefix(got, "xxxxx\n") || !strings.HasSuffix(got, "tail!") {
+		t.Errorf("Expected the start and end of a large write, got %q", got)
+	}
+	if b.Size() != 1005 {
+		t.Errorf("Expected the full size to be counted, got %d", b.Size())
+	}
+}
+
+func TestBuffer_Unlimited(t *testing.T) {
+	b := NewBuffer(0)
+	data := strings.Repeat("output\n", 1000)
+	fmt.Fprint(b, data)
+
+	if b.Truncated() || b.String() != data {
+		t.Errorf("Expected a zero limit to keep everything, got %d bytes", len(b.String()))
+	}
+}
+
+func TestBuffer_Split(t *testing.T) {
+	b := NewBuffer(10)
+	fmt.Fprint(b, "abcde")
+	if b.Split() {
+		t.Error("Expected output within the start not to be split")
+	}
+	fmt.Fprint(b, "f")
+	if !b.Split() || b.Truncated() || b.String() != "abcdef" {
+		t.Errorf("Expected output past the start to be split but whole, got %q", b.String())
+	}
+}
@@ -0,0 +1,138 @@
+// Package environments provides the server-side registry of deployment
+// environments and the protection rules of jobs that target them
+package environments
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Environment holds the protection rules of a deployment environment
+type Environment struct {
+	Name string `yaml:"-" json:"name"`
+
+	// Reviewers may approve jobs that target the environment. When set, such
+	// jobs wait for one of them to approve before they start.
+	Reviewers []string `yaml:"reviewers" json:"reviewers,omitempty"`
+
+	// Branches restricts the branches runs may target the environment from,
+	// as path.Match patterns (e.g. release/*). Empty allows every branch.
+	Branches []string `yaml:"branches" json:"branches,omitempty"`
+}
+
+// Protected reports whether jobs targeting the environment need approval
+func (e Environment) Protected() bool {
+	return len(e.Reviewers) > 0
+}
+
+// AllowsBranch reports whether runs on branch may target the environment
+func (e Environment) AllowsBranch(branch string) bool {
+	if len(e.Branches) == 0 {
+		return true
+	}
+	for _, pattern := range e.Branches {
+		if ok, _ := path.Match(pattern, branch); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// CanApprove reports whether reviewer is one of the environment's reviewers
+func (e Environment) CanApprove(reviewer string) bool {
+	for _, r := range e.Reviewers {
+		if r == reviewer {
+			return true
+		}
+	}
+	return false
+}
+
+// Store provides environments by name
+type Store interface {
+	Get(name string) (Environment, bool)
+	Names() []string
+}
+
+// MemoryStore implements an in-memory environment registry
+type MemoryStore struct {
+	environments map[string]Environment
+	mu           sync.RWMutex
+}
+
+// NewMemoryStore creates an empty environment registry
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		environments: make(map[string]Environment),
+	}
+}
+
+// NewStoreFromFile creates a registry from a YAML file mapping environment
+// names to their rules. An empty path yields an empty registry.
+func NewStoreFromFile(file string) (*MemoryStore, error) {
+	store := NewMemoryStore()
+	if file == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read environments file: %w", err)
+	}
+
+	var defs map[string]Environment
+	if err := yaml.Unmarshal(data, &defs); err != nil {
+		return nil, fmt.Errorf("failed to parse environments file: %w", err)
+	}
+	for name, env := range defs {
+		env.Name = name
+		if err := store.Set(env); err != nil {
+			return nil, err
+		}
+	}
+
+	return store, nil
+}
+
+// Get retrieves an environment by name
+func (s *MemoryStore) Get(name string) (Environment, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	env, exists := s.environments[name]
+	return env, exists
+}
+
+// Names returns the sorted names of all environments
+func (s *MemoryStore) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.environments))
+	for name := range s.environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Set stores an environment, replacing one with the same name
+func (s *MemoryStore) Set(env Environment) error {
+	if env.Name == "" {
+		return fmt.Errorf("environment name must not be empty")
+	}
+	for _, pattern := range env.Branches {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("environment '%s' has an invalid branch pattern '%s'", env.Name, pattern)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.environments[env.Name] = env
+	return nil
+}
@@ -0,0 +1,58 @@
+package environments
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewStoreFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "environments.yaml")
+	content := "production:\n  reviewers: [alice, bob]\n  branches: [main, release/*]\nstaging: {}\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write environments file: %v", err)
+	}
+
+	store, err := NewStoreFromFile(path)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	prod, ok := store.Get("production")
+	if !ok || prod.Name != "production" || !prod.Protected() {
+		t.Fatalf("Expected protected production environment, got %+v (exists=%v)", prod, ok)
+	}
+	if !prod.CanApprove("bob") || prod.CanApprove("mallory") {
+		t.Error("Expected only listed reviewers to approve")
+	}
+	if !prod.AllowsBranch("main") || !prod.AllowsBranch("release/1.2") || prod.AllowsBranch("feature/x") {
+		t.Error("Expected branch patterns to restrict deployments")
+	}
+
+	staging, ok := store.Get("staging")
+	if !ok || staging.Protected() || !staging.AllowsBranch("anything") {
+		t.Errorf("Expected unrestricted staging environment, got %+v (exists=%v)", staging, ok)
+	}
+
+	if names := store.Names(); len(names) != 2 || names[0] != "production" {
+		t.Errorf("Expected sorted names, got %v", names)
+	}
+}
+
+func TestNewStoreFromFile_Invalid(t *testing.T) {
+	if store, err := NewStoreFromFile(""); err != nil || len(store.Names()) != 0 {
+		t.Errorf("Expected empty store without a file, got %v (%v)", store, err)
+	}
+
+	if _, err := NewStoreFromFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected error for missing environments file, got nil")
+	}
+
+	path := filepath.Join(t.TempDir(), "environments.yaml")
+	if err := os.WriteFile(path, []byte("production:\n  branches: ['[main']\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write environments file: %v", err)
+	}
+	if _, err := NewStoreFromFile(path); err == nil {
+		t.Error("Expected error for invalid branch pattern, got nil")
+	}
+}
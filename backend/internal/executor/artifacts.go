@@ -0,0 +1,83 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"gantry/internal/models"
+)
+
+// uploadArtifact copies the step's path out of the container as a tar
+// archive and saves it under the artifact's name
+func uploadArtifact(ctx context.Context, c jobContainer, req Request, step models.Step,
+	out io.Writer) error {
+	a := step.UploadArtifact
+	src := stepPath(step.WorkingDirectory, a.Path)
+
+	reader, err := c.copyFrom(ctx, src)
+	if err != nil {
+		if errors.Is(err, errPathNotFound) {
+			return fmt.Errorf("path '%s' does not exist", src)
+		}
+		return fmt.Errorf("failed to copy '%s': %w", src, err)
+	}
+	defer reader.Close()
+
+	artifact, err := req.Artifacts.Save(req.RunID, a.Name, reader)
+	if err != nil {
+		return fmt.Errorf("failed to upload artifact '%s': %w", a.Name, err)
+	}
+
+	_, _ = fmt.Fprintf(out, "Uploaded artifact '%s' from %s (%d bytes)\n", a.Name, src, artifact.Size)
+	return nil
+}
+
+// downloadArtifact extracts an artifact into the step's path, creating the
+// directory if needed. Artifacts keep the base name of the path they were
+// uploaded from, so uploading `bin` and downloading into `.` restores `./bin`.
+func downloadArtifact(ctx context.Context, c jobContainer, req Request, step models.Step,
+	out io.Writer) error {
+	a := step.DownloadArtifact
+	dst := stepPath(step.WorkingDirectory, a.Path)
+
+	archive, err := req.Artifacts.Open(req.RunID, a.Name)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	if err := mkdirAll(ctx, c, dst); err != nil {
+		return err
+	}
+
+	if err := c.copyTo(ctx, dst, archive); err != nil {
+		return fmt.Errorf("failed to download artifact '%s': %w", a.Name, err)
+	}
+
+	_, _ = fmt.Fprintf(out, "Downloaded artifact '%s' to %s\n", a.Name, dst)
+	return nil
+}
+
+// collectArtifacts saves the job's declared artifacts once its steps have
+// run. It runs after failures and timeouts too, so is given its own time;
+// a path that can't be collected is noted in the output and skipped.
+func collectArtifacts(ctx context.Context, c jobContainer, req Request, out io.Writer) {
+	if req.Artifacts == nil {
+		_, _ = fmt.Fprintln(out, "=== Artifact storage is not configured; skipping job artifacts ===")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Minute)
+	defer cancel()
+
+	dir := workingDir(req.Job.WorkingDirectory, "")
+	for _, a := range req.Job.Artifacts {
+		step := models.Step{WorkingDirectory: dir, UploadArtifact: &a}
+		if err := uploadArtifact(ctx, c, req, step, out); err != nil {
+			_, _ = fmt.Fprintf(out, "=== Failed to collect artifact '%s': %v ===\n", a.Name, err)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package executor
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gantry/internal/models"
+
+	"github.com/docker/docker/api/types/build"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// Outputs of a build step
+const (
+	ImageIDOutput = "image-id" // the ID of the built image
+	DigestOutput  = "digest"   // the registry digest of the pushed image
+)
+
+// imageBuilder is implemented by job containers whose engine can build
+// images and push them to a registry
+type imageBuilder interface {
+	// buildImage builds an image from a tar archive of the build context and
+	// returns its ID
+	buildImage(ctx context.Context, buildContext io.Reader, b *models.BuildStep,
+		auths map[string]registry.AuthConfig, out io.Writer) (string, error)
+
+	// pushImage pushes a tag and returns the digest the registry stored
+	pushImage(ctx context.Context, tag string, auth registry.AuthConfig, out io.Writer) (string, error)
+}
+
+// buildImage builds a build step's image from its context directory in the
+// job, and pushes its tags when the step asks to
+func buildImage(ctx context.Context, c jobContainer, step models.Step, out io.Writer) (map[string]string, error) {
+	builder, ok := c.(imageBuilder)
+	if !ok {
+		return nil, fmt.Errorf("this executor can't build images; use the docker or podman executor")
+	}
+	b := step.Build
+
+	dir := stepPath(step.WorkingDirectory, b.Context)
+	archive, err := c.copyFrom(ctx, dir)
+	if errors.Is(err, errPathNotFound) {
+		return nil, fmt.Errorf("build context '%s' does not exist", dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read build context '%s': %w", dir, err)
+	}
+	defer archive.Close()
+
+	auths := make(map[string]registry.AuthConfig)
+	if b.Username != "" {
+		for _, tag := range b.Tags {
+			auth, err := registryAuth(tag, b)
+			if err != nil {
+				return nil, err
+			}
+			auths[auth.ServerAddress] = auth
+		}
+	}
+
+	_, _ = fmt.Fprintf(out, "Building %s from %s\n", strings.Join(b.Tags, ", "), dir)
+	id, err := builder.buildImage(ctx, contextArchive(archive), b, auths, out)
+	if err != nil {
+		return nil, err
+	}
+	outputs := map[string]string{ImageIDOutput: id}
+	if !b.Push {
+		return outputs, nil
+	}
+
+	for _, tag := range b.Tags {
+		_, _ = fmt.Fprintf(out, "Pushing %s\n", tag)
+		auth, err := registryAuth(tag, b)
+		if err != nil {
+			return nil, err
+		}
+		digest, err := builder.pushImage(ctx, tag, auth, out)
+		if err != nil {
+			return nil, err
+		}
+		if outputs[DigestOutput] == "" {
+			outputs[DigestOutput] = digest
+		}
+	}
+	return outputs, nil
+}
+
+// registryAuth returns the step's credentials for the registry of a tag
+func registryAuth(tag string, b *models.BuildStep) (registry.AuthConfig, error) {
+	host, err := registryHost(tag)
+	if err != nil {
+		return registry.AuthConfig{}, err
+	}
+	return authConfig(host, models.RegistryCredentials{Username: b.Username, Password: b.Password}), nil
+}
+
+// contextArchive turns an archive of the context directory, whose entries
+// start with the directory's name, into one of its contents, as the engine
+// expects a build context
+func contextArchive(archive io.Reader) io.Reader {
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(stripArchiveRoot(archive, writer))
+	}()
+	return reader
+}
+
+// stripArchiveRoot copies a tar archive, removing the first component of
+// every entry's name
+func stripArchiveRoot(r io.Reader, w io.Writer) error {
+	in := tar.NewReader(r)
+	out := tar.NewWriter(w)
+	for {
+		header, err := in.Next()
+		if errors.Is(err, io.EOF) {
+			return out.Close()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read build context: %w", err)
+		}
+
+		_, rest, _ := strings.Cut(strings.TrimPrefix(header.Name, "./"), "/")
+		if rest == "" {
+			continue
+		}
+		header.Name = rest
+		if err := out.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			return err
+		}
+	}
+}
+
+// buildImage builds an image with the container's engine, streaming the
+// build's output
+func (c *dockerContainer) buildImage(ctx context.Context, buildContext io.Reader, b *models.BuildStep,
+	auths map[string]registry.AuthConfig, out io.Writer) (string, error) {
+	args := make(map[string]*string, len(b.BuildArgs))
+	for k, v := range b.BuildArgs {
+		args[k] = &v
+	}
+
+	resp, err := c.client.ImageBuild(ctx, buildContext, build.ImageBuildOptions{
+		Tags:        b.Tags,
+		Dockerfile:  b.Dockerfile,
+		BuildArgs:   args,
+		Target:      b.Target,
+		AuthConfigs: auths,
+		Remove:      true,
+		ForceRemove: true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to build image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var id string
+	err = jsonmessage.DisplayJSONMessagesStream(resp.Body, out, 0, false, func(msg jsonmessage.JSONMessage) {
+		var aux build.Result
+		if msg.Aux != nil && json.Unmarshal(*msg.Aux, &aux) == nil && aux.ID != "" {
+			id = aux.ID
+		}
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to build image: %w", err)
+	}
+	return id, nil
+}
+
+// pushImage pushes a tag with the container's engine, streaming the push's
+// output
+func (c *dockerContainer) pushImage(ctx context.Context, tag string, auth registry.AuthConfig, out io.Writer) (string, error) {
+	encoded, err := registry.EncodeAuthConfig(auth)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode registry credentials: %w", err)
+	}
+
+	reader, err := c.client.ImagePush(ctx, tag, image.PushOptions{RegistryAuth: encoded})
+	if err != nil {
+		return "", fmt.Errorf("failed to push %s: %w", tag, err)
+	}
+	defer reader.Close()
+
+	var digest string
+	err = jsonmessage.DisplayJSONMessagesStream(reader, out, 0, false, func(msg jsonmessage.JSONMessage) {
+		var aux struct{ Digest string }
+		if msg.Aux != nil && json.Unmarshal(*msg.Aux, &aux) == nil && aux.Digest != "" {
+			digest = aux.Digest
+		}
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to push %s: %w", tag, err)
+	}
+	return digest, nil
+}
@@ -0,0 +1,69 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"gantry/internal/models"
+)
+
+// runBuiltinStep runs a step that uses one of the built-in step types
+// instead of a script
+func runBuiltinStep(ctx context.Context, c jobContainer, req Request, step models.Step,
+	out io.Writer) (models.StepResult, error) {
+	stepCtx := ctx
+	if step.TimeoutMinutes > 0 {
+		var cancel context.CancelFunc
+		stepCtx, cancel = context.WithTimeout(ctx, minutes(step.TimeoutMinutes))
+		defer cancel()
+	}
+
+	result := models.StepResult{StartedAt: time.Now()}
+	_, _ = fmt.Fprintf(out, "=== [ %s ] Starting: %s ===\n", time.Now().Format("2006-01-02 15:04:05"), step.Name)
+
+	var err error
+	switch {
+	case step.Cache != nil:
+		result.Outputs, err = restoreCache(stepCtx, c, req, step, out)
+	case step.Build != nil:
+		result.Outputs, err = buildImage(stepCtx, c, step, out)
+	case req.Artifacts == nil:
+		err = fmt.Errorf("artifact storage is not configured")
+	case step.UploadArtifact != nil:
+		err = uploadArtifact(stepCtx, c, req, step, out)
+	default:
+		err = downloadArtifact(stepCtx, c, req, step, out)
+	}
+
+	result.EndedAt = time.Now()
+	result.Success = err == nil
+	if err != nil {
+		return result, stepError(ctx, stepCtx, step, fmt.Errorf("step '%s' failed: %w", step.Name, err))
+	}
+
+	_, _ = fmt.Fprintf(out, "=== [ %s ] Completed: %s ===\n", time.Now().Format("2006-01-02 15:04:05"), step.Name)
+	return result, nil
+}
+
+// mkdirAll creates a directory in the container, along with its parents
+func mkdirAll(ctx context.Context, c jobContainer, dir string) error {
+	code, err := execQuiet(ctx, c, []string{"mkdir", "-p", dir})
+	if err != nil {
+		return fmt.Errorf("failed to create '%s': %w", dir, err)
+	}
+	if code != 0 {
+		return fmt.Errorf("failed to create '%s' (mkdir exited with status %d)", dir, code)
+	}
+	return nil
+}
+
+// stepPath resolves a built-in step's path against its working directory
+func stepPath(dir, p string) string {
+	if path.IsAbs(p) {
+		return path.Clean(p)
+	}
+	return path.Join(dir, p)
+}
@@ -0,0 +1,72 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+
+	"gantry/internal/cache"
+	"gantry/internal/models"
+)
+
+// CacheHitOutput is the output a cache step sets to "true" when its exact
+// key was found
+const CacheHitOutput = "cache-hit"
+
+// restoreCache extracts the cache entry matching the step's keys over its
+// path. A miss is not an error; the path is saved once the job succeeds.
+func restoreCache(ctx context.Context, c jobContainer, req Request, step models.Step,
+	out io.Writer) (map[string]string, error) {
+	spec := step.Cache
+	outputs := map[string]string{CacheHitOutput: "false"}
+	if req.Cache == nil {
+		_, _ = fmt.Fprintln(out, "Cache storage is not configured; skipping restore")
+		return outputs, nil
+	}
+
+	entry, archive, err := req.Cache.Restore(spec.Key, spec.RestoreKeys)
+	if errors.Is(err, cache.ErrNotFound) {
+		_, _ = fmt.Fprintf(out, "Cache not found for key '%s'\n", spec.Key)
+		return outputs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore cache: %w", err)
+	}
+	defer archive.Close()
+
+	// The archive holds the cached directory itself, so it is extracted
+	// into the directory's parent
+	dst := path.Dir(stepPath(step.WorkingDirectory, spec.Path))
+	if err := mkdirAll(ctx, c, dst); err != nil {
+		return nil, err
+	}
+	if err := c.copyTo(ctx, dst, archive); err != nil {
+		return nil, fmt.Errorf("failed to restore cache '%s': %w", entry.Key, err)
+	}
+
+	if entry.Key == spec.Key {
+		outputs[CacheHitOutput] = "true"
+	}
+	_, _ = fmt.Fprintf(out, "Cache restored from key '%s' (%d bytes)\n", entry.Key, entry.Size)
+	return outputs, nil
+}
+
+// saveCache copies a cache step's path out of the container and saves it
+// under the step's key
+func saveCache(ctx context.Context, c jobContainer, req Request, step models.Step) (*cache.Entry, error) {
+	spec := step.Cache
+	src := stepPath(step.WorkingDirectory, spec.Path)
+
+	reader, err := c.copyFrom(ctx, src)
+	if err != nil {
+		if errors.Is(err, errPathNotFound) {
+			return nil, fmt.Errorf("path '%s' does not exist", src)
+		}
+		return nil, fmt.Errorf("failed to copy '%s': %w", src, err)
+	}
+	defer reader.Close()
+
+	return req.Cache.Save(spec.Key, reader)
+}
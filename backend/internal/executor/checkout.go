@@ -0,0 +1,156 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"gantry/internal/models"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// commitPattern matches full commit hashes, which are checked out by hash
+// rather than looked up as a branch or tag
+var commitPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// checkout clones a job's repository on the server and copies it into the
+// workspace before the steps run, so job images don't need git or the
+// credentials
+func checkout(ctx context.Context, c jobContainer, co *models.Checkout, out io.Writer) error {
+	ref := co.Ref
+	if ref == "" {
+		ref = "the default branch"
+	}
+	_, _ = fmt.Fprintf(out, "=== [ %s ] Checking out %s at %s ===\n", time.Now().Format("2006-01-02 15:04:05"), co.Repository, ref)
+
+	dir, err := os.MkdirTemp("", "gantry-checkout-")
+	if err != nil {
+		return fmt.Errorf("failed to create checkout directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	head, err := cloneRepository(ctx, dir, co)
+	if err != nil {
+		return err
+	}
+
+	dst := stepPath(Workspace, co.Path)
+	if err := mkdirAll(ctx, c, dst); err != nil {
+		return err
+	}
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(writeArchive(writer, dir, dir))
+	}()
+	if err := c.copyTo(ctx, dst, reader); err != nil {
+		_ = reader.CloseWithError(err)
+		return fmt.Errorf("failed to copy checkout to %s: %w", dst, err)
+	}
+
+	_, _ = fmt.Fprintf(out, "Checked out %s into %s\n", head, dst)
+	return nil
+}
+
+// cloneRepository clones a checkout's repository into dir and returns the
+// commit checked out. Commits are found in the full history; branches and
+// tags are fetched alone, to the checkout's depth.
+func cloneRepository(ctx context.Context, dir string, co *models.Checkout) (string, error) {
+	auth, err := checkoutAuth(co)
+	if err != nil {
+		return "", err
+	}
+
+	opts := &git.CloneOptions{URL: co.Repository, Auth: auth, Tags: git.NoTags}
+	commit := commitPattern.MatchString(co.Ref)
+	if co.Ref != "" && !commit {
+		name, err := resolveRef(ctx, co.Repository, auth, co.Ref)
+		if err != nil {
+			return "", err
+		}
+		opts.ReferenceName = name
+		opts.SingleBranch = true
+	}
+	if !commit {
+		opts.Depth = co.Depth
+	}
+
+	repo, err := git.PlainCloneContext(ctx, dir, false, opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to clone %s: %w", co.Repository, err)
+	}
+
+	if commit {
+		worktree, err := repo.Worktree()
+		if err != nil {
+			return "", err
+		}
+		if err := worktree.Checkout(&git.CheckoutOptions{Hash: plumbing.NewHash(co.Ref), Force: true}); err != nil {
+			return "", fmt.Errorf("failed to check out commit %s: %w", co.Ref, err)
+		}
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to read checked out commit: %w", err)
+	}
+	return head.Hash().String(), nil
+}
+
+// resolveRef finds the full name of a branch or tag on the remote. Full
+// refs such as refs/pull/12/head are used as they are.
+func resolveRef(ctx context.Context, url string, auth transport.AuthMethod, ref string) (plumbing.ReferenceName, error) {
+	if strings.HasPrefix(ref, "refs/") {
+		return plumbing.ReferenceName(ref), nil
+	}
+
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{Name: git.DefaultRemoteName, URLs: []string{url}})
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: auth})
+	if err != nil {
+		return "", fmt.Errorf("failed to list refs of %s: %w", url, err)
+	}
+
+	branch, tag := plumbing.NewBranchReferenceName(ref), plumbing.NewTagReferenceName(ref)
+	found := map[plumbing.ReferenceName]bool{}
+	for _, r := range refs {
+		found[r.Name()] = true
+	}
+	switch {
+	case found[branch]:
+		return branch, nil
+	case found[tag]:
+		return tag, nil
+	}
+	return "", fmt.Errorf("ref '%s' is neither a branch nor a tag of %s", ref, url)
+}
+
+// checkoutAuth returns the credentials a checkout clones with. SSH host
+// keys are checked against SSH_KNOWN_HOSTS or ~/.ssh/known_hosts.
+func checkoutAuth(co *models.Checkout) (transport.AuthMethod, error) {
+	switch {
+	case co.Token != "":
+		// Git hosts ignore the user name when the password is a token
+		return &githttp.BasicAuth{Username: "gantry", Password: co.Token}, nil
+	case co.SSHKey != "":
+		user := "git"
+		if ep, err := transport.NewEndpoint(co.Repository); err == nil && ep.User != "" {
+			user = ep.User
+		}
+		auth, err := gitssh.NewPublicKeys(user, []byte(co.SSHKey), "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid checkout ssh-key: %w", err)
+		}
+		return auth, nil
+	}
+	return nil, nil
+}
@@ -2,21 +2,54 @@ package executor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"path"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"gantry/internal/models"
 
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/distribution/reference"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 )
 
+// Workspace is the directory job containers start in; relative
+// working-directory values are resolved against it
+const Workspace = "/workspace"
+
+// DefaultJobTimeout applies to jobs without timeout-minutes
+const DefaultJobTimeout = 30 * time.Minute
+
+// ErrTimeout is wrapped by errors for jobs and steps that exceeded their timeout
+var ErrTimeout = errors.New("timed out")
+
+// ErrOutOfMemory is wrapped by errors for steps killed for exceeding their
+// job's memory limit
+var ErrOutOfMemory = errors.New("out of memory")
+
 // DockerExecutor executes jobs using Docker containers
 type DockerExecutor struct {
 	client *client.Client
+
+	// podman works around Podman's Docker-compatible API; see NewPodmanExecutor
+	podman bool
+
+	cacheUsage cacheUsage
+	networkMu  sync.Mutex // serializes creating run networks
+
+	warm *warmPool // nil unless containers are kept warm
 }
 
 // NewDockerExecutor creates a new Docker-based executor
@@ -31,90 +64,442 @@ func NewDockerExecutor() (*DockerExecutor, error) {
 	}, nil
 }
 
-// Execute runs a job in a Docker container
-func (e *DockerExecutor) Execute(ctx context.Context, _ string, job models.Job) (string, error) {
+// Execute runs a job's steps one at a time in a long-lived container, after
+// starting its services. Job and step timeouts kill the container.
+func (e *DockerExecutor) Execute(ctx context.Context, req Request) (*models.JobResult, error) {
 	// Use background context for Docker operations to avoid premature cancellation
 	// Create separate timeouts for each operation
+	job := req.Job
+	result := &models.JobResult{}
+
+	timeout := jobTimeout(req)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	imageName, err := jobImage(req)
+	if err != nil {
+		return result, err
+	}
+	imageName = e.qualify(imageName)
+
+	var own *models.RegistryCredentials
+	if job.Container != nil {
+		own = job.Container.Credentials
+	}
+	if err := e.ensureImage(imageName, req.PullPolicy, imageCredentials(req.RegistryAuths, imageName, own)); err != nil {
+		return result, err
+	}
+
+	// The container idles while steps are exec'd into it
+	config := &container.Config{
+		Image:      imageName,
+		Cmd:        []string{"tail", "-f", "/dev/null"},
+		Env:        envList(job.Env),
+		WorkingDir: Workspace,
+		Labels:     jobLabels(req),
+	}
+	hostConfig := &container.HostConfig{}
+	if hostConfig.NanoCPUs, err = job.Resources.NanoCPUs(); err != nil {
+		return result, err
+	}
+	if hostConfig.Memory, err = job.Resources.MemoryBytes(); err != nil {
+		return result, err
+	}
+	// Without swap, the limit is what the job can use
+	hostConfig.MemorySwap = hostConfig.Memory
+	hostConfig.Privileged = job.Privileged
+	if job.VMIsolated() {
+		if err := e.CheckVMRuntime(req.VMRuntime); err != nil {
+			return result, err
+		}
+		hostConfig.Runtime = req.VMRuntime
+	}
+	if job.GPUs != "" {
+		if err := e.CheckGPUs(); err != nil {
+			return result, err
+		}
+		if hostConfig.DeviceRequests, err = gpuRequests(job.GPUs); err != nil {
+			return result, err
+		}
+	}
+	for _, spec := range job.Volumes {
+		v, err := models.ParseVolume(spec)
+		if err != nil {
+			return result, err
+		}
+		hostConfig.Binds = append(hostConfig.Binds, v.String())
+	}
+	if sharesWorkspace(req) {
+		workspace, err := e.runWorkspace(req.RunID)
+		if err != nil {
+			return result, err
+		}
+		hostConfig.Binds = append(hostConfig.Binds, workspace+":"+Workspace)
+	}
+	if !job.Privileged {
+		req.Hardening.applyDocker(hostConfig, sharesWorkspace(req) || mountsWorkspace(job.Volumes))
+	}
+	if err := applyStorage(hostConfig, job); err != nil {
+		return result, err
+	}
+	if len(job.CacheVolumes) > 0 {
+		binds, err := e.mountCacheVolumes(job.CacheVolumes)
+		if err != nil {
+			return result, err
+		}
+		hostConfig.Binds = append(hostConfig.Binds, binds...)
+		// Deferred before the container's cleanup so it runs once the
+		// volumes are released
+		defer e.releaseCacheVolumes(job.CacheVolumes, req.CacheVolumeBudget)
+	}
+
+	if len(job.Services) > 0 {
+		services, err := e.startServices(ctx, req)
+		// Deferred before the job container's cleanup so it runs after it;
+		// the network can only be removed once nothing is attached
+		defer func() {
+			result.ServiceLogs, result.ServiceStderr = e.serviceLogs(services)
+			e.stopServices(services)
+		}()
+		if err != nil {
+			return result, jobError(ctx, timeout, err)
+		}
 
-	// Select image based on runs-on
-	imageName := "ubuntu:latest"
-	if job.RunsOn == "alpine" {
-		imageName = "alpine:latest"
+		hostConfig.NetworkMode = container.NetworkMode(services.network)
+		hostConfig.ExtraHosts = services.hosts()
+		config.Env = envList(mergeEnv(serviceHosts(job.Services), job.Env))
+	} else if name, _ := jobNetwork(req); name != "" {
+		if err := e.runNetwork(req.RunID); err != nil {
+			return result, err
+		}
+		hostConfig.NetworkMode = container.NetworkMode(name)
 	}
 
-	// Build script with step tracking and timestamps
-	script := "#!/bin/sh\nset -e\n"
-	for i, step := range job.Steps {
-		script += fmt.Sprintf("\n# Step %d: %s\n", i+1, step.Name)
-		script += fmt.Sprintf("echo '=== [' $(date '+%%Y-%%m-%%d %%H:%%M:%%S') '] Starting: %s ==='\n", step.Name)
-		script += step.Run + "\n"
-		script += fmt.Sprintf("echo '=== [' $(date '+%%Y-%%m-%%d %%H:%%M:%%S') '] Completed: %s ==='\n", step.Name)
+	config.User = jobUser(req)
+	if c := job.Container; c != nil {
+		config.Env = envList(mergeEnv(serviceHosts(job.Services), c.Env, job.Env))
+		// Steps must be able to run in the container, so an image's own
+		// ENTRYPOINT is cleared unless the job explicitly sets one
+		config.Entrypoint = []string{""}
+		if len(c.Entrypoint) > 0 {
+			config.Entrypoint = []string(c.Entrypoint)
+		}
+	}
+
+	var id string
+	warm := e.warm != nil && warmable(req)
+	if warm {
+		if id, err = e.takeWarm(config, hostConfig); err != nil {
+			return result, err
+		}
+		defer e.removeWarm(id)
+	} else {
+		// Create container with separate context
+		createCtx, createCancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer createCancel()
+
+		resp, err := e.client.ContainerCreate(createCtx, config, hostConfig, nil, nil, "")
+		if err != nil {
+			return result, fmt.Errorf("failed to create container: %w", err)
+		}
+		id = resp.ID
+		defer e.cleanupContainer(id)
+
+		// Start container with separate context
+		startCtx, startCancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer startCancel()
+
+		if err := e.client.ContainerStart(startCtx, id, container.StartOptions{}); err != nil {
+			return result, fmt.Errorf("failed to start container: %w", err)
+		}
+	}
+
+	c := &dockerContainer{client: e.client, id: id, limited: hostConfig.Memory > 0}
+	if warm {
+		c.env = config.Env
+	}
+	if !models.IsRootUser(config.User) {
+		c.user = config.User
+	}
+	// Volumes are created owned by root, so a job running as another user
+	// couldn't write to its workspace
+	c.chown(ctx, false, Workspace)
+	if err := checkShells(ctx, c, imageName, job.Steps); err != nil {
+		return result, jobError(ctx, timeout, err)
+	}
+
+	err = runSteps(ctx, c, req, timeout, result)
+	return result, err
+}
+
+// PullImage pulls an image ahead of the jobs that run in it, unless the
+// pull policy finds it on the host already
+func (e *DockerExecutor) PullImage(imageName, policy string, own *models.RegistryCredentials,
+	auths map[string]models.RegistryCredentials) error {
+	imageName, err := normalizeImage(imageName)
+	if err != nil {
+		return err
+	}
+	imageName = e.qualify(imageName)
+	return e.ensureImage(imageName, policy, imageCredentials(auths, imageName, own))
+}
+
+// ensureImage makes sure an image is on the host, pulling it as the pull
+// policy says
+func (e *DockerExecutor) ensureImage(imageName, policy string, creds *models.RegistryCredentials) error {
+	policy = pullPolicy(policy, imageName)
+	if policy == models.PullAlways {
+		return e.pullImage(imageName, creds)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	images, err := e.client.ImageList(ctx, image.ListOptions{Filters: filters.NewArgs(filters.Arg("reference", imageName))})
+	if err != nil {
+		return fmt.Errorf("failed to list images: %w", err)
+	}
+	if len(images) > 0 {
+		log.Printf("Image %s is present; not pulling", imageName)
+		return nil
+	}
+	if policy == models.PullNever {
+		return fmt.Errorf("image %s is not on the host and the pull policy is %s", imageName, models.PullNever)
+	}
+	return e.pullImage(imageName, creds)
+}
+
+// pullPolicy returns the policy an image is pulled by. As in Kubernetes,
+// images tagged latest are always pulled unless a policy is set.
+func pullPolicy(policy, imageName string) string {
+	if policy != "" {
+		return policy
+	}
+	named, err := reference.ParseNormalizedNamed(imageName)
+	if err != nil {
+		return models.PullAlways
+	}
+	if tagged, ok := named.(reference.Tagged); ok && tagged.Tag() != "latest" {
+		return models.PullIfNotPresent
+	}
+	if _, ok := named.(reference.Digested); ok {
+		return models.PullIfNotPresent
+	}
+	return models.PullAlways
+}
+
+// pullImage pulls an image, with credentials for its registry if it has
+// any, reading the response to completion
+func (e *DockerExecutor) pullImage(imageName string, creds *models.RegistryCredentials) error {
+	auth, err := encodeAuth(imageName, creds)
+	if err != nil {
+		return err
 	}
 
-	// Pull image with separate context and timeout
 	log.Printf("Pulling image %s...", imageName)
 	pullCtx, pullCancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer pullCancel()
 
-	reader, err := e.client.ImagePull(pullCtx, imageName, image.PullOptions{})
+	reader, err := e.client.ImagePull(pullCtx, imageName, image.PullOptions{RegistryAuth: auth})
 	if err != nil {
-		return "", fmt.Errorf("failed to pull image: %w", err)
+		return fmt.Errorf("failed to pull image: %w", err)
 	}
 	// Must read the response to completion
 	_, err = io.Copy(io.Discard, reader)
 	_ = reader.Close()
 	if err != nil {
-		return "", fmt.Errorf("failed to pull image: %w", err)
+		return fmt.Errorf("failed to pull image: %w", err)
 	}
 	log.Printf("Image %s pulled successfully", imageName)
+	return nil
+}
 
-	// Create container with separate context
-	createCtx, createCancel := context.WithTimeout(context.Background(), 1*time.Minute)
-	defer createCancel()
+// jobImage returns the fully tagged image a job runs in: its container image
+// if set, otherwise the image selected by runs-on
+func jobImage(req Request) (string, error) {
+	image := req.RunnerImage
+	if req.Job.Container != nil {
+		image = req.Job.Container.Image
+	} else if image == "" {
+		return "", fmt.Errorf("no image for runs-on '%s'", req.Job.RunsOn)
+	}
 
-	resp, err := e.client.ContainerCreate(createCtx, &container.Config{
-		Image: imageName,
-		Cmd:   []string{"/bin/sh", "-c", script},
-	}, nil, nil, nil, "")
-	if err != nil {
-		return "", fmt.Errorf("failed to create container: %w", err)
+	if err := req.ImagePolicy.Check(image); err != nil {
+		return "", err
 	}
+	return normalizeImage(image)
+}
 
-	// Start container with separate context
-	startCtx, startCancel := context.WithTimeout(context.Background(), 1*time.Minute)
-	defer startCancel()
+// normalizeImage returns an image reference with an explicit tag, so that
+// untagged images pull :latest rather than every tag
+func normalizeImage(name string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(name)
+	if err != nil {
+		return "", fmt.Errorf("invalid image '%s': %w", name, err)
+	}
+	return reference.FamiliarString(reference.TagNameOnly(named)), nil
+}
 
-	if err := e.client.ContainerStart(startCtx, resp.ID, container.StartOptions{}); err != nil {
-		return "", fmt.Errorf("failed to start container: %w", err)
+// workingDir returns the directory a step runs in: the step's own
+// working-directory, else the job's, resolved against the workspace
+func workingDir(jobDir, stepDir string) string {
+	dir := stepDir
+	if dir == "" {
+		dir = jobDir
+	}
+	if dir == "" {
+		return Workspace
+	}
+	if path.IsAbs(dir) {
+		return path.Clean(dir)
 	}
+	return path.Join(Workspace, dir)
+}
 
-	// Wait for completion with longer timeout (use parent context here)
-	statusCh, errCh := e.client.ContainerWait(ctx, resp.ID, container.WaitConditionNotRunning)
-	select {
-	case err := <-errCh:
-		if err != nil {
-			return "", fmt.Errorf("error waiting for container: %w", err)
-		}
-	case status := <-statusCh:
-		if status.StatusCode != 0 {
-			// Get logs even on failure
-			logs := e.getContainerLogs(resp.ID)
-			e.cleanupContainer(resp.ID)
-			return logs, fmt.Errorf("container exited with status %d", status.StatusCode)
+// mergeEnv combines env maps, later maps taking precedence
+func mergeEnv(maps ...map[string]string) map[string]string {
+	merged := make(map[string]string)
+	for _, m := range maps {
+		for k, v := range m {
+			merged[k] = v
 		}
 	}
+	return merged
+}
+
+// envList converts an env map into sorted KEY=value pairs
+func envList(env map[string]string) []string {
+	if len(env) == 0 {
+		return nil
+	}
+	list := make([]string, 0, len(env))
+	for k, v := range env {
+		list = append(list, k+"="+v)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// dockerContainer is a job container run by Docker
+type dockerContainer struct {
+	client *client.Client
+	id     string
+
+	limited  bool // whether the container has a memory limit
+	oomKills int  // OOM kills already reported
+
+	user string // the non-root user the job runs as, if any
+
+	env []string // the job's env, set on each exec when the container was made without it
+}
+
+// exec runs a command in the container, streaming its output
+func (c *dockerContainer) exec(ctx context.Context, cmd, env []string, dir string, out io.Writer) (int, error) {
+	exec, err := c.client.ContainerExecCreate(ctx, c.id, container.ExecOptions{
+		Cmd:          cmd,
+		Env:          append(append([]string(nil), c.env...), env...),
+		WorkingDir:   dir,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create exec: %w", err)
+	}
+	return c.attachExec(ctx, exec.ID, out)
+}
+
+// attachExec starts a created exec, streaming its output, and returns its
+// exit code
+func (c *dockerContainer) attachExec(ctx context.Context, execID string, out io.Writer) (int, error) {
+	attach, err := c.client.ContainerExecAttach(ctx, execID, container.ExecAttachOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to attach to exec: %w", err)
+	}
+	defer attach.Close()
+
+	if _, err := stdcopy.StdCopy(out, out, attach.Reader); err != nil {
+		return 0, fmt.Errorf("failed to read exec output: %w", err)
+	}
+
+	inspectCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	info, err := c.client.ContainerExecInspect(inspectCtx, execID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to inspect exec: %w", err)
+	}
+	return info.ExitCode, nil
+}
+
+// kill stops the container immediately
+func (c *dockerContainer) kill() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := c.client.ContainerKill(ctx, c.id, "KILL"); err != nil {
+		log.Printf("WARNING: failed to kill container %s: %v", c.id, err)
+	}
+}
 
-	// Get logs
-	logs := e.getContainerLogs(resp.ID)
+// stop signals the container's commands, then stops it, killing it once
+// grace has passed
+func (c *dockerContainer) stop(grace time.Duration) {
+	signalCommands(c)
 
-	// Remove container
-	e.cleanupContainer(resp.ID)
+	ctx, cancel := context.WithTimeout(context.Background(), grace+30*time.Second)
+	defer cancel()
 
-	return logs, nil
+	seconds := int(grace.Seconds())
+	if err := c.client.ContainerStop(ctx, c.id, container.StopOptions{Timeout: &seconds}); err != nil {
+		log.Printf("WARNING: failed to stop container %s: %v", c.id, err)
+		c.kill()
+	}
 }
 
-// getContainerLogs retrieves logs from a container
-func (e *DockerExecutor) getContainerLogs(containerID string) string {
+// oomKilled reports whether the kernel killed a command of the container for
+// exceeding its memory limit since the last time it was asked
+func (c *dockerContainer) oomKilled(ctx context.Context) bool {
+	if !c.limited {
+		return false
+	}
+
+	// Killing the job container's own process stops the container, which
+	// Docker records
+	inspectCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if info, err := c.client.ContainerInspect(inspectCtx, c.id); err == nil && info.State != nil && info.State.OOMKilled {
+		return true
+	}
+
+	kills := cgroupOOMKills(ctx, c)
+	killed := kills > c.oomKills
+	c.oomKills = kills
+	return killed
+}
+
+// copyFrom copies a path out of the container as a tar archive
+func (c *dockerContainer) copyFrom(ctx context.Context, src string) (io.ReadCloser, error) {
+	reader, _, err := c.client.CopyFromContainer(ctx, c.id, src)
+	if cerrdefs.IsNotFound(err) {
+		return nil, errPathNotFound
+	}
+	return reader, err
+}
+
+// copyTo extracts a tar archive into a directory of the container
+func (c *dockerContainer) copyTo(ctx context.Context, dst string, archive io.Reader) error {
+	if err := c.client.CopyToContainer(ctx, c.id, dst, archive, container.CopyToContainerOptions{}); err != nil {
+		return err
+	}
+	// Copied files are owned by root
+	c.chown(ctx, true, dst)
+	return nil
+}
+
+// getContainerLogs retrieves logs from a container, and what it wrote to
+// stderr on its own. Containers without a TTY, as Gantry creates them,
+// stream their logs with a header on every frame telling stdout and stderr
+// apart, which is stripped.
+func (e *DockerExecutor) getContainerLogs(containerID string) (string, string) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
@@ -123,13 +508,16 @@ func (e *DockerExecutor) getContainerLogs(containerID string) string {
 		ShowStderr: true,
 	})
 	if err != nil {
-		return fmt.Sprintf("Failed to get logs: %v", err)
+		return fmt.Sprintf("Failed to get logs: %v", err), ""
 	}
 
 	defer func() { _ = out.Close() }()
 
-	logs, _ := io.ReadAll(out)
-	return string(logs)
+	var logs, stderr strings.Builder
+	if _, err := stdcopy.StdCopy(&logs, io.MultiWriter(&logs, &stderr), out); err != nil {
+		fmt.Fprintf(&logs, "\nFailed to read logs: %v\n", err)
+	}
+	return logs.String(), stderr.String()
 }
 
 // cleanupContainer removes a container
@@ -142,8 +530,63 @@ func (e *DockerExecutor) cleanupContainer(containerID string) {
 	})
 }
 
+// runWorkspace creates the named volume the jobs of a run share as their
+// workspace, or returns it when an earlier job already did
+func (e *DockerExecutor) runWorkspace(runID string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	name := workspaceName(runID)
+	_, err := e.client.VolumeCreate(ctx, volume.CreateOptions{
+		Name:   name,
+		Labels: runLabels(runID),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create workspace volume: %w", err)
+	}
+	return name, nil
+}
+
+// runNetwork creates the bridge network the containers of a run join for
+// the run's first job; later jobs reuse it
+func (e *DockerExecutor) runNetwork(runID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	name := runNetworkName(runID)
+	e.networkMu.Lock()
+	defer e.networkMu.Unlock()
+
+	if _, err := e.client.NetworkInspect(ctx, name, network.InspectOptions{}); err == nil {
+		return nil
+	} else if !cerrdefs.IsNotFound(err) {
+		return fmt.Errorf("failed to inspect run network: %w", err)
+	}
+	_, err := e.client.NetworkCreate(ctx, name, network.CreateOptions{Driver: "bridge", Labels: runLabels(runID)})
+	if err != nil && !cerrdefs.IsConflict(err) {
+		return fmt.Errorf("failed to create run network: %w", err)
+	}
+	return nil
+}
+
+// CleanupRun removes the workspace volume and network the jobs of a run
+// shared
+func (e *DockerExecutor) CleanupRun(runID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := e.client.VolumeRemove(ctx, workspaceName(runID), true); err != nil && !cerrdefs.IsNotFound(err) {
+		return fmt.Errorf("failed to remove workspace volume: %w", err)
+	}
+	if err := e.client.NetworkRemove(ctx, runNetworkName(runID)); err != nil && !cerrdefs.IsNotFound(err) {
+		return fmt.Errorf("failed to remove run network: %w", err)
+	}
+	return nil
+}
+
 // Cleanup performs any necessary cleanup
 func (e *DockerExecutor) Cleanup() error {
+	e.closeWarm()
 	if e.client != nil {
 		return e.client.Close()
 	}
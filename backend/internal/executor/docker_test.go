@@ -0,0 +1,301 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"gantry/internal/models"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+)
+
+// fakeDaemon is a Docker daemon that keeps networks and has every image.
+// Volumes are created but never listed, containers are created but fail to
+// start, and anything else gets an empty success. It records requests as
+// "METHOD /path" without the API version.
+type fakeDaemon struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []string
+	networks map[string]map[string]string // labels by name
+	created  []*container.Config          // containers created, in order
+}
+
+var apiVersionPrefix = regexp.MustCompile(`^/v[0-9.]+`)
+
+func newFakeDaemon(t *testing.T) *fakeDaemon {
+	t.Helper()
+
+	d := &fakeDaemon{networks: make(map[string]map[string]string)}
+	d.Server = httptest.NewServer(http.HandlerFunc(d.serve))
+	t.Cleanup(d.Close)
+	return d
+}
+
+func (d *fakeDaemon) serve(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Api-Version", "1.45")
+	if r.URL.Path == "/_ping" {
+		w.Write([]byte("OK"))
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	path := apiVersionPrefix.ReplaceAllString(r.URL.Path, "")
+	d.requests = append(d.requests, r.Method+" "+path)
+
+	reply := func(status int, body any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	}
+	name, isNetwork := strings.CutPrefix(path, "/networks/")
+	switch {
+	case r.Method == http.MethodGet && path == "/networks":
+		list := []network.Summary{}
+		for name, labels := range d.networks {
+			list = append(list, network.Summary{Name: name, ID: name, Labels: labels})
+		}
+		reply(http.StatusOK, list)
+	case r.Method == http.MethodPost && path == "/networks/create":
+		var req network.CreateRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if _, ok := d.networks[req.Name]; ok {
+			reply(http.StatusConflict, map[string]string{"message": "network already exists"})
+			return
+		}
+		d.networks[req.Name] = req.Labels
+		reply(http.StatusCreated, network.CreateResponse{ID: req.Name})
+	case isNetwork:
+		labels, ok := d.networks[name]
+		if !ok {
+			reply(http.StatusNotFound, map[string]string{"message": "network not found"})
+			return
+		}
+		if r.Method == http.MethodDelete {
+			delete(d.networks, name)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		reply(http.StatusOK, network.Inspect{Name: name, ID: name, Labels: labels})
+	case r.Method == http.MethodGet && path == "/images/json":
+		reply(http.StatusOK, []image.Summary{{ID: "sha256:fake"}})
+	case r.Method == http.MethodPost && path == "/containers/create":
+		var req container.CreateRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		d.created = append(d.created, req.Config)
+		reply(http.StatusCreated, container.CreateResponse{ID: fmt.Sprintf("c%d", len(d.created))})
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/start"):
+		reply(http.StatusInternalServerError, map[string]string{"message": "containers don't start here"})
+	case r.Method == http.MethodGet && path == "/containers/json":
+		reply(http.StatusOK, []any{})
+	case r.Method == http.MethodPost && path == "/volumes/create":
+		var req volume.CreateOptions
+		json.NewDecoder(r.Body).Decode(&req)
+		reply(http.StatusCreated, volume.Volume{Name: req.Name, Labels: req.Labels})
+	case r.Method == http.MethodGet && path == "/volumes":
+		reply(http.StatusOK, map[string]any{"Volumes": []any{}})
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// host returns the daemon's address as a Docker host
+func (d *fakeDaemon) host() DockerHost {
+	return DockerHost{URL: "tcp://" + strings.TrimPrefix(d.URL, "http://")}
+}
+
+// received returns the requests made so far
+func (d *fakeDaemon) received() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return slices.Clone(d.requests)
+}
+
+// networkNames returns the names of the daemon's networks, sorted
+func (d *fakeDaemon) networkNames() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var names []string
+	for name := range d.networks {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// newFakeDockerExecutor returns an executor talking to a fake daemon
+func newFakeDockerExecutor(t *testing.T) (*DockerExecutor, *fakeDaemon) {
+	t.Helper()
+
+	d := newFakeDaemon(t)
+	e, err := NewRemoteDockerExecutor(d.host())
+	if err != nil {
+		t.Fatalf("Failed to create executor: %v", err)
+	}
+	t.Cleanup(func() { e.Cleanup() })
+	return e, d
+}
+
+func TestJobNetwork(t *testing.T) {
+	services := map[string]models.Service{"db": {Image: "postgres:16"}}
+	tests := []struct {
+		name  string
+		req   Request
+		want  string
+		owned bool
+	}{
+		{"job of a run", Request{RunID: "run-1", JobName: "build"}, "gantry-run-1-network", false},
+		{"job of a run with services", Request{RunID: "run-1", JobName: "test", Job: models.Job{Services: services}}, "gantry-run-1-network", false},
+		{"job with services", Request{JobName: "test", Job: models.Job{Services: services}}, "gantry--test", true},
+		{"job on its own", Request{JobName: "build"}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, owned := jobNetwork(tt.req)
+			if name != tt.want || owned != tt.owned {
+				t.Errorf("Expected network %q (owned %v), got %q (owned %v)", tt.want, tt.owned, name, owned)
+			}
+		})
+	}
+}
+
+func TestOrphanedRun(t *testing.T) {
+	active := func(runID string) bool { return runID == "run-1" }
+	tests := []struct {
+		labels map[string]string
+		want   bool
+	}{
+		{map[string]string{runIDLabel: "run-1"}, false},
+		{map[string]string{runIDLabel: "run-2"}, true},
+		{map[string]string{runIDLabel: ""}, false},
+		{map[string]string{"other": "run-2"}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := orphanedRun(tt.labels, active); got != tt.want {
+			t.Errorf("orphanedRun(%v) = %v, want %v", tt.labels, got, tt.want)
+		}
+	}
+}
+
+func TestDockerExecutor_RunNetwork(t *testing.T) {
+	e, d := newFakeDockerExecutor(t)
+
+	// Every job of a run joins the network its first job created
+	for _, runID := range []string{"run-1", "run-1", "run-2", "run-1"} {
+		if err := e.runNetwork(runID); err != nil {
+			t.Fatalf("Failed to get network of %s: %v", runID, err)
+		}
+	}
+	creates := 0
+	for _, req := range d.received() {
+		if req == "POST /networks/create" {
+			creates++
+		}
+	}
+	if creates != 2 {
+		t.Errorf("Expected one network created per run, got %d", creates)
+	}
+	want := []string{runNetworkName("run-1"), runNetworkName("run-2")}
+	if got := d.networkNames(); !slices.Equal(got, want) {
+		t.Fatalf("Expected networks %v, got %v", want, got)
+	}
+	if labels := d.networks[runNetworkName("run-1")]; labels[runIDLabel] != "run-1" {
+		t.Errorf("Expected the network to be labelled with its run, got %v", labels)
+	}
+
+	if err := e.CleanupRun("run-1"); err != nil {
+		t.Fatalf("Failed to clean up run: %v", err)
+	}
+	if got := d.networkNames(); !slices.Equal(got, []string{runNetworkName("run-2")}) {
+		t.Errorf("Expected only the other run's network to be left, got %v", got)
+	}
+	if err := e.CleanupRun("run-1"); err != nil {
+		t.Errorf("Expected cleaning up twice to succeed, got %v", err)
+	}
+}
+
+func TestDockerExecutor_CleanupOrphans_Networks(t *testing.T) {
+	e, d := newFakeDockerExecutor(t)
+	for _, runID := range []string{"run-1", "run-2"} {
+		if err := e.runNetwork(runID); err != nil {
+			t.Fatalf("Failed to create network of %s: %v", runID, err)
+		}
+	}
+	d.networks["bridge"] = nil
+
+	removed, err := e.CleanupOrphans(func(runID string) bool { return runID == "run-1" })
+	if err != nil {
+		t.Fatalf("Failed to clean up orphans: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("Expected 1 network removed, got %d", removed)
+	}
+	want := []string{"bridge", runNetworkName("run-1")}
+	if got := d.networkNames(); !slices.Equal(got, want) {
+		t.Errorf("Expected networks %v to be left, got %v", want, got)
+	}
+}
+
+func TestDockerExecutor_ContainerLabels(t *testing.T) {
+	e, d := newFakeDockerExecutor(t)
+
+	_, err := e.Execute(context.Background(), Request{
+		RunID:       "run-1",
+		JobName:     "build",
+		Workflow:    "CI",
+		Labels:      map[string]string{"team": "web", "release/channel": "beta"},
+		Job:         models.Job{Steps: []models.Step{{Name: "Build", Run: "make"}}},
+		RunnerImage: "alpine:3",
+	})
+	if err == nil || !strings.Contains(err.Error(), "failed to start container") {
+		t.Fatalf("Expected the fake daemon not to start the container, got %v", err)
+	}
+	if len(d.created) != 1 {
+		t.Fatalf("Expected the job's container to be created, got %d containers", len(d.created))
+	}
+
+	want := map[string]string{
+		runIDLabel:                         "run-1",
+		workflowLabel:                      "CI",
+		jobLabel:                           "build",
+		runLabelPrefix + "team":            "web",
+		runLabelPrefix + "release/channel": "beta",
+	}
+	labels := d.created[0].Labels
+	for key, value := range want {
+		if labels[key] != value {
+			t.Errorf("Expected label %s=%q, got %q", key, value, labels[key])
+		}
+	}
+	if len(labels) != len(want) {
+		t.Errorf("Expected labels %v, got %v", want, labels)
+	}
+}
+
+func TestJobLabels_KeepsGantryLabels(t *testing.T) {
+	labels := jobLabels(Request{
+		RunID:   "run-1",
+		JobName: "build",
+		Labels:  map[string]string{"gantry.run_id": "run-2", "gantry.job": "deploy"},
+	})
+	if labels[runIDLabel] != "run-1" || labels[jobLabel] != "build" {
+		t.Errorf("Expected a run's labels not to replace Gantry's, got %v", labels)
+	}
+	if labels[runLabelPrefix+"gantry.run_id"] != "run-2" {
+		t.Errorf("Expected the run's label to be kept under its prefix, got %v", labels)
+	}
+}
@@ -2,18 +2,128 @@ package executor
 
 import (
 	"context"
+	"io"
+	"time"
+
+	"gantry/internal/artifacts"
+	"gantry/internal/cache"
 	"gantry/internal/models"
 )
 
 // Executor defines the interface for job execution
 type Executor interface {
-	// Execute runs a single job and returns the output
-	Execute(ctx context.Context, jobName string, job models.Job) (string, error)
+	// Execute runs a single job and returns its result. The result is
+	// returned alongside an error whenever output was produced.
+	Execute(ctx context.Context, req Request) (*models.JobResult, error)
 
 	// Cleanup performs any necessary cleanup
 	Cleanup() error
 }
 
+// RunCleaner is implemented by executors that keep resources for the
+// duration of a run, such as the workspace its jobs share
+type RunCleaner interface {
+	// CleanupRun removes a run's resources once all of its jobs finished
+	CleanupRun(runID string) error
+}
+
+// OrphanCleaner is implemented by executors that can find the containers
+// and other resources of runs that ended without cleaning up, as when the
+// server stopped mid-run
+type OrphanCleaner interface {
+	// CleanupOrphans removes the resources of every run active doesn't
+	// report as running, returning how many it removed
+	CleanupOrphans(active func(runID string) bool) (int, error)
+}
+
+// Request identifies a job being executed within a run
+type Request struct {
+	RunID   string
+	JobName string
+	Job     models.Job
+
+	// Workflow names the workflow of the run, for labelling its containers
+	Workflow string
+	// Labels are the labels the run was triggered with, which its Docker
+	// containers carry prefixed with gantry.label.
+	Labels map[string]string
+
+	// RunnerImage is the image the job's runs-on label selects, used when
+	// the job has no container
+	RunnerImage string
+	// RegistryAuths are the server's registry credentials by registry host,
+	// for pulling images the job sets no credentials for
+	RegistryAuths map[string]models.RegistryCredentials
+	// PullPolicy decides whether images already on the host are pulled
+	// again; empty pulls only images tagged latest
+	PullPolicy string
+
+	// Artifacts stores the files the job's artifact steps upload and download
+	Artifacts artifacts.Store
+	// Cache stores the directories the job's cache steps restore and save
+	Cache cache.Store
+
+	// Output, when set, receives the job's output as its steps write it
+	Output io.Writer
+	// MaxOutput, when set, is how many bytes of the job's output, and of
+	// each step's, the result keeps; the middle of longer output is left out
+	MaxOutput int64
+
+	// MaxRuntime, when set, caps how long the job may run whatever its
+	// timeout-minutes says
+	MaxRuntime time.Duration
+
+	// CacheVolumeBudget, when set, is the total size in bytes of cache
+	// volumes kept; past it the least recently used are removed
+	CacheVolumeBudget int64
+
+	// Hardening restricts the job's container, unless the job is privileged
+	Hardening Hardening
+	// ImagePolicy restricts the images the job and its services run in
+	ImagePolicy models.ImagePolicy
+	// VMRuntime runs the containers of jobs with isolation vm in microVMs:
+	// a Docker runtime, or on Kubernetes a RuntimeClass
+	VMRuntime string
+	// DefaultUser is the user job containers run as unless the job sets one;
+	// empty runs them as their image's user
+	DefaultUser string
+
+	// ResolveStep, when set, is called right before each step with the
+	// results of the steps before it and returns the step to run, for steps
+	// whose expressions depend on earlier steps' outcomes and outputs
+	ResolveStep func(index int, results []models.StepResult) (models.Step, error)
+}
+
+// ImagePuller is implemented by executors that can pull images ahead of the
+// jobs that run in them
+type ImagePuller interface {
+	// PullImage makes sure an image is on the host as the pull policy says,
+	// pulling it with own credentials or else those auths has for its registry
+	PullImage(image, policy string, own *models.RegistryCredentials, auths map[string]models.RegistryCredentials) error
+}
+
+// GPUChecker is implemented by executors that can tell whether their host
+// can give jobs GPUs
+type GPUChecker interface {
+	// CheckGPUs returns an error saying why jobs can't have GPUs
+	CheckGPUs() error
+}
+
+// WarmPooler is implemented by executors that can start job containers
+// ahead of the jobs that will take them
+type WarmPooler interface {
+	// EnableWarmPool keeps up to size idle containers for each container
+	// configuration jobs run with, removing any idle for longer than ttl
+	EnableWarmPool(size int, ttl time.Duration)
+}
+
+// VMChecker is implemented by executors that can tell whether their host
+// has a runtime that runs containers in microVMs
+type VMChecker interface {
+	// CheckVMRuntime returns an error saying why runtime can't run jobs
+	CheckVMRuntime(runtime string) error
+}
+
 // Config holds executor configuration
 type Config struct {
 	DockerHost string
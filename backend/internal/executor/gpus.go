@@ -0,0 +1,46 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gantry/internal/models"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// nvidiaRuntime is the container runtime the NVIDIA Container Toolkit
+// registers with Docker
+const nvidiaRuntime = "nvidia"
+
+// CheckGPUs reports whether the Docker host has the NVIDIA container runtime.
+// Podman gives containers GPUs through CDI, which its API can't be asked
+// about, so Podman hosts are assumed to have it.
+func (e *DockerExecutor) CheckGPUs() error {
+	if e.podman {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	info, err := e.client.Info(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to inspect the Docker host: %w", err)
+	}
+	if _, ok := info.Runtimes[nvidiaRuntime]; !ok {
+		return fmt.Errorf("the Docker host has no NVIDIA container runtime; install the NVIDIA Container Toolkit to run jobs with gpus")
+	}
+	return nil
+}
+
+// gpuRequests converts a job's gpus into Docker device requests, as
+// `docker run --gpus` does
+func gpuRequests(gpus string) ([]container.DeviceRequest, error) {
+	count, err := models.ParseGPUs(gpus)
+	if err != nil || count == 0 {
+		return nil, err
+	}
+	return []container.DeviceRequest{{Count: count, Capabilities: [][]string{{"gpu"}}}}, nil
+}
@@ -0,0 +1,73 @@
+package executor
+
+import (
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/strslice"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Hardening restricts job containers beyond the container runtime's
+// defaults. Service containers and privileged jobs aren't hardened.
+type Hardening struct {
+	// CapDrop lists the capabilities to drop, or "ALL"
+	CapDrop []string
+	// NoNewPrivileges stops processes gaining privileges, e.g. through setuid
+	NoNewPrivileges bool
+	// ReadOnlyRootfs mounts the image read-only, leaving the workspace,
+	// /tmp and the job's volumes writable
+	ReadOnlyRootfs bool
+	// PidsLimit caps the number of processes; zero doesn't limit them
+	PidsLimit int64
+}
+
+// applyDocker hardens a job container's host config
+func (h Hardening) applyDocker(hostConfig *container.HostConfig, workspaceMounted bool) {
+	hostConfig.CapDrop = strslice.StrSlice(h.CapDrop)
+	if h.NoNewPrivileges {
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "no-new-privileges:true")
+	}
+	if h.PidsLimit > 0 {
+		limit := h.PidsLimit
+		hostConfig.PidsLimit = &limit
+	}
+	if h.ReadOnlyRootfs {
+		hostConfig.ReadonlyRootfs = true
+		// Docker mounts tmpfs noexec by default, which would stop steps
+		// running what they build
+		hostConfig.Tmpfs = map[string]string{"/tmp": "rw,exec"}
+		if !workspaceMounted {
+			hostConfig.Tmpfs[Workspace] = "rw,exec"
+		}
+	}
+}
+
+// applyPod hardens a job container of a pod, returning the volumes it adds.
+// Pod process limits are set by the kubelet, so PidsLimit doesn't apply.
+func (h Hardening) applyPod(c *corev1.Container) []corev1.Volume {
+	if len(h.CapDrop) == 0 && !h.NoNewPrivileges && !h.ReadOnlyRootfs {
+		return nil
+	}
+	if c.SecurityContext == nil {
+		c.SecurityContext = &corev1.SecurityContext{}
+	}
+	security := c.SecurityContext
+
+	if len(h.CapDrop) > 0 {
+		security.Capabilities = &corev1.Capabilities{}
+		for _, capability := range h.CapDrop {
+			security.Capabilities.Drop = append(security.Capabilities.Drop, corev1.Capability(capability))
+		}
+	}
+	if h.NoNewPrivileges {
+		escalation := false
+		security.AllowPrivilegeEscalation = &escalation
+	}
+	if !h.ReadOnlyRootfs {
+		return nil
+	}
+
+	readOnly := true
+	security.ReadOnlyRootFilesystem = &readOnly
+	c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{Name: "tmp", MountPath: "/tmp"})
+	return []corev1.Volume{{Name: "tmp", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}}
+}
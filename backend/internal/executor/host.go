@@ -0,0 +1,365 @@
+package executor
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gantry/internal/models"
+)
+
+// hostTmp is the directory step output files live in, mapped to each job's
+// private temporary directory on the host
+const hostTmp = "/tmp"
+
+// ShellExecutor runs steps directly on the host, for developing Gantry and
+// running small jobs on machines without a container engine. Steps aren't
+// isolated from the host or from each other.
+type ShellExecutor struct {
+	mu         sync.Mutex
+	workspaces map[string]string // shared workspace directory of each run
+}
+
+// NewShellExecutor creates a new host shell executor
+func NewShellExecutor() *ShellExecutor {
+	return &ShellExecutor{workspaces: make(map[string]string)}
+}
+
+// Execute runs a job's steps one at a time on the host, in the temporary
+// workspace the jobs of its run share. Jobs with a container, services or
+// volumes can't run on the host.
+func (e *ShellExecutor) Execute(ctx context.Context, req Request) (*models.JobResult, error) {
+	job := req.Job
+	result := &models.JobResult{}
+
+	if job.Container != nil {
+		return result, fmt.Errorf("job '%s' sets a container, which the shell executor can't run", req.JobName)
+	}
+	if len(job.Services) > 0 {
+		return result, fmt.Errorf("job '%s' has services, which the shell executor can't run", req.JobName)
+	}
+	if len(job.Volumes) > 0 {
+		return result, fmt.Errorf("job '%s' mounts volumes, which the shell executor can't run", req.JobName)
+	}
+	if job.GPUs != "" {
+		return result, fmt.Errorf("job '%s' has gpus, which the shell executor can't give it", req.JobName)
+	}
+	if job.Privileged {
+		return result, fmt.Errorf("job '%s' is privileged, which the shell executor can't run", req.JobName)
+	}
+	if job.VMIsolated() {
+		return result, fmt.Errorf("job '%s' has isolation vm, which the shell executor can't run", req.JobName)
+	}
+	if job.User != "" {
+		return result, fmt.Errorf("job '%s' sets a user, which the shell executor can't run it as", req.JobName)
+	}
+	if len(job.Tmpfs) > 0 {
+		return result, fmt.Errorf("job '%s' mounts tmpfs, which the shell executor can't run", req.JobName)
+	}
+	if len(job.CacheVolumes) > 0 {
+		return result, fmt.Errorf("job '%s' mounts cache volumes, which the shell executor can't run", req.JobName)
+	}
+
+	timeout := jobTimeout(req)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var workspace string
+	if sharesWorkspace(req) {
+		var err error
+		if workspace, err = e.runWorkspace(req.RunID); err != nil {
+			return result, err
+		}
+	}
+
+	c, err := newHostContainer(req, workspace, job.Env)
+	if err != nil {
+		return result, err
+	}
+	defer c.remove()
+
+	if err := checkShells(ctx, c, "host", job.Steps); err != nil {
+		return result, jobError(ctx, timeout, err)
+	}
+
+	err = runSteps(ctx, c, req, timeout, result)
+	return result, err
+}
+
+// runWorkspace creates the directory the jobs of a run share as their
+// workspace, or returns it when an earlier job already did
+func (e *ShellExecutor) runWorkspace(runID string) (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if dir, ok := e.workspaces[runID]; ok {
+		return dir, nil
+	}
+	dir, err := os.MkdirTemp("", workspaceName(runID)+"-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create workspace: %w", err)
+	}
+	e.workspaces[runID] = dir
+	return dir, nil
+}
+
+// CleanupRun removes the workspace directory the jobs of a run shared
+func (e *ShellExecutor) CleanupRun(runID string) error {
+	e.mu.Lock()
+	dir, ok := e.workspaces[runID]
+	delete(e.workspaces, runID)
+	e.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove workspace: %w", err)
+	}
+	return nil
+}
+
+// Cleanup performs any necessary cleanup
+func (e *ShellExecutor) Cleanup() error {
+	return nil
+}
+
+// hostContainer runs a job's commands on the host. Paths under Workspace and
+// hostTmp are mapped into the job's temporary directory, or Workspace into
+// the run's shared workspace.
+type hostContainer struct {
+	root      string
+	workspace string // the run's shared workspace, if the job uses one
+	env       []string
+
+	killed context.Context
+	stop   context.CancelFunc
+}
+
+// newHostContainer creates a job's temporary directory
+func newHostContainer(req Request, workspace string, env map[string]string) (*hostContainer, error) {
+	root, err := os.MkdirTemp("", resourceName("gantry", req.RunID, req.JobName)+"-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	c := &hostContainer{root: root, workspace: workspace}
+	for _, dir := range []string{Workspace, hostTmp} {
+		if err := os.MkdirAll(c.hostPath(dir), 0o755); err != nil {
+			c.remove()
+			return nil, fmt.Errorf("failed to create workspace: %w", err)
+		}
+	}
+
+	c.env = append(os.Environ(), envList(mergeEnv(env, map[string]string{"TMPDIR": c.hostPath(hostTmp)}))...)
+	c.killed, c.stop = context.WithCancel(context.Background())
+	return c, nil
+}
+
+// hostPath maps a path of the job onto the host
+func (c *hostContainer) hostPath(p string) string {
+	if rest, ok := strings.CutPrefix(p, Workspace); c.workspace != "" && ok && (rest == "" || rest[0] == '/') {
+		return filepath.Join(c.workspace, filepath.FromSlash(rest))
+	}
+	for _, dir := range []string{Workspace, hostTmp} {
+		if p == dir || strings.HasPrefix(p, dir+"/") {
+			return filepath.Join(c.root, filepath.FromSlash(p))
+		}
+	}
+	return filepath.FromSlash(p)
+}
+
+// exec runs a command on the host. Later variables replace earlier ones, so
+// the step's env takes precedence over the job's and the host's.
+func (c *hostContainer) exec(ctx context.Context, cmd, env []string, dir string, out io.Writer) (int, error) {
+	if c.killed.Err() != nil {
+		return 0, fmt.Errorf("job was stopped")
+	}
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+	defer context.AfterFunc(c.killed, stop)()
+
+	if dir == "" {
+		dir = Workspace
+	}
+	command := exec.CommandContext(ctx, cmd[0], cmd[1:]...)
+	command.Dir = c.hostPath(dir)
+	command.Env = append([]string(nil), c.env...)
+	for _, kv := range env {
+		if value, ok := strings.CutPrefix(kv, OutputEnv+"="); ok {
+			kv = OutputEnv + "=" + c.hostPath(value)
+		}
+		command.Env = append(command.Env, kv)
+	}
+
+	// Children left running by a killed step would otherwise hold the output
+	// open
+	command.WaitDelay = 5 * time.Second
+	w := &syncWriter{w: out}
+	command.Stdout, command.Stderr = w, w
+
+	err := command.Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && ctx.Err() == nil {
+		return exit.ExitCode(), nil
+	}
+	if errors.Is(err, exec.ErrNotFound) {
+		// As a shell reports a missing command
+		return 127, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to run %s: %w", cmd[0], err)
+	}
+	return 0, nil
+}
+
+// kill stops every running command, and fails any started later
+func (c *hostContainer) kill() {
+	c.stop()
+}
+
+// copyFrom archives a path on the host
+func (c *hostContainer) copyFrom(ctx context.Context, src string) (io.ReadCloser, error) {
+	hostSrc := c.hostPath(src)
+	if _, err := os.Lstat(hostSrc); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, errPathNotFound
+		}
+		return nil, err
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(writeArchive(writer, filepath.Dir(hostSrc), hostSrc))
+	}()
+	return reader, nil
+}
+
+// copyTo extracts an archive into a directory on the host
+func (c *hostContainer) copyTo(ctx context.Context, dst string, archive io.Reader) error {
+	return extractArchive(archive, c.hostPath(dst))
+}
+
+// remove deletes the job's temporary directory
+func (c *hostContainer) remove() {
+	c.stop()
+	if err := os.RemoveAll(c.root); err != nil {
+		log.Printf("WARNING: failed to remove workspace %s: %v", c.root, err)
+	}
+}
+
+// writeArchive writes src, and everything below it if it is a directory,
+// as a tar archive of entries named relative to parent: src's directory
+// starts them with its base name, and src itself archives its contents.
+func writeArchive(w io.Writer, parent, src string) error {
+	archive := tar.NewWriter(w)
+
+	err := filepath.Walk(src, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(file); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(parent, file)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		header.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(archive, f)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to archive '%s': %w", src, err)
+	}
+	return archive.Close()
+}
+
+// extractArchive extracts a tar archive into dst, keeping entries that
+// name parent directories inside it
+func extractArchive(r io.Reader, dst string) error {
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		name := path.Clean("/" + header.Name)
+		if name == "/" {
+			continue
+		}
+		target := filepath.Join(dst, filepath.FromSlash(name))
+		mode := os.FileMode(header.Mode).Perm()
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, mode|0o700)
+		case tar.TypeSymlink:
+			if err = os.MkdirAll(filepath.Dir(target), 0o755); err == nil {
+				_ = os.Remove(target)
+				err = os.Symlink(header.Linkname, target)
+			}
+		case tar.TypeReg:
+			err = extractFile(archive, target, mode)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to extract '%s': %w", header.Name, err)
+		}
+	}
+}
+
+// extractFile writes one regular file of an archive
+func extractFile(r io.Reader, target string, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,141 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gantry/internal/models"
+)
+
+// runShellJob runs a job of the given steps on the host
+func runShellJob(t *testing.T, e *ShellExecutor, runID string, job models.Job) (*models.JobResult, error) {
+	t.Helper()
+	return e.Execute(context.Background(), Request{RunID: runID, JobName: "test", Job: job})
+}
+
+func TestShellExecutor_ExitCode(t *testing.T) {
+	e := NewShellExecutor()
+
+	result, err := runShellJob(t, e, "", models.Job{Steps: []models.Step{
+		{Name: "Pass", Run: "echo passing"},
+		{Name: "Fail", Run: "echo failing; exit 7"},
+		{Name: "After", Run: "echo after"},
+	}})
+	if err == nil || !strings.Contains(err.Error(), "step 'Fail' exited with status 7") {
+		t.Fatalf("Expected the second step to exit with status 7, got %v", err)
+	}
+	if len(result.Steps) != 3 || !result.Steps[2].Skipped {
+		t.Fatalf("Expected the steps after the failure to be skipped, got %+v", result.Steps)
+	}
+	if !result.Steps[0].Success || result.Steps[0].ExitCode != 0 {
+		t.Errorf("Expected the first step to succeed, got %+v", result.Steps[0])
+	}
+	if result.Steps[1].Success || result.Steps[1].ExitCode != 7 || !strings.Contains(result.Steps[1].Output, "failing") {
+		t.Errorf("Expected the second step to fail with status 7, got %+v", result.Steps[1])
+	}
+
+	result, _ = runShellJob(t, e, "", models.Job{Steps: []models.Step{{Name: "Missing", Run: "gantry-no-such-command"}}})
+	if result.Steps[0].ExitCode != 127 {
+		t.Errorf("Expected a missing command to exit with status 127, got %d", result.Steps[0].ExitCode)
+	}
+}
+
+func TestShellExecutor_Env(t *testing.T) {
+	t.Setenv("GANTRY_TEST_HOST", "host")
+	e := NewShellExecutor()
+
+	result, err := runShellJob(t, e, "", models.Job{
+		Env: map[string]string{"GANTRY_TEST_JOB": "job", "GANTRY_TEST_HOST": "job"},
+		Steps: []models.Step{{
+			Name: "Env",
+			Env:  map[string]string{"GANTRY_TEST_STEP": "step", "GANTRY_TEST_JOB": "step"},
+			Run:  `echo "$GANTRY_TEST_HOST $GANTRY_TEST_JOB $GANTRY_TEST_STEP"; echo "tmp=$TMPDIR"`,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to run job: %v", err)
+	}
+	output := result.Steps[0].Output
+	if !strings.Contains(output, "job step step\n") {
+		t.Errorf("Expected the step's env over the job's over the host's, got %q", output)
+	}
+	if strings.Contains(output, "tmp=\n") || strings.Contains(output, "tmp="+os.TempDir()+"\n") {
+		t.Errorf("Expected the job's own temporary directory, got %q", output)
+	}
+}
+
+func TestShellExecutor_WorkingDirectory(t *testing.T) {
+	e := NewShellExecutor()
+	defer e.CleanupRun("run-1")
+	dir, err := e.runWorkspace("run-1")
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+
+	result, err := runShellJob(t, e, "run-1", models.Job{
+		WorkingDirectory: "src",
+		Steps: []models.Step{
+			{Name: "Make", WorkingDirectory: Workspace, Run: "mkdir -p src/sub && echo made > src/sub/file"},
+			{Name: "Job", Run: "cat sub/file && pwd"},
+			{Name: "Step", WorkingDirectory: "src/sub", Run: "pwd"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to run job: %v", err)
+	}
+	if output := result.Steps[1].Output; !strings.Contains(output, "made\n") || !strings.Contains(output, dir+"/src\n") {
+		t.Errorf("Expected the job's working directory under the workspace, got %q", output)
+	}
+	if output := result.Steps[2].Output; !strings.Contains(output, dir+"/src/sub\n") {
+		t.Errorf("Expected the step's working directory, got %q", output)
+	}
+
+	// Jobs of the run share its workspace until it is cleaned up
+	if _, err := runShellJob(t, e, "run-1", models.Job{Steps: []models.Step{{Name: "Read", Run: "test -f src/sub/file"}}}); err != nil {
+		t.Errorf("Expected a later job to see the run's files, got %v", err)
+	}
+	if err := e.CleanupRun("run-1"); err != nil {
+		t.Fatalf("Failed to clean up run: %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("Expected the run's workspace to be removed, got %v", err)
+	}
+}
+
+func TestShellExecutor_Timeout(t *testing.T) {
+	tests := []struct {
+		name string
+		job  models.Job
+		want string
+	}{
+		{
+			name: "step",
+			job:  models.Job{Steps: []models.Step{{Name: "Sleep", Run: "exec sleep 30", TimeoutMinutes: 0.005}}},
+			want: "step 'Sleep' exceeded its timeout",
+		},
+		{
+			name: "job",
+			job:  models.Job{TimeoutMinutes: 0.005, Steps: []models.Step{{Name: "Sleep", Run: "exec sleep 30"}}},
+			want: "job exceeded its timeout",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			result, err := runShellJob(t, NewShellExecutor(), "", tt.job)
+			if !errors.Is(err, ErrTimeout) || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Expected %q, got %v", tt.want, err)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("Expected the step to be killed at the timeout, took %s", elapsed)
+			}
+			if len(result.Steps) != 1 || !strings.Contains(result.Steps[0].Output, "Stopped: Sleep") {
+				t.Errorf("Expected the step to be stopped, got %+v", result.Steps)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gantry/internal/models"
+
+	"github.com/docker/docker/client"
+)
+
+// DockerHost is a Docker daemon jobs may run on
+type DockerHost struct {
+	URL     string // e.g. tcp://build1:2376 or unix:///var/run/docker.sock
+	CertDir string // holds ca.pem, cert.pem and key.pem; empty connects without TLS
+}
+
+// Strategies the multi-host executor picks a run's host by
+const (
+	StrategyRoundRobin  = "round-robin"  // each run goes to the next host in turn
+	StrategyLeastLoaded = "least-loaded" // each run goes to the host running the fewest jobs
+)
+
+// NewRemoteDockerExecutor creates an executor running jobs on the Docker
+// daemon at host, authenticating with its TLS certificates if it has any
+func NewRemoteDockerExecutor(host DockerHost) (*DockerExecutor, error) {
+	opts := []client.Opt{client.WithAPIVersionNegotiation()}
+	if host.CertDir != "" {
+		opts = append(opts, client.WithTLSClientConfig(
+			filepath.Join(host.CertDir, "ca.pem"),
+			filepath.Join(host.CertDir, "cert.pem"),
+			filepath.Join(host.CertDir, "key.pem"),
+		))
+	}
+	opts = append(opts, client.WithHost(host.URL))
+
+	cli, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client for %s: %w", host.URL, err)
+	}
+	return &DockerExecutor{client: cli}, nil
+}
+
+// MultiDockerExecutor runs jobs on several Docker daemons. Every job of a
+// run goes to the host its first job was given, as they share a workspace
+// volume and network there; runs are spread across hosts by the strategy.
+type MultiDockerExecutor struct {
+	hosts    []*dockerHost
+	strategy string
+
+	mu   sync.Mutex
+	next int                    // the host round-robin picks next
+	runs map[string]*dockerHost // the host of each run that started a job
+}
+
+// dockerHost is a daemon of the multi-host executor and how many jobs it
+// is running
+type dockerHost struct {
+	url      string
+	executor *DockerExecutor
+	running  int
+}
+
+// NewMultiDockerExecutor creates an executor spreading runs across hosts
+// by strategy. Hosts should be interchangeable: have the same images,
+// runtimes and GPUs available.
+func NewMultiDockerExecutor(hosts []DockerHost, strategy string) (*MultiDockerExecutor, error) {
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("no docker hosts")
+	}
+	switch strategy {
+	case "":
+		strategy = StrategyLeastLoaded
+	case StrategyRoundRobin, StrategyLeastLoaded:
+	default:
+		return nil, fmt.Errorf("unknown strategy '%s' (expected %s or %s)", strategy, StrategyRoundRobin, StrategyLeastLoaded)
+	}
+
+	e := &MultiDockerExecutor{strategy: strategy, runs: make(map[string]*dockerHost)}
+	for _, host := range hosts {
+		exec, err := NewRemoteDockerExecutor(host)
+		if err != nil {
+			_ = e.Cleanup()
+			return nil, err
+		}
+		e.hosts = append(e.hosts, &dockerHost{url: host.URL, executor: exec})
+	}
+	return e, nil
+}
+
+// Execute runs a job on its run's host, picking one for the run's first job
+func (e *MultiDockerExecutor) Execute(ctx context.Context, req Request) (*models.JobResult, error) {
+	host := e.acquire(req.RunID)
+	defer e.release(host)
+	return host.executor.Execute(ctx, req)
+}
+
+// acquire returns the host a run's jobs go to, counting one more job on it
+func (e *MultiDockerExecutor) acquire(runID string) *dockerHost {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	host, ok := e.runs[runID]
+	if !ok {
+		host = e.pick()
+		if runID != "" {
+			e.runs[runID] = host
+		}
+	}
+	host.running++
+	return host
+}
+
+// pick chooses the host for a new run. Least-loaded breaks ties in
+// round-robin order, so idle hosts take turns.
+func (e *MultiDockerExecutor) pick() *dockerHost {
+	start := e.next
+	e.next = (e.next + 1) % len(e.hosts)
+	if e.strategy == StrategyRoundRobin {
+		return e.hosts[start]
+	}
+
+	best := e.hosts[start]
+	for i := 1; i < len(e.hosts); i++ {
+		if host := e.hosts[(start+i)%len(e.hosts)]; host.running < best.running {
+			best = host
+		}
+	}
+	return best
+}
+
+// release records that a job finished on host
+func (e *MultiDockerExecutor) release(host *dockerHost) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	host.running--
+}
+
+// CleanupRun removes a run's workspace and network from its host
+func (e *MultiDockerExecutor) CleanupRun(runID string) error {
+	e.mu.Lock()
+	host, ok := e.runs[runID]
+	delete(e.runs, runID)
+	e.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return host.executor.CleanupRun(runID)
+}
+
+// CleanupOrphans removes what runs that aren't running left on every host
+func (e *MultiDockerExecutor) CleanupOrphans(active func(runID string) bool) (int, error) {
+	removed := 0
+	var errs []error
+	for _, host := range e.hosts {
+		n, err := host.executor.CleanupOrphans(active)
+		removed += n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", host.url, err))
+		}
+	}
+	return removed, errors.Join(errs...)
+}
+
+// PullImage pulls an image on every host, since any of them may run the
+// jobs that use it
+func (e *MultiDockerExecutor) PullImage(imageName, policy string, own *models.RegistryCredentials,
+	auths map[string]models.RegistryCredentials) error {
+	return e.each(func(exec *DockerExecutor) error {
+		return exec.PullImage(imageName, policy, own, auths)
+	})
+}
+
+// CheckGPUs fails unless every host can give jobs GPUs
+func (e *MultiDockerExecutor) CheckGPUs() error {
+	return e.each((*DockerExecutor).CheckGPUs)
+}
+
+// CheckVMRuntime fails unless every host has the runtime
+func (e *MultiDockerExecutor) CheckVMRuntime(runtime string) error {
+	return e.each(func(exec *DockerExecutor) error {
+		return exec.CheckVMRuntime(runtime)
+	})
+}
+
+// EnableWarmPool keeps warm containers on every host
+func (e *MultiDockerExecutor) EnableWarmPool(size int, ttl time.Duration) {
+	for _, host := range e.hosts {
+		host.executor.EnableWarmPool(size, ttl)
+	}
+}
+
+// Cleanup closes the connections to every host
+func (e *MultiDockerExecutor) Cleanup() error {
+	return e.each((*DockerExecutor).Cleanup)
+}
+
+// each calls fn for every host at once, returning their errors named by host
+func (e *MultiDockerExecutor) each(fn func(*DockerExecutor) error) error {
+	errs := make([]error, len(e.hosts))
+	var wg sync.WaitGroup
+	for i, host := range e.hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := fn(host.executor); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", host.url, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,159 @@
+package executor
+
+import (
+	"slices"
+	"testing"
+)
+
+// newIdleMultiExecutor returns a multi-host executor over hosts that aren't
+// connected to anything, running the given numbers of jobs
+func newIdleMultiExecutor(strategy string, running ...int) *MultiDockerExecutor {
+	e := &MultiDockerExecutor{strategy: strategy, runs: make(map[string]*dockerHost)}
+	for i, n := range running {
+		e.hosts = append(e.hosts, &dockerHost{url: string(rune('a' + i)), running: n})
+	}
+	return e
+}
+
+func TestMultiDockerExecutor_Pick(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy string
+		running  []int
+		next     int
+		want     string
+	}{
+		{"round robin takes the next host", StrategyRoundRobin, []int{0, 0, 0}, 1, "b"},
+		{"round robin ignores load", StrategyRoundRobin, []int{5, 0, 0}, 0, "a"},
+		{"round robin wraps around", StrategyRoundRobin, []int{0, 0, 0}, 2, "c"},
+		{"least loaded takes the idlest host", StrategyLeastLoaded, []int{2, 0, 1}, 0, "b"},
+		{"least loaded takes the next of idle hosts", StrategyLeastLoaded, []int{0, 0, 0}, 1, "b"},
+		{"least loaded breaks ties from the next host", StrategyLeastLoaded, []int{1, 0, 0}, 2, "c"},
+		{"least loaded ties wrap around", StrategyLeastLoaded, []int{0, 1, 1}, 1, "a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newIdleMultiExecutor(tt.strategy, tt.running...)
+			e.next = tt.next
+
+			if host := e.pick(); host.url != tt.want {
+				t.Errorf("Expected host %s, got %s", tt.want, host.url)
+			}
+			if want := (tt.next + 1) % len(tt.running); e.next != want {
+				t.Errorf("Expected the next host to be %d, got %d", want, e.next)
+			}
+		})
+	}
+}
+
+func TestMultiDockerExecutor_Acquire(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy string
+		runs     []string // acquired in turn, without releasing
+		want     []string // the host each run was given
+		running  []int    // the jobs each host runs at the end
+	}{
+		{
+			name:     "round robin spreads runs",
+			strategy: StrategyRoundRobin,
+			runs:     []string{"r1", "r2", "r3"},
+			want:     []string{"a", "b", "a"},
+			running:  []int{2, 1},
+		},
+		{
+			name:     "jobs of a run share its host",
+			strategy: StrategyRoundRobin,
+			runs:     []string{"r1", "r1", "r2", "r1"},
+			want:     []string{"a", "a", "b", "a"},
+			running:  []int{3, 1},
+		},
+		{
+			name:     "a run stays on its host however busy",
+			strategy: StrategyLeastLoaded,
+			runs:     []string{"r1", "r2", "r1", "r1", "r3"},
+			want:     []string{"a", "b", "a", "a", "b"},
+			running:  []int{3, 2},
+		},
+		{
+			name:     "jobs without a run aren't pinned",
+			strategy: StrategyLeastLoaded,
+			runs:     []string{"", ""},
+			want:     []string{"a", "b"},
+			running:  []int{1, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newIdleMultiExecutor(tt.strategy, 0, 0)
+
+			var acquired []*dockerHost
+			for i, runID := range tt.runs {
+				host := e.acquire(runID)
+				if host.url != tt.want[i] {
+					t.Errorf("Expected job %d of %q on host %s, got %s", i, runID, tt.want[i], host.url)
+				}
+				acquired = append(acquired, host)
+			}
+			for i, host := range e.hosts {
+				if host.running != tt.running[i] {
+					t.Errorf("Expected host %s to run %d jobs, got %d", host.url, tt.running[i], host.running)
+				}
+			}
+			if _, ok := e.runs[""]; ok {
+				t.Error("Expected jobs without a run not to be recorded")
+			}
+
+			for _, host := range acquired {
+				e.release(host)
+			}
+			for _, host := range e.hosts {
+				if host.running != 0 {
+					t.Errorf("Expected host %s to run no jobs once released, got %d", host.url, host.running)
+				}
+			}
+		})
+	}
+}
+
+func TestMultiDockerExecutor_CleanupRun(t *testing.T) {
+	daemons := []*fakeDaemon{newFakeDaemon(t), newFakeDaemon(t)}
+	e, err := NewMultiDockerExecutor([]DockerHost{daemons[0].host(), daemons[1].host()}, StrategyRoundRobin)
+	if err != nil {
+		t.Fatalf("Failed to create executor: %v", err)
+	}
+	defer e.Cleanup()
+
+	e.release(e.acquire("run-1"))
+	e.release(e.acquire("run-2"))
+
+	if err := e.CleanupRun("run-2"); err != nil {
+		t.Fatalf("Failed to clean up run: %v", err)
+	}
+	if _, ok := e.runs["run-2"]; ok {
+		t.Error("Expected the run to be forgotten")
+	}
+	if _, ok := e.runs["run-1"]; !ok {
+		t.Error("Expected other runs to be kept")
+	}
+	want := []string{
+		"DELETE /volumes/" + workspaceName("run-2"),
+		"DELETE /networks/" + runNetworkName("run-2"),
+	}
+	if got := daemons[1].received(); !slices.Equal(got, want) {
+		t.Errorf("Expected the run's host to remove %v, got %v", want, got)
+	}
+	if got := daemons[0].received(); len(got) != 0 {
+		t.Errorf("Expected the other host to be left alone, got %v", got)
+	}
+
+	// A run that never started a job, or was cleaned up, has nothing left
+	for _, runID := range []string{"run-2", "run-3"} {
+		if err := e.CleanupRun(runID); err != nil {
+			t.Errorf("Expected cleaning up %s to succeed, got %v", runID, err)
+		}
+	}
+	if got := daemons[1].received(); len(got) != len(want) {
+		t.Errorf("Expected no more requests, got %v", got)
+	}
+}
@@ -0,0 +1,67 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// errNoVMRuntime is returned for jobs with isolation vm when the server has
+// no runtime to run them with
+var errNoVMRuntime = errors.New("no VM runtime is configured; set VM_RUNTIME to run jobs with isolation vm")
+
+// vmRuntime returns the runtime a job's containers run with: the request's
+// VM runtime for jobs with isolation vm, or the default runtime
+func vmRuntime(req Request) string {
+	if req.Job.VMIsolated() {
+		return req.VMRuntime
+	}
+	return ""
+}
+
+// CheckVMRuntime reports whether the Docker or Podman host has runtime, such
+// as Kata Containers configured with Firecracker
+func (e *DockerExecutor) CheckVMRuntime(runtime string) error {
+	if runtime == "" {
+		return errNoVMRuntime
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	info, err := e.client.Info(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to inspect the container engine: %w", err)
+	}
+	if _, ok := info.Runtimes[runtime]; !ok {
+		return fmt.Errorf("the container engine has no runtime '%s' to run jobs with isolation vm", runtime)
+	}
+	return nil
+}
+
+// CheckVMRuntime reports whether the cluster has the RuntimeClass runtime.
+// Reading RuntimeClasses needs a cluster-wide permission, so a server that
+// lacks it leaves pods to fail when they're scheduled.
+func (e *KubernetesExecutor) CheckVMRuntime(runtime string) error {
+	if runtime == "" {
+		return errNoVMRuntime
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := e.client.NodeV1().RuntimeClasses().Get(ctx, runtime, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		return fmt.Errorf("the cluster has no RuntimeClass '%s' to run jobs with isolation vm", runtime)
+	case apierrors.IsForbidden(err):
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to get RuntimeClass '%s': %w", runtime, err)
+	}
+	return nil
+}
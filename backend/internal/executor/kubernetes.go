@@ -0,0 +1,826 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gantry/internal/models"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+const (
+	// jobContainerName is the pod container steps are exec'd into
+	jobContainerName = "job"
+
+	// podStartTimeout bounds pulling images and waiting for services
+	podStartTimeout = 10 * time.Minute
+
+	// nvidiaGPUResource is the resource the NVIDIA device plugin offers GPUs as
+	nvidiaGPUResource corev1.ResourceName = "nvidia.com/gpu"
+)
+
+// invalidKubernetesChars matches characters Kubernetes doesn't allow in
+// pod and container names
+var invalidKubernetesChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// waitingFailures are reasons a container waits that it won't recover from
+var waitingFailures = map[string]bool{
+	"ImagePullBackOff":           true,
+	"InvalidImageName":           true,
+	"ErrImageNeverPull":          true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+}
+
+// KubernetesConfig configures the pods jobs run in
+type KubernetesConfig struct {
+	Kubeconfig     string // kubeconfig file; the in-cluster or default config when empty
+	Namespace      string // namespace pods are created in (default "default")
+	ServiceAccount string // service account pods run as (default the namespace's)
+
+	// Resource requests and limits of the job container, e.g. "500m" or "1Gi"
+	CPURequest    string
+	CPULimit      string
+	MemoryRequest string
+	MemoryLimit   string
+
+	// WorkspaceStorageClass, when set, provisions a ReadWriteMany persistent
+	// volume claim of WorkspaceSize (default 1Gi) that the jobs of a run
+	// share as their workspace. Without it each pod has its own.
+	WorkspaceStorageClass string
+	WorkspaceSize         string
+}
+
+// KubernetesExecutor executes jobs as pods in a Kubernetes cluster
+type KubernetesExecutor struct {
+	client         kubernetes.Interface
+	config         *rest.Config
+	namespace      string
+	serviceAccount string
+	resources      corev1.ResourceRequirements
+
+	workspaceClass string
+	workspaceSize  resource.Quantity
+}
+
+// NewKubernetesExecutor creates a new Kubernetes-based executor
+func NewKubernetesExecutor(cfg KubernetesConfig) (*KubernetesExecutor, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = cfg.Kubeconfig
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubernetes config: %w", err)
+	}
+
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+
+	resources, err := resourceRequirements(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := cfg.Namespace
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+
+	workspaceSize := resource.MustParse("1Gi")
+	if cfg.WorkspaceSize != "" {
+		if workspaceSize, err = resource.ParseQuantity(cfg.WorkspaceSize); err != nil {
+			return nil, fmt.Errorf("invalid workspace size '%s': %w", cfg.WorkspaceSize, err)
+		}
+	}
+
+	return &KubernetesExecutor{
+		client:         client,
+		config:         config,
+		namespace:      namespace,
+		serviceAccount: cfg.ServiceAccount,
+		resources:      resources,
+		workspaceClass: cfg.WorkspaceStorageClass,
+		workspaceSize:  workspaceSize,
+	}, nil
+}
+
+// Execute runs a job's steps one at a time in a pod, with its services as
+// sidecar containers reachable under their names. Job and step timeouts
+// delete the pod.
+func (e *KubernetesExecutor) Execute(ctx context.Context, req Request) (*models.JobResult, error) {
+	job := req.Job
+	result := &models.JobResult{}
+
+	timeout := jobTimeout(req)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	imageName, err := jobImage(req)
+	if err != nil {
+		return result, err
+	}
+	if len(job.CacheVolumes) > 0 {
+		return result, fmt.Errorf("job '%s' mounts cache volumes, which the kubernetes executor can't run", req.JobName)
+	}
+
+	var claim string
+	if e.workspaceClass != "" && sharesWorkspace(req) {
+		if claim, err = e.runWorkspace(req.RunID); err != nil {
+			return result, err
+		}
+	}
+
+	pod, err := e.jobPod(req, imageName, claim)
+	if err != nil {
+		return result, err
+	}
+	pullSecret, err := e.createPullSecret(req, imageName)
+	if err != nil {
+		return result, err
+	}
+	if pullSecret != "" {
+		defer e.deleteSecret(pullSecret)
+		pod.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: pullSecret}}
+	}
+
+	createCtx, createCancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer createCancel()
+
+	pod, err = e.client.CoreV1().Pods(e.namespace).Create(createCtx, pod, metav1.CreateOptions{})
+	if err != nil {
+		return result, fmt.Errorf("failed to create pod: %w", err)
+	}
+	log.Printf("Created pod %s/%s for job %s", e.namespace, pod.Name, req.JobName)
+	defer func() {
+		if len(job.Services) > 0 {
+			result.ServiceLogs = e.serviceLogs(pod.Name, job.Services)
+		}
+		e.deletePod(pod.Name, 0)
+	}()
+
+	if err := e.waitForPod(ctx, pod.Name, podReadyTimeout(job.Services)); err != nil {
+		return result, jobError(ctx, timeout, err)
+	}
+
+	_, limited := pod.Spec.Containers[0].Resources.Limits[corev1.ResourceMemory]
+	c := &podContainer{executor: e, pod: pod.Name, limited: limited}
+	if err := checkShells(ctx, c, imageName, job.Steps); err != nil {
+		return result, jobError(ctx, timeout, err)
+	}
+
+	err = runSteps(ctx, c, req, timeout, result)
+	return result, err
+}
+
+// jobPod builds the pod a job runs in. The job container idles while steps
+// are exec'd into it; services share its network, so their names resolve
+// to localhost. The workspace is the persistent volume claim named by
+// workspace, or else a directory of the pod's own.
+func (e *KubernetesExecutor) jobPod(req Request, imageName, workspace string) (*corev1.Pod, error) {
+	job := req.Job
+	idle := []string{"tail", "-f", "/dev/null"}
+
+	resources, err := jobResources(e.resources, job.Resources)
+	if err != nil {
+		return nil, err
+	}
+	switch gpus, err := models.ParseGPUs(job.GPUs); {
+	case err != nil:
+		return nil, err
+	case gpus == models.AllGPUs:
+		return nil, fmt.Errorf("job '%s' asks for all gpus, which kubernetes can't schedule; ask for a number of them", req.JobName)
+	case gpus > 0:
+		// Extended resources are only set as limits; the request follows
+		if resources.Limits == nil {
+			resources.Limits = corev1.ResourceList{}
+		}
+		resources.Limits[nvidiaGPUResource] = *resource.NewQuantity(int64(gpus), resource.DecimalSI)
+	}
+	if job.VMIsolated() && req.VMRuntime == "" {
+		return nil, errNoVMRuntime
+	}
+	var volumes []corev1.Volume
+	var mounts []corev1.VolumeMount
+	if !mountsWorkspace(job.Volumes) {
+		source := corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}
+		if workspace != "" {
+			source = corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: workspace}}
+		}
+		volumes = append(volumes, corev1.Volume{Name: "workspace", VolumeSource: source})
+		mounts = append(mounts, corev1.VolumeMount{Name: "workspace", MountPath: Workspace})
+	}
+	for i, spec := range job.Volumes {
+		volume, mount, err := podVolume(fmt.Sprintf("volume-%d", i), spec)
+		if err != nil {
+			return nil, err
+		}
+		volumes = append(volumes, volume)
+		mounts = append(mounts, mount)
+	}
+
+	main := corev1.Container{
+		Name:         jobContainerName,
+		Image:        imageName,
+		Command:      idle,
+		WorkingDir:   Workspace,
+		Env:          envVars(mergeEnv(serviceHosts(job.Services), job.Env)),
+		Resources:    resources,
+		VolumeMounts: mounts,
+
+		ImagePullPolicy: corev1.PullPolicy(req.PullPolicy),
+	}
+
+	if c := job.Container; c != nil {
+		main.Env = envVars(mergeEnv(serviceHosts(job.Services), c.Env, job.Env))
+		// As with Docker, an image's own ENTRYPOINT is replaced unless the job
+		// explicitly sets one
+		if len(c.Entrypoint) > 0 {
+			main.Command = []string(c.Entrypoint)
+			main.Args = idle
+		}
+	}
+	var podSecurity *corev1.PodSecurityContext
+	if user := jobUser(req); user != "" {
+		security, err := securityContext(user)
+		if err != nil {
+			return nil, err
+		}
+		main.SecurityContext = security
+		// Volumes such as the workspace are handed to the user's group,
+		// so steps can write to them
+		group := security.RunAsUser
+		if security.RunAsGroup != nil {
+			group = security.RunAsGroup
+		}
+		podSecurity = &corev1.PodSecurityContext{FSGroup: group}
+	}
+	if !job.Privileged {
+		volumes = append(volumes, req.Hardening.applyPod(&main)...)
+	}
+	tmpfs, err := podTmpfs(&main, job.Tmpfs)
+	if err != nil {
+		return nil, err
+	}
+	volumes = append(volumes, tmpfs...)
+	if job.Privileged {
+		if main.SecurityContext == nil {
+			main.SecurityContext = &corev1.SecurityContext{}
+		}
+		privileged := true
+		main.SecurityContext.Privileged = &privileged
+	}
+
+	containers := []corev1.Container{main}
+	var hostnames []string
+	for _, name := range sortedServiceNames(job.Services) {
+		svc := job.Services[name]
+		if err := req.ImagePolicy.Check(svc.Image); err != nil {
+			return nil, fmt.Errorf("service '%s': %w", name, err)
+		}
+		serviceImage, err := normalizeImage(svc.Image)
+		if err != nil {
+			return nil, fmt.Errorf("service '%s': %w", name, err)
+		}
+		probe, err := readinessProbe(svc.Healthcheck)
+		if err != nil {
+			return nil, fmt.Errorf("service '%s': %w", name, err)
+		}
+
+		containers = append(containers, corev1.Container{
+			Name:           serviceContainerName(name),
+			Image:          serviceImage,
+			Args:           []string(svc.Command),
+			Env:            envVars(svc.Env),
+			ReadinessProbe: probe,
+
+			ImagePullPolicy: corev1.PullPolicy(req.PullPolicy),
+		})
+		hostnames = append(hostnames, name)
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: kubernetesName("gantry", req.RunID, req.JobName) + "-",
+			Labels:       map[string]string{"app.kubernetes.io/managed-by": "gantry"},
+			Annotations:  map[string]string{"gantry/run-id": req.RunID, "gantry/workflow": req.Workflow, "gantry/job": req.JobName},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy:      corev1.RestartPolicyNever,
+			ServiceAccountName: e.serviceAccount,
+			Containers:         containers,
+			Volumes:            volumes,
+			SecurityContext:    podSecurity,
+		},
+	}
+	// The RuntimeClass puts the whole pod, services included, in a microVM
+	if runtime := vmRuntime(req); runtime != "" {
+		pod.Spec.RuntimeClassName = &runtime
+	}
+	if len(hostnames) > 0 {
+		pod.Spec.HostAliases = []corev1.HostAlias{{IP: "127.0.0.1", Hostnames: hostnames}}
+	}
+	return pod, nil
+}
+
+// waitForPod blocks until every container of a pod is running and its
+// services report ready
+func (e *KubernetesExecutor) waitForPod(ctx context.Context, name string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		pod, err := e.client.CoreV1().Pods(e.namespace).Get(ctx, name, metav1.GetOptions{})
+		if ctx.Err() != nil {
+			return fmt.Errorf("timed out waiting for pod %s to start", name)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to inspect pod %s: %w", name, err)
+		}
+		if ready, err := podReady(pod); err != nil || ready {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for pod %s to start", name)
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// podReadyTimeout returns how long to wait for a job's pod to become ready:
+// podStartTimeout, unless a service's healthcheck allows longer
+func podReadyTimeout(services map[string]models.Service) time.Duration {
+	timeout := podStartTimeout
+	for _, svc := range services {
+		timeout = max(timeout, readyTimeout(svc.Healthcheck, 0))
+	}
+	return timeout
+}
+
+// podReady reports whether a pod is ready to run steps, or why it never will be
+func podReady(pod *corev1.Pod) (bool, error) {
+	switch pod.Status.Phase {
+	case corev1.PodFailed, corev1.PodSucceeded:
+		return false, fmt.Errorf("pod %s stopped: %s", pod.Name, pod.Status.Message)
+	}
+
+	for _, status := range pod.Status.ContainerStatuses {
+		if waiting := status.State.Waiting; waiting != nil && waitingFailures[waiting.Reason] {
+			return false, fmt.Errorf("container '%s' can't start: %s: %s", status.Name, waiting.Reason, waiting.Message)
+		}
+		if terminated := status.State.Terminated; terminated != nil {
+			return false, fmt.Errorf("container '%s' exited with status %d", status.Name, terminated.ExitCode)
+		}
+	}
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue, nil
+		}
+	}
+	return false, nil
+}
+
+// serviceLogs collects the output of every service container
+func (e *KubernetesExecutor) serviceLogs(pod string, services map[string]models.Service) map[string]string {
+	logs := make(map[string]string, len(services))
+	for _, name := range sortedServiceNames(services) {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		data, err := e.client.CoreV1().Pods(e.namespace).GetLogs(pod, &corev1.PodLogOptions{
+			Container: serviceContainerName(name),
+		}).DoRaw(ctx)
+		cancel()
+		if err != nil {
+			logs[name] = fmt.Sprintf("Failed to get logs: %v", err)
+			continue
+		}
+		logs[name] = string(data)
+	}
+	return logs
+}
+
+// deletePod removes a pod, giving its containers grace to exit before they
+// are killed, without waiting for them to stop
+func (e *KubernetesExecutor) deletePod(name string, grace time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	seconds := int64(grace.Seconds())
+	err := e.client.CoreV1().Pods(e.namespace).Delete(ctx, name, metav1.DeleteOptions{GracePeriodSeconds: &seconds})
+	if err != nil && !apierrors.IsNotFound(err) {
+		log.Printf("WARNING: failed to delete pod %s: %v", name, err)
+	}
+}
+
+// createPullSecret creates a Secret holding the registry credentials a
+// job's images are pulled with, or returns "" when they need none
+func (e *KubernetesExecutor) createPullSecret(req Request, imageName string) (string, error) {
+	job := req.Job
+	auths := make(map[string]models.RegistryCredentials)
+	add := func(imageName string, own *models.RegistryCredentials) error {
+		host, err := registryHost(imageName)
+		if err != nil {
+			return err
+		}
+		if creds := imageCredentials(req.RegistryAuths, imageName, own); creds != nil {
+			auths[host] = *creds
+		}
+		return nil
+	}
+
+	var own *models.RegistryCredentials
+	if job.Container != nil {
+		own = job.Container.Credentials
+	}
+	if err := add(imageName, own); err != nil {
+		return "", err
+	}
+	for _, name := range sortedServiceNames(job.Services) {
+		svc := job.Services[name]
+		if err := add(svc.Image, svc.Credentials); err != nil {
+			return "", fmt.Errorf("service '%s': %w", name, err)
+		}
+	}
+	if len(auths) == 0 {
+		return "", nil
+	}
+
+	config, err := models.DockerConfig(auths)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode registry credentials: %w", err)
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: kubernetesName("gantry", req.RunID, req.JobName) + "-",
+			Labels:       map[string]string{"app.kubernetes.io/managed-by": "gantry"},
+			Annotations:  map[string]string{"gantry/run-id": req.RunID},
+		},
+		Type: corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{corev1.DockerConfigJsonKey: config},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	secret, err = e.client.CoreV1().Secrets(e.namespace).Create(ctx, secret, metav1.CreateOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to create image pull secret: %w", err)
+	}
+	return secret.Name, nil
+}
+
+// deleteSecret removes a job's image pull secret
+func (e *KubernetesExecutor) deleteSecret(name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	err := e.client.CoreV1().Secrets(e.namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		log.Printf("WARNING: failed to delete secret %s: %v", name, err)
+	}
+}
+
+// Cleanup performs any necessary cleanup
+func (e *KubernetesExecutor) Cleanup() error {
+	return nil
+}
+
+// podContainer is the job container of a pod
+type podContainer struct {
+	executor *KubernetesExecutor
+	pod      string
+
+	limited  bool // whether the container has a memory limit
+	oomKills int  // OOM kills already reported
+}
+
+// exec runs a command in the container, streaming its output. Kubernetes
+// exec can't set the environment or directory, so the command is wrapped.
+func (c *podContainer) exec(ctx context.Context, cmd, env []string, dir string, out io.Writer) (int, error) {
+	if dir == "" {
+		dir = Workspace
+	}
+	wrapped := append([]string{"/bin/sh", "-c", `cd "$0" && exec env "$@"`, dir}, env...)
+
+	// stdout and stderr are copied concurrently
+	w := &syncWriter{w: out}
+	return c.stream(ctx, append(wrapped, cmd...), nil, w, w)
+}
+
+// kill deletes the pod, ending any running commands
+func (c *podContainer) kill() {
+	c.executor.deletePod(c.pod, 0)
+}
+
+// stop signals the pod's commands, then deletes it, giving its containers
+// grace to exit before they are killed
+func (c *podContainer) stop(grace time.Duration) {
+	signalCommands(c)
+	c.executor.deletePod(c.pod, grace)
+}
+
+// oomKilled reports whether the kernel killed a command of the container for
+// exceeding its memory limit since the last time it was asked
+func (c *podContainer) oomKilled(ctx context.Context) bool {
+	if !c.limited {
+		return false
+	}
+	kills := cgroupOOMKills(ctx, c)
+	killed := kills > c.oomKills
+	c.oomKills = kills
+	return killed
+}
+
+// copyFrom archives a path of the container with tar
+func (c *podContainer) copyFrom(ctx context.Context, src string) (io.ReadCloser, error) {
+	code, err := execQuiet(ctx, c, []string{"test", "-e", src})
+	if err != nil {
+		return nil, err
+	}
+	if code != 0 {
+		return nil, errPathNotFound
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		var stderr strings.Builder
+		code, err := c.stream(ctx, []string{"tar", "cf", "-", "-C", path.Dir(src), path.Base(src)}, nil, writer, &stderr)
+		if err == nil && code != 0 {
+			err = fmt.Errorf("tar exited with status %d: %s", code, strings.TrimSpace(stderr.String()))
+		}
+		writer.CloseWithError(err)
+	}()
+	return reader, nil
+}
+
+// copyTo extracts a tar archive into a directory of the container with tar
+func (c *podContainer) copyTo(ctx context.Context, dst string, archive io.Reader) error {
+	var stderr strings.Builder
+	code, err := c.stream(ctx, []string{"tar", "xf", "-", "-C", dst}, archive, nil, &stderr)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return fmt.Errorf("tar exited with status %d: %s", code, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// stream execs cmd in the job container, attaching the streams that aren't
+// nil, and returns its exit code
+func (c *podContainer) stream(ctx context.Context, cmd []string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	e := c.executor
+	req := e.client.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(e.namespace).
+		Name(c.pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: jobContainerName,
+			Command:   cmd,
+			Stdin:     stdin != nil,
+			Stdout:    stdout != nil,
+			Stderr:    stderr != nil,
+		}, scheme.ParameterCodec)
+
+	exec, err := remotecommand.NewSPDYExecutor(e.config, http.MethodPost, req.URL())
+	if err != nil {
+		return 0, fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{Stdin: stdin, Stdout: stdout, Stderr: stderr})
+	var exit utilexec.ExitError
+	if errors.As(err, &exit) {
+		return exit.ExitStatus(), nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to exec: %w", err)
+	}
+	return 0, nil
+}
+
+// resourceRequirements parses the job container's requests and limits
+func resourceRequirements(cfg KubernetesConfig) (corev1.ResourceRequirements, error) {
+	requirements := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{},
+		Limits:   corev1.ResourceList{},
+	}
+	for _, q := range []struct {
+		list  corev1.ResourceList
+		name  corev1.ResourceName
+		value string
+		kind  string
+	}{
+		{requirements.Requests, corev1.ResourceCPU, cfg.CPURequest, "CPU request"},
+		{requirements.Limits, corev1.ResourceCPU, cfg.CPULimit, "CPU limit"},
+		{requirements.Requests, corev1.ResourceMemory, cfg.MemoryRequest, "memory request"},
+		{requirements.Limits, corev1.ResourceMemory, cfg.MemoryLimit, "memory limit"},
+	} {
+		if q.value == "" {
+			continue
+		}
+		quantity, err := resource.ParseQuantity(q.value)
+		if err != nil {
+			return requirements, fmt.Errorf("invalid %s '%s': %w", q.kind, q.value, err)
+		}
+		q.list[q.name] = quantity
+	}
+	return requirements, nil
+}
+
+// runWorkspace creates the persistent volume claim the jobs of a run share
+// as their workspace, or returns it when an earlier job already did
+func (e *KubernetesExecutor) runWorkspace(runID string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	claim := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        kubernetesName(workspaceName(runID)),
+			Labels:      map[string]string{"app.kubernetes.io/managed-by": "gantry"},
+			Annotations: map[string]string{"gantry/run-id": runID},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+			StorageClassName: &e.workspaceClass,
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: e.workspaceSize},
+			},
+		},
+	}
+	_, err := e.client.CoreV1().PersistentVolumeClaims(e.namespace).Create(ctx, claim, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return "", fmt.Errorf("failed to create workspace volume claim: %w", err)
+	}
+	return claim.Name, nil
+}
+
+// CleanupRun deletes the workspace volume claim the jobs of a run shared
+func (e *KubernetesExecutor) CleanupRun(runID string) error {
+	if e.workspaceClass == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	name := kubernetesName(workspaceName(runID))
+	err := e.client.CoreV1().PersistentVolumeClaims(e.namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete workspace volume claim: %w", err)
+	}
+	return nil
+}
+
+// podVolume maps a job volume onto the pod: host paths of the node it is
+// scheduled on, or the persistent volume claim a named volume names
+func podVolume(name, spec string) (corev1.Volume, corev1.VolumeMount, error) {
+	v, err := models.ParseVolume(spec)
+	if err != nil {
+		return corev1.Volume{}, corev1.VolumeMount{}, err
+	}
+
+	volume := corev1.Volume{Name: name}
+	if v.HostPath() {
+		volume.HostPath = &corev1.HostPathVolumeSource{Path: v.Source}
+	} else {
+		volume.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{ClaimName: v.Source, ReadOnly: v.ReadOnly}
+	}
+	return volume, corev1.VolumeMount{Name: name, MountPath: v.Target, ReadOnly: v.ReadOnly}, nil
+}
+
+// jobResources applies a job's own limits over the configured requirements,
+// as both the request and the limit
+func jobResources(defaults corev1.ResourceRequirements, r *models.Resources) (corev1.ResourceRequirements, error) {
+	requirements := *defaults.DeepCopy()
+	nanoCPUs, err := r.NanoCPUs()
+	if err != nil {
+		return requirements, err
+	}
+	memory, err := r.MemoryBytes()
+	if err != nil {
+		return requirements, err
+	}
+	disk, err := r.DiskBytes()
+	if err != nil {
+		return requirements, err
+	}
+
+	if nanoCPUs > 0 {
+		cpu := *resource.NewMilliQuantity(nanoCPUs/1e6, resource.DecimalSI)
+		requirements.Requests[corev1.ResourceCPU] = cpu
+		requirements.Limits[corev1.ResourceCPU] = cpu
+	}
+	if memory > 0 {
+		bytes := *resource.NewQuantity(memory, resource.BinarySI)
+		requirements.Requests[corev1.ResourceMemory] = bytes
+		requirements.Limits[corev1.ResourceMemory] = bytes
+	}
+	// The kubelet evicts a pod that writes more than this
+	if disk > 0 {
+		bytes := *resource.NewQuantity(disk, resource.BinarySI)
+		requirements.Requests[corev1.ResourceEphemeralStorage] = bytes
+		requirements.Limits[corev1.ResourceEphemeralStorage] = bytes
+	}
+	return requirements, nil
+}
+
+// securityContext runs the job container as the job's user, which
+// Kubernetes only accepts as a numeric uid[:gid]
+func securityContext(user string) (*corev1.SecurityContext, error) {
+	uid, gid, hasGroup := strings.Cut(user, ":")
+	invalid := fmt.Errorf("user '%s' must be a numeric uid or uid:gid to run on kubernetes", user)
+
+	runAsUser, err := strconv.ParseInt(uid, 10, 64)
+	if err != nil {
+		return nil, invalid
+	}
+	security := &corev1.SecurityContext{RunAsUser: &runAsUser}
+	if hasGroup {
+		runAsGroup, err := strconv.ParseInt(gid, 10, 64)
+		if err != nil {
+			return nil, invalid
+		}
+		security.RunAsGroup = &runAsGroup
+	}
+	return security, nil
+}
+
+// readinessProbe converts a service healthcheck into a readiness probe
+func readinessProbe(h *models.Healthcheck) (*corev1.Probe, error) {
+	if h == nil {
+		return nil, nil
+	}
+
+	interval, retries, err := healthSettings(h)
+	if err != nil {
+		return nil, err
+	}
+	seconds := int32(max(1, interval/time.Second))
+
+	handler := corev1.ProbeHandler{
+		Exec: &corev1.ExecAction{Command: []string{"/bin/sh", "-c", h.Run}},
+	}
+	if h.Port != 0 {
+		handler = corev1.ProbeHandler{
+			TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt32(int32(h.Port))},
+		}
+	}
+
+	return &corev1.Probe{
+		ProbeHandler:     handler,
+		PeriodSeconds:    seconds,
+		TimeoutSeconds:   seconds,
+		FailureThreshold: int32(retries),
+	}, nil
+}
+
+// envVars converts an env map into sorted container variables
+func envVars(env map[string]string) []corev1.EnvVar {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	vars := make([]corev1.EnvVar, 0, len(names))
+	for _, name := range names {
+		vars = append(vars, corev1.EnvVar{Name: name, Value: env[name]})
+	}
+	return vars
+}
+
+// serviceContainerName returns the pod container a service runs in
+func serviceContainerName(name string) string {
+	return kubernetesName("service", name)
+}
+
+// kubernetesName joins parts into a valid pod or container name
+func kubernetesName(parts ...string) string {
+	name := invalidKubernetesChars.ReplaceAllString(strings.ToLower(strings.Join(parts, "-")), "-")
+	if len(name) > 52 {
+		name = name[:52]
+	}
+	return strings.Trim(name, "-")
+}
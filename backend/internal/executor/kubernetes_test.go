@@ -0,0 +1,228 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gantry/internal/models"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newFakeKubernetesExecutor returns an executor whose cluster is a fake
+// that names objects created with a generated name, as the API server does
+func newFakeKubernetesExecutor(t *testing.T, cfg KubernetesConfig) (*KubernetesExecutor, *fake.Clientset) {
+	t.Helper()
+
+	resources, err := resourceRequirements(cfg)
+	if err != nil {
+		t.Fatalf("Failed to parse resources: %v", err)
+	}
+	client := fake.NewSimpleClientset()
+	var generated atomic.Int64
+	client.PrependReactor("create", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		obj := action.(k8stesting.CreateAction).GetObject().(metav1.Object)
+		if obj.GetName() == "" && obj.GetGenerateName() != "" {
+			obj.SetName(fmt.Sprintf("%s%d", obj.GetGenerateName(), generated.Add(1)))
+		}
+		return false, nil, nil
+	})
+
+	return &KubernetesExecutor{
+		client:         client,
+		namespace:      "ci",
+		serviceAccount: cfg.ServiceAccount,
+		resources:      resources,
+		workspaceClass: cfg.WorkspaceStorageClass,
+		workspaceSize:  resource.MustParse("1Gi"),
+	}, client
+}
+
+func TestKubernetesExecutor_JobPod(t *testing.T) {
+	e, _ := newFakeKubernetesExecutor(t, KubernetesConfig{
+		ServiceAccount: "gantry-runner",
+		CPURequest:     "250m",
+		MemoryLimit:    "1Gi",
+	})
+
+	req := Request{
+		RunID:    "run-1",
+		JobName:  "test",
+		Workflow: "Build",
+		Job: models.Job{
+			Env:       map[string]string{"GOFLAGS": "-race"},
+			Resources: &models.Resources{CPU: "2"},
+			Volumes:   []string{"/srv/fixtures:/fixtures:ro"},
+			Services: map[string]models.Service{
+				"db": {
+					Image:       "postgres:16",
+					Env:         map[string]string{"POSTGRES_PASSWORD": "test"},
+					Healthcheck: &models.Healthcheck{Port: 5432},
+				},
+			},
+		},
+		RunnerImage: "golang:1.24",
+		PullPolicy:  "always",
+	}
+	pod, err := e.jobPod(req, "golang:1.24", "")
+	if err != nil {
+		t.Fatalf("Failed to build pod: %v", err)
+	}
+
+	if pod.Spec.ServiceAccountName != "gantry-runner" || pod.Spec.RestartPolicy != corev1.RestartPolicyNever {
+		t.Errorf("Expected the service account and no restarts, got %q and %q", pod.Spec.ServiceAccountName, pod.Spec.RestartPolicy)
+	}
+	if !strings.HasPrefix(pod.GenerateName, "gantry-run-1-test") || pod.Annotations["gantry/workflow"] != "Build" {
+		t.Errorf("Expected the pod to be named and annotated for its job, got %q and %v", pod.GenerateName, pod.Annotations)
+	}
+	if len(pod.Spec.Containers) != 2 {
+		t.Fatalf("Expected the job and service containers, got %d", len(pod.Spec.Containers))
+	}
+
+	main := pod.Spec.Containers[0]
+	if main.Name != jobContainerName || main.Image != "golang:1.24" || main.ImagePullPolicy != "always" {
+		t.Errorf("Expected the job container to run the job's image, got %s %s (%s)", main.Name, main.Image, main.ImagePullPolicy)
+	}
+	if main.WorkingDir != Workspace {
+		t.Errorf("Expected the workspace as working directory, got %q", main.WorkingDir)
+	}
+	env := map[string]string{}
+	for _, v := range main.Env {
+		env[v.Name] = v.Value
+	}
+	if env["GOFLAGS"] != "-race" {
+		t.Errorf("Expected the job's env, got %v", main.Env)
+	}
+	cpu := main.Resources.Limits[corev1.ResourceCPU]
+	memory := main.Resources.Limits[corev1.ResourceMemory]
+	request := main.Resources.Requests[corev1.ResourceCPU]
+	if cpu.String() != "2" || request.String() != "2" || memory.String() != "1Gi" {
+		t.Errorf("Expected the job's CPU as request and limit over the configured ones, got %v", main.Resources)
+	}
+
+	mounts := map[string]string{}
+	for _, m := range main.VolumeMounts {
+		mounts[m.MountPath] = m.Name
+	}
+	volumes := map[string]corev1.Volume{}
+	for _, v := range pod.Spec.Volumes {
+		volumes[v.Name] = v
+	}
+	if v := volumes[mounts[Workspace]]; v.EmptyDir == nil {
+		t.Errorf("Expected an emptyDir workspace, got %+v", v)
+	}
+	if v := volumes[mounts["/fixtures"]]; v.HostPath == nil || v.HostPath.Path != "/srv/fixtures" {
+		t.Errorf("Expected /fixtures to mount the host path, got %+v", v)
+	}
+
+	db := pod.Spec.Containers[1]
+	if db.Image != "postgres:16" || len(db.Env) != 1 || db.ReadinessProbe == nil {
+		t.Errorf("Expected the service container with its env and probe, got %+v", db)
+	}
+	if len(pod.Spec.HostAliases) != 1 || pod.Spec.HostAliases[0].Hostnames[0] != "db" {
+		t.Errorf("Expected the service's name to resolve to the pod, got %+v", pod.Spec.HostAliases)
+	}
+
+	// A shared workspace is the run's volume claim
+	pod, err = e.jobPod(req, "golang:1.24", "gantry-run-1-workspace")
+	if err != nil {
+		t.Fatalf("Failed to build pod: %v", err)
+	}
+	for _, v := range pod.Spec.Volumes {
+		if v.Name == "workspace" && (v.PersistentVolumeClaim == nil || v.PersistentVolumeClaim.ClaimName != "gantry-run-1-workspace") {
+			t.Errorf("Expected the workspace to be the run's claim, got %+v", v)
+		}
+	}
+}
+
+func TestKubernetesExecutor_JobPod_Invalid(t *testing.T) {
+	e, _ := newFakeKubernetesExecutor(t, KubernetesConfig{})
+	for name, job := range map[string]models.Job{
+		"all gpus":     {GPUs: "all"},
+		"bad resource": {Resources: &models.Resources{Memory: "lots"}},
+		"bad volume":   {Volumes: []string{":"}},
+	} {
+		if _, err := e.jobPod(Request{JobName: "test", Job: job}, "alpine:3", ""); err == nil {
+			t.Errorf("Expected %s to be refused", name)
+		}
+	}
+}
+
+func TestKubernetesExecutor_DeletesPodOnCancel(t *testing.T) {
+	e, client := newFakeKubernetesExecutor(t, KubernetesConfig{})
+
+	// The fake's pods never become ready, so the job waits until cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := e.Execute(ctx, Request{
+			RunID:       "run-1",
+			JobName:     "test",
+			Job:         models.Job{Steps: []models.Step{{Name: "Test", Run: "true"}}},
+			RunnerImage: "alpine:3",
+		})
+		done <- err
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		pods, err := client.CoreV1().Pods("ci").List(context.Background(), metav1.ListOptions{})
+		if err != nil {
+			t.Fatalf("Failed to list pods: %v", err)
+		}
+		if len(pods.Items) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the job's pod to be created")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Expected the cancelled job to fail")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the job to end once cancelled")
+	}
+	pods, _ := client.CoreV1().Pods("ci").List(context.Background(), metav1.ListOptions{})
+	if len(pods.Items) != 0 {
+		t.Errorf("Expected the pod to be deleted, got %d left", len(pods.Items))
+	}
+}
+
+func TestKubernetesExecutor_CleanupRun(t *testing.T) {
+	e, client := newFakeKubernetesExecutor(t, KubernetesConfig{WorkspaceStorageClass: "nfs"})
+
+	claim, err := e.runWorkspace("run-1")
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	// A later job of the run shares the claim
+	if again, err := e.runWorkspace("run-1"); err != nil || again != claim {
+		t.Errorf("Expected the claim %s to be shared, got %s (%v)", claim, again, err)
+	}
+
+	if err := e.CleanupRun("run-1"); err != nil {
+		t.Fatalf("Failed to clean up run: %v", err)
+	}
+	claims, _ := client.CoreV1().PersistentVolumeClaims("ci").List(context.Background(), metav1.ListOptions{})
+	if len(claims.Items) != 0 {
+		t.Errorf("Expected the workspace claim to be deleted, got %d left", len(claims.Items))
+	}
+	if err := e.CleanupRun("run-1"); err != nil {
+		t.Errorf("Expected cleaning up twice to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package executor
+
+// Labels of the Docker containers, networks and volumes Gantry creates, for
+// filtering them with e.g. `docker ps --filter label=gantry.workflow=build`
+// and for finding those of runs that never cleaned them up
+const (
+	runIDLabel    = "gantry.run_id"
+	workflowLabel = "gantry.workflow"
+	jobLabel      = "gantry.job"
+	serviceLabel  = "gantry.service" // only on service containers
+
+	// runLabelPrefix prefixes the labels a run was triggered with, so they
+	// can't replace Gantry's own
+	runLabelPrefix = "gantry.label."
+)
+
+// runLabels returns the labels of resources a run's jobs share
+func runLabels(runID string) map[string]string {
+	return map[string]string{runIDLabel: runID}
+}
+
+// jobLabels returns the labels of a job's containers and network, with
+// those its run was triggered with
+func jobLabels(req Request) map[string]string {
+	labels := runLabels(req.RunID)
+	for key, value := range req.Labels {
+		labels[runLabelPrefix+key] = value
+	}
+	labels[workflowLabel] = req.Workflow
+	labels[jobLabel] = req.JobName
+	return labels
+}
@@ -0,0 +1,162 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CleanupOrphans removes the containers, networks and workspace volumes of
+// runs that aren't running, and warm containers no pool keeps. Containers go first, since networks and volumes
+// can't be removed while something uses them.
+func (e *DockerExecutor) CleanupOrphans(active func(runID string) bool) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	labelled := filters.NewArgs(filters.Arg("label", runIDLabel))
+	removed := 0
+
+	containers, err := e.client.ContainerList(ctx, container.ListOptions{All: true, Filters: labelled})
+	if err != nil {
+		return removed, fmt.Errorf("failed to list containers: %w", err)
+	}
+	for _, c := range containers {
+		if !orphanedRun(c.Labels, active) {
+			continue
+		}
+		if err := e.client.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true}); err != nil && !cerrdefs.IsNotFound(err) {
+			log.Printf("WARNING: failed to remove orphaned container %s: %v", c.ID, err)
+			continue
+		}
+		removed++
+	}
+
+	// Warm containers carry no run label until a job takes them, so those
+	// this executor's pool doesn't know of were left by an earlier server
+	warm, err := e.client.ContainerList(ctx, container.ListOptions{All: true, Filters: filters.NewArgs(filters.Arg("label", warmLabel))})
+	if err != nil {
+		return removed, fmt.Errorf("failed to list warm containers: %w", err)
+	}
+	for _, c := range warm {
+		if e.warm.owns(c.ID) {
+			continue
+		}
+		if err := e.client.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true}); err != nil && !cerrdefs.IsNotFound(err) {
+			log.Printf("WARNING: failed to remove orphaned warm container %s: %v", c.ID, err)
+			continue
+		}
+		removed++
+	}
+
+	networks, err := e.client.NetworkList(ctx, network.ListOptions{Filters: labelled})
+	if err != nil {
+		return removed, fmt.Errorf("failed to list networks: %w", err)
+	}
+	for _, n := range networks {
+		if !orphanedRun(n.Labels, active) {
+			continue
+		}
+		if err := e.client.NetworkRemove(ctx, n.ID); err != nil && !cerrdefs.IsNotFound(err) {
+			log.Printf("WARNING: failed to remove orphaned network %s: %v", n.Name, err)
+			continue
+		}
+		removed++
+	}
+
+	volumes, err := e.client.VolumeList(ctx, volume.ListOptions{Filters: labelled})
+	if err != nil {
+		return removed, fmt.Errorf("failed to list volumes: %w", err)
+	}
+	for _, v := range volumes.Volumes {
+		if !orphanedRun(v.Labels, active) {
+			continue
+		}
+		if err := e.client.VolumeRemove(ctx, v.Name, true); err != nil && !cerrdefs.IsNotFound(err) {
+			log.Printf("WARNING: failed to remove orphaned volume %s: %v", v.Name, err)
+			continue
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// orphanedRun reports whether a Docker resource was labelled with a run
+// that isn't running. Resources without a run label aren't Gantry's to
+// remove.
+func orphanedRun(labels map[string]string, active func(runID string) bool) bool {
+	runID := labels[runIDLabel]
+	return runID != "" && !active(runID)
+}
+
+// CleanupOrphans deletes the pods, pull secrets and workspace volume claims
+// of runs that aren't running
+func (e *KubernetesExecutor) CleanupOrphans(active func(runID string) bool) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	orphaned := func(meta metav1.ObjectMeta) bool {
+		runID := meta.Annotations["gantry/run-id"]
+		return runID != "" && !active(runID)
+	}
+	managed := metav1.ListOptions{LabelSelector: "app.kubernetes.io/managed-by=gantry"}
+	grace := int64(0)
+	removed := 0
+
+	pods, err := e.client.CoreV1().Pods(e.namespace).List(ctx, managed)
+	if err != nil {
+		return removed, fmt.Errorf("failed to list pods: %w", err)
+	}
+	for _, pod := range pods.Items {
+		if !orphaned(pod.ObjectMeta) {
+			continue
+		}
+		err := e.client.CoreV1().Pods(e.namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{GracePeriodSeconds: &grace})
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Printf("WARNING: failed to delete orphaned pod %s: %v", pod.Name, err)
+			continue
+		}
+		removed++
+	}
+
+	secrets, err := e.client.CoreV1().Secrets(e.namespace).List(ctx, managed)
+	if err != nil {
+		return removed, fmt.Errorf("failed to list secrets: %w", err)
+	}
+	for _, secret := range secrets.Items {
+		if !orphaned(secret.ObjectMeta) {
+			continue
+		}
+		err := e.client.CoreV1().Secrets(e.namespace).Delete(ctx, secret.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Printf("WARNING: failed to delete orphaned secret %s: %v", secret.Name, err)
+			continue
+		}
+		removed++
+	}
+
+	claims, err := e.client.CoreV1().PersistentVolumeClaims(e.namespace).List(ctx, managed)
+	if err != nil {
+		return removed, fmt.Errorf("failed to list volume claims: %w", err)
+	}
+	for _, claim := range claims.Items {
+		if !orphaned(claim.ObjectMeta) {
+			continue
+		}
+		err := e.client.CoreV1().PersistentVolumeClaims(e.namespace).Delete(ctx, claim.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Printf("WARNING: failed to delete orphaned volume claim %s: %v", claim.Name, err)
+			continue
+		}
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,110 @@
+package executor
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// OutputEnv names the variable holding the file a step writes its outputs to,
+// one `key=value` per line:
+//
+//	echo "version=1.2.3" >> "$GANTRY_OUTPUT"
+//
+// Multi-line values use a heredoc-style delimiter:
+//
+//	key<<EOF
+//	line one
+//	line two
+//	EOF
+const OutputEnv = "GANTRY_OUTPUT"
+
+// maxOutputSize caps how much of a step's output file is read
+const maxOutputSize = 1 << 20
+
+// outputPath returns the output file of the step at index in the job container
+func outputPath(index int) string {
+	return fmt.Sprintf("/tmp/gantry_output_%d", index)
+}
+
+// readOutputs copies a step's output file out of the job container. A step
+// that wrote no outputs has no file.
+func readOutputs(c jobContainer, path string) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	reader, err := c.copyFrom(ctx, path)
+	if err != nil {
+		if errors.Is(err, errPathNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to copy outputs: %w", err)
+	}
+	defer reader.Close()
+
+	archive := tar.NewReader(reader)
+	if _, err := archive.Next(); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read outputs: %w", err)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(archive, maxOutputSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outputs: %w", err)
+	}
+	return parseOutputs(string(data))
+}
+
+// parseOutputs parses the contents of an output file. Later values for a key
+// replace earlier ones.
+func parseOutputs(data string) (map[string]string, error) {
+	outputs := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), maxOutputSize)
+
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if key, delimiter, ok := strings.Cut(line, "<<"); ok && !strings.Contains(key, "=") {
+			var lines []string
+			closed := false
+			for scanner.Scan() {
+				next := strings.TrimSuffix(scanner.Text(), "\r")
+				if next == delimiter {
+					closed = true
+					break
+				}
+				lines = append(lines, next)
+			}
+			if !closed {
+				return nil, fmt.Errorf("output '%s' is missing its closing delimiter '%s'", key, delimiter)
+			}
+			if key == "" || delimiter == "" {
+				return nil, fmt.Errorf("invalid output line '%s'", line)
+			}
+			outputs[key] = strings.Join(lines, "\n")
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid output line '%s' (expected key=value)", line)
+		}
+		outputs[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read outputs: %w", err)
+	}
+
+	return outputs, nil
+}
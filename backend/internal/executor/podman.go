@@ -0,0 +1,90 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"gantry/internal/models"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/client"
+)
+
+// NewPodmanExecutor creates an executor for Podman's Docker-compatible API,
+// for hosts that don't allow a Docker daemon. An empty socket uses
+// CONTAINER_HOST, then the current user's rootless socket, then the system
+// socket.
+//
+// Podman may not resolve short image names, so images are pulled by their
+// fully qualified name, and service healthchecks are run by the executor
+// since rootless Podman often has no systemd to schedule them.
+func NewPodmanExecutor(socket string) (*DockerExecutor, error) {
+	if socket == "" {
+		socket = podmanSocket()
+	}
+
+	cli, err := client.NewClientWithOpts(client.WithHost(socket), client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create podman client: %w", err)
+	}
+
+	return &DockerExecutor{
+		client: cli,
+		podman: true,
+	}, nil
+}
+
+// podmanSocket returns the address of the Podman API socket to use
+func podmanSocket() string {
+	if host := os.Getenv("CONTAINER_HOST"); host != "" {
+		return host
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		rootless := path.Join(dir, "podman", "podman.sock")
+		if _, err := os.Stat(rootless); err == nil {
+			return "unix://" + rootless
+		}
+	}
+	return "unix:///run/podman/podman.sock"
+}
+
+// qualify returns the reference an image is pulled by: for Podman, short
+// names like ubuntu:latest become docker.io/library/ubuntu:latest
+func (e *DockerExecutor) qualify(imageName string) string {
+	if !e.podman {
+		return imageName
+	}
+	named, err := reference.ParseNormalizedNamed(imageName)
+	if err != nil {
+		return imageName
+	}
+	return named.String()
+}
+
+// runHealthcheck runs a service's healthcheck in its container until it
+// passes or has failed as many times as it may
+func (e *DockerExecutor) runHealthcheck(ctx context.Context, name, containerID string, h *models.Healthcheck) error {
+	interval, retries, err := healthSettings(h)
+	if err != nil {
+		return fmt.Errorf("service '%s': %w", name, err)
+	}
+
+	c := &dockerContainer{client: e.client, id: containerID}
+	for attempt := 1; ; attempt++ {
+		if code, err := execQuiet(ctx, c, []string{"/bin/sh", "-c", probeCommand(h)}); err == nil && code == 0 {
+			return nil
+		}
+		if attempt >= retries {
+			return fmt.Errorf("service '%s' is unhealthy", name)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for service '%s' to become ready", name)
+		case <-time.After(interval):
+		}
+	}
+}
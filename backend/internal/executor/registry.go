@@ -0,0 +1,65 @@
+package executor
+
+import (
+	"fmt"
+
+	"gantry/internal/models"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/registry"
+)
+
+// dockerHubAddress is the address Docker keys Docker Hub credentials by
+const dockerHubAddress = "https://index.docker.io/v1/"
+
+// registryHost returns the host of the registry an image is pulled from
+func registryHost(imageName string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(imageName)
+	if err != nil {
+		return "", fmt.Errorf("invalid image '%s': %w", imageName, err)
+	}
+	return reference.Domain(named), nil
+}
+
+// imageCredentials returns the credentials an image is pulled with: own,
+// set by the job for the image, or else the server's for its registry
+func imageCredentials(auths map[string]models.RegistryCredentials, imageName string,
+	own *models.RegistryCredentials) *models.RegistryCredentials {
+	if own != nil {
+		return own
+	}
+	host, err := registryHost(imageName)
+	if err != nil {
+		return nil
+	}
+	if creds, ok := auths[host]; ok {
+		return &creds
+	}
+	return nil
+}
+
+// authConfig returns credentials as Docker expects them for a registry host
+func authConfig(host string, creds models.RegistryCredentials) registry.AuthConfig {
+	address := host
+	if host == models.DockerHubRegistry {
+		address = dockerHubAddress
+	}
+	return registry.AuthConfig{Username: creds.Username, Password: creds.Password, ServerAddress: address}
+}
+
+// encodeAuth encodes the credentials for an image as the RegistryAuth of
+// Docker API requests, or returns "" for anonymous pulls
+func encodeAuth(imageName string, creds *models.RegistryCredentials) (string, error) {
+	if creds == nil {
+		return "", nil
+	}
+	host, err := registryHost(imageName)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := registry.EncodeAuthConfig(authConfig(host, *creds))
+	if err != nil {
+		return "", fmt.Errorf("failed to encode registry credentials: %w", err)
+	}
+	return encoded, nil
+}
@@ -0,0 +1,348 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gantry/internal/models"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+)
+
+const (
+	defaultHealthInterval = 2 * time.Second
+	defaultHealthRetries  = 30
+	serviceReadyTimeout   = 5 * time.Minute
+	serviceProbeInterval  = 500 * time.Millisecond
+)
+
+// invalidNameChars matches characters Docker doesn't allow in object names
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// serviceGroup tracks the network and containers started for a job's services
+type serviceGroup struct {
+	network    string
+	owned      bool              // the network is the job's own, removed with it
+	names      []string          // start order
+	containers map[string]string // service name -> container ID
+	addresses  map[string]string // service name -> IP, on a shared network
+}
+
+// startServices starts every service on the job's network and waits for
+// them to become ready. Jobs in a run share their run's network, where the
+// job container reaches services through host entries, so concurrent jobs
+// with a service of the same name don't clash; other jobs get a network of
+// their own, where each service is known by its name. The returned group
+// holds whatever was started, even on error, so it can be torn down.
+func (e *DockerExecutor) startServices(ctx context.Context, req Request) (*serviceGroup, error) {
+	group := &serviceGroup{containers: make(map[string]string), addresses: make(map[string]string)}
+	prefix := resourceName("gantry", req.RunID, req.JobName)
+
+	name, owned := jobNetwork(req)
+	if owned {
+		netCtx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer cancel()
+
+		if _, err := e.client.NetworkCreate(netCtx, name, network.CreateOptions{Driver: "bridge", Labels: jobLabels(req)}); err != nil {
+			return group, fmt.Errorf("failed to create network: %w", err)
+		}
+		group.owned = true
+	} else if err := e.runNetwork(req.RunID); err != nil {
+		return group, err
+	}
+	group.network = name
+
+	for _, name := range sortedServiceNames(req.Job.Services) {
+		if err := e.startService(group, req, resourceName(prefix, name), name); err != nil {
+			return group, err
+		}
+	}
+
+	for _, name := range group.names {
+		if err := e.waitForService(ctx, name, group.containers[name], req.Job.Services[name]); err != nil {
+			return group, err
+		}
+	}
+
+	return group, nil
+}
+
+// startService pulls, creates and starts one of a job's services on the
+// group's network
+func (e *DockerExecutor) startService(group *serviceGroup, req Request, containerName, name string) error {
+	svc := req.Job.Services[name]
+	if err := req.ImagePolicy.Check(svc.Image); err != nil {
+		return fmt.Errorf("service '%s': %w", name, err)
+	}
+	imageName, err := normalizeImage(svc.Image)
+	if err != nil {
+		return fmt.Errorf("service '%s': %w", name, err)
+	}
+	imageName = e.qualify(imageName)
+	if err := e.ensureImage(imageName, req.PullPolicy, imageCredentials(req.RegistryAuths, imageName, svc.Credentials)); err != nil {
+		return fmt.Errorf("service '%s': %w", name, err)
+	}
+
+	health, err := healthConfig(svc.Healthcheck)
+	if err != nil {
+		return fmt.Errorf("service '%s': %w", name, err)
+	}
+	if e.podman {
+		// waitForService runs the healthcheck instead
+		health = nil
+	}
+
+	labels := jobLabels(req)
+	labels[serviceLabel] = name
+
+	// On a shared network a service is only known by name to the job and
+	// the services started before it
+	endpoint := &network.EndpointSettings{}
+	if group.owned {
+		endpoint.Aliases = []string{name}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	resp, err := e.client.ContainerCreate(ctx, &container.Config{
+		Image:       imageName,
+		Env:         envList(svc.Env),
+		Cmd:         []string(svc.Command),
+		Healthcheck: health,
+		Labels:      labels,
+	}, &container.HostConfig{
+		NetworkMode: container.NetworkMode(group.network),
+		ExtraHosts:  group.hosts(),
+		Runtime:     vmRuntime(req),
+	}, &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{group.network: endpoint},
+	}, nil, containerName)
+	if err != nil {
+		return fmt.Errorf("failed to create service '%s': %w", name, err)
+	}
+	group.names = append(group.names, name)
+	group.containers[name] = resp.ID
+
+	if err := e.client.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		return fmt.Errorf("failed to start service '%s': %w", name, err)
+	}
+	log.Printf("Started service %s (%s)", name, imageName)
+
+	if !group.owned {
+		info, err := e.client.ContainerInspect(ctx, resp.ID)
+		if err != nil {
+			return fmt.Errorf("failed to inspect service '%s': %w", name, err)
+		}
+		var address string
+		if info.NetworkSettings != nil {
+			if endpoint, ok := info.NetworkSettings.Networks[group.network]; ok {
+				address = endpoint.IPAddress
+			}
+		}
+		if address == "" {
+			return fmt.Errorf("service '%s' has no address on network %s", name, group.network)
+		}
+		group.addresses[name] = address
+	}
+	return nil
+}
+
+// hosts returns the host entries resolving the group's services by name on
+// a shared network
+func (g *serviceGroup) hosts() []string {
+	hosts := make([]string, 0, len(g.addresses))
+	for _, name := range g.names {
+		if ip, ok := g.addresses[name]; ok {
+			hosts = append(hosts, name+":"+ip)
+		}
+	}
+	return hosts
+}
+
+// waitForService blocks until a service is running and, if it has a
+// healthcheck, reports healthy
+func (e *DockerExecutor) waitForService(ctx context.Context, name, containerID string, svc models.Service) error {
+	timeout := readyTimeout(svc.Healthcheck, serviceReadyTimeout)
+	return waitReady(ctx, name, timeout, serviceProbeInterval, func(ctx context.Context) (bool, error) {
+		info, err := e.client.ContainerInspect(ctx, containerID)
+		if err != nil {
+			return false, fmt.Errorf("failed to inspect service '%s': %w", name, err)
+		}
+		if state := info.State; e.podman && svc.Healthcheck != nil && state != nil && state.Running {
+			return true, e.runHealthcheck(ctx, name, containerID, svc.Healthcheck)
+		}
+		return serviceReady(name, info.State)
+	})
+}
+
+// waitReady probes a service every interval until it is ready, the probe
+// fails or timeout passes
+func waitReady(ctx context.Context, name string, timeout, interval time.Duration, probe func(ctx context.Context) (bool, error)) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		if ready, err := probe(ctx); err != nil || ready {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for service '%s' to become ready", name)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// serviceReady reports whether a service container's state shows it ready:
+// running, and healthy if it has a healthcheck. A service that exited or
+// was found unhealthy will never be.
+func serviceReady(name string, state *container.State) (bool, error) {
+	switch {
+	case state == nil:
+		return false, nil
+	case !state.Running:
+		return false, fmt.Errorf("service '%s' exited with status %d", name, state.ExitCode)
+	case state.Health == nil || state.Health.Status == container.Healthy:
+		return true, nil
+	case state.Health.Status == container.Unhealthy:
+		return false, fmt.Errorf("service '%s' is unhealthy", name)
+	}
+	return false, nil
+}
+
+// serviceLogs collects the output of every started service, and the stderr
+// of those that wrote to it
+func (e *DockerExecutor) serviceLogs(group *serviceGroup) (map[string]string, map[string]string) {
+	if group == nil || len(group.names) == 0 {
+		return nil, nil
+	}
+
+	logs := make(map[string]string, len(group.names))
+	var stderr map[string]string
+	for _, name := range group.names {
+		combined, errs := e.getContainerLogs(group.containers[name])
+		logs[name] = combined
+		if errs != "" {
+			if stderr == nil {
+				stderr = make(map[string]string)
+			}
+			stderr[name] = errs
+		}
+	}
+	return logs, stderr
+}
+
+// stopServices removes a job's service containers and network
+func (e *DockerExecutor) stopServices(group *serviceGroup) {
+	if group == nil {
+		return
+	}
+
+	for _, name := range group.names {
+		e.cleanupContainer(group.containers[name])
+	}
+
+	if group.owned {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := e.client.NetworkRemove(ctx, group.network); err != nil {
+			log.Printf("WARNING: failed to remove network %s: %v", group.network, err)
+		}
+	}
+}
+
+// healthConfig converts a service healthcheck into Docker's format
+func healthConfig(h *models.Healthcheck) (*container.HealthConfig, error) {
+	if h == nil {
+		return nil, nil
+	}
+
+	interval, retries, err := healthSettings(h)
+	if err != nil {
+		return nil, err
+	}
+
+	return &container.HealthConfig{
+		Test:     []string{"CMD-SHELL", probeCommand(h)},
+		Interval: interval,
+		Timeout:  interval,
+		Retries:  retries,
+	}, nil
+}
+
+// healthSettings returns how often a healthcheck runs and how many failures
+// mark a service unhealthy, applying the defaults
+func healthSettings(h *models.Healthcheck) (time.Duration, int, error) {
+	interval := defaultHealthInterval
+	if h.Interval != "" {
+		d, err := time.ParseDuration(h.Interval)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid healthcheck interval: %w", err)
+		}
+		interval = d
+	}
+
+	retries := defaultHealthRetries
+	switch {
+	case h.Retries > 0:
+		retries = h.Retries
+	case h.Timeout != "":
+		// Keep probing for as long as the timeout allows
+		retries = int(max(1, readyTimeout(h, 0)/max(interval, time.Millisecond)))
+	}
+	return interval, retries, nil
+}
+
+// probeCommand returns the shell command a healthcheck runs inside its
+// service container. A port probe uses nc, falling back to bash's /dev/tcp.
+func probeCommand(h *models.Healthcheck) string {
+	if h.Port == 0 {
+		return h.Run
+	}
+	return fmt.Sprintf("nc -z 127.0.0.1 %[1]d || bash -c 'exec 3<>/dev/tcp/127.0.0.1/%[1]d'", h.Port)
+}
+
+// readyTimeout returns how long to wait for a service to become ready,
+// falling back to def when its healthcheck doesn't say
+func readyTimeout(h *models.Healthcheck, def time.Duration) time.Duration {
+	if h == nil || h.Timeout == "" {
+		return def
+	}
+	if d, err := time.ParseDuration(h.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
+
+// serviceHosts returns the <NAME>_HOST variables pointing steps at each service
+func serviceHosts(services map[string]models.Service) map[string]string {
+	hosts := make(map[string]string, len(services))
+	for name := range services {
+		key := strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_HOST"
+		hosts[key] = name
+	}
+	return hosts
+}
+
+func sortedServiceNames(services map[string]models.Service) []string {
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resourceName joins parts into a valid Docker container or network name
+func resourceName(parts ...string) string {
+	name := strings.Join(parts, "-")
+	return strings.Trim(invalidNameChars.ReplaceAllString(name, "-"), "-.")
+}
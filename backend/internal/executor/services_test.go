@@ -0,0 +1,94 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestServiceReady(t *testing.T) {
+	health := func(status container.HealthStatus) *container.Health {
+		return &container.Health{Status: status}
+	}
+	tests := []struct {
+		name  string
+		state *container.State
+		ready bool
+		err   string
+	}{
+		{"not yet inspected", nil, false, ""},
+		{"running without healthcheck", &container.State{Running: true}, true, ""},
+		{"starting", &container.State{Running: true, Health: health(container.Starting)}, false, ""},
+		{"healthy", &container.State{Running: true, Health: health(container.Healthy)}, true, ""},
+		{"unhealthy", &container.State{Running: true, Health: health(container.Unhealthy)}, false, "service 'db' is unhealthy"},
+		{"exited", &container.State{ExitCode: 3}, false, "service 'db' exited with status 3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ready, err := serviceReady("db", tt.state)
+			if ready != tt.ready {
+				t.Errorf("Expected ready %v, got %v", tt.ready, ready)
+			}
+			if tt.err == "" && err != nil || tt.err != "" && (err == nil || err.Error() != tt.err) {
+				t.Errorf("Expected error %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestWaitReady(t *testing.T) {
+	// The probe passes on its third attempt
+	probes := 0
+	err := waitReady(context.Background(), "db", time.Second, time.Millisecond, func(ctx context.Context) (bool, error) {
+		probes++
+		return probes == 3, nil
+	})
+	if err != nil || probes != 3 {
+		t.Errorf("Expected the service to be ready after 3 probes, got %d (%v)", probes, err)
+	}
+
+	// A failing probe is given up on at once
+	failed := errors.New("service 'db' exited with status 1")
+	probes = 0
+	err = waitReady(context.Background(), "db", time.Second, time.Millisecond, func(ctx context.Context) (bool, error) {
+		probes++
+		return false, failed
+	})
+	if !errors.Is(err, failed) || probes != 1 {
+		t.Errorf("Expected the probe's error after 1 probe, got %d (%v)", probes, err)
+	}
+}
+
+func TestWaitReady_Timeout(t *testing.T) {
+	start := time.Now()
+	probes := 0
+	err := waitReady(context.Background(), "db", 50*time.Millisecond, 10*time.Millisecond, func(ctx context.Context) (bool, error) {
+		probes++
+		return false, nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "timed out waiting for service 'db'") {
+		t.Fatalf("Expected to time out waiting for the service, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected to give up at the timeout, took %s", elapsed)
+	}
+	if probes < 2 {
+		t.Errorf("Expected the service to be probed until the timeout, got %d probes", probes)
+	}
+
+	// The probe is given the deadline, so a probe blocking on it ends too
+	err = waitReady(context.Background(), "db", 50*time.Millisecond, 10*time.Millisecond, func(ctx context.Context) (bool, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("Expected the probe to be given the deadline")
+		}
+		<-ctx.Done()
+		return false, nil
+	})
+	if err == nil {
+		t.Error("Expected a probe blocking past the timeout to time out")
+	}
+}
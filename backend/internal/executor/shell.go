@@ -0,0 +1,104 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"gantry/internal/models"
+)
+
+// shellCommand returns the command that runs a step's script with its shell
+func shellCommand(shell, script string) []string {
+	switch shell {
+	case models.ShellBash:
+		return []string{"bash", "--noprofile", "--norc", "-e", "-o", "pipefail", "-c", script}
+	case models.ShellPython:
+		return []string{"python3", "-c", script}
+	case models.ShellNode:
+		return []string{"node", "-e", script}
+	default:
+		return []string{"/bin/sh", "-e", "-c", script}
+	}
+}
+
+// languageScript is the file, in a temporary directory, a step's script in
+// a language is saved to; its interpreter command runs the file at "$f"
+var languageScript = map[string]struct{ file, run string }{
+	models.LanguagePython: {"step.py", `python3 "$f"`},
+	models.LanguageNode:   {"step.js", `node "$f"`},
+	models.LanguageDeno:   {"step.ts", `deno run -A "$f"`},
+}
+
+// stepCommand returns the command that runs a step's script. A script in a
+// language is passed to sh as an argument, which saves it to a temporary
+// file, runs the interpreter on it and removes the file afterwards; sh
+// exits with the interpreter's status.
+func stepCommand(step models.Step) []string {
+	script, ok := languageScript[step.Language]
+	if !ok {
+		return shellCommand(step.Shell, step.Run)
+	}
+	wrapper := `d=$(mktemp -d) && trap 'rm -rf "$d"' EXIT && f="$d/` + script.file + `" && printf '%s' "$1" > "$f" && ` + script.run
+	return []string{"/bin/sh", "-e", "-c", wrapper, "sh", step.Run}
+}
+
+// interpreter returns the program a step's shell or language needs, or ""
+// for sh, which is assumed to exist since the job container itself relies
+// on it
+func interpreter(step models.Step) string {
+	switch {
+	case step.Language == models.LanguageDeno:
+		return "deno"
+	case step.Language != "":
+		return shellCommand(step.Language, "")[0]
+	case step.Shell != "" && step.Shell != models.ShellSh:
+		return shellCommand(step.Shell, "")[0]
+	}
+	return ""
+}
+
+// checkShells fails fast when a step's shell or language is missing from
+// the job image, before any step has run
+func checkShells(ctx context.Context, c jobContainer, imageName string, steps []models.Step) error {
+	interpreters := make(map[string]string) // what needs each program
+	for _, step := range steps {
+		program := interpreter(step)
+		switch {
+		case program == "":
+		case step.Language != "":
+			interpreters[program] = fmt.Sprintf("language '%s'", step.Language)
+		default:
+			interpreters[program] = fmt.Sprintf("shell '%s'", step.Shell)
+		}
+	}
+
+	names := make([]string, 0, len(interpreters))
+	for program := range interpreters {
+		names = append(names, program)
+	}
+	sort.Strings(names)
+
+	for _, program := range names {
+		needed := interpreters[program]
+		code, err := execQuiet(ctx, c, []string{program, "--version"})
+		if err != nil {
+			return fmt.Errorf("failed to check %s: %w", needed, err)
+		}
+		if code != 0 {
+			return fmt.Errorf("%s is not available in image '%s' (%s not found)", needed, imageName, program)
+		}
+	}
+	return nil
+}
+
+// execQuiet runs a command in the container, discarding its output, and
+// returns its exit code
+func execQuiet(ctx context.Context, c jobContainer, cmd []string) (int, error) {
+	execCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.exec(execCtx, cmd, nil, "", io.Discard)
+}
@@ -0,0 +1,381 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gantry/internal/capture"
+	"gantry/internal/models"
+)
+
+// errPathNotFound is returned by jobContainer.copyFrom for missing paths
+var errPathNotFound = errors.New("path not found")
+
+// jobContainer is the long-lived container a job's steps are exec'd into,
+// whichever executor started it
+type jobContainer interface {
+	// exec runs cmd in dir with the KEY=value pairs of env added, writing
+	// its output to out, and returns its exit code. A running command may
+	// only stop once the container is killed.
+	exec(ctx context.Context, cmd, env []string, dir string, out io.Writer) (int, error)
+
+	// kill stops the container immediately, ending any running commands
+	kill()
+
+	// copyFrom returns a tar archive of src, or errPathNotFound
+	copyFrom(ctx context.Context, src string) (io.ReadCloser, error)
+
+	// copyTo extracts a tar archive into the existing directory dst
+	copyTo(ctx context.Context, dst string, archive io.Reader) error
+}
+
+// sigkillStatus is the exit status of a command killed with SIGKILL, as the
+// kernel's OOM killer does
+const sigkillStatus = 137
+
+// memoryLimiter is implemented by job containers that can tell whether the
+// kernel killed a command for exceeding their memory limit
+type memoryLimiter interface {
+	oomKilled(ctx context.Context) bool
+}
+
+// watchdogDelay is how long after a job's deadline its container is killed
+// if the job is still running
+const watchdogDelay = 30 * time.Second
+
+// stopGracePeriod is how long the commands of a cancelled job have to exit
+// before its container is killed
+const stopGracePeriod = 10 * time.Second
+
+// gracefulStopper is implemented by job containers that can be stopped
+// giving their commands time to exit, rather than killed outright
+type gracefulStopper interface {
+	// stop asks the container's commands to exit and stops the container,
+	// killing it once grace has passed
+	stop(grace time.Duration)
+}
+
+// signalCommands sends SIGTERM to every process of a container but its main
+// one. Engines only signal the main process on stop, which merely idles
+// while the steps run.
+func signalCommands(c jobContainer) {
+	_, _ = execQuiet(context.Background(), c, []string{"kill", "-TERM", "-1"})
+}
+
+// cgroupOOMKills counts the processes of a container the kernel has killed
+// for exceeding its memory limit, from its cgroup v2 memory.events
+func cgroupOOMKills(ctx context.Context, c jobContainer) int {
+	execCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var events strings.Builder
+	if code, err := c.exec(execCtx, []string{"cat", "/sys/fs/cgroup/memory.events"}, nil, "", &events); err != nil || code != 0 {
+		return 0
+	}
+	for _, line := range strings.Split(events.String(), "\n") {
+		if value, ok := strings.CutPrefix(line, "oom_kill "); ok {
+			kills, _ := strconv.Atoi(strings.TrimSpace(value))
+			return kills
+		}
+	}
+	return 0
+}
+
+// runSteps runs a job's steps one at a time in its container. Once a step
+// fails, only steps that run on failure (such as `if: always()` cleanup)
+// still run. Caches are saved once every step has succeeded.
+func runSteps(ctx context.Context, c jobContainer, req Request, timeout time.Duration, result *models.JobResult) error {
+	job := req.Job
+
+	// Past the job's deadline the container is killed outright, in case
+	// something it runs doesn't stop with the job's context
+	if deadline, ok := ctx.Deadline(); ok {
+		watchdog := time.AfterFunc(time.Until(deadline)+watchdogDelay, func() {
+			log.Printf("WARNING: job %s outlived its timeout of %s, killing its container", req.JobName, timeout)
+			c.kill()
+		})
+		defer watchdog.Stop()
+	}
+
+	output := capture.NewBuffer(req.MaxOutput)
+	var caches []models.Step // cache steps that missed their exact key
+	var failure error        // the first step failure; later steps only run if they run on failure
+
+	// The job's output is also streamed as it is written, if asked for
+	var jobOut io.Writer = output
+	if req.Output != nil {
+		jobOut = io.MultiWriter(output, req.Output)
+	}
+
+	// Declared artifacts are collected however the steps ended, before the
+	// container is removed
+	if len(job.Artifacts) > 0 {
+		defer func() {
+			collectArtifacts(ctx, c, req, jobOut)
+			recordOutput(result, output)
+		}()
+	}
+
+	if job.Checkout != nil {
+		if err := checkout(ctx, c, job.Checkout, jobOut); err != nil {
+			fmt.Fprintf(jobOut, "=== Checkout failed: %v ===\n", err)
+			recordOutput(result, output)
+			return jobError(ctx, timeout, fmt.Errorf("checkout failed: %w", err))
+		}
+	}
+	for i, step := range job.Steps {
+		if req.ResolveStep != nil {
+			resolved, err := req.ResolveStep(i, result.Steps)
+			if err != nil {
+				message := fmt.Sprintf("=== Failed to resolve step '%s': %v ===\n", step.Name, err)
+				_, _ = io.WriteString(jobOut, message)
+				now := time.Now()
+				result.Steps = append(result.Steps, models.StepResult{StartedAt: now, EndedAt: now, Output: message})
+				if failure == nil {
+					failure = fmt.Errorf("step '%s': %w", step.Name, err)
+				}
+				continue
+			}
+			step = resolved
+		}
+		if !step.RunsAfter(failure != nil) {
+			fmt.Fprintf(jobOut, "=== [ %s ] Skipping: %s ===\n", time.Now().Format("2006-01-02 15:04:05"), step.Name)
+			result.Steps = append(result.Steps, models.StepResult{Skipped: true})
+			continue
+		}
+
+		path := outputPath(i)
+		step.Env = mergeEnv(step.Env, map[string]string{OutputEnv: path})
+		step.WorkingDirectory = workingDir(job.WorkingDirectory, step.WorkingDirectory)
+
+		// Each step's output is also kept on its own, so the run can show
+		// what the failing step printed
+		stepOutput := capture.NewBuffer(req.MaxOutput)
+		out := io.MultiWriter(jobOut, stepOutput)
+
+		var stepResult models.StepResult
+		var err error
+		if step.IsBuiltin() {
+			stepResult, err = runBuiltinStep(ctx, c, req, step, out)
+			if step.Cache != nil && err == nil && stepResult.Outputs[CacheHitOutput] != "true" {
+				caches = append(caches, step)
+			}
+		} else {
+			stepResult, err = runStep(ctx, c, step, req.MaxOutput, out)
+			if outputs, outputErr := readOutputs(c, path); outputErr != nil {
+				fmt.Fprintf(out, "=== Failed to collect outputs of '%s': %v ===\n", step.Name, outputErr)
+			} else {
+				stepResult.Outputs = outputs
+			}
+		}
+		stepResult.Output = stepOutput.String()
+		result.Steps = append(result.Steps, stepResult)
+		if err == nil {
+			continue
+		}
+		// A timed-out step has killed the container, so later steps can't run
+		if stepResult.TimedOut || ctx.Err() != nil {
+			recordOutput(result, output)
+			return jobError(ctx, timeout, err)
+		}
+		if step.ContinueOnError {
+			fmt.Fprintf(jobOut, "=== Continuing after failed step '%s': %v ===\n", step.Name, err)
+			continue
+		}
+		if failure == nil {
+			failure = err
+		}
+	}
+
+	if failure != nil {
+		recordOutput(result, output)
+		return jobError(ctx, timeout, failure)
+	}
+
+	// Caches are only saved by jobs that succeeded, so a broken dependency
+	// folder is never stored. Failing to save doesn't fail the job.
+	if req.Cache != nil {
+		for _, step := range caches {
+			entry, err := saveCache(ctx, c, req, step)
+			if err != nil {
+				fmt.Fprintf(jobOut, "=== Failed to save cache '%s': %v ===\n", step.Cache.Key, err)
+				continue
+			}
+			fmt.Fprintf(jobOut, "=== Saved cache '%s' (%d bytes) ===\n", entry.Key, entry.Size)
+		}
+	}
+
+	recordOutput(result, output)
+	return nil
+}
+
+// recordOutput sets a job's output on its result, with the size it had
+// before any was left out
+func recordOutput(result *models.JobResult, output *capture.Buffer) {
+	result.Output = output.String()
+	result.OutputSize = output.Size()
+}
+
+// runStep runs a step, retrying it as its retry policy allows. Timeouts are
+// never retried since they kill the job container. Each attempt's output is
+// kept up to maxOutput bytes.
+func runStep(ctx context.Context, c jobContainer, step models.Step, maxOutput int64, out io.Writer) (models.StepResult, error) {
+	maxAttempts := step.Retry.MaxAttempts()
+	if maxAttempts == 1 {
+		return runAttempt(ctx, c, step, out)
+	}
+
+	var result models.StepResult
+	for attempt := 1; ; attempt++ {
+		attemptOut := capture.NewBuffer(maxOutput)
+		attemptResult, err := runAttempt(ctx, c, step, io.MultiWriter(out, attemptOut))
+		if attempt == 1 {
+			result.StartedAt = attemptResult.StartedAt
+		}
+		result.EndedAt = attemptResult.EndedAt
+		result.Success = attemptResult.Success
+		result.TimedOut = attemptResult.TimedOut
+		result.OutOfMemory = attemptResult.OutOfMemory
+		result.ExitCode = attemptResult.ExitCode
+		result.Attempts = append(result.Attempts, models.StepAttempt{
+			Success:   attemptResult.Success,
+			StartedAt: attemptResult.StartedAt,
+			EndedAt:   attemptResult.EndedAt,
+			Output:    attemptOut.String(),
+			ExitCode:  attemptResult.ExitCode,
+		})
+
+		if err == nil || attempt == maxAttempts || result.TimedOut || ctx.Err() != nil {
+			return result, err
+		}
+
+		_, _ = fmt.Fprintf(out, "=== [ %s ] Retrying: %s (attempt %d of %d): %v ===\n",
+			time.Now().Format("2006-01-02 15:04:05"), step.Name, attempt+1, maxAttempts, err)
+		select {
+		case <-time.After(step.Retry.DelayDuration()):
+		case <-ctx.Done():
+			return result, err
+		}
+	}
+}
+
+// runAttempt execs a single step in the job container, writing its output
+func runAttempt(ctx context.Context, c jobContainer, step models.Step, out io.Writer) (models.StepResult, error) {
+	stepCtx := ctx
+	if step.TimeoutMinutes > 0 {
+		var cancel context.CancelFunc
+		stepCtx, cancel = context.WithTimeout(ctx, minutes(step.TimeoutMinutes))
+		defer cancel()
+	}
+
+	result := models.StepResult{StartedAt: time.Now()}
+	finish := func(err error) (models.StepResult, error) {
+		result.EndedAt = time.Now()
+		result.Success = err == nil
+		result.TimedOut = err != nil && errors.Is(stepCtx.Err(), context.DeadlineExceeded)
+		return result, err
+	}
+
+	_, _ = fmt.Fprintf(out, "=== [ %s ] Starting: %s ===\n", time.Now().Format("2006-01-02 15:04:05"), step.Name)
+
+	type exited struct {
+		code int
+		err  error
+	}
+	done := make(chan exited, 1)
+	go func() {
+		code, err := c.exec(stepCtx, stepCommand(step), envList(step.Env), step.WorkingDirectory, out)
+		done <- exited{code, err}
+	}()
+
+	var exit exited
+	select {
+	case <-stepCtx.Done():
+		// A running exec can't be stopped on its own, so stop the container;
+		// that also ends the output stream. A cancelled job's commands get
+		// time to exit, as they may be cleaning up.
+		if stopper, ok := c.(gracefulStopper); ok && errors.Is(ctx.Err(), context.Canceled) {
+			stopper.stop(stopGracePeriod)
+		} else {
+			c.kill()
+		}
+		<-done
+		_, _ = fmt.Fprintf(out, "=== [ %s ] Stopped: %s ===\n", time.Now().Format("2006-01-02 15:04:05"), step.Name)
+		return finish(stepError(ctx, stepCtx, step, stepCtx.Err()))
+	case exit = <-done:
+	}
+
+	if exit.err != nil {
+		return finish(stepError(ctx, stepCtx, step, fmt.Errorf("step '%s': %w", step.Name, exit.err)))
+	}
+	if exit.code != 0 {
+		result.ExitCode = exit.code
+		if m, ok := c.(memoryLimiter); ok && exit.code == sigkillStatus && m.oomKilled(ctx) {
+			result.OutOfMemory = true
+			_, _ = fmt.Fprintf(out, "=== [ %s ] Out of memory: %s was killed for exceeding the job's memory limit ===\n",
+				time.Now().Format("2006-01-02 15:04:05"), step.Name)
+			return finish(fmt.Errorf("step '%s' was killed for exceeding the job's memory limit: %w", step.Name, ErrOutOfMemory))
+		}
+		return finish(fmt.Errorf("step '%s' exited with status %d", step.Name, exit.code))
+	}
+
+	_, _ = fmt.Fprintf(out, "=== [ %s ] Completed: %s ===\n", time.Now().Format("2006-01-02 15:04:05"), step.Name)
+	return finish(nil)
+}
+
+// stepError explains why a step stopped. Only the step's own deadline is
+// reported here; the job's deadline is reported by jobError.
+func stepError(jobCtx, stepCtx context.Context, step models.Step, err error) error {
+	if jobCtx.Err() == nil && errors.Is(stepCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("step '%s' exceeded its timeout of %s: %w", step.Name, minutes(step.TimeoutMinutes), ErrTimeout)
+	}
+	return err
+}
+
+// jobError reports the job's deadline or cancellation in place of err
+func jobError(ctx context.Context, timeout time.Duration, err error) error {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return fmt.Errorf("job exceeded its timeout of %s: %w", timeout, ErrTimeout)
+	case context.Canceled:
+		return fmt.Errorf("job cancelled: %w", ctx.Err())
+	}
+	return err
+}
+
+// jobTimeout is how long a job may run: its timeout-minutes, or
+// DefaultJobTimeout, capped at the request's MaxRuntime
+func jobTimeout(req Request) time.Duration {
+	timeout := DefaultJobTimeout
+	if req.Job.TimeoutMinutes > 0 {
+		timeout = minutes(req.Job.TimeoutMinutes)
+	}
+	if req.MaxRuntime > 0 && req.MaxRuntime < timeout {
+		timeout = req.MaxRuntime
+	}
+	return timeout
+}
+
+// minutes converts a timeout-minutes value to a duration
+func minutes(m float64) time.Duration {
+	return time.Duration(m * float64(time.Minute))
+}
+
+// syncWriter serializes writes from concurrently copied streams
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
@@ -0,0 +1,71 @@
+package executor
+
+import (
+	"fmt"
+	"strconv"
+
+	"gantry/internal/models"
+
+	"github.com/docker/docker/api/types/container"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// applyStorage sets a job container's disk limit and tmpfs mounts. A job's
+// tmpfs replaces any hardening put at the same path.
+func applyStorage(hostConfig *container.HostConfig, job models.Job) error {
+	disk, err := job.Resources.DiskBytes()
+	if err != nil {
+		return err
+	}
+	if disk > 0 {
+		// Only some storage drivers can limit a container's writable layer,
+		// such as overlay2 on xfs mounted with pquota
+		hostConfig.StorageOpt = map[string]string{"size": strconv.FormatInt(disk, 10)}
+	}
+
+	for _, spec := range job.Tmpfs {
+		t, err := models.ParseTmpfs(spec)
+		if err != nil {
+			return err
+		}
+		if hostConfig.Tmpfs == nil {
+			hostConfig.Tmpfs = make(map[string]string)
+		}
+		// As with hardening, steps must be able to run what they build there
+		options := "rw,exec"
+		if t.Size > 0 {
+			options += fmt.Sprintf(",size=%d", t.Size)
+		}
+		hostConfig.Tmpfs[t.Path] = options
+	}
+	return nil
+}
+
+// podTmpfs mounts a job's tmpfs in a pod's job container as memory-backed
+// emptyDirs, returning the volumes they need. Mounts already at their paths
+// are replaced.
+func podTmpfs(c *corev1.Container, specs []string) ([]corev1.Volume, error) {
+	var volumes []corev1.Volume
+	for i, spec := range specs {
+		t, err := models.ParseTmpfs(spec)
+		if err != nil {
+			return nil, err
+		}
+		source := &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory}
+		if t.Size > 0 {
+			source.SizeLimit = resource.NewQuantity(t.Size, resource.BinarySI)
+		}
+		name := fmt.Sprintf("tmpfs-%d", i)
+		volumes = append(volumes, corev1.Volume{Name: name, VolumeSource: corev1.VolumeSource{EmptyDir: source}})
+
+		mounts := c.VolumeMounts[:0]
+		for _, m := range c.VolumeMounts {
+			if m.MountPath != t.Path {
+				mounts = append(mounts, m)
+			}
+		}
+		c.VolumeMounts = append(mounts, corev1.VolumeMount{Name: name, MountPath: t.Path})
+	}
+	return volumes, nil
+}
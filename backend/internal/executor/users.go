@@ -0,0 +1,61 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// jobUser returns the user a job's container runs as: the job's user, its
+// container's, or the server's default. Empty runs as the image's user.
+func jobUser(req Request) string {
+	if req.Job.User != "" {
+		return req.Job.User
+	}
+	if c := req.Job.Container; c != nil && c.User != "" {
+		return c.User
+	}
+	return req.DefaultUser
+}
+
+// chown hands paths in the container to the user the job runs as, exec'ing
+// chown as root, so steps can write to the workspace and to files copied in.
+// It is best effort: without CAP_CHOWN the files stay as they are.
+func (c *dockerContainer) chown(ctx context.Context, recursive bool, paths ...string) {
+	if c.user == "" || len(paths) == 0 {
+		return
+	}
+	cmd := []string{"chown"}
+	if recursive {
+		cmd = append(cmd, "-R")
+	}
+	cmd = append(append(cmd, c.user), paths...)
+
+	var out strings.Builder
+	code, err := c.execAs(ctx, "0", cmd, &out)
+	if err == nil && code != 0 {
+		err = fmt.Errorf("chown exited with status %d: %s", code, strings.TrimSpace(out.String()))
+	}
+	if err != nil {
+		log.Printf("WARNING: failed to give %s to user %s: %v", strings.Join(paths, ", "), c.user, err)
+	}
+}
+
+// execAs runs a command in the container as user, rather than the
+// container's own user
+func (c *dockerContainer) execAs(ctx context.Context, user string, cmd []string, out io.Writer) (int, error) {
+	exec, err := c.client.ContainerExecCreate(ctx, c.id, container.ExecOptions{
+		Cmd:          cmd,
+		User:         user,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create exec: %w", err)
+	}
+	return c.attachExec(ctx, exec.ID, out)
+}
@@ -0,0 +1,157 @@
+package executor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"gantry/internal/models"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/volume"
+)
+
+// cacheKeyLabel marks a cache volume with the key it is kept under. Cache
+// volumes carry no run label, so orphan cleanup leaves them alone.
+const cacheKeyLabel = "gantry.cache_key"
+
+// cacheUsage remembers when each cache volume was last mounted, for picking
+// which to evict. Volumes not used since the server started fall back to
+// when they were created.
+type cacheUsage struct {
+	mu       sync.Mutex
+	lastUsed map[string]time.Time // by volume name
+}
+
+// touch records that a volume is being used now
+func (u *cacheUsage) touch(name string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.lastUsed == nil {
+		u.lastUsed = make(map[string]time.Time)
+	}
+	u.lastUsed[name] = time.Now()
+}
+
+// since returns when a volume was last used, or created if it hasn't been
+func (u *cacheUsage) since(v *volume.Volume) time.Time {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if used, ok := u.lastUsed[v.Name]; ok {
+		return used
+	}
+	created, _ := time.Parse(time.RFC3339, v.CreatedAt)
+	return created
+}
+
+// forget drops an evicted volume
+func (u *cacheUsage) forget(name string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	delete(u.lastUsed, name)
+}
+
+// cacheVolumeName names the volume kept for a cache key. Keys may hold any
+// characters, so the name is a readable prefix of the key plus its hash.
+func cacheVolumeName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	prefix := key
+	if len(prefix) > 40 {
+		prefix = prefix[:40]
+	}
+	return resourceName("gantry-cache", prefix, hex.EncodeToString(sum[:6]))
+}
+
+// cacheTarget resolves where a cache volume is mounted; relative paths are
+// inside the workspace
+func cacheTarget(p string) string {
+	if path.IsAbs(p) {
+		return path.Clean(p)
+	}
+	return path.Join(Workspace, p)
+}
+
+// mountCacheVolumes creates the job's cache volumes that don't exist yet and
+// returns the binds mounting them
+func (e *DockerExecutor) mountCacheVolumes(volumes []models.CacheVolume) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	binds := make([]string, 0, len(volumes))
+	for _, v := range volumes {
+		name := cacheVolumeName(v.Key)
+		// Creating a volume that exists returns it unchanged
+		_, err := e.client.VolumeCreate(ctx, volume.CreateOptions{
+			Name:   name,
+			Labels: map[string]string{cacheKeyLabel: v.Key},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cache volume '%s': %w", v.Key, err)
+		}
+		e.cacheUsage.touch(name)
+		binds = append(binds, name+":"+cacheTarget(v.Path))
+	}
+	return binds, nil
+}
+
+// releaseCacheVolumes records that a finished job used its cache volumes,
+// then evicts others if they have outgrown budget
+func (e *DockerExecutor) releaseCacheVolumes(volumes []models.CacheVolume, budget int64) {
+	for _, v := range volumes {
+		e.cacheUsage.touch(cacheVolumeName(v.Key))
+	}
+	if budget > 0 {
+		e.evictCacheVolumes(budget)
+	}
+}
+
+// evictCacheVolumes removes the least recently used cache volumes until
+// those left fit within budget bytes. Volumes in use, or just mounted, are
+// never removed, even if that leaves the total over budget.
+func (e *DockerExecutor) evictCacheVolumes(budget int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	usage, err := e.client.DiskUsage(ctx, types.DiskUsageOptions{Types: []types.DiskUsageObject{types.VolumeObject}})
+	if err != nil {
+		log.Printf("WARNING: failed to measure cache volumes: %v", err)
+		return
+	}
+
+	var caches []*volume.Volume
+	var total int64
+	for _, v := range usage.Volumes {
+		if _, ok := v.Labels[cacheKeyLabel]; !ok || v.UsageData == nil || v.UsageData.Size < 0 {
+			continue
+		}
+		caches = append(caches, v)
+		total += v.UsageData.Size
+	}
+	sort.Slice(caches, func(i, j int) bool {
+		return e.cacheUsage.since(caches[i]).Before(e.cacheUsage.since(caches[j]))
+	})
+
+	for _, v := range caches {
+		if total <= budget {
+			return
+		}
+		// A volume just mounted may not be attached to its container yet
+		if v.UsageData.RefCount > 0 || time.Since(e.cacheUsage.since(v)) < time.Minute {
+			continue
+		}
+		if err := e.client.VolumeRemove(ctx, v.Name, false); err != nil && !cerrdefs.IsNotFound(err) {
+			log.Printf("WARNING: failed to evict cache volume %s: %v", v.Name, err)
+			continue
+		}
+		e.cacheUsage.forget(v.Name)
+		total -= v.UsageData.Size
+		log.Printf("Evicted cache volume '%s' (%d bytes)", v.Labels[cacheKeyLabel], v.UsageData.Size)
+	}
+}
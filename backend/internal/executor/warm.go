@@ -0,0 +1,182 @@
+package executor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+)
+
+// warmLabel marks containers started ahead of the jobs that will take them,
+// with the key of the configuration they were created with
+const warmLabel = "gantry.warm"
+
+// EnableWarmPool keeps up to size idle containers for each configuration
+// jobs have run with, removing those that stay idle for longer than ttl.
+// Containers of jobs with services, cache volumes or GPUs, or sharing a
+// run's workspace, are never kept warm.
+func (e *DockerExecutor) EnableWarmPool(size int, ttl time.Duration) {
+	if size <= 0 || e.warm != nil {
+		return
+	}
+	e.warm = newWarmPool(size, ttl)
+	go e.reapWarm()
+}
+
+// warmable reports whether a job can take a warm container: one whose
+// container depends on nothing but the job's own configuration
+func warmable(req Request) bool {
+	job := req.Job
+	return len(job.Services) == 0 && len(job.CacheVolumes) == 0 && job.GPUs == "" && !sharesWorkspace(req)
+}
+
+// takeWarm returns a started container for a job, taking an idle one created
+// with the same configuration or else starting one, and starts another in
+// the background for the next job. The job's env and labels differ from run
+// to run, so warm containers are created without them: the job's env is
+// set on every exec instead. The container joins the job's network once
+// taken.
+func (e *DockerExecutor) takeWarm(config *container.Config, hostConfig *container.HostConfig) (string, error) {
+	warmConfig := *config
+	warmConfig.Env = nil
+	warmConfig.Labels = nil
+	warmHostConfig := *hostConfig
+	warmHostConfig.NetworkMode = ""
+
+	key, err := e.warmKey(&warmConfig, &warmHostConfig)
+	if err != nil {
+		return "", err
+	}
+	warmConfig.Labels = map[string]string{warmLabel: key}
+
+	id, ok := e.warm.take(key)
+	if !ok {
+		if id, err = e.startWarm(&warmConfig, &warmHostConfig); err != nil {
+			return "", err
+		}
+	}
+	go e.fillWarm(key, &warmConfig, &warmHostConfig)
+
+	if hostConfig.NetworkMode != "" {
+		if err := e.joinNetwork(id, string(hostConfig.NetworkMode)); err != nil {
+			e.removeWarm(id)
+			return "", err
+		}
+	}
+	return id, nil
+}
+
+// warmKey identifies the configuration of a warm container, including the
+// image it was created from, so an image pulled anew isn't served by
+// containers of the old one
+func (e *DockerExecutor) warmKey(config *container.Config, hostConfig *container.HostConfig) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	img, err := e.client.ImageInspect(ctx, config.Image)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect image %s: %w", config.Image, err)
+	}
+
+	data, err := json.Marshal(struct {
+		ImageID    string
+		Config     *container.Config
+		HostConfig *container.HostConfig
+	}{img.ID, config, hostConfig})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16]), nil
+}
+
+// startWarm creates and starts a container for the pool
+func (e *DockerExecutor) startWarm(config *container.Config, hostConfig *container.HostConfig) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	resp, err := e.client.ContainerCreate(ctx, config, hostConfig, nil, nil, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to create container: %w", err)
+	}
+	e.warm.own(resp.ID)
+	if err := e.client.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		e.removeWarm(resp.ID)
+		return "", fmt.Errorf("failed to start container: %w", err)
+	}
+	return resp.ID, nil
+}
+
+// fillWarm starts containers until the pool holds its size of idle ones
+// for key
+func (e *DockerExecutor) fillWarm(key string, config *container.Config, hostConfig *container.HostConfig) {
+	for e.warm.reserve(key) {
+		id, err := e.startWarm(config, hostConfig)
+		if err != nil {
+			e.warm.unreserve(key)
+			log.Printf("WARNING: failed to start warm container: %v", err)
+			return
+		}
+		if !e.warm.add(key, id) {
+			e.removeWarm(id)
+			return
+		}
+	}
+}
+
+// joinNetwork moves a started container from the engine's default network
+// onto name
+func (e *DockerExecutor) joinNetwork(id, name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	if err := e.client.NetworkConnect(ctx, name, id, &network.EndpointSettings{}); err != nil {
+		return fmt.Errorf("failed to connect container to network %s: %w", name, err)
+	}
+	info, err := e.client.ContainerInspect(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to inspect container: %w", err)
+	}
+	if info.NetworkSettings == nil {
+		return nil
+	}
+	for other := range info.NetworkSettings.Networks {
+		if other == name {
+			continue
+		}
+		if err := e.client.NetworkDisconnect(ctx, other, id, true); err != nil {
+			return fmt.Errorf("failed to disconnect container from network %s: %w", other, err)
+		}
+	}
+	return nil
+}
+
+// removeWarm removes a container of the pool, idle or taken
+func (e *DockerExecutor) removeWarm(id string) {
+	e.cleanupContainer(id)
+	e.warm.disown(id)
+}
+
+// reapWarm removes containers that stayed idle for too long, until the
+// pool is closed
+func (e *DockerExecutor) reapWarm() {
+	ticker := time.NewTicker(e.warm.reapInterval())
+	defer ticker.Stop()
+	e.warm.reap(ticker.C, e.removeWarm)
+}
+
+// closeWarm stops the reaper and removes the idle containers. Containers
+// taken by jobs are removed when those jobs finish.
+func (e *DockerExecutor) closeWarm() {
+	if e.warm == nil {
+		return
+	}
+	for _, id := range e.warm.close() {
+		e.removeWarm(id)
+	}
+}
@@ -0,0 +1,178 @@
+package executor
+
+import (
+	"sync"
+	"time"
+)
+
+// warmPool keeps idle job containers started ahead of the jobs that will
+// take them, so a job doesn't wait for its container to be created and
+// started. Each container serves one job and is removed with it; taking one
+// starts a replacement, and those left idle past ttl are removed.
+type warmPool struct {
+	size int           // idle containers kept per configuration
+	ttl  time.Duration // how long a container may stay idle
+
+	mu      sync.Mutex
+	idle    map[string][]warmContainer // by configuration key, oldest first
+	filling map[string]int             // containers being started, by key
+	owned   map[string]bool            // containers of the pool that still exist, idle or taken
+	closed  bool
+	stop    chan struct{}
+}
+
+// warmContainer is an idle container of the pool
+type warmContainer struct {
+	id      string
+	started time.Time
+}
+
+// newWarmPool creates an empty pool keeping size idle containers per
+// configuration for up to ttl
+func newWarmPool(size int, ttl time.Duration) *warmPool {
+	return &warmPool{
+		size:    size,
+		ttl:     ttl,
+		idle:    make(map[string][]warmContainer),
+		filling: make(map[string]int),
+		owned:   make(map[string]bool),
+		stop:    make(chan struct{}),
+	}
+}
+
+// take removes the newest idle container for key from the pool
+func (p *warmPool) take(key string) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	idle := p.idle[key]
+	if len(idle) == 0 {
+		return "", false
+	}
+	c := idle[len(idle)-1]
+	p.idle[key] = idle[:len(idle)-1]
+	return c.id, true
+}
+
+// reserve claims a place for a container being started for key, unless
+// the pool has enough already
+func (p *warmPool) reserve(key string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed || len(p.idle[key])+p.filling[key] >= p.size {
+		return false
+	}
+	p.filling[key]++
+	return true
+}
+
+// unreserve gives up a place claimed with reserve
+func (p *warmPool) unreserve(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.filling[key]--
+}
+
+// add puts a container started for a reserved place into the pool,
+// reporting false when the pool closed meanwhile
+func (p *warmPool) add(key, id string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.filling[key]--
+	if p.closed {
+		return false
+	}
+	p.idle[key] = append(p.idle[key], warmContainer{id: id, started: time.Now()})
+	return true
+}
+
+// expired removes the containers idle since before ttl from the pool
+func (p *warmPool) expired(now time.Time) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var ids []string
+	for key, idle := range p.idle {
+		kept := idle[:0]
+		for _, c := range idle {
+			if now.Sub(c.started) > p.ttl {
+				ids = append(ids, c.id)
+			} else {
+				kept = append(kept, c)
+			}
+		}
+		if len(kept) == 0 {
+			delete(p.idle, key)
+		} else {
+			p.idle[key] = kept
+		}
+	}
+	return ids
+}
+
+// close empties the pool, returning its idle containers
+func (p *warmPool) close() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	close(p.stop)
+
+	var ids []string
+	for _, idle := range p.idle {
+		for _, c := range idle {
+			ids = append(ids, c.id)
+		}
+	}
+	p.idle = make(map[string][]warmContainer)
+	return ids
+}
+
+// own records a container the pool created
+func (p *warmPool) own(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.owned[id] = true
+}
+
+// disown forgets a container that was removed
+func (p *warmPool) disown(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.owned, id)
+}
+
+// owns reports whether a container belongs to the pool; a nil pool owns
+// none, so warm containers a previous server left behind are orphans
+func (p *warmPool) owns(id string) bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.owned[id]
+}
+
+// reapInterval is how often the pool looks for expired containers
+func (p *warmPool) reapInterval() time.Duration {
+	interval := p.ttl / 2
+	if interval < 10*time.Second {
+		interval = 10 * time.Second
+	}
+	return interval
+}
+
+// reap passes the containers expired at each tick to remove, until the
+// pool is closed
+func (p *warmPool) reap(tick <-chan time.Time, remove func(id string)) {
+	for {
+		select {
+		case <-p.stop:
+			return
+		case now := <-tick:
+			for _, id := range p.expired(now) {
+				remove(id)
+			}
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package executor
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// fillPool reserves and adds containers for key as fillWarm does
+func fillPool(t *testing.T, p *warmPool, key string, ids ...string) {
+	t.Helper()
+	for _, id := range ids {
+		if !p.reserve(key) {
+			t.Fatalf("Expected a place for %s", id)
+		}
+		if !p.add(key, id) {
+			t.Fatalf("Expected %s to be added", id)
+		}
+	}
+}
+
+func TestWarmPool_Take(t *testing.T) {
+	p := newWarmPool(2, time.Minute)
+	fillPool(t, p, "go", "c1", "c2")
+
+	// The newest container is taken first
+	for _, want := range []string{"c2", "c1"} {
+		if id, ok := p.take("go"); !ok || id != want {
+			t.Errorf("Expected to take %s, got %q (%v)", want, id, ok)
+		}
+	}
+	if id, ok := p.take("go"); ok {
+		t.Errorf("Expected the pool to be empty, took %s", id)
+	}
+	if id, ok := p.take("node"); ok {
+		t.Errorf("Expected no container for another configuration, took %s", id)
+	}
+}
+
+func TestWarmPool_Full(t *testing.T) {
+	p := newWarmPool(2, time.Minute)
+	fillPool(t, p, "go", "c1")
+
+	// A container being started counts towards the size
+	if !p.reserve("go") {
+		t.Fatal("Expected a second place")
+	}
+	if p.reserve("go") {
+		t.Error("Expected a full pool to refuse another place")
+	}
+	if !p.reserve("node") {
+		t.Error("Expected another configuration to have its own places")
+	}
+
+	p.unreserve("go")
+	if !p.reserve("go") {
+		t.Error("Expected a place given up to be free again")
+	}
+	if !p.add("go", "c2") {
+		t.Fatal("Expected c2 to be added")
+	}
+	if p.reserve("go") {
+		t.Error("Expected a full pool to refuse another place")
+	}
+
+	// Taking a container makes room for its replacement
+	p.take("go")
+	if !p.reserve("go") {
+		t.Error("Expected a place once a container was taken")
+	}
+}
+
+func TestWarmPool_Expired(t *testing.T) {
+	p := newWarmPool(3, time.Minute)
+	fillPool(t, p, "go", "c1", "c2")
+	fillPool(t, p, "node", "c3")
+
+	if ids := p.expired(time.Now()); len(ids) != 0 {
+		t.Errorf("Expected no container to have expired yet, got %v", ids)
+	}
+
+	// c4 is started after the others, so is still fresh when they expire
+	p.mu.Lock()
+	for i := range p.idle["go"] {
+		p.idle["go"][i].started = time.Now().Add(-2 * time.Minute)
+	}
+	p.idle["node"][0].started = time.Now().Add(-2 * time.Minute)
+	p.mu.Unlock()
+	fillPool(t, p, "go", "c4")
+
+	ids := p.expired(time.Now())
+	slices.Sort(ids)
+	if !slices.Equal(ids, []string{"c1", "c2", "c3"}) {
+		t.Errorf("Expected c1, c2 and c3 to have expired, got %v", ids)
+	}
+	if id, ok := p.take("go"); !ok || id != "c4" {
+		t.Errorf("Expected c4 to be kept, got %q (%v)", id, ok)
+	}
+	if _, ok := p.take("node"); ok {
+		t.Error("Expected the expired container not to be taken")
+	}
+	if _, ok := p.idle["node"]; ok {
+		t.Error("Expected a configuration left without containers to be forgotten")
+	}
+}
+
+func TestWarmPool_Reap(t *testing.T) {
+	p := newWarmPool(1, time.Minute)
+	fillPool(t, p, "go", "c1")
+
+	tick := make(chan time.Time)
+	removed := make(chan string, 1)
+	done := make(chan struct{})
+	go func() {
+		p.reap(tick, func(id string) { removed <- id })
+		close(done)
+	}()
+
+	tick <- time.Now()
+	tick <- time.Now().Add(2 * time.Minute)
+	if id := <-removed; id != "c1" {
+		t.Errorf("Expected c1 to be reaped, got %s", id)
+	}
+
+	p.close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the reaper to stop once the pool closed")
+	}
+}
+
+func TestWarmPool_Close(t *testing.T) {
+	p := newWarmPool(2, time.Minute)
+	fillPool(t, p, "go", "c1")
+	p.own("c1")
+	if !p.reserve("go") {
+		t.Fatal("Expected a second place")
+	}
+
+	if ids := p.close(); !slices.Equal(ids, []string{"c1"}) {
+		t.Errorf("Expected the idle container to be returned, got %v", ids)
+	}
+	if p.add("go", "c2") {
+		t.Error("Expected a container started while closing to be refused")
+	}
+	if p.reserve("go") {
+		t.Error("Expected a closed pool to refuse places")
+	}
+	if ids := p.close(); ids != nil {
+		t.Errorf("Expected closing twice to return nothing, got %v", ids)
+	}
+
+	// Containers are the pool's until removed
+	if !p.owns("c1") {
+		t.Error("Expected the pool to own c1 until it is removed")
+	}
+	p.disown("c1")
+	if p.owns("c1") {
+		t.Error("Expected the pool to forget a removed container")
+	}
+	var none *warmPool
+	if none.owns("c1") {
+		t.Error("Expected a nil pool to own nothing")
+	}
+}
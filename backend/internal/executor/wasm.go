@@ -0,0 +1,302 @@
+package executor
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gantry/internal/models"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// WasmExecutor runs steps as WASI modules with wazero, on the host but
+// without a container engine. Modules only see the job's workspace and
+// temporary directory, and only the environment the job sets. It is
+// experimental and suited to simple build and lint tasks.
+type WasmExecutor struct {
+	runtime    wazero.Runtime
+	modulesDir string // where modules named without a path are looked up
+
+	workspaces *ShellExecutor // keeps the workspaces runs share
+}
+
+// NewWasmExecutor creates a WASI executor. Modules a step names without a
+// path are looked up in modulesDir, if set.
+func NewWasmExecutor(modulesDir string) (*WasmExecutor, error) {
+	ctx := context.Background()
+	// Stopping a job must stop modules that never make a system call
+	config := wazero.NewRuntimeConfig().
+		WithCloseOnContextDone(true).
+		WithCompilationCache(wazero.NewCompilationCache())
+	r := wazero.NewRuntimeWithConfig(ctx, config)
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, r); err != nil {
+		_ = r.Close(ctx)
+		return nil, fmt.Errorf("failed to instantiate WASI: %w", err)
+	}
+
+	return &WasmExecutor{runtime: r, modulesDir: modulesDir, workspaces: NewShellExecutor()}, nil
+}
+
+// Execute runs a job's steps one at a time as WASI modules, in a temporary
+// workspace on the host. Jobs needing anything but modules and files, such
+// as a container, services or another shell, can't run.
+func (e *WasmExecutor) Execute(ctx context.Context, req Request) (*models.JobResult, error) {
+	job := req.Job
+	result := &models.JobResult{}
+
+	if err := checkWasmJob(req); err != nil {
+		return result, err
+	}
+
+	timeout := jobTimeout(req)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var workspace string
+	if sharesWorkspace(req) {
+		var err error
+		if workspace, err = e.workspaces.runWorkspace(req.RunID); err != nil {
+			return result, err
+		}
+	}
+
+	host, err := newHostContainer(req, workspace, nil)
+	if err != nil {
+		return result, err
+	}
+	defer host.remove()
+
+	c := &wasmContainer{
+		hostContainer: host,
+		executor:      e,
+		env:           envList(mergeEnv(job.Env, map[string]string{"TMPDIR": hostTmp})),
+	}
+	err = runSteps(ctx, c, req, timeout, result)
+	return result, err
+}
+
+// checkWasmJob rejects jobs relying on what only a container can give them
+func checkWasmJob(req Request) error {
+	job := req.Job
+	switch {
+	case job.Container != nil:
+		return fmt.Errorf("job '%s' sets a container, which the wasm executor can't run", req.JobName)
+	case len(job.Services) > 0:
+		return fmt.Errorf("job '%s' has services, which the wasm executor can't run", req.JobName)
+	case len(job.Volumes) > 0:
+		return fmt.Errorf("job '%s' mounts volumes, which the wasm executor can't run", req.JobName)
+	case job.GPUs != "":
+		return fmt.Errorf("job '%s' has gpus, which the wasm executor can't give it", req.JobName)
+	case job.Privileged:
+		return fmt.Errorf("job '%s' is privileged, which the wasm executor can't run", req.JobName)
+	case job.VMIsolated():
+		return fmt.Errorf("job '%s' has isolation vm, which the wasm executor can't run", req.JobName)
+	case job.User != "":
+		return fmt.Errorf("job '%s' sets a user, which the wasm executor can't run it as", req.JobName)
+	case len(job.Tmpfs) > 0:
+		return fmt.Errorf("job '%s' mounts tmpfs, which the wasm executor can't run", req.JobName)
+	case len(job.CacheVolumes) > 0:
+		return fmt.Errorf("job '%s' mounts cache volumes, which the wasm executor can't run", req.JobName)
+	}
+	for _, step := range job.Steps {
+		if step.Shell != "" && step.Shell != models.ShellSh {
+			return fmt.Errorf("step '%s' uses shell '%s'; the wasm executor runs modules, not shells", step.Name, step.Shell)
+		}
+		if step.Language != "" {
+			return fmt.Errorf("step '%s' uses language '%s'; the wasm executor runs modules, not interpreters", step.Name, step.Language)
+		}
+	}
+	return nil
+}
+
+// CleanupRun removes the workspace directory the jobs of a run shared
+func (e *WasmExecutor) CleanupRun(runID string) error {
+	return e.workspaces.CleanupRun(runID)
+}
+
+// Cleanup closes the runtime, stopping any module still running
+func (e *WasmExecutor) Cleanup() error {
+	return e.runtime.Close(context.Background())
+}
+
+// wasmContainer runs a job's steps as WASI modules, keeping its files in a
+// host container's directories. Each line of a step's script runs a module
+// with the arguments that follow it, split on whitespace; the script stops
+// at the first module that exits non-zero.
+type wasmContainer struct {
+	*hostContainer
+	executor *WasmExecutor
+	env      []string // the job's environment, without the host's
+}
+
+// exec runs a step's script, or one of the few commands the steps runner
+// itself runs. Other commands aren't found, as a shell would report them.
+func (c *wasmContainer) exec(ctx context.Context, cmd, env []string, dir string, out io.Writer) (int, error) {
+	if c.killed.Err() != nil {
+		return 0, fmt.Errorf("job was stopped")
+	}
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+	defer context.AfterFunc(c.killed, stop)()
+
+	if dir == "" {
+		dir = Workspace
+	}
+
+	switch {
+	case len(cmd) == 4 && cmd[0] == "/bin/sh" && cmd[2] == "-c":
+		return c.runScript(ctx, cmd[3], env, dir, out)
+	case len(cmd) == 3 && cmd[0] == "mkdir" && cmd[1] == "-p":
+		target, ok := c.jobPath(stepPath(dir, cmd[2]))
+		if !ok {
+			fmt.Fprintf(out, "mkdir: %s is outside the workspace\n", cmd[2])
+			return 1, nil
+		}
+		if err := os.MkdirAll(target, 0o755); err != nil {
+			fmt.Fprintf(out, "mkdir: %v\n", err)
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 127, nil
+}
+
+// runScript runs each module a script names in turn
+func (c *wasmContainer) runScript(ctx context.Context, script string, env []string, dir string, out io.Writer) (int, error) {
+	w := &syncWriter{w: out}
+	for _, line := range strings.Split(script, "\n") {
+		args := strings.Fields(line)
+		if len(args) == 0 || strings.HasPrefix(args[0], "#") {
+			continue
+		}
+		code, err := c.runModule(ctx, args, env, dir, w)
+		if err != nil || code != 0 {
+			return code, err
+		}
+	}
+	return 0, nil
+}
+
+// runModule runs one module to completion. The step's working directory is
+// mounted as the module's root, so relative paths resolve against it, with
+// the workspace and temporary directory mounted at their usual paths.
+func (c *wasmContainer) runModule(ctx context.Context, args, env []string, dir string, out io.Writer) (int, error) {
+	root, ok := c.jobPath(dir)
+	if !ok {
+		return 0, fmt.Errorf("working directory %s is outside the workspace", dir)
+	}
+	file, ok := c.modulePath(args[0], dir)
+	if !ok {
+		fmt.Fprintf(out, "%s: module not found\n", args[0])
+		return 127, nil
+	}
+	binary, err := os.ReadFile(file)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read module %s: %w", args[0], err)
+	}
+
+	r := c.executor.runtime
+	compiled, err := r.CompileModule(ctx, binary)
+	if err != nil {
+		fmt.Fprintf(out, "%s: invalid module: %v\n", args[0], err)
+		return 126, nil
+	}
+	defer compiled.Close(context.Background())
+
+	fsConfig := wazero.NewFSConfig().
+		WithDirMount(root, "/").
+		WithDirMount(c.hostPath(Workspace), Workspace).
+		WithDirMount(c.hostPath(hostTmp), hostTmp)
+	config := wazero.NewModuleConfig().
+		WithName("").
+		WithArgs(args...).
+		WithStdout(out).
+		WithStderr(out).
+		WithFSConfig(fsConfig).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithSysNanosleep().
+		WithRandSource(rand.Reader)
+	// Later variables replace earlier ones, so the step's env takes
+	// precedence over the job's
+	for _, kv := range append(append([]string(nil), c.env...), env...) {
+		key, value, _ := strings.Cut(kv, "=")
+		config = config.WithEnv(key, value)
+	}
+
+	mod, err := r.InstantiateModule(ctx, compiled, config)
+	if mod != nil {
+		_ = mod.Close(context.Background())
+	}
+	var exit *sys.ExitError
+	if errors.As(err, &exit) && ctx.Err() == nil {
+		return int(exit.ExitCode()), nil
+	}
+	if ctx.Err() != nil {
+		return 0, fmt.Errorf("module %s was stopped", args[0])
+	}
+	if err != nil {
+		// Traps such as unreachable code end the module as a crash would
+		fmt.Fprintf(out, "%s: %v\n", args[0], err)
+		return 134, nil
+	}
+	return 0, nil
+}
+
+// modulePath finds the module a script line names. A name without a slash
+// is looked up in the executor's modules directory, with or without the
+// .wasm extension; a path is resolved in the job like any other file.
+func (c *wasmContainer) modulePath(name, dir string) (string, bool) {
+	var candidates []string
+	if !strings.Contains(name, "/") && c.executor.modulesDir != "" {
+		for _, file := range []string{name, name + ".wasm"} {
+			candidates = append(candidates, filepath.Join(c.executor.modulesDir, file))
+		}
+	} else if file, ok := c.jobPath(stepPath(dir, name)); ok {
+		candidates = append(candidates, file)
+	}
+
+	for _, file := range candidates {
+		if info, err := os.Stat(file); err == nil && info.Mode().IsRegular() {
+			return file, true
+		}
+	}
+	return "", false
+}
+
+// jobPath maps a path of the job onto the host, refusing paths outside the
+// workspace and temporary directory, which modules can't see either
+func (c *wasmContainer) jobPath(p string) (string, bool) {
+	p = path.Clean(p)
+	for _, dir := range []string{Workspace, hostTmp} {
+		if p == dir || strings.HasPrefix(p, dir+"/") {
+			return c.hostPath(p), true
+		}
+	}
+	return "", false
+}
+
+// copyFrom archives a path in the job, which must be one modules can see
+func (c *wasmContainer) copyFrom(ctx context.Context, src string) (io.ReadCloser, error) {
+	if _, ok := c.jobPath(src); !ok {
+		return nil, errPathNotFound
+	}
+	return c.hostContainer.copyFrom(ctx, src)
+}
+
+// copyTo extracts an archive into a directory modules can see
+func (c *wasmContainer) copyTo(ctx context.Context, dst string, archive io.Reader) error {
+	if _, ok := c.jobPath(dst); !ok {
+		return fmt.Errorf("%s is outside the workspace", dst)
+	}
+	return c.hostContainer.copyTo(ctx, dst, archive)
+}
@@ -0,0 +1,178 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gantry/internal/models"
+)
+
+// wasiModule assembles a WASI command that writes message to stdout and
+// exits with code, which must be below 64. A spinning module never gets as
+// far, looping until it is stopped.
+func wasiModule(message string, code byte, spin bool) []byte {
+	uleb := func(n int) []byte {
+		var b []byte
+		for {
+			c := byte(n & 0x7f)
+			n >>= 7
+			if n == 0 {
+				return append(b, c)
+			}
+			b = append(b, c|0x80)
+		}
+	}
+	vec := func(items ...[]byte) []byte {
+		b := uleb(len(items))
+		for _, item := range items {
+			b = append(b, item...)
+		}
+		return b
+	}
+	name := func(s string) []byte { return append(uleb(len(s)), s...) }
+	section := func(id byte, content []byte) []byte {
+		return append(append([]byte{id}, uleb(len(content))...), content...)
+	}
+	cat := func(parts ...[]byte) []byte {
+		var b []byte
+		for _, p := range parts {
+			b = append(b, p...)
+		}
+		return b
+	}
+
+	// The iovec at 0 points at the message that follows it at 8
+	data := cat([]byte{8, 0, 0, 0, byte(len(message)), 0, 0, 0}, []byte(message))
+	body := []byte{
+		0x41, 1, // i32.const 1 (stdout)
+		0x41, 0, // i32.const 0 (iovs)
+		0x41, 1, // i32.const 1 (iovs_len)
+		0x41, 0x80, 0x08, // i32.const 1024 (nwritten)
+		0x10, 0, // call fd_write
+		0x1a,       // drop
+		0x41, code, // i32.const code
+		0x10, 1, // call proc_exit
+		0x0b, // end
+	}
+	if spin {
+		body = []byte{0x03, 0x40, 0x0c, 0, 0x0b, 0x0b} // loop br 0 end end
+	}
+	body = append([]byte{0}, body...) // no locals
+
+	return cat(
+		[]byte("\x00asm\x01\x00\x00\x00"),
+		section(1, vec(
+			[]byte{0x60, 4, 0x7f, 0x7f, 0x7f, 0x7f, 1, 0x7f}, // fd_write
+			[]byte{0x60, 1, 0x7f, 0},                         // proc_exit
+			[]byte{0x60, 0, 0},                               // _start
+		)),
+		section(2, vec(
+			cat(name("wasi_snapshot_preview1"), name("fd_write"), []byte{0, 0}),
+			cat(name("wasi_snapshot_preview1"), name("proc_exit"), []byte{0, 1}),
+		)),
+		section(3, vec([]byte{2})),
+		section(5, vec([]byte{0, 1})),
+		section(7, vec(
+			cat(name("memory"), []byte{2, 0}),
+			cat(name("_start"), []byte{0, 2}),
+		)),
+		section(10, vec(cat(uleb(len(body)), body))),
+		section(11, vec(cat([]byte{0, 0x41, 0, 0x0b}, uleb(len(data)), data))),
+	)
+}
+
+// newTestWasmExecutor returns an executor whose modules directory holds
+// hello, which prints a greeting, fail, which exits 3, and spin, which
+// never exits
+func newTestWasmExecutor(t *testing.T) *WasmExecutor {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, module := range map[string][]byte{
+		"hello": wasiModule("hello from wasm\n", 0, false),
+		"fail":  wasiModule("failing\n", 3, false),
+		"spin":  wasiModule("", 0, true),
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name+".wasm"), module, 0o644); err != nil {
+			t.Fatalf("Failed to write module: %v", err)
+		}
+	}
+	e, err := NewWasmExecutor(dir)
+	if err != nil {
+		t.Fatalf("Failed to create executor: %v", err)
+	}
+	t.Cleanup(func() { e.Cleanup() })
+	return e
+}
+
+func TestWasmExecutor_Execute(t *testing.T) {
+	e := newTestWasmExecutor(t)
+
+	result, err := e.Execute(context.Background(), Request{
+		JobName: "test",
+		Job:     models.Job{Steps: []models.Step{{Name: "Hello", Run: "hello\nhello"}}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to run job: %v", err)
+	}
+	if len(result.Steps) != 1 || !result.Steps[0].Success {
+		t.Fatalf("Expected the step to succeed, got %+v", result.Steps)
+	}
+	if got := strings.Count(result.Steps[0].Output, "hello from wasm\n"); got != 2 {
+		t.Errorf("Expected the modules' stdout twice, got %q", result.Steps[0].Output)
+	}
+}
+
+func TestWasmExecutor_ExitCode(t *testing.T) {
+	e := newTestWasmExecutor(t)
+
+	// The script stops at the first module that fails
+	result, err := e.Execute(context.Background(), Request{
+		JobName: "test",
+		Job:     models.Job{Steps: []models.Step{{Name: "Fail", Run: "fail\nhello"}}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "exited with status 3") {
+		t.Fatalf("Expected the step to exit with status 3, got %v", err)
+	}
+	if len(result.Steps) != 1 || result.Steps[0].ExitCode != 3 {
+		t.Fatalf("Expected the step's exit code to be recorded, got %+v", result.Steps)
+	}
+	if output := result.Steps[0].Output; !strings.Contains(output, "failing\n") || strings.Contains(output, "hello from wasm") {
+		t.Errorf("Expected only the failing module to run, got %q", output)
+	}
+
+	result, err = e.Execute(context.Background(), Request{
+		JobName: "test",
+		Job:     models.Job{Steps: []models.Step{{Name: "Missing", Run: "missing"}}},
+	})
+	if err == nil || result.Steps[0].ExitCode != 127 {
+		t.Errorf("Expected a missing module to exit with status 127, got %v", err)
+	}
+}
+
+func TestWasmExecutor_Timeout(t *testing.T) {
+	e := newTestWasmExecutor(t)
+
+	start := time.Now()
+	result, err := e.Execute(context.Background(), Request{
+		JobName: "test",
+		Job: models.Job{Steps: []models.Step{
+			{Name: "Spin", Run: "spin", TimeoutMinutes: 0.005},
+			{Name: "After", Run: "hello"},
+		}},
+	})
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Expected the step to time out, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the module to be stopped at its timeout, took %s", elapsed)
+	}
+	if len(result.Steps) != 1 || !result.Steps[0].TimedOut {
+		t.Errorf("Expected only the spinning step to run and time out, got %+v", result.Steps)
+	}
+}
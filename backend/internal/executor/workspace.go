@@ -0,0 +1,45 @@
+package executor
+
+import "gantry/internal/models"
+
+// workspaceName names the volume or directory the jobs of a run share as
+// their workspace
+func workspaceName(runID string) string {
+	return resourceName("gantry", runID, "workspace")
+}
+
+// runNetworkName names the network the containers of a run join
+func runNetworkName(runID string) string {
+	return resourceName("gantry", runID, "network")
+}
+
+// jobNetwork names the network a job's containers join, reporting whether
+// it is the job's own, created for its services and removed with them. The
+// jobs of a run share the run's network; a job outside a run only gets one
+// for its services, and otherwise stays on the engine's default network.
+func jobNetwork(req Request) (name string, owned bool) {
+	switch {
+	case req.RunID != "":
+		return runNetworkName(req.RunID), false
+	case len(req.Job.Services) > 0:
+		return resourceName("gantry", req.RunID, req.JobName), true
+	}
+	return "", false
+}
+
+// sharesWorkspace reports whether a job uses its run's shared workspace.
+// Jobs outside a run, and jobs mounting a volume of their own there, don't.
+func sharesWorkspace(req Request) bool {
+	return req.RunID != "" && !mountsWorkspace(req.Job.Volumes)
+}
+
+// mountsWorkspace reports whether one of a job's volumes is mounted at the
+// workspace
+func mountsWorkspace(volumes []string) bool {
+	for _, spec := range volumes {
+		if v, err := models.ParseVolume(spec); err == nil && v.Target == Workspace {
+			return true
+		}
+	}
+	return false
+}
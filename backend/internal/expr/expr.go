@@ -0,0 +1,520 @@
+// Package expr implements the expression language used in workflow
+// conditions and ${{ }} interpolation, modeled on the GitHub Actions syntax
+package expr
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Function is a callable available to expressions
+type Function func(args ...interface{}) (interface{}, error)
+
+// Context supplies the named contexts (needs, matrix, ...) and functions an
+// expression may reference
+type Context struct {
+	Values    map[string]interface{}
+	Functions map[string]Function
+}
+
+// statusFunctions change the implicit `success()` guard of a condition
+var statusFunctions = map[string]bool{
+	"success":   true,
+	"failure":   true,
+	"always":    true,
+	"cancelled": true,
+}
+
+// Expression is a parsed expression ready for evaluation
+type Expression struct {
+	source string
+	root   node
+}
+
+// Parse parses an expression, without the surrounding ${{ }}
+func Parse(source string) (*Expression, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression '%s': %w", source, err)
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression '%s': %w", source, err)
+	}
+	if p.peek().kind != tokEOF {
+		return nil, fmt.Errorf("invalid expression '%s': %w", source, p.errorf("unexpected token"))
+	}
+
+	return &Expression{source: source, root: root}, nil
+}
+
+// ParseCondition parses an `if:` condition, which may optionally be wrapped in ${{ }}
+func ParseCondition(condition string) (*Expression, error) {
+	return Parse(unwrap(condition))
+}
+
+// Evaluate evaluates the expression against a context
+func (e *Expression) Evaluate(ctx *Context) (interface{}, error) {
+	if ctx == nil {
+		ctx = &Context{}
+	}
+	value, err := e.root.eval(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate '%s': %w", e.source, err)
+	}
+	return value, nil
+}
+
+// UsesStatusFunction reports whether the expression calls success(),
+// failure(), always() or cancelled()
+func (e *Expression) UsesStatusFunction() bool {
+	found := false
+	walk(e.root, func(n node) {
+		if call, ok := n.(*callNode); ok && statusFunctions[call.name] {
+			found = true
+		}
+	})
+	return found
+}
+
+// UsesContext reports whether the expression reads from a named context
+func (e *Expression) UsesContext(context string) bool {
+	found := false
+	walk(e.root, func(n node) {
+		if c, ok := n.(*contextNode); ok && c.name == context {
+			found = true
+		}
+	})
+	return found
+}
+
+// References returns the property names the expression reads from a named
+// context, e.g. TOKEN for secrets.TOKEN or secrets['TOKEN']
+func (e *Expression) References(context string) []string {
+	var refs []string
+	walk(e.root, func(n node) {
+		switch v := n.(type) {
+		case *propertyNode:
+			if c, ok := v.target.(*contextNode); ok && c.name == context {
+				refs = append(refs, v.name)
+			}
+		case *indexNode:
+			if c, ok := v.target.(*contextNode); ok && c.name == context {
+				if lit, ok := v.index.(*literalNode); ok {
+					refs = append(refs, toString(lit.value))
+				}
+			}
+		}
+	})
+	return refs
+}
+
+// EvaluateCondition evaluates an `if:` condition. An empty condition is
+// treated as `success()`, and conditions that do not call a status function
+// are implicitly combined with `success()`.
+func EvaluateCondition(condition string, ctx *Context) (bool, error) {
+	if strings.TrimSpace(condition) == "" {
+		condition = "success()"
+	}
+
+	e, err := ParseCondition(condition)
+	if err != nil {
+		return false, err
+	}
+
+	if !e.UsesStatusFunction() {
+		e.root = &logicalNode{op: "&&", left: &callNode{name: "success"}, right: e.root}
+	}
+
+	value, err := e.Evaluate(ctx)
+	if err != nil {
+		return false, err
+	}
+	return Truthy(value), nil
+}
+
+// Truthy applies the expression language's truthiness rules
+func Truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0 && !math.IsNaN(v)
+	case string:
+		return v != ""
+	default:
+		return true
+	}
+}
+
+// unwrap strips a surrounding ${{ }} from a condition
+func unwrap(s string) string {
+	trimmed := strings.TrimSpace(s)
+	if strings.HasPrefix(trimmed, "${{") && strings.HasSuffix(trimmed, "}}") {
+		return strings.TrimSpace(trimmed[3 : len(trimmed)-2])
+	}
+	return trimmed
+}
+
+type node interface {
+	eval(ctx *Context) (interface{}, error)
+}
+
+// walk visits every node in the tree
+func walk(n node, visit func(node)) {
+	visit(n)
+	switch v := n.(type) {
+	case *logicalNode:
+		walk(v.left, visit)
+		walk(v.right, visit)
+	case *compareNode:
+		walk(v.left, visit)
+		walk(v.right, visit)
+	case *notNode:
+		walk(v.operand, visit)
+	case *propertyNode:
+		walk(v.target, visit)
+	case *indexNode:
+		walk(v.target, visit)
+		walk(v.index, visit)
+	case *wildcardNode:
+		walk(v.target, visit)
+	case *callNode:
+		for _, arg := range v.args {
+			walk(arg, visit)
+		}
+	}
+}
+
+type literalNode struct{ value interface{} }
+
+func (n *literalNode) eval(_ *Context) (interface{}, error) { return n.value, nil }
+
+type contextNode struct{ name string }
+
+func (n *contextNode) eval(ctx *Context) (interface{}, error) {
+	value, ok := ctx.Values[n.name]
+	if !ok {
+		return nil, fmt.Errorf("unknown context '%s'", n.name)
+	}
+	return normalize(value), nil
+}
+
+type propertyNode struct {
+	target node
+	name   string
+}
+
+func (n *propertyNode) eval(ctx *Context) (interface{}, error) {
+	target, err := n.target.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return property(target, n.name), nil
+}
+
+type indexNode struct {
+	target node
+	index  node
+}
+
+func (n *indexNode) eval(ctx *Context) (interface{}, error) {
+	target, err := n.target.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	index, err := n.index.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := target.(type) {
+	case []interface{}:
+		return element(t, index), nil
+	case filtered:
+		return element(t, index), nil
+	default:
+		return property(target, toString(index)), nil
+	}
+}
+
+// filtered is the result of a `.*` filter; property access on it is applied
+// to every element, dropping those without the property
+type filtered []interface{}
+
+func element(items []interface{}, index interface{}) interface{} {
+	i, ok := index.(float64)
+	if !ok || i < 0 || int(i) >= len(items) || i != math.Trunc(i) {
+		return nil
+	}
+	return normalize(items[int(i)])
+}
+
+// wildcardNode implements `.*` object and array filters
+type wildcardNode struct{ target node }
+
+func (n *wildcardNode) eval(ctx *Context) (interface{}, error) {
+	target, err := n.target.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := target.(type) {
+	case map[string]interface{}:
+		items := make(filtered, 0, len(t))
+		for _, key := range sortedKeys(t) {
+			items = append(items, normalize(t[key]))
+		}
+		return items, nil
+	case filtered:
+		return t, nil
+	case []interface{}:
+		return filtered(t), nil
+	default:
+		return filtered{}, nil
+	}
+}
+
+type notNode struct{ operand node }
+
+func (n *notNode) eval(ctx *Context) (interface{}, error) {
+	value, err := n.operand.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return !Truthy(value), nil
+}
+
+// logicalNode implements && and ||, which short-circuit and yield an operand
+type logicalNode struct {
+	op          string
+	left, right node
+}
+
+func (n *logicalNode) eval(ctx *Context) (interface{}, error) {
+	left, err := n.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if (n.op == "&&" && !Truthy(left)) || (n.op == "||" && Truthy(left)) {
+		return left, nil
+	}
+	return n.right.eval(ctx)
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n *compareNode) eval(ctx *Context) (interface{}, error) {
+	left, err := n.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	}
+
+	// Ordering compares strings case-insensitively and everything else numerically
+	if ls, ok := left.(string); ok {
+		if rs, ok := right.(string); ok {
+			c := strings.Compare(strings.ToLower(ls), strings.ToLower(rs))
+			return orderResult(n.op, float64(c), 0), nil
+		}
+	}
+	return orderResult(n.op, toNumber(left), toNumber(right)), nil
+}
+
+func orderResult(op string, l, r float64) bool {
+	if math.IsNaN(l) || math.IsNaN(r) {
+		return false
+	}
+	switch op {
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	default:
+		return l >= r
+	}
+}
+
+type callNode struct {
+	name string
+	args []node
+}
+
+func (n *callNode) eval(ctx *Context) (interface{}, error) {
+	fn, ok := ctx.Functions[n.name]
+	if !ok {
+		fn, ok = builtins[n.name]
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown function '%s'", n.name)
+	}
+
+	args := make([]interface{}, 0, len(n.args))
+	for _, arg := range n.args {
+		value, err := arg.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+	}
+
+	return fn(args...)
+}
+
+// equal implements loose equality: strings compare case-insensitively,
+// mismatched types are compared numerically
+func equal(left, right interface{}) bool {
+	switch l := left.(type) {
+	case string:
+		if r, ok := right.(string); ok {
+			return strings.EqualFold(l, r)
+		}
+	case nil:
+		if right == nil {
+			return true
+		}
+	case map[string]interface{}, []interface{}, filtered:
+		// Objects and arrays are only equal to themselves, which we can't
+		// observe after normalization, so treat them as unequal
+		return false
+	}
+
+	switch right.(type) {
+	case map[string]interface{}, []interface{}, filtered:
+		return false
+	}
+
+	l, r := toNumber(left), toNumber(right)
+	return !math.IsNaN(l) && !math.IsNaN(r) && l == r
+}
+
+func toNumber(value interface{}) float64 {
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	case float64:
+		return v
+	case string:
+		s := strings.TrimSpace(v)
+		if s == "" {
+			return 0
+		}
+		n, err := parseNumber(s)
+		if err != nil {
+			return math.NaN()
+		}
+		return n
+	default:
+		return math.NaN()
+	}
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+			return strconv.FormatInt(int64(v), 10)
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}, filtered:
+		return "Array"
+	default:
+		return "Object"
+	}
+}
+
+// property looks up a key case-insensitively, as the expression language does
+func property(target interface{}, name string) interface{} {
+	if items, ok := target.(filtered); ok {
+		values := make(filtered, 0, len(items))
+		for _, item := range items {
+			if value := property(item, name); value != nil {
+				values = append(values, value)
+			}
+		}
+		return values
+	}
+
+	obj, ok := target.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if value, ok := obj[name]; ok {
+		return normalize(value)
+	}
+	for key, value := range obj {
+		if strings.EqualFold(key, name) {
+			return normalize(value)
+		}
+	}
+	return nil
+}
+
+// normalize converts Go values supplied by callers into the handful of
+// types the evaluator works with
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, bool, float64, string, map[string]interface{}, []interface{}, filtered:
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case map[string]string:
+		obj := make(map[string]interface{}, len(v))
+		for k, s := range v {
+			obj[k] = s
+		}
+		return obj
+	case []string:
+		items := make([]interface{}, len(v))
+		for i, s := range v {
+			items[i] = s
+		}
+		return items
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,144 @@
+package expr
+
+import (
+	"strings"
+	"testing"
+)
+
+func testContext() *Context {
+	return &Context{
+		Values: map[string]interface{}{
+			"needs": map[string]interface{}{
+				"build": map[string]interface{}{"result": "success"},
+				"lint":  map[string]interface{}{"result": "failed"},
+			},
+			"matrix": map[string]string{"os": "alpine", "go-version": "1.21"},
+			"gantry": map[string]interface{}{"workflow": "CI", "attempt": 2},
+		},
+	}
+}
+
+func TestEvaluate_Expressions(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"true", true},
+		{"'it''s'", "it's"},
+		{"42", float64(42)},
+		{"-1.5", -1.5},
+		{"null", nil},
+		{"needs.build.result", "success"},
+		{"needs['lint'].result", "failed"},
+		{"needs.missing.result", nil},
+		{"matrix.go-version", "1.21"},
+		{"matrix.OS == 'ALPINE'", true},
+		{"needs.build.result != 'success'", false},
+		{"gantry.attempt > 1", true},
+		{"gantry.attempt >= '2'", true},
+		{"'abc' < 'abd'", true},
+		{"!true", false},
+		{"!(matrix.os == 'ubuntu')", true},
+		{"matrix.os == 'ubuntu' || matrix.os == 'alpine'", true},
+		{"matrix.os == 'alpine' && gantry.workflow", "CI"},
+		{"'' || 'fallback'", "fallback"},
+		{"needs.*.result[0]", "success"},
+	}
+
+	for _, tt := range tests {
+		e, err := Parse(tt.expression)
+		if err != nil {
+			t.Errorf("Parse(%q) returned error: %v", tt.expression, err)
+			continue
+		}
+
+		got, err := e.Evaluate(testContext())
+		if err != nil {
+			t.Errorf("Evaluate(%q) returned error: %v", tt.expression, err)
+			continue
+		}
+
+		if got != tt.expected {
+			t.Errorf("Evaluate(%q) = %v (%T), expected %v (%T)", tt.expression, got, got, tt.expected, tt.expected)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, expression := range []string{
+		"",
+		"needs.",
+		"'unterminated",
+		"a ==",
+		"(a",
+		"a b",
+		"success(",
+		"a = b",
+	} {
+		if _, err := Parse(expression); err == nil {
+			t.Errorf("Expected Parse(%q) to fail", expression)
+		}
+	}
+}
+
+func TestEvaluate_UnknownContext(t *testing.T) {
+	e, err := Parse("secrets.token")
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	if _, err := e.Evaluate(testContext()); err == nil || !strings.Contains(err.Error(), "unknown context") {
+		t.Errorf("Expected unknown context error, got %v", err)
+	}
+}
+
+func TestEvaluateCondition_ImplicitSuccess(t *testing.T) {
+	failed := testContext()
+	failed.Functions = map[string]Function{
+		"success": func(...interface{}) (interface{}, error) { return false, nil },
+		"failure": func(...interface{}) (interface{}, error) { return true, nil },
+	}
+
+	tests := []struct {
+		condition string
+		ctx       *Context
+		expected  bool
+	}{
+		{"", testContext(), true},
+		{"", failed, false},
+		{"matrix.os == 'alpine'", testContext(), true},
+		{"${{ matrix.os == 'alpine' }}", testContext(), true},
+		{"matrix.os == 'alpine'", failed, false},
+		{"always()", failed, true},
+		{"failure()", failed, true},
+		{"failure()", testContext(), false},
+		{"${{ always() && matrix.os == 'ubuntu' }}", failed, false},
+	}
+
+	for _, tt := range tests {
+		got, err := EvaluateCondition(tt.condition, tt.ctx)
+		if err != nil {
+			t.Errorf("EvaluateCondition(%q) returned error: %v", tt.condition, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("EvaluateCondition(%q) = %v, expected %v", tt.condition, got, tt.expected)
+		}
+	}
+}
+
+func TestTruthy(t *testing.T) {
+	falsy := []interface{}{nil, false, float64(0), ""}
+	for _, v := range falsy {
+		if Truthy(v) {
+			t.Errorf("Expected %v to be falsy", v)
+		}
+	}
+
+	truthy := []interface{}{true, float64(1), "false", map[string]interface{}{}, []interface{}{}}
+	for _, v := range truthy {
+		if !Truthy(v) {
+			t.Errorf("Expected %v to be truthy", v)
+		}
+	}
+}
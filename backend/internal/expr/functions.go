@@ -0,0 +1,156 @@
+package expr
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// builtins are available in every context. Status functions default to a
+// successful, uncancelled state unless the context overrides them.
+var builtins = map[string]Function{
+	"success":    func(...interface{}) (interface{}, error) { return true, nil },
+	"failure":    func(...interface{}) (interface{}, error) { return false, nil },
+	"always":     func(...interface{}) (interface{}, error) { return true, nil },
+	"cancelled":  func(...interface{}) (interface{}, error) { return false, nil },
+	"contains":   containsFn,
+	"startsWith": startsWithFn,
+	"endsWith":   endsWithFn,
+	"format":     formatFn,
+	"join":       joinFn,
+	"toJSON":     toJSONFn,
+	"fromJSON":   fromJSONFn,
+}
+
+func expectArgs(name string, args []interface{}, min, max int) error {
+	if len(args) < min || len(args) > max {
+		if min == max {
+			return fmt.Errorf("%s() expects %d arguments, got %d", name, min, len(args))
+		}
+		return fmt.Errorf("%s() expects %d to %d arguments, got %d", name, min, max, len(args))
+	}
+	return nil
+}
+
+// containsFn checks for a substring (case-insensitive) or an array element
+func containsFn(args ...interface{}) (interface{}, error) {
+	if err := expectArgs("contains", args, 2, 2); err != nil {
+		return nil, err
+	}
+
+	var items []interface{}
+	switch search := args[0].(type) {
+	case []interface{}:
+		items = search
+	case filtered:
+		items = search
+	default:
+		return strings.Contains(strings.ToLower(toString(search)), strings.ToLower(toString(args[1]))), nil
+	}
+
+	for _, item := range items {
+		if equal(item, args[1]) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func startsWithFn(args ...interface{}) (interface{}, error) {
+	if err := expectArgs("startsWith", args, 2, 2); err != nil {
+		return nil, err
+	}
+	return strings.HasPrefix(strings.ToLower(toString(args[0])), strings.ToLower(toString(args[1]))), nil
+}
+
+func endsWithFn(args ...interface{}) (interface{}, error) {
+	if err := expectArgs("endsWith", args, 2, 2); err != nil {
+		return nil, err
+	}
+	return strings.HasSuffix(strings.ToLower(toString(args[0])), strings.ToLower(toString(args[1]))), nil
+}
+
+// formatFn replaces {0}, {1}, ... with the remaining arguments; {{ and }}
+// escape literal braces
+func formatFn(args ...interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("format() expects at least 1 argument")
+	}
+
+	pattern := toString(args[0])
+	var sb strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '{' && i+1 < len(pattern) && pattern[i+1] == '{':
+			sb.WriteByte('{')
+			i++
+		case c == '}' && i+1 < len(pattern) && pattern[i+1] == '}':
+			sb.WriteByte('}')
+			i++
+		case c == '{':
+			end := strings.IndexByte(pattern[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("format() has an unclosed placeholder in '%s'", pattern)
+			}
+			var index int
+			if _, err := fmt.Sscanf(pattern[i+1:i+end], "%d", &index); err != nil || index < 0 || index+1 >= len(args) {
+				return nil, fmt.Errorf("format() placeholder '%s' has no matching argument", pattern[i:i+end+1])
+			}
+			sb.WriteString(toString(args[index+1]))
+			i += end
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String(), nil
+}
+
+func joinFn(args ...interface{}) (interface{}, error) {
+	if err := expectArgs("join", args, 1, 2); err != nil {
+		return nil, err
+	}
+
+	sep := ","
+	if len(args) == 2 {
+		sep = toString(args[1])
+	}
+
+	var items []interface{}
+	switch v := args[0].(type) {
+	case []interface{}:
+		items = v
+	case filtered:
+		items = v
+	default:
+		return toString(v), nil
+	}
+
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = toString(item)
+	}
+	return strings.Join(parts, sep), nil
+}
+
+func toJSONFn(args ...interface{}) (interface{}, error) {
+	if err := expectArgs("toJSON", args, 1, 1); err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(args[0], "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("toJSON(): %w", err)
+	}
+	return string(data), nil
+}
+
+func fromJSONFn(args ...interface{}) (interface{}, error) {
+	if err := expectArgs("fromJSON", args, 1, 1); err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(toString(args[0])), &value); err != nil {
+		return nil, fmt.Errorf("fromJSON(): %w", err)
+	}
+	return value, nil
+}
@@ -0,0 +1,66 @@
+package expr
+
+import "testing"
+
+func TestFunctions(t *testing.T) {
+	ctx := &Context{
+		Values: map[string]interface{}{
+			"gantry": map[string]interface{}{"branch": "release/1.2"},
+			"labels": []string{"bug", "urgent"},
+		},
+	}
+
+	tests := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"contains('Hello World', 'world')", true},
+		{"contains(labels, 'URGENT')", true},
+		{"contains(labels, 'docs')", false},
+		{"startsWith(gantry.branch, 'release/')", true},
+		{"endsWith(gantry.branch, '.2')", true},
+		{"endsWith(gantry.branch, '.3')", false},
+		{"format('{0}/{1}', 'a', 'b')", "a/b"},
+		{"format('{{literal}} {0}', 1)", "{literal} 1"},
+		{"join(labels, ', ')", "bug, urgent"},
+		{"join(labels)", "bug,urgent"},
+		{"toJSON(labels)", "[\n  \"bug\",\n  \"urgent\"\n]"},
+		{"fromJSON('{\"a\": 1}').a", float64(1)},
+		{"fromJSON('true')", true},
+	}
+
+	for _, tt := range tests {
+		e, err := Parse(tt.expression)
+		if err != nil {
+			t.Errorf("Parse(%q) returned error: %v", tt.expression, err)
+			continue
+		}
+		got, err := e.Evaluate(ctx)
+		if err != nil {
+			t.Errorf("Evaluate(%q) returned error: %v", tt.expression, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("Evaluate(%q) = %#v, expected %#v", tt.expression, got, tt.expected)
+		}
+	}
+}
+
+func TestFunctions_Errors(t *testing.T) {
+	for _, expression := range []string{
+		"contains('a')",
+		"format('{1}', 'a')",
+		"format('{0')",
+		"fromJSON('{')",
+		"unknown()",
+	} {
+		e, err := Parse(expression)
+		if err != nil {
+			t.Errorf("Parse(%q) returned error: %v", expression, err)
+			continue
+		}
+		if _, err := e.Evaluate(&Context{}); err == nil {
+			t.Errorf("Expected Evaluate(%q) to fail", expression)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// segment is either literal text or an embedded ${{ }} expression
+type segment struct {
+	text string
+	expr *Expression
+}
+
+// splitTemplate breaks a string into literal text and ${{ }} expressions.
+// The closing braces are found outside of string literals, so values like
+// '}}' inside an expression don't end it early.
+func splitTemplate(s string) ([]segment, error) {
+	var segments []segment
+
+	for {
+		start := strings.Index(s, "${{")
+		if start < 0 {
+			if s != "" {
+				segments = append(segments, segment{text: s})
+			}
+			return segments, nil
+		}
+
+		if start > 0 {
+			segments = append(segments, segment{text: s[:start]})
+		}
+
+		body := s[start+3:]
+		end, inString := -1, false
+		for i := 0; i < len(body); i++ {
+			switch {
+			case body[i] == '\'':
+				inString = !inString
+			case !inString && strings.HasPrefix(body[i:], "}}"):
+				end = i
+			}
+			if end >= 0 {
+				break
+			}
+		}
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated expression '${{%s'", body)
+		}
+
+		e, err := Parse(strings.TrimSpace(body[:end]))
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, segment{expr: e})
+		s = body[end+2:]
+	}
+}
+
+// ValidateTemplate checks that every ${{ }} expression in s parses
+func ValidateTemplate(s string) error {
+	_, err := splitTemplate(s)
+	return err
+}
+
+// TemplateReferences returns the properties read from a named context by
+// every expression in a template
+func TemplateReferences(s, context string) ([]string, error) {
+	segments, err := splitTemplate(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []string
+	for _, seg := range segments {
+		if seg.expr != nil {
+			refs = append(refs, seg.expr.References(context)...)
+		}
+	}
+	return refs, nil
+}
+
+// TemplateUsesContext reports whether any expression in a template reads
+// from a named context
+func TemplateUsesContext(s, context string) (bool, error) {
+	segments, err := splitTemplate(s)
+	if err != nil {
+		return false, err
+	}
+	for _, seg := range segments {
+		if seg.expr != nil && seg.expr.UsesContext(context) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// HasExpressions reports whether s contains a ${{ }} expression
+func HasExpressions(s string) bool {
+	return strings.Contains(s, "${{")
+}
+
+// Interpolate replaces every ${{ }} expression in s with its string value
+func Interpolate(s string, ctx *Context) (string, error) {
+	if !HasExpressions(s) {
+		return s, nil
+	}
+
+	segments, err := splitTemplate(s)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for _, seg := range segments {
+		if seg.expr == nil {
+			sb.WriteString(seg.text)
+			continue
+		}
+		value, err := seg.expr.Evaluate(ctx)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(toString(value))
+	}
+
+	return sb.String(), nil
+}
+
+// InterpolateMap interpolates every value of a string map
+func InterpolateMap(values map[string]string, ctx *Context) (map[string]string, error) {
+	if values == nil {
+		return nil, nil
+	}
+
+	resolved := make(map[string]string, len(values))
+	for k, v := range values {
+		value, err := Interpolate(v, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", k, err)
+		}
+		resolved[k] = value
+	}
+	return resolved, nil
+}
+
+// With returns a copy of the context with one named context replaced
+func (c *Context) With(name string, value interface{}) *Context {
+	values := make(map[string]interface{}, len(c.Values)+1)
+	for k, v := range c.Values {
+		values[k] = v
+	}
+	values[name] = value
+	return &Context{Values: values, Functions: c.Functions}
+}
@@ -0,0 +1,91 @@
+package expr
+
+import "testing"
+
+func TestInterpolate(t *testing.T) {
+	ctx := &Context{
+		Values: map[string]interface{}{
+			"env":    map[string]string{"GREETING": "hello"},
+			"matrix": map[string]string{"go": "1.21"},
+			"inputs": map[string]interface{}{"debug": true, "count": 3},
+		},
+	}
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{"plain text", "plain text"},
+		{"go ${{ matrix.go }}", "go 1.21"},
+		{"${{env.GREETING}}, ${{ matrix.go }}!", "hello, 1.21!"},
+		{"debug=${{ inputs.debug }} count=${{ inputs.count }}", "debug=true count=3"},
+		{"brace ${{ '}}' }}", "brace }}"},
+		{"missing='${{ inputs.nope }}'", "missing=''"},
+		{"${{ format('{0}-{1}', env.GREETING, matrix.go) }}", "hello-1.21"},
+	}
+
+	for _, tt := range tests {
+		got, err := Interpolate(tt.template, ctx)
+		if err != nil {
+			t.Errorf("Interpolate(%q) returned error: %v", tt.template, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("Interpolate(%q) = %q, expected %q", tt.template, got, tt.expected)
+		}
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	valid := []string{"", "echo hi", "${{ matrix.os }}", "a ${{ contains(env.X, 'y') }} b"}
+	for _, s := range valid {
+		if err := ValidateTemplate(s); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", s, err)
+		}
+	}
+
+	invalid := []string{"${{ matrix.os", "${{ }}", "${{ a == }}"}
+	for _, s := range invalid {
+		if err := ValidateTemplate(s); err == nil {
+			t.Errorf("Expected %q to be invalid", s)
+		}
+	}
+}
+
+func TestInterpolateMap(t *testing.T) {
+	ctx := &Context{Values: map[string]interface{}{"matrix": map[string]string{"os": "alpine"}}}
+
+	resolved, err := InterpolateMap(map[string]string{"IMAGE": "${{ matrix.os }}:latest"}, ctx)
+	if err != nil {
+		t.Fatalf("InterpolateMap returned error: %v", err)
+	}
+	if resolved["IMAGE"] != "alpine:latest" {
+		t.Errorf("Expected IMAGE 'alpine:latest', got %q", resolved["IMAGE"])
+	}
+}
+
+func TestTemplateReferences(t *testing.T) {
+	refs, err := TemplateReferences("login ${{ secrets.USER }}:${{ secrets['PASS'] }} ${{ env.HOST }}", "secrets")
+	if err != nil {
+		t.Fatalf("TemplateReferences returned error: %v", err)
+	}
+	if len(refs) != 2 || refs[0] != "USER" || refs[1] != "PASS" {
+		t.Errorf("Expected [USER PASS], got %v", refs)
+	}
+
+	if _, err := TemplateReferences("${{ secrets. }}", "secrets"); err == nil {
+		t.Error("Expected error for invalid expression")
+	}
+}
+
+func TestTemplateUsesContext(t *testing.T) {
+	uses, err := TemplateUsesContext("echo ${{ toJSON(steps) }} ${{ env.HOST }}", "steps")
+	if err != nil || !uses {
+		t.Errorf("Expected the steps context to be used, got %v (%v)", uses, err)
+	}
+
+	uses, err = TemplateUsesContext("echo ${{ env.steps }}", "steps")
+	if err != nil || uses {
+		t.Errorf("Expected a property named steps not to count, got %v (%v)", uses, err)
+	}
+}
@@ -0,0 +1,70 @@
+// Package glob matches branch names against the patterns of trigger and
+// environment branch filters
+package glob
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Match reports whether name matches pattern. `*` matches any characters
+// except `/`, `**` matches any characters including `/`, and `?` matches a
+// single character other than `/`. Everything else matches itself.
+func Match(pattern, name string) bool {
+	return compile(pattern).MatchString(name)
+}
+
+// Filter applies an ordered list of patterns to name. Patterns starting with
+// `!` exclude the names they match, and the last pattern that matches
+// decides. A name that matches no pattern is excluded, unless every pattern
+// is an exclusion. An empty list matches everything.
+func Filter(patterns []string, name string) bool {
+	matched := true
+	for _, pattern := range patterns {
+		if !strings.HasPrefix(pattern, "!") {
+			matched = false
+			break
+		}
+	}
+
+	for _, pattern := range patterns {
+		if negated, ok := strings.CutPrefix(pattern, "!"); ok {
+			if Match(negated, name) {
+				matched = false
+			}
+		} else if Match(pattern, name) {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// Validate checks that pattern can be used in a filter list
+func Validate(pattern string) error {
+	if strings.TrimPrefix(pattern, "!") == "" {
+		return fmt.Errorf("branch pattern must not be empty")
+	}
+	return nil
+}
+
+// compile translates a pattern into an anchored regular expression
+func compile(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '*' && i+1 < len(pattern) && pattern[i+1] == '*':
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
@@ -0,0 +1,62 @@
+package glob
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"main", "main", true},
+		{"main", "main2", false},
+		{"release/*", "release/1.2", true},
+		{"release/*", "release/1.2/hotfix", false},
+		{"release/**", "release/1.2/hotfix", true},
+		{"feature-?", "feature-a", true},
+		{"feature-?", "feature-ab", false},
+		{"v1.*", "v1.0", true},
+		{"v1.*", "v1x0", false},
+		{"**", "any/thing", true},
+	}
+
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	patterns := []string{"release/**", "!release/**-rc", "release/1.0-rc"}
+
+	tests := map[string]bool{
+		"release/1.0":    true,
+		"release/2.0-rc": false,
+		"release/1.0-rc": true,
+		"main":           false,
+	}
+	for name, want := range tests {
+		if got := Filter(patterns, name); got != want {
+			t.Errorf("Filter(%q) = %v, want %v", name, got, want)
+		}
+	}
+
+	if !Filter(nil, "anything") {
+		t.Error("Expected an empty filter to match everything")
+	}
+	if Filter([]string{"!wip/*"}, "wip/idea") || !Filter([]string{"!wip/*"}, "main") {
+		t.Error("Expected a filter of only exclusions to match everything else")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, pattern := range []string{"", "!"} {
+		if err := Validate(pattern); err == nil {
+			t.Errorf("Expected error for pattern %q, got nil", pattern)
+		}
+	}
+	if err := Validate("!wip/*"); err != nil {
+		t.Errorf("Expected valid pattern, got %v", err)
+	}
+}
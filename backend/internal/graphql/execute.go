@@ -0,0 +1,345 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// object is an object of a response, which keeps its fields in the order
+// the query selected them
+type object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o *object) set(key string, v interface{}) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = v
+}
+
+// MarshalJSON implements json.Marshaler
+func (o *object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// executor executes an operation, collecting the errors of its fields
+type executor struct {
+	ctx    context.Context
+	p      *prepared
+	errors []*Error
+}
+
+// execute resolves the operation's selections on source, an object of type
+// root
+func (p *prepared) execute(ctx context.Context, root *Object, source interface{}) *Response {
+	e := &executor{ctx: ctx, p: p}
+	data, _ := e.selectionSet(root, source, p.op.selections, nil)
+	if data == nil {
+		// Executed requests have data, if only null
+		return &Response{Data: json.RawMessage("null"), Errors: e.errors}
+	}
+	return &Response{Data: data, Errors: e.errors}
+}
+
+// subscribe starts the source of the subscription's field and executes
+// the operation for each of its events
+func (p *prepared) subscribe(ctx context.Context) (<-chan *Response, *Response) {
+	root := p.schema.Subscription
+	e := &executor{ctx: ctx, p: p}
+	fields := e.collectFields(root, p.op.selections, nil)
+	if len(fields) == 0 {
+		return nil, &Response{Errors: []*Error{errorf(p.op.loc, "the subscription selects no field")}}
+	}
+
+	var first *field
+	for _, group := range fields {
+		if group.fields[0].name != "__typename" {
+			first = group.fields[0]
+			break
+		}
+	}
+	if first == nil {
+		return nil, &Response{Errors: []*Error{errorf(p.op.loc, "the subscription selects no field")}}
+	}
+	def := root.field(first.name)
+	path := []interface{}{first.responseKey()}
+
+	args, err := coerceArguments(def.Args, first.args, p.variables)
+	if err != nil {
+		return nil, &Response{Errors: []*Error{fieldError(err, first, path)}}
+	}
+	events, err := def.Subscribe(ResolveParams{Context: ctx, Args: args})
+	if err != nil {
+		return nil, &Response{Errors: []*Error{fieldError(err, first, path)}}
+	}
+
+	responses := make(chan *Response)
+	go func() {
+		defer close(responses)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				select {
+				case responses <- p.execute(ctx, root, event):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return responses, nil
+}
+
+// fieldError reports err as the error of a field at path
+func fieldError(err error, f *field, path []interface{}) *Error {
+	e := &Error{Message: err.Error(), Locations: []Location{f.loc}, Path: path}
+	if gqlErr, ok := err.(*Error); ok {
+		e.Message = gqlErr.Message
+		if len(gqlErr.Locations) > 0 {
+			e.Locations = gqlErr.Locations
+		}
+	}
+	return e
+}
+
+// fieldGroup is the fields a selection set selects under the same response
+// key, which are resolved together
+type fieldGroup struct {
+	key    string
+	fields []*field
+}
+
+// collectFields returns the fields selections select on an object of type
+// parent, in order and grouped by response key, leaving out those skipped
+// by their directives
+func (e *executor) collectFields(parent *Object, selections []selection, visited map[string]bool) []*fieldGroup {
+	var groups []*fieldGroup
+	index := make(map[string]*fieldGroup)
+	add := func(f *field) {
+		key := f.responseKey()
+		if g, ok := index[key]; ok {
+			g.fields = append(g.fields, f)
+			return
+		}
+		g := &fieldGroup{key: key, fields: []*field{f}}
+		index[key] = g
+		groups = append(groups, g)
+	}
+
+	var collect func(selections []selection)
+	collect = func(selections []selection) {
+		for _, sel := range selections {
+			switch sel := sel.(type) {
+			case *field:
+				if e.included(sel.directives) {
+					add(sel)
+				}
+			case *inlineFragment:
+				if e.included(sel.directives) && (sel.typeCondition == "" || sel.typeCondition == parent.Name) {
+					collect(sel.selections)
+				}
+			case *fragmentSpread:
+				if !e.included(sel.directives) || visited[sel.name] {
+					continue
+				}
+				frag := e.p.doc.fragments[sel.name]
+				if frag == nil || !e.included(frag.directives) || frag.typeCondition != parent.Name {
+					continue
+				}
+				if visited == nil {
+					visited = make(map[string]bool)
+				}
+				visited[sel.name] = true
+				collect(frag.selections)
+			}
+		}
+	}
+	collect(selections)
+	return groups
+}
+
+// included reports whether directives keep what they are applied to in
+// the response
+func (e *executor) included(directives []*directive) bool {
+	for _, d := range directives {
+		args, err := coerceArguments(directiveArgs, d.args, e.p.variables)
+		if err != nil {
+			continue
+		}
+		cond, _ := args["if"].(bool)
+		if (d.name == "skip" && cond) || (d.name == "include" && !cond) {
+			return false
+		}
+	}
+	return true
+}
+
+// selectionSet resolves the fields selections select on source, an object
+// of type parent. It reports whether the object is null because one of its
+// non-null fields failed.
+func (e *executor) selectionSet(parent *Object, source interface{}, selections []selection, path []interface{}) (*object, bool) {
+	result := &object{values: make(map[string]interface{})}
+	for _, group := range e.collectFields(parent, selections, nil) {
+		fieldPath := append(append([]interface{}{}, path...), group.key)
+		f := group.fields[0]
+
+		if f.name == "__typename" {
+			result.set(group.key, parent.Name)
+			continue
+		}
+
+		def := parent.field(f.name)
+		v, failed := e.field(def, group.fields, source, fieldPath)
+		if _, nonNull := def.Type.(*NonNull); failed && nonNull {
+			return nil, true
+		}
+		result.set(group.key, v)
+	}
+	return result, false
+}
+
+// field resolves a field of source and completes its value. It reports
+// whether the value is null because of an error.
+func (e *executor) field(def *Field, fields []*field, source interface{}, path []interface{}) (interface{}, bool) {
+	f := fields[0]
+	args, err := coerceArguments(def.Args, f.args, e.p.variables)
+	if err != nil {
+		e.errors = append(e.errors, fieldError(err, f, path))
+		return nil, true
+	}
+
+	var v interface{}
+	switch {
+	case def.Resolve != nil:
+		v, err = e.resolve(def, ResolveParams{Context: e.ctx, Source: source, Args: args})
+		if err != nil {
+			e.errors = append(e.errors, fieldError(err, f, path))
+			return nil, true
+		}
+	case def.Subscribe != nil:
+		// Events of a subscription field are its value
+		v = source
+	default:
+		if m, ok := source.(map[string]interface{}); ok {
+			v = m[def.Name]
+		}
+	}
+	return e.complete(def.Type, fields, v, path)
+}
+
+// resolve calls a field's resolver, turning a panic into an error so that
+// one bad field doesn't fail the whole request
+func (e *executor) resolve(def *Field, p ResolveParams) (v interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("internal error resolving %s: %v", def.Name, r)
+		}
+	}()
+	return def.Resolve(p)
+}
+
+// complete turns what a resolver returned into a value of type t for the
+// response. It reports whether the value is null because of an error.
+func (e *executor) complete(t Type, fields []*field, v interface{}, path []interface{}) (interface{}, bool) {
+	if nn, ok := t.(*NonNull); ok {
+		completed, failed := e.complete(nn.Of, fields, v, path)
+		if completed == nil && !failed {
+			e.errors = append(e.errors, &Error{
+				Message:   fmt.Sprintf("field of non-null type %s is null", t),
+				Locations: []Location{fields[0].loc},
+				Path:      path,
+			})
+			failed = true
+		}
+		return completed, failed
+	}
+
+	if _, ok := t.(*List); ok {
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice && rv.IsNil() {
+			// A nil slice is an empty list rather than null, as in Go
+			return []interface{}{}, false
+		}
+	}
+	if isNil(v) {
+		return nil, false
+	}
+
+	switch t := t.(type) {
+	case *Scalar:
+		serialized, err := t.Serialize(v)
+		if err != nil {
+			e.errors = append(e.errors, fieldError(err, fields[0], path))
+			return nil, true
+		}
+		return serialized, false
+	case *List:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			e.errors = append(e.errors, fieldError(fmt.Errorf("expected a list, got %T", v), fields[0], path))
+			return nil, true
+		}
+		_, itemNonNull := t.Of.(*NonNull)
+		items := make([]interface{}, rv.Len())
+		for i := range items {
+			item, failed := e.complete(t.Of, fields, rv.Index(i).Interface(), append(append([]interface{}{}, path...), i))
+			if failed && itemNonNull {
+				return nil, true
+			}
+			items[i] = item
+		}
+		return items, false
+	case *Object:
+		var selections []selection
+		for _, f := range fields {
+			selections = append(selections, f.selections...)
+		}
+		obj, failed := e.selectionSet(t, v, selections, path)
+		if failed {
+			return nil, true
+		}
+		return obj, false
+	}
+	return nil, false
+}
+
+// isNil reports whether v is nil, or a nil pointer, slice or map
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface, reflect.Chan, reflect.Func:
+		return rv.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,371 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var testRuns = []map[string]interface{}{
+	{"id": "r1", "status": "success", "jobs": []map[string]interface{}{{"name": "build"}, {"name": "test"}}},
+	{"id": "r2", "status": "failed", "jobs": []map[string]interface{}(nil)},
+}
+
+func testSchema(t *testing.T) *Schema {
+	t.Helper()
+
+	job := &Object{Name: "Job", Fields: []*Field{{Name: "name", Type: &NonNull{Of: String}}}}
+	run := &Object{Name: "Run", Description: "A run of a workflow", Fields: []*Field{
+		{Name: "id", Type: &NonNull{Of: ID}},
+		{Name: "status", Type: &NonNull{Of: String}},
+		{Name: "jobs", Type: &NonNull{Of: &List{Of: &NonNull{Of: job}}}},
+		{Name: "missing", Type: &NonNull{Of: String}},
+	}}
+	query := &Object{Name: "Query", Fields: []*Field{
+		{
+			Name: "hello",
+			Type: &NonNull{Of: String},
+			Args: []*Argument{{Name: "name", Type: String, Default: "world"}},
+			Resolve: func(p ResolveParams) (interface{}, error) {
+				name, _ := p.Args["name"].(string)
+				return "hello " + name, nil
+			},
+		},
+		{
+			Name:        "runs",
+			Description: "Runs, optionally\nwith some statuses",
+			Type:        &NonNull{Of: &List{Of: &NonNull{Of: run}}},
+			Args:        []*Argument{{Name: "status", Type: &List{Of: &NonNull{Of: String}}}, {Name: "limit", Type: Int}},
+			Resolve: func(p ResolveParams) (interface{}, error) {
+				statuses, _ := p.Args["status"].([]interface{})
+				runs := []map[string]interface{}{}
+				for _, r := range testRuns {
+					match := len(statuses) == 0
+					for _, s := range statuses {
+						match = match || s == r["status"]
+					}
+					if match {
+						runs = append(runs, r)
+					}
+				}
+				if limit, ok := p.Args["limit"].(int); ok && limit < len(runs) {
+					runs = runs[:limit]
+				}
+				return runs, nil
+			},
+		},
+		{
+			Name: "run",
+			Type: run,
+			Args: []*Argument{{Name: "id", Type: &NonNull{Of: ID}}},
+			Resolve: func(p ResolveParams) (interface{}, error) {
+				for _, r := range testRuns {
+					if r["id"] == p.Args["id"] {
+						return r, nil
+					}
+				}
+				return nil, nil
+			},
+		},
+		{
+			Name: "fail",
+			Type: String,
+			Resolve: func(p ResolveParams) (interface{}, error) {
+				return nil, errors.New("resolver failed")
+			},
+		},
+		{
+			Name: "panic",
+			Type: String,
+			Resolve: func(p ResolveParams) (interface{}, error) {
+				panic("boom")
+			},
+		},
+	}}
+	tick := &Object{Name: "Tick", Fields: []*Field{{Name: "n", Type: &NonNull{Of: Int}}}}
+	subscription := &Object{Name: "Subscription", Fields: []*Field{
+		{
+			Name: "ticks",
+			Type: &NonNull{Of: tick},
+			Args: []*Argument{{Name: "count", Type: &NonNull{Of: Int}}},
+			Subscribe: func(p ResolveParams) (<-chan interface{}, error) {
+				count := p.Args["count"].(int)
+				if count < 0 {
+					return nil, errors.New("count must not be negative")
+				}
+				events := make(chan interface{}, count)
+				for i := 1; i <= count; i++ {
+					events <- map[string]interface{}{"n": i}
+				}
+				close(events)
+				return events, nil
+			},
+		},
+	}}
+
+	schema, err := NewSchema(query, subscription)
+	if err != nil {
+		t.Fatalf("NewSchema returned error: %v", err)
+	}
+	return schema
+}
+
+// responseJSON returns a response as JSON
+func responseJSON(t *testing.T, resp *Response) string {
+	t.Helper()
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatalf("failed to encode response: %v", err)
+	}
+	return string(data)
+}
+
+func TestExecute(t *testing.T) {
+	schema := testSchema(t)
+
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		expected  string
+	}{
+		{
+			name:     "default argument",
+			query:    `{ hello }`,
+			expected: `{"data":{"hello":"hello world"}}`,
+		},
+		{
+			name:      "variables and aliases",
+			query:     `query ($who: String) { a: hello(name: $who) b: hello(name: "you") }`,
+			variables: map[string]interface{}{"who": "me"},
+			expected:  `{"data":{"a":"hello me","b":"hello you"}}`,
+		},
+		{
+			name:     "nested lists keep the selection order",
+			query:    `{ runs { status id jobs { name } } }`,
+			expected: `{"data":{"runs":[{"status":"success","id":"r1","jobs":[{"name":"build"},{"name":"test"}]},{"status":"failed","id":"r2","jobs":[]}]}}`,
+		},
+		{
+			name:      "list arguments from single values and JSON numbers",
+			query:     `query ($limit: Int) { runs(status: "failed", limit: $limit) { id } }`,
+			variables: map[string]interface{}{"limit": float64(1)},
+			expected:  `{"data":{"runs":[{"id":"r2"}]}}`,
+		},
+		{
+			name:     "fragments and typename",
+			query:    `{ run(id: "r1") { ...F ... on Run { status } __typename } } fragment F on Run { id }`,
+			expected: `{"data":{"run":{"id":"r1","status":"success","__typename":"Run"}}}`,
+		},
+		{
+			name:      "skip and include",
+			query:     `query ($yes: Boolean!) { run(id: "r1") { id @skip(if: $yes) status @include(if: $yes) } }`,
+			variables: map[string]interface{}{"yes": true},
+			expected:  `{"data":{"run":{"status":"success"}}}`,
+		},
+		{
+			name:     "null objects",
+			query:    `{ run(id: "nope") { id } }`,
+			expected: `{"data":{"run":null}}`,
+		},
+		{
+			name:     "resolver errors null the field",
+			query:    `{ hello fail }`,
+			expected: `{"data":{"hello":"hello world","fail":null},"errors":[{"message":"resolver failed","locations":[{"line":1,"column":9}],"path":["fail"]}]}`,
+		},
+		{
+			name:     "panics are errors",
+			query:    `{ panic }`,
+			expected: `{"data":{"panic":null},"errors":[{"message":"internal error resolving panic: boom","locations":[{"line":1,"column":3}],"path":["panic"]}]}`,
+		},
+		{
+			name:     "null non-null fields null their parent",
+			query:    `{ run(id: "r1") { id missing } }`,
+			expected: `{"data":{"run":null},"errors":[{"message":"field of non-null type String! is null","locations":[{"line":1,"column":22}],"path":["run","missing"]}]}`,
+		},
+		{
+			name:     "null non-null fields propagate through non-null lists",
+			query:    `{ hello runs { missing } }`,
+			expected: `{"data":null,"errors":[{"message":"field of non-null type String! is null","locations":[{"line":1,"column":16}],"path":["runs",0,"missing"]}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := schema.Execute(context.Background(), Request{Query: tt.query, Variables: tt.variables})
+			if got := responseJSON(t, resp); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestExecute_RequestErrors(t *testing.T) {
+	schema := testSchema(t)
+
+	tests := []struct {
+		query     string
+		variables map[string]interface{}
+		expected  string
+	}{
+		{`{ nope }`, nil, "type Query has no field 'nope'"},
+		{`{ run { id } }`, nil, "field 'run' needs argument 'id' of type ID!"},
+		{`{ hello(who: "x") }`, nil, "field 'hello' has no argument 'who'"},
+		{`{ hello(name: 1) }`, nil, "argument 'name': String can't represent 1"},
+		{`{ runs }`, nil, "field 'runs' of type [Run!]! must select fields"},
+		{`{ hello { length } }`, nil, "field 'hello' of type String! can't have selections"},
+		{`{ hello @defer }`, nil, "unknown directive @defer"},
+		{`{ hello @skip }`, nil, "directive @skip needs argument 'if' of type Boolean!"},
+		{`{ hello(name: $n) }`, nil, "variable $n is not defined by operation (anonymous)"},
+		{`query ($id: ID) { run(id: $id) { id } }`, nil, "variable $id of type ID can't be used as argument 'id' of type ID!"},
+		{`query ($id: ID!) { run(id: $id) { id } }`, nil, "variable $id of required type ID! was not provided"},
+		{`query ($n: Int) { runs(limit: $n) { id } }`, map[string]interface{}{"n": 1.5}, "variable $n: Int can't represent 1.5"},
+		{`query ($r: Run) { hello }`, nil, "variable $r has unknown input type Run"},
+		{`{ run(id: "r1") { ...F } } fragment F on Job { name }`, nil, "fragments on Job can't be spread within Run"},
+		{`{ run(id: "r1") { ...F } } fragment F on Run { ...F }`, nil, "fragment 'F' spreads itself"},
+		{`{ run(id: "r1") { ...G } }`, nil, "unknown fragment 'G'"},
+		{`mutation { hello }`, nil, "the schema has no mutation operations"},
+		{`subscription { ticks(count: 1) { n } }`, nil, "subscription operations can't be executed"},
+		{`{ hello`, nil, "unexpected end of document"},
+	}
+
+	for _, tt := range tests {
+		resp := schema.Execute(context.Background(), Request{Query: tt.query, Variables: tt.variables})
+		if resp.Data != nil || len(resp.Errors) == 0 {
+			t.Errorf("%s: expected errors and no data, got %s", tt.query, responseJSON(t, resp))
+			continue
+		}
+		if !strings.Contains(resp.Errors[0].Message, tt.expected) {
+			t.Errorf("%s: expected error containing %q, got %q", tt.query, tt.expected, resp.Errors[0].Message)
+		}
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	schema := testSchema(t)
+
+	responses, errResp := schema.Subscribe(context.Background(), Request{
+		Query:     `subscription ($count: Int!) { tick: ticks(count: $count) { n } }`,
+		Variables: map[string]interface{}{"count": 3},
+	})
+	if errResp != nil {
+		t.Fatalf("Subscribe failed: %s", responseJSON(t, errResp))
+	}
+
+	var got []string
+	for resp := range responses {
+		got = append(got, responseJSON(t, resp))
+	}
+	expected := []string{`{"data":{"tick":{"n":1}}}`, `{"data":{"tick":{"n":2}}}`, `{"data":{"tick":{"n":3}}}`}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected responses %v, got %v", expected, got)
+	}
+}
+
+func TestSubscribe_Errors(t *testing.T) {
+	schema := testSchema(t)
+
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{`subscription { ticks(count: -1) { n } }`, "count must not be negative"},
+		{`subscription { a: ticks(count: 1) { n } b: ticks(count: 2) { n } }`, "exactly one field, not 2"},
+	}
+	for _, tt := range tests {
+		responses, errResp := schema.Subscribe(context.Background(), Request{Query: tt.query})
+		if responses != nil || errResp == nil {
+			t.Errorf("%s: expected an error", tt.query)
+			continue
+		}
+		if !strings.Contains(errResp.Errors[0].Message, tt.expected) {
+			t.Errorf("%s: expected error containing %q, got %q", tt.query, tt.expected, errResp.Errors[0].Message)
+		}
+	}
+}
+
+func TestSubscribe_Cancel(t *testing.T) {
+	schema := testSchema(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	responses, errResp := schema.Subscribe(ctx, Request{Query: `subscription { ticks(count: 5) { n } }`})
+	if errResp != nil {
+		t.Fatalf("Subscribe failed: %s", responseJSON(t, errResp))
+	}
+	<-responses
+	cancel()
+	for range responses {
+		// Drains what was sent before the subscription noticed
+	}
+}
+
+func TestSubscribe_Query(t *testing.T) {
+	schema := testSchema(t)
+
+	responses, errResp := schema.Subscribe(context.Background(), Request{Query: `{ hello }`})
+	if errResp != nil {
+		t.Fatalf("Subscribe failed: %s", responseJSON(t, errResp))
+	}
+	resp, ok := <-responses
+	if !ok || responseJSON(t, resp) != `{"data":{"hello":"hello world"}}` {
+		t.Errorf("Expected the query's result, got %v", resp)
+	}
+	if _, ok := <-responses; ok {
+		t.Error("Expected queries to send one response")
+	}
+}
+
+func TestOperationType(t *testing.T) {
+	if typ := OperationType(Request{Query: `subscription S { ticks(count: 1) { n } }`}); typ != OperationSubscription {
+		t.Errorf("Expected subscription, got %s", typ)
+	}
+	if typ := OperationType(Request{Query: `{ hello }`}); typ != OperationQuery {
+		t.Errorf("Expected query, got %s", typ)
+	}
+}
+
+func TestNewSchema_Errors(t *testing.T) {
+	ok := &Field{Name: "a", Type: String}
+	tests := []struct {
+		name         string
+		query        *Object
+		subscription *Object
+		expected     string
+	}{
+		{"no query", nil, nil, "needs a query type"},
+		{"no fields", &Object{Name: "Query"}, nil, "has no fields"},
+		{"duplicate fields", &Object{Name: "Query", Fields: []*Field{ok, ok}}, nil, "two fields named a"},
+		{"object arguments", &Object{Name: "Query", Fields: []*Field{{Name: "a", Type: String,
+			Args: []*Argument{{Name: "x", Type: &Object{Name: "X", Fields: []*Field{ok}}}}}}}, nil, "must be a scalar"},
+		{"duplicate type names", &Object{Name: "Query", Fields: []*Field{{Name: "a", Type: &Object{Name: "String", Fields: []*Field{ok}}}}},
+			nil, "two types are named String"},
+		{"subscription without source", &Object{Name: "Query", Fields: []*Field{ok}},
+			&Object{Name: "Subscription", Fields: []*Field{ok}}, "must subscribe"},
+	}
+
+	for _, tt := range tests {
+		_, err := NewSchema(tt.query, tt.subscription)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.expected, err)
+		}
+	}
+}
+
+func TestSchema_String(t *testing.T) {
+	sdl := testSchema(t).String()
+
+	for _, expected := range []string{
+		"type Query {\n  hello(name: String = \"world\"): String!\n",
+		"  \"\"\"\n  Runs, optionally\n  with some statuses\n  \"\"\"\n  runs(status: [String!], limit: Int): [Run!]!\n",
+		"\"\"\"A run of a workflow\"\"\"\ntype Run {\n",
+		"type Subscription {\n  ticks(count: Int!): Tick!\n}\n",
+	} {
+		if !strings.Contains(sdl, expected) {
+			t.Errorf("Expected the schema to contain %q, got:\n%s", expected, sdl)
+		}
+	}
+	if !strings.HasPrefix(sdl, "type Query") || strings.Index(sdl, "type Subscription") > strings.Index(sdl, "type Job") {
+		t.Errorf("Expected the root types first, got:\n%s", sdl)
+	}
+}
@@ -0,0 +1,145 @@
+// Package graphql executes GraphQL queries and subscriptions against a
+// schema of Go resolvers. It implements the parts of the language clients
+// use: operations with variables, aliases, arguments, fragments and the
+// @skip and @include directives. Schemas are read only, so mutations are
+// not supported.
+package graphql
+
+import (
+	"context"
+	"fmt"
+)
+
+// Location is where something is in a GraphQL document, counted from 1
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Error is a GraphQL error, as responses report it: with where in the
+// document it was caused and the path of the field that failed, if any
+type Error struct {
+	Message   string        `json:"message"`
+	Locations []Location    `json:"locations,omitempty"`
+	Path      []interface{} `json:"path,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if len(e.Locations) == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%s (line %d, column %d)", e.Message, e.Locations[0].Line, e.Locations[0].Column)
+}
+
+// errorf creates an error caused at loc
+func errorf(loc Location, format string, args ...interface{}) *Error {
+	return &Error{Message: fmt.Sprintf(format, args...), Locations: []Location{loc}}
+}
+
+// Request is a GraphQL request, as clients send it
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Response is the result of a GraphQL request. Data is left out when the
+// request failed before it could be executed, and null when it failed
+// while executing.
+type Response struct {
+	Data   interface{} `json:"data,omitempty"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+// Operation types
+const (
+	OperationQuery        = "query"
+	OperationMutation     = "mutation"
+	OperationSubscription = "subscription"
+)
+
+// prepared is a request parsed and validated against a schema, ready to
+// execute
+type prepared struct {
+	schema    *Schema
+	doc       *document
+	op        *operation
+	variables map[string]interface{}
+}
+
+// prepare parses a request and checks it against the schema, coercing its
+// variables to the types its operation declares
+func (s *Schema) prepare(req Request) (*prepared, []*Error) {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return nil, []*Error{asError(err)}
+	}
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return nil, []*Error{asError(err)}
+	}
+	if errs := s.validate(doc); len(errs) > 0 {
+		return nil, errs
+	}
+	variables, err := s.coerceVariables(op, req.Variables)
+	if err != nil {
+		return nil, []*Error{asError(err)}
+	}
+	return &prepared{schema: s, doc: doc, op: op, variables: variables}, nil
+}
+
+// asError returns err as a GraphQL error
+func asError(err error) *Error {
+	if e, ok := err.(*Error); ok {
+		return e
+	}
+	return &Error{Message: err.Error()}
+}
+
+// OperationType returns the type of the operation a request would run:
+// query or subscription. Requests that fail to parse are queries, so that
+// executing them reports why.
+func OperationType(req Request) string {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return OperationQuery
+	}
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return OperationQuery
+	}
+	return op.kind
+}
+
+// Execute runs a query. Fields that fail are null in the response's data,
+// with their errors listed beside it.
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	p, errs := s.prepare(req)
+	if errs != nil {
+		return &Response{Errors: errs}
+	}
+	if p.op.kind != OperationQuery {
+		return &Response{Errors: []*Error{errorf(p.op.loc, "%s operations can't be executed, only subscribed to", p.op.kind)}}
+	}
+	return p.execute(ctx, s.Query, nil)
+}
+
+// Subscribe starts a subscription, which sends a response for every event
+// of its field's source until ctx is done or the source ends, then closes
+// the channel. Requests that aren't subscriptions are executed once.
+func (s *Schema) Subscribe(ctx context.Context, req Request) (<-chan *Response, *Response) {
+	p, errs := s.prepare(req)
+	if errs != nil {
+		return nil, &Response{Errors: errs}
+	}
+	if p.op.kind == OperationQuery {
+		responses := make(chan *Response, 1)
+		responses <- p.execute(ctx, s.Query, nil)
+		close(responses)
+		return responses, nil
+	}
+	if p.op.kind != OperationSubscription || s.Subscription == nil {
+		return nil, &Response{Errors: []*Error{errorf(p.op.loc, "the schema has no %s operations", p.op.kind)}}
+	}
+	return p.subscribe(ctx)
+}
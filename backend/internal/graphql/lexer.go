@@ -0,0 +1,267 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// tokenKind is the kind of a lexical token of a GraphQL document
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+// token is a lexical token, with where it starts in the document
+type token struct {
+	kind  tokenKind
+	value string
+	loc   Location
+}
+
+// punctuators are the GraphQL punctuators but "...", which is lexed apart
+const punctuators = "!$&()@:=[]{}|"
+
+// lex splits a document into tokens, dropping whitespace, commas and
+// comments, which GraphQL ignores
+func lex(src string) ([]token, error) {
+	var tokens []token
+	line, lineStart := 1, 0
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		loc := Location{Line: line, Column: i - lineStart + 1}
+
+		switch {
+		case c == '\n':
+			i++
+			line, lineStart = line+1, i
+		case c == ' ' || c == '\t' || c == '\r' || c == ',':
+			i++
+		case strings.HasPrefix(src[i:], "\uFEFF"):
+			i += len("\uFEFF")
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "..."):
+			tokens = append(tokens, token{kind: tokPunct, value: "...", loc: loc})
+			i += 3
+		case strings.IndexByte(punctuators, c) >= 0:
+			tokens = append(tokens, token{kind: tokPunct, value: string(c), loc: loc})
+			i++
+		case isNameStart(c):
+			start := i
+			for i < len(src) && isNameContinue(src[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokName, value: src[start:i], loc: loc})
+		case c == '-' || isDigit(c):
+			tok, n, err := lexNumber(src[i:])
+			if err != nil {
+				return nil, &Error{Message: err.Error(), Locations: []Location{loc}}
+			}
+			tok.loc = loc
+			tokens = append(tokens, tok)
+			i += n
+		case strings.HasPrefix(src[i:], `"""`):
+			value, n, lines, err := lexBlockString(src[i:])
+			if err != nil {
+				return nil, &Error{Message: err.Error(), Locations: []Location{loc}}
+			}
+			tokens = append(tokens, token{kind: tokString, value: value, loc: loc})
+			i += n
+			if lines > 0 {
+				line += lines
+				lineStart = i - (n - strings.LastIndexByte(src[i-n:i], '\n') - 1)
+			}
+		case c == '"':
+			value, n, err := lexString(src[i:])
+			if err != nil {
+				return nil, &Error{Message: err.Error(), Locations: []Location{loc}}
+			}
+			tokens = append(tokens, token{kind: tokString, value: value, loc: loc})
+			i += n
+		default:
+			r, _ := utf8.DecodeRuneInString(src[i:])
+			return nil, &Error{Message: fmt.Sprintf("unexpected character %q", r), Locations: []Location{loc}}
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokEOF, loc: Location{Line: line, Column: len(src) - lineStart + 1}})
+	return tokens, nil
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+func isNameContinue(c byte) bool {
+	return isNameStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// lexNumber reads an Int or Float token from the start of src, returning it
+// and how many bytes it took
+func lexNumber(src string) (token, int, error) {
+	i := 0
+	if src[i] == '-' {
+		i++
+	}
+	digits := i
+	for i < len(src) && isDigit(src[i]) {
+		i++
+	}
+	if i == digits {
+		return token{}, 0, fmt.Errorf("invalid number %q", src[:i])
+	}
+	if src[digits] == '0' && i-digits > 1 {
+		return token{}, 0, fmt.Errorf("invalid number %q: leading zero", src[:i])
+	}
+
+	kind := tokInt
+	if i < len(src) && src[i] == '.' {
+		kind = tokFloat
+		i++
+		start := i
+		for i < len(src) && isDigit(src[i]) {
+			i++
+		}
+		if i == start {
+			return token{}, 0, fmt.Errorf("invalid number %q", src[:i])
+		}
+	}
+	if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+		kind = tokFloat
+		i++
+		if i < len(src) && (src[i] == '+' || src[i] == '-') {
+			i++
+		}
+		start := i
+		for i < len(src) && isDigit(src[i]) {
+			i++
+		}
+		if i == start {
+			return token{}, 0, fmt.Errorf("invalid number %q", src[:i])
+		}
+	}
+	if i < len(src) && (isNameStart(src[i]) || src[i] == '.') {
+		return token{}, 0, fmt.Errorf("invalid number %q", src[:i+1])
+	}
+	return token{kind: kind, value: src[:i]}, i, nil
+}
+
+// lexString reads a quoted string from the start of src, returning its
+// value and how many bytes it took
+func lexString(src string) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '"':
+			return b.String(), i + 1, nil
+		case c == '\n' || c == '\r':
+			return "", 0, fmt.Errorf("unterminated string")
+		case c == '\\':
+			if i+1 >= len(src) {
+				return "", 0, fmt.Errorf("unterminated string")
+			}
+			switch esc := src[i+1]; esc {
+			case '"', '\\', '/':
+				b.WriteByte(esc)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if i+6 > len(src) {
+					return "", 0, fmt.Errorf("invalid unicode escape")
+				}
+				code, err := strconv.ParseUint(src[i+2:i+6], 16, 32)
+				if err != nil {
+					return "", 0, fmt.Errorf("invalid unicode escape %q", src[i:i+6])
+				}
+				b.WriteRune(rune(code))
+				i += 4
+			default:
+				return "", 0, fmt.Errorf("invalid escape \\%c", esc)
+			}
+			i += 2
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// lexBlockString reads a """block string""" from the start of src,
+// returning its value with common indentation removed, how many bytes it
+// took and how many newlines it spans
+func lexBlockString(src string) (string, int, int, error) {
+	var raw strings.Builder
+	for i := 3; i < len(src); {
+		switch {
+		case strings.HasPrefix(src[i:], `\"""`):
+			raw.WriteString(`"""`)
+			i += 4
+		case strings.HasPrefix(src[i:], `"""`):
+			body := raw.String()
+			return blockStringValue(body), i + 3, strings.Count(src[:i], "\n"), nil
+		default:
+			raw.WriteByte(src[i])
+			i++
+		}
+	}
+	return "", 0, 0, fmt.Errorf("unterminated block string")
+}
+
+// blockStringValue removes the common indentation of a block string's
+// lines, and its leading and trailing blank lines
+func blockStringValue(raw string) string {
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+
+	indent := -1
+	for _, l := range lines[1:] {
+		trimmed := strings.TrimLeft(l, " \t")
+		if trimmed == "" {
+			continue
+		}
+		if n := len(l) - len(trimmed); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	if indent > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= indent {
+				lines[i] = lines[i][indent:]
+			} else {
+				lines[i] = strings.TrimLeft(lines[i], " \t")
+			}
+		}
+	}
+
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
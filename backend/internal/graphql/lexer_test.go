@@ -0,0 +1,75 @@
+package graphql
+
+import (
+	"testing"
+)
+
+func TestLex_Tokens(t *testing.T) {
+	tokens, err := lex("query Q($n: Int = -12) {\n  runs(limit: $n, f: 1.5e3) { ...F } # comment\n}")
+	if err != nil {
+		t.Fatalf("lex returned error: %v", err)
+	}
+
+	var values []string
+	for _, tok := range tokens {
+		values = append(values, tok.value)
+	}
+	expected := []string{"query", "Q", "(", "$", "n", ":", "Int", "=", "-12", ")", "{",
+		"runs", "(", "limit", ":", "$", "n", "f", ":", "1.5e3", ")", "{", "...", "F", "}", "}", ""}
+	if len(values) != len(expected) {
+		t.Fatalf("Expected tokens %q, got %q", expected, values)
+	}
+	for i := range expected {
+		if values[i] != expected[i] {
+			t.Fatalf("Expected tokens %q, got %q", expected, values)
+		}
+	}
+
+	if tok := tokens[11]; tok.loc != (Location{Line: 2, Column: 3}) {
+		t.Errorf("Expected runs at 2:3, got %d:%d", tok.loc.Line, tok.loc.Column)
+	}
+	if tokens[8].kind != tokInt || tokens[19].kind != tokFloat {
+		t.Errorf("Expected -12 to be an Int and 1.5e3 a Float")
+	}
+}
+
+func TestLex_Strings(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{`"plain"`, "plain"},
+		{`"tab\there \"quoted\" é"`, "tab\there \"quoted\" é"},
+		{"\"\"\"\n    block\n      indented\n    \"\"\"", "block\n  indented"},
+		{`"""with \""" inside"""`, `with """ inside`},
+	}
+
+	for _, tt := range tests {
+		tokens, err := lex(tt.src)
+		if err != nil {
+			t.Errorf("lex(%q) returned error: %v", tt.src, err)
+			continue
+		}
+		if tokens[0].kind != tokString || tokens[0].value != tt.expected {
+			t.Errorf("lex(%q): expected string %q, got %q", tt.src, tt.expected, tokens[0].value)
+		}
+	}
+}
+
+func TestLex_LocationsAfterBlockString(t *testing.T) {
+	tokens, err := lex("\"\"\"a\nb\"\"\" name")
+	if err != nil {
+		t.Fatalf("lex returned error: %v", err)
+	}
+	if tok := tokens[1]; tok.loc != (Location{Line: 2, Column: 6}) {
+		t.Errorf("Expected name at 2:6, got %d:%d", tok.loc.Line, tok.loc.Column)
+	}
+}
+
+func TestLex_Errors(t *testing.T) {
+	for _, src := range []string{`"unterminated`, `"bad \q escape"`, `007`, `1.`, `12abc`, `?`, `"""open`} {
+		if _, err := lex(src); err == nil {
+			t.Errorf("lex(%q): expected an error", src)
+		}
+	}
+}
@@ -0,0 +1,588 @@
+package graphql
+
+import (
+	"fmt"
+)
+
+// document is a parsed GraphQL document: its operations and the fragments
+// they spread
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+// operation is a query, mutation or subscription of a document
+type operation struct {
+	kind       string
+	name       string
+	variables  []*variableDef
+	directives []*directive
+	selections []selection
+	loc        Location
+}
+
+// variableDef declares a variable of an operation
+type variableDef struct {
+	name         string
+	typ          *typeRef
+	defaultValue *value // nil without a default
+	loc          Location
+}
+
+// typeRef is a type as a document writes it: a named type or a list, either
+// of which may be non-null
+type typeRef struct {
+	name    string
+	elem    *typeRef // the element type of lists
+	nonNull bool
+	loc     Location
+}
+
+func (t *typeRef) String() string {
+	s := t.name
+	if t.elem != nil {
+		s = "[" + t.elem.String() + "]"
+	}
+	if t.nonNull {
+		s += "!"
+	}
+	return s
+}
+
+// selection is a field, fragment spread or inline fragment of a selection set
+type selection interface {
+	location() Location
+}
+
+// field selects a field of an object, under its alias if it has one
+type field struct {
+	alias      string
+	name       string
+	args       []*argument
+	directives []*directive
+	selections []selection
+	loc        Location
+}
+
+// responseKey is the name the field's value has in the response
+func (f *field) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+// fragmentSpread selects the fields of a named fragment
+type fragmentSpread struct {
+	name       string
+	directives []*directive
+	loc        Location
+}
+
+// inlineFragment selects fields in place, for objects of a type if it has
+// a type condition
+type inlineFragment struct {
+	typeCondition string
+	directives    []*directive
+	selections    []selection
+	loc           Location
+}
+
+func (f *field) location() Location          { return f.loc }
+func (f *fragmentSpread) location() Location { return f.loc }
+func (f *inlineFragment) location() Location { return f.loc }
+
+// fragment is a named fragment of a document
+type fragment struct {
+	name          string
+	typeCondition string
+	directives    []*directive
+	selections    []selection
+	loc           Location
+}
+
+// directive is a directive applied to an operation, field or fragment
+type directive struct {
+	name string
+	args []*argument
+	loc  Location
+}
+
+// argument is an argument of a field or directive
+type argument struct {
+	name  string
+	value *value
+	loc   Location
+}
+
+// valueKind is the kind of a value literal
+type valueKind int
+
+const (
+	valueVariable valueKind = iota
+	valueInt
+	valueFloat
+	valueString
+	valueBoolean
+	valueNull
+	valueEnum
+	valueList
+	valueObject
+)
+
+// value is a value literal, or a reference to a variable
+type value struct {
+	kind   valueKind
+	raw    string // the literal's text, or the variable's or enum value's name
+	list   []*value
+	fields []*objectField
+	loc    Location
+}
+
+// objectField is a field of an input object literal
+type objectField struct {
+	name  string
+	value *value
+	loc   Location
+}
+
+// operation returns the operation of the document named name, or its only
+// operation when name is empty
+func (d *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(d.operations) != 1 {
+			return nil, &Error{Message: "the document has several operations; name the one to run"}
+		}
+		return d.operations[0], nil
+	}
+	for _, op := range d.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, &Error{Message: fmt.Sprintf("the document has no operation named '%s'", name)}
+}
+
+// parser reads a document from its tokens
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parse reads a GraphQL document, which may only define operations and
+// fragments
+func parse(src string) (*document, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	doc := &document{fragments: make(map[string]*fragment)}
+
+	if p.peek().kind == tokEOF {
+		return nil, errorf(p.peek().loc, "the document is empty")
+	}
+	for p.peek().kind != tokEOF {
+		tok := p.peek()
+		switch {
+		case tok.kind == tokPunct && tok.value == "{":
+			selections, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &operation{kind: OperationQuery, selections: selections, loc: tok.loc})
+		case tok.kind == tokName && (tok.value == OperationQuery || tok.value == OperationMutation ||
+			tok.value == OperationSubscription):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case tok.kind == tokName && tok.value == "fragment":
+			frag, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := doc.fragments[frag.name]; ok {
+				return nil, errorf(frag.loc, "fragment '%s' is defined twice", frag.name)
+			}
+			doc.fragments[frag.name] = frag
+		default:
+			return nil, p.unexpected(tok)
+		}
+	}
+
+	names := make(map[string]bool)
+	for _, op := range doc.operations {
+		if op.name == "" && len(doc.operations) > 1 {
+			return nil, errorf(op.loc, "anonymous operations must be the only operation of a document")
+		}
+		if op.name != "" && names[op.name] {
+			return nil, errorf(op.loc, "operation '%s' is defined twice", op.name)
+		}
+		names[op.name] = true
+	}
+	return doc, nil
+}
+
+// peek returns the next token without taking it
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+// next takes the next token
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// at reports whether the next token is the punctuator punct
+func (p *parser) at(punct string) bool {
+	tok := p.peek()
+	return tok.kind == tokPunct && tok.value == punct
+}
+
+// skip takes the next token if it is the punctuator punct, reporting
+// whether it did
+func (p *parser) skip(punct string) bool {
+	if p.at(punct) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// expect takes the punctuator punct, failing if the next token is another
+func (p *parser) expect(punct string) error {
+	if !p.skip(punct) {
+		return p.unexpected(p.peek())
+	}
+	return nil
+}
+
+// name takes a name
+func (p *parser) name() (token, error) {
+	tok := p.peek()
+	if tok.kind != tokName {
+		return tok, p.unexpected(tok)
+	}
+	p.pos++
+	return tok, nil
+}
+
+// unexpected describes tok as an error where another token was expected
+func (p *parser) unexpected(tok token) error {
+	if tok.kind == tokEOF {
+		return errorf(tok.loc, "unexpected end of document")
+	}
+	return errorf(tok.loc, "unexpected %q", tok.value)
+}
+
+// operation reads an operation definition, starting with its type
+func (p *parser) operation() (*operation, error) {
+	tok := p.next()
+	op := &operation{kind: tok.value, loc: tok.loc}
+	if p.peek().kind == tokName {
+		op.name = p.next().value
+	}
+
+	if p.skip("(") {
+		for !p.skip(")") {
+			def, err := p.variableDef()
+			if err != nil {
+				return nil, err
+			}
+			op.variables = append(op.variables, def)
+		}
+	}
+
+	var err error
+	if op.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if op.selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// variableDef reads a variable definition: $name: Type = default
+func (p *parser) variableDef() (*variableDef, error) {
+	loc := p.peek().loc
+	if err := p.expect("$"); err != nil {
+		return nil, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	typ, err := p.typeRef()
+	if err != nil {
+		return nil, err
+	}
+
+	def := &variableDef{name: name.value, typ: typ, loc: loc}
+	if p.skip("=") {
+		if def.defaultValue, err = p.value(true); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	return def, nil
+}
+
+// typeRef reads a type: Name, [Type], either followed by ! when non-null
+func (p *parser) typeRef() (*typeRef, error) {
+	loc := p.peek().loc
+	var t *typeRef
+	if p.skip("[") {
+		elem, err := p.typeRef()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		t = &typeRef{elem: elem, loc: loc}
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		t = &typeRef{name: name.value, loc: loc}
+	}
+	t.nonNull = p.skip("!")
+	return t, nil
+}
+
+// fragment reads a fragment definition
+func (p *parser) fragment() (*fragment, error) {
+	loc := p.next().loc
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if name.value == "on" {
+		return nil, p.unexpected(name)
+	}
+	if on, err := p.name(); err != nil || on.value != "on" {
+		return nil, p.unexpected(on)
+	}
+	typ, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+
+	frag := &fragment{name: name.value, typeCondition: typ.value, loc: loc}
+	if frag.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if frag.selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return frag, nil
+}
+
+// selectionSet reads the selections between braces, of which there must be
+// at least one
+func (p *parser) selectionSet() ([]selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var selections []selection
+	for !p.skip("}") {
+		sel, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, sel)
+	}
+	if len(selections) == 0 {
+		return nil, errorf(p.tokens[p.pos-1].loc, "selection sets must select something")
+	}
+	return selections, nil
+}
+
+// selection reads a field, fragment spread or inline fragment
+func (p *parser) selection() (selection, error) {
+	if p.at("...") {
+		return p.spread()
+	}
+
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	f := &field{name: name.value, loc: name.loc}
+	if p.skip(":") {
+		actual, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		f.alias, f.name = f.name, actual.value
+	}
+
+	if f.args, err = p.arguments(false); err != nil {
+		return nil, err
+	}
+	if f.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.at("{") {
+		if f.selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// spread reads a fragment spread or an inline fragment, starting at "..."
+func (p *parser) spread() (selection, error) {
+	loc := p.next().loc
+
+	if tok := p.peek(); tok.kind == tokName && tok.value != "on" {
+		p.pos++
+		directives, err := p.directives()
+		if err != nil {
+			return nil, err
+		}
+		return &fragmentSpread{name: tok.value, directives: directives, loc: loc}, nil
+	}
+
+	inline := &inlineFragment{loc: loc}
+	if tok := p.peek(); tok.kind == tokName {
+		p.pos++
+		typ, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		inline.typeCondition = typ.value
+	}
+	var err error
+	if inline.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if inline.selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return inline, nil
+}
+
+// arguments reads the arguments between parentheses, if there are any
+func (p *parser) arguments(constant bool) ([]*argument, error) {
+	if !p.skip("(") {
+		return nil, nil
+	}
+	var args []*argument
+	for !p.skip(")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		v, err := p.value(constant)
+		if err != nil {
+			return nil, err
+		}
+		for _, arg := range args {
+			if arg.name == name.value {
+				return nil, errorf(name.loc, "argument '%s' is given twice", name.value)
+			}
+		}
+		args = append(args, &argument{name: name.value, value: v, loc: name.loc})
+	}
+	if len(args) == 0 {
+		return nil, errorf(p.tokens[p.pos-1].loc, "argument lists must have arguments")
+	}
+	return args, nil
+}
+
+// directives reads the directives applied to something
+func (p *parser) directives() ([]*directive, error) {
+	var directives []*directive
+	for p.at("@") {
+		loc := p.next().loc
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.arguments(false)
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, &directive{name: name.value, args: args, loc: loc})
+	}
+	return directives, nil
+}
+
+// value reads a value; constant values, such as variables' defaults, may
+// not refer to variables
+func (p *parser) value(constant bool) (*value, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokInt:
+		return &value{kind: valueInt, raw: tok.value, loc: tok.loc}, nil
+	case tokFloat:
+		return &value{kind: valueFloat, raw: tok.value, loc: tok.loc}, nil
+	case tokString:
+		return &value{kind: valueString, raw: tok.value, loc: tok.loc}, nil
+	case tokName:
+		switch tok.value {
+		case "true", "false":
+			return &value{kind: valueBoolean, raw: tok.value, loc: tok.loc}, nil
+		case "null":
+			return &value{kind: valueNull, loc: tok.loc}, nil
+		}
+		return &value{kind: valueEnum, raw: tok.value, loc: tok.loc}, nil
+	case tokPunct:
+		switch tok.value {
+		case "$":
+			if constant {
+				return nil, errorf(tok.loc, "variables can't be used here")
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			return &value{kind: valueVariable, raw: name.value, loc: tok.loc}, nil
+		case "[":
+			list := &value{kind: valueList, loc: tok.loc}
+			for !p.skip("]") {
+				item, err := p.value(constant)
+				if err != nil {
+					return nil, err
+				}
+				list.list = append(list.list, item)
+			}
+			return list, nil
+		case "{":
+			obj := &value{kind: valueObject, loc: tok.loc}
+			for !p.skip("}") {
+				name, err := p.name()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expect(":"); err != nil {
+					return nil, err
+				}
+				v, err := p.value(constant)
+				if err != nil {
+					return nil, err
+				}
+				obj.fields = append(obj.fields, &objectField{name: name.value, value: v, loc: name.loc})
+			}
+			return obj, nil
+		}
+	}
+	return nil, p.unexpected(tok)
+}
@@ -0,0 +1,107 @@
+package graphql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse_Document(t *testing.T) {
+	doc, err := parse(`
+		query Runs($status: [String!] = ["failed"], $first: Int!) @include(if: true) {
+			recent: runs(status: $status, limit: $first) {
+				id
+				...RunFields
+				... on Run { status }
+				... @skip(if: false) { workflowName }
+			}
+		}
+
+		fragment RunFields on Run { startedAt }
+	`)
+	if err != nil {
+		t.Fatalf("parse returned error: %v", err)
+	}
+
+	op, err := doc.operation("")
+	if err != nil {
+		t.Fatalf("operation returned error: %v", err)
+	}
+	if op.kind != OperationQuery || op.name != "Runs" || len(op.directives) != 1 {
+		t.Errorf("Unexpected operation %+v", op)
+	}
+	if len(op.variables) != 2 || op.variables[0].typ.String() != "[String!]" || op.variables[1].typ.String() != "Int!" {
+		t.Fatalf("Unexpected variables %+v", op.variables)
+	}
+	if def := op.variables[0].defaultValue; def == nil || def.kind != valueList || def.list[0].raw != "failed" {
+		t.Errorf("Expected the default [\"failed\"], got %+v", def)
+	}
+
+	runs := op.selections[0].(*field)
+	if runs.alias != "recent" || runs.name != "runs" || runs.responseKey() != "recent" {
+		t.Errorf("Expected runs aliased as recent, got %+v", runs)
+	}
+	if len(runs.args) != 2 || runs.args[1].value.kind != valueVariable || runs.args[1].value.raw != "first" {
+		t.Errorf("Unexpected arguments %+v", runs.args)
+	}
+	if len(runs.selections) != 4 {
+		t.Fatalf("Expected 4 selections, got %d", len(runs.selections))
+	}
+	if spread, ok := runs.selections[1].(*fragmentSpread); !ok || spread.name != "RunFields" {
+		t.Errorf("Expected a spread of RunFields, got %+v", runs.selections[1])
+	}
+	if inline, ok := runs.selections[2].(*inlineFragment); !ok || inline.typeCondition != "Run" {
+		t.Errorf("Expected an inline fragment on Run, got %+v", runs.selections[2])
+	}
+	if inline, ok := runs.selections[3].(*inlineFragment); !ok || inline.typeCondition != "" || len(inline.directives) != 1 {
+		t.Errorf("Expected an inline fragment with @skip, got %+v", runs.selections[3])
+	}
+
+	if frag := doc.fragments["RunFields"]; frag == nil || frag.typeCondition != "Run" {
+		t.Errorf("Expected fragment RunFields on Run, got %+v", frag)
+	}
+}
+
+func TestParse_Operations(t *testing.T) {
+	doc, err := parse(`query A { a } subscription B { b }`)
+	if err != nil {
+		t.Fatalf("parse returned error: %v", err)
+	}
+	if _, err := doc.operation(""); err == nil {
+		t.Error("Expected an error picking an operation of several without a name")
+	}
+	if op, err := doc.operation("B"); err != nil || op.kind != OperationSubscription {
+		t.Errorf("Expected subscription B, got %+v, %v", op, err)
+	}
+	if _, err := doc.operation("C"); err == nil {
+		t.Error("Expected an error picking an unknown operation")
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{``, "the document is empty"},
+		{`{ }`, "must select something"},
+		{`{ a(x: 1, x: 2) }`, "given twice"},
+		{`{ a } { b }`, "anonymous operations"},
+		{`query A { a } query A { b }`, "defined twice"},
+		{`query ($v: Int = $w) { a }`, "variables can't be used here"},
+		{`fragment on on T { a }`, `unexpected "on"`},
+		{`{ a(x: ) }`, `unexpected ")"`},
+		{`{ a`, "unexpected end of document"},
+		{`type Query { a: Int }`, `unexpected "type"`},
+	}
+
+	for _, tt := range tests {
+		_, err := parse(tt.src)
+		if err == nil {
+			t.Errorf("parse(%q): expected an error", tt.src)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("parse(%q): expected error containing %q, got %v", tt.src, tt.expected, err)
+		}
+	}
+}
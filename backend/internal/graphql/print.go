@@ -0,0 +1,118 @@
+package graphql
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// String returns the schema in the GraphQL schema definition language,
+// for clients and tools that generate code from it
+func (s *Schema) String() string {
+	var b strings.Builder
+
+	var names []string
+	for name, t := range s.types {
+		if scalar, ok := t.(*Scalar); ok && isBuiltin(scalar) {
+			continue
+		}
+		names = append(names, name)
+	}
+	// The root types come first, the others by name
+	sort.Slice(names, func(i, j int) bool {
+		ri, rj := s.rootOrder(names[i]), s.rootOrder(names[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return names[i] < names[j]
+	})
+
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		switch t := s.types[name].(type) {
+		case *Scalar:
+			printDescription(&b, t.Description, "")
+			fmt.Fprintf(&b, "scalar %s\n", t.Name)
+		case *Object:
+			printDescription(&b, t.Description, "")
+			fmt.Fprintf(&b, "type %s {\n", t.Name)
+			for _, f := range t.Fields {
+				printDescription(&b, f.Description, "  ")
+				fmt.Fprintf(&b, "  %s%s: %s\n", f.Name, printArgs(f.Args), f.Type)
+			}
+			b.WriteString("}\n")
+		}
+	}
+	return b.String()
+}
+
+// rootOrder sorts the query type first and the subscription type second
+func (s *Schema) rootOrder(name string) int {
+	switch {
+	case name == s.Query.Name:
+		return 0
+	case s.Subscription != nil && name == s.Subscription.Name:
+		return 1
+	}
+	return 2
+}
+
+// isBuiltin reports whether a scalar is one of the built-in scalars, which
+// schemas don't declare
+func isBuiltin(s *Scalar) bool {
+	return s == String || s == Int || s == Float || s == Boolean || s == ID
+}
+
+// printDescription writes a description as a block string above what it
+// describes
+func printDescription(b *strings.Builder, description, indent string) {
+	if description == "" {
+		return
+	}
+	description = strings.ReplaceAll(description, `"""`, `\"""`)
+	if !strings.Contains(description, "\n") {
+		fmt.Fprintf(b, "%s\"\"\"%s\"\"\"\n", indent, description)
+		return
+	}
+	fmt.Fprintf(b, "%s\"\"\"\n", indent)
+	for _, line := range strings.Split(description, "\n") {
+		fmt.Fprintf(b, "%s%s\n", indent, line)
+	}
+	fmt.Fprintf(b, "%s\"\"\"\n", indent)
+}
+
+// printArgs returns a field's argument definitions, or nothing if it takes
+// no arguments
+func printArgs(args []*Argument) string {
+	if len(args) == 0 {
+		return ""
+	}
+	defs := make([]string, len(args))
+	for i, arg := range args {
+		defs[i] = fmt.Sprintf("%s: %s", arg.Name, arg.Type)
+		if arg.Default != nil {
+			defs[i] += " = " + printValue(arg.Default)
+		}
+	}
+	return "(" + strings.Join(defs, ", ") + ")"
+}
+
+// printValue returns an input value as a GraphQL literal
+func printValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = printValue(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%v", v)
+}
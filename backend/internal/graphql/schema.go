@@ -0,0 +1,399 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Type is a type of a schema: a *Scalar, an *Object, or a *List or
+// *NonNull of another type
+type Type interface {
+	String() string
+}
+
+// Scalar is a leaf type. Serialize turns what resolvers return into the
+// value responses carry; ParseValue turns the value of an argument or
+// variable into what resolvers receive.
+type Scalar struct {
+	Name        string
+	Description string
+	Serialize   func(v interface{}) (interface{}, error)
+	ParseValue  func(v interface{}) (interface{}, error)
+}
+
+func (s *Scalar) String() string { return s.Name }
+
+// Object is a type with fields, which queries select from
+type Object struct {
+	Name        string
+	Description string
+	Fields      []*Field
+}
+
+func (o *Object) String() string { return o.Name }
+
+// field returns the object's field named name, or nil
+func (o *Object) field(name string) *Field {
+	for _, f := range o.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// List is a list of values of a type
+type List struct {
+	Of Type
+}
+
+func (l *List) String() string { return "[" + l.Of.String() + "]" }
+
+// NonNull is a type whose values are never null
+type NonNull struct {
+	Of Type
+}
+
+func (n *NonNull) String() string { return n.Of.String() + "!" }
+
+// Field is a field of an object. Resolve returns its value from the object
+// the field is selected on; fields without Resolve take the value of the
+// same key when the object is a map[string]interface{}.
+//
+// Fields of the subscription type set Subscribe instead, returning a
+// channel of events that it closes once there are no more or ctx is done.
+// Each event is resolved with Resolve as the field's source object, or is
+// the field's value when it has no Resolve.
+type Field struct {
+	Name        string
+	Description string
+	Type        Type
+	Args        []*Argument
+	Resolve     func(p ResolveParams) (interface{}, error)
+	Subscribe   func(p ResolveParams) (<-chan interface{}, error)
+}
+
+// argument returns the field's argument named name, or nil
+func (f *Field) argument(name string) *Argument {
+	for _, arg := range f.Args {
+		if arg.Name == name {
+			return arg
+		}
+	}
+	return nil
+}
+
+// Argument is an argument of a field. Its type must be an input type: a
+// scalar, or a list or non-null of one. Default, if set, is its value when
+// a query leaves it out.
+type Argument struct {
+	Name        string
+	Description string
+	Type        Type
+	Default     interface{}
+}
+
+// ResolveParams are what a field is resolved with: the request's context,
+// the object the field is selected on and the field's arguments. Arguments
+// left out without a default are missing from Args.
+type ResolveParams struct {
+	Context context.Context
+	Source  interface{}
+	Args    map[string]interface{}
+}
+
+// Schema is the types a GraphQL API serves, from its root types
+type Schema struct {
+	Query        *Object
+	Subscription *Object // nil when there are no subscriptions
+
+	types map[string]Type // named types by name
+}
+
+// NewSchema creates a schema of a query type and, optionally, a
+// subscription type, checking that the types they reach are consistent
+func NewSchema(query, subscription *Object) (*Schema, error) {
+	s := &Schema{Query: query, Subscription: subscription, types: make(map[string]Type)}
+	for _, scalar := range []*Scalar{String, Int, Float, Boolean, ID} {
+		s.types[scalar.Name] = scalar
+	}
+
+	if query == nil {
+		return nil, fmt.Errorf("the schema needs a query type")
+	}
+	roots := []*Object{query}
+	if subscription != nil {
+		roots = append(roots, subscription)
+	}
+	for _, root := range roots {
+		if err := s.addType(root); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, f := range query.Fields {
+		if f.Subscribe != nil {
+			return nil, fmt.Errorf("field %s.%s: only subscription fields subscribe", query.Name, f.Name)
+		}
+	}
+	if subscription != nil {
+		for _, f := range subscription.Fields {
+			if f.Subscribe == nil {
+				return nil, fmt.Errorf("field %s.%s: subscription fields must subscribe", subscription.Name, f.Name)
+			}
+		}
+	}
+	return s, nil
+}
+
+// addType adds a type and the types it refers to to the schema's named types
+func (s *Schema) addType(t Type) error {
+	switch t := t.(type) {
+	case *List:
+		return s.addType(t.Of)
+	case *NonNull:
+		if _, ok := t.Of.(*NonNull); ok {
+			return fmt.Errorf("type %s: non-null types can't be non-null again", t)
+		}
+		return s.addType(t.Of)
+	case *Scalar:
+		if t.Serialize == nil || t.ParseValue == nil {
+			return fmt.Errorf("scalar %s needs Serialize and ParseValue", t.Name)
+		}
+		return s.addNamed(t.Name, t)
+	case *Object:
+		if _, ok := s.types[t.Name]; ok {
+			return s.addNamed(t.Name, t)
+		}
+		if err := s.addNamed(t.Name, t); err != nil {
+			return err
+		}
+		if len(t.Fields) == 0 {
+			return fmt.Errorf("object %s has no fields", t.Name)
+		}
+		names := make(map[string]bool)
+		for _, f := range t.Fields {
+			if names[f.Name] {
+				return fmt.Errorf("object %s has two fields named %s", t.Name, f.Name)
+			}
+			names[f.Name] = true
+			if f.Type == nil {
+				return fmt.Errorf("field %s.%s has no type", t.Name, f.Name)
+			}
+			if err := s.addType(f.Type); err != nil {
+				return err
+			}
+			for _, arg := range f.Args {
+				if !isInputType(arg.Type) {
+					return fmt.Errorf("argument %s of %s.%s must be a scalar or a list of them", arg.Name, t.Name, f.Name)
+				}
+				if err := s.addType(arg.Type); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case nil:
+		return fmt.Errorf("type is nil")
+	default:
+		return fmt.Errorf("unsupported type %T", t)
+	}
+}
+
+// addNamed records a named type, failing if another type has its name
+func (s *Schema) addNamed(name string, t Type) error {
+	if existing, ok := s.types[name]; ok && existing != t {
+		return fmt.Errorf("two types are named %s", name)
+	}
+	s.types[name] = t
+	return nil
+}
+
+// isInputType reports whether arguments and variables may have type t
+func isInputType(t Type) bool {
+	switch t := t.(type) {
+	case *List:
+		return isInputType(t.Of)
+	case *NonNull:
+		return isInputType(t.Of)
+	case *Scalar:
+		return true
+	}
+	return false
+}
+
+// namedType returns the scalar or object at the core of a list or non-null type
+func namedType(t Type) Type {
+	for {
+		switch wrapped := t.(type) {
+		case *List:
+			t = wrapped.Of
+		case *NonNull:
+			t = wrapped.Of
+		default:
+			return t
+		}
+	}
+}
+
+// The built-in scalars
+var (
+	String = &Scalar{
+		Name:        "String",
+		Description: "UTF-8 text",
+		Serialize: func(v interface{}) (interface{}, error) {
+			switch v := v.(type) {
+			case string:
+				return v, nil
+			case fmt.Stringer:
+				return v.String(), nil
+			}
+			return nil, fmt.Errorf("String can't represent %T", v)
+		},
+		ParseValue: func(v interface{}) (interface{}, error) {
+			if s, ok := v.(string); ok {
+				return s, nil
+			}
+			return nil, fmt.Errorf("String can't represent %s", describe(v))
+		},
+	}
+
+	Int = &Scalar{
+		Name:        "Int",
+		Description: "A signed 32-bit integer",
+		Serialize: func(v interface{}) (interface{}, error) {
+			n, ok := toInt(v)
+			if !ok {
+				return nil, fmt.Errorf("Int can't represent %v", v)
+			}
+			return n, nil
+		},
+		ParseValue: func(v interface{}) (interface{}, error) {
+			n, ok := toInt(v)
+			if !ok {
+				return nil, fmt.Errorf("Int can't represent %s", describe(v))
+			}
+			return n, nil
+		},
+	}
+
+	Float = &Scalar{
+		Name:        "Float",
+		Description: "A double-precision floating point number",
+		Serialize: func(v interface{}) (interface{}, error) {
+			f, ok := toFloat(v)
+			if !ok {
+				return nil, fmt.Errorf("Float can't represent %v", v)
+			}
+			return f, nil
+		},
+		ParseValue: func(v interface{}) (interface{}, error) {
+			f, ok := toFloat(v)
+			if !ok {
+				return nil, fmt.Errorf("Float can't represent %s", describe(v))
+			}
+			return f, nil
+		},
+	}
+
+	Boolean = &Scalar{
+		Name:        "Boolean",
+		Description: "true or false",
+		Serialize: func(v interface{}) (interface{}, error) {
+			if b, ok := v.(bool); ok {
+				return b, nil
+			}
+			return nil, fmt.Errorf("Boolean can't represent %T", v)
+		},
+		ParseValue: func(v interface{}) (interface{}, error) {
+			if b, ok := v.(bool); ok {
+				return b, nil
+			}
+			return nil, fmt.Errorf("Boolean can't represent %s", describe(v))
+		},
+	}
+
+	ID = &Scalar{
+		Name:        "ID",
+		Description: "A unique identifier, serialized as a string",
+		Serialize: func(v interface{}) (interface{}, error) {
+			if s, ok := v.(string); ok {
+				return s, nil
+			}
+			if n, ok := toInt(v); ok {
+				return strconv.Itoa(n), nil
+			}
+			return nil, fmt.Errorf("ID can't represent %T", v)
+		},
+		ParseValue: func(v interface{}) (interface{}, error) {
+			if s, ok := v.(string); ok {
+				return s, nil
+			}
+			if n, ok := toInt(v); ok {
+				return strconv.Itoa(n), nil
+			}
+			return nil, fmt.Errorf("ID can't represent %s", describe(v))
+		},
+	}
+)
+
+// toInt returns v as an int if it is a whole number in the range of Int
+func toInt(v interface{}) (int, bool) {
+	var f float64
+	switch v := v.(type) {
+	case int:
+		f = float64(v)
+	case int32:
+		f = float64(v)
+	case int64:
+		f = float64(v)
+	case float64:
+		f = v
+	default:
+		return 0, false
+	}
+	if f != math.Trunc(f) || f < math.MinInt32 || f > math.MaxInt32 {
+		return 0, false
+	}
+	return int(f), true
+}
+
+// toFloat returns v as a float64 if it is a finite number
+func toFloat(v interface{}) (float64, bool) {
+	var f float64
+	switch v := v.(type) {
+	case int:
+		f = float64(v)
+	case int32:
+		f = float64(v)
+	case int64:
+		f = float64(v)
+	case float32:
+		f = float64(v)
+	case float64:
+		f = v
+	default:
+		return 0, false
+	}
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, false
+	}
+	return f, true
+}
+
+// describe describes an input value for error messages
+func describe(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(v)
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}:
+		return "an object"
+	}
+	return fmt.Sprintf("%v", v)
+}
@@ -0,0 +1,316 @@
+package graphql
+
+import (
+	"fmt"
+)
+
+// directiveArgs are the arguments of the directives queries may use,
+// @skip and @include
+var directiveArgs = []*Argument{{Name: "if", Type: &NonNull{Of: Boolean}}}
+
+// validator checks one operation of a document against a schema
+type validator struct {
+	schema    *Schema
+	doc       *document
+	op        *operation
+	variables map[string]*variableDef
+	spreading map[string]bool // fragments being checked, to find cycles
+	errors    []*Error
+}
+
+// validate checks that every operation of a document selects fields its
+// schema has, with arguments of the right types and variables it defines
+func (s *Schema) validate(doc *document) []*Error {
+	var errs []*Error
+	seen := make(map[string]bool)
+	for _, op := range doc.operations {
+		v := &validator{schema: s, doc: doc, op: op, variables: make(map[string]*variableDef),
+			spreading: make(map[string]bool)}
+		v.operation()
+		for _, err := range v.errors {
+			// Operations spreading the same fragment find its errors again
+			key := err.Error()
+			if !seen[key] {
+				seen[key] = true
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
+
+func (v *validator) errorf(loc Location, format string, args ...interface{}) {
+	v.errors = append(v.errors, errorf(loc, format, args...))
+}
+
+// operation checks the validator's operation
+func (v *validator) operation() {
+	op := v.op
+	for _, def := range op.variables {
+		if _, ok := v.variables[def.name]; ok {
+			v.errorf(def.loc, "variable $%s is defined twice", def.name)
+		}
+		v.variables[def.name] = def
+
+		t := v.schema.typeOf(def.typ)
+		if t == nil {
+			v.errorf(def.typ.loc, "variable $%s has unknown input type %s", def.name, def.typ)
+			continue
+		}
+		if def.defaultValue != nil {
+			if _, err := coerceLiteral(def.defaultValue, t, nil); err != nil {
+				v.errorf(def.defaultValue.loc, "default of variable $%s: %v", def.name, err)
+			}
+		}
+	}
+	v.directives(op.directives)
+
+	var root *Object
+	switch op.kind {
+	case OperationQuery:
+		root = v.schema.Query
+	case OperationSubscription:
+		root = v.schema.Subscription
+	}
+	if root == nil {
+		v.errorf(op.loc, "the schema has no %s operations", op.kind)
+		return
+	}
+	if op.kind == OperationSubscription {
+		if fields := v.rootFields(op.selections); fields != 1 {
+			v.errorf(op.loc, "subscriptions must select exactly one field, not %d", fields)
+		}
+	}
+	v.selections(root, op.selections)
+}
+
+// rootFields counts the fields a subscription selects at its root
+func (v *validator) rootFields(selections []selection) int {
+	n := 0
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *field:
+			if sel.name != "__typename" {
+				n++
+			}
+		case *inlineFragment:
+			n += v.rootFields(sel.selections)
+		case *fragmentSpread:
+			if frag, ok := v.doc.fragments[sel.name]; ok && !v.spreading[sel.name] {
+				v.spreading[sel.name] = true
+				n += v.rootFields(frag.selections)
+				delete(v.spreading, sel.name)
+			}
+		}
+	}
+	return n
+}
+
+// selections checks selections made on an object of type parent
+func (v *validator) selections(parent *Object, selections []selection) {
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *field:
+			v.field(parent, sel)
+		case *inlineFragment:
+			v.directives(sel.directives)
+			if v.typeCondition(parent, sel.typeCondition, sel.loc) {
+				v.selections(parent, sel.selections)
+			}
+		case *fragmentSpread:
+			v.directives(sel.directives)
+			frag, ok := v.doc.fragments[sel.name]
+			if !ok {
+				v.errorf(sel.loc, "unknown fragment '%s'", sel.name)
+				continue
+			}
+			if v.spreading[sel.name] {
+				v.errorf(sel.loc, "fragment '%s' spreads itself", sel.name)
+				continue
+			}
+			v.directives(frag.directives)
+			if v.typeCondition(parent, frag.typeCondition, frag.loc) {
+				v.spreading[sel.name] = true
+				v.selections(parent, frag.selections)
+				delete(v.spreading, sel.name)
+			}
+		}
+	}
+}
+
+// typeCondition checks that a fragment on type name applies to objects of
+// type parent. Fragments without a type condition apply to any object.
+func (v *validator) typeCondition(parent *Object, name string, loc Location) bool {
+	if name == "" || name == parent.Name {
+		return true
+	}
+	if _, ok := v.schema.types[name].(*Object); !ok {
+		v.errorf(loc, "unknown type '%s'", name)
+		return false
+	}
+	v.errorf(loc, "fragments on %s can't be spread within %s", name, parent.Name)
+	return false
+}
+
+// field checks a field selected on an object of type parent
+func (v *validator) field(parent *Object, f *field) {
+	v.directives(f.directives)
+
+	if f.name == "__typename" {
+		if len(f.args) > 0 {
+			v.errorf(f.args[0].loc, "__typename takes no arguments")
+		}
+		if f.selections != nil {
+			v.errorf(f.loc, "__typename is a String and can't have selections")
+		}
+		return
+	}
+
+	def := parent.field(f.name)
+	if def == nil {
+		v.errorf(f.loc, "type %s has no field '%s'", parent.Name, f.name)
+		return
+	}
+	v.arguments(fmt.Sprintf("field '%s'", f.name), def.Args, f.args, f.loc)
+
+	switch t := namedType(def.Type).(type) {
+	case *Object:
+		if f.selections == nil {
+			v.errorf(f.loc, "field '%s' of type %s must select fields", f.name, def.Type)
+			return
+		}
+		v.selections(t, f.selections)
+	default:
+		if f.selections != nil {
+			v.errorf(f.loc, "field '%s' of type %s can't have selections", f.name, def.Type)
+		}
+	}
+}
+
+// directives checks the directives applied to something, which may only
+// be @skip and @include
+func (v *validator) directives(directives []*directive) {
+	for _, d := range directives {
+		if d.name != "skip" && d.name != "include" {
+			v.errorf(d.loc, "unknown directive @%s", d.name)
+			continue
+		}
+		v.arguments("directive @"+d.name, directiveArgs, d.args, d.loc)
+	}
+}
+
+// arguments checks the arguments given to what, a field or directive,
+// against its argument definitions
+func (v *validator) arguments(what string, defs []*Argument, args []*argument, loc Location) {
+	for _, arg := range args {
+		if def := argumentDef(defs, arg.name); def == nil {
+			v.errorf(arg.loc, "%s has no argument '%s'", what, arg.name)
+		}
+	}
+
+	for _, def := range defs {
+		var arg *argument
+		for _, a := range args {
+			if a.name == def.Name {
+				arg = a
+			}
+		}
+		if arg == nil {
+			if _, ok := def.Type.(*NonNull); ok && def.Default == nil {
+				v.errorf(loc, "%s needs argument '%s' of type %s", what, def.Name, def.Type)
+			}
+			continue
+		}
+		v.value(arg.value, def.Type, def.Default != nil, fmt.Sprintf("argument '%s'", def.Name))
+	}
+}
+
+// argumentDef returns the argument definition named name, or nil
+func argumentDef(defs []*Argument, name string) *Argument {
+	for _, def := range defs {
+		if def.Name == name {
+			return def
+		}
+	}
+	return nil
+}
+
+// value checks that a value may be given to an input of type t. Inputs
+// with a default may be given nullable variables even if they are non-null.
+func (v *validator) value(val *value, t Type, hasDefault bool, what string) {
+	if val.kind == valueVariable {
+		def, ok := v.variables[val.raw]
+		if !ok {
+			v.errorf(val.loc, "variable $%s is not defined by operation %s", val.raw, v.operationName())
+			return
+		}
+		varType := v.schema.typeOf(def.typ)
+		if varType == nil {
+			return
+		}
+		if !assignable(varType, t, hasDefault || def.defaultValue != nil) {
+			v.errorf(val.loc, "variable $%s of type %s can't be used as %s of type %s", val.raw, def.typ, what, t)
+		}
+		return
+	}
+
+	switch t := t.(type) {
+	case *NonNull:
+		if val.kind == valueNull {
+			v.errorf(val.loc, "%s: expected %s, got null", what, t)
+			return
+		}
+		v.value(val, t.Of, false, what)
+	case *List:
+		if val.kind != valueList {
+			v.value(val, t.Of, false, what)
+			return
+		}
+		for _, item := range val.list {
+			v.value(item, t.Of, false, what)
+		}
+	case *Scalar:
+		if val.kind == valueNull {
+			return
+		}
+		literal, err := literalValue(val)
+		if err == nil {
+			_, err = t.ParseValue(literal)
+		}
+		if err != nil {
+			v.errorf(val.loc, "%s: %v", what, err)
+		}
+	}
+}
+
+// operationName names the validator's operation in error messages
+func (v *validator) operationName() string {
+	if v.op.name == "" {
+		return "(anonymous)"
+	}
+	return "'" + v.op.name + "'"
+}
+
+// assignable reports whether a variable of type from may be used where
+// type to is expected. A nullable variable may stand in for a non-null
+// input that has a default.
+func assignable(from, to Type, hasDefault bool) bool {
+	if nn, ok := to.(*NonNull); ok {
+		fromNN, ok := from.(*NonNull)
+		if !ok {
+			return hasDefault && assignable(from, nn.Of, false)
+		}
+		return assignable(fromNN.Of, nn.Of, false)
+	}
+	if nn, ok := from.(*NonNull); ok {
+		return assignable(nn.Of, to, false)
+	}
+	if toList, ok := to.(*List); ok {
+		fromList, ok := from.(*List)
+		return ok && assignable(fromList.Of, toList.Of, false)
+	}
+	if _, ok := from.(*List); ok {
+		return false
+	}
+	return from == to
+}
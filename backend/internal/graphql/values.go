@@ -0,0 +1,210 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// typeOf returns the schema type a document's type reference names, or nil
+// if it names no input type of the schema
+func (s *Schema) typeOf(ref *typeRef) Type {
+	var t Type
+	if ref.elem != nil {
+		elem := s.typeOf(ref.elem)
+		if elem == nil {
+			return nil
+		}
+		t = &List{Of: elem}
+	} else {
+		named, ok := s.types[ref.name]
+		if !ok || !isInputType(named) {
+			return nil
+		}
+		t = named
+	}
+	if ref.nonNull {
+		t = &NonNull{Of: t}
+	}
+	return t
+}
+
+// coerceVariables returns the values of an operation's variables: those the
+// request gave, coerced to their declared types, or their defaults
+func (s *Schema) coerceVariables(op *operation, given map[string]interface{}) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	for _, def := range op.variables {
+		t := s.typeOf(def.typ)
+		v, ok := given[def.name]
+		switch {
+		case ok:
+			coerced, err := coerceInput(v, t)
+			if err != nil {
+				return nil, errorf(def.loc, "variable $%s: %v", def.name, err)
+			}
+			values[def.name] = coerced
+		case def.defaultValue != nil:
+			coerced, err := coerceLiteral(def.defaultValue, t, nil)
+			if err != nil {
+				return nil, errorf(def.loc, "variable $%s: %v", def.name, err)
+			}
+			values[def.name] = coerced
+		case def.typ.nonNull:
+			return nil, errorf(def.loc, "variable $%s of required type %s was not provided", def.name, def.typ)
+		}
+	}
+	return values, nil
+}
+
+// coerceArguments returns the values of a field's or directive's arguments:
+// those given, or their defaults
+func coerceArguments(defs []*Argument, args []*argument, variables map[string]interface{}) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	for _, def := range defs {
+		var arg *argument
+		for _, a := range args {
+			if a.name == def.Name {
+				arg = a
+			}
+		}
+
+		given := arg != nil
+		if given && arg.value.kind == valueVariable {
+			_, given = variables[arg.value.raw]
+		}
+		if !given {
+			if def.Default != nil {
+				values[def.Name] = def.Default
+			} else if _, ok := def.Type.(*NonNull); ok {
+				return nil, fmt.Errorf("argument '%s' of type %s is required", def.Name, def.Type)
+			}
+			continue
+		}
+
+		v, err := coerceLiteral(arg.value, def.Type, variables)
+		if err != nil {
+			return nil, errorf(arg.loc, "argument '%s': %v", def.Name, err)
+		}
+		values[def.Name] = v
+	}
+	return values, nil
+}
+
+// coerceLiteral returns the value a literal gives an input of type t.
+// Variables in it take their values from variables.
+func coerceLiteral(v *value, t Type, variables map[string]interface{}) (interface{}, error) {
+	if v.kind == valueVariable {
+		value := variables[v.raw]
+		if _, ok := t.(*NonNull); ok && value == nil {
+			return nil, fmt.Errorf("variable $%s is null but %s is required", v.raw, t)
+		}
+		return value, nil
+	}
+
+	switch t := t.(type) {
+	case *NonNull:
+		if v.kind == valueNull {
+			return nil, fmt.Errorf("expected %s, got null", t)
+		}
+		return coerceLiteral(v, t.Of, variables)
+	case *List:
+		if v.kind == valueNull {
+			return nil, nil
+		}
+		if v.kind != valueList {
+			// A single value is a list of one
+			item, err := coerceLiteral(v, t.Of, variables)
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{item}, nil
+		}
+		items := make([]interface{}, len(v.list))
+		for i, item := range v.list {
+			coerced, err := coerceLiteral(item, t.Of, variables)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = coerced
+		}
+		return items, nil
+	case *Scalar:
+		if v.kind == valueNull {
+			return nil, nil
+		}
+		literal, err := literalValue(v)
+		if err != nil {
+			return nil, err
+		}
+		return t.ParseValue(literal)
+	}
+	return nil, fmt.Errorf("%s is not an input type", t)
+}
+
+// literalValue returns a scalar literal as the Go value JSON would decode
+// it to, but for integers, which are ints
+func literalValue(v *value) (interface{}, error) {
+	switch v.kind {
+	case valueInt:
+		n, err := strconv.ParseInt(v.raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %s", v.raw)
+		}
+		return n, nil
+	case valueFloat:
+		f, err := strconv.ParseFloat(v.raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s", v.raw)
+		}
+		return f, nil
+	case valueString:
+		return v.raw, nil
+	case valueBoolean:
+		return v.raw == "true", nil
+	case valueEnum:
+		return nil, fmt.Errorf("unexpected enum value %s", v.raw)
+	case valueList:
+		return nil, fmt.Errorf("unexpected list")
+	case valueObject:
+		return nil, fmt.Errorf("unexpected object")
+	}
+	return nil, nil
+}
+
+// coerceInput returns a value a request gave for an input of type t, such
+// as a variable decoded from JSON, as the type's resolvers expect it
+func coerceInput(v interface{}, t Type) (interface{}, error) {
+	switch t := t.(type) {
+	case *NonNull:
+		if v == nil {
+			return nil, fmt.Errorf("expected %s, got null", t)
+		}
+		return coerceInput(v, t.Of)
+	case *List:
+		if v == nil {
+			return nil, nil
+		}
+		list, ok := v.([]interface{})
+		if !ok {
+			item, err := coerceInput(v, t.Of)
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{item}, nil
+		}
+		items := make([]interface{}, len(list))
+		for i, item := range list {
+			coerced, err := coerceInput(item, t.Of)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = coerced
+		}
+		return items, nil
+	case *Scalar:
+		if v == nil {
+			return nil, nil
+		}
+		return t.ParseValue(v)
+	}
+	return nil, fmt.Errorf("%s is not an input type", t)
+}
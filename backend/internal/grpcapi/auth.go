@@ -0,0 +1,133 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"strings"
+
+	"gantry/internal/auth"
+	"gantry/internal/grpcapi/gantryv1"
+	"gantry/internal/server"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// userKey is the call context key of the signed-in user's name
+type userKey struct{}
+
+// methodPermissions are the permissions the methods need, as their HTTP
+// endpoints do
+var methodPermissions = map[string]string{
+	gantryv1.Gantry_Upload_FullMethodName:     auth.PermEditWorkflows,
+	gantryv1.Gantry_Trigger_FullMethodName:    auth.PermTriggerRuns,
+	gantryv1.Gantry_GetRun_FullMethodName:     auth.PermViewRuns,
+	gantryv1.Gantry_StreamLogs_FullMethodName: auth.PermViewLogs,
+}
+
+// rateLimitedMethods are the methods limited by RATE_LIMIT
+var rateLimitedMethods = map[string]bool{
+	gantryv1.Gantry_Upload_FullMethodName:  true,
+	gantryv1.Gantry_Trigger_FullMethodName: true,
+}
+
+func (s *Service) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	username, err := s.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	if username != "" {
+		ctx = context.WithValue(ctx, userKey{}, username)
+	}
+	return handler(ctx, req)
+}
+
+func (s *Service) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if _, err := s.authorize(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// authorize lets a call through only when it carries a valid access token
+// of a user whose role has the method's permission, and its client hasn't
+// used up its share of rate-limited calls. Without authentication every
+// call has the permission. It returns the name of the signed-in user.
+func (s *Service) authorize(ctx context.Context, method string) (string, error) {
+	permission, ok := methodPermissions[method]
+	if !ok {
+		return "", status.Errorf(codes.PermissionDenied, "%s needs a permission no role has", method)
+	}
+
+	username := ""
+	if s.server.AuthEnabled() {
+		token := bearerToken(ctx)
+		if token == "" {
+			return "", status.Error(codes.Unauthenticated, "authentication required")
+		}
+		var err error
+		if username, err = s.server.Authenticate(token); err != nil {
+			return "", status.Errorf(codes.Unauthenticated, "authentication failed: %v", err)
+		}
+		if err := s.server.Authorize(username, permission, method); err != nil {
+			if errors.Is(err, server.ErrForbidden) {
+				return "", status.Errorf(codes.PermissionDenied, "not allowed: %v", err)
+			}
+			return "", status.Errorf(codes.Internal, "not allowed: %v", err)
+		}
+	}
+
+	if rateLimitedMethods[method] {
+		if wait, ok := s.server.AllowRequest(s.client(ctx, username)); !ok {
+			return "", status.Errorf(codes.ResourceExhausted, "too many requests, try again in %d seconds",
+				int(math.Ceil(wait.Seconds())))
+		}
+	}
+	return username, nil
+}
+
+// bearerToken returns the access token of a call's authorization metadata
+func bearerToken(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		if token, ok := strings.CutPrefix(value, "Bearer "); ok {
+			return token
+		}
+	}
+	return ""
+}
+
+// client names who made a call, for rate limiting, as the HTTP API does
+func (s *Service) client(ctx context.Context, username string) string {
+	if username != "" {
+		return "user:" + username
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	if s.server.TrustProxyHeaders() {
+		if ip := md.Get("x-real-ip"); len(ip) > 0 && ip[0] != "" {
+			return "ip:" + strings.TrimSpace(ip[0])
+		}
+		// The proxy appends the address it was called from, so the last in
+		// the list is the only one the client can't make up
+		if forwarded := md.Get("x-forwarded-for"); len(forwarded) > 0 {
+			addrs := strings.Split(forwarded[len(forwarded)-1], ",")
+			return "ip:" + strings.TrimSpace(addrs[len(addrs)-1])
+		}
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "ip:unknown"
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+	return "ip:" + host
+}
@@ -0,0 +1,110 @@
+package grpcapi
+
+import (
+	"time"
+
+	"gantry/internal/grpcapi/gantryv1"
+	"gantry/internal/models"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// runMessage converts a run to its message, with its jobs in the order
+// they run
+func runMessage(run *models.WorkflowRun) *gantryv1.Run {
+	msg := &gantryv1.Run{
+		Id:           run.ID,
+		WorkflowId:   run.WorkflowID,
+		WorkflowName: run.WorkflowName,
+		Status:       run.Status,
+		StartedAt:    timestamp(run.StartedAt),
+		RerunOf:      run.RerunOf,
+		Variables:    run.Variables,
+		Env:          run.Env,
+		Labels:       run.Labels,
+	}
+	if run.CompletedAt != nil {
+		msg.CompletedAt = timestamp(*run.CompletedAt)
+	}
+	if t := run.Trigger; t != nil {
+		msg.Trigger = &gantryv1.Trigger{
+			Event:       t.Event,
+			Action:      t.Action,
+			Actor:       t.Actor,
+			Branch:      t.Branch,
+			Command:     t.Command,
+			PullRequest: int32(t.PullRequest),
+			Repository:  t.Repository,
+			Sha:         t.SHA,
+		}
+		if c := t.Commit; c != nil {
+			msg.Trigger.Commit = &gantryv1.Commit{
+				Message:   c.Message,
+				Author:    c.Author,
+				Url:       c.URL,
+				Timestamp: timestamp(c.Timestamp),
+			}
+		}
+	}
+	for _, name := range run.JobNames() {
+		msg.Jobs = append(msg.Jobs, jobMessage(name, run.Jobs[name]))
+	}
+	return msg
+}
+
+// jobMessage converts a job of a run to its message
+func jobMessage(name string, job models.Job) *gantryv1.Job {
+	msg := &gantryv1.Job{
+		Name:          name,
+		Status:        job.Status,
+		RunsOn:        job.RunsOn,
+		Needs:         job.Needs,
+		Environment:   job.Environment,
+		Call:          job.Call,
+		StartedAt:     timestamp(job.StartedAt),
+		FailureReason: job.FailureReason,
+		FailedStep:    job.FailedStep,
+		ExitCode:      int32(job.ExitCode),
+		Matrix:        job.Matrix,
+		Outputs:       job.Outputs,
+	}
+	if job.EndedAt != nil {
+		msg.EndedAt = timestamp(*job.EndedAt)
+	}
+	for _, step := range job.Steps {
+		msg.Steps = append(msg.Steps, stepMessage(step))
+	}
+	return msg
+}
+
+// stepMessage converts a step of a job to its message
+func stepMessage(step models.Step) *gantryv1.Step {
+	msg := &gantryv1.Step{
+		Id:            step.ID,
+		Name:          step.Name,
+		Status:        step.Status,
+		StartedAt:     timestamp(step.StartedAt),
+		ExitCode:      int32(step.ExitCode),
+		FailureReason: step.FailureReason,
+	}
+	if step.EndedAt != nil {
+		msg.EndedAt = timestamp(*step.EndedAt)
+	}
+	for _, attempt := range step.Attempts {
+		msg.Attempts = append(msg.Attempts, &gantryv1.StepAttempt{
+			Success:   attempt.Success,
+			StartedAt: timestamp(attempt.StartedAt),
+			EndedAt:   timestamp(attempt.EndedAt),
+			ExitCode:  int32(attempt.ExitCode),
+		})
+	}
+	return msg
+}
+
+// timestamp converts a time to its message, leaving the zero time unset
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
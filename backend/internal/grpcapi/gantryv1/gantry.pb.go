@@ -0,0 +1,1316 @@
+// The gRPC API of the Gantry CI/CD server, for agents, CLIs and other
+// services. It offers a strongly typed subset of the HTTP API described in
+// docs/API.md.
+//
+// The Go code in internal/grpcapi/gantryv1 is generated from this file;
+// run `buf generate` in backend/ after changing it.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: gantry/v1/gantry.proto
+
+package gantryv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type UploadRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The workflow's YAML
+	Workflow      []byte `protobuf:"bytes,1,opt,name=workflow,proto3" json:"workflow,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadRequest) Reset() {
+	*x = UploadRequest{}
+	mi := &file_gantry_v1_gantry_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadRequest) ProtoMessage() {}
+
+func (x *UploadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gantry_v1_gantry_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadRequest.ProtoReflect.Descriptor instead.
+func (*UploadRequest) Descriptor() ([]byte, []int) {
+	return file_gantry_v1_gantry_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *UploadRequest) GetWorkflow() []byte {
+	if x != nil {
+		return x.Workflow
+	}
+	return nil
+}
+
+type UploadResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadResponse) Reset() {
+	*x = UploadResponse{}
+	mi := &file_gantry_v1_gantry_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadResponse) ProtoMessage() {}
+
+func (x *UploadResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gantry_v1_gantry_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadResponse.ProtoReflect.Descriptor instead.
+func (*UploadResponse) Descriptor() ([]byte, []int) {
+	return file_gantry_v1_gantry_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *UploadResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *UploadResponse) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type TriggerRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The workflow's ID or name
+	Workflow string `protobuf:"bytes,1,opt,name=workflow,proto3" json:"workflow,omitempty"`
+	// Inputs of the workflow's workflow_dispatch trigger
+	Inputs *structpb.Struct `protobuf:"bytes,2,opt,name=inputs,proto3" json:"inputs,omitempty"`
+	// Overrides of the workflow's variables
+	Variables map[string]string `protobuf:"bytes,3,rep,name=variables,proto3" json:"variables,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// The branch to run for, checked against the workflow's push branches
+	Branch string `protobuf:"bytes,4,opt,name=branch,proto3" json:"branch,omitempty"`
+	// Overrides of the workflow's env, taken as they are
+	Env map[string]string `protobuf:"bytes,5,rep,name=env,proto3" json:"env,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Labels recorded on the run
+	Labels        map[string]string `protobuf:"bytes,6,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TriggerRequest) Reset() {
+	*x = TriggerRequest{}
+	mi := &file_gantry_v1_gantry_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TriggerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TriggerRequest) ProtoMessage() {}
+
+func (x *TriggerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gantry_v1_gantry_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TriggerRequest.ProtoReflect.Descriptor instead.
+func (*TriggerRequest) Descriptor() ([]byte, []int) {
+	return file_gantry_v1_gantry_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *TriggerRequest) GetWorkflow() string {
+	if x != nil {
+		return x.Workflow
+	}
+	return ""
+}
+
+func (x *TriggerRequest) GetInputs() *structpb.Struct {
+	if x != nil {
+		return x.Inputs
+	}
+	return nil
+}
+
+func (x *TriggerRequest) GetVariables() map[string]string {
+	if x != nil {
+		return x.Variables
+	}
+	return nil
+}
+
+func (x *TriggerRequest) GetBranch() string {
+	if x != nil {
+		return x.Branch
+	}
+	return ""
+}
+
+func (x *TriggerRequest) GetEnv() map[string]string {
+	if x != nil {
+		return x.Env
+	}
+	return nil
+}
+
+func (x *TriggerRequest) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+type TriggerResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The run started; unset when the branch filters kept it from starting
+	Run *Run `protobuf:"bytes,1,opt,name=run,proto3" json:"run,omitempty"`
+	// Why no run was started
+	SkippedReason string `protobuf:"bytes,2,opt,name=skipped_reason,json=skippedReason,proto3" json:"skipped_reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TriggerResponse) Reset() {
+	*x = TriggerResponse{}
+	mi := &file_gantry_v1_gantry_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TriggerResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TriggerResponse) ProtoMessage() {}
+
+func (x *TriggerResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gantry_v1_gantry_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TriggerResponse.ProtoReflect.Descriptor instead.
+func (*TriggerResponse) Descriptor() ([]byte, []int) {
+	return file_gantry_v1_gantry_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *TriggerResponse) GetRun() *Run {
+	if x != nil {
+		return x.Run
+	}
+	return nil
+}
+
+func (x *TriggerResponse) GetSkippedReason() string {
+	if x != nil {
+		return x.SkippedReason
+	}
+	return ""
+}
+
+type GetRunRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRunRequest) Reset() {
+	*x = GetRunRequest{}
+	mi := &file_gantry_v1_gantry_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRunRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRunRequest) ProtoMessage() {}
+
+func (x *GetRunRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gantry_v1_gantry_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRunRequest.ProtoReflect.Descriptor instead.
+func (*GetRunRequest) Descriptor() ([]byte, []int) {
+	return file_gantry_v1_gantry_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetRunRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type StreamLogsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RunId         string                 `protobuf:"bytes,1,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	Job           string                 `protobuf:"bytes,2,opt,name=job,proto3" json:"job,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamLogsRequest) Reset() {
+	*x = StreamLogsRequest{}
+	mi := &file_gantry_v1_gantry_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamLogsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamLogsRequest) ProtoMessage() {}
+
+func (x *StreamLogsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gantry_v1_gantry_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamLogsRequest.ProtoReflect.Descriptor instead.
+func (*StreamLogsRequest) Descriptor() ([]byte, []int) {
+	return file_gantry_v1_gantry_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *StreamLogsRequest) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+func (x *StreamLogsRequest) GetJob() string {
+	if x != nil {
+		return x.Job
+	}
+	return ""
+}
+
+type LogChunk struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Chunk:
+	//
+	//	*LogChunk_Output
+	//	*LogChunk_Status
+	Chunk         isLogChunk_Chunk `protobuf_oneof:"chunk"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogChunk) Reset() {
+	*x = LogChunk{}
+	mi := &file_gantry_v1_gantry_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogChunk) ProtoMessage() {}
+
+func (x *LogChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_gantry_v1_gantry_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogChunk.ProtoReflect.Descriptor instead.
+func (*LogChunk) Descriptor() ([]byte, []int) {
+	return file_gantry_v1_gantry_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *LogChunk) GetChunk() isLogChunk_Chunk {
+	if x != nil {
+		return x.Chunk
+	}
+	return nil
+}
+
+func (x *LogChunk) GetOutput() string {
+	if x != nil {
+		if x, ok := x.Chunk.(*LogChunk_Output); ok {
+			return x.Output
+		}
+	}
+	return ""
+}
+
+func (x *LogChunk) GetStatus() string {
+	if x != nil {
+		if x, ok := x.Chunk.(*LogChunk_Status); ok {
+			return x.Status
+		}
+	}
+	return ""
+}
+
+type isLogChunk_Chunk interface {
+	isLogChunk_Chunk()
+}
+
+type LogChunk_Output struct {
+	// Output the job printed
+	Output string `protobuf:"bytes,1,opt,name=output,proto3,oneof"`
+}
+
+type LogChunk_Status struct {
+	// The status the job finished with, sent last
+	Status string `protobuf:"bytes,2,opt,name=status,proto3,oneof"`
+}
+
+func (*LogChunk_Output) isLogChunk_Chunk() {}
+
+func (*LogChunk_Status) isLogChunk_Chunk() {}
+
+type Run struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Id           string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	WorkflowId   string                 `protobuf:"bytes,2,opt,name=workflow_id,json=workflowId,proto3" json:"workflow_id,omitempty"`
+	WorkflowName string                 `protobuf:"bytes,3,opt,name=workflow_name,json=workflowName,proto3" json:"workflow_name,omitempty"`
+	Status       string                 `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
+	StartedAt    *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	CompletedAt  *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=completed_at,json=completedAt,proto3" json:"completed_at,omitempty"`
+	// The ID of the run this one re-ran
+	RerunOf   string            `protobuf:"bytes,7,opt,name=rerun_of,json=rerunOf,proto3" json:"rerun_of,omitempty"`
+	Trigger   *Trigger          `protobuf:"bytes,8,opt,name=trigger,proto3" json:"trigger,omitempty"`
+	Variables map[string]string `protobuf:"bytes,9,rep,name=variables,proto3" json:"variables,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// The run's jobs in the order they run
+	Jobs []*Job `protobuf:"bytes,10,rep,name=jobs,proto3" json:"jobs,omitempty"`
+	// Overrides of the workflow's env and labels the run was triggered with
+	Env           map[string]string `protobuf:"bytes,11,rep,name=env,proto3" json:"env,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Labels        map[string]string `protobuf:"bytes,12,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Run) Reset() {
+	*x = Run{}
+	mi := &file_gantry_v1_gantry_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Run) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Run) ProtoMessage() {}
+
+func (x *Run) ProtoReflect() protoreflect.Message {
+	mi := &file_gantry_v1_gantry_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Run.ProtoReflect.Descriptor instead.
+func (*Run) Descriptor() ([]byte, []int) {
+	return file_gantry_v1_gantry_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Run) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Run) GetWorkflowId() string {
+	if x != nil {
+		return x.WorkflowId
+	}
+	return ""
+}
+
+func (x *Run) GetWorkflowName() string {
+	if x != nil {
+		return x.WorkflowName
+	}
+	return ""
+}
+
+func (x *Run) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Run) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *Run) GetCompletedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CompletedAt
+	}
+	return nil
+}
+
+func (x *Run) GetRerunOf() string {
+	if x != nil {
+		return x.RerunOf
+	}
+	return ""
+}
+
+func (x *Run) GetTrigger() *Trigger {
+	if x != nil {
+		return x.Trigger
+	}
+	return nil
+}
+
+func (x *Run) GetVariables() map[string]string {
+	if x != nil {
+		return x.Variables
+	}
+	return nil
+}
+
+func (x *Run) GetJobs() []*Job {
+	if x != nil {
+		return x.Jobs
+	}
+	return nil
+}
+
+func (x *Run) GetEnv() map[string]string {
+	if x != nil {
+		return x.Env
+	}
+	return nil
+}
+
+func (x *Run) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+// Trigger is the event that started a run
+type Trigger struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Event       string                 `protobuf:"bytes,1,opt,name=event,proto3" json:"event,omitempty"`
+	Action      string                 `protobuf:"bytes,2,opt,name=action,proto3" json:"action,omitempty"`
+	Actor       string                 `protobuf:"bytes,3,opt,name=actor,proto3" json:"actor,omitempty"`
+	Branch      string                 `protobuf:"bytes,4,opt,name=branch,proto3" json:"branch,omitempty"`
+	Command     string                 `protobuf:"bytes,5,opt,name=command,proto3" json:"command,omitempty"`
+	PullRequest int32                  `protobuf:"varint,6,opt,name=pull_request,json=pullRequest,proto3" json:"pull_request,omitempty"`
+	Repository  string                 `protobuf:"bytes,7,opt,name=repository,proto3" json:"repository,omitempty"`
+	Sha         string                 `protobuf:"bytes,8,opt,name=sha,proto3" json:"sha,omitempty"`
+	// The commit sha names, when the event's webhook described it
+	Commit        *Commit `protobuf:"bytes,9,opt,name=commit,proto3" json:"commit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Trigger) Reset() {
+	*x = Trigger{}
+	mi := &file_gantry_v1_gantry_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Trigger) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Trigger) ProtoMessage() {}
+
+func (x *Trigger) ProtoReflect() protoreflect.Message {
+	mi := &file_gantry_v1_gantry_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Trigger.ProtoReflect.Descriptor instead.
+func (*Trigger) Descriptor() ([]byte, []int) {
+	return file_gantry_v1_gantry_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Trigger) GetEvent() string {
+	if x != nil {
+		return x.Event
+	}
+	return ""
+}
+
+func (x *Trigger) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *Trigger) GetActor() string {
+	if x != nil {
+		return x.Actor
+	}
+	return ""
+}
+
+func (x *Trigger) GetBranch() string {
+	if x != nil {
+		return x.Branch
+	}
+	return ""
+}
+
+func (x *Trigger) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+func (x *Trigger) GetPullRequest() int32 {
+	if x != nil {
+		return x.PullRequest
+	}
+	return 0
+}
+
+func (x *Trigger) GetRepository() string {
+	if x != nil {
+		return x.Repository
+	}
+	return ""
+}
+
+func (x *Trigger) GetSha() string {
+	if x != nil {
+		return x.Sha
+	}
+	return ""
+}
+
+func (x *Trigger) GetCommit() *Commit {
+	if x != nil {
+		return x.Commit
+	}
+	return nil
+}
+
+type Commit struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Message       string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	Author        string                 `protobuf:"bytes,2,opt,name=author,proto3" json:"author,omitempty"`
+	Url           string                 `protobuf:"bytes,3,opt,name=url,proto3" json:"url,omitempty"`
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Commit) Reset() {
+	*x = Commit{}
+	mi := &file_gantry_v1_gantry_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Commit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Commit) ProtoMessage() {}
+
+func (x *Commit) ProtoReflect() protoreflect.Message {
+	mi := &file_gantry_v1_gantry_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Commit.ProtoReflect.Descriptor instead.
+func (*Commit) Descriptor() ([]byte, []int) {
+	return file_gantry_v1_gantry_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Commit) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Commit) GetAuthor() string {
+	if x != nil {
+		return x.Author
+	}
+	return ""
+}
+
+func (x *Commit) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *Commit) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+type Job struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Name        string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Status      string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	RunsOn      string                 `protobuf:"bytes,3,opt,name=runs_on,json=runsOn,proto3" json:"runs_on,omitempty"`
+	Needs       []string               `protobuf:"bytes,4,rep,name=needs,proto3" json:"needs,omitempty"`
+	Environment string                 `protobuf:"bytes,5,opt,name=environment,proto3" json:"environment,omitempty"`
+	// The job whose uses: this job was expanded from
+	Call          string                 `protobuf:"bytes,6,opt,name=call,proto3" json:"call,omitempty"`
+	StartedAt     *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	EndedAt       *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=ended_at,json=endedAt,proto3" json:"ended_at,omitempty"`
+	FailureReason string                 `protobuf:"bytes,9,opt,name=failure_reason,json=failureReason,proto3" json:"failure_reason,omitempty"`
+	FailedStep    string                 `protobuf:"bytes,10,opt,name=failed_step,json=failedStep,proto3" json:"failed_step,omitempty"`
+	ExitCode      int32                  `protobuf:"varint,11,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	Matrix        map[string]string      `protobuf:"bytes,12,rep,name=matrix,proto3" json:"matrix,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Outputs       map[string]string      `protobuf:"bytes,13,rep,name=outputs,proto3" json:"outputs,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Steps         []*Step                `protobuf:"bytes,14,rep,name=steps,proto3" json:"steps,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Job) Reset() {
+	*x = Job{}
+	mi := &file_gantry_v1_gantry_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Job) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Job) ProtoMessage() {}
+
+func (x *Job) ProtoReflect() protoreflect.Message {
+	mi := &file_gantry_v1_gantry_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Job.ProtoReflect.Descriptor instead.
+func (*Job) Descriptor() ([]byte, []int) {
+	return file_gantry_v1_gantry_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Job) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Job) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Job) GetRunsOn() string {
+	if x != nil {
+		return x.RunsOn
+	}
+	return ""
+}
+
+func (x *Job) GetNeeds() []string {
+	if x != nil {
+		return x.Needs
+	}
+	return nil
+}
+
+func (x *Job) GetEnvironment() string {
+	if x != nil {
+		return x.Environment
+	}
+	return ""
+}
+
+func (x *Job) GetCall() string {
+	if x != nil {
+		return x.Call
+	}
+	return ""
+}
+
+func (x *Job) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *Job) GetEndedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EndedAt
+	}
+	return nil
+}
+
+func (x *Job) GetFailureReason() string {
+	if x != nil {
+		return x.FailureReason
+	}
+	return ""
+}
+
+func (x *Job) GetFailedStep() string {
+	if x != nil {
+		return x.FailedStep
+	}
+	return ""
+}
+
+func (x *Job) GetExitCode() int32 {
+	if x != nil {
+		return x.ExitCode
+	}
+	return 0
+}
+
+func (x *Job) GetMatrix() map[string]string {
+	if x != nil {
+		return x.Matrix
+	}
+	return nil
+}
+
+func (x *Job) GetOutputs() map[string]string {
+	if x != nil {
+		return x.Outputs
+	}
+	return nil
+}
+
+func (x *Job) GetSteps() []*Step {
+	if x != nil {
+		return x.Steps
+	}
+	return nil
+}
+
+type Step struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	StartedAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	EndedAt       *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=ended_at,json=endedAt,proto3" json:"ended_at,omitempty"`
+	ExitCode      int32                  `protobuf:"varint,6,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	FailureReason string                 `protobuf:"bytes,7,opt,name=failure_reason,json=failureReason,proto3" json:"failure_reason,omitempty"`
+	// The attempts of a retried step
+	Attempts      []*StepAttempt `protobuf:"bytes,8,rep,name=attempts,proto3" json:"attempts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Step) Reset() {
+	*x = Step{}
+	mi := &file_gantry_v1_gantry_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Step) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Step) ProtoMessage() {}
+
+func (x *Step) ProtoReflect() protoreflect.Message {
+	mi := &file_gantry_v1_gantry_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Step.ProtoReflect.Descriptor instead.
+func (*Step) Descriptor() ([]byte, []int) {
+	return file_gantry_v1_gantry_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *Step) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Step) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Step) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Step) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *Step) GetEndedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EndedAt
+	}
+	return nil
+}
+
+func (x *Step) GetExitCode() int32 {
+	if x != nil {
+		return x.ExitCode
+	}
+	return 0
+}
+
+func (x *Step) GetFailureReason() string {
+	if x != nil {
+		return x.FailureReason
+	}
+	return ""
+}
+
+func (x *Step) GetAttempts() []*StepAttempt {
+	if x != nil {
+		return x.Attempts
+	}
+	return nil
+}
+
+type StepAttempt struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	StartedAt     *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	EndedAt       *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=ended_at,json=endedAt,proto3" json:"ended_at,omitempty"`
+	ExitCode      int32                  `protobuf:"varint,4,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StepAttempt) Reset() {
+	*x = StepAttempt{}
+	mi := &file_gantry_v1_gantry_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StepAttempt) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StepAttempt) ProtoMessage() {}
+
+func (x *StepAttempt) ProtoReflect() protoreflect.Message {
+	mi := &file_gantry_v1_gantry_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StepAttempt.ProtoReflect.Descriptor instead.
+func (*StepAttempt) Descriptor() ([]byte, []int) {
+	return file_gantry_v1_gantry_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *StepAttempt) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *StepAttempt) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *StepAttempt) GetEndedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EndedAt
+	}
+	return nil
+}
+
+func (x *StepAttempt) GetExitCode() int32 {
+	if x != nil {
+		return x.ExitCode
+	}
+	return 0
+}
+
+var File_gantry_v1_gantry_proto protoreflect.FileDescriptor
+
+const file_gantry_v1_gantry_proto_rawDesc = "" +
+	"\n" +
+	"\x16gantry/v1/gantry.proto\x12\tgantry.v1\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"+\n" +
+	"\rUploadRequest\x12\x1a\n" +
+	"\bworkflow\x18\x01 \x01(\fR\bworkflow\"4\n" +
+	"\x0eUploadResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\"\xe3\x03\n" +
+	"\x0eTriggerRequest\x12\x1a\n" +
+	"\bworkflow\x18\x01 \x01(\tR\bworkflow\x12/\n" +
+	"\x06inputs\x18\x02 \x01(\v2\x17.google.protobuf.StructR\x06inputs\x12F\n" +
+	"\tvariables\x18\x03 \x03(\v2(.gantry.v1.TriggerRequest.VariablesEntryR\tvariables\x12\x16\n" +
+	"\x06branch\x18\x04 \x01(\tR\x06branch\x124\n" +
+	"\x03env\x18\x05 \x03(\v2\".gantry.v1.TriggerRequest.EnvEntryR\x03env\x12=\n" +
+	"\x06labels\x18\x06 \x03(\v2%.gantry.v1.TriggerRequest.LabelsEntryR\x06labels\x1a<\n" +
+	"\x0eVariablesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a6\n" +
+	"\bEnvEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"Z\n" +
+	"\x0fTriggerResponse\x12 \n" +
+	"\x03run\x18\x01 \x01(\v2\x0e.gantry.v1.RunR\x03run\x12%\n" +
+	"\x0eskipped_reason\x18\x02 \x01(\tR\rskippedReason\"\x1f\n" +
+	"\rGetRunRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"<\n" +
+	"\x11StreamLogsRequest\x12\x15\n" +
+	"\x06run_id\x18\x01 \x01(\tR\x05runId\x12\x10\n" +
+	"\x03job\x18\x02 \x01(\tR\x03job\"G\n" +
+	"\bLogChunk\x12\x18\n" +
+	"\x06output\x18\x01 \x01(\tH\x00R\x06output\x12\x18\n" +
+	"\x06status\x18\x02 \x01(\tH\x00R\x06statusB\a\n" +
+	"\x05chunk\"\xa7\x05\n" +
+	"\x03Run\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1f\n" +
+	"\vworkflow_id\x18\x02 \x01(\tR\n" +
+	"workflowId\x12#\n" +
+	"\rworkflow_name\x18\x03 \x01(\tR\fworkflowName\x12\x16\n" +
+	"\x06status\x18\x04 \x01(\tR\x06status\x129\n" +
+	"\n" +
+	"started_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x12=\n" +
+	"\fcompleted_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\vcompletedAt\x12\x19\n" +
+	"\brerun_of\x18\a \x01(\tR\arerunOf\x12,\n" +
+	"\atrigger\x18\b \x01(\v2\x12.gantry.v1.TriggerR\atrigger\x12;\n" +
+	"\tvariables\x18\t \x03(\v2\x1d.gantry.v1.Run.VariablesEntryR\tvariables\x12\"\n" +
+	"\x04jobs\x18\n" +
+	" \x03(\v2\x0e.gantry.v1.JobR\x04jobs\x12)\n" +
+	"\x03env\x18\v \x03(\v2\x17.gantry.v1.Run.EnvEntryR\x03env\x122\n" +
+	"\x06labels\x18\f \x03(\v2\x1a.gantry.v1.Run.LabelsEntryR\x06labels\x1a<\n" +
+	"\x0eVariablesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a6\n" +
+	"\bEnvEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xff\x01\n" +
+	"\aTrigger\x12\x14\n" +
+	"\x05event\x18\x01 \x01(\tR\x05event\x12\x16\n" +
+	"\x06action\x18\x02 \x01(\tR\x06action\x12\x14\n" +
+	"\x05actor\x18\x03 \x01(\tR\x05actor\x12\x16\n" +
+	"\x06branch\x18\x04 \x01(\tR\x06branch\x12\x18\n" +
+	"\acommand\x18\x05 \x01(\tR\acommand\x12!\n" +
+	"\fpull_request\x18\x06 \x01(\x05R\vpullRequest\x12\x1e\n" +
+	"\n" +
+	"repository\x18\a \x01(\tR\n" +
+	"repository\x12\x10\n" +
+	"\x03sha\x18\b \x01(\tR\x03sha\x12)\n" +
+	"\x06commit\x18\t \x01(\v2\x11.gantry.v1.CommitR\x06commit\"\x86\x01\n" +
+	"\x06Commit\x12\x18\n" +
+	"\amessage\x18\x01 \x01(\tR\amessage\x12\x16\n" +
+	"\x06author\x18\x02 \x01(\tR\x06author\x12\x10\n" +
+	"\x03url\x18\x03 \x01(\tR\x03url\x128\n" +
+	"\ttimestamp\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\"\xf6\x04\n" +
+	"\x03Job\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x17\n" +
+	"\aruns_on\x18\x03 \x01(\tR\x06runsOn\x12\x14\n" +
+	"\x05needs\x18\x04 \x03(\tR\x05needs\x12 \n" +
+	"\venvironment\x18\x05 \x01(\tR\venvironment\x12\x12\n" +
+	"\x04call\x18\x06 \x01(\tR\x04call\x129\n" +
+	"\n" +
+	"started_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x125\n" +
+	"\bended_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\aendedAt\x12%\n" +
+	"\x0efailure_reason\x18\t \x01(\tR\rfailureReason\x12\x1f\n" +
+	"\vfailed_step\x18\n" +
+	" \x01(\tR\n" +
+	"failedStep\x12\x1b\n" +
+	"\texit_code\x18\v \x01(\x05R\bexitCode\x122\n" +
+	"\x06matrix\x18\f \x03(\v2\x1a.gantry.v1.Job.MatrixEntryR\x06matrix\x125\n" +
+	"\aoutputs\x18\r \x03(\v2\x1b.gantry.v1.Job.OutputsEntryR\aoutputs\x12%\n" +
+	"\x05steps\x18\x0e \x03(\v2\x0f.gantry.v1.StepR\x05steps\x1a9\n" +
+	"\vMatrixEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a:\n" +
+	"\fOutputsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xac\x02\n" +
+	"\x04Step\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x129\n" +
+	"\n" +
+	"started_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x125\n" +
+	"\bended_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\aendedAt\x12\x1b\n" +
+	"\texit_code\x18\x06 \x01(\x05R\bexitCode\x12%\n" +
+	"\x0efailure_reason\x18\a \x01(\tR\rfailureReason\x122\n" +
+	"\battempts\x18\b \x03(\v2\x16.gantry.v1.StepAttemptR\battempts\"\xb6\x01\n" +
+	"\vStepAttempt\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x129\n" +
+	"\n" +
+	"started_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x125\n" +
+	"\bended_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\aendedAt\x12\x1b\n" +
+	"\texit_code\x18\x04 \x01(\x05R\bexitCode2\x80\x02\n" +
+	"\x06Gantry\x12=\n" +
+	"\x06Upload\x12\x18.gantry.v1.UploadRequest\x1a\x19.gantry.v1.UploadResponse\x12@\n" +
+	"\aTrigger\x12\x19.gantry.v1.TriggerRequest\x1a\x1a.gantry.v1.TriggerResponse\x122\n" +
+	"\x06GetRun\x12\x18.gantry.v1.GetRunRequest\x1a\x0e.gantry.v1.Run\x12A\n" +
+	"\n" +
+	"StreamLogs\x12\x1c.gantry.v1.StreamLogsRequest\x1a\x13.gantry.v1.LogChunk0\x01B\"Z gantry/internal/grpcapi/gantryv1b\x06proto3"
+
+var (
+	file_gantry_v1_gantry_proto_rawDescOnce sync.Once
+	file_gantry_v1_gantry_proto_rawDescData []byte
+)
+
+func file_gantry_v1_gantry_proto_rawDescGZIP() []byte {
+	file_gantry_v1_gantry_proto_rawDescOnce.Do(func() {
+		file_gantry_v1_gantry_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_gantry_v1_gantry_proto_rawDesc), len(file_gantry_v1_gantry_proto_rawDesc)))
+	})
+	return file_gantry_v1_gantry_proto_rawDescData
+}
+
+var file_gantry_v1_gantry_proto_msgTypes = make([]protoimpl.MessageInfo, 21)
+var file_gantry_v1_gantry_proto_goTypes = []any{
+	(*UploadRequest)(nil),         // 0: gantry.v1.UploadRequest
+	(*UploadResponse)(nil),        // 1: gantry.v1.UploadResponse
+	(*TriggerRequest)(nil),        // 2: gantry.v1.TriggerRequest
+	(*TriggerResponse)(nil),       // 3: gantry.v1.TriggerResponse
+	(*GetRunRequest)(nil),         // 4: gantry.v1.GetRunRequest
+	(*StreamLogsRequest)(nil),     // 5: gantry.v1.StreamLogsRequest
+	(*LogChunk)(nil),              // 6: gantry.v1.LogChunk
+	(*Run)(nil),                   // 7: gantry.v1.Run
+	(*Trigger)(nil),               // 8: gantry.v1.Trigger
+	(*Commit)(nil),                // 9: gantry.v1.Commit
+	(*Job)(nil),                   // 10: gantry.v1.Job
+	(*Step)(nil),                  // 11: gantry.v1.Step
+	(*StepAttempt)(nil),           // 12: gantry.v1.StepAttempt
+	nil,                           // 13: gantry.v1.TriggerRequest.VariablesEntry
+	nil,                           // 14: gantry.v1.TriggerRequest.EnvEntry
+	nil,                           // 15: gantry.v1.TriggerRequest.LabelsEntry
+	nil,                           // 16: gantry.v1.Run.VariablesEntry
+	nil,                           // 17: gantry.v1.Run.EnvEntry
+	nil,                           // 18: gantry.v1.Run.LabelsEntry
+	nil,                           // 19: gantry.v1.Job.MatrixEntry
+	nil,                           // 20: gantry.v1.Job.OutputsEntry
+	(*structpb.Struct)(nil),       // 21: google.protobuf.Struct
+	(*timestamppb.Timestamp)(nil), // 22: google.protobuf.Timestamp
+}
+var file_gantry_v1_gantry_proto_depIdxs = []int32{
+	21, // 0: gantry.v1.TriggerRequest.inputs:type_name -> google.protobuf.Struct
+	13, // 1: gantry.v1.TriggerRequest.variables:type_name -> gantry.v1.TriggerRequest.VariablesEntry
+	14, // 2: gantry.v1.TriggerRequest.env:type_name -> gantry.v1.TriggerRequest.EnvEntry
+	15, // 3: gantry.v1.TriggerRequest.labels:type_name -> gantry.v1.TriggerRequest.LabelsEntry
+	7,  // 4: gantry.v1.TriggerResponse.run:type_name -> gantry.v1.Run
+	22, // 5: gantry.v1.Run.started_at:type_name -> google.protobuf.Timestamp
+	22, // 6: gantry.v1.Run.completed_at:type_name -> google.protobuf.Timestamp
+	8,  // 7: gantry.v1.Run.trigger:type_name -> gantry.v1.Trigger
+	16, // 8: gantry.v1.Run.variables:type_name -> gantry.v1.Run.VariablesEntry
+	10, // 9: gantry.v1.Run.jobs:type_name -> gantry.v1.Job
+	17, // 10: gantry.v1.Run.env:type_name -> gantry.v1.Run.EnvEntry
+	18, // 11: gantry.v1.Run.labels:type_name -> gantry.v1.Run.LabelsEntry
+	9,  // 12: gantry.v1.Trigger.commit:type_name -> gantry.v1.Commit
+	22, // 13: gantry.v1.Commit.timestamp:type_name -> google.protobuf.Timestamp
+	22, // 14: gantry.v1.Job.started_at:type_name -> google.protobuf.Timestamp
+	22, // 15: gantry.v1.Job.ended_at:type_name -> google.protobuf.Timestamp
+	19, // 16: gantry.v1.Job.matrix:type_name -> gantry.v1.Job.MatrixEntry
+	20, // 17: gantry.v1.Job.outputs:type_name -> gantry.v1.Job.OutputsEntry
+	11, // 18: gantry.v1.Job.steps:type_name -> gantry.v1.Step
+	22, // 19: gantry.v1.Step.started_at:type_name -> google.protobuf.Timestamp
+	22, // 20: gantry.v1.Step.ended_at:type_name -> google.protobuf.Timestamp
+	12, // 21: gantry.v1.Step.attempts:type_name -> gantry.v1.StepAttempt
+	22, // 22: gantry.v1.StepAttempt.started_at:type_name -> google.protobuf.Timestamp
+	22, // 23: gantry.v1.StepAttempt.ended_at:type_name -> google.protobuf.Timestamp
+	0,  // 24: gantry.v1.Gantry.Upload:input_type -> gantry.v1.UploadRequest
+	2,  // 25: gantry.v1.Gantry.Trigger:input_type -> gantry.v1.TriggerRequest
+	4,  // 26: gantry.v1.Gantry.GetRun:input_type -> gantry.v1.GetRunRequest
+	5,  // 27: gantry.v1.Gantry.StreamLogs:input_type -> gantry.v1.StreamLogsRequest
+	1,  // 28: gantry.v1.Gantry.Upload:output_type -> gantry.v1.UploadResponse
+	3,  // 29: gantry.v1.Gantry.Trigger:output_type -> gantry.v1.TriggerResponse
+	7,  // 30: gantry.v1.Gantry.GetRun:output_type -> gantry.v1.Run
+	6,  // 31: gantry.v1.Gantry.StreamLogs:output_type -> gantry.v1.LogChunk
+	28, // [28:32] is the sub-list for method output_type
+	24, // [24:28] is the sub-list for method input_type
+	24, // [24:24] is the sub-list for extension type_name
+	24, // [24:24] is the sub-list for extension extendee
+	0,  // [0:24] is the sub-list for field type_name
+}
+
+func init() { file_gantry_v1_gantry_proto_init() }
+func file_gantry_v1_gantry_proto_init() {
+	if File_gantry_v1_gantry_proto != nil {
+		return
+	}
+	file_gantry_v1_gantry_proto_msgTypes[6].OneofWrappers = []any{
+		(*LogChunk_Output)(nil),
+		(*LogChunk_Status)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_gantry_v1_gantry_proto_rawDesc), len(file_gantry_v1_gantry_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   21,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_gantry_v1_gantry_proto_goTypes,
+		DependencyIndexes: file_gantry_v1_gantry_proto_depIdxs,
+		MessageInfos:      file_gantry_v1_gantry_proto_msgTypes,
+	}.Build()
+	File_gantry_v1_gantry_proto = out.File
+	file_gantry_v1_gantry_proto_goTypes = nil
+	file_gantry_v1_gantry_proto_depIdxs = nil
+}
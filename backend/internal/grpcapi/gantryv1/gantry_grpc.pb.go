@@ -0,0 +1,270 @@
+// The gRPC API of the Gantry CI/CD server, for agents, CLIs and other
+// services. It offers a strongly typed subset of the HTTP API described in
+// docs/API.md.
+//
+// The Go code in internal/grpcapi/gantryv1 is generated from this file;
+// run `buf generate` in backend/ after changing it.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: gantry/v1/gantry.proto
+
+package gantryv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Gantry_Upload_FullMethodName     = "/gantry.v1.Gantry/Upload"
+	Gantry_Trigger_FullMethodName    = "/gantry.v1.Gantry/Trigger"
+	Gantry_GetRun_FullMethodName     = "/gantry.v1.Gantry/GetRun"
+	Gantry_StreamLogs_FullMethodName = "/gantry.v1.Gantry/StreamLogs"
+)
+
+// GantryClient is the client API for Gantry service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Gantry uploads workflows, starts runs and follows them. When the server
+// sets JWT_SECRET, calls carry an access token in the authorization
+// metadata, as "Bearer <token>".
+type GantryClient interface {
+	// Upload saves a workflow, replacing the workflow of the same name. An
+	// invalid workflow fails with INVALID_ARGUMENT and a BadRequest detail
+	// listing every problem.
+	Upload(ctx context.Context, in *UploadRequest, opts ...grpc.CallOption) (*UploadResponse, error)
+	// Trigger starts a run of a workflow
+	Trigger(ctx context.Context, in *TriggerRequest, opts ...grpc.CallOption) (*TriggerResponse, error)
+	// GetRun returns a run, without what its jobs printed
+	GetRun(ctx context.Context, in *GetRunRequest, opts ...grpc.CallOption) (*Run, error)
+	// StreamLogs sends a job's output as it runs: what it has printed so far,
+	// then each line it prints, then the status it finished with. A job that
+	// hasn't started is waited for.
+	StreamLogs(ctx context.Context, in *StreamLogsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LogChunk], error)
+}
+
+type gantryClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGantryClient(cc grpc.ClientConnInterface) GantryClient {
+	return &gantryClient{cc}
+}
+
+func (c *gantryClient) Upload(ctx context.Context, in *UploadRequest, opts ...grpc.CallOption) (*UploadResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UploadResponse)
+	err := c.cc.Invoke(ctx, Gantry_Upload_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gantryClient) Trigger(ctx context.Context, in *TriggerRequest, opts ...grpc.CallOption) (*TriggerResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TriggerResponse)
+	err := c.cc.Invoke(ctx, Gantry_Trigger_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gantryClient) GetRun(ctx context.Context, in *GetRunRequest, opts ...grpc.CallOption) (*Run, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Run)
+	err := c.cc.Invoke(ctx, Gantry_GetRun_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gantryClient) StreamLogs(ctx context.Context, in *StreamLogsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LogChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Gantry_ServiceDesc.Streams[0], Gantry_StreamLogs_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamLogsRequest, LogChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Gantry_StreamLogsClient = grpc.ServerStreamingClient[LogChunk]
+
+// GantryServer is the server API for Gantry service.
+// All implementations must embed UnimplementedGantryServer
+// for forward compatibility.
+//
+// Gantry uploads workflows, starts runs and follows them. When the server
+// sets JWT_SECRET, calls carry an access token in the authorization
+// metadata, as "Bearer <token>".
+type GantryServer interface {
+	// Upload saves a workflow, replacing the workflow of the same name. An
+	// invalid workflow fails with INVALID_ARGUMENT and a BadRequest detail
+	// listing every problem.
+	Upload(context.Context, *UploadRequest) (*UploadResponse, error)
+	// Trigger starts a run of a workflow
+	Trigger(context.Context, *TriggerRequest) (*TriggerResponse, error)
+	// GetRun returns a run, without what its jobs printed
+	GetRun(context.Context, *GetRunRequest) (*Run, error)
+	// StreamLogs sends a job's output as it runs: what it has printed so far,
+	// then each line it prints, then the status it finished with. A job that
+	// hasn't started is waited for.
+	StreamLogs(*StreamLogsRequest, grpc.ServerStreamingServer[LogChunk]) error
+	mustEmbedUnimplementedGantryServer()
+}
+
+// UnimplementedGantryServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedGantryServer struct{}
+
+func (UnimplementedGantryServer) Upload(context.Context, *UploadRequest) (*UploadResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Upload not implemented")
+}
+func (UnimplementedGantryServer) Trigger(context.Context, *TriggerRequest) (*TriggerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Trigger not implemented")
+}
+func (UnimplementedGantryServer) GetRun(context.Context, *GetRunRequest) (*Run, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRun not implemented")
+}
+func (UnimplementedGantryServer) StreamLogs(*StreamLogsRequest, grpc.ServerStreamingServer[LogChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamLogs not implemented")
+}
+func (UnimplementedGantryServer) mustEmbedUnimplementedGantryServer() {}
+func (UnimplementedGantryServer) testEmbeddedByValue()                {}
+
+// UnsafeGantryServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GantryServer will
+// result in compilation errors.
+type UnsafeGantryServer interface {
+	mustEmbedUnimplementedGantryServer()
+}
+
+func RegisterGantryServer(s grpc.ServiceRegistrar, srv GantryServer) {
+	// If the following call pancis, it indicates UnimplementedGantryServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Gantry_ServiceDesc, srv)
+}
+
+func _Gantry_Upload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UploadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GantryServer).Upload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gantry_Upload_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GantryServer).Upload(ctx, req.(*UploadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gantry_Trigger_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TriggerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GantryServer).Trigger(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gantry_Trigger_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GantryServer).Trigger(ctx, req.(*TriggerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gantry_GetRun_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRunRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GantryServer).GetRun(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gantry_GetRun_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GantryServer).GetRun(ctx, req.(*GetRunRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gantry_StreamLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamLogsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GantryServer).StreamLogs(m, &grpc.GenericServerStream[StreamLogsRequest, LogChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Gantry_StreamLogsServer = grpc.ServerStreamingServer[LogChunk]
+
+// Gantry_ServiceDesc is the grpc.ServiceDesc for Gantry service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Gantry_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gantry.v1.Gantry",
+	HandlerType: (*GantryServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Upload",
+			Handler:    _Gantry_Upload_Handler,
+		},
+		{
+			MethodName: "Trigger",
+			Handler:    _Gantry_Trigger_Handler,
+		},
+		{
+			MethodName: "GetRun",
+			Handler:    _Gantry_GetRun_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamLogs",
+			Handler:       _Gantry_StreamLogs_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "gantry/v1/gantry.proto",
+}
@@ -0,0 +1,159 @@
+// Package grpcapi serves the gRPC API of proto/gantry/v1/gantry.proto,
+// alongside the HTTP API of package api
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gantry/internal/grpcapi/gantryv1"
+	"gantry/internal/parser"
+	"gantry/internal/server"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+)
+
+// keepaliveInterval is how often idle connections are pinged, so proxies
+// keep log streams open, as the HTTP API pings its WebSockets
+const keepaliveInterval = 30 * time.Second
+
+// Service implements the Gantry gRPC service on a server
+type Service struct {
+	gantryv1.UnimplementedGantryServer
+
+	server *server.Server
+}
+
+// NewServer creates a gRPC server serving the Gantry service, which checks
+// the access token and permission of each call as the HTTP API does
+func NewServer(srv *server.Server) *grpc.Server {
+	s := &Service{server: srv}
+	g := grpc.NewServer(
+		grpc.UnaryInterceptor(s.unaryInterceptor),
+		grpc.StreamInterceptor(s.streamInterceptor),
+		grpc.KeepaliveParams(keepalive.ServerParameters{Time: keepaliveInterval}),
+	)
+	gantryv1.RegisterGantryServer(g, s)
+	return g
+}
+
+// Upload saves a workflow
+func (s *Service) Upload(ctx context.Context, req *gantryv1.UploadRequest) (*gantryv1.UploadResponse, error) {
+	username, _ := ctx.Value(userKey{}).(string)
+	wf, err := s.server.ParseAndSaveWorkflow(req.GetWorkflow(), username)
+	if err != nil {
+		return nil, validationStatus(err)
+	}
+	return &gantryv1.UploadResponse{Id: wf.ID, Name: wf.Name}, nil
+}
+
+// validationStatus reports an invalid workflow, with a field violation for
+// each of its problems
+func validationStatus(err error) error {
+	violations := &errdetails.BadRequest{}
+	for _, problem := range parser.AsValidationErrors(err) {
+		violations.FieldViolations = append(violations.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       problem.Path,
+			Description: problem.Error(),
+		})
+	}
+
+	st := status.Newf(codes.InvalidArgument, "failed to parse workflow: %v", err)
+	if detailed, detailErr := st.WithDetails(violations); detailErr == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// Trigger starts a run of a workflow
+func (s *Service) Trigger(ctx context.Context, req *gantryv1.TriggerRequest) (*gantryv1.TriggerResponse, error) {
+	if _, err := s.server.GetWorkflow(req.GetWorkflow()); err != nil {
+		return nil, status.Errorf(codes.NotFound, "workflow '%s' not found", req.GetWorkflow())
+	}
+
+	opts := server.TriggerOptions{
+		Variables: req.GetVariables(),
+		Branch:    req.GetBranch(),
+		Env:       req.GetEnv(),
+		Labels:    req.GetLabels(),
+	}
+	if req.GetInputs() != nil {
+		opts.Inputs = req.GetInputs().AsMap()
+	}
+
+	run, err := s.server.TriggerWorkflow(ctx, req.GetWorkflow(), opts)
+	if errors.Is(err, server.ErrSkipped) {
+		return &gantryv1.TriggerResponse{SkippedReason: err.Error()}, nil
+	}
+	if err != nil {
+		code := codes.Internal
+		if errors.Is(err, server.ErrInvalidInputs) || errors.Is(err, server.ErrInvalidVariables) ||
+			errors.Is(err, server.ErrInvalidEnv) || errors.Is(err, server.ErrInvalidLabels) ||
+			errors.Is(err, server.ErrInvalidCall) {
+			code = codes.InvalidArgument
+		}
+		return nil, status.Errorf(code, "failed to trigger workflow: %v", err)
+	}
+	return &gantryv1.TriggerResponse{Run: runMessage(run.Summary())}, nil
+}
+
+// GetRun returns a run without its jobs' output
+func (s *Service) GetRun(ctx context.Context, req *gantryv1.GetRunRequest) (*gantryv1.Run, error) {
+	run, err := s.server.GetRun(req.GetId())
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "run not found")
+	}
+	return runMessage(run.Summary()), nil
+}
+
+// StreamLogs sends a job's output until it finishes, then its status
+func (s *Service) StreamLogs(req *gantryv1.StreamLogsRequest, stream grpc.ServerStreamingServer[gantryv1.LogChunk]) error {
+	ctx := stream.Context()
+	if _, err := s.server.GetRun(req.GetRunId()); err != nil {
+		return status.Error(codes.NotFound, "run not found")
+	}
+
+	logs, err := s.server.FollowJobLogs(ctx, req.GetRunId(), req.GetJob())
+	if err != nil {
+		if ctx.Err() != nil {
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		return status.Error(codes.NotFound, err.Error())
+	}
+	defer logs.Close()
+
+	if logs.Output != "" {
+		if err := stream.Send(&gantryv1.LogChunk{Chunk: &gantryv1.LogChunk_Output{Output: logs.Output}}); err != nil {
+			return err
+		}
+	}
+
+	for done := false; !done; {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case output, ok := <-logs.Lines:
+			if !ok {
+				done = true
+				break
+			}
+			if err := stream.Send(&gantryv1.LogChunk{Chunk: &gantryv1.LogChunk_Output{Output: output}}); err != nil {
+				return err
+			}
+		}
+	}
+
+	if logs.Dropped() {
+		return status.Error(codes.Unavailable, "fell behind the job's output")
+	}
+	job, err := s.server.WaitForJob(ctx, req.GetRunId(), req.GetJob())
+	if err != nil {
+		return status.FromContextError(err).Err()
+	}
+	return stream.Send(&gantryv1.LogChunk{Chunk: &gantryv1.LogChunk_Status{Status: job.Status}})
+}
@@ -0,0 +1,224 @@
+package grpcapi
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"gantry/internal/grpcapi/gantryv1"
+	"gantry/internal/server"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const testWorkflow = `name: Hello
+on:
+  workflow_dispatch:
+    inputs:
+      greeting:
+        default: hello
+jobs:
+  greet:
+    runs-on: ubuntu
+    steps:
+      - name: Greet
+        run: echo "${{ inputs.greeting }} world"
+`
+
+// newTestClient serves the Gantry service of a server running jobs in the
+// shell, returning a client of it
+func newTestClient(t *testing.T, cfg server.Config) (gantryv1.GantryClient, *server.Server) {
+	t.Helper()
+
+	cfg.StorageType = "memory"
+	cfg.ExecutorType = "shell"
+	cfg.ArtifactsDir = t.TempDir()
+	cfg.CacheDir = t.TempDir()
+	srv, err := server.NewServer(&cfg)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	t.Cleanup(func() { _ = srv.Cleanup() })
+
+	lis := bufconn.Listen(1 << 20)
+	g := NewServer(srv)
+	go func() { _ = g.Serve(lis) }()
+	t.Cleanup(g.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return gantryv1.NewGantryClient(conn), srv
+}
+
+func TestService_UploadTriggerAndStreamLogs(t *testing.T) {
+	client, _ := newTestClient(t, server.Config{})
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	uploaded, err := client.Upload(ctx, &gantryv1.UploadRequest{Workflow: []byte(testWorkflow)})
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if uploaded.GetName() != "Hello" || uploaded.GetId() == "" {
+		t.Errorf("Expected the uploaded workflow Hello with an ID, got %v", uploaded)
+	}
+
+	inputs, err := structpb.NewStruct(map[string]interface{}{"greeting": "howdy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	triggered, err := client.Trigger(ctx, &gantryv1.TriggerRequest{Workflow: "Hello", Inputs: inputs})
+	if err != nil {
+		t.Fatalf("Trigger failed: %v", err)
+	}
+	run := triggered.GetRun()
+	if run.GetId() == "" || run.GetWorkflowName() != "Hello" {
+		t.Fatalf("Expected a run of Hello, got %v", run)
+	}
+
+	logs, err := client.StreamLogs(ctx, &gantryv1.StreamLogsRequest{RunId: run.GetId(), Job: "greet"})
+	if err != nil {
+		t.Fatalf("StreamLogs failed: %v", err)
+	}
+	var output strings.Builder
+	var jobStatus string
+	for {
+		chunk, err := logs.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Receiving logs failed: %v", err)
+		}
+		if jobStatus != "" {
+			t.Fatalf("Expected the status to come last, got %v after it", chunk)
+		}
+		output.WriteString(chunk.GetOutput())
+		jobStatus = chunk.GetStatus()
+	}
+	if jobStatus != "success" {
+		t.Errorf("Expected the job to end with success, got '%s'", jobStatus)
+	}
+	if !strings.Contains(output.String(), "howdy world") {
+		t.Errorf("Expected the job's output to hold the greeting, got %q", output.String())
+	}
+
+	got, err := client.GetRun(ctx, &gantryv1.GetRunRequest{Id: run.GetId()})
+	if err != nil {
+		t.Fatalf("GetRun failed: %v", err)
+	}
+	if len(got.GetJobs()) != 1 || got.GetJobs()[0].GetName() != "greet" {
+		t.Fatalf("Expected the run's job greet, got %v", got.GetJobs())
+	}
+	job := got.GetJobs()[0]
+	if job.GetStatus() != "success" || job.GetStartedAt() == nil || len(job.GetSteps()) != 1 {
+		t.Errorf("Expected a finished job with its step, got %v", job)
+	}
+}
+
+func TestService_Errors(t *testing.T) {
+	client, _ := newTestClient(t, server.Config{})
+	ctx := context.Background()
+
+	_, err := client.Upload(ctx, &gantryv1.UploadRequest{Workflow: []byte("name: Broken\njobs:\n  build:\n    steps: []\n")})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected InvalidArgument for an invalid workflow, got %v", err)
+	}
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, detail := range status.Convert(err).Details() {
+		if br, ok := detail.(*errdetails.BadRequest); ok {
+			violations = append(violations, br.GetFieldViolations()...)
+		}
+	}
+	if len(violations) == 0 {
+		t.Errorf("Expected the problems as field violations, got %v", status.Convert(err).Details())
+	}
+
+	if _, err := client.Trigger(ctx, &gantryv1.TriggerRequest{Workflow: "nope"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound triggering an unknown workflow, got %v", err)
+	}
+	if _, err := client.GetRun(ctx, &gantryv1.GetRunRequest{Id: "nope"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound getting an unknown run, got %v", err)
+	}
+
+	logs, err := client.StreamLogs(ctx, &gantryv1.StreamLogsRequest{RunId: "nope", Job: "build"})
+	if err == nil {
+		_, err = logs.Recv()
+	}
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound streaming logs of an unknown run, got %v", err)
+	}
+}
+
+func TestService_Auth(t *testing.T) {
+	client, srv := newTestClient(t, server.Config{
+		JWTSecret:         "a-secret-long-enough-for-signing-tokens",
+		AccessTokenTTL:    time.Hour,
+		RefreshTokenTTL:   time.Hour,
+		AllowRegistration: true,
+	})
+
+	// The first user is an admin, later ones viewers
+	for _, name := range []string{"alice", "bob"} {
+		if _, err := srv.Register(name, "password123"); err != nil {
+			t.Fatalf("Failed to register %s: %v", name, err)
+		}
+	}
+	signIn := func(name string) context.Context {
+		tokens, err := srv.Login(name, "password123")
+		if err != nil {
+			t.Fatalf("Failed to sign in %s: %v", name, err)
+		}
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+tokens.AccessToken)
+	}
+	upload := &gantryv1.UploadRequest{Workflow: []byte(testWorkflow)}
+
+	if _, err := client.Upload(context.Background(), upload); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated without a token, got %v", err)
+	}
+	bad := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer nonsense")
+	if _, err := client.Upload(bad, upload); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated with an invalid token, got %v", err)
+	}
+	if _, err := client.Upload(signIn("bob"), upload); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied uploading as a viewer, got %v", err)
+	}
+	if _, err := client.Upload(signIn("alice"), upload); err != nil {
+		t.Errorf("Expected an admin to upload, got %v", err)
+	}
+	if _, err := client.GetRun(signIn("bob"), &gantryv1.GetRunRequest{Id: "nope"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected a viewer to get runs, got %v", err)
+	}
+}
+
+func TestService_RateLimit(t *testing.T) {
+	client, _ := newTestClient(t, server.Config{RateLimit: 60, RateLimitBurst: 1})
+	ctx := context.Background()
+	upload := &gantryv1.UploadRequest{Workflow: []byte(testWorkflow)}
+
+	if _, err := client.Upload(ctx, upload); err != nil {
+		t.Fatalf("Expected the first upload to pass, got %v", err)
+	}
+	if _, err := client.Upload(ctx, upload); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted for the second upload, got %v", err)
+	}
+	// Reading runs isn't limited
+	if _, err := client.GetRun(ctx, &gantryv1.GetRunRequest{Id: "nope"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected GetRun not to be limited, got %v", err)
+	}
+}
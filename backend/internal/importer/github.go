@@ -0,0 +1,467 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// githubContexts maps GitHub Actions context references to the gantry
+// context values that hold the same information
+var githubContexts = map[string]string{
+	"github.actor":                     "gantry.actor",
+	"github.triggering_actor":          "gantry.actor",
+	"github.event_name":                "gantry.event",
+	"github.ref_name":                  "gantry.branch",
+	"github.base_ref":                  "gantry.branch",
+	"github.run_id":                    "gantry.run_id",
+	"github.workflow":                  "gantry.workflow",
+	"github.job":                       "gantry.job",
+	"github.event.action":              "gantry.action",
+	"github.event.number":              "gantry.pull_request",
+	"github.event.pull_request.number": "gantry.pull_request",
+}
+
+// githubForeignContexts are GitHub Actions contexts Gantry doesn't provide
+var githubForeignContexts = map[string]bool{"github": true, "runner": true, "job": true, "strategy": true}
+
+// githubPullRequestTypes are the pull_request activity types Gantry supports
+var githubPullRequestTypes = map[string]bool{
+	"opened": true, "synchronize": true, "reopened": true,
+	"closed": true, "edited": true, "ready_for_review": true,
+}
+
+// githubShells are the step shells Gantry supports
+var githubShells = map[string]bool{"sh": true, "bash": true, "python": true, "node": true}
+
+// githubStepKeys are step keys copied unchanged
+var githubStepKeys = map[string]bool{
+	"id": true, "if": true, "run": true, "env": true,
+	"working-directory": true, "continue-on-error": true, "timeout-minutes": true,
+}
+
+// githubJobKeys are job keys copied unchanged
+var githubJobKeys = map[string]bool{
+	"needs": true, "if": true, "env": true, "timeout-minutes": true, "continue-on-error": true,
+}
+
+// GitHub translates a GitHub Actions workflow. Jobs, steps, needs, env,
+// matrices, containers, services and the push, pull_request,
+// workflow_dispatch, workflow_call and issue_comment triggers carry over;
+// marketplace actions, other events and GitHub-only settings are dropped and
+// reported.
+func GitHub(data []byte, opts Options) (*Result, error) {
+	root, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	name := opts.Name
+	if n := value(root, "name"); name == "" && n != nil && n.Kind == yaml.ScalarNode {
+		name = n.Value
+	}
+	if name == "" {
+		return nil, fmt.Errorf("%w: the workflow must have a name", ErrInvalidSource)
+	}
+
+	t := &translator{contexts: githubContexts, foreign: githubForeignContexts}
+	wf := newMapping()
+	setKey(wf, "name", newScalar(name))
+
+	pairs(root, func(key string, v *yaml.Node) {
+		switch key {
+		case "name":
+		case "on":
+			if on := t.githubTriggers(v); len(on.Content) > 0 {
+				setKey(wf, "on", on)
+			}
+		case "env":
+			setKey(wf, "env", t.copy("env", v))
+		case "defaults":
+			if defaults := t.githubDefaults("defaults", v); defaults != nil {
+				setKey(wf, "defaults", defaults)
+			}
+		case "jobs":
+			setKey(wf, "jobs", t.githubJobs(v))
+		default:
+			t.ignore(key, "not supported")
+		}
+	})
+
+	return t.result(wf)
+}
+
+func (t *translator) githubTriggers(n *yaml.Node) *yaml.Node {
+	on := newMapping()
+	switch n.Kind {
+	case yaml.ScalarNode, yaml.SequenceNode:
+		for _, event := range scalars(n) {
+			t.githubTrigger(on, event, nil)
+		}
+	case yaml.MappingNode:
+		pairs(n, func(event string, cfg *yaml.Node) {
+			t.githubTrigger(on, event, cfg)
+		})
+	}
+	return on
+}
+
+func (t *translator) githubTrigger(on *yaml.Node, event string, cfg *yaml.Node) {
+	path := "on." + event
+	switch event {
+	case "push", "pull_request":
+		trigger := newMapping()
+		var branches, types []string
+		pairs(cfg, func(key string, v *yaml.Node) {
+			switch {
+			case key == "branches":
+				branches = append(branches, scalars(v)...)
+			case key == "branches-ignore":
+				for _, branch := range scalars(v) {
+					branches = append(branches, "!"+branch)
+				}
+			case key == "types" && event == "pull_request":
+				for _, typ := range scalars(v) {
+					if githubPullRequestTypes[typ] {
+						types = append(types, typ)
+					} else {
+						t.ignore(path+".types", "activity type '%s' is not supported", typ)
+					}
+				}
+			default:
+				t.ignore(joinPath(path, key), "filter is not supported")
+			}
+		})
+		if len(branches) > 0 {
+			setKey(trigger, "branches", newList(branches))
+		}
+		if len(types) > 0 {
+			setKey(trigger, "types", newList(types))
+		}
+		setKey(on, event, trigger)
+
+	case "workflow_dispatch", "workflow_call":
+		trigger := newMapping()
+		pairs(cfg, func(key string, v *yaml.Node) {
+			if key != "inputs" {
+				t.ignore(joinPath(path, key), "not supported")
+				return
+			}
+			inputs := newMapping()
+			pairs(v, func(name string, input *yaml.Node) {
+				setKey(inputs, name, t.githubInput(path+".inputs."+name, input))
+			})
+			setKey(trigger, "inputs", inputs)
+		})
+		setKey(on, event, trigger)
+
+	case "issue_comment":
+		if value(cfg, "types") != nil {
+			t.ignore(path+".types", "comment triggers only react to slash commands")
+		}
+		setKey(on, event, newMapping())
+
+	default:
+		t.ignore(path, "event is not supported")
+	}
+}
+
+func (t *translator) githubInput(path string, n *yaml.Node) *yaml.Node {
+	input := newMapping()
+	pairs(n, func(key string, v *yaml.Node) {
+		switch key {
+		case "description", "required", "default", "options":
+			setKey(input, key, t.copy(joinPath(path, key), v))
+		case "type":
+			switch v.Value {
+			case "string", "boolean", "choice":
+				setKey(input, key, t.copy(joinPath(path, key), v))
+			default:
+				t.ignore(path+".type", "type '%s' became a string", v.Value)
+			}
+		default:
+			t.ignore(joinPath(path, key), "not supported")
+		}
+	})
+	return input
+}
+
+// githubDefaults translates defaults.run, which Gantry applies to every step
+func (t *translator) githubDefaults(path string, n *yaml.Node) *yaml.Node {
+	run := newMapping()
+	pairs(n, func(key string, v *yaml.Node) {
+		if key != "run" {
+			t.ignore(joinPath(path, key), "not supported")
+			return
+		}
+		pairs(v, func(key string, v *yaml.Node) {
+			switch {
+			case key == "working-directory", key == "shell" && githubShells[v.Value]:
+				setKey(run, key, t.copy(path+".run."+key, v))
+			case key == "shell":
+				t.ignore(path+".run.shell", "shell '%s' is not supported", v.Value)
+			default:
+				t.ignore(path+".run."+key, "not supported")
+			}
+		})
+	})
+	if len(run.Content) == 0 {
+		return nil
+	}
+	defaults := newMapping()
+	setKey(defaults, "run", run)
+	return defaults
+}
+
+func (t *translator) githubJobs(n *yaml.Node) *yaml.Node {
+	jobs := newMapping()
+	pairs(n, func(name string, v *yaml.Node) {
+		if job := t.githubJob("jobs."+name, v); job != nil {
+			setKey(jobs, name, job)
+		}
+	})
+	return jobs
+}
+
+func (t *translator) githubJob(path string, n *yaml.Node) *yaml.Node {
+	if uses := value(n, "uses"); uses != nil {
+		t.ignore(path, "calls to reusable workflows are not supported; upload '%s' to Gantry and call it by name", uses.Value)
+		return nil
+	}
+
+	job := newMapping()
+	pairs(n, func(key string, v *yaml.Node) {
+		switch key {
+		case "runs-on":
+			setKey(job, key, newScalar(t.githubRunner(path+".runs-on", v)))
+		case "strategy":
+			if strategy := t.githubStrategy(path+".strategy", v); strategy != nil {
+				setKey(job, key, strategy)
+			}
+		case "container":
+			setKey(job, key, t.githubContainer(path+".container", v))
+		case "services":
+			services := newMapping()
+			pairs(v, func(name string, svc *yaml.Node) {
+				setKey(services, name, t.githubContainer(path+".services."+name, svc))
+			})
+			setKey(job, key, services)
+		case "environment":
+			if v.Kind == yaml.MappingNode {
+				if url := value(v, "url"); url != nil {
+					t.ignore(path+".environment.url", "not supported")
+				}
+				v = value(v, "name")
+			}
+			if v != nil {
+				setKey(job, key, t.copy(path+".environment", v))
+			}
+		case "defaults":
+			pairs(value(v, "run"), func(key string, v *yaml.Node) {
+				if key == "working-directory" {
+					setKey(job, key, t.copy(path+".defaults.run."+key, v))
+				} else {
+					t.ignore(path+".defaults.run."+key, "not supported for a job")
+				}
+			})
+		case "steps":
+			steps := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			for i, step := range resolve(v).Content {
+				if s := t.githubStep(fmt.Sprintf("%s.steps[%d]", path, i), i, resolve(step)); s != nil {
+					steps.Content = append(steps.Content, s)
+				}
+			}
+			setKey(job, key, steps)
+		default:
+			if githubJobKeys[key] {
+				setKey(job, key, t.copy(joinPath(path, key), v))
+			} else {
+				t.ignore(joinPath(path, key), "not supported")
+			}
+		}
+	})
+	return job
+}
+
+// githubRunner maps a runs-on label to the Gantry runner closest to it
+func (t *translator) githubRunner(path string, n *yaml.Node) string {
+	if n.Kind == yaml.ScalarNode {
+		label := strings.ToLower(n.Value)
+		switch {
+		case strings.Contains(label, "${{"):
+			return n.Value
+		case strings.HasPrefix(label, "ubuntu"):
+			return "ubuntu"
+		case strings.Contains(label, "alpine"):
+			return "alpine"
+		}
+	}
+	t.ignore(path, "runner '%s' became ubuntu", strings.Join(scalars(n), ", "))
+	return "ubuntu"
+}
+
+func (t *translator) githubStrategy(path string, n *yaml.Node) *yaml.Node {
+	strategy := newMapping()
+	pairs(n, func(key string, v *yaml.Node) {
+		switch key {
+		case "matrix":
+			matrix := newMapping()
+			pairs(v, func(dimension string, values *yaml.Node) {
+				if values.Kind != yaml.SequenceNode {
+					t.ignore(path+".matrix."+dimension, "only lists of values are supported")
+					return
+				}
+				if dimension == "include" || dimension == "exclude" {
+					values = t.githubMatrixEntries(path+".matrix."+dimension, values)
+					if len(values.Content) == 0 {
+						return
+					}
+					setKey(matrix, dimension, values)
+					return
+				}
+				setKey(matrix, dimension, t.copy(path+".matrix."+dimension, values))
+			})
+			if len(matrix.Content) > 0 {
+				setKey(strategy, key, matrix)
+			}
+		case "fail-fast", "max-parallel":
+			setKey(strategy, key, t.copy(joinPath(path, key), v))
+		default:
+			t.ignore(joinPath(path, key), "not supported")
+		}
+	})
+	if value(strategy, "matrix") == nil {
+		return nil
+	}
+	return strategy
+}
+
+// githubMatrixEntries translates an include or exclude list, keeping the
+// entries whose values are all scalars
+func (t *translator) githubMatrixEntries(path string, n *yaml.Node) *yaml.Node {
+	entries := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for i, entry := range n.Content {
+		entryPath := fmt.Sprintf("%s[%d]", path, i)
+		scalar := resolve(entry).Kind == yaml.MappingNode
+		pairs(entry, func(key string, v *yaml.Node) {
+			scalar = scalar && v.Kind == yaml.ScalarNode
+		})
+		if !scalar {
+			t.ignore(entryPath, "only entries of single values are supported")
+			continue
+		}
+		entries.Content = append(entries.Content, t.copy(entryPath, entry))
+	}
+	return entries
+}
+
+// githubContainer translates a job container or service, keeping its image
+// and env
+func (t *translator) githubContainer(path string, n *yaml.Node) *yaml.Node {
+	if n.Kind == yaml.ScalarNode {
+		return t.copy(path, n)
+	}
+	container := newMapping()
+	pairs(n, func(key string, v *yaml.Node) {
+		switch key {
+		case "image", "env":
+			setKey(container, key, t.copy(joinPath(path, key), v))
+		default:
+			t.ignore(joinPath(path, key), "not supported")
+		}
+	})
+	return container
+}
+
+func (t *translator) githubStep(path string, index int, n *yaml.Node) *yaml.Node {
+	step := newMapping()
+	setKey(step, "name", newScalar(githubStepName(index, n)))
+
+	if uses := value(n, "uses"); uses != nil {
+		if !t.githubAction(path, step, uses.Value, value(n, "with")) {
+			return nil
+		}
+	}
+
+	pairs(n, func(key string, v *yaml.Node) {
+		switch {
+		case key == "name", key == "uses", key == "with":
+		case key == "shell" && githubShells[v.Value]:
+			setKey(step, key, t.copy(path+".shell", v))
+		case key == "shell":
+			t.ignore(path+".shell", "shell '%s' is not supported", v.Value)
+		case githubStepKeys[key]:
+			setKey(step, key, t.copy(joinPath(path, key), v))
+		default:
+			t.ignore(joinPath(path, key), "not supported")
+		}
+	})
+	return step
+}
+
+// githubAction translates a step's action into the Gantry step that does the
+// same. It reports false when the step has no equivalent and is dropped.
+func (t *translator) githubAction(path string, step *yaml.Node, uses string, with *yaml.Node) bool {
+	if strings.HasPrefix(uses, "./") {
+		setKey(step, "uses", newScalar(uses))
+		if with != nil {
+			setKey(step, "with", t.copy(path+".with", with))
+		}
+		return true
+	}
+
+	var kind string
+	var keys []string
+	action, _, _ := strings.Cut(uses, "@")
+	switch action {
+	case "actions/upload-artifact":
+		kind, keys = "upload-artifact", []string{"name", "path"}
+	case "actions/download-artifact":
+		kind, keys = "download-artifact", []string{"name", "path"}
+	case "actions/cache":
+		kind, keys = "cache", []string{"path", "key", "restore-keys"}
+	case "actions/checkout":
+		t.ignore(path, "actions/checkout has no Gantry equivalent; clone the repository in a run step")
+		return false
+	default:
+		t.ignore(path, "action '%s' is not supported", uses)
+		return false
+	}
+
+	cfg := newMapping()
+	pairs(with, func(key string, v *yaml.Node) {
+		supported := false
+		for _, k := range keys {
+			supported = supported || k == key
+		}
+		if supported {
+			setKey(cfg, key, t.copy(path+".with."+key, v))
+		} else {
+			t.ignore(path+".with."+key, "not supported by %s", kind)
+		}
+	})
+	setKey(step, kind, cfg)
+	return true
+}
+
+// githubStepName returns a step's name, making one up from what the step
+// does when it has none, since Gantry requires step names
+func githubStepName(index int, n *yaml.Node) string {
+	if name := value(n, "name"); name != nil && name.Value != "" {
+		return name.Value
+	}
+	if uses := value(n, "uses"); uses != nil {
+		return uses.Value
+	}
+	if run := value(n, "run"); run != nil {
+		line, _, _ := strings.Cut(strings.TrimSpace(run.Value), "\n")
+		if len(line) > 60 {
+			line = line[:60] + "..."
+		}
+		if line != "" {
+			return line
+		}
+	}
+	return fmt.Sprintf("Step %d", index+1)
+}
@@ -0,0 +1,190 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gantry/internal/models"
+	"gantry/internal/parser"
+)
+
+const githubWorkflow = `
+name: CI
+on:
+  push:
+    branches: [main, "release/**"]
+    branches-ignore: ["wip/*"]
+    paths: ["src/**"]
+  pull_request:
+    types: [opened, labeled]
+  workflow_dispatch:
+    inputs:
+      level:
+        type: choice
+        options: [info, debug]
+        default: info
+      count:
+        type: number
+  schedule:
+    - cron: "0 0 * * *"
+permissions:
+  contents: read
+env:
+  GO_VERSION: "1.24"
+jobs:
+  test:
+    name: Unit tests
+    runs-on: ubuntu-latest
+    timeout-minutes: 15
+    strategy:
+      fail-fast: false
+      matrix:
+        go: ["1.23", "1.24"]
+        include:
+          - go: "1.22"
+    steps:
+      - uses: actions/checkout@v4
+      - uses: actions/setup-go@v5
+        with:
+          go-version: ${{ matrix.go }}
+      - uses: actions/cache@v4
+        with:
+          path: ~/.cache/go-build
+          key: go-${{ matrix.go }}
+          enableCrossOsArchive: true
+      - run: |
+          go test ./...
+        shell: pwsh
+      - name: Report
+        if: github.event_name == 'push'
+        run: echo "${{ github.actor }} pushed ${{ github.sha }}"
+      - uses: actions/upload-artifact@v4
+        with:
+          name: coverage
+          path: coverage.out
+  deploy:
+    needs: test
+    runs-on: [self-hosted, linux]
+    environment:
+      name: production
+      url: https://example.com
+    steps:
+      - name: Deploy
+        run: ./deploy.sh
+  shared:
+    uses: org/repo/.github/workflows/shared.yml@main
+`
+
+func TestGitHub(t *testing.T) {
+	result, err := GitHub([]byte(githubWorkflow), Options{})
+	if err != nil {
+		t.Fatalf("GitHub returned error: %v", err)
+	}
+
+	p := parser.NewParser()
+	wf, err := p.Parse(result.Workflow)
+	if err != nil {
+		t.Fatalf("Translated workflow doesn't parse: %v\n%s", err, result.Workflow)
+	}
+	if err := p.Validate(wf); err != nil {
+		t.Fatalf("Translated workflow is invalid: %v\n%s", err, result.Workflow)
+	}
+
+	if wf.Name != "CI" || wf.Env["GO_VERSION"] != "1.24" {
+		t.Errorf("Expected name and env to carry over, got %q and %v", wf.Name, wf.Env)
+	}
+	if push := wf.On.Push; push == nil || strings.Join(push.Branches, ",") != "main,release/**,!wip/*" {
+		t.Errorf("Expected branches with ignored branches negated, got %+v", wf.On.Push)
+	}
+	if pr := wf.On.PullRequest; pr == nil || len(pr.Types) != 1 || pr.Types[0] != "opened" {
+		t.Errorf("Expected only supported pull_request types, got %+v", wf.On.PullRequest)
+	}
+	if input := wf.On.WorkflowDispatch.Inputs["level"]; input.Type != models.InputChoice || len(input.Options) != 2 {
+		t.Errorf("Expected choice input to carry over, got %+v", input)
+	}
+
+	if len(wf.JobOrder) != 2 || wf.JobOrder[0] != "test" || wf.JobOrder[1] != "deploy" {
+		t.Fatalf("Expected jobs test and deploy, got %v", wf.JobOrder)
+	}
+	test := wf.Jobs["test"]
+	if test.RunsOn != "ubuntu" || test.TimeoutMinutes != 15 || test.Strategy.FailsFast() {
+		t.Errorf("Unexpected test job: %+v", test)
+	}
+	if m := test.Strategy.Matrix; len(m.Dimensions["go"]) != 2 || len(m.Include) != 1 || m.Include[0]["go"] != "1.22" {
+		t.Errorf("Expected matrix with its include entry, got %+v", m)
+	}
+	if len(test.Steps) != 4 {
+		t.Fatalf("Expected checkout and setup-go to be dropped, got %+v", test.Steps)
+	}
+	if cache := test.Steps[0].Cache; cache == nil || cache.Key != "go-${{ matrix.go }}" {
+		t.Errorf("Expected cache step, got %+v", test.Steps[0])
+	}
+	if test.Steps[1].Name != "go test ./..." || test.Steps[1].Shell != "" {
+		t.Errorf("Expected unnamed step named after its command without its unsupported shell, got %+v", test.Steps[1])
+	}
+	report := test.Steps[2]
+	if report.If != "gantry.event == 'push'" || report.Run != `echo "${{ gantry.actor }} pushed ${{ github.sha }}"` {
+		t.Errorf("Expected github context references to be rewritten, got if %q run %q", report.If, report.Run)
+	}
+	if artifact := test.Steps[3].UploadArtifact; artifact == nil || artifact.Name != "coverage" {
+		t.Errorf("Expected upload-artifact step, got %+v", test.Steps[3])
+	}
+
+	deploy := wf.Jobs["deploy"]
+	if deploy.RunsOn != "ubuntu" || deploy.Environment != "production" || len(deploy.Needs) != 1 {
+		t.Errorf("Unexpected deploy job: %+v", deploy)
+	}
+
+	for _, want := range []string{
+		"on.push.paths", "on.pull_request.types: activity type 'labeled'", "on.workflow_dispatch.inputs.count.type",
+		"on.schedule", "permissions", "jobs.test.name",
+		"jobs.test.steps[0]: actions/checkout", "jobs.test.steps[1]: action 'actions/setup-go@v5'",
+		"jobs.test.steps[2].with.enableCrossOsArchive", "jobs.test.steps[3].shell", "jobs.test.steps[4].run: github.sha",
+		"jobs.deploy.runs-on: runner 'self-hosted, linux'", "jobs.deploy.environment.url", "jobs.shared",
+	} {
+		if !containsNote(result.Ignored, want) {
+			t.Errorf("Expected a note about %q, got:\n%s", want, strings.Join(result.Ignored, "\n"))
+		}
+	}
+}
+
+func TestGitHub_Invalid(t *testing.T) {
+	for name, source := range map[string]string{
+		"syntax":     "name: [",
+		"not a map":  "- job",
+		"no name":    "on: push\njobs: {}\n",
+		"empty name": "name: ''\njobs: {}\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := GitHub([]byte(source), Options{}); !errors.Is(err, ErrInvalidSource) {
+				t.Errorf("Expected ErrInvalidSource, got %v", err)
+			}
+		})
+	}
+}
+
+func TestGitHub_NameOption(t *testing.T) {
+	result, err := GitHub([]byte("name: CI\njobs: {}\n"), Options{Name: "Imported CI"})
+	if err != nil {
+		t.Fatalf("GitHub returned error: %v", err)
+	}
+	if !strings.HasPrefix(string(result.Workflow), "name: Imported CI\n") {
+		t.Errorf("Expected the name option to replace the source's name, got:\n%s", result.Workflow)
+	}
+}
+
+func TestImport_UnsupportedFormat(t *testing.T) {
+	if _, err := Import("jenkins", []byte("pipeline {}"), Options{}); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Expected ErrUnsupportedFormat, got %v", err)
+	}
+}
+
+func containsNote(notes []string, want string) bool {
+	for _, note := range notes {
+		if strings.Contains(note, want) {
+			return true
+		}
+	}
+	return false
+}
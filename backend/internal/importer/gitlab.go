@@ -0,0 +1,652 @@
+package importer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// gitlabDefaultStages are the stages of a file that doesn't list its own
+var gitlabDefaultStages = []string{"build", "test", "deploy"}
+
+// gitlabKeywords are the top-level keys that don't define jobs
+var gitlabKeywords = map[string]bool{
+	"stages": true, "variables": true, "default": true, "workflow": true, "include": true,
+	"image": true, "services": true, "before_script": true, "after_script": true, "cache": true,
+}
+
+// gitlabDefaultKeys are the job keys `default:`, and the deprecated
+// top-level keywords of the same name, set for every job
+var gitlabDefaultKeys = []string{"image", "services", "before_script", "after_script", "cache", "timeout", "retry"}
+
+// gitlabVariablePattern matches references to GitLab's predefined variables
+var gitlabVariablePattern = regexp.MustCompile(`\$\{?(CI_[A-Z0-9_]+)`)
+
+// gitlabDurationPattern matches the parts of a duration such as "1h 30m"
+var gitlabDurationPattern = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*([a-z]+)`)
+
+// gitlabUnitMinutes are the lengths of duration units, in minutes
+var gitlabUnitMinutes = map[string]float64{
+	"d": 1440, "day": 1440, "days": 1440,
+	"h": 60, "hr": 60, "hrs": 60, "hour": 60, "hours": 60,
+	"m": 1, "min": 1, "mins": 1, "minute": 1, "minutes": 1,
+	"s": 1.0 / 60, "sec": 1.0 / 60, "secs": 1.0 / 60, "second": 1.0 / 60, "seconds": 1.0 / 60,
+}
+
+// unsafeNamePattern matches the characters not allowed in artifact and
+// service names
+var unsafeNamePattern = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// gitlabJob is a job being translated, with the stage it runs in
+type gitlabJob struct {
+	name  string
+	stage int
+	job   *yaml.Node
+
+	// needs are the jobs listed by the job's own needs; explicit is false
+	// when it has none, so it needs the jobs of the stage before it
+	needs    []string
+	explicit bool
+}
+
+// GitLab translates a .gitlab-ci.yml file. Jobs with their scripts, images,
+// services, variables, needs, artifacts and caches carry over, and stages
+// become needs on the jobs of the previous stage. GitLab CI files have no
+// name, so opts.Name is required unless the file sets workflow:name.
+func GitLab(data []byte, opts Options) (*Result, error) {
+	root, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	name := opts.Name
+	if n := value(value(root, "workflow"), "name"); name == "" && n != nil {
+		name = n.Value
+	}
+	if name == "" {
+		return nil, fmt.Errorf("%w: GitLab CI files have no name; name the workflow", ErrInvalidSource)
+	}
+
+	t := &translator{}
+	wf := newMapping()
+	setKey(wf, "name", newScalar(name))
+	if variables := value(root, "variables"); variables != nil {
+		setKey(wf, "env", t.gitlabVariables(variables))
+	}
+
+	stages := append([]string{".pre"}, gitlabDefaultStages...)
+	if list := value(root, "stages"); list != nil {
+		stages = append([]string{".pre"}, scalars(list)...)
+	}
+	stages = append(stages, ".post")
+
+	defaults := map[string]*yaml.Node{}
+	for _, key := range gitlabDefaultKeys {
+		if v := value(root, key); v != nil {
+			defaults[key] = v
+		}
+	}
+	pairs(value(root, "default"), func(key string, v *yaml.Node) {
+		if containsKey(gitlabDefaultKeys, key) {
+			defaults[key] = v
+		} else {
+			t.ignore("default."+key, "not supported")
+		}
+	})
+
+	definitions := map[string]*yaml.Node{}
+	var names []string
+	pairs(root, func(key string, v *yaml.Node) {
+		switch {
+		case key == "workflow":
+			pairs(v, func(key string, _ *yaml.Node) {
+				if key != "name" {
+					t.ignore("workflow."+key, "not supported")
+				}
+			})
+		case key == "include":
+			t.ignore(key, "included files are not imported; import their jobs into this file first")
+		case gitlabKeywords[key]:
+		case v.Kind != yaml.MappingNode:
+			t.ignore(key, "not supported")
+		default:
+			definitions[key] = v
+			if !strings.HasPrefix(key, ".") {
+				names = append(names, key)
+			}
+		}
+	})
+
+	var jobs []*gitlabJob
+	for _, name := range names {
+		path := name
+		def := t.gitlabExtend(path, definitions[name], definitions, []string{name})
+		if job := t.gitlabJob(path, name, def, defaults, stages); job != nil {
+			jobs = append(jobs, job)
+		}
+	}
+
+	setKey(wf, "jobs", t.gitlabJobs(jobs, stages))
+	return t.result(wf)
+}
+
+// gitlabExtend merges the definitions a job extends into it, in order, with
+// the job's own keys winning. Mappings merge key by key; other values are
+// replaced.
+func (t *translator) gitlabExtend(path string, def *yaml.Node, definitions map[string]*yaml.Node, stack []string) *yaml.Node {
+	extends := value(def, "extends")
+	if extends == nil {
+		return def
+	}
+
+	merged := newMapping()
+	for _, name := range scalars(extends) {
+		base, ok := definitions[name]
+		switch {
+		case !ok:
+			t.ignore(path+".extends", "'%s' is not defined", name)
+		case containsKey(stack, name):
+			t.ignore(path+".extends", "'%s' extends itself", name)
+		default:
+			merged = mergeMappings(merged, t.gitlabExtend(path, base, definitions, append(stack, name)))
+		}
+	}
+	return mergeMappings(merged, def)
+}
+
+func (t *translator) gitlabJob(path, name string, def *yaml.Node, defaults map[string]*yaml.Node, stages []string) *gitlabJob {
+	if when := value(def, "when"); when != nil && when.Value == "never" {
+		t.ignore(path, "jobs with when: never are not imported")
+		return nil
+	}
+	if value(def, "trigger") != nil {
+		t.ignore(path, "trigger jobs are not supported")
+		return nil
+	}
+
+	// Fill in the defaults the job inherits
+	inherit := value(value(def, "inherit"), "default")
+	for _, key := range gitlabDefaultKeys {
+		if value(def, key) != nil || defaults[key] == nil {
+			continue
+		}
+		if inherit != nil && (inherit.Value == "false" || inherit.Kind == yaml.SequenceNode && !containsKey(scalars(inherit), key)) {
+			continue
+		}
+		def = mergeMappings(def, singleKey(key, defaults[key]))
+	}
+
+	g := &gitlabJob{name: name, stage: indexOf(stages, "test")}
+	job := newMapping()
+	setKey(job, "runs-on", newScalar("ubuntu"))
+	g.job = job
+
+	var container *yaml.Node
+	var cacheSteps, scriptSteps, artifactSteps []*yaml.Node
+	pairs(def, func(key string, v *yaml.Node) {
+		keyPath := joinPath(path, key)
+		switch key {
+		case "extends", "inherit":
+		case "stage":
+			if g.stage = indexOf(stages, v.Value); g.stage < 0 {
+				t.ignore(keyPath, "stage '%s' is not listed in stages; the job runs last", v.Value)
+				g.stage = len(stages)
+			}
+		case "image":
+			container = t.gitlabImage(keyPath, v)
+		case "services":
+			setKey(job, "services", t.gitlabServices(keyPath, v))
+		case "variables":
+			setKey(job, "env", t.gitlabVariables(v))
+		case "needs":
+			g.explicit = true
+			for _, need := range resolve(v).Content {
+				if need = resolve(need); need.Kind == yaml.ScalarNode {
+					g.needs = append(g.needs, need.Value)
+				} else if ref := value(need, "job"); ref != nil {
+					g.needs = append(g.needs, ref.Value)
+				} else {
+					t.ignore(keyPath, "only needs on jobs of this pipeline are supported")
+				}
+			}
+		case "when":
+			switch v.Value {
+			case "on_success":
+			case "on_failure":
+				setKey(job, "if", newScalar("failure()"))
+			case "always":
+				setKey(job, "if", newScalar("always()"))
+			default:
+				t.ignore(keyPath, "'%s' jobs run automatically", v.Value)
+			}
+		case "allow_failure":
+			if v.Kind == yaml.MappingNode {
+				t.ignore(keyPath, "exit codes are not supported; every failure is allowed")
+			}
+			if v.Kind == yaml.MappingNode || v.Value == "true" {
+				setKey(job, "continue-on-error", newBool(true))
+			}
+		case "timeout":
+			if minutes, ok := gitlabMinutes(v.Value); ok {
+				setKey(job, "timeout-minutes", newNumber(minutes))
+			} else {
+				t.ignore(keyPath, "can't read duration '%s'", v.Value)
+			}
+		case "environment":
+			if v.Kind == yaml.MappingNode {
+				v = value(v, "name")
+			}
+			if v != nil {
+				setKey(job, "environment", t.copy(keyPath, v))
+			}
+		case "parallel":
+			if strategy := t.gitlabParallel(keyPath, v); strategy != nil {
+				setKey(job, "strategy", strategy)
+			}
+		case "before_script", "script", "after_script", "retry":
+			// Become steps below, in script order
+		case "cache":
+			cacheSteps = t.gitlabCache(keyPath, name, v)
+		case "artifacts":
+			artifactSteps = t.gitlabArtifacts(keyPath, name, v)
+		case "dependencies":
+			t.ignore(keyPath, "artifacts are not passed between jobs automatically; add download-artifact steps")
+		default:
+			t.ignore(keyPath, "not supported")
+		}
+	})
+
+	if g.stage < 0 {
+		t.ignore(path, "jobs without a stage run in 'test', which is not listed in stages; the job runs last")
+		g.stage = len(stages)
+	}
+
+	retry := value(def, "retry")
+	for _, script := range []struct{ key, name, condition string }{
+		{"before_script", "Before script", ""},
+		{"script", "Script", ""},
+		{"after_script", "After script", "always()"},
+	} {
+		lines := scalars(value(def, script.key))
+		if len(lines) == 0 {
+			continue
+		}
+		t.gitlabNoteVariables(joinPath(path, script.key), lines)
+
+		step := newMapping()
+		setKey(step, "name", newScalar(script.name))
+		if script.condition != "" {
+			setKey(step, "if", newScalar(script.condition))
+		}
+		setKey(step, "run", newBlock(strings.Join(lines, "\n")))
+		if retry != nil && script.key == "script" {
+			if attempts := t.gitlabRetry(path+".retry", retry); attempts > 1 {
+				policy := newMapping()
+				setKey(policy, "attempts", newNumber(float64(attempts)))
+				setKey(step, "retry", policy)
+			}
+		}
+		scriptSteps = append(scriptSteps, step)
+	}
+
+	if container != nil {
+		setKey(job, "container", container)
+	}
+	steps := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	steps.Content = append(append(append(steps.Content, cacheSteps...), scriptSteps...), artifactSteps...)
+	setKey(job, "steps", steps)
+	return g
+}
+
+// gitlabJobs orders jobs by stage and makes each job without needs of its
+// own need the jobs of the closest earlier stage that has any
+func (t *translator) gitlabJobs(jobs []*gitlabJob, stages []string) *yaml.Node {
+	sort.SliceStable(jobs, func(i, j int) bool { return jobs[i].stage < jobs[j].stage })
+
+	imported := map[string]bool{}
+	for _, g := range jobs {
+		imported[g.name] = true
+	}
+
+	out := newMapping()
+	var previous, current []string
+	for i, g := range jobs {
+		if i > 0 && g.stage != jobs[i-1].stage {
+			previous, current = current, nil
+		}
+		current = append(current, g.name)
+
+		needs := previous
+		if g.explicit {
+			needs = nil
+			for _, need := range g.needs {
+				if imported[need] {
+					needs = append(needs, need)
+				} else {
+					t.ignore(g.name+".needs", "'%s' was not imported", need)
+				}
+			}
+		}
+		if len(needs) > 0 {
+			g.job.Content = append(g.job.Content[:2], append([]*yaml.Node{newScalar("needs"), newList(needs)}, g.job.Content[2:]...)...)
+		}
+		setKey(out, g.name, g.job)
+	}
+	return out
+}
+
+// gitlabVariables translates variables, which may be values or mappings
+// with a value and a description
+func (t *translator) gitlabVariables(n *yaml.Node) *yaml.Node {
+	env := newMapping()
+	pairs(n, func(name string, v *yaml.Node) {
+		if v.Kind == yaml.MappingNode {
+			v = value(v, "value")
+		}
+		if v != nil && v.Kind == yaml.ScalarNode {
+			setKey(env, name, newScalar(v.Value))
+		}
+	})
+	return env
+}
+
+// gitlabImage translates a job image into a container
+func (t *translator) gitlabImage(path string, n *yaml.Node) *yaml.Node {
+	if n.Kind == yaml.ScalarNode {
+		return newScalar(n.Value)
+	}
+	container := newMapping()
+	pairs(n, func(key string, v *yaml.Node) {
+		switch key {
+		case "name":
+			setKey(container, "image", newScalar(v.Value))
+		case "entrypoint":
+			if entrypoint := scalars(v); len(entrypoint) > 0 && entrypoint[0] != "" {
+				setKey(container, key, newList(entrypoint))
+			}
+		default:
+			t.ignore(joinPath(path, key), "not supported")
+		}
+	})
+	return container
+}
+
+// gitlabServices translates a list of service images, naming each service
+// after its alias or image
+func (t *translator) gitlabServices(path string, n *yaml.Node) *yaml.Node {
+	services := newMapping()
+	for i, item := range resolve(n).Content {
+		item = resolve(item)
+		image, alias := item.Value, ""
+		service := newMapping()
+		if item.Kind == yaml.MappingNode {
+			image = ""
+			pairs(item, func(key string, v *yaml.Node) {
+				switch key {
+				case "name":
+					image = v.Value
+				case "alias":
+					alias, _, _ = strings.Cut(v.Value, ",")
+				case "command":
+					setKey(service, key, newList(scalars(v)))
+				case "variables":
+					setKey(service, "env", t.gitlabVariables(v))
+				default:
+					t.ignore(fmt.Sprintf("%s[%d].%s", path, i, key), "not supported")
+				}
+			})
+		}
+		if alias == "" {
+			alias = serviceName(image)
+		}
+		service.Content = append([]*yaml.Node{newScalar("image"), newScalar(image)}, service.Content...)
+		setKey(services, safeName(alias), service)
+	}
+	return services
+}
+
+// gitlabParallel translates a parallel matrix with a single entry. GitLab
+// runs every entry of a longer list, which a single Gantry matrix can't
+// express.
+func (t *translator) gitlabParallel(path string, n *yaml.Node) *yaml.Node {
+	entries := value(n, "matrix")
+	if entries == nil || len(entries.Content) != 1 {
+		t.ignore(path, "only a matrix with a single entry is supported")
+		return nil
+	}
+
+	matrix := newMapping()
+	pairs(entries.Content[0], func(name string, v *yaml.Node) {
+		setKey(matrix, name, newList(scalars(v)))
+	})
+	strategy := newMapping()
+	setKey(strategy, "matrix", matrix)
+	return strategy
+}
+
+// gitlabCache translates a cache, or a list of caches, into a cache step per
+// path
+func (t *translator) gitlabCache(path, job string, n *yaml.Node) []*yaml.Node {
+	caches := []*yaml.Node{n}
+	if n.Kind == yaml.SequenceNode {
+		caches = n.Content
+	}
+
+	var steps []*yaml.Node
+	for _, c := range caches {
+		c = resolve(c)
+		key := "default"
+		if k := value(c, "key"); k != nil && k.Kind == yaml.ScalarNode {
+			key = k.Value
+		} else if k != nil {
+			key = job
+			t.ignore(path+".key", "keys computed from files are not supported; the cache is keyed by the job name")
+		}
+		pairs(c, func(k string, _ *yaml.Node) {
+			if k != "key" && k != "paths" {
+				t.ignore(joinPath(path, k), "not supported")
+			}
+		})
+
+		paths := scalars(value(c, "paths"))
+		for i, p := range paths {
+			cache := newMapping()
+			setKey(cache, "path", newScalar(p))
+			if len(paths) > 1 {
+				setKey(cache, "key", newScalar(fmt.Sprintf("%s-%d", key, i+1)))
+			} else {
+				setKey(cache, "key", newScalar(key))
+			}
+			step := newMapping()
+			setKey(step, "name", newScalar("Cache "+p))
+			setKey(step, "cache", cache)
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// gitlabArtifacts translates artifact paths into upload-artifact steps run
+// after the scripts
+func (t *translator) gitlabArtifacts(path, job string, n *yaml.Node) []*yaml.Node {
+	condition := ""
+	pairs(n, func(key string, v *yaml.Node) {
+		switch key {
+		case "paths":
+		case "when":
+			switch v.Value {
+			case "always":
+				condition = "always()"
+			case "on_failure":
+				condition = "failure()"
+			}
+		default:
+			t.ignore(joinPath(path, key), "not supported")
+		}
+	})
+
+	paths := scalars(value(n, "paths"))
+	var steps []*yaml.Node
+	for i, p := range paths {
+		name := safeName(job)
+		if len(paths) > 1 {
+			name = fmt.Sprintf("%s-%d", name, i+1)
+		}
+		artifact := newMapping()
+		setKey(artifact, "name", newScalar(name))
+		setKey(artifact, "path", newScalar(p))
+
+		step := newMapping()
+		setKey(step, "name", newScalar("Upload "+p))
+		if condition != "" {
+			setKey(step, "if", newScalar(condition))
+		}
+		setKey(step, "upload-artifact", artifact)
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// gitlabRetry returns how many attempts a retry setting allows
+func (t *translator) gitlabRetry(path string, n *yaml.Node) int {
+	if n.Kind == yaml.MappingNode {
+		pairs(n, func(key string, _ *yaml.Node) {
+			if key != "max" {
+				t.ignore(joinPath(path, key), "not supported; every failure is retried")
+			}
+		})
+		n = value(n, "max")
+	}
+	if n == nil {
+		return 1
+	}
+	retries, err := strconv.Atoi(n.Value)
+	if err != nil {
+		t.ignore(path, "can't read retry count '%s'", n.Value)
+		return 1
+	}
+	return retries + 1
+}
+
+// gitlabNoteVariables notes each predefined GitLab variable a script uses,
+// since Gantry doesn't set them
+func (t *translator) gitlabNoteVariables(path string, lines []string) {
+	seen := map[string]bool{}
+	for _, line := range lines {
+		for _, match := range gitlabVariablePattern.FindAllStringSubmatch(line, -1) {
+			if !seen[match[1]] {
+				seen[match[1]] = true
+				t.ignore(path, "predefined variable $%s is not set", match[1])
+			}
+		}
+	}
+}
+
+// gitlabMinutes parses a GitLab duration such as "1h 30m" into minutes.
+// Plain numbers are seconds.
+func gitlabMinutes(s string) (float64, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+		return seconds / 60, true
+	}
+
+	matches := gitlabDurationPattern.FindAllStringSubmatch(s, -1)
+	if len(matches) == 0 {
+		return 0, false
+	}
+	minutes := 0.0
+	for _, match := range matches {
+		unit, ok := gitlabUnitMinutes[match[2]]
+		if !ok {
+			return 0, false
+		}
+		n, _ := strconv.ParseFloat(match[1], 64)
+		minutes += n * unit
+	}
+	return minutes, true
+}
+
+// mergeMappings returns dst with the keys of src merged over it, recursing
+// into mappings both set. Neither node is modified.
+func mergeMappings(dst, src *yaml.Node) *yaml.Node {
+	if dst.Kind != yaml.MappingNode || src.Kind != yaml.MappingNode {
+		return src
+	}
+	merged := newMapping()
+	merged.Content = append(merged.Content, dst.Content...)
+	pairs(src, func(key string, v *yaml.Node) {
+		for i := 0; i+1 < len(merged.Content); i += 2 {
+			if merged.Content[i].Value == key {
+				merged.Content[i+1] = mergeMappings(resolve(merged.Content[i+1]), v)
+				return
+			}
+		}
+		merged.Content = append(merged.Content, newScalar(key), v)
+	})
+	return merged
+}
+
+func singleKey(key string, v *yaml.Node) *yaml.Node {
+	m := newMapping()
+	setKey(m, key, v)
+	return m
+}
+
+func newBool(b bool) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(b)}
+}
+
+func newNumber(f float64) *yaml.Node {
+	if f == float64(int64(f)) {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.FormatInt(int64(f), 10)}
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: strconv.FormatFloat(f, 'f', -1, 64)}
+}
+
+// newBlock returns a string scalar, using a literal block for scripts of
+// several lines
+func newBlock(s string) *yaml.Node {
+	n := newScalar(s)
+	if strings.Contains(s, "\n") {
+		n.Style = yaml.LiteralStyle
+	}
+	return n
+}
+
+// serviceName derives a service name from an image, e.g. postgres from
+// docker.io/library/postgres:16
+func serviceName(image string) string {
+	name := image
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	name, _, _ = strings.Cut(name, "@")
+	name, _, _ = strings.Cut(name, ":")
+	return name
+}
+
+// safeName replaces the characters artifact and service names can't hold
+func safeName(name string) string {
+	name = strings.Trim(unsafeNamePattern.ReplaceAllString(name, "-"), "-_")
+	if name == "" {
+		return "job"
+	}
+	return name
+}
+
+func containsKey(keys []string, key string) bool {
+	return indexOf(keys, key) >= 0
+}
+
+func indexOf(values []string, s string) int {
+	for i, v := range values {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,165 @@
+package importer
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gantry/internal/parser"
+)
+
+const gitlabPipeline = `
+stages: [build, test, deploy]
+variables:
+  GO_VERSION: "1.24"
+  LEVEL:
+    value: info
+    description: Log level
+default:
+  image: golang:1.24
+  before_script:
+    - go version
+  tags: [docker]
+.tested:
+  services:
+    - postgres:16
+    - name: redis:7
+      alias: cache
+  retry: 2
+build:
+  stage: build
+  script:
+    - go build -o bin/app ./...
+    - echo built $CI_COMMIT_SHA
+  cache:
+    key: go-mod
+    paths: [.cache/go]
+  artifacts:
+    paths: [bin/app]
+    expire_in: 1 week
+unit:
+  extends: .tested
+  script: go test ./...
+  timeout: 1h 30m
+lint:
+  image:
+    name: golangci/golangci-lint:v1
+    entrypoint: [""]
+  before_script: []
+  script: golangci-lint run
+  allow_failure: true
+  parallel:
+    matrix:
+      - GOOS: [linux, darwin]
+deploy:
+  stage: deploy
+  needs: [build]
+  environment:
+    name: production
+  when: manual
+  only: [main]
+  script: ./deploy.sh
+cleanup:
+  stage: .post
+  when: always
+  script: ./cleanup.sh
+disabled:
+  when: never
+  script: exit 1
+`
+
+func TestGitLab(t *testing.T) {
+	result, err := GitLab([]byte(gitlabPipeline), Options{Name: "Pipeline"})
+	if err != nil {
+		t.Fatalf("GitLab returned error: %v", err)
+	}
+
+	p := parser.NewParser()
+	wf, err := p.Parse(result.Workflow)
+	if err != nil {
+		t.Fatalf("Translated workflow doesn't parse: %v\n%s", err, result.Workflow)
+	}
+	if err := p.Validate(wf); err != nil {
+		t.Fatalf("Translated workflow is invalid: %v\n%s", err, result.Workflow)
+	}
+
+	if wf.Name != "Pipeline" || wf.Env["GO_VERSION"] != "1.24" || wf.Env["LEVEL"] != "info" {
+		t.Errorf("Expected name and variables to carry over, got %q and %v", wf.Name, wf.Env)
+	}
+	if want := []string{"build", "unit", "lint", "deploy", "cleanup"}; !reflect.DeepEqual(wf.JobOrder, want) {
+		t.Errorf("Expected jobs in stage order %v, got %v", want, wf.JobOrder)
+	}
+
+	build := wf.Jobs["build"]
+	if build.Container == nil || build.Container.Image != "golang:1.24" || len(build.Needs) != 0 {
+		t.Errorf("Expected build to use the default image and need nothing, got %+v", build)
+	}
+	if len(build.Steps) != 4 || build.Steps[0].Cache == nil || build.Steps[1].Run != "go version" ||
+		build.Steps[2].Run != "go build -o bin/app ./...\necho built $CI_COMMIT_SHA" ||
+		build.Steps[3].UploadArtifact == nil || build.Steps[3].UploadArtifact.Path != "bin/app" {
+		t.Errorf("Expected cache, before_script, script and upload steps, got %+v", build.Steps)
+	}
+
+	unit := wf.Jobs["unit"]
+	if !reflect.DeepEqual([]string(unit.Needs), []string{"build"}) || unit.TimeoutMinutes != 90 {
+		t.Errorf("Expected unit to need the build stage with a 90 minute timeout, got %+v", unit)
+	}
+	if _, ok := unit.Services["cache"]; !ok || unit.Services["postgres"].Image != "postgres:16" {
+		t.Errorf("Expected services from the extended template, got %+v", unit.Services)
+	}
+	if script := unit.Steps[1]; script.Retry == nil || script.Retry.Attempts != 3 {
+		t.Errorf("Expected two retries to allow three attempts, got %+v", script)
+	}
+
+	lint := wf.Jobs["lint"]
+	if !lint.ContinueOnError || len(lint.Steps) != 1 || len(lint.Container.Entrypoint) != 0 {
+		t.Errorf("Expected lint to allow failure without before_script or entrypoint, got %+v", lint)
+	}
+	if dims := lint.Strategy.Matrix.Dimensions["GOOS"]; len(dims) != 2 {
+		t.Errorf("Expected the parallel matrix to carry over, got %+v", lint.Strategy)
+	}
+
+	if deploy := wf.Jobs["deploy"]; deploy.Environment != "production" || len(deploy.Needs) != 1 {
+		t.Errorf("Expected deploy to keep its own needs and environment, got %+v", deploy)
+	}
+	if cleanup := wf.Jobs["cleanup"]; cleanup.If != "always()" || len(cleanup.Needs) != 1 || cleanup.Needs[0] != "deploy" {
+		t.Errorf("Expected cleanup to always run after deploy, got %+v", cleanup)
+	}
+
+	for _, want := range []string{
+		"default.tags", "build.script: predefined variable $CI_COMMIT_SHA", "build.artifacts.expire_in",
+		"deploy.when: 'manual' jobs run automatically", "deploy.only", "disabled: jobs with when: never",
+	} {
+		if !containsNote(result.Ignored, want) {
+			t.Errorf("Expected a note about %q, got:\n%s", want, strings.Join(result.Ignored, "\n"))
+		}
+	}
+}
+
+func TestGitLab_Name(t *testing.T) {
+	source := "workflow:\n  name: From file\njob:\n  script: make\n"
+	result, err := GitLab([]byte(source), Options{})
+	if err != nil {
+		t.Fatalf("GitLab returned error: %v", err)
+	}
+	if !strings.HasPrefix(string(result.Workflow), "name: From file\n") {
+		t.Errorf("Expected workflow:name to name the workflow, got:\n%s", result.Workflow)
+	}
+
+	if _, err := GitLab([]byte("job:\n  script: make\n"), Options{}); !errors.Is(err, ErrInvalidSource) {
+		t.Errorf("Expected ErrInvalidSource without a name, got %v", err)
+	}
+}
+
+func TestGitlabMinutes(t *testing.T) {
+	tests := map[string]float64{"1h 30m": 90, "45 minutes": 45, "2 hours": 120, "90": 1.5, "1d": 1440}
+	for duration, want := range tests {
+		if got, ok := gitlabMinutes(duration); !ok || got != want {
+			t.Errorf("gitlabMinutes(%q) = %v, %v; want %v", duration, got, ok, want)
+		}
+	}
+	if _, ok := gitlabMinutes("soon"); ok {
+		t.Error("Expected an unknown unit to be rejected")
+	}
+}
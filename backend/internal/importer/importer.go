@@ -0,0 +1,237 @@
+// Package importer translates workflow files of other CI systems into
+// Gantry workflows, reporting the constructs it couldn't carry over
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Import formats
+const (
+	FormatGitHub = "github"
+	FormatGitLab = "gitlab"
+)
+
+// ErrUnsupportedFormat is returned for an import format that doesn't exist
+var ErrUnsupportedFormat = errors.New("unsupported import format")
+
+// ErrInvalidSource is returned when the file to import can't be translated
+var ErrInvalidSource = errors.New("invalid source workflow")
+
+// Result is a workflow translated from another CI system
+type Result struct {
+	// Workflow is the translated workflow, as Gantry YAML
+	Workflow []byte
+
+	// Ignored describes each construct that was dropped or changed because
+	// Gantry has no equivalent, prefixed with its path in the source file
+	Ignored []string
+}
+
+// Options adjust a translation
+type Options struct {
+	// Name names the translated workflow, in place of the name in the source
+	// file. Formats whose files have no name require it.
+	Name string
+}
+
+// Import translates data from format into a Gantry workflow
+func Import(format string, data []byte, opts Options) (*Result, error) {
+	switch format {
+	case FormatGitHub:
+		return GitHub(data, opts)
+	case FormatGitLab:
+		return GitLab(data, opts)
+	default:
+		return nil, fmt.Errorf("%w: '%s'", ErrUnsupportedFormat, format)
+	}
+}
+
+// translator builds a Gantry workflow node by node, collecting notes on
+// what it couldn't translate. Expressions in copied values have references
+// to the source system's contexts rewritten: contexts maps them to Gantry
+// references, and references into foreign contexts that aren't mapped are
+// noted.
+type translator struct {
+	contexts map[string]string
+	foreign  map[string]bool
+	ignored  []string
+}
+
+func (t *translator) ignore(path, format string, args ...interface{}) {
+	t.ignored = append(t.ignored, path+": "+fmt.Sprintf(format, args...))
+}
+
+// result encodes the translated workflow
+func (t *translator) result(wf *yaml.Node) (*Result, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(wf); err != nil {
+		return nil, fmt.Errorf("failed to encode workflow: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode workflow: %w", err)
+	}
+
+	ignored := t.ignored
+	if ignored == nil {
+		ignored = []string{}
+	}
+	return &Result{Workflow: buf.Bytes(), Ignored: ignored}, nil
+}
+
+// decode parses a source file whose top level must be a mapping
+func decode(data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSource, err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%w: expected a mapping at the top level", ErrInvalidSource)
+	}
+	return doc.Content[0], nil
+}
+
+// resolve follows aliases to the node they refer to
+func resolve(n *yaml.Node) *yaml.Node {
+	for n != nil && n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	return n
+}
+
+// pairs calls fn with every key and resolved value of a mapping node
+func pairs(n *yaml.Node, fn func(key string, value *yaml.Node)) {
+	n = resolve(n)
+	if n == nil || n.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		fn(n.Content[i].Value, resolve(n.Content[i+1]))
+	}
+}
+
+// value returns the resolved value of key in a mapping node, if present
+func value(n *yaml.Node, key string) *yaml.Node {
+	var found *yaml.Node
+	pairs(n, func(k string, v *yaml.Node) {
+		if k == key && found == nil {
+			found = v
+		}
+	})
+	return found
+}
+
+// scalars returns the values of a scalar or a list of scalars
+func scalars(n *yaml.Node) []string {
+	n = resolve(n)
+	if n == nil {
+		return nil
+	}
+	if n.Kind == yaml.ScalarNode {
+		return []string{n.Value}
+	}
+	var values []string
+	for _, item := range n.Content {
+		if item = resolve(item); item.Kind == yaml.ScalarNode {
+			values = append(values, item.Value)
+		}
+	}
+	return values
+}
+
+func newMapping() *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+}
+
+func newScalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+func newList(values []string) *yaml.Node {
+	list := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle}
+	for _, v := range values {
+		list.Content = append(list.Content, newScalar(v))
+	}
+	return list
+}
+
+func setKey(m *yaml.Node, key string, value *yaml.Node) {
+	m.Content = append(m.Content, newScalar(key), value)
+}
+
+// clone copies a node tree, resolving aliases and dropping anchors
+func clone(n *yaml.Node) *yaml.Node {
+	n = resolve(n)
+	c := *n
+	c.Anchor = ""
+	c.Content = make([]*yaml.Node, len(n.Content))
+	for i, child := range n.Content {
+		c.Content[i] = clone(child)
+	}
+	return &c
+}
+
+// expressionPattern matches the ${{ }} expressions of a template
+var expressionPattern = regexp.MustCompile(`\$\{\{.*?\}\}`)
+
+// contextPattern matches a dotted context reference such as github.actor
+var contextPattern = regexp.MustCompile(`\b([A-Za-z_]+)((?:\.[A-Za-z_][A-Za-z0-9_-]*)+)`)
+
+// copy clones a source node found at path for the translated workflow,
+// rewriting the expressions of every string in it. Values of if keys are
+// expressions without ${{ }}.
+func (t *translator) copy(path string, n *yaml.Node) *yaml.Node {
+	c := clone(n)
+	t.rewriteExpressions(c, path)
+	return c
+}
+
+func (t *translator) rewriteExpressions(n *yaml.Node, path string) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			t.rewriteExpressions(n.Content[i+1], joinPath(path, n.Content[i].Value))
+		}
+	case yaml.SequenceNode:
+		for i, item := range n.Content {
+			t.rewriteExpressions(item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case yaml.ScalarNode:
+		if (path == "if" || strings.HasSuffix(path, ".if")) && !strings.Contains(n.Value, "${{") {
+			n.Value = t.rewriteExpression(n.Value, path)
+			return
+		}
+		n.Value = expressionPattern.ReplaceAllStringFunc(n.Value, func(e string) string {
+			return t.rewriteExpression(e, path)
+		})
+	}
+}
+
+// rewriteExpression rewrites the context references in a single expression
+func (t *translator) rewriteExpression(e, path string) string {
+	return contextPattern.ReplaceAllStringFunc(e, func(ref string) string {
+		if mapped, ok := t.contexts[ref]; ok {
+			return mapped
+		}
+		name, _, _ := strings.Cut(ref, ".")
+		if t.foreign[name] {
+			t.ignore(path, "%s has no Gantry equivalent", ref)
+		}
+		return ref
+	})
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,19 @@
+package models
+
+// ActionComposite is the only kind of action Gantry runs
+const ActionComposite = "composite"
+
+// Action is a local composite action, loaded from an action.yaml file
+// and inlined into the steps of the jobs that use it
+type Action struct {
+	Name        string           `yaml:"name"`
+	Description string           `yaml:"description"`
+	Inputs      map[string]Input `yaml:"inputs"`
+	Runs        ActionRuns       `yaml:"runs"`
+}
+
+// ActionRuns holds the steps of a composite action
+type ActionRuns struct {
+	Using string `yaml:"using"`
+	Steps []Step `yaml:"steps"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Events outgoing webhooks are sent
+const (
+	HookRunStarted   = "run_started"
+	HookRunSucceeded = "run_succeeded"
+	HookRunFailed    = "run_failed"
+	HookRunCancelled = "run_cancelled"
+)
+
+// HookEvents are every event an outgoing webhook may be sent, which it is
+// sent when it lists none
+var HookEvents = []string{HookRunStarted, HookRunSucceeded, HookRunFailed, HookRunCancelled}
+
+// Hook is an outgoing webhook: a URL the runs of one workflow, or of every
+// workflow, are posted to as they start and finish
+type Hook struct {
+	ID  string `json:"id" bson:"id"`
+	URL string `json:"url" bson:"url"`
+
+	// WorkflowID is the workflow whose runs are sent; every workflow's when
+	// empty
+	WorkflowID string   `json:"workflow_id,omitempty" bson:"workflow_id,omitempty"`
+	Events     []string `json:"events" bson:"events"`
+
+	// Secret signs deliveries. It is only shown when the hook is created.
+	Secret string `json:"secret,omitempty" bson:"secret"`
+
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	CreatedBy string    `json:"created_by,omitempty" bson:"created_by,omitempty"`
+}
+
+// HookFailure records a delivery to an outgoing webhook that failed every
+// attempt, with what would have been posted
+type HookFailure struct {
+	HookID   string    `json:"hook_id" bson:"hook_id"`
+	URL      string    `json:"url" bson:"url"`
+	Event    string    `json:"event" bson:"event"`
+	RunID    string    `json:"run_id" bson:"run_id"`
+	Payload  string    `json:"payload" bson:"payload"`
+	Attempts int       `json:"attempts" bson:"attempts"`
+	Error    string    `json:"error" bson:"error"` // why the last attempt failed
+	FailedAt time.Time `json:"failed_at" bson:"failed_at"`
+}
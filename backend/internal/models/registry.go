@@ -0,0 +1,154 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gantry/internal/glob"
+
+	"github.com/distribution/reference"
+)
+
+// DockerHubRegistry is the registry host of images without one
+const DockerHubRegistry = "docker.io"
+
+// RegistryCredentials log in to a container registry to pull private images
+type RegistryCredentials struct {
+	Username string `yaml:"username" json:"username"`
+	Password string `yaml:"password" json:"password,omitempty"`
+}
+
+// dockerConfig is the layout of a Docker config.json, as written by
+// `docker login`
+type dockerConfig struct {
+	Auths map[string]dockerAuth `json:"auths"`
+}
+
+// dockerAuth holds one registry's credentials in a Docker config.json;
+// Auth is the base64 of username:password
+type dockerAuth struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Auth     string `json:"auth,omitempty"`
+}
+
+// ParseDockerConfig reads the registry credentials of a Docker config.json,
+// keyed by registry host
+func ParseDockerConfig(data []byte) (map[string]RegistryCredentials, error) {
+	var config dockerConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid Docker config: %w", err)
+	}
+
+	auths := make(map[string]RegistryCredentials, len(config.Auths))
+	for key, a := range config.Auths {
+		creds := RegistryCredentials{Username: a.Username, Password: a.Password}
+		if a.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(a.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("registry '%s' has an invalid auth: %w", key, err)
+			}
+			var ok bool
+			if creds.Username, creds.Password, ok = strings.Cut(string(decoded), ":"); !ok {
+				return nil, fmt.Errorf("registry '%s' has an invalid auth (expected base64 of username:password)", key)
+			}
+		}
+		if creds.Username == "" {
+			// Entries managed by a credential helper have no credentials here
+			continue
+		}
+		auths[RegistryHost(key)] = creds
+	}
+	return auths, nil
+}
+
+// DockerConfig encodes credentials keyed by registry host as a Docker
+// config.json
+func DockerConfig(auths map[string]RegistryCredentials) ([]byte, error) {
+	config := dockerConfig{Auths: make(map[string]dockerAuth, len(auths))}
+	for host, creds := range auths {
+		if host == DockerHubRegistry {
+			host = "https://index.docker.io/v1/"
+		}
+		config.Auths[host] = dockerAuth{
+			Username: creds.Username,
+			Password: creds.Password,
+			Auth:     base64.StdEncoding.EncodeToString([]byte(creds.Username + ":" + creds.Password)),
+		}
+	}
+	return json.Marshal(config)
+}
+
+// RegistryHost normalizes a Docker config.json key, such as
+// https://index.docker.io/v1/ or https://ghcr.io, to the host images
+// name their registry by
+func RegistryHost(key string) string {
+	host := key
+	if _, rest, ok := strings.Cut(host, "://"); ok {
+		host = rest
+	}
+	host, _, _ = strings.Cut(host, "/")
+	switch host {
+	case "index.docker.io", "registry-1.docker.io":
+		return DockerHubRegistry
+	}
+	return host
+}
+
+// ImagePolicy restricts the images jobs and services may run in. Patterns
+// use `*` and `**` as branch filters do, and match an image by its full
+// reference or its repository, as written or fully qualified: ubuntu:22.04,
+// ubuntu, docker.io/library/ubuntu:22.04 and docker.io/library/ubuntu all
+// match the image ubuntu:22.04.
+type ImagePolicy struct {
+	Allowed []string // when set, images must match one of these
+	Denied  []string // images matching one of these are refused, even if allowed
+}
+
+// ParseImagePatterns splits a comma-separated list of image patterns
+func ParseImagePatterns(list string) []string {
+	var patterns []string
+	for _, pattern := range strings.Split(list, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// Check returns an error when image may not be used
+func (p ImagePolicy) Check(image string) error {
+	if len(p.Allowed) == 0 && len(p.Denied) == 0 {
+		return nil
+	}
+
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return fmt.Errorf("invalid image '%s': %w", image, err)
+	}
+	tagged := reference.TagNameOnly(named)
+	forms := []string{
+		reference.FamiliarString(tagged), reference.FamiliarName(named),
+		tagged.String(), named.Name(),
+	}
+	matches := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			for _, form := range forms {
+				if glob.Match(pattern, form) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	if matches(p.Denied) {
+		return fmt.Errorf("image '%s' is denied by the server's image policy", image)
+	}
+	if len(p.Allowed) > 0 && !matches(p.Allowed) {
+		return fmt.Errorf("image '%s' is not allowed by the server's image policy", image)
+	}
+	return nil
+}
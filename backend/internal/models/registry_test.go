@@ -0,0 +1,82 @@
+package models
+
+import "testing"
+
+func TestParseDockerConfig(t *testing.T) {
+	data := `{
+  "auths": {
+    "https://index.docker.io/v1/": {"auth": "YWNtZTpodWItdG9rZW4="},
+    "ghcr.io": {"username": "acme", "password": "ghcr-token"},
+    "https://registry.example.com/v2/": {"auth": "Y2k6czNjcmV0"},
+    "quay.io": {}
+  },
+  "credsStore": "desktop"
+}`
+	auths, err := ParseDockerConfig([]byte(data))
+	if err != nil {
+		t.Fatalf("Failed to parse Docker config: %v", err)
+	}
+
+	want := map[string]RegistryCredentials{
+		DockerHubRegistry:      {Username: "acme", Password: "hub-token"},
+		"ghcr.io":              {Username: "acme", Password: "ghcr-token"},
+		"registry.example.com": {Username: "ci", Password: "s3cret"},
+	}
+	if len(auths) != len(want) {
+		t.Fatalf("Expected credentials for %d registries, got %v", len(want), auths)
+	}
+	for host, creds := range want {
+		if auths[host] != creds {
+			t.Errorf("Expected %s credentials %+v, got %+v", host, creds, auths[host])
+		}
+	}
+
+	for _, invalid := range []string{`{"auths": [`, `{"auths": {"ghcr.io": {"auth": "!!"}}}`, `{"auths": {"ghcr.io": {"auth": "bm9jb2xvbg=="}}}`} {
+		if _, err := ParseDockerConfig([]byte(invalid)); err == nil {
+			t.Errorf("Expected error for %s, got nil", invalid)
+		}
+	}
+}
+
+func TestDockerConfig_RoundTrip(t *testing.T) {
+	auths := map[string]RegistryCredentials{
+		DockerHubRegistry: {Username: "acme", Password: "hub-token"},
+		"localhost:5000":  {Username: "ci", Password: "s3cret"},
+	}
+	data, err := DockerConfig(auths)
+	if err != nil {
+		t.Fatalf("Failed to encode Docker config: %v", err)
+	}
+	parsed, err := ParseDockerConfig(data)
+	if err != nil {
+		t.Fatalf("Failed to parse encoded Docker config: %v", err)
+	}
+	if len(parsed) != 2 || parsed[DockerHubRegistry] != auths[DockerHubRegistry] || parsed["localhost:5000"] != auths["localhost:5000"] {
+		t.Errorf("Expected %v back, got %v", auths, parsed)
+	}
+}
+
+func TestImagePolicy_Check(t *testing.T) {
+	policy := ImagePolicy{
+		Allowed: ParseImagePatterns("ubuntu, golang:1.*, ghcr.io/acme/**,"),
+		Denied:  []string{"ghcr.io/acme/legacy/**"},
+	}
+
+	for _, image := range []string{"ubuntu", "ubuntu:22.04", "docker.io/library/ubuntu:24.04", "golang:1.22", "ghcr.io/acme/tools/lint:v2"} {
+		if err := policy.Check(image); err != nil {
+			t.Errorf("Expected %s to be allowed, got %v", image, err)
+		}
+	}
+	for _, image := range []string{"alpine", "golang:latest", "ghcr.io/other/app", "ghcr.io/acme/legacy/app:1"} {
+		if err := policy.Check(image); err == nil {
+			t.Errorf("Expected %s to be refused, got nil", image)
+		}
+	}
+
+	if err := (ImagePolicy{}).Check("anything:latest"); err != nil {
+		t.Errorf("Expected an empty policy to allow every image, got %v", err)
+	}
+	if err := (ImagePolicy{Denied: []string{"docker.io/**"}}).Check("redis:7"); err == nil {
+		t.Error("Expected a registry pattern to deny Docker Hub images, got nil")
+	}
+}
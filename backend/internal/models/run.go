@@ -2,20 +2,75 @@
 package models
 
 import (
+	"sort"
 	"sync"
 	"time"
 )
 
 // WorkflowRun tracks execution of a workflow
 type WorkflowRun struct {
-	ID           string         `json:"id" bson:"id"`
-	WorkflowName string         `json:"workflow_name" bson:"workflow_name"`
-	Status       string         `json:"status" bson:"status"` // pending, running, success, failed
-	Jobs         map[string]Job `json:"jobs" bson:"jobs"`
-	JobOrder     []string       `json:"job_order" bson:"job_order"` // Preserve execution order
-	StartedAt    time.Time      `json:"started_at" bson:"started_at"`
-	CompletedAt  *time.Time     `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
-	mu           sync.RWMutex   `bson:"-"`
+	ID           string                 `json:"id" bson:"id"`
+	WorkflowID   string                 `json:"workflow_id" bson:"workflow_id"`
+	WorkflowName string                 `json:"workflow_name" bson:"workflow_name"`
+	Status       string                 `json:"status" bson:"status"` // pending, running, success, failed
+	Jobs         map[string]Job         `json:"jobs" bson:"jobs"`
+	JobOrder     []string               `json:"job_order" bson:"job_order"` // Preserve execution order
+	Inputs       map[string]interface{} `json:"inputs,omitempty" bson:"inputs,omitempty"`
+	Variables    map[string]string      `json:"variables,omitempty" bson:"variables,omitempty"`
+	Env          map[string]string      `json:"env,omitempty" bson:"env,omitempty"` // overrides of the workflow's env
+	Labels       map[string]string      `json:"labels,omitempty" bson:"labels,omitempty"`
+	Trigger      *TriggerInfo           `json:"trigger,omitempty" bson:"trigger,omitempty"`
+	StartedAt    time.Time              `json:"started_at" bson:"started_at"`
+	CompletedAt  *time.Time             `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+	mu           sync.RWMutex           `bson:"-"`
+
+	// Workflow is the workflow as the run started, so the run can be re-run
+	// the same way after the workflow changes
+	Workflow *Workflow `json:"-" bson:"workflow,omitempty"`
+
+	// RerunOf is the ID of the run this one re-ran
+	RerunOf string `json:"rerun_of,omitempty" bson:"rerun_of,omitempty"`
+
+	// WorkflowVersion is the version of the workflow the run executed
+	WorkflowVersion int `json:"workflow_version,omitempty" bson:"workflow_version,omitempty"`
+}
+
+// Trigger event names
+const (
+	EventPush             = "push"
+	EventWorkflowDispatch = "workflow_dispatch"
+	EventIssueComment     = "issue_comment"
+	EventPullRequest      = "pull_request"
+	EventWebhook          = "webhook"
+)
+
+// TriggerInfo records the event that started a run
+type TriggerInfo struct {
+	Event       string `json:"event" bson:"event"`
+	Action      string `json:"action,omitempty" bson:"action,omitempty"`
+	Actor       string `json:"actor,omitempty" bson:"actor,omitempty"`
+	Branch      string `json:"branch,omitempty" bson:"branch,omitempty"`
+	Command     string `json:"command,omitempty" bson:"command,omitempty"`
+	PullRequest int    `json:"pull_request,omitempty" bson:"pull_request,omitempty"`
+
+	// Repository and SHA are the clone URL and commit the event concerns,
+	// the defaults of a job's checkout
+	Repository string `json:"repository,omitempty" bson:"repository,omitempty"`
+	SHA        string `json:"sha,omitempty" bson:"sha,omitempty"`
+
+	// Commit describes the commit SHA names, when the event's webhook did
+	Commit *CommitInfo `json:"commit,omitempty" bson:"commit,omitempty"`
+
+	// Payload is the JSON object a webhook delivered
+	Payload map[string]interface{} `json:"payload,omitempty" bson:"payload,omitempty"`
+}
+
+// CommitInfo describes a commit a run was triggered for
+type CommitInfo struct {
+	Message   string    `json:"message,omitempty" bson:"message,omitempty"`
+	Author    string    `json:"author,omitempty" bson:"author,omitempty"`
+	URL       string    `json:"url,omitempty" bson:"url,omitempty"`
+	Timestamp time.Time `json:"timestamp,omitempty" bson:"timestamp,omitempty"`
 }
 
 // UpdateJob safely updates a job in the run
@@ -33,6 +88,30 @@ func (r *WorkflowRun) GetJob(name string) (Job, bool) {
 	return job, exists
 }
 
+// JobNames returns the names of the run's jobs in the order they run, with
+// any job missing from JobOrder last, by name
+func (r *WorkflowRun) JobNames() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.Jobs))
+	seen := make(map[string]bool, len(r.Jobs))
+	for _, name := range r.JobOrder {
+		if _, ok := r.Jobs[name]; ok && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	var rest []string
+	for name := range r.Jobs {
+		if !seen[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(names, rest...)
+}
+
 // SetStatus safely sets the run status
 func (r *WorkflowRun) SetStatus(status string) {
 	r.mu.Lock()
@@ -40,6 +119,25 @@ func (r *WorkflowRun) SetStatus(status string) {
 	r.Status = status
 }
 
+// GetStatus safely retrieves the run status
+func (r *WorkflowRun) GetStatus() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.Status
+}
+
+// SwapStatus sets the run status to status if it is old, reporting whether
+// it did
+func (r *WorkflowRun) SwapStatus(old, status string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Status != old {
+		return false
+	}
+	r.Status = status
+	return true
+}
+
 // Complete marks the run as completed
 func (r *WorkflowRun) Complete() {
 	r.mu.Lock()
@@ -55,18 +153,130 @@ func (r *WorkflowRun) Clone() *WorkflowRun {
 
 	clone := &WorkflowRun{
 		ID:           r.ID,
+		WorkflowID:   r.WorkflowID,
 		WorkflowName: r.WorkflowName,
 		Status:       r.Status,
 		Jobs:         make(map[string]Job),
 		JobOrder:     make([]string, len(r.JobOrder)),
 		StartedAt:    r.StartedAt,
 		CompletedAt:  r.CompletedAt,
+		Workflow:     r.Workflow,
+		RerunOf:      r.RerunOf,
+
+		WorkflowVersion: r.WorkflowVersion,
 	}
 
 	for k, v := range r.Jobs {
 		clone.Jobs[k] = v
 	}
 	copy(clone.JobOrder, r.JobOrder)
+	if r.Trigger != nil {
+		trigger := *r.Trigger
+		clone.Trigger = &trigger
+	}
+	if r.Inputs != nil {
+		clone.Inputs = make(map[string]interface{}, len(r.Inputs))
+		for k, v := range r.Inputs {
+			clone.Inputs[k] = v
+		}
+	}
+	if r.Variables != nil {
+		clone.Variables = make(map[string]string, len(r.Variables))
+		for k, v := range r.Variables {
+			clone.Variables[k] = v
+		}
+	}
+	if r.Env != nil {
+		clone.Env = make(map[string]string, len(r.Env))
+		for k, v := range r.Env {
+			clone.Env[k] = v
+		}
+	}
+	if r.Labels != nil {
+		clone.Labels = make(map[string]string, len(r.Labels))
+		for k, v := range r.Labels {
+			clone.Labels[k] = v
+		}
+	}
 
 	return clone
 }
+
+// Summary returns a copy of the run without what its jobs printed, which
+// JobLog returns one job at a time, so listing and polling runs stays cheap
+func (r *WorkflowRun) Summary() *WorkflowRun {
+	summary := r.Clone()
+	for name, job := range summary.Jobs {
+		summary.Jobs[name] = job.Summary()
+	}
+	return summary
+}
+
+// Summary returns a copy of the job without the output of the job, its
+// steps and its services
+func (j Job) Summary() Job {
+	j.Output = ""
+	j.ServiceLogs = nil
+	j.ServiceStderr = nil
+	if j.Steps != nil {
+		steps := make([]Step, len(j.Steps))
+		for i, step := range j.Steps {
+			step.Output = ""
+			if step.Attempts != nil {
+				attempts := make([]StepAttempt, len(step.Attempts))
+				for k, attempt := range step.Attempts {
+					attempt.Output = ""
+					attempts[k] = attempt
+				}
+				step.Attempts = attempts
+			}
+			steps[i] = step
+		}
+		j.Steps = steps
+	}
+	return j
+}
+
+// JobLog is what a job printed, as its run's summary leaves it out
+type JobLog struct {
+	Job             string            `json:"job"`
+	Status          string            `json:"status"`
+	Output          string            `json:"output"`
+	OutputSize      int64             `json:"output_size,omitempty"`
+	OutputTruncated bool              `json:"output_truncated,omitempty"`
+	Steps           []StepLog         `json:"steps,omitempty"`
+	ServiceLogs     map[string]string `json:"service_logs,omitempty"`
+	ServiceStderr   map[string]string `json:"service_stderr,omitempty"`
+}
+
+// StepLog is what a step printed, and what each of its attempts printed
+// when it was retried
+type StepLog struct {
+	Name     string   `json:"name"`
+	Output   string   `json:"output,omitempty"`
+	Attempts []string `json:"attempts,omitempty"`
+}
+
+// Log returns what the job, named name in its run, printed
+func (j Job) Log(name string) JobLog {
+	jobLog := JobLog{
+		Job:             name,
+		Status:          j.Status,
+		Output:          j.Output,
+		OutputSize:      j.OutputSize,
+		OutputTruncated: j.OutputTruncated,
+		ServiceLogs:     j.ServiceLogs,
+		ServiceStderr:   j.ServiceStderr,
+	}
+	for _, step := range j.Steps {
+		if step.Output == "" && len(step.Attempts) == 0 {
+			continue
+		}
+		stepLog := StepLog{Name: step.Name, Output: step.Output}
+		for _, attempt := range step.Attempts {
+			stepLog.Attempts = append(stepLog.Attempts, attempt.Output)
+		}
+		jobLog.Steps = append(jobLog.Steps, stepLog)
+	}
+	return jobLog
+}
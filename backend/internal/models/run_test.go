@@ -57,6 +57,25 @@ func TestWorkflowRun_GetJob(t *testing.T) {
 	}
 }
 
+func TestWorkflowRun_JobNames(t *testing.T) {
+	run := &WorkflowRun{
+		ID: "run-1",
+		Jobs: map[string]Job{
+			"deploy": {Status: "pending"},
+			"build":  {Status: "success"},
+			"test":   {Status: "running"},
+			"lint":   {Status: "success"},
+		},
+		// Job order may name jobs the run doesn't have, or miss some
+		JobOrder: []string{"build", "gone", "test", "build"},
+	}
+
+	got := fmt.Sprint(run.JobNames())
+	if want := "[build test deploy lint]"; got != want {
+		t.Errorf("Expected job names %s, got %s", want, got)
+	}
+}
+
 func TestWorkflowRun_SetStatus(t *testing.T) {
 	run := &WorkflowRun{
 		ID:     "run-1",
@@ -71,6 +90,21 @@ func TestWorkflowRun_SetStatus(t *testing.T) {
 	}
 }
 
+func TestWorkflowRun_SwapStatus(t *testing.T) {
+	run := &WorkflowRun{
+		ID:     "run-1",
+		Status: "queued",
+		Jobs:   make(map[string]Job),
+	}
+
+	if !run.SwapStatus("queued", "running") || run.Status != "running" {
+		t.Errorf("Expected status to be swapped to 'running', got '%s'", run.Status)
+	}
+	if run.SwapStatus("queued", "success") || run.Status != "running" {
+		t.Errorf("Expected status not to be swapped from another status, got '%s'", run.Status)
+	}
+}
+
 func TestWorkflowRun_Complete(t *testing.T) {
 	run := &WorkflowRun{
 		ID:   "run-1",
@@ -105,6 +139,7 @@ func TestWorkflowRun_Clone(t *testing.T) {
 			"test": {RunsOn: "ubuntu", Status: "success"},
 		},
 		JobOrder:    []string{"test"},
+		Labels:      map[string]string{"team": "web"},
 		StartedAt:   startTime,
 		CompletedAt: &endTime,
 	}
@@ -126,11 +161,51 @@ func TestWorkflowRun_Clone(t *testing.T) {
 
 	// Verify clone is independent
 	clone.Status = "failed"
-	if original.Status == "failed" {
+	clone.Labels["team"] = "api"
+	if original.Status == "failed" || original.Labels["team"] != "web" {
 		t.Error("Modifying clone should not affect original")
 	}
 }
 
+func TestWorkflowRun_Summary(t *testing.T) {
+	original := &WorkflowRun{
+		ID:     "run-1",
+		Status: "failed",
+		Jobs: map[string]Job{
+			"test": {
+				Status:      "failed",
+				Output:      "FAIL\n",
+				OutputSize:  5,
+				ServiceLogs: map[string]string{"db": "ready"},
+				Steps: []Step{{
+					Name:     "Test",
+					Status:   "failed",
+					Output:   "FAIL\n",
+					Attempts: []StepAttempt{{Output: "flaky\n"}, {Output: "FAIL\n"}},
+				}},
+			},
+		},
+	}
+
+	summary := original.Summary()
+	job := summary.Jobs["test"]
+	if job.Output != "" || job.ServiceLogs != nil || job.Steps[0].Output != "" || job.Steps[0].Attempts[0].Output != "" {
+		t.Errorf("Expected the summary to leave out output, got %+v", job)
+	}
+	if job.Status != "failed" || job.OutputSize != 5 || job.Steps[0].Status != "failed" || len(job.Steps[0].Attempts) != 2 {
+		t.Errorf("Expected the summary to keep statuses and sizes, got %+v", job)
+	}
+
+	// The run keeps its output
+	log := original.Jobs["test"].Log("test")
+	if log.Output != "FAIL\n" || log.ServiceLogs["db"] != "ready" {
+		t.Errorf("Expected the original's output to be kept, got %+v", log)
+	}
+	if len(log.Steps) != 1 || log.Steps[0].Output != "FAIL\n" || len(log.Steps[0].Attempts) != 2 || log.Steps[0].Attempts[0] != "flaky\n" {
+		t.Errorf("Expected the steps' output, got %+v", log.Steps)
+	}
+}
+
 func TestWorkflowRun_ThreadSafety(t *testing.T) {
 	run := &WorkflowRun{
 		ID:       "run-1",
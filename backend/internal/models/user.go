@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// User is an account that signs in to the web UI and the API
+type User struct {
+	Username     string    `json:"username" bson:"username"`
+	PasswordHash string    `json:"-" bson:"password_hash"`
+	CreatedAt    time.Time `json:"created_at" bson:"created_at"`
+
+	// Role decides what the user may do: viewer, maintainer or admin
+	Role string `json:"role" bson:"role"`
+}
+
+// AuditEntry records a request a user made that needed a permission
+// beyond viewing, or that was refused
+type AuditEntry struct {
+	Time       time.Time `json:"time" bson:"time"`
+	User       string    `json:"user" bson:"user"`
+	Role       string    `json:"role,omitempty" bson:"role,omitempty"`
+	Permission string    `json:"permission" bson:"permission"`
+	Action     string    `json:"action" bson:"action"` // method and path, e.g. DELETE /api/workflows/build
+	Allowed    bool      `json:"allowed" bson:"allowed"`
+}
@@ -1,47 +1,1085 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+	"gopkg.in/yaml.v3"
+)
 
 // Workflow defines the CI/CD pipeline structure
 type Workflow struct {
-	Name     string         `yaml:"name" json:"name"`
-	On       TriggerConfig  `yaml:"on" json:"on"`
-	Jobs     map[string]Job `yaml:"jobs" json:"jobs"`
-	JobOrder []string       `json:"job_order"` // Preserve YAML order
+	Name     string            `yaml:"name" json:"name"`
+	On       TriggerConfig     `yaml:"on" json:"on"`
+	Env      map[string]string `yaml:"env" json:"env,omitempty"`
+	Defaults *Defaults         `yaml:"defaults" json:"defaults,omitempty"`
+	Jobs     map[string]Job    `yaml:"jobs" json:"jobs"`
+	JobOrder []string          `json:"job_order"` // Preserve YAML order
+
+	// Variables are non-secret settings available as vars.<name>, which a
+	// manual trigger may override
+	Variables map[string]string `yaml:"variables" json:"variables,omitempty"`
+
+	// Extends names a stored workflow and Include lists fragment files that
+	// were merged into this one when it was parsed
+	Extends string     `yaml:"extends" json:"extends,omitempty"`
+	Include StringList `yaml:"include" json:"include,omitempty"`
+
+	// Source is the YAML the workflow was parsed from, kept so that other
+	// workflows can extend it
+	Source string `yaml:"-" json:"-"`
+
+	// ID identifies the workflow across renames. Storage assigns it when the
+	// workflow is first saved and it never changes.
+	ID string `yaml:"-" json:"id"`
+
+	// Version counts the saves of the workflow, starting at 1, and UpdatedBy
+	// and UpdatedAt record the latest
+	Version   int       `yaml:"-" json:"version"`
+	UpdatedBy string    `yaml:"-" json:"updated_by,omitempty"`
+	UpdatedAt time.Time `yaml:"-" json:"updated_at"`
+}
+
+// WorkflowRevision is a saved version of a workflow, kept so that earlier
+// versions can be looked at after it changes
+type WorkflowRevision struct {
+	WorkflowID string    `json:"workflow_id" bson:"workflow_id"`
+	Version    int       `json:"version" bson:"version"`
+	Name       string    `json:"name" bson:"name"`
+	Source     string    `json:"source" bson:"source"`
+	UpdatedBy  string    `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// Defaults holds settings applied to every job and step that doesn't set its own
+type Defaults struct {
+	Run RunDefaults `yaml:"run" json:"run"`
+}
+
+// RunDefaults are the defaults for steps' run settings
+type RunDefaults struct {
+	Shell            string            `yaml:"shell" json:"shell,omitempty"`
+	WorkingDirectory string            `yaml:"working-directory" json:"working_directory,omitempty"`
+	Env              map[string]string `yaml:"env" json:"env,omitempty"`
 }
 
 // TriggerConfig defines when the workflow triggers
 type TriggerConfig struct {
-	Push PushConfig `yaml:"push"`
+	Push             *PushConfig        `yaml:"push" json:"push,omitempty"`
+	WorkflowDispatch *DispatchConfig    `yaml:"workflow_dispatch" json:"workflow_dispatch,omitempty"`
+	WorkflowCall     *CallConfig        `yaml:"workflow_call" json:"workflow_call,omitempty"`
+	IssueComment     *CommentConfig     `yaml:"issue_comment" json:"issue_comment,omitempty"`
+	PullRequest      *PullRequestConfig `yaml:"pull_request" json:"pull_request,omitempty"`
+	Webhook          *WebhookConfig     `yaml:"webhook" json:"webhook,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler. Triggers declared without
+// settings (`push:`) are enabled with their defaults rather than left unset.
+func (t *TriggerConfig) UnmarshalYAML(value *yaml.Node) error {
+	type plain TriggerConfig
+	if err := value.Decode((*plain)(t)); err != nil {
+		return err
+	}
+	if value.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		if value.Content[i+1].ShortTag() != "!!null" {
+			continue
+		}
+		switch value.Content[i].Value {
+		case "push":
+			t.Push = &PushConfig{}
+		case "workflow_dispatch":
+			t.WorkflowDispatch = &DispatchConfig{}
+		case "workflow_call":
+			t.WorkflowCall = &CallConfig{}
+		case "issue_comment":
+			t.IssueComment = &CommentConfig{}
+		case "pull_request":
+			t.PullRequest = &PullRequestConfig{}
+		case "webhook":
+			t.Webhook = &WebhookConfig{}
+		}
+	}
+	return nil
 }
 
 // PushConfig defines push trigger configuration
 type PushConfig struct {
-	Branches []string `yaml:"branches"`
+	// Branches filters the pushed branch with glob patterns (release/*);
+	// patterns starting with ! exclude branches. All when empty.
+	Branches []string `yaml:"branches" json:"branches,omitempty"`
+}
+
+// Pull request activity types
+const (
+	PullRequestOpened         = "opened"
+	PullRequestSynchronize    = "synchronize"
+	PullRequestReopened       = "reopened"
+	PullRequestClosed         = "closed"
+	PullRequestEdited         = "edited"
+	PullRequestReadyForReview = "ready_for_review"
+)
+
+// DefaultPullRequestTypes are matched when a pull_request trigger lists no types
+var DefaultPullRequestTypes = []string{PullRequestOpened, PullRequestSynchronize, PullRequestReopened}
+
+// PullRequestConfig defines pull request trigger configuration
+type PullRequestConfig struct {
+	Branches []string `yaml:"branches" json:"branches,omitempty"` // target branch patterns; all when empty
+	Types    []string `yaml:"types" json:"types,omitempty"`
+}
+
+// CommentConfig defines comment-command trigger configuration
+type CommentConfig struct {
+	// Commands lists the accepted commands, e.g. /retest. When empty, any
+	// comment starting with a slash command triggers the workflow.
+	Commands []string `yaml:"commands" json:"commands,omitempty"`
+}
+
+// WebhookConfig lets any system start the workflow by posting JSON to its
+// own webhook URL, signed with a shared secret
+type WebhookConfig struct {
+	// Secret names the secret whose value deliveries are signed with
+	Secret string `yaml:"secret" json:"secret,omitempty"`
+}
+
+// Input types accepted by workflow_dispatch
+const (
+	InputString  = "string"
+	InputBoolean = "boolean"
+	InputChoice  = "choice"
+)
+
+// DispatchConfig defines manual trigger configuration
+type DispatchConfig struct {
+	Inputs map[string]Input `yaml:"inputs" json:"inputs,omitempty"`
+}
+
+// CallConfig lets other workflows run this one as a job via `uses:`
+type CallConfig struct {
+	Inputs map[string]Input `yaml:"inputs" json:"inputs,omitempty"`
+}
+
+// Input declares a typed input supplied when a workflow is triggered manually
+type Input struct {
+	Description string   `yaml:"description" json:"description,omitempty"`
+	Type        string   `yaml:"type" json:"type,omitempty"` // string (default), boolean or choice
+	Required    bool     `yaml:"required" json:"required,omitempty"`
+	Default     string   `yaml:"default" json:"default,omitempty"`
+	Options     []string `yaml:"options" json:"options,omitempty"` // choice only
 }
 
 // Job represents a single job in the workflow
 type Job struct {
-	RunsOn    string     `yaml:"runs-on" json:"runs_on"`
-	Steps     []Step     `yaml:"steps" json:"steps"`
+	RunsOn    string             `yaml:"runs-on" json:"runs_on"`
+	Container *Container         `yaml:"container" json:"container,omitempty"`
+	Services  map[string]Service `yaml:"services" json:"services,omitempty"`
+	Needs     StringList         `yaml:"needs" json:"needs,omitempty"`
+	If        string             `yaml:"if" json:"if,omitempty"`
+	Strategy  *Strategy          `yaml:"strategy" json:"strategy,omitempty"`
+	Env       map[string]string  `yaml:"env" json:"env,omitempty"`
+	Steps     []Step             `yaml:"steps" json:"steps"`
+
+	// Environment names the deployment environment the job targets, whose
+	// protection rules are registered on the server
+	Environment string `yaml:"environment" json:"environment,omitempty"`
+
+	// Uses names a stored workflow to run in place of steps, with With as
+	// its inputs
+	Uses string            `yaml:"uses" json:"uses,omitempty"`
+	With map[string]string `yaml:"with" json:"with,omitempty"`
+
+	TimeoutMinutes   float64 `yaml:"timeout-minutes" json:"timeout_minutes,omitempty"`
+	ContinueOnError  bool    `yaml:"continue-on-error" json:"continue_on_error,omitempty"`
+	WorkingDirectory string  `yaml:"working-directory" json:"working_directory,omitempty"`
+
+	// Resources limits the CPU and memory of the job's container
+	Resources *Resources `yaml:"resources" json:"resources,omitempty"`
+
+	// GPUs gives the job's container NVIDIA GPUs: a number of them, or "all"
+	GPUs string `yaml:"gpus" json:"gpus,omitempty"`
+
+	// Volumes mounts host paths or named volumes into the job's container,
+	// as "source:target" or "source:target:ro"
+	Volumes []string `yaml:"volumes" json:"volumes,omitempty"`
+
+	// Tmpfs mounts in-memory filesystems into the job's container, as
+	// "path" or "path:size"
+	Tmpfs []string `yaml:"tmpfs" json:"tmpfs,omitempty"`
+
+	// Privileged runs the job's container with full access to the host,
+	// for nested containers and the like, if the server allows it
+	Privileged bool `yaml:"privileged" json:"privileged,omitempty"`
+
+	// Isolation is how the job is kept apart from the host: in a container,
+	// the default, or in a microVM of its own
+	Isolation string `yaml:"isolation" json:"isolation,omitempty"`
+
+	// User runs the job's container as a user other than its image's, as a
+	// name, uid or uid:gid, like a container's user
+	User string `yaml:"user" json:"user,omitempty"`
+
+	// Checkout clones a repository into the workspace before the steps run
+	Checkout *Checkout `yaml:"checkout" json:"checkout,omitempty"`
+
+	// PullPolicy decides when the job's and its services' images are
+	// pulled, overriding the server's default
+	PullPolicy string `yaml:"pull-policy" json:"pull_policy,omitempty"`
+
+	// Artifacts are copied out of the job's container once its steps have
+	// run, whether they passed or not, and saved to the run's artifacts
+	Artifacts []ArtifactStep `yaml:"artifacts" json:"artifacts,omitempty"`
+
+	// CacheVolumes mounts volumes kept between runs under a cache key, for
+	// dependency and build caches too large to archive every time
+	CacheVolumes []CacheVolume `yaml:"cache-volumes" json:"cache_volumes,omitempty"`
+
 	Status    string     `json:"status"`
 	Output    string     `json:"output"`
 	StartedAt time.Time  `json:"started_at,omitempty"`
 	EndedAt   *time.Time `json:"ended_at,omitempty"`
+
+	// OutputSize is how many bytes the job printed; when OutputTruncated is
+	// set, the middle of that was left out of Output to keep it in the
+	// server's limit
+	OutputSize      int64 `yaml:"-" json:"output_size,omitempty"`
+	OutputTruncated bool  `yaml:"-" json:"output_truncated,omitempty"`
+
+	// FailureReason explains a failure that isn't a step's exit code, e.g. timeout
+	FailureReason string `yaml:"-" json:"failure_reason,omitempty"`
+
+	// FailedStep names the step whose failure failed the job
+	FailedStep string `yaml:"-" json:"failed_step,omitempty"`
+
+	// ExitCode is the status the failed step's command exited with
+	ExitCode int `yaml:"-" json:"exit_code,omitempty"`
+
+	// Approval records the review of a job targeting a protected environment
+	Approval *Approval `yaml:"-" json:"approval,omitempty"`
+
+	// Outputs are the key=value pairs written by the job's steps, available
+	// to downstream jobs as needs.<job>.outputs
+	Outputs map[string]string `yaml:"-" json:"outputs,omitempty"`
+
+	// StepStates records the outcome and outputs of each step with an id,
+	// keyed by id, as later steps saw them through steps.<id>
+	StepStates map[string]StepState `yaml:"-" json:"step_states,omitempty"`
+
+	// Call is the job whose `uses:` this job was expanded from, and Inputs
+	// the inputs it was called with
+	Call   string                 `yaml:"-" json:"call,omitempty"`
+	Inputs map[string]interface{} `yaml:"-" json:"inputs,omitempty"`
+
+	// Variables are the called workflow's variables, for jobs expanded from a call
+	Variables map[string]string `yaml:"-" json:"variables,omitempty"`
+
+	// Matrix holds the values of a single expanded matrix leg
+	Matrix map[string]string `yaml:"-" json:"matrix,omitempty"`
+
+	// ServiceLogs holds the output of each service container, for debugging
+	ServiceLogs map[string]string `yaml:"-" json:"service_logs,omitempty"`
+	// ServiceStderr holds what each service wrote to stderr alone, where
+	// the executor can tell it apart
+	ServiceStderr map[string]string `yaml:"-" json:"service_stderr,omitempty"`
+}
+
+// Templates returns every field of the job and its steps that may contain
+// ${{ }} expressions, not including if conditions
+func (j Job) Templates() []string {
+	templates := []string{j.RunsOn, j.WorkingDirectory, j.Environment, j.User}
+	for _, v := range j.Env {
+		templates = append(templates, v)
+	}
+	if c := j.Container; c != nil {
+		templates = append(templates, c.Image, c.User)
+		templates = append(templates, c.Entrypoint...)
+		for _, v := range c.Env {
+			templates = append(templates, v)
+		}
+		if r := c.Credentials; r != nil {
+			templates = append(templates, r.Username, r.Password)
+		}
+	}
+	if c := j.Checkout; c != nil {
+		templates = append(templates, c.Repository, c.Ref, c.Path, c.Token, c.SSHKey)
+	}
+	for _, svc := range j.Services {
+		templates = append(templates, svc.Image)
+		templates = append(templates, svc.Command...)
+		for _, v := range svc.Env {
+			templates = append(templates, v)
+		}
+		if r := svc.Credentials; r != nil {
+			templates = append(templates, r.Username, r.Password)
+		}
+	}
+	for _, a := range j.Artifacts {
+		templates = append(templates, a.Name, a.Path)
+	}
+	for _, v := range j.CacheVolumes {
+		templates = append(templates, v.Key, v.Path)
+	}
+	for _, step := range j.Steps {
+		templates = append(templates, step.Templates()...)
+	}
+	return templates
+}
+
+// Conditions returns the if conditions of the job and its steps
+func (j Job) Conditions() []string {
+	conditions := []string{j.If}
+	for _, step := range j.Steps {
+		conditions = append(conditions, step.If)
+	}
+	return conditions
+}
+
+// Approval is a reviewer's decision on a job waiting for a protected
+// environment
+type Approval struct {
+	Reviewer   string    `json:"reviewer"`
+	Approved   bool      `json:"approved"`
+	ReviewedAt time.Time `json:"reviewed_at"`
+}
+
+// Strategy configures how a job is expanded into multiple instances
+type Strategy struct {
+	Matrix Matrix `yaml:"matrix" json:"matrix"`
+
+	// FailFast cancels the remaining legs once one fails (default true)
+	FailFast *bool `yaml:"fail-fast" json:"fail_fast,omitempty"`
+	// MaxParallel caps how many legs run at once; 0 means no limit
+	MaxParallel int `yaml:"max-parallel" json:"max_parallel,omitempty"`
+}
+
+// FailsFast reports whether a failing leg cancels its siblings
+func (s *Strategy) FailsFast() bool {
+	return s == nil || s.FailFast == nil || *s.FailFast
+}
+
+// Matrix defines the dimensions a job is expanded across
+type Matrix struct {
+	Order      []string            `json:"order" bson:"order"` // Preserve YAML key order
+	Dimensions map[string][]string `json:"dimensions" bson:"dimensions"`
+
+	// Include adds values to the combinations an entry matches, or adds the
+	// entry as a combination of its own when it matches none
+	Include []map[string]string `json:"include,omitempty" bson:"include,omitempty"`
+
+	// Exclude removes the combinations an entry matches
+	Exclude []map[string]string `json:"exclude,omitempty" bson:"exclude,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler. Values are kept as their raw
+// scalar text so that versions like 1.20 are not collapsed into floats.
+func (m *Matrix) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: matrix must be a mapping", value.Line)
+	}
+
+	m.Order = make([]string, 0, len(value.Content)/2)
+	m.Dimensions = make(map[string][]string, len(value.Content)/2)
+
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		key, values := value.Content[i].Value, value.Content[i+1]
+		if values.Kind != yaml.SequenceNode {
+			return fmt.Errorf("line %d: matrix '%s' must be a list of values", values.Line, key)
+		}
+
+		if key == "include" || key == "exclude" {
+			entries, err := matrixEntries(key, values)
+			if err != nil {
+				return err
+			}
+			if key == "include" {
+				m.Include = entries
+			} else {
+				m.Exclude = entries
+			}
+			continue
+		}
+
+		items := make([]string, 0, len(values.Content))
+		for _, item := range values.Content {
+			if item.Kind != yaml.ScalarNode {
+				return fmt.Errorf("line %d: matrix '%s' values must be scalars", item.Line, key)
+			}
+			items = append(items, item.Value)
+		}
+
+		m.Order = append(m.Order, key)
+		m.Dimensions[key] = items
+	}
+
+	return nil
+}
+
+// matrixEntries decodes the include or exclude list of a matrix
+func matrixEntries(key string, list *yaml.Node) ([]map[string]string, error) {
+	entries := make([]map[string]string, 0, len(list.Content))
+	for _, item := range list.Content {
+		if item.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("line %d: matrix %s entries must be mappings", item.Line, key)
+		}
+		entry := make(map[string]string, len(item.Content)/2)
+		for i := 0; i+1 < len(item.Content); i += 2 {
+			if item.Content[i+1].Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: matrix %s values must be scalars", item.Content[i+1].Line, key)
+			}
+			entry[item.Content[i].Value] = item.Content[i+1].Value
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Empty reports whether the matrix defines no combinations at all
+func (m *Matrix) Empty() bool {
+	return len(m.Order) == 0 && len(m.Include) == 0
+}
+
+// Combinations returns every combination of matrix values, varying the last
+// declared dimension fastest. Combinations matching an exclude entry are
+// dropped first. Each include entry is then added to every remaining
+// combination whose declared values it doesn't contradict, overriding only
+// values added by earlier entries; an entry that fits no combination is
+// appended as a combination of its own.
+func (m *Matrix) Combinations() []map[string]string {
+	var combos []map[string]string
+	if len(m.Order) > 0 {
+		combos = []map[string]string{{}}
+	}
+	for _, key := range m.Order {
+		var next []map[string]string
+		for _, combo := range combos {
+			for _, value := range m.Dimensions[key] {
+				expanded := make(map[string]string, len(combo)+1)
+				for k, v := range combo {
+					expanded[k] = v
+				}
+				expanded[key] = value
+				next = append(next, expanded)
+			}
+		}
+		combos = next
+	}
+
+	kept := combos[:0]
+	for _, combo := range combos {
+		excluded := false
+		for _, entry := range m.Exclude {
+			if matchesEntry(combo, entry) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			kept = append(kept, combo)
+		}
+	}
+	combos = kept
+
+	declared := len(combos)
+	for _, entry := range m.Include {
+		added := false
+		for _, combo := range combos[:declared] {
+			if !m.fits(combo, entry) {
+				continue
+			}
+			for k, v := range entry {
+				combo[k] = v
+			}
+			added = true
+		}
+		if !added {
+			combo := make(map[string]string, len(entry))
+			for k, v := range entry {
+				combo[k] = v
+			}
+			combos = append(combos, combo)
+		}
+	}
+
+	return combos
+}
+
+// fits reports whether an include entry agrees with a combination on every
+// declared dimension
+func (m *Matrix) fits(combo, entry map[string]string) bool {
+	for k, v := range entry {
+		if _, declared := m.Dimensions[k]; declared && combo[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesEntry reports whether a combination has every value of an entry
+func matchesEntry(combo, entry map[string]string) bool {
+	for k, v := range entry {
+		if combo[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Image pull policies, named as in Kubernetes
+const (
+	PullAlways       = "Always"       // pull before every job
+	PullIfNotPresent = "IfNotPresent" // pull only images missing from the host
+	PullNever        = "Never"        // only use images already on the host
+)
+
+// ValidatePullPolicy checks an image pull policy; empty selects the default
+func ValidatePullPolicy(policy string) error {
+	switch policy {
+	case "", PullAlways, PullIfNotPresent, PullNever:
+		return nil
+	}
+	return fmt.Errorf("invalid pull policy '%s' (expected %s, %s or %s)", policy, PullAlways, PullIfNotPresent, PullNever)
+}
+
+// IsRootUser reports whether a container user, as a name, uid or uid:gid,
+// is root
+func IsRootUser(user string) bool {
+	name, _, _ := strings.Cut(user, ":")
+	return name == "root" || name == "0"
+}
+
+// Container selects the image a job's steps run in, overriding `runs-on`
+type Container struct {
+	Image      string            `yaml:"image" json:"image"`
+	Entrypoint StringList        `yaml:"entrypoint" json:"entrypoint,omitempty"`
+	User       string            `yaml:"user" json:"user,omitempty"`
+	Env        map[string]string `yaml:"env" json:"env,omitempty"`
+
+	// Credentials log in to the image's registry, in place of the server's
+	Credentials *RegistryCredentials `yaml:"credentials" json:"credentials,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler, accepting the `container: node:20`
+// shorthand for an image with no other options
+func (c *Container) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*c = Container{Image: value.Value}
+		return nil
+	}
+
+	type plain Container
+	return value.Decode((*plain)(c))
+}
+
+// Checkout is the repository a job clones into its workspace. Without a
+// repository or ref, those of the event that triggered the run are used.
+type Checkout struct {
+	Repository string `yaml:"repository" json:"repository,omitempty"` // clone URL
+	Ref        string `yaml:"ref" json:"ref,omitempty"`               // branch, tag, full ref or commit
+	Path       string `yaml:"path" json:"path,omitempty"`             // relative to the workspace
+	Depth      int    `yaml:"depth" json:"depth,omitempty"`           // commits of history to fetch; 0 fetches all
+
+	// Token and SSHKey authenticate HTTPS and SSH clones; both must refer
+	// to secrets, e.g. ${{ secrets.REPO_TOKEN }}
+	Token  string `yaml:"token" json:"token,omitempty"`
+	SSHKey string `yaml:"ssh-key" json:"ssh_key,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler, accepting `checkout: true` for
+// the triggering event's repository at its commit
+func (c *Checkout) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		var enabled bool
+		if err := value.Decode(&enabled); err != nil || !enabled {
+			return fmt.Errorf("checkout must be true or a mapping")
+		}
+		*c = Checkout{}
+		return nil
+	}
+
+	type plain Checkout
+	return value.Decode((*plain)(c))
+}
+
+// Resources limits the CPU, memory and disk a job's container may use
+type Resources struct {
+	CPU    string `yaml:"cpu" json:"cpu,omitempty"`       // cores, e.g. "2" or "0.5"
+	Memory string `yaml:"memory" json:"memory,omitempty"` // e.g. "512m" or "2g"
+
+	// Disk caps what the container may write outside its volumes, e.g. "10g"
+	Disk string `yaml:"disk" json:"disk,omitempty"`
+}
+
+// NanoCPUs returns the CPU limit in billionths of a core, or 0 when unset
+func (r *Resources) NanoCPUs() (int64, error) {
+	if r == nil || r.CPU == "" {
+		return 0, nil
+	}
+	cpus, err := strconv.ParseFloat(r.CPU, 64)
+	if err != nil || cpus <= 0 {
+		return 0, fmt.Errorf("invalid cpu '%s' (expected a positive number of cores)", r.CPU)
+	}
+	return int64(cpus * 1e9), nil
+}
+
+// MemoryBytes returns the memory limit in bytes, or 0 when unset. Units are
+// binary, so "1g" is 1024 MiB.
+func (r *Resources) MemoryBytes() (int64, error) {
+	if r == nil || r.Memory == "" {
+		return 0, nil
+	}
+	bytes, err := units.RAMInBytes(r.Memory)
+	if err != nil || bytes <= 0 {
+		return 0, fmt.Errorf("invalid memory '%s' (expected a size such as 512m or 2g)", r.Memory)
+	}
+	return bytes, nil
+}
+
+// DiskBytes returns the disk limit in bytes, or 0 when unset
+func (r *Resources) DiskBytes() (int64, error) {
+	if r == nil || r.Disk == "" {
+		return 0, nil
+	}
+	bytes, err := units.RAMInBytes(r.Disk)
+	if err != nil || bytes <= 0 {
+		return 0, fmt.Errorf("invalid disk '%s' (expected a size such as 10g)", r.Disk)
+	}
+	return bytes, nil
+}
+
+// Values of a job's isolation
+const (
+	IsolationContainer = "container"
+	IsolationVM        = "vm"
+)
+
+// VMIsolated reports whether the job runs in a microVM
+func (j Job) VMIsolated() bool {
+	return j.Isolation == IsolationVM
+}
+
+// AllGPUs is the GPU count ParseGPUs returns for "all"
+const AllGPUs = -1
+
+// ParseGPUs returns how many GPUs a job's gpus asks for: 0 when unset, or
+// AllGPUs for "all"
+func ParseGPUs(value string) (int, error) {
+	switch value {
+	case "":
+		return 0, nil
+	case "all":
+		return AllGPUs, nil
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil || count <= 0 {
+		return 0, fmt.Errorf("invalid gpus '%s' (expected a positive number or all)", value)
+	}
+	return count, nil
+}
+
+// volumeNamePattern matches the names of named volumes
+var volumeNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// Volume is a parsed entry of a job's volumes
+type Volume struct {
+	Source   string // an absolute host path, or the name of a named volume
+	Target   string // the absolute path in the container
+	ReadOnly bool
+}
+
+// ParseVolume parses a "source:target[:ro|rw]" volume. Sources starting with
+// a slash are host paths; anything else names a volume.
+func ParseVolume(spec string) (Volume, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Volume{}, fmt.Errorf("invalid volume '%s' (expected source:target or source:target:ro)", spec)
+	}
+
+	v := Volume{Source: parts[0], Target: parts[1]}
+	if len(parts) == 3 {
+		switch parts[2] {
+		case "ro":
+			v.ReadOnly = true
+		case "rw":
+		default:
+			return Volume{}, fmt.Errorf("invalid volume mode '%s' (expected ro or rw)", parts[2])
+		}
+	}
+
+	if v.HostPath() {
+		v.Source = path.Clean(v.Source)
+	} else if !volumeNamePattern.MatchString(v.Source) {
+		return Volume{}, fmt.Errorf("invalid volume source '%s' (expected an absolute host path or a volume name)", v.Source)
+	}
+	if !path.IsAbs(v.Target) {
+		return Volume{}, fmt.Errorf("volume target '%s' must be an absolute path", v.Target)
+	}
+	v.Target = path.Clean(v.Target)
+	if v.Target == "/" {
+		return Volume{}, fmt.Errorf("volume target must not be /")
+	}
+	return v, nil
+}
+
+// Tmpfs is a parsed entry of a job's tmpfs
+type Tmpfs struct {
+	Path string // the absolute path in the container
+	Size int64  // bytes; zero leaves the size to the container runtime
+}
+
+// ParseTmpfs parses a "path[:size]" tmpfs mount
+func ParseTmpfs(spec string) (Tmpfs, error) {
+	p, size, sized := strings.Cut(spec, ":")
+	if !path.IsAbs(p) {
+		return Tmpfs{}, fmt.Errorf("tmpfs path '%s' must be an absolute path", p)
+	}
+	t := Tmpfs{Path: path.Clean(p)}
+	if t.Path == "/" {
+		return Tmpfs{}, fmt.Errorf("tmpfs path must not be /")
+	}
+	if sized {
+		bytes, err := units.RAMInBytes(size)
+		if err != nil || bytes <= 0 {
+			return Tmpfs{}, fmt.Errorf("invalid tmpfs size '%s' (expected a size such as 512m)", size)
+		}
+		t.Size = bytes
+	}
+	return t, nil
+}
+
+// HostPath reports whether the volume mounts a host path rather than a
+// named volume
+func (v Volume) HostPath() bool {
+	return strings.HasPrefix(v.Source, "/")
+}
+
+// String formats the volume as Docker's bind syntax
+func (v Volume) String() string {
+	if v.ReadOnly {
+		return v.Source + ":" + v.Target + ":ro"
+	}
+	return v.Source + ":" + v.Target
+}
+
+// Service is a sidecar container started before a job's steps and reachable
+// from them by its name
+type Service struct {
+	Image       string            `yaml:"image" json:"image"`
+	Env         map[string]string `yaml:"env" json:"env,omitempty"`
+	Command     StringList        `yaml:"command" json:"command,omitempty"`
+	Healthcheck *Healthcheck      `yaml:"healthcheck" json:"healthcheck,omitempty"`
+
+	// Credentials log in to the image's registry, in place of the server's
+	Credentials *RegistryCredentials `yaml:"credentials" json:"credentials,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler, accepting the `redis: redis:7`
+// shorthand for an image with no other options
+func (s *Service) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*s = Service{Image: value.Value}
+		return nil
+	}
+
+	type plain Service
+	return value.Decode((*plain)(s))
+}
+
+// Healthcheck is a command run inside a service container, or a TCP port
+// probed on it; the job starts once it succeeds
+type Healthcheck struct {
+	Run      string `yaml:"run" json:"run,omitempty"`
+	Interval string `yaml:"interval" json:"interval,omitempty"` // e.g. "2s"
+	Retries  int    `yaml:"retries" json:"retries,omitempty"`
+
+	// Port is probed instead of running a command
+	Port int `yaml:"port" json:"port,omitempty"`
+	// Timeout bounds the wait for the service to become ready, e.g. "2m"
+	Timeout string `yaml:"timeout" json:"timeout,omitempty"`
+}
+
+// UsesNeeds reports whether any job declares explicit dependencies.
+// Workflows without `needs` keep the legacy sequential YAML-order semantics.
+func (wf *Workflow) UsesNeeds() bool {
+	for _, job := range wf.Jobs {
+		if len(job.Needs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// StringList is a list of strings that also accepts a single YAML scalar,
+// so both `needs: build` and `needs: [build, lint]` are valid
+type StringList []string
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (l *StringList) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		*l = StringList{value.Value}
+		return nil
+	case yaml.SequenceNode:
+		var items []string
+		if err := value.Decode(&items); err != nil {
+			return err
+		}
+		*l = items
+		return nil
+	default:
+		return fmt.Errorf("line %d: expected a string or a list of strings", value.Line)
+	}
 }
 
 // Step represents a single step in a job
 type Step struct {
-	Name      string     `yaml:"name" json:"name"`
-	Run       string     `yaml:"run" json:"run"`
-	Status    string     `json:"status,omitempty"`
-	StartedAt time.Time  `json:"started_at,omitempty"`
-	EndedAt   *time.Time `json:"ended_at,omitempty"`
-	Output    string     `json:"output,omitempty"`
+	ID        string            `yaml:"id" json:"id,omitempty"`
+	Name      string            `yaml:"name" json:"name"`
+	If        string            `yaml:"if" json:"if,omitempty"`
+	Run       string            `yaml:"run" json:"run"`
+	Uses      string            `yaml:"uses" json:"uses,omitempty"`
+	With      map[string]string `yaml:"with" json:"with,omitempty"`
+	Shell     string            `yaml:"shell" json:"shell,omitempty"`
+	Language  string            `yaml:"language" json:"language,omitempty"`
+	Env       map[string]string `yaml:"env" json:"env,omitempty"`
+	Status    string            `json:"status,omitempty"`
+	StartedAt time.Time         `json:"started_at,omitempty"`
+	EndedAt   *time.Time        `json:"ended_at,omitempty"`
+	Output    string            `json:"output,omitempty"`
+
+	TimeoutMinutes   float64      `yaml:"timeout-minutes" json:"timeout_minutes,omitempty"`
+	ContinueOnError  bool         `yaml:"continue-on-error" json:"continue_on_error,omitempty"`
+	Retry            *RetryPolicy `yaml:"retry" json:"retry,omitempty"`
+	WorkingDirectory string       `yaml:"working-directory" json:"working_directory,omitempty"`
+	FailureReason    string       `yaml:"-" json:"failure_reason,omitempty"`
+
+	// UploadArtifact and DownloadArtifact replace run for steps that move
+	// files between a job and the run's artifact store
+	UploadArtifact   *ArtifactStep `yaml:"upload-artifact" json:"upload_artifact,omitempty"`
+	DownloadArtifact *ArtifactStep `yaml:"download-artifact" json:"download_artifact,omitempty"`
+	// Cache replaces run for steps that restore a directory from the server
+	// cache, saving it back once the job succeeds
+	Cache *CacheStep `yaml:"cache" json:"cache,omitempty"`
+	// Build replaces run for steps that build a Docker image from a
+	// directory of the job, optionally pushing it to a registry
+	Build *BuildStep `yaml:"build" json:"build,omitempty"`
+
+	// Inputs are the inputs of the composite action a step was inlined
+	// from, as templates evaluated when the step runs
+	Inputs map[string]string `yaml:"-" json:"inputs,omitempty"`
+
+	// Attempts records every attempt of a step with a retry policy
+	Attempts []StepAttempt `yaml:"-" json:"attempts,omitempty"`
+
+	// ExitCode is the status a failed step's command exited with
+	ExitCode int `yaml:"-" json:"exit_code,omitempty"`
+
+	// When is decided by the server from the step's if condition and tells
+	// the executor whether the step runs after an earlier step has failed
+	When string `yaml:"-" json:"-"`
+}
+
+// StepState is what later steps see of a step through steps.<id>. Outcome
+// is success, failed or skipped; Conclusion is the same except that a failed
+// step with continue-on-error concludes as success.
+type StepState struct {
+	Outcome    string            `json:"outcome"`
+	Conclusion string            `json:"conclusion"`
+	Outputs    map[string]string `json:"outputs,omitempty"`
+}
+
+// When values of a resolved step
+const (
+	StepWhenSuccess = ""        // runs while every earlier step has succeeded
+	StepWhenFailure = "failure" // runs only once an earlier step has failed
+	StepWhenAlways  = "always"  // runs either way
+	StepWhenNever   = "never"   // ruled out by its condition
+)
+
+// RunsAfter reports whether a step runs given whether an earlier step failed
+func (s Step) RunsAfter(failed bool) bool {
+	switch s.When {
+	case StepWhenAlways:
+		return true
+	case StepWhenFailure:
+		return failed
+	case StepWhenNever:
+		return false
+	default:
+		return !failed
+	}
+}
+
+// IsBuiltin reports whether a step uses one of Gantry's built-in step types
+// (artifacts, cache or build) instead of running a script
+func (s Step) IsBuiltin() bool {
+	return s.UploadArtifact != nil || s.DownloadArtifact != nil || s.Cache != nil || s.Build != nil
+}
+
+// Templates returns every field of the step that may contain ${{ }}
+// expressions, not including its if condition
+func (s Step) Templates() []string {
+	templates := []string{s.Name, s.Run, s.WorkingDirectory}
+	for _, v := range s.Env {
+		templates = append(templates, v)
+	}
+	for _, v := range s.Inputs {
+		templates = append(templates, v)
+	}
+	for _, a := range []*ArtifactStep{s.UploadArtifact, s.DownloadArtifact} {
+		if a != nil {
+			templates = append(templates, a.Name, a.Path)
+		}
+	}
+	if c := s.Cache; c != nil {
+		templates = append(templates, c.Path, c.Key)
+		templates = append(templates, c.RestoreKeys...)
+	}
+	if b := s.Build; b != nil {
+		templates = append(templates, b.Context, b.Dockerfile, b.Target, b.Username, b.Password)
+		templates = append(templates, b.Tags...)
+		for _, v := range b.BuildArgs {
+			templates = append(templates, v)
+		}
+	}
+	return templates
+}
+
+// ArtifactStep names an artifact and the file or directory it is uploaded
+// from, or the directory it is downloaded into. Relative paths are resolved
+// against the step's working directory.
+type ArtifactStep struct {
+	Name string `yaml:"name" json:"name"`
+	Path string `yaml:"path" json:"path,omitempty"`
 }
 
+// CacheVolume mounts the volume kept for Key at Path, creating it empty the
+// first time the key is used
+type CacheVolume struct {
+	Key  string `yaml:"key" json:"key"`
+	Path string `yaml:"path" json:"path"`
+}
+
+// CacheStep restores Path from the entry saved under Key or, failing that,
+// the newest entry whose key starts with one of RestoreKeys
+type CacheStep struct {
+	Path        string     `yaml:"path" json:"path"`
+	Key         string     `yaml:"key" json:"key"`
+	RestoreKeys StringList `yaml:"restore-keys" json:"restore_keys,omitempty"`
+}
+
+// BuildStep builds an image tagged with Tags from Context, a directory
+// resolved against the step's working directory, and pushes every tag when
+// Push is set. Username and Password log in to the registry of the tags and
+// must come from secrets.
+type BuildStep struct {
+	Context    string            `yaml:"context" json:"context,omitempty"`       // default the working directory
+	Dockerfile string            `yaml:"dockerfile" json:"dockerfile,omitempty"` // relative to Context (default Dockerfile)
+	Tags       StringList        `yaml:"tags" json:"tags"`
+	BuildArgs  map[string]string `yaml:"build-args" json:"build_args,omitempty"`
+	Target     string            `yaml:"target" json:"target,omitempty"`
+	Push       bool              `yaml:"push" json:"push,omitempty"`
+	Username   string            `yaml:"username" json:"username,omitempty"`
+	Password   string            `yaml:"password" json:"password,omitempty"`
+}
+
+// Shells a step's run script can be executed with
+const (
+	ShellSh     = "sh"
+	ShellBash   = "bash"
+	ShellPython = "python"
+	ShellNode   = "node"
+)
+
+// Languages a step's run script can be written in; the script is saved to a
+// file and run by the language's interpreter
+const (
+	LanguagePython = "python"
+	LanguageNode   = "node"
+	LanguageDeno   = "deno"
+)
+
+// RetryPolicy re-runs a failing step up to Attempts times in total
+type RetryPolicy struct {
+	Attempts int    `yaml:"attempts" json:"attempts"`
+	Delay    string `yaml:"delay" json:"delay,omitempty"` // e.g. "10s"
+}
+
+// MaxAttempts returns how many times a step may run; a nil policy runs once
+func (r *RetryPolicy) MaxAttempts() int {
+	if r == nil || r.Attempts < 1 {
+		return 1
+	}
+	return r.Attempts
+}
+
+// DelayDuration returns the pause between attempts
+func (r *RetryPolicy) DelayDuration() time.Duration {
+	if r == nil || r.Delay == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(r.Delay)
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+// StepAttempt is the outcome of a single attempt of a step
+type StepAttempt struct {
+	Success   bool      `json:"success"`
+	StartedAt time.Time `json:"started_at"`
+	EndedAt   time.Time `json:"ended_at"`
+	Output    string    `json:"output"`
+	ExitCode  int       `json:"exit_code,omitempty"`
+}
+
+// Failure reasons of jobs and steps
+const (
+	FailureTimeout   = "timeout"   // ran out of time
+	FailureCancelled = "cancelled" // stopped by fail-fast or a cancelled run
+	FailureRejected  = "rejected"  // its environment deployment was rejected
+
+	// FailureOutOfMemory marks a step killed for exceeding its job's memory limit
+	FailureOutOfMemory = "out_of_memory"
+)
+
 // JobResult contains the result of job execution
 type JobResult struct {
-	Output string
-	Error  error
+	Output      string
+	ServiceLogs map[string]string
+	Steps       []StepResult // one per executed step, in order, up to the first failure
+
+	// ServiceStderr is the part of ServiceLogs each service wrote to stderr
+	ServiceStderr map[string]string
+
+	// OutputSize is how many bytes the job printed, which is more than
+	// Output holds when its middle was left out
+	OutputSize int64
+}
+
+// StepResult contains the result of a single executed step
+type StepResult struct {
+	Success   bool
+	TimedOut  bool
+	Skipped   bool // the step's condition ruled it out once the job was running
+	StartedAt time.Time
+	EndedAt   time.Time
+	Attempts  []StepAttempt // only set for steps with a retry policy
+	Outputs   map[string]string
+	Output    string // what the step printed, between its start and end markers
+
+	// OutOfMemory is set when the step was killed for exceeding the job's
+	// memory limit
+	OutOfMemory bool
+
+	// ExitCode is the non-zero status the step's command exited with
+	ExitCode int
 }
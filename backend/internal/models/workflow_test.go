@@ -0,0 +1,271 @@
+package models
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMatrix_UnmarshalKeepsRawValues(t *testing.T) {
+	var strategy Strategy
+	data := []byte(`
+matrix:
+  go-version: [1.20, 1.21]
+  os: [ubuntu, alpine]
+`)
+	if err := yaml.Unmarshal(data, &strategy); err != nil {
+		t.Fatalf("Failed to unmarshal strategy: %v", err)
+	}
+
+	if len(strategy.Matrix.Order) != 2 || strategy.Matrix.Order[0] != "go-version" {
+		t.Errorf("Expected matrix order [go-version os], got %v", strategy.Matrix.Order)
+	}
+
+	versions := strategy.Matrix.Dimensions["go-version"]
+	if len(versions) != 2 || versions[0] != "1.20" || versions[1] != "1.21" {
+		t.Errorf("Expected raw versions [1.20 1.21], got %v", versions)
+	}
+}
+
+func TestMatrix_UnmarshalRejectsScalarDimension(t *testing.T) {
+	var strategy Strategy
+	if err := yaml.Unmarshal([]byte("matrix:\n  os: ubuntu\n"), &strategy); err == nil {
+		t.Error("Expected error for non-list matrix dimension, got nil")
+	}
+}
+
+func TestMatrix_Combinations(t *testing.T) {
+	m := Matrix{
+		Order: []string{"go", "os"},
+		Dimensions: map[string][]string{
+			"go": {"1.20", "1.21"},
+			"os": {"ubuntu", "alpine"},
+		},
+	}
+
+	combos := m.Combinations()
+	if len(combos) != 4 {
+		t.Fatalf("Expected 4 combinations, got %d", len(combos))
+	}
+
+	if combos[0]["go"] != "1.20" || combos[0]["os"] != "ubuntu" {
+		t.Errorf("Unexpected first combination: %v", combos[0])
+	}
+	if combos[1]["go"] != "1.20" || combos[1]["os"] != "alpine" {
+		t.Errorf("Unexpected second combination: %v", combos[1])
+	}
+	if combos[3]["go"] != "1.21" || combos[3]["os"] != "alpine" {
+		t.Errorf("Unexpected last combination: %v", combos[3])
+	}
+}
+
+func TestMatrix_UnmarshalIncludeExclude(t *testing.T) {
+	var strategy Strategy
+	data := []byte(`
+matrix:
+  go: [1.20, 1.21]
+  include:
+    - go: 1.21
+      experimental: true
+  exclude:
+    - go: 1.20
+`)
+	if err := yaml.Unmarshal(data, &strategy); err != nil {
+		t.Fatalf("Failed to unmarshal strategy: %v", err)
+	}
+
+	m := strategy.Matrix
+	if len(m.Order) != 1 || m.Order[0] != "go" {
+		t.Errorf("Expected include and exclude not to be dimensions, got %v", m.Order)
+	}
+	if len(m.Include) != 1 || m.Include[0]["experimental"] != "true" || m.Include[0]["go"] != "1.21" {
+		t.Errorf("Unexpected include entries: %v", m.Include)
+	}
+	if len(m.Exclude) != 1 || m.Exclude[0]["go"] != "1.20" {
+		t.Errorf("Unexpected exclude entries: %v", m.Exclude)
+	}
+
+	if err := yaml.Unmarshal([]byte("matrix:\n  include: [linux]\n"), &strategy); err == nil {
+		t.Error("Expected error for a scalar include entry, got nil")
+	}
+}
+
+func TestMatrix_CombinationsIncludeExclude(t *testing.T) {
+	m := Matrix{
+		Order: []string{"go", "os"},
+		Dimensions: map[string][]string{
+			"go": {"1.20", "1.21"},
+			"os": {"ubuntu", "alpine"},
+		},
+		Exclude: []map[string]string{{"go": "1.20", "os": "alpine"}},
+		Include: []map[string]string{
+			{"os": "alpine", "libc": "musl"},
+			{"go": "1.21", "os": "ubuntu", "experimental": "true"},
+			{"go": "1.22", "os": "ubuntu"},
+		},
+	}
+
+	combos := m.Combinations()
+	if len(combos) != 4 {
+		t.Fatalf("Expected 4 combinations, got %v", combos)
+	}
+	if combos[0]["go"] != "1.20" || combos[0]["os"] != "ubuntu" || len(combos[0]) != 2 {
+		t.Errorf("Expected first combination untouched, got %v", combos[0])
+	}
+	if combos[1]["experimental"] != "true" || combos[1]["libc"] != "" {
+		t.Errorf("Expected the matching include to extend 1.21 on ubuntu only, got %v", combos[1])
+	}
+	if combos[2]["os"] != "alpine" || combos[2]["libc"] != "musl" {
+		t.Errorf("Expected the alpine leg, which wasn't excluded, to gain libc, got %v", combos[2])
+	}
+	if combos[3]["go"] != "1.22" || combos[3]["os"] != "ubuntu" {
+		t.Errorf("Expected an include matching no combination to be added, got %v", combos[3])
+	}
+}
+
+func TestMatrix_CombinationsIncludeOnly(t *testing.T) {
+	m := Matrix{Include: []map[string]string{{"os": "ubuntu"}, {"os": "alpine"}}}
+	if m.Empty() {
+		t.Fatal("Expected a matrix with include entries not to be empty")
+	}
+	combos := m.Combinations()
+	if len(combos) != 2 || combos[0]["os"] != "ubuntu" || combos[1]["os"] != "alpine" {
+		t.Errorf("Expected each include entry as a combination, got %v", combos)
+	}
+}
+
+func TestStringList_UnmarshalScalarOrList(t *testing.T) {
+	var job Job
+	if err := yaml.Unmarshal([]byte("needs: build\n"), &job); err != nil {
+		t.Fatalf("Failed to unmarshal scalar needs: %v", err)
+	}
+	if len(job.Needs) != 1 || job.Needs[0] != "build" {
+		t.Errorf("Expected needs [build], got %v", job.Needs)
+	}
+
+	if err := yaml.Unmarshal([]byte("needs: [build, lint]\n"), &job); err != nil {
+		t.Fatalf("Failed to unmarshal list needs: %v", err)
+	}
+	if len(job.Needs) != 2 {
+		t.Errorf("Expected 2 needs, got %v", job.Needs)
+	}
+}
+
+func TestResources_Limits(t *testing.T) {
+	r := &Resources{CPU: "1.5", Memory: "512m", Disk: "10g"}
+	if cpus, err := r.NanoCPUs(); err != nil || cpus != 1_500_000_000 {
+		t.Errorf("NanoCPUs() = %d, %v; want 1500000000", cpus, err)
+	}
+	if memory, err := r.MemoryBytes(); err != nil || memory != 512*1024*1024 {
+		t.Errorf("MemoryBytes() = %d, %v; want %d", memory, err, 512*1024*1024)
+	}
+	if disk, err := r.DiskBytes(); err != nil || disk != 10<<30 {
+		t.Errorf("DiskBytes() = %d, %v; want %d", disk, err, 10<<30)
+	}
+
+	var unset *Resources
+	if cpus, err := unset.NanoCPUs(); err != nil || cpus != 0 {
+		t.Errorf("Expected no CPU limit when unset, got %d, %v", cpus, err)
+	}
+
+	for _, bad := range []Resources{{CPU: "two"}, {CPU: "0"}, {CPU: "-1"}, {Memory: "lots"}, {Disk: "0"}} {
+		_, cpuErr := bad.NanoCPUs()
+		_, memErr := bad.MemoryBytes()
+		_, diskErr := bad.DiskBytes()
+		if cpuErr == nil && memErr == nil && diskErr == nil {
+			t.Errorf("Expected error for %+v, got nil", bad)
+		}
+	}
+}
+
+func TestParseGPUs(t *testing.T) {
+	tests := map[string]int{"": 0, "2": 2, "all": AllGPUs}
+	for value, want := range tests {
+		if got, err := ParseGPUs(value); err != nil || got != want {
+			t.Errorf("ParseGPUs(%q) = %d, %v; want %d", value, got, err, want)
+		}
+	}
+	for _, bad := range []string{"0", "-1", "two", "ALL"} {
+		if _, err := ParseGPUs(bad); err == nil {
+			t.Errorf("Expected error for %q, got nil", bad)
+		}
+	}
+}
+
+func TestParseVolume(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Volume
+	}{
+		{"/srv/cache/:/cache", Volume{Source: "/srv/cache", Target: "/cache"}},
+		{"gomod:/go/pkg/mod:ro", Volume{Source: "gomod", Target: "/go/pkg/mod", ReadOnly: true}},
+		{"/data:/data:rw", Volume{Source: "/data", Target: "/data"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseVolume(tt.spec)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseVolume(%q) = %+v, %v; want %+v", tt.spec, got, err, tt.want)
+		}
+	}
+	if v, _ := ParseVolume("gomod:/go/pkg/mod:ro"); v.HostPath() || v.String() != "gomod:/go/pkg/mod:ro" {
+		t.Errorf("Expected a read-only named volume, got %+v", v)
+	}
+
+	for _, spec := range []string{"/data", "/data:relative", "./data:/data", "/data:/data:rx", "/data:/", "a:/b:ro:x"} {
+		if _, err := ParseVolume(spec); err == nil {
+			t.Errorf("Expected error for %q, got nil", spec)
+		}
+	}
+}
+
+func TestParseTmpfs(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Tmpfs
+	}{
+		{"/tmp", Tmpfs{Path: "/tmp"}},
+		{"/build/out/:512m", Tmpfs{Path: "/build/out", Size: 512 << 20}},
+	}
+	for _, tt := range tests {
+		got, err := ParseTmpfs(tt.spec)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseTmpfs(%q) = %+v, %v; want %+v", tt.spec, got, err, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"tmp", "/", "/tmp:lots", "/tmp:0"} {
+		if _, err := ParseTmpfs(spec); err == nil {
+			t.Errorf("Expected error for %q, got nil", spec)
+		}
+	}
+}
+
+func TestCheckout_UnmarshalShorthand(t *testing.T) {
+	var job Job
+	if err := yaml.Unmarshal([]byte("checkout: true\n"), &job); err != nil {
+		t.Fatalf("Failed to unmarshal checkout shorthand: %v", err)
+	}
+	if job.Checkout == nil || *job.Checkout != (Checkout{}) {
+		t.Errorf("Expected the event's repository to be checked out, got %+v", job.Checkout)
+	}
+
+	if err := yaml.Unmarshal([]byte("checkout: false\n"), &job); err == nil {
+		t.Error("Expected error for checkout: false, got nil")
+	}
+}
+
+func TestTriggerConfig_UnmarshalDeclaredWithoutSettings(t *testing.T) {
+	var on TriggerConfig
+	if err := yaml.Unmarshal([]byte("push:\npull_request:\n  branches: [main]\n"), &on); err != nil {
+		t.Fatalf("Failed to unmarshal triggers: %v", err)
+	}
+	if on.Push == nil || len(on.Push.Branches) != 0 {
+		t.Errorf("Expected push to be enabled without branch filters, got %+v", on.Push)
+	}
+	if on.PullRequest == nil || len(on.PullRequest.Branches) != 1 {
+		t.Errorf("Expected pull_request branches [main], got %+v", on.PullRequest)
+	}
+	if on.IssueComment != nil || on.WorkflowDispatch != nil {
+		t.Error("Expected undeclared triggers to stay unset")
+	}
+}
@@ -0,0 +1,220 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gantry/internal/expr"
+	"gantry/internal/models"
+)
+
+// maxActionDepth limits how deeply composite actions may use each other
+const maxActionDepth = 4
+
+// actionFiles are the file names an action directory may use
+var actionFiles = []string{"action.yaml", "action.yml"}
+
+// isLocalAction reports whether a step's uses refers to a local action
+func isLocalAction(uses string) bool {
+	return strings.HasPrefix(uses, "./")
+}
+
+// inlineActions replaces every step that uses a local composite action with
+// the action's steps
+func (p *Parser) inlineActions(wf *models.Workflow) error {
+	for name, job := range wf.Jobs {
+		steps, err := p.expandSteps(job.Steps, nil)
+		if err != nil {
+			return fmt.Errorf("job '%s': %w", name, err)
+		}
+		job.Steps = steps
+		wf.Jobs[name] = job
+	}
+	return nil
+}
+
+func (p *Parser) expandSteps(steps []models.Step, stack []string) ([]models.Step, error) {
+	var expanded []models.Step
+	for _, step := range steps {
+		if step.Uses == "" {
+			expanded = append(expanded, step)
+			continue
+		}
+		if !isLocalAction(step.Uses) {
+			return nil, fmt.Errorf("step uses unsupported action '%s' (expected a local path such as ./actions/build)", step.Uses)
+		}
+
+		inlined, err := p.inlineAction(step, stack)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, inlined...)
+	}
+	return expanded, nil
+}
+
+// inlineAction loads the action a step uses and returns its steps. Each
+// inlined step carries the action's inputs, resolved against the calling
+// step's with values and defaults, and inherits the calling step's if and
+// env.
+func (p *Parser) inlineAction(step models.Step, stack []string) ([]models.Step, error) {
+	ref := path.Clean(step.Uses)
+	for _, seen := range stack {
+		if seen == ref {
+			return nil, fmt.Errorf("action '%s' uses itself", step.Uses)
+		}
+	}
+	if len(stack) >= maxActionDepth {
+		return nil, fmt.Errorf("actions are nested more than %d deep", maxActionDepth)
+	}
+
+	action, err := p.loadAction(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	inputs, err := actionInputs(step, action)
+	if err != nil {
+		return nil, err
+	}
+
+	label := step.Name
+	if label == "" {
+		label = action.Name
+	}
+	if label == "" {
+		label = ref
+	}
+
+	steps, err := p.expandSteps(action.Runs.Steps, append(stack, ref))
+	if err != nil {
+		return nil, fmt.Errorf("action '%s': %w", step.Uses, err)
+	}
+
+	inlined := make([]models.Step, len(steps))
+	for i, inner := range steps {
+		inner.Name = fmt.Sprintf("%s: %s", label, inner.Name)
+		inner.If = combineConditions(step.If, inner.If)
+		inner.Env = mergeEnv(step.Env, inner.Env)
+		if inner.WorkingDirectory == "" {
+			inner.WorkingDirectory = step.WorkingDirectory
+		}
+		if inner.Inputs == nil {
+			inner.Inputs = inputs
+		}
+		inlined[i] = inner
+	}
+	return inlined, nil
+}
+
+// loadAction reads an action from its directory under the actions root
+func (p *Parser) loadAction(ref string) (*models.Action, error) {
+	if p.actionsRoot == "" {
+		return nil, fmt.Errorf("action '%s' can't be loaded: local actions are not enabled", ref)
+	}
+	if ref == ".." || strings.HasPrefix(ref, "../") {
+		return nil, fmt.Errorf("action '%s' must be inside the actions root", ref)
+	}
+
+	dir := filepath.Join(p.actionsRoot, filepath.FromSlash(ref))
+	for _, file := range actionFiles {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read action '%s': %w", ref, err)
+		}
+
+		var action models.Action
+		root, err := decodeYAML(data)
+		if err == nil && root != nil {
+			err = root.Decode(&action)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse action '%s': %w", ref, err)
+		}
+		if action.Runs.Using != models.ActionComposite {
+			return nil, fmt.Errorf("action '%s' must set runs.using: %s", ref, models.ActionComposite)
+		}
+		if len(action.Runs.Steps) == 0 {
+			return nil, fmt.Errorf("action '%s' must have at least one step", ref)
+		}
+		for name, input := range action.Inputs {
+			if err := validateInput(name, input); err != nil {
+				return nil, fmt.Errorf("action '%s': %w", ref, err)
+			}
+		}
+		return &action, nil
+	}
+
+	return nil, fmt.Errorf("action '%s' not found (expected %s in %s)", ref, actionFiles[0], dir)
+}
+
+// actionInputs resolves a step's with values against an action's inputs.
+// Values are kept as templates and evaluated when the step runs.
+func actionInputs(step models.Step, action *models.Action) (map[string]string, error) {
+	for name := range step.With {
+		if _, declared := action.Inputs[name]; !declared {
+			return nil, fmt.Errorf("action '%s' has no input '%s'", step.Uses, name)
+		}
+	}
+
+	inputs := make(map[string]string, len(action.Inputs))
+	for name, input := range action.Inputs {
+		value, given := step.With[name]
+		if !given {
+			if input.Required && input.Default == "" {
+				return nil, fmt.Errorf("action '%s' input '%s' is required", step.Uses, name)
+			}
+			value = input.Default
+		}
+		if err := expr.ValidateTemplate(value); err != nil {
+			return nil, fmt.Errorf("action '%s' input '%s' is invalid: %w", step.Uses, name, err)
+		}
+		inputs[name] = value
+	}
+	return inputs, nil
+}
+
+// combineConditions joins a calling step's if with an inlined step's own
+func combineConditions(outer, inner string) string {
+	outer, inner = unwrapCondition(outer), unwrapCondition(inner)
+	switch {
+	case outer == "":
+		return inner
+	case inner == "":
+		return outer
+	default:
+		return fmt.Sprintf("(%s) && (%s)", outer, inner)
+	}
+}
+
+// unwrapCondition strips a surrounding ${{ }} from a condition
+func unwrapCondition(condition string) string {
+	trimmed := strings.TrimSpace(condition)
+	if strings.HasPrefix(trimmed, "${{") && strings.HasSuffix(trimmed, "}}") {
+		return strings.TrimSpace(trimmed[3 : len(trimmed)-2])
+	}
+	return trimmed
+}
+
+// mergeEnv combines env maps; later maps win
+func mergeEnv(base, override map[string]string) map[string]string {
+	if len(base) == 0 {
+		return override
+	}
+	merged := make(map[string]string, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
@@ -0,0 +1,131 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeAction(t *testing.T, root, dir, content string) {
+	t.Helper()
+
+	actionDir := filepath.Join(root, dir)
+	if err := os.MkdirAll(actionDir, 0o755); err != nil {
+		t.Fatalf("Failed to create action dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(actionDir, "action.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write action: %v", err)
+	}
+}
+
+func TestParse_InlinesLocalActions(t *testing.T) {
+	root := t.TempDir()
+	writeAction(t, root, "actions/setup-go", `
+name: Setup Go
+inputs:
+  version:
+    required: true
+  cache:
+    default: "true"
+runs:
+  using: composite
+  steps:
+    - name: Install
+      run: install-go ${{ inputs.version }}
+    - name: Cache
+      if: inputs.cache == 'true'
+      run: restore-cache
+`)
+
+	yaml := `
+name: Build
+jobs:
+  build:
+    runs-on: ubuntu
+    steps:
+      - name: Go
+        uses: ./actions/setup-go
+        if: always()
+        with:
+          version: ${{ matrix.go }}
+      - name: Build
+        run: go build ./...
+`
+
+	p := NewParser(WithActionsRoot(root))
+	wf, err := p.Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := p.Validate(wf); err != nil {
+		t.Fatalf("Expected valid workflow, got: %v", err)
+	}
+
+	steps := wf.Jobs["build"].Steps
+	if len(steps) != 3 {
+		t.Fatalf("Expected 3 steps after inlining, got %d", len(steps))
+	}
+	if steps[0].Name != "Go: Install" || steps[1].Name != "Go: Cache" || steps[2].Name != "Build" {
+		t.Errorf("Unexpected step names: %q, %q, %q", steps[0].Name, steps[1].Name, steps[2].Name)
+	}
+	if steps[0].If != "always()" || steps[1].If != "(always()) && (inputs.cache == 'true')" {
+		t.Errorf("Unexpected conditions: %q, %q", steps[0].If, steps[1].If)
+	}
+
+	inputs := steps[0].Inputs
+	if inputs["version"] != "${{ matrix.go }}" || inputs["cache"] != "true" {
+		t.Errorf("Expected with values and defaults as inputs, got %v", inputs)
+	}
+	if steps[2].Inputs != nil {
+		t.Errorf("Expected regular steps to have no inputs, got %v", steps[2].Inputs)
+	}
+}
+
+func TestParse_InvalidLocalActions(t *testing.T) {
+	root := t.TempDir()
+	writeAction(t, root, "actions/greet", `
+name: Greet
+inputs:
+  who:
+    required: true
+runs:
+  using: composite
+  steps:
+    - name: Hello
+      run: echo hello ${{ inputs.who }}
+`)
+	writeAction(t, root, "actions/loop", `
+runs:
+  using: composite
+  steps:
+    - uses: ./actions/loop
+`)
+	writeAction(t, root, "actions/docker", `
+runs:
+  using: docker
+`)
+
+	tests := map[string]string{
+		"missing action": "uses: ./actions/nope",
+		"missing input":  "uses: ./actions/greet",
+		"unknown input":  "uses: ./actions/greet\n        with: {who: me, loud: 'yes'}",
+		"outside root":   "uses: ./../secrets",
+		"recursive":      "uses: ./actions/loop",
+		"not composite":  "uses: ./actions/docker",
+		"remote action":  "uses: actions/checkout@v4",
+	}
+
+	for name, step := range tests {
+		t.Run(name, func(t *testing.T) {
+			yaml := "name: Bad\njobs:\n  build:\n    runs-on: ubuntu\n    steps:\n      - " + step + "\n"
+			if _, err := NewParser(WithActionsRoot(root)).Parse([]byte(yaml)); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+
+	yaml := "name: Bad\njobs:\n  build:\n    runs-on: ubuntu\n    steps:\n      - uses: ./actions/greet\n"
+	if _, err := NewParser().Parse([]byte(yaml)); err == nil {
+		t.Error("Expected error when local actions are not enabled, got nil")
+	}
+}
@@ -0,0 +1,153 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxResolvedNodes caps how many nodes expanding aliases may produce, so a
+// document that nests aliases of aliases can't exhaust memory
+const maxResolvedNodes = 1 << 20
+
+// unknownAnchorPattern matches yaml.v3's error for an undefined alias
+var unknownAnchorPattern = regexp.MustCompile(`unknown anchor '([^']*)' referenced`)
+
+// decodeYAML parses a YAML document and resolves its anchors, aliases and
+// merge keys into plain nodes, so that key order and custom unmarshalers see
+// the same content the aliases stand for. An empty document yields nil.
+func decodeYAML(data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, anchorError(data, err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	r := &aliasResolver{}
+	return r.resolve(doc.Content[0])
+}
+
+// anchorError adds the line of an undefined alias to yaml.v3's error, which
+// doesn't report one
+func anchorError(data []byte, err error) error {
+	match := unknownAnchorPattern.FindStringSubmatch(err.Error())
+	if match == nil {
+		return err
+	}
+
+	name := match[1]
+	alias := regexp.MustCompile(`\*` + regexp.QuoteMeta(name) + `([^A-Za-z0-9_.-]|$)`)
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if alias.Match(line) {
+			return fmt.Errorf("line %d: alias '*%s' refers to an undefined anchor (define it with '&%s' before it is used)", i+1, name, name)
+		}
+	}
+	return fmt.Errorf("alias '*%s' refers to an undefined anchor (define it with '&%s' before it is used)", name, name)
+}
+
+// aliasResolver copies a node tree, replacing aliases with the nodes they
+// refer to and merge keys with the entries of the mappings they merge
+type aliasResolver struct {
+	stack []*yaml.Node // anchored nodes being resolved, to detect cycles
+	nodes int
+}
+
+func (r *aliasResolver) resolve(n *yaml.Node) (*yaml.Node, error) {
+	r.nodes++
+	if r.nodes > maxResolvedNodes {
+		return nil, fmt.Errorf("line %d: aliases expand to more than %d nodes", n.Line, maxResolvedNodes)
+	}
+
+	if n.Kind == yaml.AliasNode {
+		for _, anchored := range r.stack {
+			if anchored == n.Alias {
+				return nil, fmt.Errorf("line %d: alias '*%s' refers to itself", n.Line, n.Value)
+			}
+		}
+		return r.resolve(n.Alias)
+	}
+
+	if n.Anchor != "" {
+		r.stack = append(r.stack, n)
+		defer func() { r.stack = r.stack[:len(r.stack)-1] }()
+	}
+
+	resolved := *n
+	resolved.Anchor = ""
+	resolved.Content = nil
+
+	if n.Kind == yaml.MappingNode {
+		return r.resolveMapping(n, &resolved)
+	}
+	for _, child := range n.Content {
+		c, err := r.resolve(child)
+		if err != nil {
+			return nil, err
+		}
+		resolved.Content = append(resolved.Content, c)
+	}
+	return &resolved, nil
+}
+
+// resolveMapping expands a mapping's merge keys in place. Keys set on the
+// mapping itself win over merged ones, and earlier merged mappings win over
+// later ones.
+func (r *aliasResolver) resolveMapping(n, resolved *yaml.Node) (*yaml.Node, error) {
+	explicit := make(map[string]bool)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if !isMergeKey(n.Content[i]) {
+			explicit[n.Content[i].Value] = true
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+
+		if !isMergeKey(key) {
+			k, err := r.resolve(key)
+			if err != nil {
+				return nil, err
+			}
+			v, err := r.resolve(value)
+			if err != nil {
+				return nil, err
+			}
+			resolved.Content = append(resolved.Content, k, v)
+			seen[k.Value] = true
+			continue
+		}
+
+		sources := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			sources = value.Content
+		}
+		for _, source := range sources {
+			merged, err := r.resolve(source)
+			if err != nil {
+				return nil, err
+			}
+			if merged.Kind != yaml.MappingNode {
+				return nil, fmt.Errorf("line %d: merge key '<<' must refer to a mapping or a list of mappings", key.Line)
+			}
+			for j := 0; j+1 < len(merged.Content); j += 2 {
+				name := merged.Content[j].Value
+				if explicit[name] || seen[name] {
+					continue
+				}
+				seen[name] = true
+				resolved.Content = append(resolved.Content, merged.Content[j], merged.Content[j+1])
+			}
+		}
+	}
+	return resolved, nil
+}
+
+// isMergeKey reports whether a mapping key is the `<<` merge key
+func isMergeKey(key *yaml.Node) bool {
+	return key.Kind == yaml.ScalarNode && key.ShortTag() == "!!merge"
+}
@@ -0,0 +1,110 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+const anchorsWorkflowYAML = `
+name: Anchors
+x-defaults: &defaults
+  runs-on: ubuntu
+  timeout-minutes: 10
+  env:
+    CI: "true"
+x-go: &go-versions
+  go: [1.21, 1.22]
+x-setup: &setup
+  name: Setup
+  run: make deps
+x-shared-jobs: &shared-jobs
+  lint:
+    <<: *defaults
+    steps:
+      - *setup
+      - name: Lint
+        run: make lint
+jobs:
+  <<: *shared-jobs
+  test:
+    <<: *defaults
+    timeout-minutes: 20
+    needs: [lint]
+    strategy:
+      matrix: *go-versions
+    steps:
+      - *setup
+      - name: Test
+        run: go test ./...
+  build:
+    <<: [*defaults]
+    needs: test
+    steps:
+      - *setup
+`
+
+func TestParse_ResolvesAnchorsAndMergeKeys(t *testing.T) {
+	p := NewParser()
+	wf, err := p.Parse([]byte(anchorsWorkflowYAML))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := p.Validate(wf); err != nil {
+		t.Fatalf("Expected valid workflow, got: %v", err)
+	}
+
+	want := []string{"lint", "test", "build"}
+	if strings.Join(wf.JobOrder, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected job order %v, got %v", want, wf.JobOrder)
+	}
+
+	lint := wf.Jobs["lint"]
+	if lint.RunsOn != "ubuntu" || lint.Env["CI"] != "true" || len(lint.Steps) != 2 || lint.Steps[0].Name != "Setup" {
+		t.Errorf("Expected lint to merge the defaults and the setup step, got %+v", lint)
+	}
+
+	test := wf.Jobs["test"]
+	if test.TimeoutMinutes != 20 {
+		t.Errorf("Expected explicit keys to win over merged ones, got timeout-minutes %v", test.TimeoutMinutes)
+	}
+	if test.Strategy == nil || strings.Join(test.Strategy.Matrix.Dimensions["go"], ",") != "1.21,1.22" {
+		t.Errorf("Expected matrix alias to be resolved, got %+v", test.Strategy)
+	}
+
+	if build := wf.Jobs["build"]; build.RunsOn != "ubuntu" || build.TimeoutMinutes != 10 {
+		t.Errorf("Expected merge from a list of aliases, got %+v", build)
+	}
+}
+
+func TestParse_InvalidAnchors(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			"undefined alias",
+			"name: Bad\njobs:\n  build:\n    <<: *defaults\n",
+			"line 4: alias '*defaults' refers to an undefined anchor",
+		},
+		{
+			"recursive alias",
+			"name: Bad\nx: &loop\n  - *loop\njobs: {}\n",
+			"refers to itself",
+		},
+		{
+			"merge of a scalar",
+			"name: Bad\nx: &image ubuntu\njobs:\n  build:\n    <<: *image\n",
+			"merge key '<<' must refer to a mapping",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParser().Parse([]byte(tt.yaml))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxExtendsDepth limits how long a chain of extended workflows may be
+const maxExtendsDepth = 4
+
+// Keys that compose a workflow from others; they are not inherited
+const (
+	extendsKey = "extends"
+	includeKey = "include"
+)
+
+// WithBaseWorkflows enables `extends:`, loading the YAML source of the named
+// base workflow with lookup
+func WithBaseWorkflows(lookup func(name string) ([]byte, error)) Option {
+	return func(p *Parser) {
+		p.baseWorkflows = lookup
+	}
+}
+
+// compose merges the workflow a document extends and the fragments it
+// includes into it. Layers apply in order: the base workflow, then each
+// include, then the document itself. Mappings merge key by key, while
+// scalars and lists from a later layer replace earlier ones; a job set to
+// null removes the job.
+func (p *Parser) compose(root *yaml.Node, stack []string) (*yaml.Node, error) {
+	if root.Kind != yaml.MappingNode {
+		return root, nil
+	}
+	extends := mappingValue(root, extendsKey)
+	include := mappingValue(root, includeKey)
+	if extends == nil && include == nil {
+		return root, nil
+	}
+
+	var layers []*yaml.Node
+	if extends != nil {
+		if extends.Kind != yaml.ScalarNode || extends.Value == "" {
+			return nil, fmt.Errorf("line %d: extends must name a workflow", extends.Line)
+		}
+		if mappingValue(root, "name") == nil {
+			return nil, fmt.Errorf("a workflow that extends '%s' must set its own name", extends.Value)
+		}
+		base, err := p.loadBase(extends.Value, stack)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, base)
+	}
+
+	if include != nil {
+		refs, err := includeRefs(include)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range refs {
+			fragment, err := p.loadFragment(ref)
+			if err != nil {
+				return nil, err
+			}
+			layers = append(layers, fragment)
+		}
+	}
+
+	merged := layers[0]
+	for _, layer := range append(layers[1:], root) {
+		merged = mergeNodes(merged, layer, "")
+	}
+	return merged, nil
+}
+
+// loadBase loads and composes the workflow named by extends, without its
+// own composition keys
+func (p *Parser) loadBase(name string, stack []string) (*yaml.Node, error) {
+	for _, seen := range stack {
+		if seen == name {
+			return nil, fmt.Errorf("workflow '%s' extends itself", name)
+		}
+	}
+	if len(stack) >= maxExtendsDepth {
+		return nil, fmt.Errorf("workflows extend each other more than %d deep", maxExtendsDepth)
+	}
+	if p.baseWorkflows == nil {
+		return nil, fmt.Errorf("workflow '%s' can't be extended: extends is not enabled", name)
+	}
+
+	data, err := p.baseWorkflows(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load base workflow '%s': %w", name, err)
+	}
+	base, err := decodeYAML(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse base workflow '%s': %w", name, err)
+	}
+	if base == nil || base.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("base workflow '%s' must be a mapping", name)
+	}
+
+	base, err = p.compose(base, append(stack, name))
+	if err != nil {
+		return nil, fmt.Errorf("base workflow '%s': %w", name, err)
+	}
+	return withoutKeys(base, extendsKey, includeKey), nil
+}
+
+// loadFragment loads an included fragment from the actions root
+func (p *Parser) loadFragment(ref string) (*yaml.Node, error) {
+	if p.actionsRoot == "" {
+		return nil, fmt.Errorf("fragment '%s' can't be loaded: includes are not enabled", ref)
+	}
+	clean := path.Clean(ref)
+	if !isLocalAction(ref) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return nil, fmt.Errorf("fragment '%s' must be a path inside the actions root starting with ./", ref)
+	}
+
+	data, err := os.ReadFile(filepath.Join(p.actionsRoot, filepath.FromSlash(clean)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fragment '%s': %w", ref, err)
+	}
+	fragment, err := decodeYAML(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse fragment '%s': %w", ref, err)
+	}
+	if fragment == nil || fragment.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("fragment '%s' must be a mapping", ref)
+	}
+	if mappingValue(fragment, extendsKey) != nil || mappingValue(fragment, includeKey) != nil {
+		return nil, fmt.Errorf("fragment '%s' must not use extends or include", ref)
+	}
+	return fragment, nil
+}
+
+// includeRefs returns the fragment paths of an include value, a path or a
+// list of paths
+func includeRefs(n *yaml.Node) ([]string, error) {
+	if n.Kind == yaml.ScalarNode {
+		return []string{n.Value}, nil
+	}
+	if n.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("line %d: include must be a path or a list of paths", n.Line)
+	}
+	refs := make([]string, 0, len(n.Content))
+	for _, item := range n.Content {
+		if item.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("line %d: include must be a path or a list of paths", item.Line)
+		}
+		refs = append(refs, item.Value)
+	}
+	return refs, nil
+}
+
+// mergeNodes returns dst with src merged over it. Neither node is modified.
+func mergeNodes(dst, src *yaml.Node, path string) *yaml.Node {
+	if dst.Kind != yaml.MappingNode || src.Kind != yaml.MappingNode {
+		return src
+	}
+
+	merged := *dst
+	merged.Content = append([]*yaml.Node(nil), dst.Content...)
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		removed := path == "jobs" && value.ShortTag() == "!!null"
+
+		found := false
+		for j := 0; j+1 < len(merged.Content); j += 2 {
+			if merged.Content[j].Value != key.Value {
+				continue
+			}
+			found = true
+			if removed {
+				merged.Content = append(merged.Content[:j], merged.Content[j+2:]...)
+			} else {
+				merged.Content[j+1] = mergeNodes(merged.Content[j+1], value, joinPath(path, key.Value))
+			}
+			break
+		}
+		if !found && !removed {
+			merged.Content = append(merged.Content, key, value)
+		}
+	}
+	return &merged
+}
+
+// mappingValue returns the value of key in a mapping node, if present
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// withoutKeys returns a copy of a mapping node without keys
+func withoutKeys(n *yaml.Node, keys ...string) *yaml.Node {
+	stripped := *n
+	stripped.Content = nil
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		drop := false
+		for _, key := range keys {
+			if n.Content[i].Value == key {
+				drop = true
+			}
+		}
+		if !drop {
+			stripped.Content = append(stripped.Content, n.Content[i], n.Content[i+1])
+		}
+	}
+	return &stripped
+}
@@ -0,0 +1,168 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const baseWorkflow = `
+name: Base
+on:
+  push:
+    branches: [main]
+env:
+  GO_VERSION: "1.24"
+  LOG_LEVEL: info
+jobs:
+  lint:
+    runs-on: ubuntu
+    steps:
+      - name: Lint
+        run: make lint
+  test:
+    runs-on: ubuntu
+    timeout-minutes: 10
+    steps:
+      - name: Test
+        run: make test
+`
+
+func baseWorkflows(sources map[string]string) Option {
+	return WithBaseWorkflows(func(name string) ([]byte, error) {
+		source, ok := sources[name]
+		if !ok {
+			return nil, fmt.Errorf("workflow '%s' not found", name)
+		}
+		return []byte(source), nil
+	})
+}
+
+func TestParse_ExtendsStoredWorkflow(t *testing.T) {
+	yaml := `
+name: Service
+extends: Base
+env:
+  LOG_LEVEL: debug
+jobs:
+  lint: null
+  test:
+    steps:
+      - name: Test
+        run: make test-service
+  deploy:
+    runs-on: alpine
+    needs: test
+    steps:
+      - name: Deploy
+        run: make deploy
+`
+
+	p := NewParser(baseWorkflows(map[string]string{"Base": baseWorkflow}))
+	wf, err := p.Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := p.Validate(wf); err != nil {
+		t.Fatalf("Expected merged workflow to be valid, got: %v", err)
+	}
+
+	if wf.Name != "Service" || wf.Extends != "Base" {
+		t.Errorf("Expected own name and recorded base, got %q extending %q", wf.Name, wf.Extends)
+	}
+	if wf.Env["GO_VERSION"] != "1.24" || wf.Env["LOG_LEVEL"] != "debug" {
+		t.Errorf("Expected env to merge with overrides winning, got %v", wf.Env)
+	}
+	if wf.On.Push == nil || len(wf.On.Push.Branches) != 1 {
+		t.Errorf("Expected push trigger to be inherited, got %+v", wf.On.Push)
+	}
+	if !reflect.DeepEqual(wf.JobOrder, []string{"test", "deploy"}) {
+		t.Errorf("Expected removed lint and appended deploy, got %v", wf.JobOrder)
+	}
+
+	test := wf.Jobs["test"]
+	if test.RunsOn != "ubuntu" || test.TimeoutMinutes != 10 {
+		t.Errorf("Expected test job settings to be inherited, got %+v", test)
+	}
+	if len(test.Steps) != 1 || test.Steps[0].Run != "make test-service" {
+		t.Errorf("Expected steps to be replaced, got %+v", test.Steps)
+	}
+	if !strings.Contains(wf.Source, "extends: Base") {
+		t.Error("Expected the original source to be kept")
+	}
+}
+
+func TestParse_IncludesFragments(t *testing.T) {
+	root := t.TempDir()
+	fragments := map[string]string{
+		"templates/env.yml":  "env:\n  REGISTRY: registry.local\n",
+		"templates/jobs.yml": "jobs:\n  build:\n    runs-on: ubuntu\n    steps:\n      - name: Build\n        run: make\n",
+	}
+	for name, content := range fragments {
+		file := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatalf("Failed to create fragment dir: %v", err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write fragment: %v", err)
+		}
+	}
+
+	yaml := `
+name: App
+include: [./templates/env.yml, ./templates/jobs.yml]
+jobs:
+  build:
+    env:
+      TARGET: app
+`
+
+	wf, err := NewParser(WithActionsRoot(root)).Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	build := wf.Jobs["build"]
+	if wf.Env["REGISTRY"] != "registry.local" || build.RunsOn != "ubuntu" || build.Env["TARGET"] != "app" {
+		t.Errorf("Expected fragments merged under the workflow, got env %v and job %+v", wf.Env, build)
+	}
+	if len(wf.Include) != 2 {
+		t.Errorf("Expected includes to be recorded, got %v", wf.Include)
+	}
+}
+
+func TestParse_ComposeErrors(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "nested.yml"), []byte("include: ./other.yml\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write fragment: %v", err)
+	}
+
+	sources := map[string]string{
+		"Base": baseWorkflow,
+		"A":    "name: A\nextends: B\njobs: {}\n",
+		"B":    "name: B\nextends: A\njobs: {}\n",
+	}
+	p := NewParser(WithActionsRoot(root), baseWorkflows(sources))
+
+	tests := map[string]string{
+		"no own name":      "extends: Base\n",
+		"unknown base":     "name: X\nextends: Missing\n",
+		"cycle":            "name: X\nextends: A\n",
+		"escaping include": "name: X\ninclude: ../secrets.yml\njobs: {}\n",
+		"nested include":   "name: X\ninclude: ./nested.yml\njobs: {}\n",
+		"missing fragment": "name: X\ninclude: ./missing.yml\njobs: {}\n",
+	}
+	for name, yaml := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := p.Parse([]byte(yaml)); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+
+	if _, err := NewParser().Parse([]byte("name: X\nextends: Base\n")); err == nil {
+		t.Error("Expected error when extends is not enabled, got nil")
+	}
+}
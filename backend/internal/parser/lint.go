@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+
+	"gantry/internal/expr"
+	"gantry/internal/models"
+)
+
+// Lint reports problems in a valid workflow that don't stop it from running
+// but are likely mistakes, such as expressions that read a variable the
+// workflow doesn't declare or a job it doesn't need, which evaluate to empty
+func (p *Parser) Lint(wf *models.Workflow) ValidationErrors {
+	var warnings ValidationErrors
+
+	for _, name := range wf.JobOrder {
+		job := wf.Jobs[name]
+		path := joinPath("jobs", name)
+
+		for _, v := range contextReferences(job, "vars") {
+			if _, declared := wf.Variables[v]; !declared {
+				warnings = append(warnings, ValidationError{
+					Path:    path,
+					Message: fmt.Sprintf("references vars.%s, which the workflow doesn't declare", v),
+				})
+			}
+		}
+
+		for _, need := range contextReferences(job, "needs") {
+			if !containsString(job.Needs, need) {
+				warnings = append(warnings, ValidationError{
+					Path:    path,
+					Message: fmt.Sprintf("references needs.%s, but the job doesn't need '%s'", need, need),
+				})
+			}
+		}
+	}
+
+	return warnings
+}
+
+// contextReferences returns the sorted properties a job's templates and
+// conditions read from a named context
+func contextReferences(job models.Job, context string) []string {
+	seen := make(map[string]bool)
+	for _, t := range job.Templates() {
+		refs, _ := expr.TemplateReferences(t, context)
+		for _, ref := range refs {
+			seen[ref] = true
+		}
+	}
+	for _, c := range job.Conditions() {
+		if c == "" {
+			continue
+		}
+		if e, err := expr.ParseCondition(c); err == nil {
+			for _, ref := range e.References(context) {
+				seen[ref] = true
+			}
+		}
+	}
+
+	refs := make([]string, 0, len(seen))
+	for ref := range seen {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	return refs
+}
@@ -0,0 +1,37 @@
+package parser
+
+import (
+	"testing"
+
+	"gantry/internal/models"
+)
+
+func TestLint(t *testing.T) {
+	wf := &models.Workflow{
+		Name:      "Lint",
+		Variables: map[string]string{"REGISTRY": "registry.local"},
+		Jobs: map[string]models.Job{
+			"build": {RunsOn: "ubuntu", Steps: []models.Step{
+				{Name: "Push", Run: "docker push ${{ vars.REGISTRY }}/app:${{ vars.TAG }}"},
+			}},
+			"deploy": {RunsOn: "ubuntu", Needs: models.StringList{"build"}, If: "needs.test.result == 'success'", Steps: []models.Step{
+				{Name: "Deploy", Run: "deploy ${{ needs.build.outputs.version }}"},
+			}},
+		},
+		JobOrder: []string{"build", "deploy"},
+	}
+
+	warnings := NewParser().Lint(wf)
+	want := []ValidationError{
+		{Path: "jobs.build", Message: "references vars.TAG, which the workflow doesn't declare"},
+		{Path: "jobs.deploy", Message: "references needs.test, but the job doesn't need 'test'"},
+	}
+	if len(warnings) != len(want) {
+		t.Fatalf("Expected %d warnings, got %v", len(want), warnings)
+	}
+	for i := range want {
+		if warnings[i] != want[i] {
+			t.Errorf("Warning %d: expected %+v, got %+v", i, want[i], warnings[i])
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SetName returns a workflow's YAML with its top-level name set to name,
+// leaving the rest of the document, comments included, as it was. A
+// document without a name gets one before its first key.
+func SetName(data []byte, name string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 ||
+		doc.Content[0].Kind != yaml.MappingNode || doc.Content[0].Style&yaml.FlowStyle != 0 {
+		return nil, fmt.Errorf("workflow is not a block mapping")
+	}
+	root := doc.Content[0]
+
+	value, err := yaml.Marshal(name)
+	if err != nil {
+		return nil, err
+	}
+	line := "name: " + strings.TrimSuffix(string(value), "\n")
+
+	lines := strings.SplitAfter(string(data), "\n")
+	newline := "\n"
+	if strings.HasSuffix(lines[0], "\r\n") {
+		newline = "\r\n"
+	}
+
+	var key, val *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "name" {
+			key, val = root.Content[i], root.Content[i+1]
+		}
+	}
+	if key == nil {
+		if len(root.Content) == 0 {
+			return nil, fmt.Errorf("workflow has no keys")
+		}
+		lines = slices.Insert(lines, root.Content[0].Line-1, line+newline)
+		return []byte(strings.Join(lines, "")), nil
+	}
+
+	if comment := val.LineComment + key.LineComment; comment != "" {
+		line += " " + comment
+	}
+	lines[key.Line-1] = line + newline
+
+	// A name written over several lines continues on indented lines, which
+	// go with the old name
+	end := key.Line
+	for end < len(lines) && isContinuation(lines[end]) {
+		end++
+	}
+	lines = slices.Delete(lines, key.Line, end)
+	return []byte(strings.Join(lines, "")), nil
+}
+
+// isContinuation reports whether a line continues the value of a top-level
+// key, being indented and neither blank nor a comment
+func isContinuation(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed != "" && !strings.HasPrefix(trimmed, "#") && (line[0] == ' ' || line[0] == '\t')
+}
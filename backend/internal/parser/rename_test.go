@@ -0,0 +1,65 @@
+package parser
+
+import "testing"
+
+func TestSetName(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "plain",
+			source: "# Builds on push\nname: Build\njobs:\n  test: {}\n",
+			want:   "# Builds on push\nname: Build and Test\njobs:\n  test: {}\n",
+		},
+		{
+			name:   "quoted with comment",
+			source: "name: 'Build' # shown in the UI\non: push\n",
+			want:   "name: Build and Test # shown in the UI\non: push\n",
+		},
+		{
+			name:   "over several lines",
+			source: "name: >-\n  Build\n  everything\n\n# Jobs\njobs: {}\n",
+			want:   "name: Build and Test\n\n# Jobs\njobs: {}\n",
+		},
+		{
+			name:   "missing",
+			source: "# Builds on push\non: push\njobs: {}\n",
+			want:   "# Builds on push\nname: Build and Test\non: push\njobs: {}\n",
+		},
+		{
+			name:   "nested names untouched",
+			source: "jobs:\n  test:\n    name: Test\nname: Build\n",
+			want:   "jobs:\n  test:\n    name: Test\nname: Build and Test\n",
+		},
+		{
+			name:   "CRLF",
+			source: "name: Build\r\njobs: {}\r\n",
+			want:   "name: Build and Test\r\njobs: {}\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SetName([]byte(tt.source), "Build and Test")
+			if err != nil {
+				t.Fatalf("Failed to set name: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Expected:\n%q\ngot:\n%q", tt.want, got)
+			}
+		})
+	}
+
+	got, err := SetName([]byte("name: Build\n"), "true: yes # or: no")
+	if err != nil {
+		t.Fatalf("Failed to set name: %v", err)
+	}
+	if want := "name: 'true: yes # or: no'\n"; string(got) != want {
+		t.Errorf("Expected the name to be quoted as %q, got %q", want, got)
+	}
+
+	if _, err := SetName([]byte("{name: Build}"), "Deploy"); err == nil {
+		t.Error("Expected a flow mapping to be refused")
+	}
+}
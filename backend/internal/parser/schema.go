@@ -0,0 +1,331 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gantry/internal/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError describes a single problem in a workflow file, with the
+// YAML position and the path of the field it was found at when known
+type ValidationError struct {
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	var b strings.Builder
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "line %d", e.Line)
+		if e.Column > 0 {
+			fmt.Fprintf(&b, ", column %d", e.Column)
+		}
+		b.WriteString(": ")
+	}
+	if e.Path != "" {
+		b.WriteString(e.Path + ": ")
+	}
+	b.WriteString(e.Message)
+	return b.String()
+}
+
+// ValidationErrors lists every problem found in a workflow file, in the
+// order they appear
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// AsValidationErrors returns the problems err describes. Errors without a
+// position become a single entry holding only their message.
+func AsValidationErrors(err error) ValidationErrors {
+	var list ValidationErrors
+	if errors.As(err, &list) {
+		return list
+	}
+	var single ValidationError
+	if errors.As(err, &single) {
+		return ValidationErrors{single}
+	}
+	return ValidationErrors{{Message: err.Error()}}
+}
+
+// linePattern matches the line prefix yaml.v3 and the alias resolver put on
+// their errors
+var linePattern = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// syntaxError turns a YAML decoding error into a validation error at its line
+func syntaxError(err error) ValidationError {
+	match := linePattern.FindStringSubmatch(err.Error())
+	if match == nil {
+		return ValidationError{Message: strings.TrimPrefix(err.Error(), "yaml: ")}
+	}
+	line, _ := strconv.Atoi(match[1])
+	return ValidationError{Line: line, Message: match[2]}
+}
+
+// Types with custom YAML unmarshalers, whose shapes the schema knows
+var (
+	stringListType = reflect.TypeOf(models.StringList{})
+	matrixType     = reflect.TypeOf(models.Matrix{})
+	containerType  = reflect.TypeOf(models.Container{})
+	serviceType    = reflect.TypeOf(models.Service{})
+	checkoutType   = reflect.TypeOf(models.Checkout{})
+	workflowType   = reflect.TypeOf(models.Workflow{})
+	jobType        = reflect.TypeOf(models.Job{})
+	stepType       = reflect.TypeOf(models.Step{})
+)
+
+// stepKinds are the keys that say what a step does; each step needs one
+var stepKinds = []string{"run", "uses", "upload-artifact", "download-artifact", "cache", "build"}
+
+// checkSchema checks a workflow document against the fields of the workflow
+// model, reporting unknown keys, values of the wrong type and missing
+// required fields. Top-level keys starting with x- are ignored, so they can
+// hold anchors.
+func checkSchema(root *yaml.Node) error {
+	c := &schemaChecker{}
+	c.check(root, workflowType, "")
+	if len(c.errs) > 0 {
+		return c.errs
+	}
+	return nil
+}
+
+type schemaChecker struct {
+	errs ValidationErrors
+}
+
+func (c *schemaChecker) errorf(n *yaml.Node, path, format string, args ...interface{}) {
+	c.errs = append(c.errs, ValidationError{
+		Line:    n.Line,
+		Column:  n.Column,
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *schemaChecker) check(n *yaml.Node, t reflect.Type, path string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// An empty value leaves the field unset
+	if n.Kind == yaml.ScalarNode && n.ShortTag() == "!!null" {
+		return
+	}
+
+	switch t {
+	case stringListType:
+		c.checkStringList(n, path)
+		return
+	case matrixType:
+		c.checkMatrix(n, path)
+		return
+	case containerType, serviceType:
+		// Accept the image shorthand
+		if n.Kind == yaml.ScalarNode {
+			return
+		}
+	case checkoutType:
+		// Accept `checkout: true`
+		if n.Kind == yaml.ScalarNode {
+			if n.ShortTag() != "!!bool" || n.Value != "true" {
+				c.errorf(n, path, "expected true or a mapping, got %s", describe(n))
+			}
+			return
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		c.checkStruct(n, t, path)
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			c.errorf(n, path, "expected a mapping, got %s", describe(n))
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			c.check(n.Content[i+1], t.Elem(), joinPath(path, n.Content[i].Value))
+		}
+	case reflect.Slice:
+		if n.Kind != yaml.SequenceNode {
+			c.errorf(n, path, "expected a list, got %s", describe(n))
+			return
+		}
+		for i, item := range n.Content {
+			c.check(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.String:
+		c.checkScalar(n, path, "a string")
+	case reflect.Bool:
+		if c.checkScalar(n, path, "a boolean") && n.ShortTag() != "!!bool" {
+			c.errorf(n, path, "expected a boolean, got %q", n.Value)
+		}
+	case reflect.Int, reflect.Int64:
+		if c.checkScalar(n, path, "an integer") && n.ShortTag() != "!!int" {
+			c.errorf(n, path, "expected an integer, got %q", n.Value)
+		}
+	case reflect.Float64:
+		if c.checkScalar(n, path, "a number") && n.ShortTag() != "!!int" && n.ShortTag() != "!!float" {
+			c.errorf(n, path, "expected a number, got %q", n.Value)
+		}
+	}
+}
+
+// checkScalar reports a node that isn't a scalar
+func (c *schemaChecker) checkScalar(n *yaml.Node, path, want string) bool {
+	if n.Kind != yaml.ScalarNode {
+		c.errorf(n, path, "expected %s, got %s", want, describe(n))
+		return false
+	}
+	return true
+}
+
+func (c *schemaChecker) checkStruct(n *yaml.Node, t reflect.Type, path string) {
+	if n.Kind != yaml.MappingNode {
+		c.errorf(n, path, "expected a mapping, got %s", describe(n))
+		return
+	}
+
+	fields := yamlFields(t)
+	present := make(map[string]bool, len(n.Content)/2)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		present[key.Value] = true
+
+		field, ok := fields[key.Value]
+		if !ok {
+			if t == workflowType && strings.HasPrefix(key.Value, "x-") {
+				continue
+			}
+			c.errorf(key, joinPath(path, key.Value), "unknown key '%s'", key.Value)
+			continue
+		}
+		c.check(value, field, joinPath(path, key.Value))
+	}
+
+	switch t {
+	case workflowType:
+		c.require(n, path, present, "name", "jobs")
+	case jobType:
+		if !present["uses"] {
+			c.require(n, path, present, "runs-on", "steps")
+		}
+	case stepType:
+		if !present["uses"] {
+			c.require(n, path, present, "name")
+		}
+		kinds := 0
+		for _, kind := range stepKinds {
+			if present[kind] {
+				kinds++
+			}
+		}
+		if kinds == 0 {
+			c.errorf(n, path, "step must set one of %s", strings.Join(stepKinds, ", "))
+		}
+	}
+}
+
+// require reports each of keys missing from a mapping
+func (c *schemaChecker) require(n *yaml.Node, path string, present map[string]bool, keys ...string) {
+	for _, key := range keys {
+		if !present[key] {
+			c.errorf(n, joinPath(path, key), "missing required key '%s'", key)
+		}
+	}
+}
+
+// checkStringList accepts a scalar or a list of scalars
+func (c *schemaChecker) checkStringList(n *yaml.Node, path string) {
+	if n.Kind == yaml.ScalarNode {
+		return
+	}
+	if n.Kind != yaml.SequenceNode {
+		c.errorf(n, path, "expected a string or a list of strings, got %s", describe(n))
+		return
+	}
+	for i, item := range n.Content {
+		c.checkScalar(item, fmt.Sprintf("%s[%d]", path, i), "a string")
+	}
+}
+
+// checkMatrix accepts a mapping of dimension names to lists of scalars, and
+// include and exclude lists of mappings of scalars
+func (c *schemaChecker) checkMatrix(n *yaml.Node, path string) {
+	if n.Kind != yaml.MappingNode {
+		c.errorf(n, path, "expected a mapping of dimensions, got %s", describe(n))
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key := n.Content[i].Value
+		dimension := joinPath(path, key)
+		values := n.Content[i+1]
+		if values.Kind != yaml.SequenceNode {
+			c.errorf(values, dimension, "expected a list of values, got %s", describe(values))
+			continue
+		}
+		for j, item := range values.Content {
+			itemPath := fmt.Sprintf("%s[%d]", dimension, j)
+			if key != "include" && key != "exclude" {
+				c.checkScalar(item, itemPath, "a scalar")
+				continue
+			}
+			if item.Kind != yaml.MappingNode {
+				c.errorf(item, itemPath, "expected a mapping of matrix values, got %s", describe(item))
+				continue
+			}
+			for k := 0; k+1 < len(item.Content); k += 2 {
+				c.checkScalar(item.Content[k+1], joinPath(itemPath, item.Content[k].Value), "a scalar")
+			}
+		}
+	}
+}
+
+// yamlFields maps the YAML keys of a struct to their field types. Fields
+// without a yaml tag are runtime state and can't be set from a file.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// describe names the kind of a node for error messages
+func describe(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	default:
+		return fmt.Sprintf("%q", n.Value)
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse_ReportsSchemaErrors(t *testing.T) {
+	yaml := `name: Bad
+jobs:
+  build:
+    runs-on: ubuntu
+    timeout-minutes: soon
+    continue-on-error: "yes"
+    needs: {lint: true}
+    steps:
+      - name: Build
+        rn: make
+  deploy:
+    steps:
+      - run: deploy
+`
+
+	_, err := NewParser().Parse([]byte(yaml))
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected ValidationErrors, got %T: %v", err, err)
+	}
+
+	want := []ValidationError{
+		{Line: 5, Column: 22, Path: "jobs.build.timeout-minutes", Message: `expected a number, got "soon"`},
+		{Line: 6, Column: 24, Path: "jobs.build.continue-on-error", Message: `expected a boolean, got "yes"`},
+		{Line: 7, Column: 12, Path: "jobs.build.needs", Message: "expected a string or a list of strings, got a mapping"},
+		{Line: 10, Column: 9, Path: "jobs.build.steps[0].rn", Message: "unknown key 'rn'"},
+		{Line: 9, Column: 9, Path: "jobs.build.steps[0]", Message: "step must set one of run, uses, upload-artifact, download-artifact, cache, build"},
+		{Line: 13, Column: 9, Path: "jobs.deploy.steps[0].name", Message: "missing required key 'name'"},
+		{Line: 12, Column: 5, Path: "jobs.deploy.runs-on", Message: "missing required key 'runs-on'"},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("Expected %d errors, got %d: %v", len(want), len(errs), errs)
+	}
+	for i := range want {
+		if errs[i] != want[i] {
+			t.Errorf("Error %d: expected %+v, got %+v", i, want[i], errs[i])
+		}
+	}
+}
+
+func TestParse_IgnoresExtensionKeys(t *testing.T) {
+	yaml := "name: Ok\nx-notes: anything\njobs:\n  build:\n    runs-on: ubuntu\n    steps:\n      - name: Build\n        run: make\n"
+	if _, err := NewParser().Parse([]byte(yaml)); err != nil {
+		t.Errorf("Expected top-level x- keys to be ignored, got %v", err)
+	}
+
+	nested := "name: Bad\njobs:\n  build:\n    x-notes: anything\n    runs-on: ubuntu\n    steps:\n      - name: Build\n        run: make\n"
+	if _, err := NewParser().Parse([]byte(nested)); err == nil {
+		t.Error("Expected x- keys below the top level to be rejected, got nil")
+	}
+}
+
+func TestParse_SyntaxErrorHasLine(t *testing.T) {
+	_, err := NewParser().Parse([]byte("name: Bad\njobs:\n  build:\n    runs-on: [ubuntu\n"))
+	errs := AsValidationErrors(err)
+	if len(errs) != 1 || errs[0].Line == 0 {
+		t.Errorf("Expected a single syntax error with a line, got %+v", errs)
+	}
+
+	if errs := AsValidationErrors(errors.New("plain")); len(errs) != 1 || errs[0].Message != "plain" {
+		t.Errorf("Expected plain errors to become a message-only entry, got %+v", errs)
+	}
+}
@@ -1,51 +1,123 @@
 package parser
 
 import (
-	"bytes"
 	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
+	"gantry/internal/artifacts"
+	"gantry/internal/cache"
+	"gantry/internal/expr"
+	"gantry/internal/glob"
 	"gantry/internal/models"
+	"gantry/internal/runners"
 
+	"github.com/distribution/reference"
 	"gopkg.in/yaml.v3"
 )
 
+// envNamePattern matches valid environment variable names
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// inputNamePattern matches workflow_dispatch input names
+var inputNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// stepIDPattern matches step ids, which later steps reference as steps.<id>
+var stepIDPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// commandPattern matches comment commands such as /retest
+var commandPattern = regexp.MustCompile(`^/[^\s/]+$`)
+
+// pullRequestTypes are the activity types a pull_request trigger may list
+var pullRequestTypes = map[string]bool{
+	models.PullRequestOpened:         true,
+	models.PullRequestSynchronize:    true,
+	models.PullRequestReopened:       true,
+	models.PullRequestClosed:         true,
+	models.PullRequestEdited:         true,
+	models.PullRequestReadyForReview: true,
+}
+
+// stepShells are the shells a step may select
+var stepShells = map[string]bool{
+	models.ShellSh:     true,
+	models.ShellBash:   true,
+	models.ShellPython: true,
+	models.ShellNode:   true,
+}
+
+// stepLanguages are the languages a step's script may be written in
+var stepLanguages = map[string]bool{
+	models.LanguagePython: true,
+	models.LanguageNode:   true,
+	models.LanguageDeno:   true,
+}
+
+// serviceNamePattern matches service names, which double as hostnames
+var serviceNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
 // Parser handles workflow parsing
-type Parser struct{}
+type Parser struct {
+	actionsRoot   string
+	baseWorkflows func(name string) ([]byte, error)
+	runners       runners.Store
+}
 
-// NewParser creates a new parser instance
-func NewParser() *Parser {
-	return &Parser{}
+// Option configures a Parser
+type Option func(*Parser)
+
+// WithActionsRoot enables local composite actions, resolving `uses: ./path`
+// against root
+func WithActionsRoot(root string) Option {
+	return func(p *Parser) {
+		p.actionsRoot = root
+	}
 }
 
-// Parse parses a YAML workflow file and preserves job order
-func (p *Parser) Parse(data []byte) (*models.Workflow, error) {
-	// First, parse the raw YAML to preserve key order
-	var rawMap map[string]interface{}
-	decoder := yaml.NewDecoder(bytes.NewReader(data))
-	if err := decoder.Decode(&rawMap); err != nil {
-		return nil, fmt.Errorf("failed to parse workflow: %w", err)
+// WithRunners sets the runners jobs may select with runs-on, in place of the
+// built-in ubuntu and alpine runners
+func WithRunners(store runners.Store) Option {
+	return func(p *Parser) {
+		p.runners = store
 	}
+}
 
-	// Now parse into struct
-	var wf models.Workflow
-	if err := yaml.Unmarshal(data, &wf); err != nil {
-		return nil, fmt.Errorf("failed to parse workflow: %w", err)
+// NewParser creates a new parser instance
+func NewParser(opts ...Option) *Parser {
+	p := &Parser{runners: runners.NewDefaultStore()}
+	for _, opt := range opts {
+		opt(p)
 	}
+	return p
+}
 
-	// Extract job order from raw YAML using yaml.v3 Node
-	var orderedYAML struct {
-		Jobs yaml.Node `yaml:"jobs"`
+// Parse parses a YAML workflow file and preserves job order. Syntax and
+// schema problems are reported as ValidationErrors.
+func (p *Parser) Parse(data []byte) (*models.Workflow, error) {
+	// Resolve anchors and aliases first so job order and the custom
+	// unmarshalers see merged content
+	root, err := decodeYAML(data)
+	if err != nil {
+		return nil, ValidationErrors{syntaxError(err)}
 	}
-	if err := yaml.Unmarshal(data, &orderedYAML); err == nil {
-		// Extract keys in order from yaml.Node
-		if orderedYAML.Jobs.Kind == yaml.MappingNode {
-			wf.JobOrder = make([]string, 0, len(orderedYAML.Jobs.Content)/2)
-			for i := 0; i < len(orderedYAML.Jobs.Content); i += 2 {
-				if orderedYAML.Jobs.Content[i].Value != "" {
-					wf.JobOrder = append(wf.JobOrder, orderedYAML.Jobs.Content[i].Value)
-				}
-			}
+
+	wf := models.Workflow{Source: string(data)}
+	if root != nil {
+		if root, err = p.compose(root, nil); err != nil {
+			return nil, fmt.Errorf("failed to parse workflow: %w", err)
 		}
+
+		if err := checkSchema(root); err != nil {
+			return nil, err
+		}
+		if err := root.Decode(&wf); err != nil {
+			return nil, fmt.Errorf("failed to parse workflow: %w", err)
+		}
+		wf.JobOrder = jobOrder(root)
 	}
 
 	// Fallback: if JobOrder is still empty, use map keys (will be random)
@@ -55,9 +127,72 @@ func (p *Parser) Parse(data []byte) (*models.Workflow, error) {
 		}
 	}
 
+	if err := p.inlineActions(&wf); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow: %w", err)
+	}
+	applyDefaults(&wf)
+
 	return &wf, nil
 }
 
+// jobOrder returns the job names in the order the jobs mapping declares them
+func jobOrder(root *yaml.Node) []string {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "jobs" || root.Content[i+1].Kind != yaml.MappingNode {
+			continue
+		}
+		jobs := root.Content[i+1]
+		order := make([]string, 0, len(jobs.Content)/2)
+		for j := 0; j+1 < len(jobs.Content); j += 2 {
+			if jobs.Content[j].Value != "" {
+				order = append(order, jobs.Content[j].Value)
+			}
+		}
+		return order
+	}
+	return nil
+}
+
+// applyDefaults merges the workflow's defaults.run into every job and step.
+// Values set on a job or step win; defaults env sits between the workflow's
+// env and each job's.
+func applyDefaults(wf *models.Workflow) {
+	if wf.Defaults == nil {
+		return
+	}
+	defaults := wf.Defaults.Run
+
+	for name, job := range wf.Jobs {
+		if job.WorkingDirectory == "" {
+			job.WorkingDirectory = defaults.WorkingDirectory
+		}
+
+		if len(defaults.Env) > 0 {
+			env := make(map[string]string, len(defaults.Env)+len(job.Env))
+			for k, v := range defaults.Env {
+				env[k] = v
+			}
+			for k, v := range job.Env {
+				env[k] = v
+			}
+			job.Env = env
+		}
+
+		if defaults.Shell != "" {
+			steps := make([]models.Step, len(job.Steps))
+			for i, step := range job.Steps {
+				if step.Shell == "" && step.Language == "" && !step.IsBuiltin() {
+					step.Shell = defaults.Shell
+				}
+				steps[i] = step
+			}
+			job.Steps = steps
+		}
+
+		wf.Jobs[name] = job
+	}
+}
+
 // Validate validates a workflow
 func (p *Parser) Validate(wf *models.Workflow) error {
 	if wf.Name == "" {
@@ -68,18 +203,900 @@ func (p *Parser) Validate(wf *models.Workflow) error {
 		return fmt.Errorf("workflow must have at least one job")
 	}
 
+	if err := validateEnv("workflow", wf.Env); err != nil {
+		return err
+	}
+
+	for name, value := range wf.Variables {
+		if !envNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid variable name '%s'", name)
+		}
+		if expr.HasExpressions(value) {
+			return fmt.Errorf("variable '%s' must not contain expressions", name)
+		}
+	}
+
+	if d := wf.Defaults; d != nil {
+		if d.Run.Shell != "" && !stepShells[d.Run.Shell] {
+			return fmt.Errorf("defaults.run has unknown shell '%s' (expected sh, bash, python or node)", d.Run.Shell)
+		}
+		if err := validateWorkingDirectory("defaults.run", d.Run.WorkingDirectory); err != nil {
+			return err
+		}
+		if err := validateEnv("defaults.run", d.Run.Env); err != nil {
+			return err
+		}
+	}
+
+	if dispatch := wf.On.WorkflowDispatch; dispatch != nil {
+		for name, input := range dispatch.Inputs {
+			if err := validateInput(name, input); err != nil {
+				return err
+			}
+		}
+	}
+
+	if call := wf.On.WorkflowCall; call != nil {
+		for name, input := range call.Inputs {
+			if err := validateInput(name, input); err != nil {
+				return err
+			}
+		}
+	}
+
+	if comment := wf.On.IssueComment; comment != nil {
+		for _, command := range comment.Commands {
+			if !commandPattern.MatchString(command) {
+				return fmt.Errorf("invalid issue_comment command '%s' (expected e.g. /retest)", command)
+			}
+		}
+	}
+
+	if push := wf.On.Push; push != nil {
+		if err := validateBranches("push", push.Branches); err != nil {
+			return err
+		}
+	}
+
+	if pr := wf.On.PullRequest; pr != nil {
+		for _, t := range pr.Types {
+			if !pullRequestTypes[t] {
+				return fmt.Errorf("unknown pull_request type '%s'", t)
+			}
+		}
+		if err := validateBranches("pull_request", pr.Branches); err != nil {
+			return err
+		}
+	}
+
+	if hook := wf.On.Webhook; hook != nil {
+		if hook.Secret == "" {
+			return fmt.Errorf("webhook trigger needs a secret to verify deliveries")
+		}
+		if !envNamePattern.MatchString(hook.Secret) {
+			return fmt.Errorf("invalid webhook secret name '%s'", hook.Secret)
+		}
+	}
+
 	for jobName, job := range wf.Jobs {
-		if len(job.Steps) == 0 {
+		if job.Uses != "" {
+			if err := validateCall(wf, jobName, job); err != nil {
+				return err
+			}
+		} else if len(job.Steps) == 0 {
 			return fmt.Errorf("job '%s' must have at least one step", jobName)
 		}
+		if job.Uses == "" && !expr.HasExpressions(job.RunsOn) {
+			if _, err := runners.Resolve(p.runners, job.RunsOn); err != nil {
+				return fmt.Errorf("job '%s' runs-on %w", jobName, err)
+			}
+		}
 
+		stepIDs := make(map[string]bool)
 		for i, step := range job.Steps {
+			if step.Uses != "" {
+				return fmt.Errorf("job '%s' step %d uses action '%s', which was not inlined by the parser", jobName, i+1, step.Uses)
+			}
 			if step.Name == "" {
 				return fmt.Errorf("job '%s' step %d is missing a name", jobName, i+1)
 			}
-			if step.Run == "" {
+			if step.IsBuiltin() {
+				if err := validateBuiltinStep(jobName, step); err != nil {
+					return err
+				}
+			} else if step.Run == "" {
 				return fmt.Errorf("job '%s' step '%s' is missing run commands", jobName, step.Name)
 			}
+			if step.If != "" {
+				if _, err := expr.ParseCondition(step.If); err != nil {
+					return fmt.Errorf("job '%s' step '%s' has an invalid if condition: %w", jobName, step.Name, err)
+				}
+			}
+			if err := expr.ValidateTemplate(step.Name); err != nil {
+				return fmt.Errorf("job '%s' step %d has an invalid name: %w", jobName, i+1, err)
+			}
+			if err := expr.ValidateTemplate(step.Run); err != nil {
+				return fmt.Errorf("job '%s' step '%s' has an invalid run command: %w", jobName, step.Name, err)
+			}
+			if err := validateEnv(fmt.Sprintf("job '%s' step '%s'", jobName, step.Name), step.Env); err != nil {
+				return err
+			}
+			if err := validateStepReferences(jobName, step, stepIDs); err != nil {
+				return err
+			}
+			if step.ID != "" {
+				if !stepIDPattern.MatchString(step.ID) {
+					return fmt.Errorf("job '%s' step '%s' has an invalid id '%s'", jobName, step.Name, step.ID)
+				}
+				if stepIDs[step.ID] {
+					return fmt.Errorf("job '%s' has more than one step with id '%s'", jobName, step.ID)
+				}
+				stepIDs[step.ID] = true
+			}
+			if step.TimeoutMinutes < 0 {
+				return fmt.Errorf("job '%s' step '%s' timeout-minutes must be positive", jobName, step.Name)
+			}
+			if err := p.validateShell(jobName, job, step); err != nil {
+				return err
+			}
+			if err := p.validateLanguage(jobName, job, step); err != nil {
+				return err
+			}
+			if err := validateWorkingDirectory(fmt.Sprintf("job '%s' step '%s'", jobName, step.Name), step.WorkingDirectory); err != nil {
+				return err
+			}
+			if r := step.Retry; r != nil {
+				if r.Attempts < 1 {
+					return fmt.Errorf("job '%s' step '%s' retry attempts must be at least 1", jobName, step.Name)
+				}
+				if r.Delay != "" {
+					if d, err := time.ParseDuration(r.Delay); err != nil || d < 0 {
+						return fmt.Errorf("job '%s' step '%s' has an invalid retry delay '%s'", jobName, step.Name, r.Delay)
+					}
+				}
+			}
+		}
+
+		if err := expr.ValidateTemplate(job.RunsOn); err != nil {
+			return fmt.Errorf("job '%s' has an invalid runs-on: %w", jobName, err)
+		}
+		if err := expr.ValidateTemplate(job.Environment); err != nil {
+			return fmt.Errorf("job '%s' has an invalid environment: %w", jobName, err)
+		}
+		if err := validateEnv(fmt.Sprintf("job '%s'", jobName), job.Env); err != nil {
+			return err
+		}
+		if err := validateWorkingDirectory(fmt.Sprintf("job '%s'", jobName), job.WorkingDirectory); err != nil {
+			return err
+		}
+		if job.TimeoutMinutes < 0 {
+			return fmt.Errorf("job '%s' timeout-minutes must be positive", jobName)
+		}
+		if err := validateResources(jobName, job.Resources); err != nil {
+			return err
+		}
+		if _, err := models.ParseGPUs(job.GPUs); err != nil {
+			return fmt.Errorf("job '%s': %w", jobName, err)
+		}
+		if err := validateVolumes(jobName, job.Volumes); err != nil {
+			return err
+		}
+		if err := validateTmpfs(jobName, job.Tmpfs, job.Volumes); err != nil {
+			return err
+		}
+		switch job.Isolation {
+		case "", models.IsolationContainer:
+		case models.IsolationVM:
+			// Firecracker can't pass devices such as GPUs through to a VM
+			if job.GPUs != "" {
+				return fmt.Errorf("job '%s' can't have gpus with isolation vm", jobName)
+			}
+		default:
+			return fmt.Errorf("job '%s' has an invalid isolation '%s' (expected container or vm)", jobName, job.Isolation)
+		}
+		if job.Checkout != nil {
+			if err := validateCheckout(jobName, job.Checkout); err != nil {
+				return err
+			}
+		}
+		if err := validateJobArtifacts(jobName, job.Artifacts); err != nil {
+			return err
+		}
+		if err := validateCacheVolumes(jobName, job.CacheVolumes); err != nil {
+			return err
+		}
+		if err := models.ValidatePullPolicy(job.PullPolicy); err != nil {
+			return fmt.Errorf("job '%s': %w", jobName, err)
+		}
+		if job.Container != nil {
+			if err := validateContainer(jobName, job.Container); err != nil {
+				return err
+			}
+		}
+		if err := expr.ValidateTemplate(job.User); err != nil {
+			return fmt.Errorf("job '%s' has an invalid user: %w", jobName, err)
+		}
+		if job.User != "" && job.Container != nil && job.Container.User != "" {
+			return fmt.Errorf("job '%s' sets both user and container.user", jobName)
+		}
+		for name, svc := range job.Services {
+			if err := validateService(jobName, name, svc); err != nil {
+				return err
+			}
+		}
+
+		if job.If != "" {
+			if _, err := expr.ParseCondition(job.If); err != nil {
+				return fmt.Errorf("job '%s' has an invalid if condition: %w", jobName, err)
+			}
+		}
+
+		if job.Strategy != nil {
+			if err := validateMatrix(jobName, &job.Strategy.Matrix); err != nil {
+				return err
+			}
+			if job.Strategy.MaxParallel < 0 {
+				return fmt.Errorf("job '%s' strategy max-parallel must not be negative", jobName)
+			}
+		}
+
+		for _, need := range job.Needs {
+			if need == jobName {
+				return fmt.Errorf("job '%s' cannot depend on itself", jobName)
+			}
+			if _, exists := wf.Jobs[need]; !exists {
+				return fmt.Errorf("job '%s' needs unknown job '%s'", jobName, need)
+			}
+		}
+	}
+
+	return validateDependencies(wf)
+}
+
+// validateContainer checks a job's container image reference and options
+func validateContainer(jobName string, c *models.Container) error {
+	if err := validateImage(fmt.Sprintf("job '%s' container", jobName), c.Image); err != nil {
+		return err
+	}
+
+	if err := expr.ValidateTemplate(c.User); err != nil {
+		return fmt.Errorf("job '%s' has an invalid container user: %w", jobName, err)
+	}
+	for _, arg := range c.Entrypoint {
+		if err := expr.ValidateTemplate(arg); err != nil {
+			return fmt.Errorf("job '%s' has an invalid container entrypoint: %w", jobName, err)
+		}
+	}
+	if err := validateCredentials(fmt.Sprintf("job '%s' container", jobName), c.Credentials); err != nil {
+		return err
+	}
+	return validateEnv(fmt.Sprintf("job '%s' container", jobName), c.Env)
+}
+
+// validateCredentials checks registry credentials, whose password must come
+// from a secret
+func validateCredentials(scope string, r *models.RegistryCredentials) error {
+	if r == nil {
+		return nil
+	}
+	if r.Username == "" || r.Password == "" {
+		return fmt.Errorf("%s credentials must set both username and password", scope)
+	}
+	for _, value := range []string{r.Username, r.Password} {
+		if err := expr.ValidateTemplate(value); err != nil {
+			return fmt.Errorf("%s has invalid credentials: %w", scope, err)
+		}
+	}
+	if !expr.HasExpressions(r.Password) {
+		return fmt.Errorf("%s credentials password must refer to a secret, e.g. ${{ secrets.REGISTRY_PASSWORD }}", scope)
+	}
+	return nil
+}
+
+// minMemory is the smallest memory limit Docker accepts
+const minMemory = 6 * 1024 * 1024
+
+// validateVolumes checks the syntax of a job's volumes; whether a host path
+// may be mounted is up to the server
+func validateVolumes(jobName string, volumes []string) error {
+	targets := make(map[string]bool, len(volumes))
+	for _, spec := range volumes {
+		v, err := models.ParseVolume(spec)
+		if err != nil {
+			return fmt.Errorf("job '%s' volumes: %w", jobName, err)
+		}
+		if targets[v.Target] {
+			return fmt.Errorf("job '%s' mounts more than one volume at '%s'", jobName, v.Target)
+		}
+		targets[v.Target] = true
+	}
+	return nil
+}
+
+// validateTmpfs checks a job's tmpfs mounts, which mustn't share a path
+// with each other or with its volumes
+func validateTmpfs(jobName string, tmpfs, volumes []string) error {
+	targets := make(map[string]bool, len(volumes))
+	for _, spec := range volumes {
+		if v, err := models.ParseVolume(spec); err == nil {
+			targets[v.Target] = true
+		}
+	}
+	for _, spec := range tmpfs {
+		t, err := models.ParseTmpfs(spec)
+		if err != nil {
+			return fmt.Errorf("job '%s' tmpfs: %w", jobName, err)
+		}
+		if targets[t.Path] {
+			return fmt.Errorf("job '%s' mounts more than one volume or tmpfs at '%s'", jobName, t.Path)
+		}
+		targets[t.Path] = true
+	}
+	return nil
+}
+
+// validateCheckout checks a job's checkout. Credentials must come from
+// secrets so they are never stored in the workflow.
+func validateCheckout(jobName string, c *models.Checkout) error {
+	fields := []struct{ name, value string }{
+		{"repository", c.Repository}, {"ref", c.Ref}, {"path", c.Path}, {"token", c.Token}, {"ssh-key", c.SSHKey},
+	}
+	for _, f := range fields {
+		if err := expr.ValidateTemplate(f.value); err != nil {
+			return fmt.Errorf("job '%s' checkout has an invalid %s: %w", jobName, f.name, err)
+		}
+		credential := f.name == "token" || f.name == "ssh-key"
+		if credential && f.value != "" && !expr.HasExpressions(f.value) {
+			return fmt.Errorf("job '%s' checkout %s must refer to a secret, e.g. ${{ secrets.REPO_TOKEN }}", jobName, f.name)
+		}
+	}
+	if c.Token != "" && c.SSHKey != "" {
+		return fmt.Errorf("job '%s' checkout sets both token and ssh-key", jobName)
+	}
+	if c.Depth < 0 {
+		return fmt.Errorf("job '%s' checkout depth must not be negative", jobName)
+	}
+	if c.Path != "" && !expr.HasExpressions(c.Path) {
+		if cleaned := path.Clean(c.Path); path.IsAbs(c.Path) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+			return fmt.Errorf("job '%s' checkout path '%s' must stay inside the workspace", jobName, c.Path)
+		}
+	}
+	return nil
+}
+
+// validateJobArtifacts checks the artifacts a job collects when it finishes
+func validateJobArtifacts(jobName string, list []models.ArtifactStep) error {
+	names := make(map[string]bool, len(list))
+	for _, a := range list {
+		switch {
+		case a.Name == "":
+			return fmt.Errorf("job '%s' has an artifact without a name", jobName)
+		case a.Path == "":
+			return fmt.Errorf("job '%s' artifact '%s' is missing a path", jobName, a.Name)
+		case names[a.Name]:
+			return fmt.Errorf("job '%s' collects artifact '%s' more than once", jobName, a.Name)
+		}
+		names[a.Name] = true
+
+		if err := expr.ValidateTemplate(a.Name); err != nil {
+			return fmt.Errorf("job '%s' has an invalid artifact name: %w", jobName, err)
+		}
+		if !expr.HasExpressions(a.Name) {
+			if err := artifacts.ValidateName(a.Name); err != nil {
+				return fmt.Errorf("job '%s' artifacts: %w", jobName, err)
+			}
+		}
+		if err := expr.ValidateTemplate(a.Path); err != nil {
+			return fmt.Errorf("job '%s' artifact '%s' has an invalid path: %w", jobName, a.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateCacheVolumes checks the keys and mount paths of a job's cache volumes
+func validateCacheVolumes(jobName string, volumes []models.CacheVolume) error {
+	paths := make(map[string]bool, len(volumes))
+	for _, v := range volumes {
+		if v.Key == "" {
+			return fmt.Errorf("job '%s' has a cache volume without a key", jobName)
+		}
+		if err := expr.ValidateTemplate(v.Key); err != nil {
+			return fmt.Errorf("job '%s' has an invalid cache volume key: %w", jobName, err)
+		}
+		if !expr.HasExpressions(v.Key) {
+			if err := cache.ValidateKey(v.Key); err != nil {
+				return fmt.Errorf("job '%s' cache volume: %w", jobName, err)
+			}
+		}
+
+		if v.Path == "" {
+			return fmt.Errorf("job '%s' cache volume '%s' is missing a path", jobName, v.Key)
+		}
+		if err := expr.ValidateTemplate(v.Path); err != nil {
+			return fmt.Errorf("job '%s' cache volume '%s' has an invalid path: %w", jobName, v.Key, err)
+		}
+		if paths[v.Path] {
+			return fmt.Errorf("job '%s' mounts more than one cache volume at '%s'", jobName, v.Path)
+		}
+		paths[v.Path] = true
+	}
+	return nil
+}
+
+// validateResources checks a job's CPU and memory limits
+func validateResources(jobName string, r *models.Resources) error {
+	if _, err := r.NanoCPUs(); err != nil {
+		return fmt.Errorf("job '%s' resources: %w", jobName, err)
+	}
+	memory, err := r.MemoryBytes()
+	if err != nil {
+		return fmt.Errorf("job '%s' resources: %w", jobName, err)
+	}
+	if memory > 0 && memory < minMemory {
+		return fmt.Errorf("job '%s' resources memory must be at least 6m", jobName)
+	}
+	if _, err := r.DiskBytes(); err != nil {
+		return fmt.Errorf("job '%s' resources: %w", jobName, err)
+	}
+	return nil
+}
+
+// validateMatrix checks that a matrix has values for every dimension, that
+// exclude entries only name declared dimensions and values, and that at least
+// one combination is left to run
+func validateMatrix(jobName string, m *models.Matrix) error {
+	if m.Empty() {
+		return fmt.Errorf("job '%s' strategy must define a matrix", jobName)
+	}
+	for _, key := range m.Order {
+		if len(m.Dimensions[key]) == 0 {
+			return fmt.Errorf("job '%s' matrix '%s' must have at least one value", jobName, key)
+		}
+	}
+
+	for i, entry := range m.Include {
+		if len(entry) == 0 {
+			return fmt.Errorf("job '%s' matrix include entry %d must set at least one value", jobName, i+1)
+		}
+	}
+	for i, entry := range m.Exclude {
+		if len(entry) == 0 {
+			return fmt.Errorf("job '%s' matrix exclude entry %d must set at least one value", jobName, i+1)
+		}
+		keys := make([]string, 0, len(entry))
+		for key := range entry {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			values, declared := m.Dimensions[key]
+			if !declared {
+				return fmt.Errorf("job '%s' matrix exclude entry %d uses unknown dimension '%s'", jobName, i+1, key)
+			}
+			if !containsString(values, entry[key]) {
+				return fmt.Errorf("job '%s' matrix exclude entry %d: '%s' isn't a value of '%s'", jobName, i+1, entry[key], key)
+			}
+		}
+	}
+
+	combos := m.Combinations()
+	if len(combos) == 0 {
+		return fmt.Errorf("job '%s' matrix excludes every combination", jobName)
+	}
+	for i := range combos {
+		for j := 0; j < i; j++ {
+			if reflect.DeepEqual(combos[i], combos[j]) {
+				return fmt.Errorf("job '%s' matrix includes the combination %v more than once", jobName, combos[i])
+			}
+		}
+	}
+	return nil
+}
+
+// validateService checks a service's name, image and healthcheck
+func validateService(jobName, name string, svc models.Service) error {
+	scope := fmt.Sprintf("job '%s' service '%s'", jobName, name)
+
+	if !serviceNamePattern.MatchString(name) {
+		return fmt.Errorf("job '%s' has an invalid service name '%s'", jobName, name)
+	}
+	if err := validateImage(scope, svc.Image); err != nil {
+		return err
+	}
+	for _, arg := range svc.Command {
+		if err := expr.ValidateTemplate(arg); err != nil {
+			return fmt.Errorf("%s has an invalid command: %w", scope, err)
+		}
+	}
+	if err := validateEnv(scope, svc.Env); err != nil {
+		return err
+	}
+	if err := validateCredentials(scope, svc.Credentials); err != nil {
+		return err
+	}
+
+	if h := svc.Healthcheck; h != nil {
+		switch {
+		case h.Run == "" && h.Port == 0:
+			return fmt.Errorf("%s healthcheck needs run or port", scope)
+		case h.Run != "" && h.Port != 0:
+			return fmt.Errorf("%s healthcheck cannot set both run and port", scope)
+		case h.Port < 0 || h.Port > 65535:
+			return fmt.Errorf("%s has an invalid healthcheck port %d", scope, h.Port)
+		}
+		if h.Interval != "" {
+			if _, err := time.ParseDuration(h.Interval); err != nil {
+				return fmt.Errorf("%s has an invalid healthcheck interval: %w", scope, err)
+			}
+		}
+		if h.Timeout != "" {
+			if d, err := time.ParseDuration(h.Timeout); err != nil {
+				return fmt.Errorf("%s has an invalid healthcheck timeout: %w", scope, err)
+			} else if d <= 0 {
+				return fmt.Errorf("%s healthcheck timeout must be positive", scope)
+			}
+		}
+		if h.Retries < 0 {
+			return fmt.Errorf("%s healthcheck retries cannot be negative", scope)
+		}
+	}
+	return nil
+}
+
+// validateCall checks a job that runs another workflow. The called workflow
+// is looked up when the run starts, since it may be uploaded later.
+func validateCall(wf *models.Workflow, jobName string, job models.Job) error {
+	switch {
+	case job.Uses == wf.Name:
+		return fmt.Errorf("job '%s' cannot call its own workflow", jobName)
+	case len(job.Steps) > 0:
+		return fmt.Errorf("job '%s' cannot have both uses and steps", jobName)
+	case job.Container != nil || len(job.Services) > 0:
+		return fmt.Errorf("job '%s' uses a workflow and cannot set container or services", jobName)
+	case job.Strategy != nil:
+		return fmt.Errorf("job '%s' uses a workflow and cannot set a strategy", jobName)
+	}
+
+	for name, value := range job.With {
+		if err := expr.ValidateTemplate(value); err != nil {
+			return fmt.Errorf("job '%s' with '%s' is invalid: %w", jobName, name, err)
+		}
+	}
+	return nil
+}
+
+// validateShell checks that a step's shell is known and, for jobs without a
+// container, available in the runs-on runner's image. Images named directly
+// in runs-on are only checked once the job runs.
+func (p *Parser) validateShell(jobName string, job models.Job, step models.Step) error {
+	if step.Shell == "" {
+		return nil
+	}
+	if !stepShells[step.Shell] {
+		return fmt.Errorf("job '%s' step '%s' has unknown shell '%s' (expected sh, bash, python or node)", jobName, step.Name, step.Shell)
+	}
+	if job.Container != nil || expr.HasExpressions(job.RunsOn) {
+		return nil
+	}
+
+	runner, ok := p.runners.Get(job.RunsOn)
+	if ok && !runner.HasShell(step.Shell) {
+		return fmt.Errorf("job '%s' step '%s' uses shell '%s', which the %s image does not provide; use a container image that does",
+			jobName, step.Name, step.Shell, runner.Label)
+	}
+	return nil
+}
+
+// validateLanguage checks that a step's language is known and not combined
+// with a shell. Like shells, languages are checked against the runs-on
+// runner's image for jobs without a container.
+func (p *Parser) validateLanguage(jobName string, job models.Job, step models.Step) error {
+	if step.Language == "" {
+		return nil
+	}
+	if !stepLanguages[step.Language] {
+		return fmt.Errorf("job '%s' step '%s' has unknown language '%s' (expected python, node or deno)", jobName, step.Name, step.Language)
+	}
+	if step.Shell != "" {
+		return fmt.Errorf("job '%s' step '%s' can't set both shell and language", jobName, step.Name)
+	}
+	if job.Container != nil || expr.HasExpressions(job.RunsOn) {
+		return nil
+	}
+
+	runner, ok := p.runners.Get(job.RunsOn)
+	if ok && !runner.HasShell(step.Language) {
+		return fmt.Errorf("job '%s' step '%s' uses language '%s', which the %s image does not provide; use a container image that does",
+			jobName, step.Name, step.Language, runner.Label)
+	}
+	return nil
+}
+
+// validateStepReferences checks that a step's templates and condition only
+// reference steps.<id> of earlier steps in its job
+func validateStepReferences(jobName string, step models.Step, earlier map[string]bool) error {
+	var refs []string
+	for _, t := range step.Templates() {
+		r, err := expr.TemplateReferences(t, "steps")
+		if err != nil {
+			return fmt.Errorf("job '%s' step '%s' has an invalid expression: %w", jobName, step.Name, err)
+		}
+		refs = append(refs, r...)
+	}
+	if step.If != "" {
+		e, err := expr.ParseCondition(step.If)
+		if err != nil {
+			return fmt.Errorf("job '%s' step '%s' has an invalid if condition: %w", jobName, step.Name, err)
+		}
+		refs = append(refs, e.References("steps")...)
+	}
+
+	for _, id := range refs {
+		if !earlier[id] {
+			return fmt.Errorf("job '%s' step '%s' references unknown step '%s' (only earlier steps with an id can be referenced)", jobName, step.Name, id)
+		}
+	}
+	return nil
+}
+
+// validateWorkingDirectory checks a working-directory. Relative paths must
+// stay inside the workspace.
+func validateWorkingDirectory(scope, dir string) error {
+	if err := expr.ValidateTemplate(dir); err != nil {
+		return fmt.Errorf("%s has an invalid working-directory: %w", scope, err)
+	}
+	if dir == "" || path.IsAbs(dir) || expr.HasExpressions(dir) {
+		return nil
+	}
+	if cleaned := path.Clean(dir); cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return fmt.Errorf("%s working-directory '%s' must stay inside the workspace", scope, dir)
+	}
+	return nil
+}
+
+// validateBuiltinStep checks a step that uploads or downloads an artifact,
+// or restores a cache, in place of running a script
+func validateBuiltinStep(jobName string, step models.Step) error {
+	scope := fmt.Sprintf("job '%s' step '%s'", jobName, step.Name)
+
+	kinds := 0
+	for _, set := range []bool{step.UploadArtifact != nil, step.DownloadArtifact != nil, step.Cache != nil, step.Build != nil} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds > 1 {
+		return fmt.Errorf("%s can only use one of upload-artifact, download-artifact, cache and build", scope)
+	}
+	if step.Run != "" || step.Shell != "" || step.Language != "" || step.Retry != nil {
+		return fmt.Errorf("%s can't combine run, shell, language or retry with a built-in step", scope)
+	}
+
+	if c := step.Cache; c != nil {
+		return validateCacheStep(scope, c)
+	}
+	if b := step.Build; b != nil {
+		return validateBuildStep(scope, b)
+	}
+
+	keyword, a := "upload-artifact", step.UploadArtifact
+	if a == nil {
+		keyword, a = "download-artifact", step.DownloadArtifact
+	}
+	if a.Name == "" {
+		return fmt.Errorf("%s %s is missing a name", scope, keyword)
+	}
+	if err := expr.ValidateTemplate(a.Name); err != nil {
+		return fmt.Errorf("%s has an invalid %s name: %w", scope, keyword, err)
+	}
+	if !expr.HasExpressions(a.Name) {
+		if err := artifacts.ValidateName(a.Name); err != nil {
+			return fmt.Errorf("%s %s: %w", scope, keyword, err)
+		}
+	}
+	if step.UploadArtifact != nil && a.Path == "" {
+		return fmt.Errorf("%s upload-artifact is missing a path", scope)
+	}
+	if err := expr.ValidateTemplate(a.Path); err != nil {
+		return fmt.Errorf("%s has an invalid %s path: %w", scope, keyword, err)
+	}
+	return nil
+}
+
+// validateBuildStep checks a build step's tags and that its registry
+// password comes from a secret
+func validateBuildStep(scope string, b *models.BuildStep) error {
+	if len(b.Tags) == 0 {
+		return fmt.Errorf("%s build must set at least one tag", scope)
+	}
+	for _, tag := range b.Tags {
+		if err := expr.ValidateTemplate(tag); err != nil {
+			return fmt.Errorf("%s has an invalid build tag: %w", scope, err)
+		}
+		if !expr.HasExpressions(tag) {
+			if _, err := reference.ParseNormalizedNamed(tag); err != nil {
+				return fmt.Errorf("%s build tag '%s' is not a valid image reference: %w", scope, tag, err)
+			}
+		}
+	}
+
+	fields := []struct{ name, value string }{
+		{"context", b.Context}, {"dockerfile", b.Dockerfile}, {"target", b.Target},
+		{"username", b.Username}, {"password", b.Password},
+	}
+	for _, f := range fields {
+		if err := expr.ValidateTemplate(f.value); err != nil {
+			return fmt.Errorf("%s has an invalid build %s: %w", scope, f.name, err)
+		}
+	}
+	for name, value := range b.BuildArgs {
+		if err := expr.ValidateTemplate(value); err != nil {
+			return fmt.Errorf("%s has an invalid build arg '%s': %w", scope, name, err)
+		}
+	}
+	if b.Password != "" && !expr.HasExpressions(b.Password) {
+		return fmt.Errorf("%s build password must refer to a secret, e.g. ${{ secrets.REGISTRY_PASSWORD }}", scope)
+	}
+	if (b.Username == "") != (b.Password == "") {
+		return fmt.Errorf("%s build must set both username and password, or neither", scope)
+	}
+	return nil
+}
+
+// validateCacheStep checks a cache step's path and keys
+func validateCacheStep(scope string, c *models.CacheStep) error {
+	if c.Path == "" {
+		return fmt.Errorf("%s cache is missing a path", scope)
+	}
+	if err := expr.ValidateTemplate(c.Path); err != nil {
+		return fmt.Errorf("%s has an invalid cache path: %w", scope, err)
+	}
+	if c.Key == "" {
+		return fmt.Errorf("%s cache is missing a key", scope)
+	}
+	for _, key := range append([]string{c.Key}, c.RestoreKeys...) {
+		if err := expr.ValidateTemplate(key); err != nil {
+			return fmt.Errorf("%s has an invalid cache key: %w", scope, err)
+		}
+		if !expr.HasExpressions(key) {
+			if err := cache.ValidateKey(key); err != nil {
+				return fmt.Errorf("%s: %w", scope, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateImage checks an image reference. Images built from expressions
+// can only be checked once interpolated.
+func validateImage(scope, image string) error {
+	if image == "" {
+		return fmt.Errorf("%s is missing an image", scope)
+	}
+
+	if expr.HasExpressions(image) {
+		if err := expr.ValidateTemplate(image); err != nil {
+			return fmt.Errorf("%s has an invalid image: %w", scope, err)
+		}
+		return nil
+	}
+	if _, err := reference.ParseNormalizedNamed(image); err != nil {
+		return fmt.Errorf("%s has an invalid image '%s': %w", scope, image, err)
+	}
+	return nil
+}
+
+// validateBranches checks the branch patterns of a trigger
+func validateBranches(trigger string, patterns []string) error {
+	for _, pattern := range patterns {
+		if err := glob.Validate(pattern); err != nil {
+			return fmt.Errorf("%s branches: %w", trigger, err)
+		}
+	}
+	return nil
+}
+
+// validateInput checks a workflow_dispatch input declaration
+func validateInput(name string, input models.Input) error {
+	if !inputNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid input name '%s'", name)
+	}
+
+	switch input.Type {
+	case "", models.InputString:
+	case models.InputBoolean:
+		if input.Default != "" && input.Default != "true" && input.Default != "false" {
+			return fmt.Errorf("input '%s' default must be true or false", name)
+		}
+	case models.InputChoice:
+		if len(input.Options) == 0 {
+			return fmt.Errorf("input '%s' of type choice must list options", name)
+		}
+		if input.Default != "" && !containsString(input.Options, input.Default) {
+			return fmt.Errorf("input '%s' default '%s' is not one of its options", name, input.Default)
+		}
+	default:
+		return fmt.Errorf("input '%s' has unknown type '%s' (expected string, boolean or choice)", name, input.Type)
+	}
+
+	if len(input.Options) > 0 && input.Type != models.InputChoice {
+		return fmt.Errorf("input '%s' can only list options when its type is choice", name)
+	}
+	return nil
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// validateEnv checks env variable names and the expressions in their values
+func validateEnv(scope string, env map[string]string) error {
+	for name, value := range env {
+		if !envNamePattern.MatchString(name) {
+			return fmt.Errorf("%s env has an invalid variable name '%s'", scope, name)
+		}
+		if err := expr.ValidateTemplate(value); err != nil {
+			return fmt.Errorf("%s env '%s' is invalid: %w", scope, name, err)
+		}
+	}
+	return nil
+}
+
+// validateDependencies ensures the `needs` graph is acyclic
+func validateDependencies(wf *models.Workflow) error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	names := make([]string, 0, len(wf.Jobs))
+	for name := range wf.Jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	state := make(map[string]int, len(wf.Jobs))
+	var path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			// Trim the path down to the start of the cycle for a readable error
+			start := 0
+			for i, n := range path {
+				if n == name {
+					start = i
+					break
+				}
+			}
+			cycle := append(append([]string{}, path[start:]...), name)
+			return fmt.Errorf("job dependency cycle detected: %s", strings.Join(cycle, " -> "))
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, need := range wf.Jobs[name].Needs {
+			if err := visit(need); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return err
 		}
 	}
 
@@ -1,9 +1,12 @@
 package parser
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"gantry/internal/models"
+	"gantry/internal/runners"
 )
 
 func TestParse_ValidWorkflow(t *testing.T) {
@@ -179,3 +182,1572 @@ func TestValidate_StepMissingRun(t *testing.T) {
 		t.Error("Expected error for step with no run command, got nil")
 	}
 }
+
+func TestParse_Needs(t *testing.T) {
+	yaml := `
+name: DAG
+jobs:
+  build:
+    runs-on: ubuntu
+    steps:
+      - name: Build
+        run: echo build
+  lint:
+    runs-on: ubuntu
+    needs: build
+    steps:
+      - name: Lint
+        run: echo lint
+  test:
+    runs-on: ubuntu
+    needs: [build, lint]
+    steps:
+      - name: Test
+        run: echo test
+`
+
+	p := NewParser()
+	wf, err := p.Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if got := wf.Jobs["lint"].Needs; len(got) != 1 || got[0] != "build" {
+		t.Errorf("Expected lint to need [build], got %v", got)
+	}
+
+	if got := wf.Jobs["test"].Needs; len(got) != 2 || got[0] != "build" || got[1] != "lint" {
+		t.Errorf("Expected test to need [build lint], got %v", got)
+	}
+
+	if err := p.Validate(wf); err != nil {
+		t.Errorf("Expected valid workflow, got: %v", err)
+	}
+}
+
+func TestValidate_UnknownNeed(t *testing.T) {
+	wf := &models.Workflow{
+		Name: "Test",
+		Jobs: map[string]models.Job{
+			"test": {
+				RunsOn: "ubuntu",
+				Needs:  models.StringList{"build"},
+				Steps:  []models.Step{{Name: "Test", Run: "echo test"}},
+			},
+		},
+	}
+
+	p := NewParser()
+	if err := p.Validate(wf); err == nil {
+		t.Error("Expected error for unknown dependency, got nil")
+	}
+}
+
+func TestValidate_DependencyCycle(t *testing.T) {
+	step := []models.Step{{Name: "Step", Run: "echo step"}}
+	wf := &models.Workflow{
+		Name: "Test",
+		Jobs: map[string]models.Job{
+			"a": {RunsOn: "ubuntu", Needs: models.StringList{"c"}, Steps: step},
+			"b": {RunsOn: "ubuntu", Needs: models.StringList{"a"}, Steps: step},
+			"c": {RunsOn: "ubuntu", Needs: models.StringList{"b"}, Steps: step},
+		},
+	}
+
+	p := NewParser()
+	err := p.Validate(wf)
+	if err == nil {
+		t.Fatal("Expected error for dependency cycle, got nil")
+	}
+
+	if !strings.Contains(err.Error(), "a -> c -> b -> a") {
+		t.Errorf("Expected cycle path in error, got: %v", err)
+	}
+}
+
+func TestValidate_SelfDependency(t *testing.T) {
+	wf := &models.Workflow{
+		Name: "Test",
+		Jobs: map[string]models.Job{
+			"test": {
+				RunsOn: "ubuntu",
+				Needs:  models.StringList{"test"},
+				Steps:  []models.Step{{Name: "Test", Run: "echo test"}},
+			},
+		},
+	}
+
+	p := NewParser()
+	if err := p.Validate(wf); err == nil {
+		t.Error("Expected error for self dependency, got nil")
+	}
+}
+
+func TestParse_MatrixStrategy(t *testing.T) {
+	yaml := `
+name: Matrix
+jobs:
+  test:
+    runs-on: ubuntu
+    strategy:
+      fail-fast: false
+      max-parallel: 1
+      matrix:
+        go-version: [1.20, 1.21]
+    steps:
+      - name: Test
+        run: echo go ${{ matrix.go-version }}
+`
+
+	p := NewParser()
+	wf, err := p.Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	strategy := wf.Jobs["test"].Strategy
+	if strategy == nil {
+		t.Fatal("Expected strategy to be parsed")
+	}
+
+	if got := strategy.Matrix.Dimensions["go-version"]; len(got) != 2 || got[0] != "1.20" {
+		t.Errorf("Expected go-version [1.20 1.21], got %v", got)
+	}
+	if strategy.FailsFast() || strategy.MaxParallel != 1 {
+		t.Errorf("Expected fail-fast false and max-parallel 1, got %v and %d", strategy.FailsFast(), strategy.MaxParallel)
+	}
+
+	if err := p.Validate(wf); err != nil {
+		t.Errorf("Expected valid workflow, got: %v", err)
+	}
+}
+
+func TestValidate_EmptyMatrixDimension(t *testing.T) {
+	wf := &models.Workflow{
+		Name: "Test",
+		Jobs: map[string]models.Job{
+			"test": {
+				RunsOn: "ubuntu",
+				Strategy: &models.Strategy{Matrix: models.Matrix{
+					Order:      []string{"os"},
+					Dimensions: map[string][]string{"os": {}},
+				}},
+				Steps: []models.Step{{Name: "Test", Run: "echo test"}},
+			},
+		},
+	}
+
+	p := NewParser()
+	if err := p.Validate(wf); err == nil {
+		t.Error("Expected error for empty matrix dimension, got nil")
+	}
+}
+
+func TestParse_MatrixIncludeExclude(t *testing.T) {
+	yaml := `
+name: Matrix
+jobs:
+  test:
+    runs-on: ubuntu
+    strategy:
+      matrix:
+        go: [1.20, 1.21]
+        os: [ubuntu, alpine]
+        include:
+          - go: 1.22
+            os: ubuntu
+        exclude:
+          - go: 1.20
+            os: alpine
+    steps:
+      - name: Test
+        run: echo go ${{ matrix.go }}
+`
+
+	p := NewParser()
+	wf, err := p.Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := p.Validate(wf); err != nil {
+		t.Errorf("Expected valid workflow, got: %v", err)
+	}
+
+	m := wf.Jobs["test"].Strategy.Matrix
+	if len(m.Order) != 2 || len(m.Include) != 1 || len(m.Exclude) != 1 {
+		t.Errorf("Expected two dimensions with one include and one exclude, got %+v", m)
+	}
+
+	if _, err := p.Parse([]byte(strings.Replace(yaml, "- go: 1.22", "- go: [1.22]", 1))); err == nil ||
+		!strings.Contains(err.Error(), "jobs.test.strategy.matrix.include[0].go") {
+		t.Errorf("Expected schema error for a list in an include entry, got %v", err)
+	}
+}
+
+func TestValidate_MatrixExclude(t *testing.T) {
+	tests := []struct {
+		name    string
+		matrix  models.Matrix
+		wantErr string
+	}{
+		{"unknown dimension", models.Matrix{Exclude: []map[string]string{{"arch": "arm64"}}}, "unknown dimension 'arch'"},
+		{"unknown value", models.Matrix{Exclude: []map[string]string{{"os": "windows"}}}, "'windows' isn't a value of 'os'"},
+		{"empty entry", models.Matrix{Exclude: []map[string]string{{}}}, "exclude entry 1 must set"},
+		{"every combination", models.Matrix{
+			Exclude: []map[string]string{{"os": "ubuntu"}, {"os": "alpine"}},
+		}, "excludes every combination"},
+		{"duplicate include", models.Matrix{
+			Include: []map[string]string{{"os": "windows"}, {"os": "windows"}},
+		}, "more than once"},
+		{"valid", models.Matrix{
+			Exclude: []map[string]string{{"os": "alpine"}},
+			Include: []map[string]string{{"os": "ubuntu", "arch": "arm64"}},
+		}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.matrix.Order = []string{"os"}
+			tt.matrix.Dimensions = map[string][]string{"os": {"ubuntu", "alpine"}}
+			wf := &models.Workflow{
+				Name: "Test",
+				Jobs: map[string]models.Job{
+					"test": {
+						RunsOn:   "ubuntu",
+						Strategy: &models.Strategy{Matrix: tt.matrix},
+						Steps:    []models.Step{{Name: "Test", Run: "echo test"}},
+					},
+				},
+			}
+
+			err := NewParser().Validate(wf)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected valid matrix, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_InvalidIfCondition(t *testing.T) {
+	wf := &models.Workflow{
+		Name: "Test",
+		Jobs: map[string]models.Job{
+			"test": {
+				RunsOn: "ubuntu",
+				If:     "${{ needs.build.result == }}",
+				Steps:  []models.Step{{Name: "Step 1", Run: "echo test"}},
+			},
+		},
+	}
+
+	p := NewParser()
+	if err := p.Validate(wf); err == nil {
+		t.Error("Expected error for invalid job if condition, got nil")
+	}
+
+	wf.Jobs["test"] = models.Job{
+		RunsOn: "ubuntu",
+		Steps:  []models.Step{{Name: "Step 1", If: "!(", Run: "echo test"}},
+	}
+	if err := p.Validate(wf); err == nil {
+		t.Error("Expected error for invalid step if condition, got nil")
+	}
+}
+
+func TestValidate_InvalidEnv(t *testing.T) {
+	step := []models.Step{{Name: "Step 1", Run: "echo test"}}
+
+	p := NewParser()
+
+	wf := &models.Workflow{
+		Name: "Test",
+		Env:  map[string]string{"NOT-VALID": "x"},
+		Jobs: map[string]models.Job{"test": {RunsOn: "ubuntu", Steps: step}},
+	}
+	if err := p.Validate(wf); err == nil {
+		t.Error("Expected error for invalid env name, got nil")
+	}
+
+	wf.Env = map[string]string{"VALID": "${{ matrix.os"}
+	if err := p.Validate(wf); err == nil {
+		t.Error("Expected error for unterminated expression in env, got nil")
+	}
+}
+
+func TestValidate_Variables(t *testing.T) {
+	step := []models.Step{{Name: "Step 1", Run: "echo ${{ vars.IMAGE_TAG }}"}}
+
+	p := NewParser()
+
+	wf := &models.Workflow{
+		Name:      "Test",
+		Variables: map[string]string{"IMAGE_TAG": "latest"},
+		Jobs:      map[string]models.Job{"test": {RunsOn: "ubuntu", Steps: step}},
+	}
+	if err := p.Validate(wf); err != nil {
+		t.Errorf("Expected valid variables, got %v", err)
+	}
+
+	wf.Variables = map[string]string{"IMAGE-TAG": "latest"}
+	if err := p.Validate(wf); err == nil {
+		t.Error("Expected error for invalid variable name, got nil")
+	}
+
+	wf.Variables = map[string]string{"IMAGE_TAG": "${{ gantry.run_id }}"}
+	if err := p.Validate(wf); err == nil {
+		t.Error("Expected error for expression in variable value, got nil")
+	}
+}
+
+func TestValidate_InvalidRunExpression(t *testing.T) {
+	wf := &models.Workflow{
+		Name: "Test",
+		Jobs: map[string]models.Job{
+			"test": {
+				RunsOn: "ubuntu",
+				Steps:  []models.Step{{Name: "Step 1", Run: "echo ${{ contains( }}"}},
+			},
+		},
+	}
+
+	p := NewParser()
+	if err := p.Validate(wf); err == nil {
+		t.Error("Expected error for invalid expression in run, got nil")
+	}
+}
+
+func TestParse_Container(t *testing.T) {
+	yaml := `
+name: Container
+jobs:
+  build:
+    runs-on: ubuntu
+    container:
+      image: ghcr.io/acme/builder:1.4
+      entrypoint: [/usr/bin/tini, --]
+      user: "1000:1000"
+      env:
+        CGO_ENABLED: "0"
+    steps:
+      - name: Build
+        run: make
+  lint:
+    runs-on: ubuntu
+    container: golangci/golangci-lint
+    steps:
+      - name: Lint
+        run: golangci-lint run
+`
+
+	p := NewParser()
+	wf, err := p.Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := p.Validate(wf); err != nil {
+		t.Fatalf("Expected valid workflow, got: %v", err)
+	}
+
+	c := wf.Jobs["build"].Container
+	if c == nil || c.Image != "ghcr.io/acme/builder:1.4" || c.User != "1000:1000" {
+		t.Fatalf("Unexpected container: %+v", c)
+	}
+	if len(c.Entrypoint) != 2 || c.Env["CGO_ENABLED"] != "0" {
+		t.Errorf("Unexpected container entrypoint or env: %+v", c)
+	}
+
+	if lint := wf.Jobs["lint"].Container; lint == nil || lint.Image != "golangci/golangci-lint" {
+		t.Errorf("Expected shorthand container image, got %+v", lint)
+	}
+}
+
+func TestValidate_InvalidContainerImage(t *testing.T) {
+	step := []models.Step{{Name: "Step 1", Run: "echo test"}}
+	p := NewParser()
+
+	for _, image := range []string{"", "Not A/Valid:Image", "node:tag:extra"} {
+		wf := &models.Workflow{
+			Name: "Test",
+			Jobs: map[string]models.Job{
+				"test": {RunsOn: "ubuntu", Container: &models.Container{Image: image}, Steps: step},
+			},
+		}
+		if err := p.Validate(wf); err == nil {
+			t.Errorf("Expected error for container image %q, got nil", image)
+		}
+	}
+
+	wf := &models.Workflow{
+		Name: "Test",
+		Jobs: map[string]models.Job{
+			"test": {RunsOn: "ubuntu", Container: &models.Container{Image: "node:${{ matrix.node }}"}, Steps: step},
+		},
+	}
+	if err := p.Validate(wf); err != nil {
+		t.Errorf("Expected image with expressions to be accepted, got: %v", err)
+	}
+}
+
+func TestParse_Services(t *testing.T) {
+	yaml := `
+name: Services
+jobs:
+  test:
+    runs-on: ubuntu
+    services:
+      postgres:
+        image: postgres:16
+        env:
+          POSTGRES_PASSWORD: postgres
+        healthcheck:
+          run: pg_isready -U postgres
+          interval: 1s
+          retries: 10
+      redis: redis:7
+      mysql:
+        image: mysql:8
+        healthcheck:
+          port: 3306
+          timeout: 2m
+    steps:
+      - name: Test
+        run: psql -h $POSTGRES_HOST -U postgres -c 'select 1'
+`
+
+	p := NewParser()
+	wf, err := p.Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := p.Validate(wf); err != nil {
+		t.Fatalf("Expected valid workflow, got: %v", err)
+	}
+
+	services := wf.Jobs["test"].Services
+	pg := services["postgres"]
+	if pg.Image != "postgres:16" || pg.Env["POSTGRES_PASSWORD"] != "postgres" {
+		t.Errorf("Unexpected postgres service: %+v", pg)
+	}
+	if pg.Healthcheck == nil || pg.Healthcheck.Run != "pg_isready -U postgres" || pg.Healthcheck.Retries != 10 {
+		t.Errorf("Unexpected postgres healthcheck: %+v", pg.Healthcheck)
+	}
+	if h := services["mysql"].Healthcheck; h == nil || h.Port != 3306 || h.Timeout != "2m" {
+		t.Errorf("Unexpected mysql healthcheck: %+v", h)
+	}
+	if services["redis"].Image != "redis:7" {
+		t.Errorf("Expected shorthand redis image, got %+v", services["redis"])
+	}
+}
+
+func TestValidate_InvalidService(t *testing.T) {
+	step := []models.Step{{Name: "Step 1", Run: "echo test"}}
+	p := NewParser()
+
+	tests := map[string]map[string]models.Service{
+		"invalid name":     {"my db": {Image: "postgres"}},
+		"missing image":    {"db": {}},
+		"invalid image":    {"db": {Image: "Postgres:16"}},
+		"missing health":   {"db": {Image: "postgres", Healthcheck: &models.Healthcheck{}}},
+		"invalid interval": {"db": {Image: "postgres", Healthcheck: &models.Healthcheck{Run: "true", Interval: "soon"}}},
+		"run and port":     {"db": {Image: "postgres", Healthcheck: &models.Healthcheck{Run: "true", Port: 5432}}},
+		"invalid port":     {"db": {Image: "postgres", Healthcheck: &models.Healthcheck{Port: 70000}}},
+		"invalid timeout":  {"db": {Image: "postgres", Healthcheck: &models.Healthcheck{Port: 5432, Timeout: "soon"}}},
+		"zero timeout":     {"db": {Image: "postgres", Healthcheck: &models.Healthcheck{Port: 5432, Timeout: "0s"}}},
+	}
+
+	for name, services := range tests {
+		wf := &models.Workflow{
+			Name: "Test",
+			Jobs: map[string]models.Job{"test": {RunsOn: "ubuntu", Services: services, Steps: step}},
+		}
+		if err := p.Validate(wf); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
+
+func TestParse_WorkflowDispatchInputs(t *testing.T) {
+	yaml := `
+name: Deploy
+on:
+  workflow_dispatch:
+    inputs:
+      environment:
+        type: choice
+        options: [staging, production]
+        default: staging
+      dry-run:
+        type: boolean
+        default: true
+      version:
+        description: Version to deploy
+        required: true
+jobs:
+  deploy:
+    runs-on: ubuntu
+    steps:
+      - name: Deploy
+        run: ./deploy.sh ${{ inputs.environment }}
+`
+
+	p := NewParser()
+	wf, err := p.Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := p.Validate(wf); err != nil {
+		t.Fatalf("Expected valid workflow, got: %v", err)
+	}
+
+	inputs := wf.On.WorkflowDispatch.Inputs
+	if env := inputs["environment"]; env.Type != models.InputChoice || len(env.Options) != 2 || env.Default != "staging" {
+		t.Errorf("Unexpected environment input: %+v", env)
+	}
+	if inputs["dry-run"].Default != "true" {
+		t.Errorf("Expected boolean default 'true', got %q", inputs["dry-run"].Default)
+	}
+	if !inputs["version"].Required {
+		t.Error("Expected version input to be required")
+	}
+}
+
+func TestValidate_InvalidInputs(t *testing.T) {
+	step := []models.Step{{Name: "Step 1", Run: "echo test"}}
+	p := NewParser()
+
+	tests := map[string]models.Input{
+		"unknown type":      {Type: "number"},
+		"choice no options": {Type: models.InputChoice},
+		"choice bad default": {
+			Type: models.InputChoice, Options: []string{"a", "b"}, Default: "c",
+		},
+		"boolean bad default": {Type: models.InputBoolean, Default: "yes"},
+		"options on string":   {Options: []string{"a"}},
+	}
+
+	for name, input := range tests {
+		wf := &models.Workflow{
+			Name: "Test",
+			On: models.TriggerConfig{WorkflowDispatch: &models.DispatchConfig{
+				Inputs: map[string]models.Input{"value": input},
+			}},
+			Jobs: map[string]models.Job{"test": {RunsOn: "ubuntu", Steps: step}},
+		}
+		if err := p.Validate(wf); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
+
+func TestValidate_IssueCommentCommands(t *testing.T) {
+	step := []models.Step{{Name: "Step 1", Run: "echo test"}}
+	p := NewParser()
+
+	wf := &models.Workflow{
+		Name: "Test",
+		On:   models.TriggerConfig{IssueComment: &models.CommentConfig{Commands: []string{"/retest", "/run-e2e"}}},
+		Jobs: map[string]models.Job{"test": {RunsOn: "ubuntu", Steps: step}},
+	}
+	if err := p.Validate(wf); err != nil {
+		t.Errorf("Expected valid commands, got: %v", err)
+	}
+
+	for _, command := range []string{"retest", "/re test", "/"} {
+		wf.On.IssueComment.Commands = []string{command}
+		if err := p.Validate(wf); err == nil {
+			t.Errorf("Expected error for command %q, got nil", command)
+		}
+	}
+}
+
+func TestParse_WebhookTrigger(t *testing.T) {
+	p := NewParser()
+	wf, err := p.Parse([]byte(`
+name: Release
+on:
+  webhook:
+    secret: RELEASE_HOOK
+jobs:
+  test:
+    runs-on: ubuntu
+    steps:
+      - name: Tag
+        run: echo ${{ gantry.payload.tag }}
+`))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if wf.On.Webhook == nil || wf.On.Webhook.Secret != "RELEASE_HOOK" {
+		t.Fatalf("Unexpected webhook config: %+v", wf.On.Webhook)
+	}
+	if err := p.Validate(wf); err != nil {
+		t.Errorf("Expected a valid webhook trigger, got: %v", err)
+	}
+
+	for _, secret := range []string{"", "release-hook"} {
+		wf.On.Webhook.Secret = secret
+		if err := p.Validate(wf); err == nil {
+			t.Errorf("Expected error for secret %q, got nil", secret)
+		}
+	}
+}
+
+func TestParse_PullRequestTrigger(t *testing.T) {
+	yaml := `
+name: PR
+on:
+  pull_request:
+    branches: [main]
+    types: [opened, synchronize]
+jobs:
+  test:
+    runs-on: ubuntu
+    steps:
+      - name: Test
+        run: echo test
+`
+
+	p := NewParser()
+	wf, err := p.Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := p.Validate(wf); err != nil {
+		t.Fatalf("Expected valid workflow, got: %v", err)
+	}
+
+	pr := wf.On.PullRequest
+	if pr == nil || len(pr.Branches) != 1 || len(pr.Types) != 2 {
+		t.Fatalf("Unexpected pull_request config: %+v", pr)
+	}
+
+	pr.Types = []string{"merged"}
+	if err := p.Validate(wf); err == nil {
+		t.Error("Expected error for unknown pull_request type, got nil")
+	}
+}
+
+func TestParse_TimeoutMinutes(t *testing.T) {
+	yaml := `
+name: Timeouts
+jobs:
+  test:
+    runs-on: ubuntu
+    timeout-minutes: 10
+    steps:
+      - name: Quick
+        run: echo quick
+        timeout-minutes: 0.5
+`
+
+	p := NewParser()
+	wf, err := p.Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := p.Validate(wf); err != nil {
+		t.Fatalf("Expected valid workflow, got: %v", err)
+	}
+
+	job := wf.Jobs["test"]
+	if job.TimeoutMinutes != 10 || job.Steps[0].TimeoutMinutes != 0.5 {
+		t.Errorf("Unexpected timeouts: job %v, step %v", job.TimeoutMinutes, job.Steps[0].TimeoutMinutes)
+	}
+
+	job.TimeoutMinutes = -1
+	wf.Jobs["test"] = job
+	if err := p.Validate(wf); err == nil {
+		t.Error("Expected error for negative timeout-minutes, got nil")
+	}
+}
+
+func TestParse_StepRetry(t *testing.T) {
+	yaml := `
+name: Retry
+jobs:
+  test:
+    runs-on: ubuntu
+    steps:
+      - name: Install
+        run: apk add curl
+        retry:
+          attempts: 3
+          delay: 5s
+`
+
+	p := NewParser()
+	wf, err := p.Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := p.Validate(wf); err != nil {
+		t.Fatalf("Expected valid workflow, got: %v", err)
+	}
+
+	retry := wf.Jobs["test"].Steps[0].Retry
+	if retry.MaxAttempts() != 3 || retry.DelayDuration() != 5*time.Second {
+		t.Errorf("Unexpected retry policy: %+v", retry)
+	}
+
+	invalid := []models.RetryPolicy{
+		{Attempts: 0},
+		{Attempts: 2, Delay: "soon"},
+		{Attempts: 2, Delay: "-1s"},
+	}
+	for _, policy := range invalid {
+		job := wf.Jobs["test"]
+		job.Steps[0].Retry = &policy
+		wf.Jobs["test"] = job
+		if err := p.Validate(wf); err == nil {
+			t.Errorf("Expected error for retry %+v, got nil", policy)
+		}
+	}
+}
+
+func TestValidate_WorkingDirectory(t *testing.T) {
+	p := NewParser()
+
+	tests := []struct {
+		name    string
+		jobDir  string
+		stepDir string
+		wantErr bool
+	}{
+		{"relative", "backend", "cmd/server", false},
+		{"absolute", "/src", "", false},
+		{"expression", "${{ matrix.dir }}", "", false},
+		{"escapes workspace", "", "../etc", true},
+		{"invalid expression", "${{ matrix.", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf := &models.Workflow{
+				Name: "Dirs",
+				Jobs: map[string]models.Job{
+					"build": {
+						RunsOn:           "ubuntu",
+						WorkingDirectory: tt.jobDir,
+						Steps:            []models.Step{{Name: "Build", Run: "make", WorkingDirectory: tt.stepDir}},
+					},
+				},
+			}
+			err := p.Validate(wf)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParse_GPUs(t *testing.T) {
+	p := NewParser()
+	wf, err := p.Parse([]byte("name: Train\njobs:\n  train:\n    runs-on: ubuntu\n    gpus: 2\n    steps:\n      - name: Train\n        run: python train.py\n"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := p.Validate(wf); err != nil {
+		t.Fatalf("Expected valid workflow, got: %v", err)
+	}
+	if gpus := wf.Jobs["train"].GPUs; gpus != "2" {
+		t.Errorf("Expected gpus '2', got '%s'", gpus)
+	}
+
+	wf.Jobs["train"] = models.Job{RunsOn: "ubuntu", GPUs: "some", Steps: wf.Jobs["train"].Steps}
+	if err := p.Validate(wf); err == nil {
+		t.Error("Expected error for invalid gpus, got nil")
+	}
+}
+
+func TestParse_Isolation(t *testing.T) {
+	p := NewParser()
+	wf, err := p.Parse([]byte("name: Untrusted\njobs:\n  test:\n    runs-on: ubuntu\n    isolation: vm\n    steps:\n      - name: Test\n        run: make test\n"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := p.Validate(wf); err != nil {
+		t.Fatalf("Expected valid workflow, got: %v", err)
+	}
+	if !wf.Jobs["test"].VMIsolated() {
+		t.Errorf("Expected the job to be VM isolated, got isolation '%s'", wf.Jobs["test"].Isolation)
+	}
+
+	steps := wf.Jobs["test"].Steps
+	wf.Jobs["test"] = models.Job{RunsOn: "ubuntu", Isolation: "sandbox", Steps: steps}
+	if err := p.Validate(wf); err == nil {
+		t.Error("Expected error for invalid isolation, got nil")
+	}
+	wf.Jobs["test"] = models.Job{RunsOn: "ubuntu", Isolation: models.IsolationVM, GPUs: "1", Steps: steps}
+	if err := p.Validate(wf); err == nil || !strings.Contains(err.Error(), "gpus") {
+		t.Errorf("Expected error for gpus in a VM, got %v", err)
+	}
+}
+
+func TestValidate_User(t *testing.T) {
+	p := NewParser()
+	steps := []models.Step{{Name: "Test", Run: "make test"}}
+
+	wf := &models.Workflow{Name: "Users", Jobs: map[string]models.Job{
+		"test": {RunsOn: "ubuntu", User: "1000:1000", Steps: steps},
+	}}
+	if err := p.Validate(wf); err != nil {
+		t.Fatalf("Expected valid workflow, got: %v", err)
+	}
+
+	wf.Jobs["test"] = models.Job{RunsOn: "ubuntu", User: "${{ matrix.user", Steps: steps}
+	if err := p.Validate(wf); err == nil {
+		t.Error("Expected error for an invalid user expression, got nil")
+	}
+	wf.Jobs["test"] = models.Job{RunsOn: "ubuntu", User: "1000", Container: &models.Container{Image: "node:20", User: "node"}, Steps: steps}
+	if err := p.Validate(wf); err == nil || !strings.Contains(err.Error(), "container.user") {
+		t.Errorf("Expected error for two users, got %v", err)
+	}
+}
+
+func TestValidate_Tmpfs(t *testing.T) {
+	p := NewParser()
+	steps := []models.Step{{Name: "Build", Run: "make"}}
+
+	wf := &models.Workflow{Name: "Scratch", Jobs: map[string]models.Job{
+		"build": {RunsOn: "ubuntu", Tmpfs: []string{"/tmp:1g", "/scratch"}, Steps: steps},
+	}}
+	if err := p.Validate(wf); err != nil {
+		t.Fatalf("Expected valid workflow, got: %v", err)
+	}
+
+	wf.Jobs["build"] = models.Job{RunsOn: "ubuntu", Tmpfs: []string{"scratch"}, Steps: steps}
+	if err := p.Validate(wf); err == nil {
+		t.Error("Expected error for a relative tmpfs path, got nil")
+	}
+	wf.Jobs["build"] = models.Job{RunsOn: "ubuntu", Volumes: []string{"cache:/scratch"}, Tmpfs: []string{"/scratch"}, Steps: steps}
+	if err := p.Validate(wf); err == nil || !strings.Contains(err.Error(), "/scratch") {
+		t.Errorf("Expected error for a tmpfs over a volume, got %v", err)
+	}
+}
+
+func TestValidate_Resources(t *testing.T) {
+	p := NewParser()
+
+	tests := []struct {
+		name      string
+		resources *models.Resources
+		wantErr   bool
+	}{
+		{"unset", nil, false},
+		{"cpu and memory", &models.Resources{CPU: "0.5", Memory: "2g"}, false},
+		{"invalid cpu", &models.Resources{CPU: "half"}, true},
+		{"zero cpu", &models.Resources{CPU: "0"}, true},
+		{"invalid memory", &models.Resources{Memory: "lots"}, true},
+		{"too little memory", &models.Resources{Memory: "1m"}, true},
+		{"disk", &models.Resources{Disk: "20g"}, false},
+		{"invalid disk", &models.Resources{Disk: "big"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf := &models.Workflow{
+				Name: "Limits",
+				Jobs: map[string]models.Job{
+					"build": {
+						RunsOn:    "ubuntu",
+						Resources: tt.resources,
+						Steps:     []models.Step{{Name: "Build", Run: "make"}},
+					},
+				},
+			}
+			err := p.Validate(wf)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_Volumes(t *testing.T) {
+	p := NewParser()
+
+	tests := []struct {
+		name    string
+		volumes []string
+		wantErr bool
+	}{
+		{"host path and named volume", []string{"/srv/cache:/cache:ro", "gomod:/go/pkg/mod"}, false},
+		{"relative target", []string{"/srv/cache:cache"}, true},
+		{"invalid mode", []string{"/srv/cache:/cache:ro,z"}, true},
+		{"duplicate target", []string{"/srv/a:/cache", "/srv/b:/cache/"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf := &models.Workflow{
+				Name: "Volumes",
+				Jobs: map[string]models.Job{
+					"build": {
+						RunsOn:  "ubuntu",
+						Volumes: tt.volumes,
+						Steps:   []models.Step{{Name: "Build", Run: "make"}},
+					},
+				},
+			}
+			err := p.Validate(wf)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParse_CheckoutShorthand(t *testing.T) {
+	p := NewParser()
+	wf, err := p.Parse([]byte("name: Build\njobs:\n  build:\n    runs-on: ubuntu\n    checkout: true\n    steps:\n      - name: Build\n        run: make\n"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if wf.Jobs["build"].Checkout == nil {
+		t.Error("Expected checkout: true to check out the event's repository")
+	}
+
+	if _, err := p.Parse([]byte("name: Build\njobs:\n  build:\n    runs-on: ubuntu\n    checkout: yes please\n    steps:\n      - name: Build\n        run: make\n")); err == nil {
+		t.Error("Expected error for a checkout that is neither true nor a mapping, got nil")
+	}
+}
+
+func TestValidate_Checkout(t *testing.T) {
+	p := NewParser()
+
+	tests := []struct {
+		name     string
+		checkout models.Checkout
+		wantErr  bool
+	}{
+		{"event defaults", models.Checkout{}, false},
+		{"token from secret", models.Checkout{Repository: "https://git.example.com/app.git", Ref: "v1.2", Token: "${{ secrets.REPO_TOKEN }}"}, false},
+		{"literal token", models.Checkout{Token: "ghp_abc"}, true},
+		{"token and ssh key", models.Checkout{Token: "${{ secrets.A }}", SSHKey: "${{ secrets.B }}"}, true},
+		{"negative depth", models.Checkout{Depth: -1}, true},
+		{"path escapes workspace", models.Checkout{Path: "../src"}, true},
+		{"absolute path", models.Checkout{Path: "/src"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkout := tt.checkout
+			wf := &models.Workflow{
+				Name: "Checkout",
+				Jobs: map[string]models.Job{
+					"build": {
+						RunsOn:   "ubuntu",
+						Checkout: &checkout,
+						Steps:    []models.Step{{Name: "Build", Run: "make"}},
+					},
+				},
+			}
+			err := p.Validate(wf)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_JobArtifacts(t *testing.T) {
+	p := NewParser()
+
+	tests := []struct {
+		name      string
+		artifacts []models.ArtifactStep
+		wantErr   bool
+	}{
+		{"name and path", []models.ArtifactStep{{Name: "reports", Path: "build/reports"}}, false},
+		{"templated name", []models.ArtifactStep{{Name: "logs-${{ matrix.os }}", Path: "logs"}}, false},
+		{"missing name", []models.ArtifactStep{{Path: "logs"}}, true},
+		{"missing path", []models.ArtifactStep{{Name: "logs"}}, true},
+		{"invalid name", []models.ArtifactStep{{Name: "../logs", Path: "logs"}}, true},
+		{"duplicate name", []models.ArtifactStep{{Name: "logs", Path: "a"}, {Name: "logs", Path: "b"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf := &models.Workflow{
+				Name: "Artifacts",
+				Jobs: map[string]models.Job{
+					"build": {
+						RunsOn:    "ubuntu",
+						Artifacts: tt.artifacts,
+						Steps:     []models.Step{{Name: "Build", Run: "make"}},
+					},
+				},
+			}
+			err := p.Validate(wf)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_CacheVolumes(t *testing.T) {
+	p := NewParser()
+
+	tests := []struct {
+		name    string
+		volumes []models.CacheVolume
+		wantErr bool
+	}{
+		{"key and path", []models.CacheVolume{{Key: "go-mod", Path: "/go/pkg/mod"}}, false},
+		{"templated key", []models.CacheVolume{{Key: "npm-${{ matrix.node }}", Path: "node_modules"}}, false},
+		{"missing key", []models.CacheVolume{{Path: "/go/pkg/mod"}}, true},
+		{"missing path", []models.CacheVolume{{Key: "go-mod"}}, true},
+		{"same path twice", []models.CacheVolume{{Key: "a", Path: "/cache"}, {Key: "b", Path: "/cache"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf := &models.Workflow{
+				Name: "Cache",
+				Jobs: map[string]models.Job{
+					"build": {
+						RunsOn:       "ubuntu",
+						CacheVolumes: tt.volumes,
+						Steps:        []models.Step{{Name: "Build", Run: "make"}},
+					},
+				},
+			}
+			err := p.Validate(wf)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_BuildStep(t *testing.T) {
+	p := NewParser()
+
+	tests := []struct {
+		name    string
+		build   models.BuildStep
+		wantErr bool
+	}{
+		{"tag", models.BuildStep{Tags: models.StringList{"app:dev"}}, false},
+		{"push with secret", models.BuildStep{
+			Context: "docker", Tags: models.StringList{"ghcr.io/org/app:${{ gantry.sha }}"}, Push: true,
+			Username: "ci", Password: "${{ secrets.REGISTRY_PASSWORD }}",
+		}, false},
+		{"no tags", models.BuildStep{}, true},
+		{"invalid tag", models.BuildStep{Tags: models.StringList{"App:Latest"}}, true},
+		{"literal password", models.BuildStep{Tags: models.StringList{"app:dev"}, Username: "ci", Password: "hunter2"}, true},
+		{"password without username", models.BuildStep{Tags: models.StringList{"app:dev"}, Password: "${{ secrets.P }}"}, true},
+		{"invalid build arg", models.BuildStep{Tags: models.StringList{"app:dev"}, BuildArgs: map[string]string{"V": "${{ env. }}"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			build := tt.build
+			wf := &models.Workflow{
+				Name: "Build",
+				Jobs: map[string]models.Job{
+					"image": {
+						RunsOn: "ubuntu",
+						Steps:  []models.Step{{Name: "Image", Build: &build}},
+					},
+				},
+			}
+			err := p.Validate(wf)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_RegistryCredentials(t *testing.T) {
+	p := NewParser()
+
+	tests := []struct {
+		name        string
+		credentials *models.RegistryCredentials
+		wantErr     bool
+	}{
+		{"password from secret", &models.RegistryCredentials{Username: "acme", Password: "${{ secrets.REGISTRY_PASSWORD }}"}, false},
+		{"literal password", &models.RegistryCredentials{Username: "acme", Password: "hunter2"}, true},
+		{"missing username", &models.RegistryCredentials{Password: "${{ secrets.REGISTRY_PASSWORD }}"}, true},
+		{"missing password", &models.RegistryCredentials{Username: "acme"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, job := range []models.Job{
+				{RunsOn: "ubuntu", Container: &models.Container{Image: "ghcr.io/acme/builder:1", Credentials: tt.credentials}},
+				{RunsOn: "ubuntu", Services: map[string]models.Service{"db": {Image: "ghcr.io/acme/db:1", Credentials: tt.credentials}}},
+			} {
+				job.Steps = []models.Step{{Name: "Build", Run: "make"}}
+				wf := &models.Workflow{Name: "Private", Jobs: map[string]models.Job{"build": job}}
+				err := p.Validate(wf)
+				if (err != nil) != tt.wantErr {
+					t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+				}
+			}
+		})
+	}
+}
+
+func TestValidate_PullPolicy(t *testing.T) {
+	p := NewParser()
+
+	for policy, wantErr := range map[string]bool{"": false, "Always": false, "IfNotPresent": false, "Never": false, "always": true, "Sometimes": true} {
+		wf := &models.Workflow{
+			Name: "Pull",
+			Jobs: map[string]models.Job{
+				"build": {RunsOn: "ubuntu", PullPolicy: policy, Steps: []models.Step{{Name: "Build", Run: "make"}}},
+			},
+		}
+		if err := p.Validate(wf); (err != nil) != wantErr {
+			t.Errorf("Validate() with pull-policy %q error = %v, wantErr %v", policy, err, wantErr)
+		}
+	}
+}
+
+func TestValidate_StepShell(t *testing.T) {
+	p := NewParser()
+
+	tests := []struct {
+		name      string
+		runsOn    string
+		container *models.Container
+		shell     string
+		wantErr   bool
+	}{
+		{"default", "ubuntu", nil, "", false},
+		{"bash on ubuntu", "ubuntu", nil, "bash", false},
+		{"bash on alpine", "alpine", nil, "bash", true},
+		{"python on ubuntu", "ubuntu", nil, "python", true},
+		{"python in container", "ubuntu", &models.Container{Image: "python:3.12"}, "python", false},
+		{"unknown shell", "ubuntu", nil, "pwsh", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf := &models.Workflow{
+				Name: "Shells",
+				Jobs: map[string]models.Job{
+					"build": {
+						RunsOn:    tt.runsOn,
+						Container: tt.container,
+						Steps:     []models.Step{{Name: "Script", Run: "print(1)", Shell: tt.shell}},
+					},
+				},
+			}
+			err := p.Validate(wf)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_StepLanguage(t *testing.T) {
+	p := NewParser()
+	python := &models.Container{Image: "python:3.12"}
+
+	tests := []struct {
+		name      string
+		container *models.Container
+		step      models.Step
+		wantErr   bool
+	}{
+		{"python in container", python, models.Step{Language: "python"}, false},
+		{"deno in container", &models.Container{Image: "denoland/deno:2.1.4"}, models.Step{Language: "deno"}, false},
+		{"python on ubuntu", nil, models.Step{Language: "python"}, true},
+		{"unknown language", python, models.Step{Language: "ruby"}, true},
+		{"with shell", python, models.Step{Language: "python", Shell: "bash"}, true},
+		{"on built-in step", python, models.Step{Language: "python", Cache: &models.CacheStep{Path: "deps", Key: "deps"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := tt.step
+			step.Name = "Script"
+			if !step.IsBuiltin() {
+				step.Run = "print(1)"
+			}
+			wf := &models.Workflow{
+				Name: "Languages",
+				Jobs: map[string]models.Job{
+					"build": {RunsOn: "ubuntu", Container: tt.container, Steps: []models.Step{step}},
+				},
+			}
+			err := p.Validate(wf)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParse_DefaultShellSkipsLanguageSteps(t *testing.T) {
+	yaml := `
+name: Languages
+on:
+  push:
+defaults:
+  run:
+    shell: bash
+jobs:
+  build:
+    runs-on: ubuntu
+    container: python:3.12
+    steps:
+      - name: Script
+        language: python
+        run: |
+          print("hello")
+      - name: Shell
+        run: echo hi
+`
+	wf, err := NewParser().Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	steps := wf.Jobs["build"].Steps
+	if steps[0].Shell != "" || steps[0].Language != models.LanguagePython {
+		t.Errorf("Expected the python step to keep no shell, got shell %q language %q", steps[0].Shell, steps[0].Language)
+	}
+	if steps[1].Shell != models.ShellBash {
+		t.Errorf("Expected the default shell on other steps, got %q", steps[1].Shell)
+	}
+}
+
+func TestValidate_RunsOn(t *testing.T) {
+	store := runners.NewMemoryStore()
+	for _, r := range []runners.Runner{
+		{Label: "debian", Image: "debian:bookworm", Shells: []string{"sh", "bash", "python"}},
+		{Label: "busybox", Image: "busybox:1.36"},
+	} {
+		if err := store.Set(r); err != nil {
+			t.Fatalf("Failed to add runner: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		parser  *Parser
+		runsOn  string
+		shell   string
+		wantErr string
+	}{
+		{"built-in", NewParser(), "alpine", "", ""},
+		{"unknown built-in", NewParser(), "ubuntu-latest", "", "runs-on 'ubuntu-latest' is not a known runner (expected one of alpine, ubuntu, or an image"},
+		{"image", NewParser(), "golang:1.22", "bash", ""},
+		{"image digest", NewParser(), "node@sha256:2e863c44b718727c860746568e1d54afd13b2fa71b160f5cd9058fc436217b30", "", ""},
+		{"invalid image", NewParser(), "Golang:1.22", "", "runs-on 'Golang:1.22' is not a valid image reference"},
+		{"registry without tag", NewParser(), "localhost:5000/app", "", "is not a known runner"},
+		{"expression", NewParser(), "${{ matrix.os }}", "", ""},
+		{"configured", NewParser(WithRunners(store)), "debian", "python", ""},
+		{"replaced built-in", NewParser(WithRunners(store)), "ubuntu", "", "expected one of busybox, debian"},
+		{"configured shells", NewParser(WithRunners(store)), "busybox", "bash", "the busybox image does not provide"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf := &models.Workflow{
+				Name: "Runners",
+				Jobs: map[string]models.Job{
+					"build": {
+						RunsOn: tt.runsOn,
+						Steps:  []models.Step{{Name: "Build", Run: "make", Shell: tt.shell}},
+					},
+				},
+			}
+			err := tt.parser.Validate(wf)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected valid runs-on, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParse_Defaults(t *testing.T) {
+	yaml := `
+name: Defaults
+defaults:
+  run:
+    shell: bash
+    working-directory: backend
+    env:
+      GOFLAGS: -mod=readonly
+      CGO_ENABLED: "0"
+jobs:
+  test:
+    runs-on: ubuntu
+    env:
+      CGO_ENABLED: "1"
+    steps:
+      - name: Test
+        run: go test ./...
+      - name: Script
+        shell: sh
+        run: ./script.sh
+  frontend:
+    runs-on: ubuntu
+    working-directory: frontend
+    steps:
+      - name: Build
+        run: npm run build
+`
+
+	p := NewParser()
+	wf, err := p.Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := p.Validate(wf); err != nil {
+		t.Fatalf("Expected valid workflow, got: %v", err)
+	}
+
+	test := wf.Jobs["test"]
+	if test.WorkingDirectory != "backend" {
+		t.Errorf("Expected default working-directory, got '%s'", test.WorkingDirectory)
+	}
+	if test.Env["GOFLAGS"] != "-mod=readonly" || test.Env["CGO_ENABLED"] != "1" {
+		t.Errorf("Expected defaults env merged under job env, got %v", test.Env)
+	}
+	if test.Steps[0].Shell != "bash" || test.Steps[1].Shell != "sh" {
+		t.Errorf("Expected default shell only on steps without one, got '%s', '%s'", test.Steps[0].Shell, test.Steps[1].Shell)
+	}
+
+	if dir := wf.Jobs["frontend"].WorkingDirectory; dir != "frontend" {
+		t.Errorf("Expected job working-directory to win, got '%s'", dir)
+	}
+
+	wf.Defaults.Run.Shell = "pwsh"
+	if err := p.Validate(wf); err == nil {
+		t.Error("Expected error for unknown default shell, got nil")
+	}
+}
+
+func TestValidate_WorkflowCall(t *testing.T) {
+	yaml := `
+name: Pipeline
+on:
+  workflow_call:
+    inputs:
+      environment:
+        required: true
+jobs:
+  deploy:
+    uses: release
+    with:
+      environment: ${{ inputs.environment }}
+`
+
+	p := NewParser()
+	wf, err := p.Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := p.Validate(wf); err != nil {
+		t.Fatalf("Expected valid workflow, got: %v", err)
+	}
+	if wf.On.WorkflowCall == nil || !wf.On.WorkflowCall.Inputs["environment"].Required {
+		t.Errorf("Expected workflow_call inputs, got %+v", wf.On.WorkflowCall)
+	}
+
+	invalid := map[string]models.Job{
+		"self":       {Uses: "Pipeline"},
+		"with steps": {Uses: "release", Steps: []models.Step{{Name: "Step", Run: "echo"}}},
+		"container":  {Uses: "release", Container: &models.Container{Image: "alpine"}},
+		"bad with":   {Uses: "release", With: map[string]string{"environment": "${{ inputs."}},
+	}
+	for name, job := range invalid {
+		wf.Jobs = map[string]models.Job{"deploy": job}
+		if err := p.Validate(wf); err == nil {
+			t.Errorf("Expected error for %s, got nil", name)
+		}
+	}
+}
+
+func TestValidate_ArtifactSteps(t *testing.T) {
+	p := NewParser()
+
+	upload := func(name, path string) models.Step {
+		return models.Step{Name: "Upload", UploadArtifact: &models.ArtifactStep{Name: name, Path: path}}
+	}
+
+	tests := []struct {
+		name    string
+		step    models.Step
+		wantErr bool
+	}{
+		{"upload", upload("binary", "bin"), false},
+		{"expression name", upload("binary-${{ matrix.os }}", "bin"), false},
+		{"download without path", models.Step{Name: "Download", DownloadArtifact: &models.ArtifactStep{Name: "binary"}}, false},
+		{"missing name", upload("", "bin"), true},
+		{"invalid name", upload("../binary", "bin"), true},
+		{"upload without path", upload("binary", ""), true},
+		{"with run", models.Step{Name: "Both", Run: "make", UploadArtifact: &models.ArtifactStep{Name: "binary", Path: "bin"}}, true},
+		{"upload and download", models.Step{
+			Name:             "Both",
+			UploadArtifact:   &models.ArtifactStep{Name: "binary", Path: "bin"},
+			DownloadArtifact: &models.ArtifactStep{Name: "binary"},
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf := &models.Workflow{
+				Name: "Artifacts",
+				Jobs: map[string]models.Job{
+					"build": {RunsOn: "ubuntu", Steps: []models.Step{tt.step}},
+				},
+			}
+			err := p.Validate(wf)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParse_CacheStep(t *testing.T) {
+	yaml := `
+name: Cache
+jobs:
+  build:
+    runs-on: ubuntu
+    steps:
+      - name: Restore modules
+        cache:
+          path: node_modules
+          key: npm-${{ matrix.node }}-v1
+          restore-keys: npm-
+      - name: Install
+        run: npm ci
+`
+
+	p := NewParser()
+	wf, err := p.Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := p.Validate(wf); err != nil {
+		t.Fatalf("Expected valid workflow, got: %v", err)
+	}
+
+	c := wf.Jobs["build"].Steps[0].Cache
+	if c == nil || c.Path != "node_modules" || len(c.RestoreKeys) != 1 || c.RestoreKeys[0] != "npm-" {
+		t.Errorf("Unexpected cache step: %+v", c)
+	}
+}
+
+func TestValidate_CacheStep(t *testing.T) {
+	p := NewParser()
+
+	tests := []struct {
+		name    string
+		cache   models.CacheStep
+		run     string
+		wantErr bool
+	}{
+		{"valid", models.CacheStep{Path: "node_modules", Key: "npm-v1"}, "", false},
+		{"missing path", models.CacheStep{Key: "npm-v1"}, "", true},
+		{"missing key", models.CacheStep{Path: "node_modules"}, "", true},
+		{"invalid restore key", models.CacheStep{Path: "node_modules", Key: "npm-v1", RestoreKeys: []string{"${{ npm"}}, "", true},
+		{"with run", models.CacheStep{Path: "node_modules", Key: "npm-v1"}, "npm ci", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.cache
+			wf := &models.Workflow{
+				Name: "Cache",
+				Jobs: map[string]models.Job{
+					"build": {RunsOn: "ubuntu", Steps: []models.Step{{Name: "Cache", Run: tt.run, Cache: &c}}},
+				},
+			}
+			err := p.Validate(wf)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_StepIDs(t *testing.T) {
+	p := NewParser()
+
+	tests := []struct {
+		name    string
+		steps   []models.Step
+		wantErr bool
+	}{
+		{"earlier step", []models.Step{
+			{ID: "ver", Name: "Version", Run: "echo version=1 >> $GANTRY_OUTPUT"},
+			{Name: "Tag", Run: "tag ${{ steps.ver.outputs.version }}", If: "steps.ver.outcome == 'success'"},
+		}, false},
+		{"invalid id", []models.Step{{ID: "1st", Name: "Build", Run: "make"}}, true},
+		{"duplicate id", []models.Step{
+			{ID: "build", Name: "Build", Run: "make"},
+			{ID: "build", Name: "Rebuild", Run: "make"},
+		}, true},
+		{"unknown step", []models.Step{{Name: "Tag", Run: "tag ${{ steps.ver.outputs.version }}"}}, true},
+		{"later step", []models.Step{
+			{Name: "Tag", Run: "tag", If: "steps.ver.outcome == 'success'"},
+			{ID: "ver", Name: "Version", Run: "version"},
+		}, true},
+		{"itself", []models.Step{{ID: "ver", Name: "Version", Run: "echo ${{ steps.ver.outputs.version }}"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf := &models.Workflow{
+				Name: "Steps",
+				Jobs: map[string]models.Job{"build": {RunsOn: "ubuntu", Steps: tt.steps}},
+			}
+			err := p.Validate(wf)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_BranchPatterns(t *testing.T) {
+	p := NewParser()
+
+	tests := []struct {
+		name    string
+		on      models.TriggerConfig
+		wantErr bool
+	}{
+		{"globs", models.TriggerConfig{Push: &models.PushConfig{Branches: []string{"release/**", "!release/*-rc"}}}, false},
+		{"empty push pattern", models.TriggerConfig{Push: &models.PushConfig{Branches: []string{""}}}, true},
+		{"bare negation", models.TriggerConfig{PullRequest: &models.PullRequestConfig{Branches: []string{"!"}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf := &models.Workflow{
+				Name: "Branches",
+				On:   tt.on,
+				Jobs: map[string]models.Job{"build": {RunsOn: "ubuntu", Steps: []models.Step{{Name: "Build", Run: "make"}}}},
+			}
+			err := p.Validate(wf)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
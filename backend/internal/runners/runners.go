@@ -0,0 +1,216 @@
+// Package runners provides the server-side registry of the images jobs
+// select with runs-on
+package runners
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"gantry/internal/models"
+
+	"github.com/distribution/reference"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix marks environment variables that set a runner's image, e.g.
+// GANTRY_RUNNER_UBUNTU=ubuntu:24.04@sha256:... sets the image of ubuntu.
+// Underscores in the label become hyphens.
+const EnvPrefix = "GANTRY_RUNNER_"
+
+// labelPattern matches runner labels
+var labelPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// Runner is an image jobs run in when they select its label with runs-on
+type Runner struct {
+	Label string `yaml:"-" json:"label"`
+
+	// Image is the image reference, ideally pinned with a digest
+	Image string `yaml:"image" json:"image"`
+
+	// Shells lists the step shells and languages the image provides
+	// (default sh)
+	Shells []string `yaml:"shells" json:"shells"`
+}
+
+// HasShell reports whether the runner's image provides shell
+func (r Runner) HasShell(shell string) bool {
+	for _, s := range r.Shells {
+		if s == shell {
+			return true
+		}
+	}
+	return false
+}
+
+// Pinned reports whether the runner's image is pinned with a digest
+func (r Runner) Pinned() bool {
+	named, err := reference.ParseNormalizedNamed(r.Image)
+	if err != nil {
+		return false
+	}
+	_, ok := named.(reference.Digested)
+	return ok
+}
+
+// defaults are the runners available when no runners file is configured
+var defaults = []Runner{
+	{Label: "ubuntu", Image: "ubuntu:latest", Shells: []string{models.ShellSh, models.ShellBash}},
+	{Label: "alpine", Image: "alpine:latest", Shells: []string{models.ShellSh}},
+}
+
+// Store provides runners by label
+type Store interface {
+	Get(label string) (Runner, bool)
+	Labels() []string
+}
+
+// MemoryStore implements an in-memory runner registry
+type MemoryStore struct {
+	runners map[string]Runner
+	mu      sync.RWMutex
+}
+
+// NewMemoryStore creates an empty runner registry
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		runners: make(map[string]Runner),
+	}
+}
+
+// NewDefaultStore creates a registry of the built-in ubuntu and alpine
+// runners
+func NewDefaultStore() *MemoryStore {
+	store := NewMemoryStore()
+	for _, r := range defaults {
+		if err := store.Set(r); err != nil {
+			panic(err)
+		}
+	}
+	return store
+}
+
+// NewStoreFromEnv creates a registry from a YAML file mapping labels to
+// runners, or the built-in runners when file is empty, and then applies the
+// images of GANTRY_RUNNER_* environment variables. A variable for a label
+// that isn't defined yet adds a runner providing sh.
+func NewStoreFromEnv(file string) (*MemoryStore, error) {
+	store := NewDefaultStore()
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read runners file: %w", err)
+		}
+
+		var defs map[string]Runner
+		if err := yaml.Unmarshal(data, &defs); err != nil {
+			return nil, fmt.Errorf("failed to parse runners file: %w", err)
+		}
+		if len(defs) == 0 {
+			return nil, fmt.Errorf("runners file must define at least one runner")
+		}
+
+		store = NewMemoryStore()
+		for label, r := range defs {
+			r.Label = label
+			if err := store.Set(r); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, EnvPrefix) {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimPrefix(kv, EnvPrefix), "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			continue
+		}
+
+		label := strings.ReplaceAll(strings.ToLower(parts[0]), "_", "-")
+		r, exists := store.Get(label)
+		if !exists {
+			r = Runner{Label: label}
+		}
+		r.Image = parts[1]
+		if err := store.Set(r); err != nil {
+			return nil, fmt.Errorf("%s%s: %w", EnvPrefix, parts[0], err)
+		}
+	}
+
+	return store, nil
+}
+
+// Get retrieves a runner by label
+func (s *MemoryStore) Get(label string) (Runner, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, exists := s.runners[label]
+	return r, exists
+}
+
+// Labels returns the sorted labels of all runners
+func (s *MemoryStore) Labels() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	labels := make([]string, 0, len(s.runners))
+	for label := range s.runners {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// Resolve returns the runner runs-on selects: a runner's label, or else an
+// image reference with a tag or digest, such as golang:1.22, which runs as a
+// runner of its own. Runners of images don't list their shells. The tag or
+// digest is required so a mistyped label isn't pulled as an image.
+func Resolve(store Store, runsOn string) (Runner, error) {
+	if r, ok := store.Get(runsOn); ok {
+		return r, nil
+	}
+
+	unknown := fmt.Errorf("'%s' is not a known runner (expected one of %s, or an image with a tag or digest such as golang:1.22)",
+		runsOn, strings.Join(store.Labels(), ", "))
+	if !strings.ContainsAny(runsOn, ":@") {
+		return Runner{}, unknown
+	}
+	named, err := reference.ParseNormalizedNamed(runsOn)
+	if err != nil {
+		return Runner{}, fmt.Errorf("'%s' is not a valid image reference: %w", runsOn, err)
+	}
+	_, tagged := named.(reference.Tagged)
+	_, digested := named.(reference.Digested)
+	if !tagged && !digested {
+		// A registry port, as in localhost:5000/app
+		return Runner{}, unknown
+	}
+	return Runner{Label: runsOn, Image: runsOn}, nil
+}
+
+// Set stores a runner, replacing one with the same label. Runners without
+// shells provide sh.
+func (s *MemoryStore) Set(r Runner) error {
+	if !labelPattern.MatchString(r.Label) {
+		return fmt.Errorf("invalid runner label '%s' (expected lowercase letters, digits, '.', '_' or '-')", r.Label)
+	}
+	if r.Image == "" {
+		return fmt.Errorf("runner '%s' must set an image", r.Label)
+	}
+	if _, err := reference.ParseNormalizedNamed(r.Image); err != nil {
+		return fmt.Errorf("runner '%s' has an invalid image '%s': %w", r.Label, r.Image, err)
+	}
+	if len(r.Shells) == 0 {
+		r.Shells = []string{models.ShellSh}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runners[r.Label] = r
+	return nil
+}
@@ -0,0 +1,112 @@
+package runners
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const pinnedUbuntu = "ubuntu:24.04@sha256:2e863c44b718727c860746568e1d54afd13b2fa71b160f5cd9058fc436217b30"
+
+func TestNewDefaultStore(t *testing.T) {
+	store := NewDefaultStore()
+	if labels := store.Labels(); len(labels) != 2 || labels[0] != "alpine" || labels[1] != "ubuntu" {
+		t.Fatalf("Expected alpine and ubuntu runners, got %v", labels)
+	}
+
+	ubuntu, _ := store.Get("ubuntu")
+	if ubuntu.Image != "ubuntu:latest" || !ubuntu.HasShell("bash") || ubuntu.Pinned() {
+		t.Errorf("Unexpected ubuntu runner: %+v", ubuntu)
+	}
+	if alpine, _ := store.Get("alpine"); alpine.HasShell("bash") {
+		t.Errorf("Expected alpine to provide sh only, got %v", alpine.Shells)
+	}
+}
+
+func TestNewStoreFromEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "runners.yaml")
+	content := "ubuntu:\n  image: " + pinnedUbuntu + "\n  shells: [sh, bash]\nbusybox:\n  image: busybox:1.36\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write runners file: %v", err)
+	}
+	t.Setenv(EnvPrefix+"BUSYBOX", "busybox:1.37")
+	t.Setenv(EnvPrefix+"DEBIAN_SLIM", "debian:bookworm-slim")
+
+	store, err := NewStoreFromEnv(path)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	if labels := store.Labels(); len(labels) != 3 || labels[0] != "busybox" || labels[1] != "debian-slim" {
+		t.Fatalf("Expected the file's runners plus debian-slim in place of the defaults, got %v", labels)
+	}
+	if ubuntu, _ := store.Get("ubuntu"); ubuntu.Label != "ubuntu" || !ubuntu.Pinned() {
+		t.Errorf("Expected the pinned ubuntu image from the file, got %+v", ubuntu)
+	}
+	busybox, _ := store.Get("busybox")
+	if busybox.Image != "busybox:1.37" || len(busybox.Shells) != 1 || !busybox.HasShell("sh") {
+		t.Errorf("Expected the environment to override the image and shells to default to sh, got %+v", busybox)
+	}
+}
+
+func TestNewStoreFromEnv_Defaults(t *testing.T) {
+	t.Setenv(EnvPrefix+"ALPINE", "alpine:3.20")
+
+	store, err := NewStoreFromEnv("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	if alpine, _ := store.Get("alpine"); alpine.Image != "alpine:3.20" {
+		t.Errorf("Expected the alpine image to be overridden, got %+v", alpine)
+	}
+	if _, ok := store.Get("ubuntu"); !ok {
+		t.Error("Expected the built-in ubuntu runner without a file")
+	}
+}
+
+func TestNewStoreFromEnv_Invalid(t *testing.T) {
+	if _, err := NewStoreFromEnv(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected error for missing runners file, got nil")
+	}
+
+	for name, content := range map[string]string{
+		"empty":         "{}\n",
+		"no image":      "ubuntu:\n  shells: [bash]\n",
+		"invalid image": "ubuntu:\n  image: Ubuntu:Latest\n",
+		"invalid label": "Ubuntu Latest:\n  image: ubuntu\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "runners.yaml")
+			if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+				t.Fatalf("Failed to write runners file: %v", err)
+			}
+			if _, err := NewStoreFromEnv(path); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	store := NewDefaultStore()
+
+	if r, err := Resolve(store, "alpine"); err != nil || r.Image != "alpine:latest" {
+		t.Errorf("Expected the alpine runner, got %+v, %v", r, err)
+	}
+	for _, image := range []string{"golang:1.22", "ghcr.io/org/builder:v2", "localhost:5000/app:1", "node@sha256:" + pinnedUbuntu[len(pinnedUbuntu)-64:]} {
+		r, err := Resolve(store, image)
+		if err != nil || r.Label != image || r.Image != image || len(r.Shells) != 0 {
+			t.Errorf("Expected %s to run as its own runner, got %+v, %v", image, r, err)
+		}
+	}
+	for runsOn, want := range map[string]string{
+		"ubuntu-latest":      "is not a known runner (expected one of alpine, ubuntu, or an image",
+		"localhost:5000/app": "is not a known runner",
+		"Golang:1.22":        "is not a valid image reference",
+	} {
+		if _, err := Resolve(store, runsOn); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Resolve(%q) = %v; want an error containing %q", runsOn, err, want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Package secrets provides the server-side store for workflow secrets
+package secrets
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/joho/godotenv"
+)
+
+// EnvPrefix marks environment variables that are loaded as secrets,
+// e.g. GANTRY_SECRET_NPM_TOKEN becomes the secret NPM_TOKEN
+const EnvPrefix = "GANTRY_SECRET_"
+
+// mask replaces secret values in output
+const mask = "***"
+
+// Store provides secret values by name
+type Store interface {
+	Get(name string) (string, bool)
+	Names() []string
+}
+
+// MutableStore is a store whose secrets can be set and deleted
+type MutableStore interface {
+	Store
+	Set(name, value string)
+	Delete(name string)
+}
+
+// MemoryStore implements an in-memory secret store
+type MemoryStore struct {
+	secrets map[string]string
+	mu      sync.RWMutex
+}
+
+// NewMemoryStore creates an empty secret store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		secrets: make(map[string]string),
+	}
+}
+
+// NewStoreFromEnv creates a store from GANTRY_SECRET_* environment variables
+// and, when path is set, a dotenv-formatted secrets file
+func NewStoreFromEnv(path string) (*MemoryStore, error) {
+	store := NewMemoryStore()
+
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, EnvPrefix) {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimPrefix(kv, EnvPrefix), "=", 2)
+		if len(parts) == 2 && parts[0] != "" {
+			store.Set(parts[0], parts[1])
+		}
+	}
+
+	if path != "" {
+		values, err := godotenv.Read(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secrets file: %w", err)
+		}
+		for name, value := range values {
+			store.Set(name, value)
+		}
+	}
+
+	return store, nil
+}
+
+// Get retrieves a secret by name
+func (s *MemoryStore) Get(name string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, exists := s.secrets[name]
+	return value, exists
+}
+
+// Names returns the sorted names of all secrets
+func (s *MemoryStore) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.secrets))
+	for name := range s.secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Set stores a secret
+func (s *MemoryStore) Set(name, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.secrets[name] = value
+}
+
+// Delete removes a secret
+func (s *MemoryStore) Delete(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.secrets, name)
+}
+
+// Mask replaces every secret value (and each line of multi-line values)
+// found in text with ***
+func Mask(text string, store Store) string {
+	if store == nil || text == "" {
+		return text
+	}
+
+	for _, value := range Values(store) {
+		text = strings.ReplaceAll(text, value, mask)
+	}
+	return text
+}
+
+// Values returns what Mask masks: every secret value and each line of
+// multi-line values, trimmed and longest first, so a secret that contains
+// another is masked as a whole
+func Values(store Store) []string {
+	if store == nil {
+		return nil
+	}
+
+	var values []string
+	add := func(value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	for _, name := range store.Names() {
+		value, _ := store.Get(name)
+		add(value)
+		if strings.Contains(value, "\n") {
+			for _, line := range strings.Split(value, "\n") {
+				add(line)
+			}
+		}
+	}
+
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	return values
+}
@@ -0,0 +1,70 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewStoreFromEnv(t *testing.T) {
+	t.Setenv(EnvPrefix+"NPM_TOKEN", "npm-123")
+
+	path := filepath.Join(t.TempDir(), "secrets.env")
+	if err := os.WriteFile(path, []byte("DEPLOY_KEY=deploy-456\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write secrets file: %v", err)
+	}
+
+	store, err := NewStoreFromEnv(path)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	if value, ok := store.Get("NPM_TOKEN"); !ok || value != "npm-123" {
+		t.Errorf("Expected NPM_TOKEN from env, got %q (exists=%v)", value, ok)
+	}
+	if value, ok := store.Get("DEPLOY_KEY"); !ok || value != "deploy-456" {
+		t.Errorf("Expected DEPLOY_KEY from file, got %q (exists=%v)", value, ok)
+	}
+}
+
+func TestNewStoreFromEnv_MissingFile(t *testing.T) {
+	if _, err := NewStoreFromEnv(filepath.Join(t.TempDir(), "missing.env")); err == nil {
+		t.Error("Expected error for missing secrets file, got nil")
+	}
+}
+
+func TestMemoryStore_SetGetDelete(t *testing.T) {
+	store := NewMemoryStore()
+	store.Set("B", "2")
+	store.Set("A", "1")
+
+	names := store.Names()
+	if len(names) != 2 || names[0] != "A" || names[1] != "B" {
+		t.Errorf("Expected sorted names [A B], got %v", names)
+	}
+
+	store.Delete("A")
+	if _, ok := store.Get("A"); ok {
+		t.Error("Expected A to be deleted")
+	}
+}
+
+func TestMask(t *testing.T) {
+	store := NewMemoryStore()
+	store.Set("TOKEN", "s3cr3t")
+	store.Set("TOKEN_EXTENDED", "s3cr3t-and-more")
+	store.Set("KEY", "line-one\nline-two")
+	store.Set("EMPTY", "")
+
+	output := "token=s3cr3t ext=s3cr3t-and-more key=line-two"
+	masked := Mask(output, store)
+
+	expected := "token=*** ext=*** key=***"
+	if masked != expected {
+		t.Errorf("Expected %q, got %q", expected, masked)
+	}
+
+	if Mask("unchanged", nil) != "unchanged" {
+		t.Error("Expected nil store to leave text unchanged")
+	}
+}
@@ -0,0 +1,24 @@
+package server
+
+import (
+	"fmt"
+	"io"
+
+	"gantry/internal/artifacts"
+)
+
+// ListArtifacts returns the artifacts uploaded during a run
+func (s *Server) ListArtifacts(runID string) ([]artifacts.Artifact, error) {
+	if s.artifacts == nil {
+		return []artifacts.Artifact{}, nil
+	}
+	return s.artifacts.List(runID)
+}
+
+// OpenArtifact returns the tar archive of an artifact uploaded during a run
+func (s *Server) OpenArtifact(runID, name string) (io.ReadCloser, error) {
+	if s.artifacts == nil {
+		return nil, fmt.Errorf("%w: '%s'", artifacts.ErrNotFound, name)
+	}
+	return s.artifacts.Open(runID, name)
+}
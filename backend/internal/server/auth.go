@@ -0,0 +1,152 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"gantry/internal/auth"
+	"gantry/internal/models"
+	"gantry/internal/storage"
+)
+
+// Errors of user accounts and sessions
+var (
+	ErrAuthDisabled       = errors.New("authentication is not enabled")
+	ErrRegistrationClosed = errors.New("registration is closed")
+	ErrInvalidUser        = errors.New("invalid user")
+	ErrInvalidCredentials = errors.New("invalid username or password")
+)
+
+// Limits of usernames and passwords
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72 // bcrypt ignores the rest
+)
+
+// usernamePattern is what usernames may look like
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{2,63}$`)
+
+// dummyPasswordHash is checked against when signing in as a user that
+// doesn't exist, so that takes as long as a wrong password
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, _ := auth.HashPassword("not the password of any user")
+	return hash
+})
+
+// sessions issues the tokens of signed-in users. Without tokens, the API
+// is open to anyone who can reach it.
+type sessions struct {
+	tokens *auth.Tokens // nil when authentication is disabled
+
+	// openRegistration lets anyone register; otherwise only the first user
+	// may, while there are none
+	openRegistration bool
+	registerMu       sync.Mutex
+}
+
+// AuthEnabled reports whether requests must be signed in
+func (s *Server) AuthEnabled() bool {
+	return s.sessions.tokens != nil
+}
+
+// Register creates a user with a password. The first user is an admin,
+// and those after viewers until an admin gives them another role.
+func (s *Server) Register(username, password string) (*models.User, error) {
+	if !s.AuthEnabled() {
+		return nil, ErrAuthDisabled
+	}
+	if !usernamePattern.MatchString(username) {
+		return nil, fmt.Errorf("%w: usernames are 3 to 64 letters, digits, '.', '_' or '-', starting with a letter or digit", ErrInvalidUser)
+	}
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return nil, fmt.Errorf("%w: passwords are %d to %d bytes", ErrInvalidUser, minPasswordLength, maxPasswordLength)
+	}
+
+	// Counting and saving together keeps two first users from registering
+	s.sessions.registerMu.Lock()
+	defer s.sessions.registerMu.Unlock()
+
+	count, err := s.storage.CountUsers()
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 && !s.sessions.openRegistration {
+		return nil, ErrRegistrationClosed
+	}
+
+	// The first user sets the server up; the others start out as viewers
+	role := auth.RoleViewer
+	if count == 0 {
+		role = auth.RoleAdmin
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	user := &models.User{Username: username, PasswordHash: hash, CreatedAt: time.Now(), Role: role}
+	if err := s.storage.SaveUser(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Login checks a user's password and starts a session for them
+func (s *Server) Login(username, password string) (auth.TokenPair, error) {
+	if !s.AuthEnabled() {
+		return auth.TokenPair{}, ErrAuthDisabled
+	}
+
+	user, err := s.storage.GetUser(username)
+	if err != nil {
+		if !errors.Is(err, storage.ErrUserNotFound) {
+			return auth.TokenPair{}, err
+		}
+		auth.CheckPassword(dummyPasswordHash(), password)
+		return auth.TokenPair{}, ErrInvalidCredentials
+	}
+	if !auth.CheckPassword(user.PasswordHash, password) {
+		return auth.TokenPair{}, ErrInvalidCredentials
+	}
+	return s.sessions.tokens.Issue(user.Username)
+}
+
+// RefreshSession exchanges a refresh token for a new pair of tokens, as
+// long as its user still exists
+func (s *Server) RefreshSession(refreshToken string) (auth.TokenPair, error) {
+	if !s.AuthEnabled() {
+		return auth.TokenPair{}, ErrAuthDisabled
+	}
+
+	claims, err := s.sessions.tokens.Verify(refreshToken, auth.RefreshToken)
+	if err != nil {
+		return auth.TokenPair{}, err
+	}
+	if _, err := s.storage.GetUser(claims.Subject); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return auth.TokenPair{}, auth.ErrInvalidToken
+		}
+		return auth.TokenPair{}, err
+	}
+	return s.sessions.tokens.Issue(claims.Subject)
+}
+
+// Authenticate returns the user an access token was issued to
+func (s *Server) Authenticate(accessToken string) (string, error) {
+	if !s.AuthEnabled() {
+		return "", ErrAuthDisabled
+	}
+	claims, err := s.sessions.tokens.Verify(accessToken, auth.AccessToken)
+	if err != nil {
+		return "", err
+	}
+	return claims.Subject, nil
+}
+
+// GetUser retrieves a user by username
+func (s *Server) GetUser(username string) (*models.User, error) {
+	return s.storage.GetUser(username)
+}
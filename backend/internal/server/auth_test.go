@@ -0,0 +1,98 @@
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gantry/internal/auth"
+	"gantry/internal/storage"
+)
+
+func newAuthTestServer(t *testing.T, openRegistration bool) *Server {
+	t.Helper()
+	tokens, err := auth.NewTokens("0123456789abcdef0123456789abcdef", time.Minute, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create tokens: %v", err)
+	}
+	return &Server{
+		storage:  storage.NewMemoryStorage(),
+		sessions: sessions{tokens: tokens, openRegistration: openRegistration},
+	}
+}
+
+func TestServer_RegisterAndLogin(t *testing.T) {
+	srv := newAuthTestServer(t, false)
+
+	user, err := srv.Register("alice", "correct horse")
+	if err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+	if user.PasswordHash == "" || user.PasswordHash == "correct horse" {
+		t.Errorf("Expected the password to be hashed, got %q", user.PasswordHash)
+	}
+
+	tokens, err := srv.Login("alice", "correct horse")
+	if err != nil {
+		t.Fatalf("Failed to sign in: %v", err)
+	}
+	if username, err := srv.Authenticate(tokens.AccessToken); err != nil || username != "alice" {
+		t.Errorf("Expected the access token to be alice's, got %q (%v)", username, err)
+	}
+	if _, err := srv.Authenticate(tokens.RefreshToken); err == nil {
+		t.Error("Expected a refresh token not to authenticate requests")
+	}
+
+	refreshed, err := srv.RefreshSession(tokens.RefreshToken)
+	if err != nil {
+		t.Fatalf("Failed to refresh session: %v", err)
+	}
+	if username, err := srv.Authenticate(refreshed.AccessToken); err != nil || username != "alice" {
+		t.Errorf("Expected the new access token to be alice's, got %q (%v)", username, err)
+	}
+
+	for _, creds := range [][2]string{{"alice", "wrong horse"}, {"bob", "correct horse"}} {
+		if _, err := srv.Login(creds[0], creds[1]); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("Login(%q, %q): expected ErrInvalidCredentials, got %v", creds[0], creds[1], err)
+		}
+	}
+}
+
+func TestServer_Register_Rules(t *testing.T) {
+	srv := newAuthTestServer(t, false)
+
+	for _, creds := range [][2]string{{"a", "correct horse"}, {"alice smith", "correct horse"}, {"alice", "short"}} {
+		if _, err := srv.Register(creds[0], creds[1]); !errors.Is(err, ErrInvalidUser) {
+			t.Errorf("Register(%q, %q): expected ErrInvalidUser, got %v", creds[0], creds[1], err)
+		}
+	}
+
+	if _, err := srv.Register("alice", "correct horse"); err != nil {
+		t.Fatalf("Failed to register the first user: %v", err)
+	}
+	if _, err := srv.Register("bob", "correct horse"); !errors.Is(err, ErrRegistrationClosed) {
+		t.Errorf("Expected registration to close after the first user, got %v", err)
+	}
+
+	srv.sessions.openRegistration = true
+	if _, err := srv.Register("bob", "correct horse"); err != nil {
+		t.Errorf("Expected open registration to accept another user, got %v", err)
+	}
+	if _, err := srv.Register("bob", "correct horse"); !errors.Is(err, storage.ErrUserExists) {
+		t.Errorf("Expected ErrUserExists, got %v", err)
+	}
+}
+
+func TestServer_AuthDisabled(t *testing.T) {
+	srv := &Server{storage: storage.NewMemoryStorage()}
+
+	if srv.AuthEnabled() {
+		t.Error("Expected authentication to be disabled without tokens")
+	}
+	if _, err := srv.Register("alice", "correct horse"); !errors.Is(err, ErrAuthDisabled) {
+		t.Errorf("Expected ErrAuthDisabled, got %v", err)
+	}
+	if _, err := srv.Login("alice", "correct horse"); !errors.Is(err, ErrAuthDisabled) {
+		t.Errorf("Expected ErrAuthDisabled, got %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"gantry/internal/models"
+	"gantry/internal/parser"
+)
+
+// ErrInvalidBulk is returned when a bulk upload has a workflow that can't be
+// saved, in which case none of them are
+var ErrInvalidBulk = errors.New("bulk upload has invalid workflows")
+
+// BulkDocument is one workflow of a bulk upload
+type BulkDocument struct {
+	File string // names the document in the results, such as its file name
+	Data []byte
+}
+
+// BulkItem is the result of one workflow of a bulk upload. Errors lists its
+// problems when the upload was refused; workflows without any have no ID
+// then, as nothing was saved.
+type BulkItem struct {
+	File    string                  `json:"file"`
+	ID      string                  `json:"id,omitempty"`
+	Name    string                  `json:"name,omitempty"`
+	Version int                     `json:"version,omitempty"`
+	Errors  parser.ValidationErrors `json:"errors,omitempty"`
+}
+
+// BulkResult is the result of a bulk upload, item by item in the order the
+// workflows were given
+type BulkResult struct {
+	Saved bool       `json:"saved"`
+	Items []BulkItem `json:"items"`
+}
+
+// SaveWorkflows saves several workflows as author's versions of them, all
+// or none: every workflow is checked as an upload would be before any is
+// saved, and workflows already saved are put back when saving another
+// fails. An invalid workflow returns the result with its problems and
+// ErrInvalidBulk.
+func (s *Server) SaveWorkflows(docs []BulkDocument, author string) (*BulkResult, error) {
+	result := &BulkResult{Items: make([]BulkItem, len(docs))}
+	if len(docs) == 0 {
+		return result, fmt.Errorf("%w: no workflows given", ErrInvalidBulk)
+	}
+
+	workflows := make([]*models.Workflow, len(docs))
+	files := make(map[string]string, len(docs)) // by workflow name
+	invalid := false
+	for i, doc := range docs {
+		item := &result.Items[i]
+		item.File = doc.File
+
+		wf, err := s.checkWorkflow(doc.Data)
+		if err == nil {
+			item.Name = wf.Name
+			if file, ok := files[wf.Name]; ok {
+				err = fmt.Errorf("workflow '%s' is also in %s", wf.Name, file)
+			}
+			files[wf.Name] = doc.File
+		}
+		if err != nil {
+			item.Errors = parser.AsValidationErrors(err)
+			invalid = true
+			continue
+		}
+		workflows[i] = wf
+	}
+	if invalid {
+		return result, ErrInvalidBulk
+	}
+
+	s.workflowMu.Lock()
+	defer s.workflowMu.Unlock()
+
+	stored := make([]*models.Workflow, 0, len(workflows)) // previous versions, nil for new workflows
+	for i, wf := range workflows {
+		previous, err := s.storeWorkflow(wf, author)
+		if err != nil {
+			s.restoreWorkflows(workflows[:i], stored)
+			return nil, fmt.Errorf("failed to save %s: %w", docs[i].File, err)
+		}
+		stored = append(stored, previous)
+	}
+
+	for i, wf := range workflows {
+		s.recordRevision(wf)
+		s.prepullImages(wf)
+		result.Items[i] = BulkItem{File: docs[i].File, ID: wf.ID, Name: wf.Name, Version: wf.Version}
+	}
+	result.Saved = true
+	return result, nil
+}
+
+// restoreWorkflows undoes the saves of a failed bulk upload, putting back
+// the previous version of each workflow and deleting new ones
+func (s *Server) restoreWorkflows(saved, previous []*models.Workflow) {
+	for i, wf := range saved {
+		var err error
+		if previous[i] != nil {
+			err = s.storage.SaveWorkflow(previous[i])
+		} else {
+			err = s.storage.DeleteWorkflow(wf.ID)
+		}
+		if err != nil {
+			log.Printf("WARNING: failed to undo saving workflow '%s': %v", wf.Name, err)
+		}
+	}
+}
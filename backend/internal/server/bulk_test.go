@@ -0,0 +1,122 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"gantry/internal/models"
+	"gantry/internal/parser"
+	"gantry/internal/storage"
+)
+
+const bulkSource = "name: %s\njobs:\n  test:\n    runs-on: ubuntu\n    steps:\n      - name: Test\n        run: echo %s\n"
+
+func bulkDocument(file, name, message string) BulkDocument {
+	return BulkDocument{File: file, Data: []byte(fmt.Sprintf(bulkSource, name, message))}
+}
+
+func TestServer_SaveWorkflows(t *testing.T) {
+	srv := &Server{
+		storage: storage.NewMemoryStorage(),
+		parser:  parser.NewParser(),
+	}
+	if _, err := srv.ParseAndSaveWorkflow([]byte(fmt.Sprintf(bulkSource, "Deploy", "old")), ""); err != nil {
+		t.Fatalf("Failed to save workflow: %v", err)
+	}
+
+	result, err := srv.SaveWorkflows([]BulkDocument{
+		bulkDocument("build.yml", "Build", "build"),
+		bulkDocument("deploy.yml", "Deploy", "new"),
+	}, "alice")
+	if err != nil {
+		t.Fatalf("Failed to save workflows: %v", err)
+	}
+	want := []BulkItem{
+		{File: "build.yml", ID: "build", Name: "Build", Version: 1},
+		{File: "deploy.yml", ID: "deploy", Name: "Deploy", Version: 2},
+	}
+	if !result.Saved || !reflect.DeepEqual(result.Items, want) {
+		t.Errorf("Expected %+v saved, got %+v", want, result)
+	}
+
+	revisions, err := srv.ListWorkflowRevisions("Deploy")
+	if err != nil || len(revisions) != 2 || revisions[0].UpdatedBy != "alice" {
+		t.Errorf("Expected alice's version 2 of Deploy to be recorded, got %+v (%v)", revisions, err)
+	}
+}
+
+func TestServer_SaveWorkflows_Invalid(t *testing.T) {
+	srv := &Server{
+		storage: storage.NewMemoryStorage(),
+		parser:  parser.NewParser(),
+	}
+
+	result, err := srv.SaveWorkflows([]BulkDocument{
+		bulkDocument("build.yml", "Build", "build"),
+		{File: "broken.yml", Data: []byte("name: Broken\njobs: [\n")},
+		bulkDocument("copy.yml", "Build", "again"),
+	}, "")
+	if !errors.Is(err, ErrInvalidBulk) {
+		t.Fatalf("Expected ErrInvalidBulk, got %v", err)
+	}
+	if result.Saved || result.Items[0].Errors != nil || result.Items[1].Errors == nil || result.Items[2].Errors == nil {
+		t.Errorf("Expected errors for broken.yml and copy.yml only, got %+v", result.Items)
+	}
+	if result.Items[0].ID != "" {
+		t.Errorf("Expected valid workflows of a refused upload to have no ID, got %+v", result.Items[0])
+	}
+	if _, total, _ := srv.ListWorkflows(storage.ListOptions{}); total != 0 {
+		t.Errorf("Expected no workflows to be saved, got %d", total)
+	}
+
+	if _, err := srv.SaveWorkflows(nil, ""); !errors.Is(err, ErrInvalidBulk) {
+		t.Errorf("Expected ErrInvalidBulk for an empty upload, got %v", err)
+	}
+}
+
+// failingStorage fails to save the workflow named failName
+type failingStorage struct {
+	*storage.MemoryStorage
+	failName string
+}
+
+func (s *failingStorage) SaveWorkflow(wf *models.Workflow) error {
+	if wf.Name == s.failName {
+		return errors.New("disk full")
+	}
+	return s.MemoryStorage.SaveWorkflow(wf)
+}
+
+func TestServer_SaveWorkflows_RestoresOnFailure(t *testing.T) {
+	store := &failingStorage{MemoryStorage: storage.NewMemoryStorage()}
+	srv := &Server{
+		storage: store,
+		parser:  parser.NewParser(),
+	}
+	if _, err := srv.ParseAndSaveWorkflow([]byte(fmt.Sprintf(bulkSource, "Deploy", "old")), ""); err != nil {
+		t.Fatalf("Failed to save workflow: %v", err)
+	}
+
+	store.failName = "Release"
+	_, err := srv.SaveWorkflows([]BulkDocument{
+		bulkDocument("build.yml", "Build", "build"),
+		bulkDocument("deploy.yml", "Deploy", "new"),
+		bulkDocument("release.yml", "Release", "release"),
+	}, "")
+	if err == nil {
+		t.Fatal("Expected the failed save to fail the upload")
+	}
+
+	if _, err := store.GetWorkflowByName("Build"); err == nil {
+		t.Error("Expected the new workflow to be deleted again")
+	}
+	deploy, err := store.GetWorkflowByName("Deploy")
+	if err != nil || deploy.Version != 1 || deploy.Source != fmt.Sprintf(bulkSource, "Deploy", "old") {
+		t.Errorf("Expected version 1 of Deploy to be put back, got %+v (%v)", deploy, err)
+	}
+	if revisions, _ := srv.ListWorkflowRevisions("Deploy"); len(revisions) != 1 {
+		t.Errorf("Expected no revision of the undone save, got %+v", revisions)
+	}
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+
+	"gantry/internal/expr"
+	"gantry/internal/models"
+)
+
+// ErrInvalidCall is returned when a job's `uses:` can't be expanded, e.g.
+// the called workflow doesn't exist or doesn't accept workflow_call
+var ErrInvalidCall = errors.New("invalid workflow call")
+
+// maxCallDepth limits how deeply reusable workflows may call each other
+const maxCallDepth = 4
+
+// expandCalls replaces every job that uses another workflow with that
+// workflow's jobs, named "<job>/<called job>". The returned groups map each
+// calling job to the jobs it expanded into, so that `needs` on the calling
+// job waits for all of them. The expanded workflow always uses explicit
+// needs, preserving the sequential order of workflows that don't.
+func (s *Server) expandCalls(wf *models.Workflow, inputs map[string]interface{}) (*models.Workflow, map[string][]string, error) {
+	groups := make(map[string][]string)
+	expanded, err := s.expandWorkflow(wf, inputs, "", []string{wf.Name}, groups)
+	if err != nil {
+		return nil, nil, err
+	}
+	return expanded, groups, nil
+}
+
+func (s *Server) expandWorkflow(wf *models.Workflow, inputs map[string]interface{}, prefix string,
+	stack []string, groups map[string][]string) (*models.Workflow, error) {
+	order := wf.JobOrder
+	if len(order) == 0 {
+		for name := range wf.Jobs {
+			order = append(order, name)
+		}
+	}
+
+	uses := false
+	for _, name := range order {
+		if wf.Jobs[name].Uses != "" {
+			uses = true
+			break
+		}
+	}
+	if !uses && prefix == "" {
+		return wf, nil
+	}
+
+	expanded := *wf
+	expanded.Jobs = make(map[string]models.Job, len(wf.Jobs))
+	expanded.JobOrder = nil
+
+	explicit := wf.UsesNeeds()
+	for i, name := range order {
+		job := wf.Jobs[name]
+
+		var needs []string
+		switch {
+		case explicit:
+			needs = job.Needs
+		case i > 0:
+			needs = []string{order[i-1]}
+		}
+		job.Needs = make(models.StringList, 0, len(needs))
+		for _, need := range needs {
+			job.Needs = append(job.Needs, prefix+need)
+		}
+
+		if job.Uses == "" {
+			expanded.Jobs[prefix+name] = job
+			expanded.JobOrder = append(expanded.JobOrder, prefix+name)
+			continue
+		}
+
+		called, callInputs, err := s.resolveCall(job, inputs, stack)
+		if err != nil {
+			return nil, fmt.Errorf("job '%s': %w", prefix+name, err)
+		}
+
+		nested, err := s.expandWorkflow(called, callInputs, prefix+name+"/", append(stack, called.Name), groups)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, nestedName := range nested.JobOrder {
+			nestedJob := nested.Jobs[nestedName]
+			if len(nestedJob.Needs) == 0 {
+				nestedJob.Needs = job.Needs
+			}
+			if nestedJob.Call == "" {
+				nestedJob.Call = prefix + name
+				nestedJob.Inputs = callInputs
+				nestedJob.Variables = called.Variables
+			}
+			nestedJob.Env = mergeEnv(called.Env, nestedJob.Env)
+
+			expanded.Jobs[nestedName] = nestedJob
+			expanded.JobOrder = append(expanded.JobOrder, nestedName)
+			groups[prefix+name] = append(groups[prefix+name], nestedName)
+		}
+	}
+
+	return &expanded, nil
+}
+
+// resolveCall loads the workflow a job uses and resolves the job's `with:`
+// values into the called workflow's inputs. `with:` values may reference the
+// caller's inputs.
+func (s *Server) resolveCall(job models.Job, inputs map[string]interface{}, stack []string) (*models.Workflow, map[string]interface{}, error) {
+	for _, name := range stack {
+		if name == job.Uses {
+			return nil, nil, fmt.Errorf("%w: workflow '%s' calls itself", ErrInvalidCall, job.Uses)
+		}
+	}
+	if len(stack) > maxCallDepth {
+		return nil, nil, fmt.Errorf("%w: workflow calls are nested more than %d deep", ErrInvalidCall, maxCallDepth)
+	}
+
+	called, err := findWorkflow(s.storage, job.Uses)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: workflow '%s' not found", ErrInvalidCall, job.Uses)
+	}
+	if called.On.WorkflowCall == nil {
+		return nil, nil, fmt.Errorf("%w: workflow '%s' does not declare on.workflow_call", ErrInvalidCall, job.Uses)
+	}
+
+	callerCtx := &expr.Context{Values: map[string]interface{}{"inputs": inputs}}
+	with, err := expr.InterpolateMap(job.With, callerCtx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: with %v", ErrInvalidCall, err)
+	}
+
+	provided := make(map[string]interface{}, len(with))
+	for k, v := range with {
+		provided[k] = v
+	}
+	callInputs, err := resolveDeclaredInputs(called.On.WorkflowCall.Inputs, provided)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: workflow '%s': %w", ErrInvalidCall, job.Uses, err)
+	}
+
+	return called, callInputs, nil
+}
+
+// mergeEnv combines env maps; later maps win
+func mergeEnv(maps ...map[string]string) map[string]string {
+	merged := make(map[string]string)
+	for _, m := range maps {
+		for k, v := range m {
+			merged[k] = v
+		}
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
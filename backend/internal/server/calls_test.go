@@ -0,0 +1,181 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gantry/internal/models"
+	"gantry/internal/parser"
+)
+
+const releaseWorkflowYAML = `
+name: release
+on:
+  workflow_call:
+    inputs:
+      environment:
+        required: true
+      dry-run:
+        type: boolean
+        default: "false"
+jobs:
+  build:
+    runs-on: ubuntu
+    steps:
+      - name: Build
+        run: make build ENV=${{ inputs.environment }}
+  publish:
+    runs-on: ubuntu
+    needs: build
+    if: needs.build.result == 'success'
+    steps:
+      - name: Publish
+        run: publish --dry-run=${{ inputs.dry-run }}
+`
+
+const pipelineWorkflowYAML = `
+name: pipeline
+on:
+  workflow_dispatch:
+    inputs:
+      target:
+        default: staging
+jobs:
+  test:
+    runs-on: ubuntu
+    steps:
+      - name: Test
+        run: make test
+  deploy:
+    uses: release
+    with:
+      environment: ${{ inputs.target }}
+  notify:
+    runs-on: ubuntu
+    needs: deploy
+    steps:
+      - name: Notify
+        run: echo ${{ needs.deploy.result }}
+`
+
+func parseTestWorkflow(t *testing.T, data string) *models.Workflow {
+	t.Helper()
+
+	p := parser.NewParser()
+	wf, err := p.Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Failed to parse workflow: %v", err)
+	}
+	if err := p.Validate(wf); err != nil {
+		t.Fatalf("Invalid workflow: %v", err)
+	}
+	return wf
+}
+
+func TestServer_WorkflowCall(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newEventTestServer(t, exec,
+		parseTestWorkflow(t, releaseWorkflowYAML),
+		parseTestWorkflow(t, pipelineWorkflowYAML),
+	)
+
+	started, err := srv.TriggerWorkflow(context.Background(), "pipeline", TriggerOptions{
+		Inputs: map[string]interface{}{"target": "production"},
+	})
+	if err != nil {
+		t.Fatalf("TriggerWorkflow returned error: %v", err)
+	}
+	run := waitForRun(t, srv, started.ID)
+
+	want := []string{"test", "deploy/build", "deploy/publish", "notify"}
+	if len(run.JobOrder) != len(want) {
+		t.Fatalf("Expected jobs %v, got %v", want, run.JobOrder)
+	}
+	for i, name := range want {
+		if run.JobOrder[i] != name {
+			t.Fatalf("Expected jobs %v, got %v", want, run.JobOrder)
+		}
+	}
+	if run.Status != successStatus {
+		t.Errorf("Expected run status '%s', got '%s'", successStatus, run.Status)
+	}
+
+	position := make(map[string]int)
+	for i, name := range exec.order {
+		position[name] = i
+	}
+	if position["deploy/publish"] < position["deploy/build"] || position["notify"] < position["deploy/publish"] {
+		t.Errorf("Expected called jobs to respect needs, got %v", exec.order)
+	}
+
+	if got := exec.jobs["deploy/build"].Steps[0].Run; got != "make build ENV=production" {
+		t.Errorf("Expected called job to see its inputs, got '%s'", got)
+	}
+	if got := exec.jobs["deploy/publish"].Steps[0].Run; got != "publish --dry-run=false" {
+		t.Errorf("Expected input default, got '%s'", got)
+	}
+	if got := exec.jobs["notify"].Steps[0].Run; got != "echo success" {
+		t.Errorf("Expected needs on the calling job to aggregate its jobs, got '%s'", got)
+	}
+
+	if build, _ := run.GetJob("deploy/build"); build.Call != "deploy" {
+		t.Errorf("Expected nested job to record its calling job, got '%s'", build.Call)
+	}
+}
+
+func TestServer_WorkflowCallErrors(t *testing.T) {
+	notCallable := parseTestWorkflow(t, releaseWorkflowYAML)
+	notCallable.Name = "plain"
+	notCallable.On = models.TriggerConfig{}
+
+	tests := []struct {
+		name string
+		uses string
+		with map[string]string
+	}{
+		{"missing workflow", "nope", nil},
+		{"not callable", "plain", nil},
+		{"missing required input", "release", nil},
+		{"unknown input", "release", map[string]string{"environment": "prod", "region": "eu"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newEventTestServer(t, &fakeExecutor{}, parseTestWorkflow(t, releaseWorkflowYAML), notCallable)
+
+			caller := &models.Workflow{
+				Name:     "caller",
+				Jobs:     map[string]models.Job{"deploy": {Uses: tt.uses, With: tt.with}},
+				JobOrder: []string{"deploy"},
+			}
+			if err := srv.storage.SaveWorkflow(caller); err != nil {
+				t.Fatalf("Failed to save workflow: %v", err)
+			}
+
+			if _, err := srv.TriggerWorkflow(context.Background(), "caller", TriggerOptions{}); !errors.Is(err, ErrInvalidCall) {
+				t.Errorf("Expected ErrInvalidCall, got %v", err)
+			}
+		})
+	}
+}
+
+func TestServer_WorkflowCallCycle(t *testing.T) {
+	a := &models.Workflow{
+		Name:     "a",
+		On:       models.TriggerConfig{WorkflowCall: &models.CallConfig{}},
+		Jobs:     map[string]models.Job{"call": {Uses: "b"}},
+		JobOrder: []string{"call"},
+	}
+	b := &models.Workflow{
+		Name:     "b",
+		On:       models.TriggerConfig{WorkflowCall: &models.CallConfig{}},
+		Jobs:     map[string]models.Job{"call": {Uses: "a"}},
+		JobOrder: []string{"call"},
+	}
+	srv := newEventTestServer(t, &fakeExecutor{}, a, b)
+
+	if _, err := srv.TriggerWorkflow(context.Background(), "a", TriggerOptions{}); !errors.Is(err, ErrInvalidCall) {
+		t.Errorf("Expected ErrInvalidCall for a call cycle, got %v", err)
+	}
+}
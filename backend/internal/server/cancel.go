@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"gantry/internal/models"
+)
+
+// cancelledStatus marks a run cancelled while it ran, and the jobs the
+// cancellation stopped or kept from starting
+const cancelledStatus = "cancelled"
+
+// cancellingStatus marks a run being cancelled, until its running jobs have
+// stopped
+const cancellingStatus = "cancelling"
+
+// ErrNotRunning is returned when cancelling a run that isn't running
+var ErrNotRunning = errors.New("run is not running")
+
+// errRunCancelled is the cause of a cancelled run's job context, telling it
+// apart from matrix legs cancelled by fail-fast
+var errRunCancelled = errors.New("run cancelled")
+
+// activeRuns holds each running run and a way to cancel it, keyed by run
+type activeRuns struct {
+	mu      sync.Mutex
+	cancels map[string]activeRun
+}
+
+// activeRun is a running run and how to cancel it
+type activeRun struct {
+	run        *models.WorkflowRun
+	cancel     context.CancelCauseFunc
+	cancelling bool
+}
+
+// trackRun records how to cancel a run until untrackRun is called
+func (s *Server) trackRun(run *models.WorkflowRun, cancel context.CancelCauseFunc) {
+	s.active.mu.Lock()
+	defer s.active.mu.Unlock()
+	if s.active.cancels == nil {
+		s.active.cancels = make(map[string]activeRun)
+	}
+	s.active.cancels[run.ID] = activeRun{run: run, cancel: cancel}
+}
+
+// untrackRun forgets a run that has finished
+func (s *Server) untrackRun(runID string) {
+	s.active.mu.Lock()
+	defer s.active.mu.Unlock()
+	delete(s.active.cancels, runID)
+}
+
+// runActive reports whether a run is running on this server
+func (s *Server) runActive(runID string) bool {
+	s.active.mu.Lock()
+	defer s.active.mu.Unlock()
+	_, ok := s.active.cancels[runID]
+	return ok
+}
+
+// CancelRun cancels a running run. The run is marked cancelling, its
+// running jobs' containers are stopped, giving their commands a grace period
+// to exit, and jobs that haven't started are marked cancelled. The run
+// finishes as cancelled in the background. Cancelling a run that is being
+// cancelled already does nothing.
+func (s *Server) CancelRun(runID string) error {
+	s.active.mu.Lock()
+	active, ok := s.active.cancels[runID]
+	cancelling := ok && active.cancelling
+	if ok {
+		active.cancelling = true
+		s.active.cancels[runID] = active
+	}
+	s.active.mu.Unlock()
+	switch {
+	case !ok:
+		return fmt.Errorf("%w: %s", ErrNotRunning, runID)
+	case cancelling:
+		return nil
+	}
+
+	// A run whose jobs have all finished keeps the status they gave it
+	run := active.run
+	if !run.SwapStatus(runningStatus, cancellingStatus) && !run.SwapStatus(queuedStatus, cancellingStatus) {
+		return fmt.Errorf("%w: %s", ErrNotRunning, runID)
+	}
+	if err := s.updateRun(run); err != nil {
+		log.Printf("ERROR: failed to update run status in storage: %v", err)
+	}
+
+	log.Printf("Cancelling run: %s", runID)
+	active.cancel(errRunCancelled)
+	return nil
+}
+
+// runCancelled reports whether ctx was cancelled by cancelling its run
+func runCancelled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errRunCancelled)
+}
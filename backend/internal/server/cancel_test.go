@@ -0,0 +1,69 @@
+package server
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gantry/internal/models"
+)
+
+func TestCancelRun_StopsRunningAndPendingJobs(t *testing.T) {
+	exec := &fakeExecutor{hang: map[string]bool{"build": true}}
+	srv := newSchedulerTestServer(exec)
+	sub := srv.SubscribeRunEvents("run-test")
+	defer sub.Close()
+
+	deploy := testJob()
+	deploy.Needs = []string{"build"}
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"build": testJob(), "deploy": deploy},
+		JobOrder: []string{"build", "deploy"},
+	}
+
+	done := make(chan *models.WorkflowRun)
+	go func() { done <- runWorkflowSync(t, srv, wf) }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for srv.CancelRun("run-test") != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Run never started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var run *models.WorkflowRun
+	select {
+	case run = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the cancelled run to finish")
+	}
+
+	if run.Status != cancelledStatus {
+		t.Errorf("Expected run to be cancelled, got '%s'", run.Status)
+	}
+	build, _ := run.GetJob("build")
+	if build.Status != cancelledStatus || build.FailureReason != models.FailureCancelled {
+		t.Errorf("Expected running job to be cancelled, got '%s' (%s)", build.Status, build.FailureReason)
+	}
+	if deploy, _ := run.GetJob("deploy"); deploy.Status != cancelledStatus {
+		t.Errorf("Expected pending job to be cancelled, got '%s'", deploy.Status)
+	}
+
+	var statuses []string
+	for _, ev := range drainEvents(sub) {
+		if strings.HasPrefix(ev, "run_status") {
+			statuses = append(statuses, strings.Fields(ev)[1])
+		}
+	}
+	if !reflect.DeepEqual(statuses, []string{cancellingStatus, cancelledStatus}) {
+		t.Errorf("Expected the run to be cancelling until it was cancelled, got %v", statuses)
+	}
+
+	if err := srv.CancelRun("run-test"); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("Expected a finished run not to be cancellable, got %v", err)
+	}
+}
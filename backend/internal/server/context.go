@@ -0,0 +1,682 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gantry/internal/artifacts"
+	"gantry/internal/cache"
+	"gantry/internal/expr"
+	"gantry/internal/models"
+
+	"github.com/distribution/reference"
+)
+
+// jobContext builds the expression context used to evaluate a job instance's
+// conditions and templates, and returns the job's resolved environment.
+// statuses holds the final status of every finished job instance.
+func (s *Server) jobContext(run *models.WorkflowRun, plan *jobPlan, name string,
+	statuses map[string]string) (*expr.Context, map[string]string, error) {
+	job := plan.jobs[name]
+
+	// Jobs of a called workflow see their siblings by their own names
+	needs := make(map[string]interface{}, len(job.Needs))
+	for _, need := range job.Needs {
+		key := need
+		if job.Call != "" {
+			key = strings.TrimPrefix(need, job.Call+"/")
+		}
+		needs[key] = jobResultContext(run, plan.instances[need], statuses)
+	}
+
+	// jobs exposes every job that has finished, whether or not it is a dependency
+	jobs := make(map[string]interface{}, len(plan.instances))
+	for jobName, instances := range plan.instances {
+		finished := true
+		for _, instance := range instances {
+			if _, done := statuses[instance]; !done {
+				finished = false
+				break
+			}
+		}
+		if finished {
+			jobs[jobName] = jobResultContext(run, instances, statuses)
+		}
+	}
+
+	allSucceeded, anyFailed := true, false
+	for _, dep := range plan.deps[name] {
+		switch statuses[dep] {
+		case successStatus:
+		case failedStatus:
+			allSucceeded = false
+			anyFailed = true
+		default:
+			allSucceeded = false
+		}
+	}
+
+	runInputs, variables := run.Inputs, run.Variables
+	if job.Call != "" {
+		runInputs, variables = job.Inputs, job.Variables
+	}
+	inputs := make(map[string]interface{}, len(runInputs))
+	for k, v := range runInputs {
+		inputs[k] = v
+	}
+
+	matrix := make(map[string]interface{}, len(job.Matrix))
+	for k, v := range job.Matrix {
+		matrix[k] = v
+	}
+
+	exprCtx := &expr.Context{
+		Values: map[string]interface{}{
+			"needs":   needs,
+			"jobs":    jobs,
+			"matrix":  matrix,
+			"inputs":  inputs,
+			"vars":    envContext(variables),
+			"secrets": s.secretsContext(),
+			"env":     map[string]interface{}{},
+			"steps":   map[string]interface{}{},
+			"gantry":  gantryContext(run, name),
+		},
+		Functions: map[string]expr.Function{
+			"success": func(...interface{}) (interface{}, error) { return allSucceeded, nil },
+			"failure": func(...interface{}) (interface{}, error) { return anyFailed, nil },
+		},
+	}
+
+	// The run's env overrides apply over the workflow's env, as they are, and
+	// the job's env over both
+	env, err := resolveEnv(exprCtx, runEnv(run, name, runInputs), plan.env)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range run.Env {
+		env[k] = v
+	}
+	if env, err = resolveEnv(exprCtx, env, job.Env); err != nil {
+		return nil, nil, err
+	}
+
+	return exprCtx.With("env", envContext(env)), env, nil
+}
+
+// gantryContext describes the run, the job and the event that triggered it
+func gantryContext(run *models.WorkflowRun, jobName string) map[string]interface{} {
+	values := map[string]interface{}{
+		"workflow":     run.WorkflowName,
+		"workflow_id":  run.WorkflowID,
+		"run_id":       run.ID,
+		"job":          jobName,
+		"event":        "",
+		"action":       "",
+		"actor":        "",
+		"branch":       "",
+		"command":      "",
+		"pull_request": nil,
+		"repository":   "",
+		"sha":          "",
+		"payload":      map[string]interface{}{},
+	}
+	if t := run.Trigger; t != nil {
+		values["event"] = t.Event
+		values["action"] = t.Action
+		values["actor"] = t.Actor
+		values["branch"] = t.Branch
+		values["command"] = t.Command
+		values["repository"] = t.Repository
+		values["sha"] = t.SHA
+		if t.Payload != nil {
+			values["payload"] = t.Payload
+		}
+		if t.PullRequest > 0 {
+			values["pull_request"] = t.PullRequest
+		}
+	}
+	return values
+}
+
+// runEnv returns the variables every job gets describing its run, the job
+// and the event that triggered it, with each trigger input as
+// GANTRY_INPUT_<NAME>. Workflow, job and step env may override them.
+func runEnv(run *models.WorkflowRun, jobName string, inputs map[string]interface{}) map[string]string {
+	env := map[string]string{
+		"CI":                 "true",
+		"GANTRY":             "true",
+		"GANTRY_WORKFLOW":    run.WorkflowName,
+		"GANTRY_WORKFLOW_ID": run.WorkflowID,
+		"GANTRY_RUN_ID":      run.ID,
+		"GANTRY_JOB":         jobName,
+	}
+	if t := run.Trigger; t != nil {
+		for name, value := range map[string]string{
+			"GANTRY_EVENT":      t.Event,
+			"GANTRY_ACTION":     t.Action,
+			"GANTRY_ACTOR":      t.Actor,
+			"GANTRY_BRANCH":     t.Branch,
+			"GANTRY_REPOSITORY": t.Repository,
+			"GANTRY_SHA":        t.SHA,
+		} {
+			if value != "" {
+				env[name] = value
+			}
+		}
+		if t.PullRequest > 0 {
+			env["GANTRY_PULL_REQUEST"] = strconv.Itoa(t.PullRequest)
+		}
+	}
+	for name, value := range inputs {
+		env["GANTRY_INPUT_"+envName(name)] = fmt.Sprint(value)
+	}
+	return env
+}
+
+// envName turns a name into an environment variable name, upper-cased with
+// anything but letters, digits and underscores replaced by underscores
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// resolveEnv interpolates env levels in order (workflow, job, step) on top of
+// base. Each level sees the variables resolved by the levels above it.
+func resolveEnv(exprCtx *expr.Context, base map[string]string, levels ...map[string]string) (map[string]string, error) {
+	env := make(map[string]string, len(base))
+	for k, v := range base {
+		env[k] = v
+	}
+
+	for _, level := range levels {
+		resolved, err := expr.InterpolateMap(level, exprCtx.With("env", envContext(env)))
+		if err != nil {
+			return nil, fmt.Errorf("env %w", err)
+		}
+		for k, v := range resolved {
+			env[k] = v
+		}
+	}
+
+	return env, nil
+}
+
+// stepWhen evaluates a step's if condition both as if every earlier step
+// had succeeded and as if one had failed, since the executor only learns
+// which once the job runs. success() and failure() in step conditions refer
+// to the job's own steps, not to its needs. A step that runs in neither case
+// is skipped up front.
+func stepWhen(condition string, exprCtx *expr.Context) (string, bool, error) {
+	onSuccess, err := expr.EvaluateCondition(condition, withStepStatus(exprCtx, true))
+	if err != nil {
+		return "", false, err
+	}
+	onFailure, err := expr.EvaluateCondition(condition, withStepStatus(exprCtx, false))
+	if err != nil {
+		return "", false, err
+	}
+
+	switch {
+	case onSuccess && onFailure:
+		return models.StepWhenAlways, true, nil
+	case onSuccess:
+		return models.StepWhenSuccess, true, nil
+	case onFailure:
+		return models.StepWhenFailure, true, nil
+	default:
+		return "", false, nil
+	}
+}
+
+// withStepStatus returns a copy of ctx whose success() and failure() report
+// whether the job's earlier steps succeeded
+func withStepStatus(exprCtx *expr.Context, succeeded bool) *expr.Context {
+	functions := make(map[string]expr.Function, len(exprCtx.Functions)+2)
+	for name, fn := range exprCtx.Functions {
+		functions[name] = fn
+	}
+	functions["success"] = func(...interface{}) (interface{}, error) { return succeeded, nil }
+	functions["failure"] = func(...interface{}) (interface{}, error) { return !succeeded, nil }
+	return &expr.Context{Values: exprCtx.Values, Functions: functions}
+}
+
+// resolveStep interpolates a step's fields against the job context and
+// returns the context the step's own expressions should use
+func resolveStep(step models.Step, exprCtx *expr.Context, jobEnv map[string]string) (models.Step, *expr.Context, error) {
+	// Steps inlined from a composite action see the action's inputs
+	if step.Inputs != nil {
+		inputs, err := expr.InterpolateMap(step.Inputs, exprCtx)
+		if err != nil {
+			return step, nil, fmt.Errorf("inputs: %w", err)
+		}
+		actionInputs := make(map[string]interface{}, len(inputs))
+		for k, v := range inputs {
+			actionInputs[k] = v
+		}
+		exprCtx = exprCtx.With("inputs", actionInputs)
+	}
+
+	env, err := resolveEnv(exprCtx, jobEnv, step.Env)
+	if err != nil {
+		return step, nil, err
+	}
+	stepCtx := exprCtx.With("env", envContext(env))
+
+	resolved := step
+	if resolved.Name, err = expr.Interpolate(step.Name, stepCtx); err != nil {
+		return step, nil, err
+	}
+	if resolved.Run, err = expr.Interpolate(step.Run, stepCtx); err != nil {
+		return step, nil, err
+	}
+	if resolved.WorkingDirectory, err = expr.Interpolate(step.WorkingDirectory, stepCtx); err != nil {
+		return step, nil, fmt.Errorf("working-directory: %w", err)
+	}
+	if resolved.UploadArtifact, err = resolveArtifact(step.UploadArtifact, stepCtx); err != nil {
+		return step, nil, fmt.Errorf("upload-artifact: %w", err)
+	}
+	if resolved.DownloadArtifact, err = resolveArtifact(step.DownloadArtifact, stepCtx); err != nil {
+		return step, nil, fmt.Errorf("download-artifact: %w", err)
+	}
+	if resolved.Cache, err = resolveCache(step.Cache, stepCtx); err != nil {
+		return step, nil, fmt.Errorf("cache: %w", err)
+	}
+	if resolved.Build, err = resolveBuild(step.Build, stepCtx); err != nil {
+		return step, nil, fmt.Errorf("build: %w", err)
+	}
+	if len(step.Env) > 0 {
+		resolved.Env = make(map[string]string, len(step.Env))
+		for k := range step.Env {
+			resolved.Env[k] = env[k]
+		}
+	}
+
+	return resolved, stepCtx, nil
+}
+
+// resolveArtifact interpolates the name and path of an artifact step
+func resolveArtifact(a *models.ArtifactStep, exprCtx *expr.Context) (*models.ArtifactStep, error) {
+	if a == nil {
+		return nil, nil
+	}
+
+	resolved := &models.ArtifactStep{}
+	var err error
+	if resolved.Name, err = expr.Interpolate(a.Name, exprCtx); err != nil {
+		return nil, fmt.Errorf("name: %w", err)
+	}
+	if err := artifacts.ValidateName(resolved.Name); err != nil {
+		return nil, err
+	}
+	if resolved.Path, err = expr.Interpolate(a.Path, exprCtx); err != nil {
+		return nil, fmt.Errorf("path: %w", err)
+	}
+	return resolved, nil
+}
+
+// resolveJobArtifacts interpolates the artifacts a job collects when it finishes
+func resolveJobArtifacts(list []models.ArtifactStep, exprCtx *expr.Context) ([]models.ArtifactStep, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	resolved := make([]models.ArtifactStep, 0, len(list))
+	for _, a := range list {
+		r, err := resolveArtifact(&a, exprCtx)
+		if err != nil {
+			return nil, fmt.Errorf("artifact '%s': %w", a.Name, err)
+		}
+		resolved = append(resolved, *r)
+	}
+	return resolved, nil
+}
+
+// resolveCache interpolates the path and keys of a cache step
+func resolveCache(c *models.CacheStep, exprCtx *expr.Context) (*models.CacheStep, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	resolved := &models.CacheStep{}
+	var err error
+	if resolved.Path, err = expr.Interpolate(c.Path, exprCtx); err != nil {
+		return nil, fmt.Errorf("path: %w", err)
+	}
+	if resolved.Key, err = expr.Interpolate(c.Key, exprCtx); err != nil {
+		return nil, fmt.Errorf("key: %w", err)
+	}
+	if err := cache.ValidateKey(resolved.Key); err != nil {
+		return nil, err
+	}
+	for _, key := range c.RestoreKeys {
+		value, err := expr.Interpolate(key, exprCtx)
+		if err != nil {
+			return nil, fmt.Errorf("restore-keys: %w", err)
+		}
+		resolved.RestoreKeys = append(resolved.RestoreKeys, value)
+	}
+	return resolved, nil
+}
+
+// resolveCacheVolumes interpolates the keys and paths of a job's cache volumes
+func resolveCacheVolumes(volumes []models.CacheVolume, exprCtx *expr.Context) ([]models.CacheVolume, error) {
+	if len(volumes) == 0 {
+		return nil, nil
+	}
+
+	resolved := make([]models.CacheVolume, 0, len(volumes))
+	for _, v := range volumes {
+		key, err := expr.Interpolate(v.Key, exprCtx)
+		if err != nil {
+			return nil, fmt.Errorf("cache volume key: %w", err)
+		}
+		if err := cache.ValidateKey(key); err != nil {
+			return nil, fmt.Errorf("cache volume: %w", err)
+		}
+		target, err := expr.Interpolate(v.Path, exprCtx)
+		if err != nil {
+			return nil, fmt.Errorf("cache volume '%s' path: %w", key, err)
+		}
+		resolved = append(resolved, models.CacheVolume{Key: key, Path: target})
+	}
+	return resolved, nil
+}
+
+// resolveBuild interpolates the options of a build step and checks its tags
+func resolveBuild(b *models.BuildStep, exprCtx *expr.Context) (*models.BuildStep, error) {
+	if b == nil {
+		return nil, nil
+	}
+
+	resolved := &models.BuildStep{Push: b.Push}
+	fields := []struct {
+		name     string
+		template string
+		value    *string
+	}{
+		{"context", b.Context, &resolved.Context},
+		{"dockerfile", b.Dockerfile, &resolved.Dockerfile},
+		{"target", b.Target, &resolved.Target},
+		{"username", b.Username, &resolved.Username},
+		{"password", b.Password, &resolved.Password},
+	}
+	for _, f := range fields {
+		value, err := expr.Interpolate(f.template, exprCtx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.name, err)
+		}
+		*f.value = value
+	}
+	for _, tag := range b.Tags {
+		value, err := expr.Interpolate(tag, exprCtx)
+		if err != nil {
+			return nil, fmt.Errorf("tags: %w", err)
+		}
+		if _, err := reference.ParseNormalizedNamed(value); err != nil {
+			return nil, fmt.Errorf("tag '%s' is not a valid image reference: %w", value, err)
+		}
+		resolved.Tags = append(resolved.Tags, value)
+	}
+	var err error
+	if resolved.BuildArgs, err = expr.InterpolateMap(b.BuildArgs, exprCtx); err != nil {
+		return nil, fmt.Errorf("build-args: %w", err)
+	}
+	return resolved, nil
+}
+
+// resolveContainer interpolates a job's container options
+func resolveContainer(c *models.Container, exprCtx *expr.Context) (*models.Container, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	resolved := &models.Container{}
+	var err error
+	if resolved.Image, err = expr.Interpolate(c.Image, exprCtx); err != nil {
+		return nil, fmt.Errorf("image: %w", err)
+	}
+	if resolved.User, err = expr.Interpolate(c.User, exprCtx); err != nil {
+		return nil, fmt.Errorf("user: %w", err)
+	}
+	for _, arg := range c.Entrypoint {
+		value, err := expr.Interpolate(arg, exprCtx)
+		if err != nil {
+			return nil, fmt.Errorf("entrypoint: %w", err)
+		}
+		resolved.Entrypoint = append(resolved.Entrypoint, value)
+	}
+	if resolved.Env, err = expr.InterpolateMap(c.Env, exprCtx); err != nil {
+		return nil, fmt.Errorf("env: %w", err)
+	}
+	if resolved.Credentials, err = resolveCredentials(c.Credentials, exprCtx); err != nil {
+		return nil, fmt.Errorf("credentials: %w", err)
+	}
+
+	return resolved, nil
+}
+
+// resolveCredentials interpolates the username and password of registry
+// credentials
+func resolveCredentials(r *models.RegistryCredentials, exprCtx *expr.Context) (*models.RegistryCredentials, error) {
+	if r == nil {
+		return nil, nil
+	}
+
+	resolved := &models.RegistryCredentials{}
+	var err error
+	if resolved.Username, err = expr.Interpolate(r.Username, exprCtx); err != nil {
+		return nil, fmt.Errorf("username: %w", err)
+	}
+	if resolved.Password, err = expr.Interpolate(r.Password, exprCtx); err != nil {
+		return nil, fmt.Errorf("password: %w", err)
+	}
+	return resolved, nil
+}
+
+// resolveCheckout interpolates a job's checkout and fills in the
+// repository and ref of the event that triggered the run. A ref is only
+// taken from the event along with its repository.
+func resolveCheckout(c *models.Checkout, exprCtx *expr.Context, trigger *models.TriggerInfo) (*models.Checkout, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	resolved := &models.Checkout{Depth: c.Depth}
+	fields := []struct {
+		name     string
+		template string
+		value    *string
+	}{
+		{"repository", c.Repository, &resolved.Repository},
+		{"ref", c.Ref, &resolved.Ref},
+		{"path", c.Path, &resolved.Path},
+		{"token", c.Token, &resolved.Token},
+		{"ssh-key", c.SSHKey, &resolved.SSHKey},
+	}
+	for _, f := range fields {
+		value, err := expr.Interpolate(f.template, exprCtx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.name, err)
+		}
+		*f.value = value
+	}
+
+	if resolved.Repository == "" && trigger != nil && trigger.Repository != "" {
+		resolved.Repository = trigger.Repository
+		if resolved.Ref == "" {
+			resolved.Ref = trigger.SHA
+		}
+		if resolved.Ref == "" {
+			resolved.Ref = trigger.Branch
+		}
+	}
+	if resolved.Repository == "" {
+		return nil, fmt.Errorf("no repository to check out; set checkout.repository or trigger the run with an event that has one")
+	}
+	return resolved, nil
+}
+
+// resolveServices interpolates each service's image, env and command
+func resolveServices(services map[string]models.Service, exprCtx *expr.Context) (map[string]models.Service, error) {
+	if len(services) == 0 {
+		return nil, nil
+	}
+
+	resolved := make(map[string]models.Service, len(services))
+	for name, svc := range services {
+		var err error
+		out := svc
+		if out.Image, err = expr.Interpolate(svc.Image, exprCtx); err != nil {
+			return nil, fmt.Errorf("service '%s' image: %w", name, err)
+		}
+		if out.Env, err = expr.InterpolateMap(svc.Env, exprCtx); err != nil {
+			return nil, fmt.Errorf("service '%s' env: %w", name, err)
+		}
+		out.Command = nil
+		for _, arg := range svc.Command {
+			value, err := expr.Interpolate(arg, exprCtx)
+			if err != nil {
+				return nil, fmt.Errorf("service '%s' command: %w", name, err)
+			}
+			out.Command = append(out.Command, value)
+		}
+		if out.Credentials, err = resolveCredentials(svc.Credentials, exprCtx); err != nil {
+			return nil, fmt.Errorf("service '%s' credentials: %w", name, err)
+		}
+		resolved[name] = out
+	}
+	return resolved, nil
+}
+
+// usesStepsContext reports whether a step's condition or templates read the
+// steps context, in which case it can only be resolved once the steps before
+// it have run
+func usesStepsContext(step models.Step) (bool, error) {
+	if step.If != "" {
+		e, err := expr.ParseCondition(step.If)
+		if err != nil {
+			return false, err
+		}
+		if e.UsesContext("steps") {
+			return true, nil
+		}
+	}
+	for _, t := range step.Templates() {
+		uses, err := expr.TemplateUsesContext(t, "steps")
+		if err != nil || uses {
+			return uses, err
+		}
+	}
+	return false, nil
+}
+
+// stepStates returns the state of each step with an id that has finished,
+// given the results of the steps handed to the executor so far. Steps
+// skipped up front are marked skipped on steps and have no result.
+func stepStates(steps []models.Step, results []models.StepResult) map[string]models.StepState {
+	states := make(map[string]models.StepState)
+	next := 0
+	for _, step := range steps {
+		var state models.StepState
+		switch {
+		case step.Status == skippedStatus:
+			state = models.StepState{Outcome: skippedStatus, Conclusion: skippedStatus}
+		case next < len(results):
+			state = stepState(step, results[next])
+			next++
+		default:
+			continue
+		}
+		if step.ID != "" {
+			states[step.ID] = state
+		}
+	}
+	return states
+}
+
+// stepState describes a step's result as steps.<id> exposes it
+func stepState(step models.Step, res models.StepResult) models.StepState {
+	if res.Skipped {
+		return models.StepState{Outcome: skippedStatus, Conclusion: skippedStatus}
+	}
+
+	state := models.StepState{Outcome: successStatus, Conclusion: successStatus, Outputs: res.Outputs}
+	if !res.Success {
+		state.Outcome = failedStatus
+		if !step.ContinueOnError {
+			state.Conclusion = failedStatus
+		}
+	}
+	return state
+}
+
+// stepsContext exposes step states to expressions as steps.<id>
+func stepsContext(states map[string]models.StepState) map[string]interface{} {
+	values := make(map[string]interface{}, len(states))
+	for id, state := range states {
+		outputs := make(map[string]interface{}, len(state.Outputs))
+		for k, v := range state.Outputs {
+			outputs[k] = v
+		}
+		values[id] = map[string]interface{}{
+			"outcome":    state.Outcome,
+			"conclusion": state.Conclusion,
+			"outputs":    outputs,
+		}
+	}
+	return values
+}
+
+func envContext(env map[string]string) map[string]interface{} {
+	values := make(map[string]interface{}, len(env))
+	for k, v := range env {
+		values[k] = v
+	}
+	return values
+}
+
+// jobResultContext describes a finished job for the needs and jobs contexts
+func jobResultContext(run *models.WorkflowRun, instances []string, statuses map[string]string) map[string]interface{} {
+	return map[string]interface{}{
+		"result":  aggregateResult(instances, statuses),
+		"outputs": aggregateOutputs(run, instances),
+	}
+}
+
+// aggregateOutputs merges the outputs of a job's instances; for matrix jobs a
+// later leg's value replaces an earlier one
+func aggregateOutputs(run *models.WorkflowRun, instances []string) map[string]interface{} {
+	outputs := make(map[string]interface{})
+	for _, instance := range instances {
+		job, _ := run.GetJob(instance)
+		for k, v := range job.Outputs {
+			outputs[k] = v
+		}
+	}
+	return outputs
+}
+
+// aggregateResult combines the statuses of a job's instances (one per matrix
+// leg) into a single result: any failure wins, then any skip
+func aggregateResult(instances []string, statuses map[string]string) string {
+	result := successStatus
+	for _, instance := range instances {
+		switch statuses[instance] {
+		case failedStatus:
+			return failedStatus
+		case successStatus:
+		default:
+			result = skippedStatus
+		}
+	}
+	return result
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gantry/internal/environments"
+	"gantry/internal/expr"
+	"gantry/internal/models"
+)
+
+// waitingStatus marks a job that waits for a reviewer of its environment
+const waitingStatus = "waiting"
+
+// ErrNotWaiting is returned when reviewing a job that isn't waiting for approval
+var ErrNotWaiting = errors.New("job is not waiting for approval")
+
+// ErrNotReviewer is returned when someone who isn't one of its environment's
+// reviewers reviews a job
+var ErrNotReviewer = errors.New("not a reviewer of the job's environment")
+
+// approvalGate holds the jobs waiting for a review, keyed by run and job
+type approvalGate struct {
+	mu      sync.Mutex
+	waiting map[string]pendingApproval
+}
+
+// pendingApproval is a job waiting for one of its environment's reviewers
+type pendingApproval struct {
+	environment environments.Environment
+	review      chan models.Approval
+}
+
+func approvalKey(runID, jobName string) string {
+	return runID + "/" + jobName
+}
+
+// checkEnvironments returns an error naming the first environment a job
+// targets that is not registered. Environments chosen by an expression are
+// checked when the job runs.
+func (s *Server) checkEnvironments(jobs ...models.Job) error {
+	for _, job := range jobs {
+		if job.Environment == "" || expr.HasExpressions(job.Environment) {
+			continue
+		}
+		if _, err := s.environment(job.Environment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// environment looks up a registered environment
+func (s *Server) environment(name string) (environments.Environment, error) {
+	if s.environments != nil {
+		if env, ok := s.environments.Get(name); ok {
+			return env, nil
+		}
+	}
+	return environments.Environment{}, fmt.Errorf("environment '%s' is not defined", name)
+}
+
+// checkEnvironmentRules resolves the environment a job targets and checks
+// that the run's branch may deploy to it
+func (s *Server) checkEnvironmentRules(run *models.WorkflowRun, name string) (environments.Environment, error) {
+	env, err := s.environment(name)
+	if err != nil {
+		return env, err
+	}
+
+	branch := ""
+	if run.Trigger != nil {
+		branch = run.Trigger.Branch
+	}
+	if !env.AllowsBranch(branch) {
+		return env, fmt.Errorf("environment '%s' does not allow deployments from branch '%s'", name, branch)
+	}
+	return env, nil
+}
+
+// awaitApproval records a job as waiting and blocks until one of its
+// environment's reviewers reviews it or ctx is cancelled
+func (s *Server) awaitApproval(ctx context.Context, run *models.WorkflowRun, jobName string, job models.Job,
+	env environments.Environment) (*models.Approval, error) {
+	key := approvalKey(run.ID, jobName)
+	pending := pendingApproval{environment: env, review: make(chan models.Approval, 1)}
+
+	s.approvals.mu.Lock()
+	if s.approvals.waiting == nil {
+		s.approvals.waiting = make(map[string]pendingApproval)
+	}
+	s.approvals.waiting[key] = pending
+	s.approvals.mu.Unlock()
+
+	defer func() {
+		s.approvals.mu.Lock()
+		delete(s.approvals.waiting, key)
+		s.approvals.mu.Unlock()
+	}()
+
+	log.Printf("Job %s is waiting for approval to deploy to %s", jobName, env.Name)
+	job.Status = waitingStatus
+	run.UpdateJob(jobName, job)
+	if err := s.updateRun(run); err != nil {
+		log.Printf("ERROR: failed to update run status in storage: %v", err)
+	}
+
+	select {
+	case approval := <-pending.review:
+		return &approval, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// ReviewJob approves or rejects a job waiting for its environment. Only the
+// environment's reviewers may review it.
+func (s *Server) ReviewJob(runID, jobName, reviewer string, approve bool) error {
+	key := approvalKey(runID, jobName)
+
+	s.approvals.mu.Lock()
+	defer s.approvals.mu.Unlock()
+
+	pending, ok := s.approvals.waiting[key]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotWaiting, jobName)
+	}
+	if !pending.environment.CanApprove(reviewer) {
+		return fmt.Errorf("%w: %s", ErrNotReviewer, reviewer)
+	}
+
+	delete(s.approvals.waiting, key)
+	pending.review <- models.Approval{Reviewer: reviewer, Approved: approve, ReviewedAt: time.Now()}
+	return nil
+}
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gantry/internal/environments"
+	"gantry/internal/models"
+)
+
+func newEnvironmentTestServer(t *testing.T, exec *fakeExecutor) *Server {
+	t.Helper()
+
+	store := environments.NewMemoryStore()
+	for _, env := range []environments.Environment{
+		{Name: "production", Reviewers: []string{"alice"}, Branches: []string{"main"}},
+		{Name: "staging"},
+	} {
+		if err := store.Set(env); err != nil {
+			t.Fatalf("Failed to register environment: %v", err)
+		}
+	}
+
+	srv := newSchedulerTestServer(exec)
+	srv.environments = store
+	return srv
+}
+
+// startEnvironmentRun starts a run of a single deploy job on branch and
+// returns a channel closed once it finishes
+func startEnvironmentRun(t *testing.T, srv *Server, environment, branch string) (*models.WorkflowRun, <-chan struct{}) {
+	t.Helper()
+
+	deploy := testJob()
+	deploy.Environment = environment
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"deploy": deploy},
+		JobOrder: []string{"deploy"},
+	}
+
+	plan := buildJobPlan(wf, nil)
+	run := &models.WorkflowRun{
+		ID:           "run-env",
+		WorkflowName: wf.Name,
+		Status:       runningStatus,
+		Jobs:         make(map[string]models.Job),
+		JobOrder:     plan.order,
+		Trigger:      &models.TriggerInfo{Event: models.EventPush, Branch: branch},
+		StartedAt:    time.Now(),
+	}
+	if err := srv.storage.SaveRun(run); err != nil {
+		t.Fatalf("Failed to save run: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		srv.runJobs(context.Background(), run, plan)
+		close(done)
+	}()
+	return run, done
+}
+
+// waitForJobStatus polls until a job of run reaches status
+func waitForJobStatus(t *testing.T, run *models.WorkflowRun, jobName, status string) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if job, _ := run.GetJob(jobName); job.Status == status {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Job %s never reached status '%s'", jobName, status)
+}
+
+func TestRunJob_WaitsForEnvironmentApproval(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newEnvironmentTestServer(t, exec)
+
+	run, done := startEnvironmentRun(t, srv, "production", "main")
+	waitForJobStatus(t, run, "deploy", waitingStatus)
+
+	if len(exec.order) != 0 {
+		t.Fatalf("Expected the job not to run before approval, got %v", exec.order)
+	}
+	if err := srv.ReviewJob(run.ID, "deploy", "mallory", true); !errors.Is(err, ErrNotReviewer) {
+		t.Errorf("Expected ErrNotReviewer, got %v", err)
+	}
+	if err := srv.ReviewJob(run.ID, "deploy", "alice", true); err != nil {
+		t.Fatalf("ReviewJob returned error: %v", err)
+	}
+	<-done
+
+	job, _ := run.GetJob("deploy")
+	if job.Status != successStatus {
+		t.Errorf("Expected approved job to succeed, got '%s'", job.Status)
+	}
+	if job.Approval == nil || job.Approval.Reviewer != "alice" || !job.Approval.Approved {
+		t.Errorf("Expected approval by alice to be recorded, got %+v", job.Approval)
+	}
+	if err := srv.ReviewJob(run.ID, "deploy", "alice", true); !errors.Is(err, ErrNotWaiting) {
+		t.Errorf("Expected ErrNotWaiting once the job ran, got %v", err)
+	}
+}
+
+func TestRunJob_RejectedDeploymentFails(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newEnvironmentTestServer(t, exec)
+
+	run, done := startEnvironmentRun(t, srv, "production", "main")
+	waitForJobStatus(t, run, "deploy", waitingStatus)
+	if err := srv.ReviewJob(run.ID, "deploy", "alice", false); err != nil {
+		t.Fatalf("ReviewJob returned error: %v", err)
+	}
+	<-done
+
+	job, _ := run.GetJob("deploy")
+	if job.Status != failedStatus || job.FailureReason != models.FailureRejected {
+		t.Errorf("Expected rejected job to fail as rejected, got '%s' (%s)", job.Status, job.FailureReason)
+	}
+	if len(exec.order) != 0 {
+		t.Errorf("Expected rejected job not to run, got %v", exec.order)
+	}
+}
+
+func TestRunJob_EnvironmentRules(t *testing.T) {
+	tests := []struct {
+		name        string
+		environment string
+		branch      string
+		wantStatus  string
+		wantOutput  string
+	}{
+		{"unprotected", "staging", "feature/x", successStatus, ""},
+		{"disallowed branch", "production", "feature/x", failedStatus, "does not allow deployments from branch 'feature/x'"},
+		{"unknown", "qa", "main", failedStatus, "environment 'qa' is not defined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newEnvironmentTestServer(t, &fakeExecutor{})
+
+			run, done := startEnvironmentRun(t, srv, tt.environment, tt.branch)
+			<-done
+
+			job, _ := run.GetJob("deploy")
+			if job.Status != tt.wantStatus || !strings.Contains(job.Output, tt.wantOutput) {
+				t.Errorf("Expected status '%s' with output containing %q, got '%s': %s",
+					tt.wantStatus, tt.wantOutput, job.Status, job.Output)
+			}
+		})
+	}
+}
+
+func TestParseAndSaveWorkflow_UnknownEnvironment(t *testing.T) {
+	srv := newEnvironmentTestServer(t, &fakeExecutor{})
+
+	yaml := "name: Deploy\njobs:\n  deploy:\n    runs-on: ubuntu\n    environment: qa\n    steps:\n      - name: Deploy\n        run: deploy\n"
+	if _, err := srv.ParseAndSaveWorkflow([]byte(yaml), ""); err == nil || !strings.Contains(err.Error(), "'qa'") {
+		t.Errorf("Expected error naming the unknown environment, got %v", err)
+	}
+
+	dynamic := strings.Replace(yaml, "qa", "${{ inputs.target }}", 1)
+	if _, err := srv.ParseAndSaveWorkflow([]byte(dynamic), ""); err != nil {
+		t.Errorf("Expected environments chosen by an expression to be checked at run time, got %v", err)
+	}
+}
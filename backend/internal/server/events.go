@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"gantry/internal/glob"
+	"gantry/internal/models"
+	"gantry/internal/storage"
+)
+
+// ErrInvalidEvent is returned for inbound events that can't be dispatched
+var ErrInvalidEvent = errors.New("invalid event")
+
+// Event is an inbound trigger event, normalized across webhook providers
+type Event struct {
+	Name     string `json:"event"`
+	Workflow string `json:"workflow,omitempty"` // optional; restricts dispatch to one workflow
+	Action   string `json:"action,omitempty"`   // e.g. opened, synchronize
+	Actor    string `json:"actor,omitempty"`
+	Branch   string `json:"branch,omitempty"` // pushed branch, or pull request target branch
+	Comment  string `json:"comment,omitempty"`
+
+	// PullRequest is the number of the pull request the event concerns, if any
+	PullRequest int `json:"pull_request,omitempty"`
+
+	// Repository is the clone URL of the repository, and SHA the pushed or
+	// pull request head commit, that jobs check out by default
+	Repository string `json:"repository,omitempty"`
+	SHA        string `json:"sha,omitempty"`
+
+	// Commit describes the commit SHA names
+	Commit *models.CommitInfo `json:"commit,omitempty"`
+}
+
+// DispatchEvent starts a run of every workflow whose triggers match the
+// event and returns the started runs
+func (s *Server) DispatchEvent(ctx context.Context, ev Event) ([]*models.WorkflowRun, error) {
+	switch ev.Name {
+	case models.EventPush, models.EventIssueComment, models.EventPullRequest:
+	default:
+		return nil, fmt.Errorf("%w: unsupported event '%s'", ErrInvalidEvent, ev.Name)
+	}
+
+	workflows, _, err := s.storage.ListWorkflows(storage.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	runs := []*models.WorkflowRun{}
+	for _, wf := range workflows {
+		if ev.Workflow != "" && wf.Name != ev.Workflow {
+			continue
+		}
+
+		trigger, ok := matchEvent(wf, ev)
+		if !ok {
+			continue
+		}
+
+		log.Printf("Event %s triggered workflow %s", ev.Name, wf.Name)
+		run, err := s.executeWorkflow(ctx, wf, trigger, runParams{variables: wf.Variables}, "")
+		if err != nil {
+			return runs, fmt.Errorf("failed to start workflow '%s': %w", wf.Name, err)
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, nil
+}
+
+// matchEvent reports whether a workflow's triggers accept an event and
+// returns what should be recorded on the run
+func matchEvent(wf *models.Workflow, ev Event) (*models.TriggerInfo, bool) {
+	switch ev.Name {
+	case models.EventPush:
+		cfg := wf.On.Push
+		if cfg == nil || !glob.Filter(cfg.Branches, ev.Branch) {
+			return nil, false
+		}
+
+		return &models.TriggerInfo{
+			Event:      ev.Name,
+			Actor:      ev.Actor,
+			Branch:     ev.Branch,
+			Repository: ev.Repository,
+			SHA:        ev.SHA,
+			Commit:     ev.Commit,
+		}, true
+
+	case models.EventIssueComment:
+		cfg := wf.On.IssueComment
+		if cfg == nil {
+			return nil, false
+		}
+
+		command := parseCommand(ev.Comment)
+		if command == "" {
+			return nil, false
+		}
+		if len(cfg.Commands) > 0 && !containsFold(cfg.Commands, command) {
+			return nil, false
+		}
+
+		return &models.TriggerInfo{
+			Event:       ev.Name,
+			Actor:       ev.Actor,
+			Command:     command,
+			PullRequest: ev.PullRequest,
+			Repository:  ev.Repository,
+			SHA:         ev.SHA,
+		}, true
+
+	case models.EventPullRequest:
+		cfg := wf.On.PullRequest
+		if cfg == nil {
+			return nil, false
+		}
+
+		types := cfg.Types
+		if len(types) == 0 {
+			types = models.DefaultPullRequestTypes
+		}
+		if !containsFold(types, ev.Action) {
+			return nil, false
+		}
+		if !glob.Filter(cfg.Branches, ev.Branch) {
+			return nil, false
+		}
+
+		return &models.TriggerInfo{
+			Event:       ev.Name,
+			Action:      ev.Action,
+			Actor:       ev.Actor,
+			Branch:      ev.Branch,
+			PullRequest: ev.PullRequest,
+			Repository:  ev.Repository,
+			SHA:         ev.SHA,
+			Commit:      ev.Commit,
+		}, true
+	}
+
+	return nil, false
+}
+
+// parseCommand returns the slash command a comment starts with, if any
+func parseCommand(comment string) string {
+	fields := strings.Fields(comment)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") || len(fields[0]) == 1 {
+		return ""
+	}
+	return fields[0]
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
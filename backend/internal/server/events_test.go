@@ -0,0 +1,254 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"gantry/internal/models"
+	"gantry/internal/parser"
+	"gantry/internal/storage"
+)
+
+func newEventTestServer(t *testing.T, exec *fakeExecutor, workflows ...*models.Workflow) *Server {
+	t.Helper()
+
+	srv := &Server{
+		storage:  storage.NewMemoryStorage(),
+		executor: exec,
+		parser:   parser.NewParser(),
+	}
+	for _, wf := range workflows {
+		if err := srv.storage.SaveWorkflow(wf); err != nil {
+			t.Fatalf("Failed to save workflow: %v", err)
+		}
+	}
+	return srv
+}
+
+func commentWorkflow(name string, commands ...string) *models.Workflow {
+	job := testJob()
+	job.Steps[0].Run = "echo ${{ gantry.command }} by ${{ gantry.actor }}"
+	return &models.Workflow{
+		Name:     name,
+		On:       models.TriggerConfig{IssueComment: &models.CommentConfig{Commands: commands}},
+		Jobs:     map[string]models.Job{"test": job},
+		JobOrder: []string{"test"},
+	}
+}
+
+func TestServer_DispatchEvent_CommentCommand(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newEventTestServer(t, exec,
+		commentWorkflow("tests", "/retest"),
+		commentWorkflow("deploy", "/deploy"),
+		&models.Workflow{Name: "push-only", Jobs: map[string]models.Job{"test": testJob()}, JobOrder: []string{"test"}},
+	)
+
+	runs, err := srv.DispatchEvent(context.Background(), Event{
+		Name:    models.EventIssueComment,
+		Actor:   "octocat",
+		Comment: "/retest please\nflaky again",
+	})
+	if err != nil {
+		t.Fatalf("DispatchEvent returned error: %v", err)
+	}
+	if len(runs) != 1 || runs[0].WorkflowName != "tests" {
+		t.Fatalf("Expected only 'tests' to run, got %v", runs)
+	}
+
+	trigger := runs[0].Trigger
+	if trigger == nil || trigger.Event != models.EventIssueComment || trigger.Actor != "octocat" || trigger.Command != "/retest" {
+		t.Errorf("Unexpected trigger recorded: %+v", trigger)
+	}
+
+	waitForRun(t, srv, runs[0].ID)
+
+	exec.mu.Lock()
+	defer exec.mu.Unlock()
+	if got := exec.jobs["test"].Steps[0].Run; got != "echo /retest by octocat" {
+		t.Errorf("Expected command and actor in context, got %q", got)
+	}
+}
+
+func TestServer_DispatchEvent_IgnoresNonCommands(t *testing.T) {
+	srv := newEventTestServer(t, &fakeExecutor{}, commentWorkflow("any"))
+
+	for _, comment := range []string{"looks good", "", "/"} {
+		runs, err := srv.DispatchEvent(context.Background(), Event{Name: models.EventIssueComment, Comment: comment})
+		if err != nil {
+			t.Fatalf("DispatchEvent returned error: %v", err)
+		}
+		if len(runs) != 0 {
+			t.Errorf("Expected comment %q not to trigger, got %d runs", comment, len(runs))
+		}
+	}
+
+	runs, err := srv.DispatchEvent(context.Background(), Event{
+		Name: models.EventIssueComment, Comment: "/rebuild", Workflow: "other",
+	})
+	if err != nil || len(runs) != 0 {
+		t.Errorf("Expected event for another workflow to be ignored, got %d runs, err %v", len(runs), err)
+	}
+}
+
+func TestServer_DispatchEvent_UnsupportedEvent(t *testing.T) {
+	srv := newEventTestServer(t, &fakeExecutor{})
+
+	if _, err := srv.DispatchEvent(context.Background(), Event{Name: "deployment"}); !errors.Is(err, ErrInvalidEvent) {
+		t.Errorf("Expected ErrInvalidEvent, got %v", err)
+	}
+}
+
+func TestServer_DispatchEvent_PullRequest(t *testing.T) {
+	prWorkflow := func(name string, cfg *models.PullRequestConfig) *models.Workflow {
+		return &models.Workflow{
+			Name:     name,
+			On:       models.TriggerConfig{PullRequest: cfg},
+			Jobs:     map[string]models.Job{"test": testJob()},
+			JobOrder: []string{"test"},
+		}
+	}
+
+	srv := newEventTestServer(t, &fakeExecutor{},
+		prWorkflow("ci", &models.PullRequestConfig{}),
+		prWorkflow("main-only", &models.PullRequestConfig{Branches: []string{"main"}}),
+		prWorkflow("on-close", &models.PullRequestConfig{Types: []string{models.PullRequestClosed}}),
+	)
+
+	dispatch := func(action, branch string) map[string]*models.WorkflowRun {
+		t.Helper()
+		runs, err := srv.DispatchEvent(context.Background(), Event{
+			Name: models.EventPullRequest, Action: action, Branch: branch, Actor: "octocat", PullRequest: 42,
+		})
+		if err != nil {
+			t.Fatalf("DispatchEvent returned error: %v", err)
+		}
+		byName := make(map[string]*models.WorkflowRun)
+		for _, run := range runs {
+			byName[run.WorkflowName] = run
+			waitForRun(t, srv, run.ID)
+		}
+		return byName
+	}
+
+	runs := dispatch(models.PullRequestOpened, "main")
+	if len(runs) != 2 || runs["ci"] == nil || runs["main-only"] == nil {
+		t.Fatalf("Expected ci and main-only to run for opened on main, got %v", runs)
+	}
+	if trigger := runs["ci"].Trigger; trigger.PullRequest != 42 || trigger.Action != "opened" || trigger.Branch != "main" {
+		t.Errorf("Unexpected trigger recorded: %+v", trigger)
+	}
+
+	if runs := dispatch(models.PullRequestSynchronize, "release"); len(runs) != 1 || runs["ci"] == nil {
+		t.Errorf("Expected only ci to run for synchronize on release, got %v", runs)
+	}
+	if runs := dispatch(models.PullRequestClosed, "main"); len(runs) != 1 || runs["on-close"] == nil {
+		t.Errorf("Expected only on-close to run for closed, got %v", runs)
+	}
+}
+
+func TestServer_DispatchEvent_PushBranchFilters(t *testing.T) {
+	pushWorkflow := func(name string, branches ...string) *models.Workflow {
+		return &models.Workflow{
+			Name:     name,
+			On:       models.TriggerConfig{Push: &models.PushConfig{Branches: branches}},
+			Jobs:     map[string]models.Job{"test": testJob()},
+			JobOrder: []string{"test"},
+		}
+	}
+
+	srv := newEventTestServer(t, &fakeExecutor{},
+		pushWorkflow("any"),
+		pushWorkflow("releases", "main", "release/*"),
+		pushWorkflow("no-wip", "!wip/**"),
+		commentWorkflow("comments"),
+	)
+
+	dispatch := func(branch string) []string {
+		t.Helper()
+		runs, err := srv.DispatchEvent(context.Background(), Event{Name: models.EventPush, Branch: branch, Actor: "octocat"})
+		if err != nil {
+			t.Fatalf("DispatchEvent returned error: %v", err)
+		}
+		names := make([]string, 0, len(runs))
+		for _, run := range runs {
+			waitForRun(t, srv, run.ID)
+			if run.Trigger.Branch != branch {
+				t.Errorf("Expected branch '%s' to be recorded, got '%s'", branch, run.Trigger.Branch)
+			}
+			names = append(names, run.WorkflowName)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	tests := map[string][]string{
+		"release/1.2":   {"any", "no-wip", "releases"},
+		"feature/login": {"any", "no-wip"},
+		"wip/spike/a":   {"any"},
+	}
+	for branch, want := range tests {
+		if got := dispatch(branch); !reflect.DeepEqual(got, want) {
+			t.Errorf("Push to %s: expected %v to run, got %v", branch, want, got)
+		}
+	}
+}
+
+func TestServer_DispatchEvent_PushCheckout(t *testing.T) {
+	job := testJob()
+	job.Checkout = &models.Checkout{Path: "src"}
+	exec := &fakeExecutor{}
+	srv := newEventTestServer(t, exec, &models.Workflow{
+		Name:     "build",
+		On:       models.TriggerConfig{Push: &models.PushConfig{}},
+		Jobs:     map[string]models.Job{"test": job},
+		JobOrder: []string{"test"},
+	})
+
+	const sha = "3f786850e387550fdab836ed7e6dc881de23001b"
+	runs, err := srv.DispatchEvent(context.Background(), Event{
+		Name:       models.EventPush,
+		Branch:     "main",
+		Repository: "https://git.example.com/org/app.git",
+		SHA:        sha,
+	})
+	if err != nil || len(runs) != 1 {
+		t.Fatalf("DispatchEvent() = %v, %v; want one run", runs, err)
+	}
+	if run := waitForRun(t, srv, runs[0].ID); run.Status != successStatus {
+		t.Fatalf("Expected the run to succeed, got '%s'", run.Status)
+	}
+
+	exec.mu.Lock()
+	defer exec.mu.Unlock()
+	want := models.Checkout{Repository: "https://git.example.com/org/app.git", Ref: sha, Path: "src"}
+	if got := exec.jobs["test"].Checkout; got == nil || *got != want {
+		t.Errorf("Expected the pushed commit to be checked out, got %+v", got)
+	}
+}
+
+func TestServer_TriggerWorkflow_SkipsFilteredBranch(t *testing.T) {
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		On:       models.TriggerConfig{Push: &models.PushConfig{Branches: []string{"main", "!main-old"}}},
+		Jobs:     map[string]models.Job{"test": testJob()},
+		JobOrder: []string{"test"},
+	}
+	srv := newEventTestServer(t, &fakeExecutor{}, wf)
+
+	if _, err := srv.TriggerWorkflow(context.Background(), testWorkflowName, TriggerOptions{Branch: "develop"}); !errors.Is(err, ErrSkipped) {
+		t.Fatalf("Expected ErrSkipped for a filtered branch, got %v", err)
+	}
+
+	run, err := srv.TriggerWorkflow(context.Background(), testWorkflowName, TriggerOptions{Branch: "main"})
+	if err != nil {
+		t.Fatalf("TriggerWorkflow returned error: %v", err)
+	}
+	waitForRun(t, srv, run.ID)
+	if run.Trigger.Event != models.EventWorkflowDispatch || run.Trigger.Branch != "main" {
+		t.Errorf("Unexpected trigger recorded: %+v", run.Trigger)
+	}
+}
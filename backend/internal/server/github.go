@@ -0,0 +1,163 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gantry/internal/models"
+)
+
+// VerifyGitHubSignature checks the X-Hub-Signature-256 header of a GitHub
+// webhook delivery, "sha256=" and the HMAC of the payload under the
+// webhook's secret
+func (s *Server) VerifyGitHubSignature(payload []byte, signature string) error {
+	if s.githubWebhookSecret == "" {
+		return fmt.Errorf("GitHub %w", ErrWebhookDisabled)
+	}
+	mac, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok || !validSignature(s.githubWebhookSecret, payload, mac) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// githubRepository is a repository of a GitHub webhook payload
+type githubRepository struct {
+	CloneURL string `json:"clone_url"`
+}
+
+// githubUser is a user of a GitHub webhook payload
+type githubUser struct {
+	Login string `json:"login"`
+}
+
+// githubPush is the payload of a push event
+type githubPush struct {
+	Ref        string `json:"ref"`
+	After      string `json:"after"`
+	Deleted    bool   `json:"deleted"`
+	HeadCommit *struct {
+		ID        string    `json:"id"`
+		Message   string    `json:"message"`
+		Timestamp time.Time `json:"timestamp"`
+		URL       string    `json:"url"`
+		Author    struct {
+			Name string `json:"name"`
+		} `json:"author"`
+	} `json:"head_commit"`
+	Repository githubRepository `json:"repository"`
+	Sender     githubUser       `json:"sender"`
+}
+
+// githubPullRequest is the payload of a pull_request event
+type githubPullRequest struct {
+	Action      string `json:"action"`
+	Number      int    `json:"number"`
+	PullRequest struct {
+		Head struct {
+			SHA  string            `json:"sha"`
+			Repo *githubRepository `json:"repo"` // nil once a fork is deleted
+		} `json:"head"`
+		Base struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
+	} `json:"pull_request"`
+	Repository githubRepository `json:"repository"`
+	Sender     githubUser       `json:"sender"`
+}
+
+// githubIssueComment is the payload of an issue_comment event
+type githubIssueComment struct {
+	Action string `json:"action"`
+	Issue  struct {
+		Number      int              `json:"number"`
+		PullRequest *json.RawMessage `json:"pull_request"` // set on pull requests only
+	} `json:"issue"`
+	Comment struct {
+		Body string `json:"body"`
+	} `json:"comment"`
+	Repository githubRepository `json:"repository"`
+	Sender     githubUser       `json:"sender"`
+}
+
+// ParseGitHubEvent turns a GitHub webhook delivery, named by its
+// X-GitHub-Event header, into an event. Deliveries that can't trigger a
+// workflow, such as pings, tag pushes and deleted branches, return
+// ErrIgnoredEvent.
+func ParseGitHubEvent(name string, payload []byte) (Event, error) {
+	switch name {
+	case models.EventPush:
+		var p githubPush
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return Event{}, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+		}
+		branch, ok := strings.CutPrefix(p.Ref, "refs/heads/")
+		if !ok {
+			return Event{}, fmt.Errorf("%w: %s is not a branch", ErrIgnoredEvent, p.Ref)
+		}
+		if p.Deleted {
+			return Event{}, fmt.Errorf("%w: branch '%s' was deleted", ErrIgnoredEvent, branch)
+		}
+
+		ev := Event{
+			Name:       name,
+			Actor:      p.Sender.Login,
+			Branch:     branch,
+			Repository: p.Repository.CloneURL,
+			SHA:        p.After,
+		}
+		if c := p.HeadCommit; c != nil {
+			ev.Commit = &models.CommitInfo{
+				Message:   c.Message,
+				Author:    c.Author.Name,
+				URL:       c.URL,
+				Timestamp: c.Timestamp,
+			}
+		}
+		return ev, nil
+
+	case models.EventPullRequest:
+		var p githubPullRequest
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return Event{}, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+		}
+
+		// Pull requests from forks are cloned from the fork, which has
+		// their head commit
+		repository := p.Repository.CloneURL
+		if head := p.PullRequest.Head.Repo; head != nil && head.CloneURL != "" {
+			repository = head.CloneURL
+		}
+		return Event{
+			Name:        name,
+			Action:      p.Action,
+			Actor:       p.Sender.Login,
+			Branch:      p.PullRequest.Base.Ref,
+			PullRequest: p.Number,
+			Repository:  repository,
+			SHA:         p.PullRequest.Head.SHA,
+		}, nil
+
+	case models.EventIssueComment:
+		var p githubIssueComment
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return Event{}, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+		}
+		if p.Action != "created" || p.Issue.PullRequest == nil {
+			return Event{}, fmt.Errorf("%w: only new comments on pull requests run commands", ErrIgnoredEvent)
+		}
+		return Event{
+			Name:        name,
+			Actor:       p.Sender.Login,
+			Comment:     p.Comment.Body,
+			PullRequest: p.Issue.Number,
+			Repository:  p.Repository.CloneURL,
+		}, nil
+
+	case "":
+		return Event{}, fmt.Errorf("%w: the X-GitHub-Event header is missing", ErrInvalidEvent)
+	}
+	return Event{}, fmt.Errorf("%w: event '%s' doesn't trigger workflows", ErrIgnoredEvent, name)
+}
@@ -0,0 +1,227 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"gantry/internal/models"
+)
+
+func githubSignature(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestServer_VerifyGitHubSignature(t *testing.T) {
+	payload := `{"ref":"refs/heads/main"}`
+
+	srv := &Server{githubWebhookSecret: "s3cret"}
+	if err := srv.VerifyGitHubSignature([]byte(payload), githubSignature("s3cret", payload)); err != nil {
+		t.Errorf("Expected a valid signature to verify, got %v", err)
+	}
+	for name, signature := range map[string]string{
+		"wrong secret":    githubSignature("other", payload),
+		"missing prefix":  githubSignature("s3cret", payload)[len("sha256="):],
+		"not hex":         "sha256=zz",
+		"missing":         "",
+		"altered payload": githubSignature("s3cret", payload+" "),
+	} {
+		if err := srv.VerifyGitHubSignature([]byte(payload), signature); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: expected ErrInvalidSignature, got %v", name, err)
+		}
+	}
+
+	disabled := &Server{}
+	if err := disabled.VerifyGitHubSignature([]byte(payload), githubSignature("", payload)); !errors.Is(err, ErrWebhookDisabled) {
+		t.Errorf("Expected ErrWebhookDisabled without a secret, got %v", err)
+	}
+}
+
+func TestParseGitHubEvent(t *testing.T) {
+	committed := time.Date(2025, 1, 15, 10, 30, 0, 0, time.FixedZone("", -5*3600))
+
+	tests := []struct {
+		name    string
+		event   string
+		payload string
+		want    Event
+		wantErr error
+	}{
+		{
+			name:  "push",
+			event: "push",
+			payload: `{
+				"ref": "refs/heads/release/1.4",
+				"after": "3f786850e387550fdab836ed7e6dc881de23001b",
+				"head_commit": {
+					"id": "3f786850e387550fdab836ed7e6dc881de23001b",
+					"message": "Fix the build",
+					"timestamp": "2025-01-15T10:30:00-05:00",
+					"url": "https://github.com/org/app/commit/3f78685",
+					"author": {"name": "Mona Lisa", "email": "mona@example.com"}
+				},
+				"repository": {"clone_url": "https://github.com/org/app.git"},
+				"sender": {"login": "octocat"}
+			}`,
+			want: Event{
+				Name:       "push",
+				Actor:      "octocat",
+				Branch:     "release/1.4",
+				Repository: "https://github.com/org/app.git",
+				SHA:        "3f786850e387550fdab836ed7e6dc881de23001b",
+				Commit: &models.CommitInfo{
+					Message:   "Fix the build",
+					Author:    "Mona Lisa",
+					URL:       "https://github.com/org/app/commit/3f78685",
+					Timestamp: committed,
+				},
+			},
+		},
+		{
+			name:    "tag push",
+			event:   "push",
+			payload: `{"ref": "refs/tags/v1.0.0", "after": "abc"}`,
+			wantErr: ErrIgnoredEvent,
+		},
+		{
+			name:    "deleted branch",
+			event:   "push",
+			payload: `{"ref": "refs/heads/old", "deleted": true, "after": "0000000000000000000000000000000000000000"}`,
+			wantErr: ErrIgnoredEvent,
+		},
+		{
+			name:  "pull request from a fork",
+			event: "pull_request",
+			payload: `{
+				"action": "synchronize",
+				"number": 42,
+				"pull_request": {
+					"head": {"sha": "abc123", "repo": {"clone_url": "https://github.com/fork/app.git"}},
+					"base": {"ref": "main"}
+				},
+				"repository": {"clone_url": "https://github.com/org/app.git"},
+				"sender": {"login": "contributor"}
+			}`,
+			want: Event{
+				Name:        "pull_request",
+				Action:      "synchronize",
+				Actor:       "contributor",
+				Branch:      "main",
+				PullRequest: 42,
+				Repository:  "https://github.com/fork/app.git",
+				SHA:         "abc123",
+			},
+		},
+		{
+			name:  "pull request of a deleted fork",
+			event: "pull_request",
+			payload: `{
+				"action": "closed",
+				"number": 7,
+				"pull_request": {"head": {"sha": "def456", "repo": null}, "base": {"ref": "main"}},
+				"repository": {"clone_url": "https://github.com/org/app.git"},
+				"sender": {"login": "octocat"}
+			}`,
+			want: Event{
+				Name:        "pull_request",
+				Action:      "closed",
+				Actor:       "octocat",
+				Branch:      "main",
+				PullRequest: 7,
+				Repository:  "https://github.com/org/app.git",
+				SHA:         "def456",
+			},
+		},
+		{
+			name:  "comment on a pull request",
+			event: "issue_comment",
+			payload: `{
+				"action": "created",
+				"issue": {"number": 42, "pull_request": {"url": "https://api.github.com/repos/org/app/pulls/42"}},
+				"comment": {"body": "/retest"},
+				"repository": {"clone_url": "https://github.com/org/app.git"},
+				"sender": {"login": "octocat"}
+			}`,
+			want: Event{
+				Name:        "issue_comment",
+				Actor:       "octocat",
+				Comment:     "/retest",
+				PullRequest: 42,
+				Repository:  "https://github.com/org/app.git",
+			},
+		},
+		{
+			name:    "comment on an issue",
+			event:   "issue_comment",
+			payload: `{"action": "created", "issue": {"number": 3}, "comment": {"body": "/retest"}}`,
+			wantErr: ErrIgnoredEvent,
+		},
+		{
+			name:    "ping",
+			event:   "ping",
+			payload: `{"zen": "Keep it logically awesome."}`,
+			wantErr: ErrIgnoredEvent,
+		},
+		{
+			name:    "invalid payload",
+			event:   "push",
+			payload: `{"ref": 1}`,
+			wantErr: ErrInvalidEvent,
+		},
+		{
+			name:    "missing event",
+			payload: `{}`,
+			wantErr: ErrInvalidEvent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGitHubEvent(tt.event, []byte(tt.payload))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseGitHubEvent returned error: %v", err)
+			}
+			if got.Commit != nil && tt.want.Commit != nil && got.Commit.Timestamp.Equal(tt.want.Commit.Timestamp) {
+				got.Commit.Timestamp = tt.want.Commit.Timestamp
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestServer_DispatchEvent_PushRecordsCommit(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newEventTestServer(t, exec, &models.Workflow{
+		Name:     "build",
+		On:       models.TriggerConfig{Push: &models.PushConfig{Branches: []string{"main"}}},
+		Jobs:     map[string]models.Job{"test": testJob()},
+		JobOrder: []string{"test"},
+	})
+
+	commit := &models.CommitInfo{Message: "Fix the build", Author: "Mona Lisa"}
+	runs, err := srv.DispatchEvent(context.Background(), Event{Name: models.EventPush, Branch: "main", SHA: "abc", Commit: commit})
+	if err != nil {
+		t.Fatalf("DispatchEvent returned error: %v", err)
+	}
+	if len(runs) != 1 {
+		t.Fatalf("Expected one run, got %d", len(runs))
+	}
+	if got := runs[0].Trigger.Commit; !reflect.DeepEqual(got, commit) {
+		t.Errorf("Expected the run to record commit %+v, got %+v", commit, got)
+	}
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gantry/internal/models"
+)
+
+// VerifyGitLabToken checks the X-Gitlab-Token header of a GitLab webhook
+// delivery, which holds the webhook's secret token as is
+func (s *Server) VerifyGitLabToken(token string) error {
+	if s.gitlabWebhookSecret == "" {
+		return fmt.Errorf("GitLab %w", ErrWebhookDisabled)
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.gitlabWebhookSecret)) != 1 {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// gitlabProject is the project of a GitLab webhook payload
+type gitlabProject struct {
+	GitHTTPURL string `json:"git_http_url"`
+}
+
+// gitlabUser is the user of a GitLab webhook payload
+type gitlabUser struct {
+	Username string `json:"username"`
+}
+
+// gitlabCommit is a commit of a GitLab webhook payload
+type gitlabCommit struct {
+	ID        string    `json:"id"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+	URL       string    `json:"url"`
+	Author    struct {
+		Name string `json:"name"`
+	} `json:"author"`
+}
+
+// commitInfo describes the commit
+func (c *gitlabCommit) commitInfo() *models.CommitInfo {
+	return &models.CommitInfo{
+		Message:   c.Message,
+		Author:    c.Author.Name,
+		URL:       c.URL,
+		Timestamp: c.Timestamp,
+	}
+}
+
+// gitlabPush is the payload of a Push Hook
+type gitlabPush struct {
+	Ref          string         `json:"ref"`
+	CheckoutSHA  *string        `json:"checkout_sha"` // null when the branch was deleted
+	UserUsername string         `json:"user_username"`
+	Project      gitlabProject  `json:"project"`
+	Commits      []gitlabCommit `json:"commits"`
+}
+
+// gitlabMergeRequest is the merge request of a GitLab webhook payload
+type gitlabMergeRequest struct {
+	IID          int            `json:"iid"`
+	TargetBranch string         `json:"target_branch"`
+	Action       string         `json:"action"`
+	OldRev       string         `json:"oldrev"` // set on updates that push commits
+	LastCommit   *gitlabCommit  `json:"last_commit"`
+	Source       *gitlabProject `json:"source"`
+}
+
+// gitlabMergeRequestHook is the payload of a Merge Request Hook
+type gitlabMergeRequestHook struct {
+	User             gitlabUser         `json:"user"`
+	Project          gitlabProject      `json:"project"`
+	ObjectAttributes gitlabMergeRequest `json:"object_attributes"`
+}
+
+// gitlabNote is the payload of a Note Hook, a comment
+type gitlabNote struct {
+	User             gitlabUser    `json:"user"`
+	Project          gitlabProject `json:"project"`
+	ObjectAttributes struct {
+		Note         string `json:"note"`
+		NoteableType string `json:"noteable_type"`
+	} `json:"object_attributes"`
+	MergeRequest *gitlabMergeRequest `json:"merge_request"`
+}
+
+// gitlabActions are the pull request activity types of merge request
+// actions; updates that push commits are synchronize, and other updates
+// edited
+var gitlabActions = map[string]string{
+	"open":   models.PullRequestOpened,
+	"reopen": models.PullRequestReopened,
+	"close":  models.PullRequestClosed,
+	"merge":  models.PullRequestClosed,
+}
+
+// ParseGitLabEvent turns a GitLab webhook delivery, named by its
+// X-Gitlab-Event header, into an event: pushes become push events, merge
+// requests pull_request events and comments on merge requests
+// issue_comment events. Deliveries that can't trigger a workflow, such as
+// tag pushes and deleted branches, return ErrIgnoredEvent.
+func ParseGitLabEvent(name string, payload []byte) (Event, error) {
+	switch name {
+	case "Push Hook":
+		var p gitlabPush
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return Event{}, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+		}
+		branch, ok := strings.CutPrefix(p.Ref, "refs/heads/")
+		if !ok {
+			return Event{}, fmt.Errorf("%w: %s is not a branch", ErrIgnoredEvent, p.Ref)
+		}
+		if p.CheckoutSHA == nil {
+			return Event{}, fmt.Errorf("%w: branch '%s' was deleted", ErrIgnoredEvent, branch)
+		}
+
+		ev := Event{
+			Name:       models.EventPush,
+			Actor:      p.UserUsername,
+			Branch:     branch,
+			Repository: p.Project.GitHTTPURL,
+			SHA:        *p.CheckoutSHA,
+		}
+		for i := range p.Commits {
+			if p.Commits[i].ID == ev.SHA {
+				ev.Commit = p.Commits[i].commitInfo()
+			}
+		}
+		return ev, nil
+
+	case "Merge Request Hook":
+		var p gitlabMergeRequestHook
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return Event{}, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+		}
+		mr := p.ObjectAttributes
+		action, ok := gitlabActions[mr.Action]
+		switch {
+		case ok:
+		case mr.Action == "update" && mr.OldRev != "":
+			action = models.PullRequestSynchronize
+		case mr.Action == "update":
+			action = models.PullRequestEdited
+		default:
+			return Event{}, fmt.Errorf("%w: merge request action '%s' doesn't trigger workflows", ErrIgnoredEvent, mr.Action)
+		}
+
+		ev := mergeRequestEvent(models.EventPullRequest, p.User, p.Project, &mr)
+		ev.Action = action
+		return ev, nil
+
+	case "Note Hook":
+		var p gitlabNote
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return Event{}, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+		}
+		if p.ObjectAttributes.NoteableType != "MergeRequest" || p.MergeRequest == nil {
+			return Event{}, fmt.Errorf("%w: only comments on merge requests run commands", ErrIgnoredEvent)
+		}
+
+		ev := mergeRequestEvent(models.EventIssueComment, p.User, p.Project, p.MergeRequest)
+		ev.Comment = p.ObjectAttributes.Note
+		return ev, nil
+
+	case "":
+		return Event{}, fmt.Errorf("%w: the X-Gitlab-Event header is missing", ErrInvalidEvent)
+	}
+	return Event{}, fmt.Errorf("%w: event '%s' doesn't trigger workflows", ErrIgnoredEvent, name)
+}
+
+// mergeRequestEvent returns the event of a merge request, which is cloned
+// from its source project, as merge requests from forks have their commits
+// there
+func mergeRequestEvent(name string, user gitlabUser, project gitlabProject, mr *gitlabMergeRequest) Event {
+	ev := Event{
+		Name:        name,
+		Actor:       user.Username,
+		Branch:      mr.TargetBranch,
+		PullRequest: mr.IID,
+		Repository:  project.GitHTTPURL,
+	}
+	if mr.Source != nil && mr.Source.GitHTTPURL != "" {
+		ev.Repository = mr.Source.GitHTTPURL
+	}
+	if mr.LastCommit != nil {
+		ev.SHA = mr.LastCommit.ID
+		ev.Commit = mr.LastCommit.commitInfo()
+	}
+	return ev
+}
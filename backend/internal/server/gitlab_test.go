@@ -0,0 +1,180 @@
+package server
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"gantry/internal/models"
+)
+
+func TestServer_VerifyGitLabToken(t *testing.T) {
+	srv := &Server{gitlabWebhookSecret: "s3cret"}
+	if err := srv.VerifyGitLabToken("s3cret"); err != nil {
+		t.Errorf("Expected the secret token to verify, got %v", err)
+	}
+	for _, token := range []string{"", "S3CRET", "s3cret "} {
+		if err := srv.VerifyGitLabToken(token); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("Expected ErrInvalidSignature for %q, got %v", token, err)
+		}
+	}
+
+	disabled := &Server{}
+	if err := disabled.VerifyGitLabToken(""); !errors.Is(err, ErrWebhookDisabled) {
+		t.Errorf("Expected ErrWebhookDisabled without a secret, got %v", err)
+	}
+}
+
+func TestParseGitLabEvent(t *testing.T) {
+	committed := time.Date(2025, 1, 15, 10, 30, 0, 0, time.FixedZone("", 2*3600))
+	lastCommit := `{
+		"id": "da1560886d4f094c3e6c9ef40349f7d38b5d27d7",
+		"message": "Fix the build",
+		"timestamp": "2025-01-15T10:30:00+02:00",
+		"url": "https://gitlab.com/org/app/-/commit/da15608",
+		"author": {"name": "Mona Lisa", "email": "mona@example.com"}
+	}`
+	commit := &models.CommitInfo{
+		Message:   "Fix the build",
+		Author:    "Mona Lisa",
+		URL:       "https://gitlab.com/org/app/-/commit/da15608",
+		Timestamp: committed,
+	}
+	mergeRequest := func(action, oldrev string) string {
+		return `{
+			"object_kind": "merge_request",
+			"user": {"username": "contributor"},
+			"project": {"git_http_url": "https://gitlab.com/org/app.git"},
+			"object_attributes": {
+				"iid": 42,
+				"target_branch": "main",
+				"action": "` + action + `",
+				"oldrev": "` + oldrev + `",
+				"last_commit": ` + lastCommit + `,
+				"source": {"git_http_url": "https://gitlab.com/fork/app.git"}
+			}
+		}`
+	}
+	pullRequest := func(action string) Event {
+		return Event{
+			Name:        "pull_request",
+			Action:      action,
+			Actor:       "contributor",
+			Branch:      "main",
+			PullRequest: 42,
+			Repository:  "https://gitlab.com/fork/app.git",
+			SHA:         "da1560886d4f094c3e6c9ef40349f7d38b5d27d7",
+			Commit:      commit,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		event   string
+		payload string
+		want    Event
+		wantErr error
+	}{
+		{
+			name:  "push",
+			event: "Push Hook",
+			payload: `{
+				"object_kind": "push",
+				"ref": "refs/heads/release/1.4",
+				"checkout_sha": "da1560886d4f094c3e6c9ef40349f7d38b5d27d7",
+				"user_username": "octocat",
+				"project": {"git_http_url": "https://gitlab.com/org/app.git"},
+				"commits": [
+					{"id": "b6568db1bc1dcd7f8b4d5a946b0b91f9dacd7327", "message": "Earlier"},
+					` + lastCommit + `
+				]
+			}`,
+			want: Event{
+				Name:       "push",
+				Actor:      "octocat",
+				Branch:     "release/1.4",
+				Repository: "https://gitlab.com/org/app.git",
+				SHA:        "da1560886d4f094c3e6c9ef40349f7d38b5d27d7",
+				Commit:     commit,
+			},
+		},
+		{
+			name:    "deleted branch",
+			event:   "Push Hook",
+			payload: `{"ref": "refs/heads/old", "checkout_sha": null}`,
+			wantErr: ErrIgnoredEvent,
+		},
+		{
+			name:    "tag push",
+			event:   "Tag Push Hook",
+			payload: `{"ref": "refs/tags/v1.0.0"}`,
+			wantErr: ErrIgnoredEvent,
+		},
+		{name: "opened merge request", event: "Merge Request Hook", payload: mergeRequest("open", ""), want: pullRequest("opened")},
+		{name: "merged merge request", event: "Merge Request Hook", payload: mergeRequest("merge", ""), want: pullRequest("closed")},
+		{name: "pushed to merge request", event: "Merge Request Hook", payload: mergeRequest("update", "b6568db1"), want: pullRequest("synchronize")},
+		{name: "edited merge request", event: "Merge Request Hook", payload: mergeRequest("update", ""), want: pullRequest("edited")},
+		{name: "approved merge request", event: "Merge Request Hook", payload: mergeRequest("approved", ""), wantErr: ErrIgnoredEvent},
+		{
+			name:  "comment on a merge request",
+			event: "Note Hook",
+			payload: `{
+				"user": {"username": "octocat"},
+				"project": {"git_http_url": "https://gitlab.com/org/app.git"},
+				"object_attributes": {"note": "/retest", "noteable_type": "MergeRequest"},
+				"merge_request": {"iid": 42, "target_branch": "main", "last_commit": ` + lastCommit + `}
+			}`,
+			want: Event{
+				Name:        "issue_comment",
+				Actor:       "octocat",
+				Branch:      "main",
+				Comment:     "/retest",
+				PullRequest: 42,
+				Repository:  "https://gitlab.com/org/app.git",
+				SHA:         "da1560886d4f094c3e6c9ef40349f7d38b5d27d7",
+				Commit:      commit,
+			},
+		},
+		{
+			name:    "comment on an issue",
+			event:   "Note Hook",
+			payload: `{"object_attributes": {"note": "/retest", "noteable_type": "Issue"}}`,
+			wantErr: ErrIgnoredEvent,
+		},
+		{
+			name:    "invalid payload",
+			event:   "Push Hook",
+			payload: `[]`,
+			wantErr: ErrInvalidEvent,
+		},
+		{
+			name:    "missing event",
+			payload: `{}`,
+			wantErr: ErrInvalidEvent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGitLabEvent(tt.event, []byte(tt.payload))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseGitLabEvent returned error: %v", err)
+			}
+			if got.Commit != nil && tt.want.Commit != nil && got.Commit.Timestamp.Equal(tt.want.Commit.Timestamp) {
+				commit := *got.Commit
+				commit.Timestamp = tt.want.Commit.Timestamp
+				got.Commit = &commit
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package server
+
+import (
+	"fmt"
+
+	"gantry/internal/executor"
+	"gantry/internal/models"
+)
+
+// checkGPUs returns an error when a job has gpus but the executor's host
+// can't give them to it
+func (s *Server) checkGPUs(jobs ...models.Job) error {
+	checker, ok := s.executor.(executor.GPUChecker)
+	if !ok {
+		return nil
+	}
+	for _, job := range jobs {
+		if job.GPUs != "" {
+			if err := checker.CheckGPUs(); err != nil {
+				return fmt.Errorf("job needs gpus: %w", err)
+			}
+			return nil
+		}
+	}
+	return nil
+}
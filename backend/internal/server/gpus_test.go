@@ -0,0 +1,57 @@
+package server
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gantry/internal/models"
+)
+
+// gpuExecutor is a fake executor whose host may lack a GPU runtime
+type gpuExecutor struct {
+	fakeExecutor
+	err error
+}
+
+func (e *gpuExecutor) CheckGPUs() error {
+	return e.err
+}
+
+func TestCheckGPUs(t *testing.T) {
+	exec := &gpuExecutor{err: errors.New("no NVIDIA container runtime")}
+	srv := newSchedulerTestServer(&exec.fakeExecutor)
+	srv.executor = exec
+
+	if err := srv.checkGPUs(testJob()); err != nil {
+		t.Errorf("Expected a job without gpus to pass, got %v", err)
+	}
+	job := testJob()
+	job.GPUs = "1"
+	if err := srv.checkGPUs(job); err == nil || !strings.Contains(err.Error(), "NVIDIA") {
+		t.Errorf("Expected a job with gpus to be rejected, got %v", err)
+	}
+
+	exec.err = nil
+	if err := srv.checkGPUs(job); err != nil {
+		t.Errorf("Expected a job with gpus to pass on a GPU host, got %v", err)
+	}
+}
+
+func TestRunJob_FailsWithoutGPURuntime(t *testing.T) {
+	exec := &gpuExecutor{err: errors.New("no NVIDIA container runtime")}
+	srv := newSchedulerTestServer(&exec.fakeExecutor)
+	srv.executor = exec
+
+	job := testJob()
+	job.GPUs = "all"
+	wf := &models.Workflow{Name: testWorkflowName, Jobs: map[string]models.Job{"train": job}}
+
+	run := runWorkflowSync(t, srv, wf)
+	if recorded, _ := run.GetJob("train"); recorded.Status != failedStatus || !strings.Contains(recorded.Output, "NVIDIA") {
+		t.Errorf("Expected the job to fail without a GPU runtime, got '%s': %s", recorded.Status, recorded.Output)
+	}
+	if len(exec.order) != 0 {
+		t.Errorf("Expected the job not to reach the executor, ran %v", exec.order)
+	}
+}
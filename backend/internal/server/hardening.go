@@ -0,0 +1,38 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gantry/internal/executor"
+)
+
+// parseHardening reads the hardening applied to every job container from
+// the values of JOB_CAP_DROP, JOB_NO_NEW_PRIVILEGES, JOB_READ_ONLY_ROOTFS and
+// JOB_PIDS_LIMIT
+func parseHardening(capDrop, noNewPrivileges, readOnly, pidsLimit string) (executor.Hardening, error) {
+	h := executor.Hardening{
+		NoNewPrivileges: noNewPrivileges == "true",
+		ReadOnlyRootfs:  readOnly == "true",
+	}
+
+	for _, capability := range strings.Split(capDrop, ",") {
+		// Kubernetes names capabilities without the CAP_ prefix, which
+		// Docker accepts too
+		capability = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(capability)), "CAP_")
+		if capability == "" {
+			continue
+		}
+		h.CapDrop = append(h.CapDrop, capability)
+	}
+
+	if pidsLimit != "" {
+		limit, err := strconv.ParseInt(pidsLimit, 10, 64)
+		if err != nil || limit < 0 {
+			return h, fmt.Errorf("JOB_PIDS_LIMIT must be a number of processes, got '%s'", pidsLimit)
+		}
+		h.PidsLimit = limit
+	}
+	return h, nil
+}
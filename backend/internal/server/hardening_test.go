@@ -0,0 +1,41 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	"gantry/internal/models"
+)
+
+func TestParseHardening(t *testing.T) {
+	h, err := parseHardening(" net_raw, CAP_SYS_ADMIN ,", "true", "false", "256")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if want := []string{"NET_RAW", "SYS_ADMIN"}; !reflect.DeepEqual(h.CapDrop, want) {
+		t.Errorf("Expected capabilities %v, got %v", want, h.CapDrop)
+	}
+	if !h.NoNewPrivileges || h.ReadOnlyRootfs || h.PidsLimit != 256 {
+		t.Errorf("Unexpected hardening: %+v", h)
+	}
+
+	if h, _ := parseHardening("all", "", "", ""); !reflect.DeepEqual(h.CapDrop, []string{"ALL"}) {
+		t.Errorf("Expected ALL to be kept as is, got %v", h.CapDrop)
+	}
+	if _, err := parseHardening("", "", "", "lots"); err == nil {
+		t.Error("Expected error for an invalid pids limit, got nil")
+	}
+}
+
+func TestRunJob_PassesHardening(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+	srv.hardening.ReadOnlyRootfs = true
+
+	wf := &models.Workflow{Name: testWorkflowName, Jobs: map[string]models.Job{"build": testJob()}}
+	runWorkflowSync(t, srv, wf)
+
+	if !exec.hardening["build"].ReadOnlyRootfs {
+		t.Errorf("Expected the job to be hardened, got %+v", exec.hardening["build"])
+	}
+}
@@ -0,0 +1,361 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"gantry/internal/models"
+	"gantry/internal/storage"
+)
+
+// ErrInvalidHook is returned when creating an outgoing webhook with a bad
+// URL or unknown events
+var ErrInvalidHook = errors.New("invalid hook")
+
+// Delivering to outgoing webhooks
+const (
+	hookAttempts = 5                // attempts before a delivery is recorded as failed
+	hookBackoff  = 2 * time.Second  // wait before the second attempt, doubling after each
+	hookTimeout  = 10 * time.Second // for each attempt
+)
+
+// hookSender posts run events to outgoing webhooks, each delivery in the
+// background so runs never wait for them
+type hookSender struct {
+	client  *http.Client  // one with hookTimeout that only dials permitted addresses when nil
+	backoff time.Duration // hookBackoff when zero
+
+	// allowed are the networks of loopback, private and link-local
+	// addresses hooks may still be delivered to
+	allowed []netip.Prefix
+
+	deliveries sync.WaitGroup
+}
+
+// HookOptions describe an outgoing webhook to create
+type HookOptions struct {
+	URL       string
+	Workflow  string   // ID or name of the workflow whose runs are sent; every workflow's when empty
+	Events    []string // models.HookEvents when empty
+	Secret    string   // generated when empty
+	CreatedBy string
+}
+
+// HookPayload is what outgoing webhooks are posted
+type HookPayload struct {
+	Event string              `json:"event"`
+	Time  time.Time           `json:"time"`
+	Run   *models.WorkflowRun `json:"run"` // without what its jobs printed
+}
+
+// CreateHook registers an outgoing webhook and returns it with its secret,
+// which it isn't listed with afterwards
+func (s *Server) CreateHook(opts HookOptions) (*models.Hook, error) {
+	u, err := url.Parse(opts.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: url must be an http or https URL, got '%s'", ErrInvalidHook, opts.URL)
+	}
+	if err := s.hooks.checkHost(u.Hostname()); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidHook, err)
+	}
+
+	events := opts.Events
+	if len(events) == 0 {
+		events = models.HookEvents
+	}
+	for _, event := range events {
+		if !slices.Contains(models.HookEvents, event) {
+			return nil, fmt.Errorf("%w: unknown event '%s'", ErrInvalidHook, event)
+		}
+	}
+
+	hook := &models.Hook{
+		ID:        fmt.Sprintf("hook-%d", time.Now().UnixNano()),
+		URL:       opts.URL,
+		Events:    events,
+		Secret:    opts.Secret,
+		CreatedAt: time.Now(),
+		CreatedBy: opts.CreatedBy,
+	}
+	if opts.Workflow != "" {
+		wf, err := findWorkflow(s.storage, opts.Workflow)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrWorkflowNotFound, opts.Workflow)
+		}
+		hook.WorkflowID = wf.ID
+	}
+	if hook.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to generate secret: %w", err)
+		}
+		hook.Secret = hex.EncodeToString(secret)
+	}
+
+	if err := s.storage.SaveHook(hook); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+// ListHooks returns the outgoing webhooks, oldest first and without their
+// secrets
+func (s *Server) ListHooks() ([]*models.Hook, error) {
+	hooks, err := s.storage.ListHooks()
+	if err != nil {
+		return nil, err
+	}
+	for _, hook := range hooks {
+		hook.Secret = ""
+	}
+	return hooks, nil
+}
+
+// DeleteHook removes an outgoing webhook
+func (s *Server) DeleteHook(id string) error {
+	return s.storage.DeleteHook(id)
+}
+
+// ListHookFailures returns a page of the deliveries that failed every
+// attempt, newest first
+func (s *Server) ListHookFailures(opts storage.ListOptions) ([]*models.HookFailure, int, error) {
+	return s.storage.ListHookFailures(opts)
+}
+
+// deleteWorkflowHooks removes the outgoing webhooks of a deleted workflow,
+// so they aren't sent the runs of a workflow that takes its ID
+func (s *Server) deleteWorkflowHooks(workflowID string) error {
+	hooks, err := s.storage.ListHooks()
+	if err != nil {
+		return err
+	}
+	for _, hook := range hooks {
+		if hook.WorkflowID == workflowID {
+			if err := s.storage.DeleteHook(hook.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hookEvent returns the outgoing webhook event of a run event, if any
+func hookEvent(ev RunEvent) string {
+	switch {
+	case ev.Type == RunEventStarted:
+		return models.HookRunStarted
+	case ev.Type != RunEventCompleted:
+		return ""
+	}
+	switch ev.Status {
+	case successStatus, successWithFailuresStatus:
+		return models.HookRunSucceeded
+	case failedStatus:
+		return models.HookRunFailed
+	case cancelledStatus:
+		return models.HookRunCancelled
+	}
+	return ""
+}
+
+// sendHooks posts a run event to the outgoing webhooks that want it
+func (s *Server) sendHooks(ev RunEvent, run *models.WorkflowRun) {
+	event := hookEvent(ev)
+	if event == "" {
+		return
+	}
+
+	body, err := json.Marshal(HookPayload{Event: event, Time: ev.Time, Run: run.Summary()})
+	if err != nil {
+		log.Printf("WARNING: failed to encode %s of run %s for hooks: %v", event, run.ID, err)
+		return
+	}
+
+	s.hooks.deliveries.Add(1)
+	go func() {
+		defer s.hooks.deliveries.Done()
+
+		hooks, err := s.storage.ListHooks()
+		if err != nil {
+			log.Printf("WARNING: failed to list hooks for %s of run %s: %v", event, run.ID, err)
+			return
+		}
+		for _, hook := range hooks {
+			if (hook.WorkflowID == "" || hook.WorkflowID == run.WorkflowID) && slices.Contains(hook.Events, event) {
+				s.hooks.deliveries.Add(1)
+				go s.deliverHook(hook, event, run.ID, body)
+			}
+		}
+	}()
+}
+
+// deliverHook posts an event to a hook, retrying with growing waits, and
+// records the delivery as failed once every attempt has
+func (s *Server) deliverHook(hook *models.Hook, event, runID string, body []byte) {
+	defer s.hooks.deliveries.Done()
+
+	backoff := s.hooks.backoff
+	if backoff == 0 {
+		backoff = hookBackoff
+	}
+
+	var err error
+	for attempt := 1; attempt <= hookAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		if err = s.hooks.post(hook, event, body); err == nil {
+			return
+		}
+		log.Printf("WARNING: delivering %s of run %s to hook %s failed (attempt %d of %d): %v",
+			event, runID, hook.ID, attempt, hookAttempts, err)
+	}
+
+	failure := &models.HookFailure{
+		HookID:   hook.ID,
+		URL:      hook.URL,
+		Event:    event,
+		RunID:    runID,
+		Payload:  string(body),
+		Attempts: hookAttempts,
+		Error:    err.Error(),
+		FailedAt: time.Now(),
+	}
+	if err := s.storage.SaveHookFailure(failure); err != nil {
+		log.Printf("WARNING: failed to record failed delivery to hook %s: %v", hook.ID, err)
+	}
+}
+
+// post makes one attempt at delivering an event, signed like deliveries to
+// workflows' webhooks are
+func (h *hookSender) post(hook *models.Hook, event string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gantry-Event", event)
+	req.Header.Set("X-Gantry-Hook", hook.ID)
+	req.Header.Set("X-Gantry-Signature-256", "sha256="+hex.EncodeToString(sign(hook.Secret, body)))
+
+	client := h.client
+	if client == nil {
+		// The address is checked as it is dialed, as the host may resolve
+		// to another than it did when the hook was created, and a
+		// redirect may lead anywhere
+		dialer := &net.Dialer{Timeout: hookTimeout, Control: h.checkDial}
+		client = &http.Client{
+			Timeout:   hookTimeout,
+			Transport: &http.Transport{DialContext: dialer.DialContext},
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("responded %s", resp.Status)
+	}
+	return nil
+}
+
+// parseHookNetworks parses a comma-separated list of the networks of
+// internal addresses hooks may be delivered to, in CIDR notation or as
+// single addresses
+func parseHookNetworks(list string) ([]netip.Prefix, error) {
+	var networks []netip.Prefix
+	for _, n := range strings.Split(list, ",") {
+		n = strings.TrimSpace(n)
+		if n == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(n)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(n)
+			if addrErr != nil {
+				return nil, fmt.Errorf("HOOK_ALLOWED_NETWORKS must list networks such as 10.0.0.0/8, got '%s'", n)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		networks = append(networks, prefix.Masked())
+	}
+	return networks, nil
+}
+
+// internalAddr reports whether an address is one of the server's own or
+// its network's: loopback, private, link-local (such as cloud metadata
+// services at 169.254.169.254), unspecified or multicast
+func internalAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsUnspecified() || addr.IsMulticast()
+}
+
+// permits reports whether hooks may be delivered to an address
+func (h *hookSender) permits(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !internalAddr(addr) {
+		return true
+	}
+	for _, prefix := range h.allowed {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHost returns an error when a hook's host is, or resolves to, an
+// address hooks may not be delivered to. A host that doesn't resolve is
+// left to be checked when it is delivered to.
+func (h *hookSender) checkHost(host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !h.permits(addr) {
+			return fmt.Errorf("url must not be an internal address, got '%s'", host)
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if !h.permits(addr) {
+			return fmt.Errorf("url must not resolve to an internal address, got '%s' at %s", host, addr.Unmap())
+		}
+	}
+	return nil
+}
+
+// checkDial refuses connections to addresses hooks may not be delivered
+// to, once the host to connect to has been resolved
+func (h *hookSender) checkDial(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("refusing to deliver to unknown address %s", address)
+	}
+	if !h.permits(addrPort.Addr()) {
+		return fmt.Errorf("refusing to deliver to internal address %s", addrPort.Addr().Unmap())
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package server
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"gantry/internal/models"
+	"gantry/internal/storage"
+)
+
+// hookDelivery is a request an outgoing webhook received
+type hookDelivery struct {
+	event     string
+	signature string
+	payload   HookPayload
+	body      []byte
+}
+
+// loopbackNetworks lets hooks be delivered to receivers started by
+// newHookReceiver
+var loopbackNetworks = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+
+// newHookReceiver starts a server that passes the deliveries it receives
+// on, responding with status
+func newHookReceiver(t *testing.T, status int) (*httptest.Server, <-chan hookDelivery) {
+	t.Helper()
+
+	deliveries := make(chan hookDelivery, 16)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		d := hookDelivery{event: r.Header.Get("X-Gantry-Event"), signature: r.Header.Get("X-Gantry-Signature-256"), body: body}
+		if err := json.Unmarshal(body, &d.payload); err != nil {
+			t.Errorf("Failed to decode delivery: %v", err)
+		}
+		deliveries <- d
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(receiver.Close)
+	return receiver, deliveries
+}
+
+func TestServer_CreateHook(t *testing.T) {
+	wf := &models.Workflow{Name: "deploy", Jobs: map[string]models.Job{"test": testJob()}, JobOrder: []string{"test"}}
+	srv := newEventTestServer(t, &fakeExecutor{}, wf)
+
+	hook, err := srv.CreateHook(HookOptions{URL: "https://chat.example.com/hook", Workflow: "deploy", CreatedBy: "alice"})
+	if err != nil {
+		t.Fatalf("CreateHook returned error: %v", err)
+	}
+	if hook.WorkflowID != wf.ID || hook.CreatedBy != "alice" || len(hook.Secret) != 64 {
+		t.Errorf("Unexpected hook: %+v", hook)
+	}
+	if !reflect.DeepEqual(hook.Events, models.HookEvents) {
+		t.Errorf("Expected every event by default, got %v", hook.Events)
+	}
+
+	hooks, err := srv.ListHooks()
+	if err != nil {
+		t.Fatalf("ListHooks returned error: %v", err)
+	}
+	if len(hooks) != 1 || hooks[0].ID != hook.ID || hooks[0].Secret != "" {
+		t.Errorf("Expected the hook listed without its secret, got %+v", hooks)
+	}
+
+	for _, opts := range []HookOptions{
+		{URL: "ftp://example.com/hook"},
+		{URL: "/hook"},
+		{URL: "https://example.com/hook", Events: []string{"run_queued"}},
+	} {
+		if _, err := srv.CreateHook(opts); !errors.Is(err, ErrInvalidHook) {
+			t.Errorf("Expected ErrInvalidHook for %+v, got %v", opts, err)
+		}
+	}
+	if _, err := srv.CreateHook(HookOptions{URL: "https://example.com/hook", Workflow: "missing"}); !errors.Is(err, ErrWorkflowNotFound) {
+		t.Errorf("Expected ErrWorkflowNotFound, got %v", err)
+	}
+
+	if err := srv.DeleteWorkflow("deploy"); err != nil {
+		t.Fatalf("DeleteWorkflow returned error: %v", err)
+	}
+	if hooks, _ := srv.ListHooks(); len(hooks) != 0 {
+		t.Errorf("Expected the workflow's hooks deleted with it, got %+v", hooks)
+	}
+}
+
+func TestServer_Hooks_DeliverRunEvents(t *testing.T) {
+	wf := &models.Workflow{Name: "deploy", Jobs: map[string]models.Job{"test": testJob()}, JobOrder: []string{"test"}}
+	other := &models.Workflow{Name: "other", Jobs: map[string]models.Job{"test": testJob()}, JobOrder: []string{"test"}}
+	srv := newEventTestServer(t, &fakeExecutor{}, wf, other)
+	srv.hooks.allowed = loopbackNetworks
+	receiver, deliveries := newHookReceiver(t, http.StatusNoContent)
+
+	for _, opts := range []HookOptions{
+		{URL: receiver.URL, Workflow: "deploy", Secret: "s3cret"},
+		{URL: receiver.URL, Workflow: "other"},
+		{URL: receiver.URL, Events: []string{models.HookRunFailed}},
+	} {
+		if _, err := srv.CreateHook(opts); err != nil {
+			t.Fatalf("CreateHook returned error: %v", err)
+		}
+	}
+
+	run, err := srv.TriggerWorkflow(context.Background(), "deploy", TriggerOptions{})
+	if err != nil {
+		t.Fatalf("TriggerWorkflow returned error: %v", err)
+	}
+	waitForRun(t, srv, run.ID)
+
+	var events []string
+	for len(events) < 2 {
+		select {
+		case d := <-deliveries:
+			events = append(events, d.event)
+			if d.payload.Event != d.event || d.payload.Run == nil || d.payload.Run.ID != run.ID {
+				t.Errorf("Unexpected payload for %s: %+v", d.event, d.payload)
+			}
+			if want := "sha256=" + hex.EncodeToString(sign("s3cret", d.body)); d.signature != want {
+				t.Errorf("Expected signature %s, got %s", want, d.signature)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected 2 deliveries, got %v", events)
+		}
+	}
+	srv.hooks.deliveries.Wait()
+
+	sort.Strings(events)
+	if want := []string{models.HookRunStarted, models.HookRunSucceeded}; !reflect.DeepEqual(events, want) {
+		t.Errorf("Expected %v, got %v", want, events)
+	}
+	select {
+	case d := <-deliveries:
+		t.Errorf("Unexpected delivery of %s", d.event)
+	default:
+	}
+}
+
+func TestServer_Hooks_RecordFailedDeliveries(t *testing.T) {
+	exec := &fakeExecutor{fail: map[string]bool{"test": true}}
+	wf := &models.Workflow{Name: "deploy", Jobs: map[string]models.Job{"test": testJob()}, JobOrder: []string{"test"}}
+	srv := newEventTestServer(t, exec, wf)
+	srv.hooks.backoff = time.Millisecond
+	srv.hooks.allowed = loopbackNetworks
+	receiver, deliveries := newHookReceiver(t, http.StatusBadGateway)
+
+	hook, err := srv.CreateHook(HookOptions{URL: receiver.URL, Events: []string{models.HookRunFailed}})
+	if err != nil {
+		t.Fatalf("CreateHook returned error: %v", err)
+	}
+	run, err := srv.TriggerWorkflow(context.Background(), "deploy", TriggerOptions{})
+	if err != nil {
+		t.Fatalf("TriggerWorkflow returned error: %v", err)
+	}
+	waitForRun(t, srv, run.ID)
+
+	for attempt := 1; attempt <= hookAttempts; attempt++ {
+		select {
+		case <-deliveries:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected %d attempts, got %d", hookAttempts, attempt-1)
+		}
+	}
+	srv.hooks.deliveries.Wait()
+
+	failures, total, err := srv.ListHookFailures(storage.ListOptions{})
+	if err != nil {
+		t.Fatalf("ListHookFailures returned error: %v", err)
+	}
+	if total != 1 {
+		t.Fatalf("Expected 1 failed delivery, got %d", total)
+	}
+	f := failures[0]
+	if f.HookID != hook.ID || f.Event != models.HookRunFailed || f.RunID != run.ID || f.Attempts != hookAttempts ||
+		f.Error != "responded 502 Bad Gateway" || f.Payload == "" {
+		t.Errorf("Unexpected failure recorded: %+v", f)
+	}
+}
+
+func TestServer_CreateHook_InternalAddresses(t *testing.T) {
+	srv := newEventTestServer(t, &fakeExecutor{})
+
+	for _, url := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://10.0.0.5/hook",
+		"http://192.168.1.10/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+		"http://[fe80::1]/hook",
+		"http://0.0.0.0/hook",
+	} {
+		if _, err := srv.CreateHook(HookOptions{URL: url}); !errors.Is(err, ErrInvalidHook) {
+			t.Errorf("Expected ErrInvalidHook for %s, got %v", url, err)
+		}
+	}
+
+	// Allowed networks may be delivered to, and only they
+	srv.hooks.allowed = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	if _, err := srv.CreateHook(HookOptions{URL: "http://10.0.0.5/hook"}); err != nil {
+		t.Errorf("Expected an allowed network to be accepted, got %v", err)
+	}
+	if _, err := srv.CreateHook(HookOptions{URL: "http://169.254.169.254/hook"}); !errors.Is(err, ErrInvalidHook) {
+		t.Errorf("Expected ErrInvalidHook outside the allowed networks, got %v", err)
+	}
+	if _, err := srv.CreateHook(HookOptions{URL: "http://203.0.113.7/hook"}); err != nil {
+		t.Errorf("Expected a public address to be accepted, got %v", err)
+	}
+}
+
+func TestServer_Hooks_RefuseInternalAddresses(t *testing.T) {
+	wf := &models.Workflow{Name: "deploy", Jobs: map[string]models.Job{"test": testJob()}, JobOrder: []string{"test"}}
+	srv := newEventTestServer(t, &fakeExecutor{}, wf)
+	srv.hooks.backoff = time.Millisecond
+	receiver, deliveries := newHookReceiver(t, http.StatusNoContent)
+
+	// A hook whose host resolved elsewhere when it was created is still
+	// refused once it resolves to an internal address
+	hook := &models.Hook{ID: "hook-1", URL: receiver.URL, Events: []string{models.HookRunStarted}, Secret: "s3cret"}
+	if err := srv.storage.SaveHook(hook); err != nil {
+		t.Fatalf("SaveHook returned error: %v", err)
+	}
+	run, err := srv.TriggerWorkflow(context.Background(), "deploy", TriggerOptions{})
+	if err != nil {
+		t.Fatalf("TriggerWorkflow returned error: %v", err)
+	}
+	waitForRun(t, srv, run.ID)
+	srv.hooks.deliveries.Wait()
+
+	select {
+	case d := <-deliveries:
+		t.Errorf("Expected no delivery to an internal address, got %s", d.event)
+	default:
+	}
+	failures, total, err := srv.ListHookFailures(storage.ListOptions{})
+	if err != nil {
+		t.Fatalf("ListHookFailures returned error: %v", err)
+	}
+	if total != 1 || !strings.Contains(failures[0].Error, "refusing to deliver to internal address 127.0.0.1") {
+		t.Errorf("Expected the delivery refused, got %+v", failures)
+	}
+}
+
+func TestParseHookNetworks(t *testing.T) {
+	networks, err := parseHookNetworks(" 10.0.0.0/8, 192.168.1.7 ,fd00::/8,10.1.2.3/16,")
+	if err != nil {
+		t.Fatalf("parseHookNetworks returned error: %v", err)
+	}
+	want := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.168.1.7/32"),
+		netip.MustParsePrefix("fd00::/8"),
+		netip.MustParsePrefix("10.1.0.0/16"),
+	}
+	if !reflect.DeepEqual(networks, want) {
+		t.Errorf("Expected %v, got %v", want, networks)
+	}
+
+	if networks, err := parseHookNetworks(""); err != nil || len(networks) != 0 {
+		t.Errorf("Expected no networks, got %v (%v)", networks, err)
+	}
+	if _, err := parseHookNetworks("10.0.0.0/33"); err == nil {
+		t.Error("Expected an error for an invalid network")
+	}
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gantry/internal/executor"
+)
+
+// parseDockerHosts reads the Docker daemons jobs are spread across from
+// DOCKER_HOSTS, a comma-separated list of URLs. Hosts reached over tcp use
+// TLS when certDir is set, with the certificates in its subdirectory named
+// after the host, or else in certDir itself.
+func parseDockerHosts(list, certDir string) ([]executor.DockerHost, error) {
+	var hosts []executor.DockerHost
+	for _, value := range strings.Split(list, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		u, err := url.Parse(value)
+		if err != nil || u.Host == "" && u.Path == "" {
+			return nil, fmt.Errorf("DOCKER_HOSTS has an invalid host '%s'", value)
+		}
+		host := executor.DockerHost{URL: value}
+		switch u.Scheme {
+		case "tcp":
+			if certDir != "" {
+				host.CertDir = hostCertDir(certDir, u.Hostname())
+			}
+		case "unix":
+		default:
+			return nil, fmt.Errorf("DOCKER_HOSTS host '%s' must be a tcp:// or unix:// URL", value)
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
+
+// hostCertDir returns the directory holding a host's TLS certificates
+func hostCertDir(certDir, hostname string) string {
+	dir := filepath.Join(certDir, hostname)
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		return dir
+	}
+	return certDir
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gantry/internal/executor"
+)
+
+func TestParseDockerHosts(t *testing.T) {
+	certs := t.TempDir()
+	if err := os.Mkdir(filepath.Join(certs, "build1"), 0o755); err != nil {
+		t.Fatalf("Failed to create cert dir: %v", err)
+	}
+
+	hosts, err := parseDockerHosts(" tcp://build1:2376, tcp://build2:2376 ,unix:///var/run/docker.sock,", certs)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	want := []executor.DockerHost{
+		{URL: "tcp://build1:2376", CertDir: filepath.Join(certs, "build1")},
+		{URL: "tcp://build2:2376", CertDir: certs},
+		{URL: "unix:///var/run/docker.sock"},
+	}
+	if !reflect.DeepEqual(hosts, want) {
+		t.Errorf("Expected hosts %+v, got %+v", want, hosts)
+	}
+
+	if hosts, _ := parseDockerHosts("tcp://build1:2376", ""); hosts[0].CertDir != "" {
+		t.Errorf("Expected no TLS without a cert path, got %+v", hosts[0])
+	}
+	if hosts, err := parseDockerHosts("", certs); err != nil || len(hosts) != 0 {
+		t.Errorf("Expected no hosts, got %v (%v)", hosts, err)
+	}
+	for _, invalid := range []string{"build1:2376", "ssh://build1", "http://build1:2375"} {
+		if _, err := parseDockerHosts(invalid, ""); err == nil {
+			t.Errorf("Expected an error for host %q", invalid)
+		}
+	}
+}
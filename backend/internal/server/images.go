@@ -0,0 +1,39 @@
+package server
+
+import (
+	"fmt"
+
+	"gantry/internal/models"
+)
+
+// checkImages returns an error naming the first image of a workflow's jobs
+// and services that the server's image policy refuses. Images that aren't
+// known until a job runs are checked by checkJobImages when it starts.
+func (s *Server) checkImages(wf *models.Workflow) error {
+	for _, img := range s.workflowImages(wf) {
+		if err := s.imagePolicy.Check(img.image); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkJobImages checks the resolved images of a job about to run: its
+// container, or the runner image when it has none, and its services
+func (s *Server) checkJobImages(job models.Job, runnerImage string) error {
+	image := runnerImage
+	if job.Container != nil {
+		image = job.Container.Image
+	}
+	if image != "" {
+		if err := s.imagePolicy.Check(image); err != nil {
+			return err
+		}
+	}
+	for _, name := range sortedServices(job.Services) {
+		if err := s.imagePolicy.Check(job.Services[name].Image); err != nil {
+			return fmt.Errorf("service '%s': %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"gantry/internal/models"
+)
+
+func TestCheckWorkflow_ImagePolicy(t *testing.T) {
+	srv := newSchedulerTestServer(&fakeExecutor{})
+	srv.imagePolicy = models.ImagePolicy{Allowed: []string{"registry.internal/*", "ubuntu"}}
+
+	allowed := []byte(`
+name: Allowed
+on:
+  push:
+jobs:
+  build:
+    runs-on: ubuntu
+    container:
+      image: registry.internal/builder:1.2
+    steps:
+      - name: Build
+        run: make
+`)
+	if _, err := srv.checkWorkflow(allowed); err != nil {
+		t.Errorf("Expected allowed images to pass, got %v", err)
+	}
+
+	refused := []byte(`
+name: Refused
+on:
+  push:
+jobs:
+  build:
+    runs-on: ubuntu
+    services:
+      db:
+        image: postgres:16
+    steps:
+      - name: Build
+        run: make
+`)
+	_, err := srv.checkWorkflow(refused)
+	if err == nil || !strings.Contains(err.Error(), "postgres:16") {
+		t.Errorf("Expected the postgres service to be refused, got %v", err)
+	}
+}
+
+func TestRunJob_ChecksResolvedImages(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+	srv.imagePolicy = models.ImagePolicy{Denied: []string{"*:latest"}}
+
+	job := matrixJob("latest")
+	job.Container = &models.Container{Image: "node:${{ matrix.n }}"}
+	wf := &models.Workflow{Name: testWorkflowName, Jobs: map[string]models.Job{"build": job}}
+
+	run := runWorkflowSync(t, srv, wf)
+	for _, name := range run.JobOrder {
+		if recorded, _ := run.GetJob(name); recorded.Status != failedStatus || !strings.Contains(recorded.Output, "denied") {
+			t.Errorf("Expected job %s to fail on its denied image, got '%s': %s", name, recorded.Status, recorded.Output)
+		}
+	}
+	if len(exec.order) != 0 {
+		t.Errorf("Expected no job to reach the executor, ran %v", exec.order)
+	}
+}
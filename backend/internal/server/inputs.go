@@ -0,0 +1,114 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"gantry/internal/models"
+)
+
+// ErrInvalidInputs is returned when trigger inputs don't match the
+// workflow's workflow_dispatch declaration
+var ErrInvalidInputs = errors.New("invalid inputs")
+
+// resolveInputs checks provided inputs against the declared schema and fills
+// in defaults. Booleans are converted to bool; everything else is a string.
+func resolveInputs(wf *models.Workflow, provided map[string]interface{}) (map[string]interface{}, error) {
+	var declared map[string]models.Input
+	if wf.On.WorkflowDispatch != nil {
+		declared = wf.On.WorkflowDispatch.Inputs
+	}
+	return resolveDeclaredInputs(declared, provided)
+}
+
+// resolveDeclaredInputs checks provided inputs against declared inputs, as
+// used by both workflow_dispatch and workflow_call
+func resolveDeclaredInputs(declared map[string]models.Input, provided map[string]interface{}) (map[string]interface{}, error) {
+	// Report unknown inputs in a stable order
+	names := make([]string, 0, len(provided))
+	for name := range provided {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, exists := declared[name]; !exists {
+			return nil, fmt.Errorf("%w: workflow has no input '%s'", ErrInvalidInputs, name)
+		}
+	}
+
+	resolved := make(map[string]interface{}, len(declared))
+	for name, input := range declared {
+		raw, given := provided[name]
+		if !given || raw == nil {
+			if input.Required && input.Default == "" {
+				return nil, fmt.Errorf("%w: input '%s' is required", ErrInvalidInputs, name)
+			}
+			raw = input.Default
+		}
+
+		value, err := convertInput(input, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: input '%s' %v", ErrInvalidInputs, name, err)
+		}
+		resolved[name] = value
+	}
+
+	return resolved, nil
+}
+
+// convertInput coerces a provided value to an input's declared type
+func convertInput(input models.Input, raw interface{}) (interface{}, error) {
+	switch input.Type {
+	case models.InputBoolean:
+		switch v := raw.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if v == "" {
+				return false, nil
+			}
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("must be true or false, got '%s'", v)
+			}
+			return b, nil
+		default:
+			return nil, fmt.Errorf("must be a boolean, got %v", raw)
+		}
+
+	case models.InputChoice:
+		s, ok := inputString(raw)
+		if !ok {
+			return nil, fmt.Errorf("must be a string, got %v", raw)
+		}
+		for _, option := range input.Options {
+			if s == option {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("must be one of %v, got '%s'", input.Options, s)
+
+	default:
+		s, ok := inputString(raw)
+		if !ok {
+			return nil, fmt.Errorf("must be a string, got %v", raw)
+		}
+		return s, nil
+	}
+}
+
+// inputString accepts strings and JSON scalars for string-typed inputs
+func inputString(raw interface{}) (string, bool) {
+	switch v := raw.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gantry/internal/models"
+	"gantry/internal/parser"
+	"gantry/internal/storage"
+)
+
+func dispatchWorkflow() *models.Workflow {
+	return &models.Workflow{
+		Name: testWorkflowName,
+		On: models.TriggerConfig{WorkflowDispatch: &models.DispatchConfig{
+			Inputs: map[string]models.Input{
+				"environment": {Type: models.InputChoice, Options: []string{"staging", "production"}, Default: "staging"},
+				"dry-run":     {Type: models.InputBoolean, Default: "true"},
+				"version":     {Required: true},
+			},
+		}},
+		Jobs:     map[string]models.Job{"deploy": testJob()},
+		JobOrder: []string{"deploy"},
+	}
+}
+
+func TestResolveInputs_AppliesDefaultsAndTypes(t *testing.T) {
+	inputs, err := resolveInputs(dispatchWorkflow(), map[string]interface{}{
+		"version": 2.5,
+		"dry-run": "false",
+	})
+	if err != nil {
+		t.Fatalf("resolveInputs returned error: %v", err)
+	}
+
+	if inputs["environment"] != "staging" {
+		t.Errorf("Expected default environment 'staging', got %v", inputs["environment"])
+	}
+	if inputs["dry-run"] != false {
+		t.Errorf("Expected dry-run false, got %v", inputs["dry-run"])
+	}
+	if inputs["version"] != "2.5" {
+		t.Errorf("Expected version '2.5', got %v", inputs["version"])
+	}
+}
+
+func TestResolveInputs_Rejects(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"missing required": {},
+		"unknown input":    {"version": "1", "region": "eu"},
+		"invalid choice":   {"version": "1", "environment": "dev"},
+		"invalid boolean":  {"version": "1", "dry-run": "maybe"},
+		"non-scalar":       {"version": []interface{}{"1"}},
+	}
+
+	for name, provided := range tests {
+		if _, err := resolveInputs(dispatchWorkflow(), provided); !errors.Is(err, ErrInvalidInputs) {
+			t.Errorf("%s: expected ErrInvalidInputs, got %v", name, err)
+		}
+	}
+}
+
+func TestServer_TriggerWorkflow_ExposesInputs(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := &Server{
+		storage:  storage.NewMemoryStorage(),
+		executor: exec,
+		parser:   parser.NewParser(),
+	}
+
+	wf := dispatchWorkflow()
+	job := testJob()
+	job.If = "inputs.dry-run == false"
+	job.Steps[0].Run = "deploy ${{ inputs.version }} to ${{ inputs.environment }}"
+	wf.Jobs["deploy"] = job
+	if err := srv.storage.SaveWorkflow(wf); err != nil {
+		t.Fatalf("Failed to save workflow: %v", err)
+	}
+
+	if _, err := srv.TriggerWorkflow(context.Background(), testWorkflowName, TriggerOptions{}); !errors.Is(err, ErrInvalidInputs) {
+		t.Fatalf("Expected missing required input to be rejected, got %v", err)
+	}
+
+	run, err := srv.TriggerWorkflow(context.Background(), testWorkflowName, TriggerOptions{
+		Inputs: map[string]interface{}{"version": "1.4.0", "environment": "production", "dry-run": false},
+	})
+	if err != nil {
+		t.Fatalf("Failed to trigger workflow: %v", err)
+	}
+	if run.Inputs["environment"] != "production" {
+		t.Errorf("Expected inputs to be recorded on the run, got %v", run.Inputs)
+	}
+
+	waitForRun(t, srv, run.ID)
+
+	exec.mu.Lock()
+	defer exec.mu.Unlock()
+	if got := exec.jobs["deploy"].Steps[0].Run; got != "deploy 1.4.0 to production" {
+		t.Errorf("Expected inputs to be interpolated, got %q", got)
+	}
+}
@@ -0,0 +1,28 @@
+package server
+
+import (
+	"fmt"
+
+	"gantry/internal/executor"
+	"gantry/internal/models"
+)
+
+// checkIsolation returns an error when a job has isolation vm but the server
+// has no VM runtime, or the executor's host lacks it
+func (s *Server) checkIsolation(jobs ...models.Job) error {
+	for _, job := range jobs {
+		if !job.VMIsolated() {
+			continue
+		}
+		if s.vmRuntime == "" {
+			return fmt.Errorf("jobs with isolation vm need a VM runtime on this server (set VM_RUNTIME)")
+		}
+		if checker, ok := s.executor.(executor.VMChecker); ok {
+			if err := checker.CheckVMRuntime(s.vmRuntime); err != nil {
+				return fmt.Errorf("job needs isolation vm: %w", err)
+			}
+		}
+		return nil
+	}
+	return nil
+}
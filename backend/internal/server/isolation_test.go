@@ -0,0 +1,66 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"gantry/internal/models"
+)
+
+// vmExecutor is a fake executor whose host may lack the VM runtime
+type vmExecutor struct {
+	fakeExecutor
+	runtimes map[string]bool
+}
+
+func (e *vmExecutor) CheckVMRuntime(runtime string) error {
+	if !e.runtimes[runtime] {
+		return fmt.Errorf("no runtime '%s'", runtime)
+	}
+	return nil
+}
+
+func TestCheckIsolation(t *testing.T) {
+	exec := &vmExecutor{runtimes: map[string]bool{"kata-fc": true}}
+	srv := newSchedulerTestServer(&exec.fakeExecutor)
+	srv.executor = exec
+
+	if err := srv.checkIsolation(testJob()); err != nil {
+		t.Errorf("Expected a container job to pass, got %v", err)
+	}
+	job := testJob()
+	job.Isolation = models.IsolationVM
+	if err := srv.checkIsolation(job); err == nil || !strings.Contains(err.Error(), "VM_RUNTIME") {
+		t.Errorf("Expected a VM job to be rejected without a VM runtime, got %v", err)
+	}
+
+	srv.vmRuntime = "kata-qemu"
+	if err := srv.checkIsolation(job); err == nil || !strings.Contains(err.Error(), "kata-qemu") {
+		t.Errorf("Expected a VM job to be rejected without the runtime on the host, got %v", err)
+	}
+
+	srv.vmRuntime = "kata-fc"
+	if err := srv.checkIsolation(job); err != nil {
+		t.Errorf("Expected a VM job to pass, got %v", err)
+	}
+}
+
+func TestRunJob_PassesVMRuntime(t *testing.T) {
+	exec := &vmExecutor{runtimes: map[string]bool{"kata-fc": true}}
+	srv := newSchedulerTestServer(&exec.fakeExecutor)
+	srv.executor = exec
+	srv.vmRuntime = "kata-fc"
+
+	job := testJob()
+	job.Isolation = models.IsolationVM
+	wf := &models.Workflow{Name: testWorkflowName, Jobs: map[string]models.Job{"test": job}}
+
+	run := runWorkflowSync(t, srv, wf)
+	if recorded, _ := run.GetJob("test"); recorded.Status != successStatus {
+		t.Fatalf("Expected the job to succeed, got '%s': %s", recorded.Status, recorded.Output)
+	}
+	if runtime := exec.vmRuntime["test"]; runtime != "kata-fc" {
+		t.Errorf("Expected the job to run with the VM runtime, got '%s'", runtime)
+	}
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gantry/internal/models"
+)
+
+// RunLogs returns what each job of a run printed, in the order the jobs
+// run, leaving out jobs that haven't started
+func (s *Server) RunLogs(runID string) ([]models.JobLog, error) {
+	run, err := s.storage.GetRun(runID)
+	if err != nil {
+		return nil, err
+	}
+	run = run.Clone()
+
+	// Jobs missing from the order, if any, come last by name
+	names := make([]string, 0, len(run.Jobs))
+	listed := make(map[string]bool, len(run.JobOrder))
+	for _, name := range run.JobOrder {
+		if _, ok := run.Jobs[name]; ok && !listed[name] {
+			listed[name] = true
+			names = append(names, name)
+		}
+	}
+	var rest []string
+	for name := range run.Jobs {
+		if !listed[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	names = append(names, rest...)
+
+	var logs []models.JobLog
+	for _, name := range names {
+		if job := run.Jobs[name]; job.Status != "" {
+			logs = append(logs, job.Log(name))
+		}
+	}
+	return logs, nil
+}
+
+// WriteLogsText writes the logs of a run's jobs as one plain-text file:
+// each job's output, then each of its services', under a header
+func WriteLogsText(w io.Writer, logs []models.JobLog) error {
+	for _, jobLog := range logs {
+		if _, err := fmt.Fprintf(w, "=== Job: %s (%s) ===\n", jobLog.Job, jobLog.Status); err != nil {
+			return err
+		}
+		if err := writeLogSection(w, jobLog.Output); err != nil {
+			return err
+		}
+		for _, service := range sortedKeys(jobLog.ServiceLogs) {
+			if _, err := fmt.Fprintf(w, "=== Service: %s (job %s) ===\n", service, jobLog.Job); err != nil {
+				return err
+			}
+			if err := writeLogSection(w, jobLog.ServiceLogs[service]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeLogSection writes output, ending it with a newline if it doesn't,
+// so the next header starts a line of its own
+func writeLogSection(w io.Writer, output string) error {
+	if output != "" && !strings.HasSuffix(output, "\n") {
+		output += "\n"
+	}
+	_, err := io.WriteString(w, output)
+	return err
+}
+
+// WriteLogsZip writes the logs of a run's jobs as a zip of one file per
+// job, <job>.log, and one per service of each job, <job>/services/<service>.log
+func WriteLogsZip(w io.Writer, logs []models.JobLog) error {
+	archive := zip.NewWriter(w)
+	add := func(name, output string) error {
+		f, err := archive.Create(name)
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %w", name, err)
+		}
+		_, err = io.WriteString(f, output)
+		return err
+	}
+
+	for _, jobLog := range logs {
+		if err := add(jobLog.Job+".log", jobLog.Output); err != nil {
+			return err
+		}
+		for _, service := range sortedKeys(jobLog.ServiceLogs) {
+			if err := add(jobLog.Job+"/services/"+service+".log", jobLog.ServiceLogs[service]); err != nil {
+				return err
+			}
+		}
+	}
+	return archive.Close()
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
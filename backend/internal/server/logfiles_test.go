@@ -0,0 +1,90 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"gantry/internal/models"
+)
+
+func TestRunLogs_JobOrder(t *testing.T) {
+	run := &models.WorkflowRun{
+		ID: "run-logs",
+		Jobs: map[string]models.Job{
+			"test":    {Status: successStatus, Output: "ok\n"},
+			"build":   {Status: successStatus, Output: "built\n"},
+			"deploy":  {},
+			"cleanup": {Status: successStatus},
+		},
+		JobOrder: []string{"build", "test", "deploy"},
+	}
+	srv := newLogsTestServer(t, run)
+
+	logs, err := srv.RunLogs("run-logs")
+	if err != nil {
+		t.Fatalf("Failed to get logs: %v", err)
+	}
+	var names []string
+	for _, jobLog := range logs {
+		names = append(names, jobLog.Job)
+	}
+	if want := []string{"build", "test", "cleanup"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Expected the started jobs in order, got %v", names)
+	}
+}
+
+func TestWriteLogsText(t *testing.T) {
+	logs := []models.JobLog{
+		{Job: "build", Status: successStatus, Output: "built", ServiceLogs: map[string]string{"db": "ready\n"}},
+		{Job: "test", Status: failedStatus, Output: "FAIL\n"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteLogsText(&buf, logs); err != nil {
+		t.Fatalf("WriteLogsText returned error: %v", err)
+	}
+	want := "=== Job: build (success) ===\nbuilt\n" +
+		"=== Service: db (job build) ===\nready\n" +
+		"=== Job: test (failed) ===\nFAIL\n"
+	if buf.String() != want {
+		t.Errorf("Expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestWriteLogsZip(t *testing.T) {
+	logs := []models.JobLog{
+		{Job: "build", Status: successStatus, Output: "built\n", ServiceLogs: map[string]string{"db": "ready\n"}},
+		{Job: "call/test", Status: failedStatus, Output: "FAIL\n"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteLogsZip(&buf, logs); err != nil {
+		t.Fatalf("WriteLogsZip returned error: %v", err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Failed to read zip: %v", err)
+	}
+
+	files := make(map[string]string)
+	for _, f := range archive.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(r)
+		r.Close()
+		files[f.Name] = string(data)
+	}
+	want := map[string]string{
+		"build.log":             "built\n",
+		"build/services/db.log": "ready\n",
+		"call/test.log":         "FAIL\n",
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("Expected %v, got %v", want, files)
+	}
+}
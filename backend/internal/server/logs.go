@@ -0,0 +1,179 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gantry/internal/models"
+)
+
+// ErrJobNotFound is returned when following a job a finished run doesn't have
+var ErrJobNotFound = errors.New("job not found")
+
+// followerBuffer is how many chunks of output a follower may fall behind
+// by before it is dropped
+const followerBuffer = 256
+
+// followPollInterval is how often a job that hasn't started or hasn't been
+// saved as finished is looked up again
+const followPollInterval = 250 * time.Millisecond
+
+// liveLogs keeps the outputs of the jobs running on this server, so their
+// logs can be followed
+type liveLogs struct {
+	mu      sync.Mutex
+	outputs map[jobKey]*liveOutput
+}
+
+// jobKey names a job of a run
+type jobKey struct {
+	runID   string
+	jobName string
+}
+
+// add records the output of a job that started
+func (l *liveLogs) add(runID, jobName string, o *liveOutput) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.outputs == nil {
+		l.outputs = make(map[jobKey]*liveOutput)
+	}
+	l.outputs[jobKey{runID, jobName}] = o
+}
+
+// remove forgets the output of a job that finished, unless another attempt
+// of it took its place
+func (l *liveLogs) remove(runID, jobName string, o *liveOutput) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := jobKey{runID, jobName}
+	if l.outputs[key] == o {
+		delete(l.outputs, key)
+	}
+}
+
+// get returns the output of a running job, or nil
+func (l *liveLogs) get(runID, jobName string) *liveOutput {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.outputs[jobKey{runID, jobName}]
+}
+
+// logFollower receives a running job's output as it is recorded
+type logFollower struct {
+	lines   chan string
+	dropped atomic.Bool
+}
+
+// JobLogs follows the output of a job. Output is what the job printed
+// before it was followed; Lines receives what it prints after, masked and
+// in whole lines, and is closed when the job's output ends.
+type JobLogs struct {
+	Output string
+	Lines  <-chan string
+
+	output   *liveOutput
+	follower *logFollower
+}
+
+// Dropped reports whether Lines was closed because the follower fell too
+// far behind the job, rather than because the job's output ended
+func (l *JobLogs) Dropped() bool {
+	return l.follower != nil && l.follower.dropped.Load()
+}
+
+// Close stops following the job
+func (l *JobLogs) Close() {
+	if l.output != nil {
+		l.output.unfollow(l.follower)
+	}
+}
+
+// FollowJobLogs starts following a job's output, waiting for the job to
+// start if it hasn't yet. For a job that has finished, Output is all it
+// printed and Lines is closed.
+func (s *Server) FollowJobLogs(ctx context.Context, runID, jobName string) (*JobLogs, error) {
+	for {
+		if o := s.logs.get(runID, jobName); o != nil {
+			if output, f, ok := o.follow(); ok {
+				return &JobLogs{Output: output, Lines: f.lines, output: o, follower: f}, nil
+			}
+		}
+
+		job, done, err := s.jobState(runID, jobName)
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			lines := make(chan string)
+			close(lines)
+			return &JobLogs{Output: job.Output, Lines: lines}, nil
+		}
+		if err := sleepContext(ctx, followPollInterval); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// WaitForJob waits until a job has finished and returns it as it ended
+func (s *Server) WaitForJob(ctx context.Context, runID, jobName string) (models.Job, error) {
+	for {
+		job, done, err := s.jobState(runID, jobName)
+		if err != nil || done {
+			return job, err
+		}
+		if err := sleepContext(ctx, followPollInterval); err != nil {
+			return models.Job{}, err
+		}
+	}
+}
+
+// GetJobLog returns what a job of a run has printed so far
+func (s *Server) GetJobLog(runID, jobName string) (models.JobLog, error) {
+	run, err := s.storage.GetRun(runID)
+	if err != nil {
+		return models.JobLog{}, err
+	}
+	job, ok := run.GetJob(jobName)
+	if !ok {
+		return models.JobLog{}, fmt.Errorf("%w: %s", ErrJobNotFound, jobName)
+	}
+	return job.Log(jobName), nil
+}
+
+// jobState looks up a job as saved, reporting whether it has finished. A
+// job its run hasn't started yet is unfinished until the run completes.
+func (s *Server) jobState(runID, jobName string) (models.Job, bool, error) {
+	run, err := s.storage.GetRun(runID)
+	if err != nil {
+		return models.Job{}, false, err
+	}
+	job, ok := run.GetJob(jobName)
+	if !ok {
+		if run.CompletedAt != nil {
+			return models.Job{}, false, fmt.Errorf("%w: %s", ErrJobNotFound, jobName)
+		}
+		return models.Job{}, false, nil
+	}
+	switch job.Status {
+	case successStatus, failedStatus, cancelledStatus, skippedStatus:
+		return job, true, nil
+	}
+	return job, run.CompletedAt != nil, nil
+}
+
+// sleepContext waits for d, returning early with the context's error
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
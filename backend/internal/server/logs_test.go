@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"gantry/internal/models"
+	"gantry/internal/secrets"
+	"gantry/internal/storage"
+)
+
+func newLogsTestServer(t *testing.T, run *models.WorkflowRun) *Server {
+	t.Helper()
+	store := secrets.NewMemoryStore()
+	store.Set("TOKEN", "s3cret")
+	srv := &Server{storage: storage.NewMemoryStorage(), secrets: store}
+	if err := srv.storage.SaveRun(run); err != nil {
+		t.Fatalf("Failed to save run: %v", err)
+	}
+	return srv
+}
+
+func receive(t *testing.T, lines <-chan string) (string, bool) {
+	t.Helper()
+	select {
+	case line, ok := <-lines:
+		return line, ok
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for output")
+		return "", false
+	}
+}
+
+func TestFollowJobLogs_StreamsRunningJob(t *testing.T) {
+	run := &models.WorkflowRun{ID: "run-logs", Jobs: map[string]models.Job{}}
+	srv := newLogsTestServer(t, run)
+
+	followed := make(chan *JobLogs)
+	go func() {
+		logs, err := srv.FollowJobLogs(context.Background(), "run-logs", "build")
+		if err != nil {
+			t.Errorf("Failed to follow logs: %v", err)
+		}
+		followed <- logs
+	}()
+
+	// The follower waits for the job to start
+	time.Sleep(2 * followPollInterval)
+	run.UpdateJob("build", models.Job{Status: runningStatus})
+	live := srv.newLiveOutput(run, "build")
+	fmt.Fprint(live, "=== Starting: Build ===\n")
+
+	logs := <-followed
+	if logs == nil {
+		t.FailNow()
+	}
+	defer logs.Close()
+	if logs.Output != "=== Starting: Build ===\n" {
+		t.Errorf("Expected the output so far, got %q", logs.Output)
+	}
+
+	fmt.Fprint(live, "using s3cret\ndone")
+	if line, _ := receive(t, logs.Lines); line != "using ***\n" {
+		t.Errorf("Expected the masked line, got %q", line)
+	}
+	live.Close()
+	if line, _ := receive(t, logs.Lines); line != "done" {
+		t.Errorf("Expected Close to pass on the incomplete line, got %q", line)
+	}
+	if _, ok := receive(t, logs.Lines); ok || logs.Dropped() {
+		t.Error("Expected the lines to end with the job's output")
+	}
+}
+
+func TestFollowJobLogs_FinishedJob(t *testing.T) {
+	run := &models.WorkflowRun{ID: "run-logs", Jobs: map[string]models.Job{
+		"build": {Status: successStatus, Output: "all done\n"},
+	}}
+	srv := newLogsTestServer(t, run)
+
+	logs, err := srv.FollowJobLogs(context.Background(), "run-logs", "build")
+	if err != nil {
+		t.Fatalf("Failed to follow logs: %v", err)
+	}
+	if logs.Output != "all done\n" {
+		t.Errorf("Expected the job's output, got %q", logs.Output)
+	}
+	if _, ok := <-logs.Lines; ok {
+		t.Error("Expected no lines to follow a finished job")
+	}
+
+	job, err := srv.WaitForJob(context.Background(), "run-logs", "build")
+	if err != nil || job.Status != successStatus {
+		t.Errorf("Expected the finished job, got %q (%v)", job.Status, err)
+	}
+}
+
+func TestFollowJobLogs_MissingJob(t *testing.T) {
+	completed := time.Now()
+	run := &models.WorkflowRun{ID: "run-logs", Jobs: map[string]models.Job{}, CompletedAt: &completed}
+	srv := newLogsTestServer(t, run)
+
+	if _, err := srv.FollowJobLogs(context.Background(), "run-logs", "nope"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected ErrJobNotFound, got %v", err)
+	}
+}
+
+func TestFollowJobLogs_DropsSlowFollower(t *testing.T) {
+	run := &models.WorkflowRun{ID: "run-logs", Jobs: map[string]models.Job{"build": {Status: runningStatus}}}
+	srv := newLogsTestServer(t, run)
+	live := srv.newLiveOutput(run, "build")
+	defer live.Close()
+
+	logs, err := srv.FollowJobLogs(context.Background(), "run-logs", "build")
+	if err != nil {
+		t.Fatalf("Failed to follow logs: %v", err)
+	}
+	for i := 0; i <= followerBuffer; i++ {
+		fmt.Fprintf(live, "line %d\n", i)
+	}
+
+	received := 0
+	for range logs.Lines {
+		received++
+	}
+	if received != followerBuffer || !logs.Dropped() {
+		t.Errorf("Expected a follower that fell behind to be dropped after %d lines, got %d (dropped %v)", followerBuffer, received, logs.Dropped())
+	}
+	logs.Close()
+}
+
+func TestGetJobLog(t *testing.T) {
+	run := &models.WorkflowRun{ID: "run-logs", Jobs: map[string]models.Job{
+		"build": {Status: successStatus, Output: "all done\n"},
+	}}
+	srv := newLogsTestServer(t, run)
+
+	jobLog, err := srv.GetJobLog("run-logs", "build")
+	if err != nil || jobLog.Job != "build" || jobLog.Status != successStatus || jobLog.Output != "all done\n" {
+		t.Errorf("Expected the job's output, got %+v (%v)", jobLog, err)
+	}
+	if _, err := srv.GetJobLog("run-logs", "nope"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected ErrJobNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+package server
+
+import (
+	"log"
+	"time"
+
+	"gantry/internal/executor"
+)
+
+// cleanupOrphans removes what the executor kept for runs that aren't running
+// on this server, such as the containers of runs interrupted by a restart.
+// It does so now and then every interval, or only now when interval is zero.
+func (s *Server) cleanupOrphans(cleaner executor.OrphanCleaner, interval time.Duration) {
+	for {
+		removed, err := cleaner.CleanupOrphans(s.runActive)
+		if err != nil {
+			log.Printf("WARNING: failed to clean up orphaned resources: %v", err)
+		}
+		if removed > 0 {
+			log.Printf("Removed %d orphaned resources of runs that are no longer running", removed)
+		}
+
+		if interval <= 0 {
+			return
+		}
+		time.Sleep(interval)
+	}
+}
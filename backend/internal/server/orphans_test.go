@@ -0,0 +1,43 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"gantry/internal/models"
+)
+
+// orphanExecutor records which runs were active when orphans were cleaned up
+type orphanExecutor struct {
+	fakeExecutor
+	active map[string]bool
+}
+
+func (e *orphanExecutor) CleanupOrphans(active func(runID string) bool) (int, error) {
+	e.active = make(map[string]bool)
+	for _, runID := range []string{"run-1", "run-2"} {
+		e.active[runID] = active(runID)
+	}
+	return 1, nil
+}
+
+func TestCleanupOrphans_KeepsActiveRuns(t *testing.T) {
+	exec := &orphanExecutor{}
+	srv := newSchedulerTestServer(&exec.fakeExecutor)
+
+	_, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	srv.trackRun(&models.WorkflowRun{ID: "run-1"}, cancel)
+
+	srv.cleanupOrphans(exec, 0)
+
+	if !exec.active["run-1"] || exec.active["run-2"] {
+		t.Errorf("Expected only run-1 to be active, got %v", exec.active)
+	}
+
+	srv.untrackRun("run-1")
+	srv.cleanupOrphans(exec, 0)
+	if exec.active["run-1"] {
+		t.Error("Expected a finished run to no longer be active")
+	}
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"bytes"
+	"log"
+	"sync"
+	"time"
+
+	"gantry/internal/capture"
+	"gantry/internal/models"
+	"gantry/internal/secrets"
+)
+
+// outputSaveInterval is how often a running job's output is saved to storage
+const outputSaveInterval = time.Second
+
+// maxPartialLine is the most of an incomplete line held back until its end
+// is written. Output that doesn't end its lines, such as progress bars drawn
+// with \r, is recorded in pieces instead of piling up past the output limit.
+const maxPartialLine = 64 << 10
+
+// liveOutput records a running job's output on the run line by line, as the
+// executor writes it, so the run shows progress before the job finishes.
+// Lines are masked before they are recorded, and the middle of output past
+// the server's limit is left out; storage is written at most once per
+// outputSaveInterval.
+type liveOutput struct {
+	s       *Server
+	run     *models.WorkflowRun
+	jobName string
+
+	mu      sync.Mutex
+	output  *capture.Buffer
+	partial []byte // the start of a line not yet complete
+	saved   time.Time
+	closed  bool
+
+	followers map[*logFollower]bool
+}
+
+// newLiveOutput starts recording the output of a job of run, which those
+// following the job's logs receive until it is closed
+func (s *Server) newLiveOutput(run *models.WorkflowRun, jobName string) *liveOutput {
+	o := &liveOutput{s: s, run: run, jobName: jobName, output: capture.NewBuffer(s.maxJobOutput), saved: time.Now()}
+	s.logs.add(run.ID, jobName, o)
+	return o
+}
+
+// Write records the complete lines of p, keeping any incomplete last line
+// until the rest of it is written
+func (o *liveOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return len(p), nil
+	}
+
+	o.partial = append(o.partial, p...)
+	end := bytes.LastIndexByte(o.partial, '\n') + 1
+	if end == 0 && len(o.partial) >= maxPartialLine {
+		end = o.cutPartial()
+	}
+	if end == 0 {
+		return len(p), nil
+	}
+	o.emit(secrets.Mask(string(o.partial[:end]), o.s.secrets))
+	o.partial = append(o.partial[:0], o.partial[end:]...)
+	// Output that outgrew the start of its limit is copied each time it is
+	// recorded, so it is only recorded when it is saved
+	due := time.Since(o.saved) >= outputSaveInterval
+	if due || !o.output.Split() {
+		o.record(due)
+	}
+	return len(p), nil
+}
+
+// cutPartial returns where to cut an incomplete line that outgrew
+// maxPartialLine so that masking each piece masks every secret: short of
+// its end, which may be the start of a secret the next write finishes, and
+// before any secret the cut would split
+func (o *liveOutput) cutPartial() int {
+	values := secrets.Values(o.s.secrets)
+	cut := len(o.partial)
+	if len(values) > 0 {
+		cut -= len(values[0]) - 1
+	}
+
+	for moved := true; moved && cut > 0; {
+		moved = false
+		for _, value := range values {
+			from := max(0, cut-len(value)+1)
+			i := bytes.Index(o.partial[from:], []byte(value))
+			if i >= 0 && from+i < cut {
+				cut, moved = from+i, true
+			}
+		}
+	}
+	if cut <= 0 {
+		// Only a secret as long as the line could be cut through
+		return len(o.partial)
+	}
+	return cut
+}
+
+// Close records what was written so far, including an incomplete last
+// line, and stops recording further writes
+func (o *liveOutput) Close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return
+	}
+	o.closed = true
+	if len(o.partial) > 0 {
+		o.emit(secrets.Mask(string(o.partial), o.s.secrets))
+		o.partial = nil
+	}
+	o.record(false)
+
+	for f := range o.followers {
+		close(f.lines)
+	}
+	o.followers = nil
+	o.s.logs.remove(o.run.ID, o.jobName, o)
+}
+
+// emit adds masked output to the job's and passes it to its followers. A
+// follower that hasn't taken what it was passed before is dropped rather
+// than holding up the job.
+func (o *liveOutput) emit(text string) {
+	o.output.WriteString(text)
+	for f := range o.followers {
+		select {
+		case f.lines <- text:
+		default:
+			f.dropped.Store(true)
+			close(f.lines)
+			delete(o.followers, f)
+		}
+	}
+}
+
+// follow returns the output recorded so far and starts passing what follows
+// it to a new follower, reporting false once the output is closed
+func (o *liveOutput) follow() (string, *logFollower, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return "", nil, false
+	}
+	f := &logFollower{lines: make(chan string, followerBuffer)}
+	if o.followers == nil {
+		o.followers = make(map[*logFollower]bool)
+	}
+	o.followers[f] = true
+	return o.output.String(), f, true
+}
+
+// unfollow stops passing output to a follower
+func (o *liveOutput) unfollow(f *logFollower) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.followers[f] {
+		delete(o.followers, f)
+		close(f.lines)
+	}
+}
+
+// record sets the job's output on the run, saving the run when save is set
+func (o *liveOutput) record(save bool) {
+	job, ok := o.run.GetJob(o.jobName)
+	if !ok {
+		return
+	}
+	job.Output = o.output.String()
+	o.run.UpdateJob(o.jobName, job)
+
+	if !save {
+		return
+	}
+	o.saved = time.Now()
+	if err := o.s.storage.UpdateRun(o.run); err != nil {
+		log.Printf("ERROR: failed to save output of job %s: %v", o.jobName, err)
+	}
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"gantry/internal/executor"
+
+	"gantry/internal/models"
+	"gantry/internal/secrets"
+	"gantry/internal/storage"
+)
+
+func TestLiveOutput_RecordsMaskedLines(t *testing.T) {
+	store := secrets.NewMemoryStore()
+	store.Set("TOKEN", "s3cret")
+	srv := &Server{storage: storage.NewMemoryStorage(), secrets: store}
+	run := &models.WorkflowRun{ID: "run-live", Jobs: map[string]models.Job{"build": {Status: runningStatus}}}
+	if err := srv.storage.SaveRun(run); err != nil {
+		t.Fatalf("Failed to save run: %v", err)
+	}
+
+	output := func() string {
+		job, _ := run.GetJob("build")
+		return job.Output
+	}
+
+	live := srv.newLiveOutput(run, "build")
+	fmt.Fprint(live, "=== Starting: Build ===\nusing s3")
+	if got := output(); got != "=== Starting: Build ===\n" {
+		t.Errorf("Expected only the complete line, got %q", got)
+	}
+	fmt.Fprint(live, "cret\ndone")
+	if got := output(); got != "=== Starting: Build ===\nusing ***\n" {
+		t.Errorf("Expected a secret split across writes to be masked, got %q", got)
+	}
+
+	live.Close()
+	if got := output(); got != "=== Starting: Build ===\nusing ***\ndone" {
+		t.Errorf("Expected Close to record the incomplete line, got %q", got)
+	}
+	fmt.Fprint(live, "late\n")
+	if got := output(); got != "=== Starting: Build ===\nusing ***\ndone" {
+		t.Errorf("Expected writes after Close to be ignored, got %q", got)
+	}
+}
+
+// chattyExecutor is a fake executor reporting more output than it kept
+type chattyExecutor struct {
+	fakeExecutor
+	maxOutput int64
+}
+
+func (e *chattyExecutor) Execute(ctx context.Context, req executor.Request) (*models.JobResult, error) {
+	e.maxOutput = req.MaxOutput
+	result, err := e.fakeExecutor.Execute(ctx, req)
+	result.OutputSize = 5000
+	return result, err
+}
+
+func TestRunJob_RecordsOutputSize(t *testing.T) {
+	exec := &chattyExecutor{}
+	srv := newSchedulerTestServer(&exec.fakeExecutor)
+	srv.executor = exec
+	srv.maxJobOutput = 1000
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"build": testJob()},
+		JobOrder: []string{"build"},
+	}
+	run := runWorkflowSync(t, srv, wf)
+
+	if exec.maxOutput != 1000 {
+		t.Errorf("Expected the executor to be given the output limit, got %d", exec.maxOutput)
+	}
+	job, _ := run.GetJob("build")
+	if job.OutputSize != 5000 || !job.OutputTruncated {
+		t.Errorf("Expected the job's output to be recorded as truncated from 5000 bytes, got %d (truncated %v)", job.OutputSize, job.OutputTruncated)
+	}
+}
+
+func TestLiveOutput_TruncatesPastLimit(t *testing.T) {
+	srv := &Server{storage: storage.NewMemoryStorage(), secrets: secrets.NewMemoryStore(), maxJobOutput: 40}
+	run := &models.WorkflowRun{ID: "run-live", Jobs: map[string]models.Job{"build": {Status: runningStatus}}}
+	if err := srv.storage.SaveRun(run); err != nil {
+		t.Fatalf("Failed to save run: %v", err)
+	}
+
+	live := srv.newLiveOutput(run, "build")
+	for i := 1; i <= 100; i++ {
+		fmt.Fprintf(live, "line %d\n", i)
+	}
+	live.Close()
+
+	job, _ := run.GetJob("build")
+	if !strings.HasPrefix(job.Output, "line 1\n") || !strings.HasSuffix(job.Output, "line 100\n") {
+		t.Errorf("Expected the start and end of the output, got %q", job.Output)
+	}
+	if !strings.Contains(job.Output, "=== Output truncated:") {
+		t.Errorf("Expected a truncation marker, got %q", job.Output)
+	}
+}
+
+func TestLiveOutput_FlushesLongLines(t *testing.T) {
+	store := secrets.NewMemoryStore()
+	store.Set("TOKEN", "s3cret")
+	srv := &Server{storage: storage.NewMemoryStorage(), secrets: store, maxJobOutput: 1000}
+	run := &models.WorkflowRun{ID: "run-live", Jobs: map[string]models.Job{"build": {Status: runningStatus}}}
+	if err := srv.storage.SaveRun(run); err != nil {
+		t.Fatalf("Failed to save run: %v", err)
+	}
+
+	// A progress bar redrawn with \r never ends its line
+	bar := strings.Repeat("\r[=====>    ] 50%", maxPartialLine/16)
+	live := srv.newLiveOutput(run, "build")
+	for i := 0; i < 10; i++ {
+		fmt.Fprint(live, bar)
+		if len(live.partial) >= maxPartialLine {
+			t.Fatalf("Expected less than %d bytes to be held back, got %d", maxPartialLine, len(live.partial))
+		}
+	}
+	live.Close()
+
+	job, _ := run.GetJob("build")
+	if len(job.Output) > 2000 || !strings.Contains(job.Output, "=== Output truncated:") {
+		t.Errorf("Expected the output to be truncated to its limit, got %d bytes", len(job.Output))
+	}
+
+	// A secret the line is cut across is still masked
+	srv.maxJobOutput = 0
+	run = &models.WorkflowRun{ID: "run-live-secret", Jobs: map[string]models.Job{"build": {Status: runningStatus}}}
+	live = srv.newLiveOutput(run, "build")
+	fmt.Fprint(live, bar[:maxPartialLine-3]+"s3c")
+	if strings.Contains(live.output.String(), "s3c") {
+		t.Error("Expected the start of a secret to be held back")
+	}
+	fmt.Fprint(live, "ret"+bar)
+	fmt.Fprint(live, bar[:maxPartialLine-8]+"s3cretxx")
+	live.Close()
+	job, _ = run.GetJob("build")
+	if strings.Contains(job.Output, "s3c") || strings.Count(job.Output, "***") != 2 {
+		t.Error("Expected the secrets the line was cut across to be masked")
+	}
+}
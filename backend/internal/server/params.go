@@ -0,0 +1,55 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// Errors of trigger parameters
+var (
+	ErrInvalidEnv    = errors.New("invalid env")
+	ErrInvalidLabels = errors.New("invalid labels")
+)
+
+// runParams are what a run starts with besides its workflow and trigger
+type runParams struct {
+	inputs    map[string]interface{}
+	variables map[string]string
+	env       map[string]string // overrides of the workflow's env, taken literally
+	labels    map[string]string
+}
+
+// envNamePattern is what env overrides may be named
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// labelPattern is what label keys may look like, such as "team" or
+// "release/channel"
+var labelPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]{0,62}$`)
+
+// maxLabelValue is the most bytes a label value may have
+const maxLabelValue = 256
+
+// checkEnvOverrides checks the names of a trigger's env overrides
+func checkEnvOverrides(env map[string]string) error {
+	for _, name := range sortedKeys(env) {
+		if !envNamePattern.MatchString(name) {
+			return fmt.Errorf("%w: '%s' is not a valid environment variable name", ErrInvalidEnv, name)
+		}
+	}
+	return nil
+}
+
+// checkLabels checks the labels a trigger records on its run
+func checkLabels(labels map[string]string) error {
+	for _, key := range sortedKeys(labels) {
+		if !labelPattern.MatchString(key) {
+			return fmt.Errorf("%w: '%s' must be at most 63 letters, digits, '.', '_', '-' or '/', starting with a letter or digit",
+				ErrInvalidLabels, key)
+		}
+		if len(labels[key]) > maxLabelValue {
+			return fmt.Errorf("%w: the value of '%s' is longer than %d bytes", ErrInvalidLabels, key, maxLabelValue)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gantry/internal/models"
+)
+
+func TestServer_TriggerWorkflow_Params(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+
+	job := testJob()
+	job.Env = map[string]string{"TARGET": "${{ env.PREFIX }}-app"}
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Env:      map[string]string{"PREFIX": "gantry", "STAGE": "dev"},
+		Jobs:     map[string]models.Job{"build": job},
+		JobOrder: []string{"build"},
+	}
+	if err := srv.storage.SaveWorkflow(wf); err != nil {
+		t.Fatalf("Failed to save workflow: %v", err)
+	}
+
+	run, err := srv.TriggerWorkflow(context.Background(), testWorkflowName, TriggerOptions{
+		Env:    map[string]string{"PREFIX": "custom", "EXTRA": "${{ gantry.run_id }}"},
+		Labels: map[string]string{"team": "web", "release/channel": "beta"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to trigger workflow: %v", err)
+	}
+	run = waitForRun(t, srv, run.ID)
+	if run.Labels["team"] != "web" || run.Env["PREFIX"] != "custom" {
+		t.Errorf("Expected the env and labels to be recorded on the run, got %v and %v", run.Env, run.Labels)
+	}
+
+	exec.mu.Lock()
+	executed := exec.jobs["build"]
+	labels := exec.labels["build"]
+	exec.mu.Unlock()
+	if labels["team"] != "web" || labels["release/channel"] != "beta" {
+		t.Errorf("Expected the executor to be given the run's labels, got %v", labels)
+	}
+	want := map[string]string{"PREFIX": "custom", "STAGE": "dev", "TARGET": "custom-app", "EXTRA": "${{ gantry.run_id }}"}
+	for name, value := range want {
+		if executed.Env[name] != value {
+			t.Errorf("Expected %s=%q, got %q", name, value, executed.Env[name])
+		}
+	}
+
+	rerun, err := srv.RerunRun(context.Background(), run.ID)
+	if err != nil {
+		t.Fatalf("Failed to re-run: %v", err)
+	}
+	if rerun.Labels["release/channel"] != "beta" || rerun.Env["EXTRA"] != "${{ gantry.run_id }}" {
+		t.Errorf("Expected the re-run to keep the env and labels, got %v and %v", rerun.Env, rerun.Labels)
+	}
+	waitForRun(t, srv, rerun.ID)
+}
+
+func TestServer_TriggerWorkflow_InvalidParams(t *testing.T) {
+	srv := newSchedulerTestServer(&fakeExecutor{})
+	if err := srv.storage.SaveWorkflow(variablesWorkflow()); err != nil {
+		t.Fatalf("Failed to save workflow: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		opts    TriggerOptions
+		wantErr error
+	}{
+		{"env name", TriggerOptions{Env: map[string]string{"IMAGE-TAG": "v2"}}, ErrInvalidEnv},
+		{"label key", TriggerOptions{Labels: map[string]string{"-team": "web"}}, ErrInvalidLabels},
+		{"long label key", TriggerOptions{Labels: map[string]string{strings.Repeat("k", 64): ""}}, ErrInvalidLabels},
+		{"long label value", TriggerOptions{Labels: map[string]string{"notes": strings.Repeat("x", 257)}}, ErrInvalidLabels},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := srv.TriggerWorkflow(context.Background(), testWorkflowName, tt.opts); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"log"
+
+	"gantry/internal/models"
+)
+
+// queuedStatus marks a job waiting for a free executor slot, and a run none
+// of whose jobs has started yet
+const queuedStatus = "queued"
+
+// jobPool bounds how many jobs the executor runs at once, across all runs.
+// A nil pool doesn't limit jobs.
+type jobPool struct {
+	slots chan struct{}
+}
+
+// newJobPool creates a pool of max slots, or returns nil when max is zero
+func newJobPool(max int) *jobPool {
+	if max <= 0 {
+		return nil
+	}
+	return &jobPool{slots: make(chan struct{}, max)}
+}
+
+// tryAcquire takes a free slot, if there is one
+func (p *jobPool) tryAcquire() bool {
+	if p == nil {
+		return true
+	}
+	select {
+	case p.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// acquire waits for a free slot, or until ctx is done
+func (p *jobPool) acquire(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees a slot taken by acquire or tryAcquire
+func (p *jobPool) release() {
+	if p != nil {
+		<-p.slots
+	}
+}
+
+// waitForSlot takes an executor slot for a job, recording the job as queued
+// while every slot is taken
+func (s *Server) waitForSlot(ctx context.Context, run *models.WorkflowRun, jobName string, job models.Job) error {
+	if s.pool.tryAcquire() {
+		return nil
+	}
+
+	log.Printf("Job %s queued until an executor slot is free", jobName)
+	job.Status = queuedStatus
+	run.UpdateJob(jobName, job)
+	if err := s.updateRun(run); err != nil {
+		log.Printf("ERROR: failed to update run status in storage: %v", err)
+	}
+	return s.pool.acquire(ctx)
+}
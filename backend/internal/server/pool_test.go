@@ -0,0 +1,75 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"gantry/internal/models"
+)
+
+func TestRunJobs_LimitsConcurrentJobs(t *testing.T) {
+	exec := &fakeExecutor{delay: 20 * time.Millisecond}
+	srv := newSchedulerTestServer(exec)
+	srv.pool = newJobPool(1)
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"test": matrixJob("1", "2", "3")},
+		JobOrder: []string{"test"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	if exec.peak != 1 {
+		t.Errorf("Expected one job at a time, got %d at once", exec.peak)
+	}
+	for _, name := range run.JobOrder {
+		if job, _ := run.GetJob(name); job.Status != successStatus {
+			t.Errorf("Expected job %s to succeed, got '%s'", name, job.Status)
+		}
+	}
+}
+
+func TestRunJobs_QueuesJobsUntilSlotIsFree(t *testing.T) {
+	exec := &fakeExecutor{hang: map[string]bool{"test (1)": true, "test (2)": true}}
+	srv := newSchedulerTestServer(exec)
+	srv.pool = newJobPool(1)
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"test": matrixJob("1", "2")},
+		JobOrder: []string{"test"},
+	}
+
+	done := make(chan *models.WorkflowRun)
+	go func() { done <- runWorkflowSync(t, srv, wf) }()
+
+	// One job runs while the other waits for its slot
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if run, err := srv.GetRun("run-test"); err == nil {
+			a, _ := run.GetJob("test (1)")
+			b, _ := run.GetJob("test (2)")
+			if (a.Status == queuedStatus) != (b.Status == queuedStatus) {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected a job to be queued")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := srv.CancelRun("run-test"); err != nil {
+		t.Fatalf("Failed to cancel run: %v", err)
+	}
+	run := <-done
+	for _, name := range run.JobOrder {
+		if job, _ := run.GetJob(name); job.Status != cancelledStatus {
+			t.Errorf("Expected job %s to be cancelled, got '%s'", name, job.Status)
+		}
+	}
+	if len(srv.pool.slots) != 0 {
+		t.Errorf("Expected every slot to be released, %d still taken", len(srv.pool.slots))
+	}
+}
@@ -0,0 +1,224 @@
+package server
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"gantry/internal/executor"
+	"gantry/internal/expr"
+	"gantry/internal/models"
+)
+
+// Image pull statuses
+const (
+	pullQueued  = "queued"
+	pullPulling = "pulling"
+	pullPulled  = "pulled"
+	pullFailed  = "failed"
+)
+
+// ImagePull reports the progress of pulling an image ahead of the runs of
+// the workflows that use it
+type ImagePull struct {
+	Image     string     `json:"image"`
+	Status    string     `json:"status"` // queued, pulling, pulled or failed
+	Error     string     `json:"error,omitempty"`
+	Workflows []string   `json:"workflows"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+}
+
+// imagePulls tracks the images pulled for uploaded workflows, by image
+type imagePulls struct {
+	mu    sync.Mutex
+	pulls map[string]*ImagePull
+}
+
+// workflowImage is an image a workflow's job or service runs in
+type workflowImage struct {
+	image       string
+	policy      string
+	credentials *models.RegistryCredentials
+}
+
+// prepullImages starts pulling the images of a workflow in the background,
+// when the server pre-pulls images and its executor can. Images already
+// queued or being pulled for another workflow aren't pulled twice.
+func (s *Server) prepullImages(wf *models.Workflow) {
+	puller, ok := s.executor.(executor.ImagePuller)
+	if s.pulls == nil || !ok {
+		return
+	}
+
+	var queued []workflowImage
+	s.pulls.mu.Lock()
+	for _, img := range s.workflowImages(wf) {
+		if img.policy == models.PullNever {
+			continue
+		}
+		pull, exists := s.pulls.pulls[img.image]
+		if !exists {
+			pull = &ImagePull{Image: img.image}
+			s.pulls.pulls[img.image] = pull
+		}
+		if !containsString(pull.Workflows, wf.Name) {
+			pull.Workflows = append(pull.Workflows, wf.Name)
+			sort.Strings(pull.Workflows)
+		}
+		if pull.Status == pullQueued || pull.Status == pullPulling {
+			continue
+		}
+		pull.Status, pull.Error = pullQueued, ""
+		queued = append(queued, img)
+	}
+	s.pulls.mu.Unlock()
+
+	if len(queued) == 0 {
+		return
+	}
+	go func() {
+		for _, img := range queued {
+			s.pullImage(puller, img)
+		}
+	}()
+}
+
+// pullImage pulls one queued image, recording its progress
+func (s *Server) pullImage(puller executor.ImagePuller, img workflowImage) {
+	started := time.Now()
+	s.pulls.update(img.image, func(pull *ImagePull) {
+		pull.Status, pull.StartedAt, pull.EndedAt = pullPulling, &started, nil
+	})
+
+	err := puller.PullImage(img.image, img.policy, img.credentials, s.registryAuths)
+	ended := time.Now()
+	s.pulls.update(img.image, func(pull *ImagePull) {
+		pull.Status, pull.EndedAt = pullPulled, &ended
+		if err != nil {
+			pull.Status, pull.Error = pullFailed, err.Error()
+		}
+	})
+	if err != nil {
+		log.Printf("WARNING: failed to pre-pull image %s: %v", img.image, err)
+	}
+}
+
+// update changes the record of an image's pull
+func (p *imagePulls) update(image string, change func(*ImagePull)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pull, ok := p.pulls[image]; ok {
+		change(pull)
+	}
+}
+
+// ImagePulls returns the pulls of a workflow's images, sorted by image, or
+// of every workflow's when name is empty
+func (s *Server) ImagePulls(name string) []ImagePull {
+	list := []ImagePull{}
+	if s.pulls == nil {
+		return list
+	}
+
+	s.pulls.mu.Lock()
+	defer s.pulls.mu.Unlock()
+	for _, pull := range s.pulls.pulls {
+		if name == "" || containsString(pull.Workflows, name) {
+			copied := *pull
+			copied.Workflows = append([]string(nil), pull.Workflows...)
+			list = append(list, copied)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Image < list[j].Image })
+	return list
+}
+
+// workflowImages lists the images a workflow's jobs and services run in, for
+// each combination of a job's matrix. Images that depend on anything but the
+// matrix and secrets aren't known until the job runs.
+func (s *Server) workflowImages(wf *models.Workflow) []workflowImage {
+	var images []workflowImage
+	seen := make(map[string]bool)
+	add := func(image, policy string, credentials *models.RegistryCredentials) {
+		if image == "" || seen[image] {
+			return
+		}
+		seen[image] = true
+		images = append(images, workflowImage{image: image, policy: policy, credentials: credentials})
+	}
+
+	secrets := s.secretsContext()
+	for _, name := range sortedJobNames(wf) {
+		job := wf.Jobs[name]
+		if job.Uses != "" {
+			continue
+		}
+		policy := job.PullPolicy
+		if policy == "" {
+			policy = s.pullPolicy
+		}
+
+		combos := []map[string]string{{}}
+		if job.Strategy != nil && !job.Strategy.Matrix.Empty() {
+			combos = job.Strategy.Matrix.Combinations()
+		}
+		for _, combo := range combos {
+			matrix := make(map[string]interface{}, len(combo))
+			for k, v := range combo {
+				matrix[k] = v
+			}
+			exprCtx := &expr.Context{Values: map[string]interface{}{"matrix": matrix, "secrets": secrets}}
+
+			if c := job.Container; c != nil {
+				credentials, _ := resolveCredentials(c.Credentials, exprCtx)
+				if image, err := expr.Interpolate(c.Image, exprCtx); err == nil {
+					add(image, policy, credentials)
+				}
+			} else if runsOn, err := expr.Interpolate(job.RunsOn, exprCtx); err == nil {
+				if r, err := s.runner(runsOn); err == nil {
+					add(r.Image, policy, nil)
+				}
+			}
+			for _, svcName := range sortedServices(job.Services) {
+				svc := job.Services[svcName]
+				credentials, _ := resolveCredentials(svc.Credentials, exprCtx)
+				if image, err := expr.Interpolate(svc.Image, exprCtx); err == nil {
+					add(image, policy, credentials)
+				}
+			}
+		}
+	}
+	return images
+}
+
+// sortedJobNames returns the names of a workflow's jobs in a stable order
+func sortedJobNames(wf *models.Workflow) []string {
+	names := make([]string, 0, len(wf.Jobs))
+	for name := range wf.Jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedServices returns the names of a job's services in a stable order
+func sortedServices(services map[string]models.Service) []string {
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// containsString reports whether list holds s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
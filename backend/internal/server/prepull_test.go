@@ -0,0 +1,165 @@
+package server
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"gantry/internal/models"
+	"gantry/internal/parser"
+	"gantry/internal/secrets"
+	"gantry/internal/storage"
+)
+
+// pullingExecutor records the images pre-pulled through it
+type pullingExecutor struct {
+	fakeExecutor
+	mu          sync.Mutex
+	pulled      map[string]string // pull policy each image was pulled with
+	credentials map[string]models.RegistryCredentials
+	failPull    map[string]bool
+}
+
+func (e *pullingExecutor) PullImage(image, policy string, own *models.RegistryCredentials,
+	auths map[string]models.RegistryCredentials) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pulled[image] = policy
+	if own != nil {
+		e.credentials[image] = *own
+	}
+	if e.failPull[image] {
+		return fmt.Errorf("pull access denied for %s", image)
+	}
+	return nil
+}
+
+func waitForPulls(t *testing.T, srv *Server, name string, want int) []ImagePull {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		pulls := srv.ImagePulls(name)
+		done := 0
+		for _, pull := range pulls {
+			if pull.Status == pullPulled || pull.Status == pullFailed {
+				done++
+			}
+		}
+		if done == want && len(pulls) == want {
+			return pulls
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %d pulls, got %+v", want, pulls)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestParseAndSaveWorkflow_PrepullsImages(t *testing.T) {
+	exec := &pullingExecutor{
+		pulled:      make(map[string]string),
+		credentials: make(map[string]models.RegistryCredentials),
+		failPull:    map[string]bool{"ghcr.io/acme/private:1": true},
+	}
+	store := secrets.NewMemoryStore()
+	store.Set("GHCR_TOKEN", "s3cret")
+	srv := &Server{
+		storage:    storage.NewMemoryStorage(),
+		executor:   exec,
+		parser:     parser.NewParser(),
+		secrets:    store,
+		pullPolicy: models.PullIfNotPresent,
+		pulls:      &imagePulls{pulls: make(map[string]*ImagePull)},
+	}
+
+	yaml := []byte(`
+name: Images
+on:
+  workflow_dispatch:
+    inputs:
+      image:
+        default: node:20
+jobs:
+  test:
+    runs-on: ${{ matrix.go }}
+    strategy:
+      matrix:
+        go: [golang:1.22, golang:1.23]
+    steps:
+      - name: Test
+        run: go test ./...
+  build:
+    runs-on: ubuntu
+    pull-policy: Always
+    container:
+      image: ghcr.io/acme/builder:1
+      credentials:
+        username: acme
+        password: ${{ secrets.GHCR_TOKEN }}
+    services:
+      redis: redis:7
+      private: ghcr.io/acme/private:1
+    steps:
+      - name: Build
+        run: make
+  lint:
+    runs-on: ${{ inputs.image }}
+    steps:
+      - name: Lint
+        run: make lint
+  offline:
+    runs-on: alpine
+    pull-policy: Never
+    steps:
+      - name: Check
+        run: true
+`)
+	if _, err := srv.ParseAndSaveWorkflow(yaml, ""); err != nil {
+		t.Fatalf("Failed to parse and save workflow: %v", err)
+	}
+
+	pulls := waitForPulls(t, srv, "Images", 5)
+	var images []string
+	for _, pull := range pulls {
+		images = append(images, pull.Image)
+		if !reflect.DeepEqual(pull.Workflows, []string{"Images"}) || pull.StartedAt == nil || pull.EndedAt == nil {
+			t.Errorf("Expected a finished pull for the workflow, got %+v", pull)
+		}
+	}
+	want := []string{"ghcr.io/acme/builder:1", "ghcr.io/acme/private:1", "golang:1.22", "golang:1.23", "redis:7"}
+	if !reflect.DeepEqual(images, want) {
+		t.Errorf("Expected pulls of %v, got %v", want, images)
+	}
+	if pulls[1].Status != pullFailed || pulls[1].Error == "" || pulls[0].Status != pullPulled {
+		t.Errorf("Expected only the private image to fail, got %+v", pulls)
+	}
+
+	if exec.pulled["golang:1.22"] != models.PullIfNotPresent || exec.pulled["redis:7"] != models.PullAlways {
+		t.Errorf("Expected the server's policy unless the job sets one, got %v", exec.pulled)
+	}
+	if creds := exec.credentials["ghcr.io/acme/builder:1"]; creds.Password != "s3cret" {
+		t.Errorf("Expected the container's credentials to be resolved, got %+v", creds)
+	}
+	if len(srv.ImagePulls("Other")) != 0 {
+		t.Error("Expected no pulls for another workflow")
+	}
+}
+
+func TestParseAndSaveWorkflow_NoPrepullByDefault(t *testing.T) {
+	exec := &pullingExecutor{pulled: make(map[string]string)}
+	srv := &Server{
+		storage:  storage.NewMemoryStorage(),
+		executor: exec,
+		parser:   parser.NewParser(),
+	}
+
+	yaml := []byte("name: Images\njobs:\n  test:\n    runs-on: golang:1.22\n    steps:\n      - name: Test\n        run: go test\n")
+	if _, err := srv.ParseAndSaveWorkflow(yaml, ""); err != nil {
+		t.Fatalf("Failed to parse and save workflow: %v", err)
+	}
+	if pulls := srv.ImagePulls(""); len(pulls) != 0 || len(exec.pulled) != 0 {
+		t.Errorf("Expected no pre-pulls, got %+v", pulls)
+	}
+}
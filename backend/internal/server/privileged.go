@@ -0,0 +1,21 @@
+package server
+
+import (
+	"fmt"
+
+	"gantry/internal/models"
+)
+
+// checkPrivileged returns an error naming the first privileged job, unless
+// the server allows privileged jobs
+func (s *Server) checkPrivileged(jobs ...models.Job) error {
+	if s.allowPrivileged {
+		return nil
+	}
+	for _, job := range jobs {
+		if job.Privileged {
+			return fmt.Errorf("privileged jobs are not allowed on this server (set ALLOW_PRIVILEGED_JOBS)")
+		}
+	}
+	return nil
+}
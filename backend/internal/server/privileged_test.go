@@ -0,0 +1,50 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"gantry/internal/models"
+)
+
+func TestCheckPrivileged(t *testing.T) {
+	srv := newSchedulerTestServer(&fakeExecutor{})
+
+	if err := srv.checkPrivileged(testJob()); err != nil {
+		t.Errorf("Expected an unprivileged job to be allowed, got %v", err)
+	}
+	privileged := testJob()
+	privileged.Privileged = true
+	if err := srv.checkPrivileged(privileged); err == nil || !strings.Contains(err.Error(), "ALLOW_PRIVILEGED_JOBS") {
+		t.Errorf("Expected a privileged job to be rejected, got %v", err)
+	}
+
+	srv.allowPrivileged = true
+	if err := srv.checkPrivileged(privileged); err != nil {
+		t.Errorf("Expected a privileged job to be allowed, got %v", err)
+	}
+}
+
+func TestRunJob_Privileged(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+
+	job := testJob()
+	job.Privileged = true
+	wf := &models.Workflow{Name: testWorkflowName, Jobs: map[string]models.Job{"build": job}}
+
+	run := runWorkflowSync(t, srv, wf)
+	if recorded, _ := run.GetJob("build"); recorded.Status != failedStatus || !strings.Contains(recorded.Output, "privileged") {
+		t.Errorf("Expected the job to fail while privileged jobs aren't allowed, got '%s': %s", recorded.Status, recorded.Output)
+	}
+
+	srv.allowPrivileged = true
+	run = runWorkflowSync(t, srv, wf)
+	recorded, _ := run.GetJob("build")
+	if recorded.Status != successStatus || !recorded.Privileged {
+		t.Errorf("Expected the run to record a successful privileged job, got '%s' (privileged %v)", recorded.Status, recorded.Privileged)
+	}
+	if !exec.jobs["build"].Privileged {
+		t.Error("Expected the executor to run the job privileged")
+	}
+}
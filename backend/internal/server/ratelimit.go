@@ -0,0 +1,99 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter keeps a token bucket per client, so that one misbehaving
+// webhook or script can't queue more runs than its share. A nil limiter
+// doesn't limit requests.
+type rateLimiter struct {
+	rate  float64 // tokens added per second
+	burst float64 // most tokens a bucket holds
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// bucket is one client's tokens as of when it was last drawn from
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter creates a limiter allowing perMinute requests a minute to
+// each client, in bursts of up to burst, or returns nil when perMinute is
+// zero. A burst of zero is perMinute.
+func newRateLimiter(perMinute, burst int) *rateLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = perMinute
+	}
+	return &rateLimiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(burst),
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// allow takes a token from a client's bucket, or returns how long until
+// it holds one
+func (l *rateLimiter) allow(key string) (time.Duration, bool) {
+	if l == nil {
+		return 0, true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return wait, false
+	}
+	b.tokens--
+	return 0, true
+}
+
+// sweep forgets, at most once a refill period, the buckets that have
+// refilled since they were last drawn from, as new ones start out full
+func (l *rateLimiter) sweep(now time.Time) {
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	if now.Sub(l.lastSweep) < refill {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= refill {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// AllowRequest reports whether a client may make another rate-limited
+// request, and if not, how long until it may
+func (s *Server) AllowRequest(client string) (time.Duration, bool) {
+	return s.limiter.allow(client)
+}
+
+// TrustProxyHeaders reports whether clients are told apart by the address
+// a reverse proxy forwards, rather than by the address requests come from
+func (s *Server) TrustProxyHeaders() bool {
+	return s.trustProxyHeaders
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestRateLimiter(perMinute, burst int) (*rateLimiter, *time.Time) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	l := newRateLimiter(perMinute, burst)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestRateLimiter_Burst(t *testing.T) {
+	l, _ := newTestRateLimiter(60, 3)
+
+	for i := 0; i < 3; i++ {
+		if _, ok := l.allow("ip:10.0.0.1"); !ok {
+			t.Fatalf("Expected request %d of the burst to be allowed", i+1)
+		}
+	}
+	wait, ok := l.allow("ip:10.0.0.1")
+	if ok {
+		t.Fatal("Expected the request after the burst to be refused")
+	}
+	if wait != time.Second {
+		t.Errorf("Expected to wait 1s for the next token, got %s", wait)
+	}
+
+	if _, ok := l.allow("ip:10.0.0.2"); !ok {
+		t.Error("Expected another client to have its own bucket")
+	}
+}
+
+func TestRateLimiter_Refills(t *testing.T) {
+	l, now := newTestRateLimiter(60, 1)
+
+	if _, ok := l.allow("user:alice"); !ok {
+		t.Fatal("Expected the first request to be allowed")
+	}
+	if _, ok := l.allow("user:alice"); ok {
+		t.Fatal("Expected the second request to be refused")
+	}
+
+	*now = now.Add(500 * time.Millisecond)
+	if wait, ok := l.allow("user:alice"); ok || wait != 500*time.Millisecond {
+		t.Errorf("Expected to wait 500ms half way through a refill, got %s (allowed %v)", wait, ok)
+	}
+
+	*now = now.Add(500 * time.Millisecond)
+	if _, ok := l.allow("user:alice"); !ok {
+		t.Error("Expected a request to be allowed once a token refilled")
+	}
+}
+
+func TestRateLimiter_ForgetsIdleClients(t *testing.T) {
+	l, now := newTestRateLimiter(60, 2)
+
+	l.allow("ip:10.0.0.1")
+	*now = now.Add(3 * time.Second)
+	l.allow("ip:10.0.0.2")
+
+	if _, ok := l.buckets["ip:10.0.0.1"]; ok {
+		t.Error("Expected the refilled bucket to be forgotten")
+	}
+	if _, ok := l.buckets["ip:10.0.0.2"]; !ok {
+		t.Error("Expected the bucket just drawn from to be kept")
+	}
+}
+
+func TestRateLimiter_Disabled(t *testing.T) {
+	l := newRateLimiter(0, 10)
+	for i := 0; i < 100; i++ {
+		if _, ok := l.allow("ip:10.0.0.1"); !ok {
+			t.Fatal("Expected a disabled limiter to allow every request")
+		}
+	}
+}
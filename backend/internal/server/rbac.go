@@ -0,0 +1,114 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gantry/internal/auth"
+	"gantry/internal/models"
+	"gantry/internal/storage"
+)
+
+// Errors of roles and permissions
+var (
+	ErrForbidden   = errors.New("permission denied")
+	ErrInvalidRole = errors.New("invalid role")
+	ErrLastAdmin   = errors.New("the last admin can't be demoted")
+)
+
+// Authorize checks that a user's role has a permission, recording in the
+// audit trail the action it was needed for unless it only views
+func (s *Server) Authorize(username, permission, action string) error {
+	// Deleted users keep their tokens until they expire, but no role
+	role := ""
+	user, err := s.storage.GetUser(username)
+	if err == nil {
+		role = userRole(user)
+	} else if !errors.Is(err, storage.ErrUserNotFound) {
+		return err
+	}
+
+	allowed := auth.Allows(role, permission)
+	if !allowed || (permission != auth.PermViewRuns && permission != auth.PermViewLogs) {
+		s.recordAudit(&models.AuditEntry{
+			Time:       time.Now(),
+			User:       username,
+			Role:       role,
+			Permission: permission,
+			Action:     action,
+			Allowed:    allowed,
+		})
+	}
+	if !allowed {
+		return fmt.Errorf("%w: %s needs %s", ErrForbidden, action, permission)
+	}
+	return nil
+}
+
+// userRole returns a user's role. Users registered before there were
+// roles had every permission, and keep them as admins.
+func userRole(user *models.User) string {
+	if user.Role == "" {
+		return auth.RoleAdmin
+	}
+	return user.Role
+}
+
+// recordAudit adds an entry to the audit trail. Failing to doesn't fail
+// the request, as the action has already been allowed or refused.
+func (s *Server) recordAudit(entry *models.AuditEntry) {
+	if err := s.storage.SaveAuditEntry(entry); err != nil {
+		log.Printf("WARNING: failed to record audit entry for %s %s: %v", entry.User, entry.Action, err)
+	}
+}
+
+// ListAuditEntries returns a page of the audit trail, newest first
+func (s *Server) ListAuditEntries(opts storage.ListOptions) ([]*models.AuditEntry, int, error) {
+	return s.storage.ListAuditEntries(opts)
+}
+
+// ListUsers returns every user, ordered by username
+func (s *Server) ListUsers() ([]*models.User, error) {
+	return s.storage.ListUsers()
+}
+
+// SetUserRole gives a user another role. The last admin keeps theirs, so
+// someone can still manage users.
+func (s *Server) SetUserRole(username, role string) (*models.User, error) {
+	if !auth.ValidRole(role) {
+		return nil, fmt.Errorf("%w '%s' (expected %s, %s or %s)", ErrInvalidRole, role,
+			auth.RoleViewer, auth.RoleMaintainer, auth.RoleAdmin)
+	}
+
+	// Registering holds the same lock, so admins are counted as saved
+	s.sessions.registerMu.Lock()
+	defer s.sessions.registerMu.Unlock()
+
+	user, err := s.storage.GetUser(username)
+	if err != nil {
+		return nil, err
+	}
+	if userRole(user) == auth.RoleAdmin && role != auth.RoleAdmin {
+		users, err := s.storage.ListUsers()
+		if err != nil {
+			return nil, err
+		}
+		admins := 0
+		for _, u := range users {
+			if userRole(u) == auth.RoleAdmin {
+				admins++
+			}
+		}
+		if admins <= 1 {
+			return nil, ErrLastAdmin
+		}
+	}
+
+	user.Role = role
+	if err := s.storage.UpdateUser(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"gantry/internal/auth"
+	"gantry/internal/models"
+	"gantry/internal/secrets"
+	"gantry/internal/storage"
+)
+
+func TestServer_Register_Roles(t *testing.T) {
+	srv := newAuthTestServer(t, true)
+
+	first, err := srv.Register("alice", "correct horse")
+	if err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+	second, err := srv.Register("bob", "correct horse")
+	if err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+	if first.Role != auth.RoleAdmin || second.Role != auth.RoleViewer {
+		t.Errorf("Expected an admin then a viewer, got %s and %s", first.Role, second.Role)
+	}
+}
+
+func TestServer_Authorize(t *testing.T) {
+	srv := newAuthTestServer(t, true)
+	if _, err := srv.Register("alice", "correct horse"); err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+	if _, err := srv.Register("bob", "correct horse"); err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+
+	if err := srv.Authorize("bob", auth.PermViewLogs, "GET /api/runs/r/jobs/j/logs"); err != nil {
+		t.Errorf("Expected a viewer to view logs, got %v", err)
+	}
+	if err := srv.Authorize("bob", auth.PermTriggerRuns, "POST /api/workflows/ci/trigger"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Expected ErrForbidden, got %v", err)
+	}
+	if err := srv.Authorize("alice", auth.PermManageSecrets, "PUT /api/secrets/TOKEN"); err != nil {
+		t.Errorf("Expected an admin to manage secrets, got %v", err)
+	}
+	if err := srv.Authorize("mallory", auth.PermViewRuns, "GET /api/runs"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Expected a deleted user to be refused, got %v", err)
+	}
+
+	// Views that were allowed aren't recorded
+	entries, total, err := srv.ListAuditEntries(storage.ListOptions{})
+	if err != nil {
+		t.Fatalf("Failed to list audit entries: %v", err)
+	}
+	if total != 3 {
+		t.Fatalf("Expected 3 audit entries, got %d", total)
+	}
+	if e := entries[0]; e.User != "mallory" || e.Allowed {
+		t.Errorf("Expected mallory's refusal first, got %+v", e)
+	}
+	if e := entries[1]; e.User != "alice" || !e.Allowed || e.Permission != auth.PermManageSecrets || e.Action != "PUT /api/secrets/TOKEN" {
+		t.Errorf("Expected alice's secret change, got %+v", e)
+	}
+	if e := entries[2]; e.User != "bob" || e.Allowed || e.Role != auth.RoleViewer {
+		t.Errorf("Expected bob's refused trigger, got %+v", e)
+	}
+}
+
+func TestServer_Authorize_UserWithoutRole(t *testing.T) {
+	srv := newAuthTestServer(t, false)
+	if err := srv.storage.SaveUser(&models.User{Username: "alice"}); err != nil {
+		t.Fatalf("Failed to save user: %v", err)
+	}
+
+	if err := srv.Authorize("alice", auth.PermManageUsers, "GET /api/users"); err != nil {
+		t.Errorf("Expected a user from before roles to keep every permission, got %v", err)
+	}
+}
+
+func TestServer_SetUserRole(t *testing.T) {
+	srv := newAuthTestServer(t, true)
+	for _, name := range []string{"alice", "bob"} {
+		if _, err := srv.Register(name, "correct horse"); err != nil {
+			t.Fatalf("Failed to register: %v", err)
+		}
+	}
+
+	if _, err := srv.SetUserRole("bob", "owner"); !errors.Is(err, ErrInvalidRole) {
+		t.Errorf("Expected ErrInvalidRole, got %v", err)
+	}
+	if _, err := srv.SetUserRole("carol", auth.RoleViewer); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+	if _, err := srv.SetUserRole("alice", auth.RoleViewer); !errors.Is(err, ErrLastAdmin) {
+		t.Errorf("Expected ErrLastAdmin, got %v", err)
+	}
+
+	if _, err := srv.SetUserRole("bob", auth.RoleAdmin); err != nil {
+		t.Fatalf("Failed to promote bob: %v", err)
+	}
+	user, err := srv.SetUserRole("alice", auth.RoleMaintainer)
+	if err != nil {
+		t.Fatalf("Expected alice to step down with another admin, got %v", err)
+	}
+	if user.Role != auth.RoleMaintainer {
+		t.Errorf("Expected alice to be a maintainer, got %s", user.Role)
+	}
+	if err := srv.Authorize("alice", auth.PermManageUsers, "GET /api/users"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Expected a maintainer not to manage users, got %v", err)
+	}
+}
+
+func TestServer_SetAndDeleteSecret(t *testing.T) {
+	srv := &Server{secrets: secrets.NewMemoryStore()}
+
+	if err := srv.SetSecret("1TOKEN", "value"); !errors.Is(err, ErrInvalidSecret) {
+		t.Errorf("Expected ErrInvalidSecret for a bad name, got %v", err)
+	}
+	if err := srv.SetSecret("TOKEN", ""); !errors.Is(err, ErrInvalidSecret) {
+		t.Errorf("Expected ErrInvalidSecret for an empty value, got %v", err)
+	}
+	if err := srv.SetSecret("TOKEN", "s3cret"); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+	if names := srv.SecretNames(); len(names) != 1 || names[0] != "TOKEN" {
+		t.Errorf("Expected [TOKEN], got %v", names)
+	}
+
+	if err := srv.DeleteSecret("TOKEN"); err != nil {
+		t.Fatalf("Failed to delete secret: %v", err)
+	}
+	if err := srv.DeleteSecret("TOKEN"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Expected ErrSecretNotFound, got %v", err)
+	}
+}
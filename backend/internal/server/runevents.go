@@ -0,0 +1,211 @@
+package server
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gantry/internal/models"
+)
+
+// Types of run events
+const (
+	RunEventStarted   = "run_started"   // a run was created
+	RunEventStatus    = "run_status"    // a run's status changed
+	RunEventJobStatus = "job_status"    // a job's status changed
+	RunEventCompleted = "run_completed" // a run finished, with its final status
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind by before
+// it is dropped
+const subscriberBuffer = 256
+
+// RunEvent is a change to a run, as clients following runs receive it
+type RunEvent struct {
+	Type     string    `json:"type"`
+	RunID    string    `json:"run_id"`
+	Workflow string    `json:"workflow"`
+	Job      string    `json:"job,omitempty"`
+	Status   string    `json:"status"`
+	Time     time.Time `json:"time"`
+}
+
+// runEvents tells subscribers how the runs of this server change. Runs are
+// compared with how they were last saved, so every save of a run reports
+// what changed since.
+type runEvents struct {
+	mu          sync.Mutex
+	seen        map[string]*runState // by run ID, until the run completes
+	subscribers map[*runSubscriber]bool
+}
+
+// runState is what was last reported of a run
+type runState struct {
+	status string
+	jobs   map[string]string // job statuses by name
+}
+
+// runSubscriber receives the events of one run, or of every run when runID
+// is empty
+type runSubscriber struct {
+	runID   string
+	events  chan RunEvent
+	dropped atomic.Bool
+}
+
+// updateRun saves a run and reports how it changed
+func (s *Server) updateRun(run *models.WorkflowRun) error {
+	if err := s.storage.UpdateRun(run); err != nil {
+		return err
+	}
+	s.observeRun(run)
+	return nil
+}
+
+// observeRun reports how a run changed to subscribers and outgoing webhooks
+func (s *Server) observeRun(run *models.WorkflowRun) {
+	for _, ev := range s.runEvents.observe(run) {
+		s.sendHooks(ev, run)
+	}
+}
+
+// observe reports how a run changed since it was last observed, and
+// returns the events it sent
+func (e *runEvents) observe(run *models.WorkflowRun) []RunEvent {
+	snapshot := run.Clone()
+	now := time.Now()
+	event := func(typ, job, status string) RunEvent {
+		return newRunEvent(snapshot, now, typ, job, status)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var events []RunEvent
+	state, ok := e.seen[snapshot.ID]
+	if !ok {
+		state = &runState{status: snapshot.Status, jobs: make(map[string]string)}
+		if e.seen == nil {
+			e.seen = make(map[string]*runState)
+		}
+		e.seen[snapshot.ID] = state
+		events = append(events, event(RunEventStarted, "", snapshot.Status))
+	} else if snapshot.Status != state.status {
+		state.status = snapshot.Status
+		events = append(events, event(RunEventStatus, "", snapshot.Status))
+	}
+
+	for _, name := range sortedJobs(snapshot) {
+		status := snapshot.Jobs[name].Status
+		if status != "" && status != state.jobs[name] {
+			state.jobs[name] = status
+			events = append(events, event(RunEventJobStatus, name, status))
+		}
+	}
+
+	if snapshot.CompletedAt != nil {
+		delete(e.seen, snapshot.ID)
+		events = append(events, event(RunEventCompleted, "", snapshot.Status))
+	}
+
+	for _, ev := range events {
+		e.send(ev)
+	}
+	return events
+}
+
+// RunSnapshot describes a run's current state as events: its status, the
+// status of each job that has one and, once it finished, its completion
+func RunSnapshot(run *models.WorkflowRun) []RunEvent {
+	snapshot := run.Clone()
+	now := time.Now()
+	event := func(typ, job, status string) RunEvent {
+		return newRunEvent(snapshot, now, typ, job, status)
+	}
+
+	events := []RunEvent{event(RunEventStatus, "", snapshot.Status)}
+	for _, name := range sortedJobs(snapshot) {
+		if status := snapshot.Jobs[name].Status; status != "" {
+			events = append(events, event(RunEventJobStatus, name, status))
+		}
+	}
+	if snapshot.CompletedAt != nil {
+		events = append(events, event(RunEventCompleted, "", snapshot.Status))
+	}
+	return events
+}
+
+// newRunEvent creates an event about run
+func newRunEvent(run *models.WorkflowRun, now time.Time, typ, job, status string) RunEvent {
+	return RunEvent{Type: typ, RunID: run.ID, Workflow: run.WorkflowName, Job: job, Status: status, Time: now}
+}
+
+// sortedJobs returns the names of a run's jobs in order, so events about
+// them come in the same order every time
+func sortedJobs(run *models.WorkflowRun) []string {
+	names := make([]string, 0, len(run.Jobs))
+	for name := range run.Jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// send passes an event to its subscribers, dropping those that haven't
+// taken the events they were passed before rather than holding up the run
+func (e *runEvents) send(ev RunEvent) {
+	for sub := range e.subscribers {
+		if sub.runID != "" && sub.runID != ev.RunID {
+			continue
+		}
+		select {
+		case sub.events <- ev:
+		default:
+			sub.dropped.Store(true)
+			close(sub.events)
+			delete(e.subscribers, sub)
+		}
+	}
+}
+
+// RunSubscription receives run events until it is closed. Events is closed
+// too when the subscriber falls too far behind.
+type RunSubscription struct {
+	Events <-chan RunEvent
+
+	events     *runEvents
+	subscriber *runSubscriber
+}
+
+// Dropped reports whether Events was closed because the subscriber fell too
+// far behind
+func (s *RunSubscription) Dropped() bool {
+	return s.subscriber.dropped.Load()
+}
+
+// Close stops receiving events
+func (s *RunSubscription) Close() {
+	e := s.events
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.subscribers[s.subscriber] {
+		delete(e.subscribers, s.subscriber)
+		close(s.subscriber.events)
+	}
+}
+
+// SubscribeRunEvents starts receiving the events of a run, or of every run
+// when runID is empty
+func (s *Server) SubscribeRunEvents(runID string) *RunSubscription {
+	sub := &runSubscriber{runID: runID, events: make(chan RunEvent, subscriberBuffer)}
+
+	e := &s.runEvents
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.subscribers == nil {
+		e.subscribers = make(map[*runSubscriber]bool)
+	}
+	e.subscribers[sub] = true
+	return &RunSubscription{Events: sub.events, events: e, subscriber: sub}
+}
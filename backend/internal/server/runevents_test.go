@@ -0,0 +1,114 @@
+package server
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"gantry/internal/models"
+)
+
+// drainEvents returns the events a subscription has received so far
+func drainEvents(sub *RunSubscription) []string {
+	var events []string
+	for {
+		select {
+		case ev := <-sub.Events:
+			events = append(events, fmt.Sprintf("%s %s %s", ev.Type, ev.Job, ev.Status))
+		default:
+			return events
+		}
+	}
+}
+
+func TestRunJobs_PublishesEvents(t *testing.T) {
+	srv := newSchedulerTestServer(&fakeExecutor{})
+	sub := srv.SubscribeRunEvents("run-test")
+	defer sub.Close()
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"build": testJob(), "test": testJob("build")},
+		JobOrder: []string{"build", "test"},
+	}
+	runWorkflowSync(t, srv, wf)
+
+	want := []string{
+		"run_started  running",
+		"job_status build running",
+		"job_status build success",
+		"job_status test running",
+		"job_status test success",
+		"run_status  success",
+		"run_completed  success",
+	}
+	if got := drainEvents(sub); !reflect.DeepEqual(got, want) {
+		t.Errorf("Events = %q, want %q", got, want)
+	}
+}
+
+func TestRunEvents_FiltersByRun(t *testing.T) {
+	srv := &Server{}
+	one := srv.SubscribeRunEvents("run-1")
+	defer one.Close()
+	all := srv.SubscribeRunEvents("")
+	defer all.Close()
+
+	for _, id := range []string{"run-1", "run-2"} {
+		srv.runEvents.observe(&models.WorkflowRun{ID: id, Status: runningStatus, Jobs: map[string]models.Job{}})
+	}
+
+	if got := drainEvents(one); len(got) != 1 {
+		t.Errorf("Expected only the events of run-1, got %q", got)
+	}
+	if got := drainEvents(all); len(got) != 2 {
+		t.Errorf("Expected the events of every run, got %q", got)
+	}
+}
+
+func TestRunEvents_DropsSlowSubscriber(t *testing.T) {
+	srv := &Server{}
+	sub := srv.SubscribeRunEvents("")
+	defer sub.Close()
+
+	for i := 0; i <= subscriberBuffer; i++ {
+		srv.runEvents.observe(&models.WorkflowRun{ID: fmt.Sprintf("run-%d", i), Jobs: map[string]models.Job{}})
+	}
+
+	received := 0
+	for range sub.Events {
+		received++
+	}
+	if received != subscriberBuffer || !sub.Dropped() {
+		t.Errorf("Expected a subscriber that fell behind to be dropped after %d events, got %d (dropped %v)", subscriberBuffer, received, sub.Dropped())
+	}
+}
+
+func TestRunSnapshot(t *testing.T) {
+	completed := time.Now()
+	run := &models.WorkflowRun{
+		ID:     "run-1",
+		Status: failedStatus,
+		Jobs: map[string]models.Job{
+			"test":  {Status: failedStatus},
+			"build": {Status: successStatus},
+			"lint":  {},
+		},
+		CompletedAt: &completed,
+	}
+
+	var got []string
+	for _, ev := range RunSnapshot(run) {
+		got = append(got, fmt.Sprintf("%s %s %s", ev.Type, ev.Job, ev.Status))
+	}
+	want := []string{
+		"run_status  failed",
+		"job_status build success",
+		"job_status test failed",
+		"run_completed  failed",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RunSnapshot = %q, want %q", got, want)
+	}
+}
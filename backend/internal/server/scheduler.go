@@ -0,0 +1,727 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"gantry/internal/executor"
+	"gantry/internal/expr"
+	"gantry/internal/models"
+	"gantry/internal/secrets"
+)
+
+// jobOutcome is reported by a job goroutine back to the scheduler
+type jobOutcome struct {
+	name   string
+	status string
+}
+
+// jobPlan is the expanded set of job instances executed for a run.
+// Matrix jobs contribute one instance per combination.
+type jobPlan struct {
+	order     []string
+	jobs      map[string]models.Job
+	deps      map[string][]string
+	instances map[string][]string // job name -> instance names
+	matrix    map[string]string   // matrix leg instance -> job name
+	env       map[string]string   // workflow-level env
+}
+
+// buildJobPlan expands matrix jobs and resolves the dependencies of every
+// instance. Workflows that never use `needs` keep running sequentially in
+// YAML order, so each job implicitly depends on the one before it. groups
+// maps jobs that called a reusable workflow to the jobs they expanded into.
+func buildJobPlan(wf *models.Workflow, groups map[string][]string) *jobPlan {
+	jobOrder := wf.JobOrder
+	if len(jobOrder) == 0 {
+		for name := range wf.Jobs {
+			jobOrder = append(jobOrder, name)
+		}
+	}
+
+	plan := &jobPlan{
+		jobs:      make(map[string]models.Job),
+		deps:      make(map[string][]string),
+		instances: make(map[string][]string, len(jobOrder)),
+		matrix:    make(map[string]string),
+		env:       wf.Env,
+	}
+
+	// Expand each job into its instances first so dependencies on a matrix
+	// job can wait for every leg
+	instances := plan.instances
+	for _, name := range jobOrder {
+		job := wf.Jobs[name]
+		if job.Strategy == nil || job.Strategy.Matrix.Empty() {
+			instances[name] = []string{name}
+			plan.jobs[name] = job
+			continue
+		}
+
+		for _, combo := range job.Strategy.Matrix.Combinations() {
+			instance := fmt.Sprintf("%s (%s)", name, strings.Join(matrixLabel(&job.Strategy.Matrix, combo), ", "))
+
+			leg := job
+			leg.Matrix = combo
+			instances[name] = append(instances[name], instance)
+			plan.jobs[instance] = leg
+			plan.matrix[instance] = name
+		}
+	}
+
+	for group, members := range groups {
+		for _, member := range members {
+			instances[group] = append(instances[group], instances[member]...)
+		}
+	}
+
+	explicit := wf.UsesNeeds()
+	for i, name := range jobOrder {
+		var needs []string
+		switch {
+		case explicit:
+			needs = wf.Jobs[name].Needs
+		case i > 0:
+			needs = []string{jobOrder[i-1]}
+		}
+
+		var deps []string
+		for _, need := range needs {
+			deps = append(deps, instances[need]...)
+		}
+
+		for _, instance := range instances[name] {
+			plan.order = append(plan.order, instance)
+			plan.deps[instance] = deps
+		}
+	}
+
+	return plan
+}
+
+// matrixLabel lists a leg's values for its instance name: declared
+// dimensions in order, then values added by include in key order
+func matrixLabel(m *models.Matrix, combo map[string]string) []string {
+	values := make([]string, 0, len(combo))
+	for _, key := range m.Order {
+		if value, ok := combo[key]; ok {
+			values = append(values, value)
+		}
+	}
+
+	var added []string
+	for key := range combo {
+		if _, declared := m.Dimensions[key]; !declared {
+			added = append(added, key)
+		}
+	}
+	sort.Strings(added)
+	for _, key := range added {
+		values = append(values, combo[key])
+	}
+	return values
+}
+
+// runJobs executes the workflow's job graph. Once a job's dependencies have
+// finished its `if:` condition is evaluated (defaulting to `success()`), so
+// jobs downstream of a failure are skipped unless they opt in. Ready jobs are
+// started concurrently.
+func (s *Server) runJobs(_ context.Context, run *models.WorkflowRun, plan *jobPlan) {
+	defer func() {
+		// Every job has finished, so what they shared can be removed
+		if cleaner, ok := s.executor.(executor.RunCleaner); ok {
+			if err := cleaner.CleanupRun(run.ID); err != nil {
+				log.Printf("WARNING: failed to clean up run %s: %v", run.ID, err)
+			}
+		}
+		run.Complete()
+
+		if err := s.updateRun(run); err != nil {
+			log.Printf("ERROR: failed to update run status in storage: %v", err)
+		}
+	}()
+
+	// Don't use the HTTP request context as it may time out; job timeouts
+	// are enforced by the executor
+	jobCtx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	s.trackRun(run, cancel)
+	defer s.untrackRun(run.ID)
+
+	outcomes := make(chan jobOutcome)
+	statuses := make(map[string]string, len(plan.order))
+	pending := append([]string(nil), plan.order...)
+	running := 0
+
+	// Matrix legs share a context per job so fail-fast can cancel them, and
+	// are counted per job to enforce max-parallel
+	legCtxs := make(map[string]context.Context)
+	legCancels := make(map[string]context.CancelFunc)
+	legsRunning := make(map[string]int)
+	failedFast := make(map[string]bool)
+	defer func() {
+		for _, cancel := range legCancels {
+			cancel()
+		}
+	}()
+
+	// schedule starts or skips every pending job whose dependencies have
+	// finished, and reports whether anything changed
+	schedule := func() bool {
+		// A cancelled run starts nothing more
+		if runCancelled(jobCtx) {
+			for _, name := range pending {
+				s.cancelJob(run, name, plan.jobs[name], cancelledStatus)
+				statuses[name] = cancelledStatus
+			}
+			progressed := len(pending) > 0
+			pending = nil
+			return progressed
+		}
+
+		progressed := false
+		waiting := pending[:0:0]
+
+		for _, name := range pending {
+			ready := true
+			for _, dep := range plan.deps[name] {
+				if _, done := statuses[dep]; !done {
+					ready = false
+					break
+				}
+			}
+			if !ready {
+				waiting = append(waiting, name)
+				continue
+			}
+
+			job := plan.jobs[name]
+			group := plan.matrix[name]
+			ctx := jobCtx
+			if group != "" {
+				if failedFast[group] {
+					progressed = true
+					s.cancelJob(run, name, job, skippedStatus)
+					statuses[name] = skippedStatus
+					continue
+				}
+				if max := job.Strategy.MaxParallel; max > 0 && legsRunning[group] >= max {
+					waiting = append(waiting, name)
+					continue
+				}
+				if legCtxs[group] == nil {
+					legCtxs[group], legCancels[group] = context.WithCancel(jobCtx)
+				}
+				ctx = legCtxs[group]
+			}
+
+			progressed = true
+			exprCtx, env, err := s.jobContext(run, plan, name, statuses)
+
+			shouldRun := false
+			if err == nil {
+				shouldRun, err = expr.EvaluateCondition(job.If, exprCtx)
+			}
+			switch {
+			case err != nil:
+				s.failJob(run, name, job, fmt.Sprintf("ERROR: %v", err))
+				statuses[name] = dependencyStatus(job, failedStatus)
+			case !shouldRun:
+				s.skipJob(run, name, job)
+				statuses[name] = skippedStatus
+			default:
+				running++
+				if group != "" {
+					legsRunning[group]++
+				}
+				go func(name string, job models.Job, exprCtx *expr.Context, env map[string]string) {
+					outcomes <- jobOutcome{name: name, status: s.runJob(ctx, run, name, job, exprCtx, env)}
+				}(name, job, exprCtx, env)
+			}
+		}
+
+		pending = waiting
+		return progressed
+	}
+
+	for {
+		for schedule() {
+		}
+
+		if running == 0 {
+			// Nothing in flight and nothing schedulable; anything still pending
+			// is unreachable (validation rejects cycles, so this is defensive)
+			for _, name := range pending {
+				s.skipJob(run, name, plan.jobs[name])
+				statuses[name] = skippedStatus
+			}
+			break
+		}
+
+		outcome := <-outcomes
+		running--
+		job := plan.jobs[outcome.name]
+		if group := plan.matrix[outcome.name]; group != "" {
+			legsRunning[group]--
+			if outcome.status == failedStatus && !job.ContinueOnError && job.Strategy.FailsFast() && !failedFast[group] {
+				log.Printf("Matrix leg %s failed, cancelling the remaining legs of %s", outcome.name, group)
+				failedFast[group] = true
+				legCancels[group]()
+			}
+		}
+		statuses[outcome.name] = dependencyStatus(job, outcome.status)
+	}
+
+	if runCancelled(jobCtx) {
+		run.SetStatus(cancelledStatus)
+	} else {
+		run.SetStatus(runStatus(run, plan, statuses))
+	}
+
+	if err := s.updateRun(run); err != nil {
+		log.Printf("ERROR: failed to update run status in storage: %v", err)
+	}
+}
+
+// dependencyStatus is the status a finished job presents to its dependents
+// and to the run: a failed job with continue-on-error counts as a success
+func dependencyStatus(job models.Job, status string) string {
+	if status == failedStatus && job.ContinueOnError {
+		return successStatus
+	}
+	return status
+}
+
+// runStatus derives the run's final status. A run whose only failures were
+// tolerated by continue-on-error is reported as success with failures.
+func runStatus(run *models.WorkflowRun, plan *jobPlan, statuses map[string]string) string {
+	for _, status := range statuses {
+		if status == failedStatus {
+			return failedStatus
+		}
+	}
+
+	for _, name := range plan.order {
+		job, ok := run.GetJob(name)
+		if !ok {
+			continue
+		}
+		if job.Status == failedStatus {
+			return successWithFailuresStatus
+		}
+		for _, step := range job.Steps {
+			if step.Status == failedStatus {
+				return successWithFailuresStatus
+			}
+		}
+	}
+	return successStatus
+}
+
+// runJob executes a single job and returns its final status
+func (s *Server) runJob(ctx context.Context, run *models.WorkflowRun, jobName string, job models.Job,
+	exprCtx *expr.Context, env map[string]string) string {
+	log.Printf("Starting job: %s", jobName)
+
+	// Check if executor is available
+	if s.executor == nil {
+		s.failJob(run, jobName, job, "ERROR: executor not initialized")
+		return failedStatus
+	}
+
+	// Secrets are resolved at execution time, so one removed since upload
+	// fails the job with a clear message instead of an empty value
+	if err := s.checkSecrets(nil, job); err != nil {
+		s.failJob(run, jobName, job, fmt.Sprintf("ERROR: %v", err))
+		return failedStatus
+	}
+	// As are volumes, since the allowed paths may have changed, privileged
+	// mode, GPUs and VM isolation
+	if err := s.checkVolumes(job); err != nil {
+		s.failJob(run, jobName, job, fmt.Sprintf("ERROR: %v", err))
+		return failedStatus
+	}
+	if err := s.checkPrivileged(job); err != nil {
+		s.failJob(run, jobName, job, fmt.Sprintf("ERROR: %v", err))
+		return failedStatus
+	}
+	if err := s.checkGPUs(job); err != nil {
+		s.failJob(run, jobName, job, fmt.Sprintf("ERROR: %v", err))
+		return failedStatus
+	}
+	if err := s.checkIsolation(job); err != nil {
+		s.failJob(run, jobName, job, fmt.Sprintf("ERROR: %v", err))
+		return failedStatus
+	}
+	if job.Privileged {
+		log.Printf("WARNING: job %s of run %s runs privileged", jobName, run.ID)
+	}
+
+	// Resolve expressions and step conditions up front; the executor only
+	// sees the interpolated job, and skipped steps stay on the recorded job
+	// without being handed to it
+	job.Steps = append([]models.Step(nil), job.Steps...)
+	execJob := job
+	execJob.Env = env
+	execJob.Steps = nil
+
+	runsOn, err := expr.Interpolate(job.RunsOn, exprCtx)
+	if err != nil {
+		s.failJob(run, jobName, job, fmt.Sprintf("ERROR: runs-on: %v", err))
+		return failedStatus
+	}
+	execJob.RunsOn = runsOn
+	runner, err := s.runner(runsOn)
+	if err != nil {
+		s.failJob(run, jobName, job, fmt.Sprintf("ERROR: runs-on: %v", err))
+		return failedStatus
+	}
+
+	if execJob.WorkingDirectory, err = expr.Interpolate(job.WorkingDirectory, exprCtx); err != nil {
+		s.failJob(run, jobName, job, fmt.Sprintf("ERROR: working-directory: %v", err))
+		return failedStatus
+	}
+
+	if execJob.Container, err = resolveContainer(job.Container, exprCtx); err != nil {
+		s.failJob(run, jobName, job, fmt.Sprintf("ERROR: container %v", err))
+		return failedStatus
+	}
+	if execJob.User, err = expr.Interpolate(job.User, exprCtx); err != nil {
+		s.failJob(run, jobName, job, fmt.Sprintf("ERROR: user: %v", err))
+		return failedStatus
+	}
+	if err := s.checkUsers(execJob); err != nil {
+		s.failJob(run, jobName, job, fmt.Sprintf("ERROR: %v", err))
+		return failedStatus
+	}
+	if execJob.Checkout, err = resolveCheckout(job.Checkout, exprCtx, run.Trigger); err != nil {
+		s.failJob(run, jobName, job, fmt.Sprintf("ERROR: checkout: %v", err))
+		return failedStatus
+	}
+	if execJob.Services, err = resolveServices(job.Services, exprCtx); err != nil {
+		s.failJob(run, jobName, job, fmt.Sprintf("ERROR: %v", err))
+		return failedStatus
+	}
+	if execJob.Artifacts, err = resolveJobArtifacts(job.Artifacts, exprCtx); err != nil {
+		s.failJob(run, jobName, job, fmt.Sprintf("ERROR: %v", err))
+		return failedStatus
+	}
+	if execJob.CacheVolumes, err = resolveCacheVolumes(job.CacheVolumes, exprCtx); err != nil {
+		s.failJob(run, jobName, job, fmt.Sprintf("ERROR: %v", err))
+		return failedStatus
+	}
+	// Images are checked once resolved, since the policy may have changed
+	// since upload and some images weren't known until now
+	if err := s.checkJobImages(execJob, runner.Image); err != nil {
+		s.failJob(run, jobName, job, fmt.Sprintf("ERROR: %v", err))
+		return failedStatus
+	}
+
+	// Steps that read steps.<id> are handed over as they are and resolved by
+	// resolveDeferred just before they run
+	deferred := make(map[int]bool)
+	for i, step := range job.Steps {
+		usesSteps, err := usesStepsContext(step)
+		if err == nil && usesSteps {
+			deferred[len(execJob.Steps)] = true
+			execJob.Steps = append(execJob.Steps, step)
+			continue
+		}
+
+		resolved, stepCtx, err := resolveStep(step, exprCtx, env)
+		shouldRun := false
+		if err == nil {
+			resolved.When, shouldRun, err = stepWhen(step.If, stepCtx)
+		}
+		if err != nil {
+			s.failJob(run, jobName, job, fmt.Sprintf("ERROR: step '%s': %v", step.Name, err))
+			return failedStatus
+		}
+		if !shouldRun {
+			job.Steps[i].Status = skippedStatus
+			continue
+		}
+		execJob.Steps = append(execJob.Steps, resolved)
+	}
+
+	resolveDeferred := func(index int, results []models.StepResult) (models.Step, error) {
+		step := execJob.Steps[index]
+		if !deferred[index] {
+			return step, nil
+		}
+
+		stepsCtx := exprCtx.With("steps", stepsContext(stepStates(job.Steps, results)))
+		resolved, stepCtx, err := resolveStep(step, stepsCtx, env)
+		if err != nil {
+			return step, err
+		}
+		when, shouldRun, err := stepWhen(step.If, stepCtx)
+		if err != nil {
+			return step, err
+		}
+		if !shouldRun {
+			when = models.StepWhenNever
+		}
+		resolved.When = when
+		return resolved, nil
+	}
+
+	// Jobs targeting a protected environment wait for a reviewer before any
+	// of their steps run
+	if job.Environment != "" {
+		name, err := expr.Interpolate(job.Environment, exprCtx)
+		if err != nil {
+			s.failJob(run, jobName, job, fmt.Sprintf("ERROR: environment: %v", err))
+			return failedStatus
+		}
+		environment, err := s.checkEnvironmentRules(run, name)
+		if err != nil {
+			s.failJob(run, jobName, job, fmt.Sprintf("ERROR: %v", err))
+			return failedStatus
+		}
+		job.Environment = name
+		if environment.Protected() {
+			job.Approval, err = s.awaitApproval(ctx, run, jobName, job, environment)
+			if err != nil && runCancelled(ctx) {
+				s.cancelJob(run, jobName, job, cancelledStatus)
+				return cancelledStatus
+			}
+			if err != nil {
+				job.FailureReason = models.FailureCancelled
+				s.failJob(run, jobName, job, fmt.Sprintf("Cancelled while waiting for approval: %v", err))
+				return failedStatus
+			}
+			if !job.Approval.Approved {
+				job.FailureReason = models.FailureRejected
+				s.failJob(run, jobName, job, fmt.Sprintf("Deployment to '%s' rejected by %s", name, job.Approval.Reviewer))
+				return failedStatus
+			}
+		}
+	}
+
+	// Jobs take one of the executor's slots, waiting while every slot is
+	// taken. A leg cancelled by fail-fast while it waits never started.
+	if err := s.waitForSlot(ctx, run, jobName, job); err != nil {
+		status := skippedStatus
+		if runCancelled(ctx) {
+			status = cancelledStatus
+		}
+		s.cancelJob(run, jobName, job, status)
+		return status
+	}
+	defer s.pool.release()
+
+	jobStartTime := time.Now()
+	job.Status = runningStatus
+	job.StartedAt = jobStartTime
+	run.UpdateJob(jobName, job)
+	run.SwapStatus(queuedStatus, runningStatus)
+
+	if err := s.updateRun(run); err != nil {
+		log.Printf("ERROR: failed to update run status in storage: %v", err)
+	}
+
+	var result *models.JobResult
+	if len(execJob.Steps) > 0 {
+		req := executor.Request{
+			RunID:     run.ID,
+			JobName:   jobName,
+			Job:       execJob,
+			Workflow:  run.WorkflowName,
+			Labels:    run.Labels,
+			Artifacts: s.artifacts,
+			Cache:     s.cache,
+
+			RunnerImage:   runner.Image,
+			RegistryAuths: s.registryAuths,
+			PullPolicy:    s.pullPolicy,
+			MaxRuntime:    s.maxJobRuntime,
+			MaxOutput:     s.maxJobOutput,
+
+			CacheVolumeBudget: s.cacheVolumeBudget,
+			Hardening:         s.hardening,
+			ImagePolicy:       s.imagePolicy,
+			VMRuntime:         s.vmRuntime,
+			DefaultUser:       s.jobUser,
+		}
+		if job.PullPolicy != "" {
+			req.PullPolicy = job.PullPolicy
+		}
+		if len(deferred) > 0 {
+			req.ResolveStep = resolveDeferred
+		}
+		live := s.newLiveOutput(run, jobName)
+		req.Output = live
+		result, err = s.executor.Execute(ctx, req)
+		live.Close()
+	}
+
+	jobEndTime := time.Now()
+	if result != nil {
+		job.Output = secrets.Mask(result.Output, s.secrets)
+		job.OutputSize = result.OutputSize
+		job.OutputTruncated = s.maxJobOutput > 0 && result.OutputSize > s.maxJobOutput
+		if len(result.ServiceLogs) > 0 {
+			job.ServiceLogs = make(map[string]string, len(result.ServiceLogs))
+			for name, logs := range result.ServiceLogs {
+				job.ServiceLogs[name] = secrets.Mask(logs, s.secrets)
+			}
+		}
+		if len(result.ServiceStderr) > 0 {
+			job.ServiceStderr = make(map[string]string, len(result.ServiceStderr))
+			for name, logs := range result.ServiceStderr {
+				job.ServiceStderr[name] = secrets.Mask(logs, s.secrets)
+			}
+		}
+	}
+	job.EndedAt = &jobEndTime
+
+	if result != nil {
+		for i := range result.Steps {
+			result.Steps[i].Output = secrets.Mask(result.Steps[i].Output, s.secrets)
+			for j := range result.Steps[i].Attempts {
+				attempt := &result.Steps[i].Attempts[j]
+				attempt.Output = secrets.Mask(attempt.Output, s.secrets)
+			}
+		}
+		job.StepStates = maskStepStates(stepStates(job.Steps, result.Steps), s.secrets)
+		recordStepResults(&job, result.Steps)
+
+		for _, step := range result.Steps {
+			for key, value := range step.Outputs {
+				if job.Outputs == nil {
+					job.Outputs = make(map[string]string)
+				}
+				job.Outputs[key] = secrets.Mask(value, s.secrets)
+			}
+		}
+	}
+
+	if err != nil {
+		job.Status = failedStatus
+		switch {
+		case errors.Is(err, executor.ErrTimeout):
+			job.FailureReason = models.FailureTimeout
+		case errors.Is(err, executor.ErrOutOfMemory):
+			job.FailureReason = models.FailureOutOfMemory
+		case errors.Is(err, context.Canceled):
+			job.FailureReason = models.FailureCancelled
+			if runCancelled(ctx) {
+				job.Status = cancelledStatus
+			}
+		}
+		log.Printf("Job %s failed: %v", jobName, err)
+	} else {
+		job.Status = successStatus
+		log.Printf("Job %s completed successfully", jobName)
+	}
+
+	run.UpdateJob(jobName, job)
+	if err := s.updateRun(run); err != nil {
+		log.Printf("ERROR: failed to update run status in storage: %v", err)
+	}
+
+	return job.Status
+}
+
+// maskStepStates masks secrets in recorded step outputs, dropping the map
+// when no step has an id
+func maskStepStates(states map[string]models.StepState, store secrets.Store) map[string]models.StepState {
+	if len(states) == 0 {
+		return nil
+	}
+	for id, state := range states {
+		if len(state.Outputs) > 0 {
+			outputs := make(map[string]string, len(state.Outputs))
+			for k, v := range state.Outputs {
+				outputs[k] = secrets.Mask(v, store)
+			}
+			state.Outputs = outputs
+			states[id] = state
+		}
+	}
+	return states
+}
+
+// recordStepResults copies executed step results onto the recorded steps,
+// which still include the skipped ones the executor never saw, and names
+// the first failed step that didn't continue on error as the job's failure
+func recordStepResults(job *models.Job, results []models.StepResult) {
+	next := 0
+	for i := range job.Steps {
+		if job.Steps[i].Status == skippedStatus {
+			continue
+		}
+		if next >= len(results) {
+			return
+		}
+
+		res := results[next]
+		next++
+
+		step := &job.Steps[i]
+		if res.Skipped {
+			step.Status = skippedStatus
+			continue
+		}
+		step.StartedAt = res.StartedAt
+		endedAt := res.EndedAt
+		step.EndedAt = &endedAt
+		step.Output = res.Output
+		step.Status = successStatus
+		step.ExitCode = res.ExitCode
+		if !res.Success {
+			step.Status = failedStatus
+			if job.FailedStep == "" && !step.ContinueOnError {
+				job.FailedStep = step.Name
+				job.ExitCode = step.ExitCode
+			}
+		}
+		if res.TimedOut {
+			step.FailureReason = models.FailureTimeout
+		}
+		if res.OutOfMemory {
+			step.FailureReason = models.FailureOutOfMemory
+		}
+		step.Attempts = res.Attempts
+	}
+}
+
+// failJob records a job that failed before reaching the executor
+func (s *Server) failJob(run *models.WorkflowRun, jobName string, job models.Job, output string) {
+	job.Status = failedStatus
+	job.Output = output
+	run.UpdateJob(jobName, job)
+	if err := s.updateRun(run); err != nil {
+		log.Printf("ERROR: failed to update run status in storage: %v", err)
+	}
+}
+
+// cancelJob records a job that never started because its run was cancelled,
+// or a matrix leg skipped because a sibling failed with fail-fast
+func (s *Server) cancelJob(run *models.WorkflowRun, jobName string, job models.Job, status string) {
+	log.Printf("Cancelling job: %s", jobName)
+
+	job.Status = status
+	job.FailureReason = models.FailureCancelled
+	run.UpdateJob(jobName, job)
+	if err := s.updateRun(run); err != nil {
+		log.Printf("ERROR: failed to update run status in storage: %v", err)
+	}
+}
+
+// skipJob records a job whose `if:` condition evaluated to false
+func (s *Server) skipJob(run *models.WorkflowRun, jobName string, job models.Job) {
+	log.Printf("Skipping job: %s", jobName)
+
+	job.Status = skippedStatus
+	run.UpdateJob(jobName, job)
+	if err := s.updateRun(run); err != nil {
+		log.Printf("ERROR: failed to update run status in storage: %v", err)
+	}
+}
@@ -0,0 +1,1583 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gantry/internal/executor"
+	"gantry/internal/models"
+	"gantry/internal/parser"
+	"gantry/internal/runners"
+	"gantry/internal/secrets"
+	"gantry/internal/storage"
+)
+
+// fakeExecutor records job execution and fails the configured jobs and steps
+type fakeExecutor struct {
+	mu        sync.Mutex
+	fail      map[string]bool
+	failSteps map[string]bool
+	exitCodes map[string]int               // exit code of each failing step, 1 if unset
+	flaky     map[string]int               // failing attempts before a step succeeds
+	outputs   map[string]map[string]string // outputs written by each step
+	timeout   map[string]bool
+	oom       map[string]bool // jobs whose first step exceeds the memory limit
+	hang      map[string]bool // jobs that run until cancelled
+	output    string
+	delay     time.Duration
+	order     []string
+	jobs      map[string]models.Job
+	images    map[string]string // runner image each job was given
+	policies  map[string]string // pull policy each job was given
+	runtimes  map[string]time.Duration
+	hardening map[string]executor.Hardening
+	vmRuntime map[string]string            // VM runtime each job was given
+	labels    map[string]map[string]string // run labels each job was given
+	active    int
+	peak      int
+	cleaned   []string // runs cleaned up, in order
+}
+
+func (e *fakeExecutor) Execute(ctx context.Context, req executor.Request) (*models.JobResult, error) {
+	jobName, job := req.JobName, req.Job
+
+	e.mu.Lock()
+	e.order = append(e.order, jobName)
+	if e.jobs == nil {
+		e.jobs = make(map[string]models.Job)
+	}
+	e.jobs[jobName] = job
+	if e.images == nil {
+		e.images = make(map[string]string)
+	}
+	e.images[jobName] = req.RunnerImage
+	if e.policies == nil {
+		e.policies = make(map[string]string)
+	}
+	e.policies[jobName] = req.PullPolicy
+	if e.runtimes == nil {
+		e.runtimes = make(map[string]time.Duration)
+	}
+	e.runtimes[jobName] = req.MaxRuntime
+	if e.hardening == nil {
+		e.hardening = make(map[string]executor.Hardening)
+	}
+	e.hardening[jobName] = req.Hardening
+	if e.vmRuntime == nil {
+		e.vmRuntime = make(map[string]string)
+	}
+	e.vmRuntime[jobName] = req.VMRuntime
+	if e.labels == nil {
+		e.labels = make(map[string]map[string]string)
+	}
+	e.labels[jobName] = req.Labels
+	e.active++
+	if e.active > e.peak {
+		e.peak = e.active
+	}
+	e.mu.Unlock()
+
+	delay := e.delay
+	if e.hang[jobName] {
+		delay = time.Minute
+	}
+
+	var cancelled error
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+		cancelled = fmt.Errorf("job cancelled: %w", ctx.Err())
+	}
+
+	e.mu.Lock()
+	e.active--
+	e.mu.Unlock()
+
+	if cancelled != nil {
+		return &models.JobResult{Output: "cancelled"}, cancelled
+	}
+
+	if e.fail[jobName] {
+		return &models.JobResult{Output: "boom"}, fmt.Errorf("job %s failed", jobName)
+	}
+	if e.timeout[jobName] {
+		return &models.JobResult{
+			Output: "slow",
+			Steps:  []models.StepResult{{TimedOut: true}},
+		}, fmt.Errorf("step '%s' exceeded its timeout: %w", job.Steps[0].Name, executor.ErrTimeout)
+	}
+	if e.oom[jobName] {
+		return &models.JobResult{
+			Output: "killed",
+			Steps:  []models.StepResult{{OutOfMemory: true}},
+		}, fmt.Errorf("step '%s' was killed for exceeding the job's memory limit: %w", job.Steps[0].Name, executor.ErrOutOfMemory)
+	}
+
+	result := &models.JobResult{Output: "ok"}
+	var failure error
+	job.Steps = append([]models.Step(nil), job.Steps...)
+	for i, step := range job.Steps {
+		if req.ResolveStep != nil {
+			resolved, err := req.ResolveStep(i, result.Steps)
+			if err != nil {
+				return result, err
+			}
+			step = resolved
+			job.Steps[i] = resolved
+		}
+		if !step.RunsAfter(failure != nil) {
+			result.Steps = append(result.Steps, models.StepResult{Skipped: true})
+			continue
+		}
+		failed := e.failSteps[step.Name]
+		stepResult := models.StepResult{Success: !failed, Outputs: e.outputs[step.Name], Output: step.Name + " output"}
+		if step.Retry != nil {
+			for i := 0; i < step.Retry.MaxAttempts(); i++ {
+				success := i >= e.flaky[step.Name]
+				stepResult.Attempts = append(stepResult.Attempts, models.StepAttempt{Success: success, Output: e.output})
+				if success {
+					break
+				}
+			}
+			failed = !stepResult.Attempts[len(stepResult.Attempts)-1].Success
+			stepResult.Success = !failed
+		}
+		if failed {
+			stepResult.ExitCode = 1
+			if code, ok := e.exitCodes[step.Name]; ok {
+				stepResult.ExitCode = code
+			}
+		}
+		result.Steps = append(result.Steps, stepResult)
+		if failed && !step.ContinueOnError && failure == nil {
+			failure = fmt.Errorf("step '%s' failed", step.Name)
+		}
+	}
+
+	// Record the steps as they were resolved while running
+	e.mu.Lock()
+	e.jobs[jobName] = job
+	e.mu.Unlock()
+
+	if failure != nil {
+		return result, failure
+	}
+	if e.output != "" {
+		result.Output = e.output
+	}
+	for name := range job.Services {
+		if result.ServiceLogs == nil {
+			result.ServiceLogs = make(map[string]string)
+		}
+		result.ServiceLogs[name] = name + " ready"
+		if result.ServiceStderr == nil {
+			result.ServiceStderr = make(map[string]string)
+		}
+		result.ServiceStderr[name] = name + " warning"
+	}
+	return result, nil
+}
+
+func (e *fakeExecutor) Cleanup() error { return nil }
+
+func (e *fakeExecutor) CleanupRun(runID string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.cleaned = append(e.cleaned, runID)
+	return nil
+}
+
+func newSchedulerTestServer(exec *fakeExecutor) *Server {
+	return &Server{
+		storage:  storage.NewMemoryStorage(),
+		executor: exec,
+		parser:   parser.NewParser(),
+	}
+}
+
+func runWorkflowSync(t *testing.T, srv *Server, wf *models.Workflow) *models.WorkflowRun {
+	t.Helper()
+
+	plan := buildJobPlan(wf, nil)
+	run := &models.WorkflowRun{
+		ID:           "run-test",
+		WorkflowName: wf.Name,
+		Status:       runningStatus,
+		Jobs:         make(map[string]models.Job),
+		JobOrder:     plan.order,
+		StartedAt:    time.Now(),
+	}
+	if err := srv.storage.SaveRun(run); err != nil {
+		t.Fatalf("Failed to save run: %v", err)
+	}
+
+	srv.runJobs(context.Background(), run, plan)
+	return run
+}
+
+// waitForRun polls until an asynchronously started run finishes
+func waitForRun(t *testing.T, srv *Server, runID string) *models.WorkflowRun {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		run, err := srv.storage.GetRun(runID)
+		if err != nil {
+			t.Fatalf("Failed to get run: %v", err)
+		}
+		if clone := run.Clone(); clone.Status != runningStatus {
+			return clone
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Run %s did not finish", runID)
+	return nil
+}
+
+func testJob(needs ...string) models.Job {
+	return models.Job{
+		RunsOn: "ubuntu",
+		Needs:  needs,
+		Steps:  []models.Step{{Name: "Step", Run: "echo step"}},
+	}
+}
+
+func TestRunJobs_RespectsNeeds(t *testing.T) {
+	exec := &fakeExecutor{delay: 20 * time.Millisecond}
+	srv := newSchedulerTestServer(exec)
+
+	wf := &models.Workflow{
+		Name: testWorkflowName,
+		Jobs: map[string]models.Job{
+			"build":  testJob(),
+			"lint":   testJob(),
+			"test":   testJob("build"),
+			"deploy": testJob("test", "lint"),
+		},
+		JobOrder: []string{"build", "lint", "test", "deploy"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	if run.Status != successStatus {
+		t.Errorf("Expected run status '%s', got '%s'", successStatus, run.Status)
+	}
+
+	position := make(map[string]int)
+	for i, name := range exec.order {
+		position[name] = i
+	}
+
+	if position["test"] < position["build"] {
+		t.Errorf("Expected test to run after build, got order %v", exec.order)
+	}
+	if position["deploy"] < position["test"] || position["deploy"] < position["lint"] {
+		t.Errorf("Expected deploy to run last, got order %v", exec.order)
+	}
+
+	if exec.peak < 2 {
+		t.Errorf("Expected independent jobs to run concurrently, peak concurrency was %d", exec.peak)
+	}
+}
+
+func TestRunJobs_CleansUpRunAfterLastJob(t *testing.T) {
+	exec := &fakeExecutor{fail: map[string]bool{"build": true}}
+	srv := newSchedulerTestServer(exec)
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"build": testJob(), "test": testJob()},
+		JobOrder: []string{"build", "test"},
+	}
+	run := runWorkflowSync(t, srv, wf)
+
+	if len(exec.cleaned) != 1 || exec.cleaned[0] != run.ID {
+		t.Errorf("Expected the run to be cleaned up once, got %v", exec.cleaned)
+	}
+}
+
+func TestRunJobs_SkipsDownstreamOfFailure(t *testing.T) {
+	exec := &fakeExecutor{fail: map[string]bool{"build": true}}
+	srv := newSchedulerTestServer(exec)
+
+	wf := &models.Workflow{
+		Name: testWorkflowName,
+		Jobs: map[string]models.Job{
+			"build":  testJob(),
+			"lint":   testJob(),
+			"test":   testJob("build"),
+			"deploy": testJob("test"),
+		},
+		JobOrder: []string{"build", "lint", "test", "deploy"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	if run.Status != failedStatus {
+		t.Errorf("Expected run status '%s', got '%s'", failedStatus, run.Status)
+	}
+
+	expected := map[string]string{
+		"build":  failedStatus,
+		"lint":   successStatus,
+		"test":   skippedStatus,
+		"deploy": skippedStatus,
+	}
+	for name, status := range expected {
+		job, exists := run.GetJob(name)
+		if !exists {
+			t.Errorf("Expected job '%s' to be recorded on the run", name)
+			continue
+		}
+		if job.Status != status {
+			t.Errorf("Expected job '%s' status '%s', got '%s'", name, status, job.Status)
+		}
+	}
+}
+
+func TestRunJobs_SequentialWithoutNeeds(t *testing.T) {
+	exec := &fakeExecutor{fail: map[string]bool{"second": true}}
+	srv := newSchedulerTestServer(exec)
+
+	wf := &models.Workflow{
+		Name: testWorkflowName,
+		Jobs: map[string]models.Job{
+			"first":  testJob(),
+			"second": testJob(),
+			"third":  testJob(),
+		},
+		JobOrder: []string{"first", "second", "third"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	if len(exec.order) != 2 || exec.order[0] != "first" || exec.order[1] != "second" {
+		t.Errorf("Expected [first second] to execute in order, got %v", exec.order)
+	}
+
+	if exec.peak != 1 {
+		t.Errorf("Expected sequential execution, peak concurrency was %d", exec.peak)
+	}
+
+	third, _ := run.GetJob("third")
+	if third.Status != skippedStatus {
+		t.Errorf("Expected third job to be skipped, got '%s'", third.Status)
+	}
+}
+
+func TestBuildJobPlan_ExpandsMatrix(t *testing.T) {
+	matrixJob := testJob()
+	matrixJob.Strategy = &models.Strategy{Matrix: models.Matrix{
+		Order:      []string{"go"},
+		Dimensions: map[string][]string{"go": {"1.20", "1.21"}},
+	}}
+
+	wf := &models.Workflow{
+		Name: testWorkflowName,
+		Jobs: map[string]models.Job{
+			"test":   matrixJob,
+			"deploy": testJob("test"),
+		},
+		JobOrder: []string{"test", "deploy"},
+	}
+
+	plan := buildJobPlan(wf, nil)
+
+	expectedOrder := []string{"test (1.20)", "test (1.21)", "deploy"}
+	if len(plan.order) != len(expectedOrder) {
+		t.Fatalf("Expected order %v, got %v", expectedOrder, plan.order)
+	}
+	for i, name := range expectedOrder {
+		if plan.order[i] != name {
+			t.Errorf("Expected order %v, got %v", expectedOrder, plan.order)
+			break
+		}
+	}
+
+	if got := plan.jobs["test (1.21)"].Matrix["go"]; got != "1.21" {
+		t.Errorf("Expected leg matrix value '1.21', got '%s'", got)
+	}
+
+	if deps := plan.deps["deploy"]; len(deps) != 2 {
+		t.Errorf("Expected deploy to depend on both matrix legs, got %v", deps)
+	}
+}
+
+func TestBuildJobPlan_MatrixIncludeExclude(t *testing.T) {
+	matrixJob := testJob()
+	matrixJob.Strategy = &models.Strategy{Matrix: models.Matrix{
+		Order:      []string{"go", "os"},
+		Dimensions: map[string][]string{"go": {"1.20", "1.21"}, "os": {"ubuntu", "alpine"}},
+		Exclude:    []map[string]string{{"go": "1.20", "os": "alpine"}},
+		Include:    []map[string]string{{"go": "1.21", "os": "alpine", "libc": "musl"}, {"go": "1.22", "os": "ubuntu"}},
+	}}
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"test": matrixJob},
+		JobOrder: []string{"test"},
+	}
+
+	plan := buildJobPlan(wf, nil)
+
+	expectedOrder := []string{"test (1.20, ubuntu)", "test (1.21, ubuntu)", "test (1.21, alpine, musl)", "test (1.22, ubuntu)"}
+	if !reflect.DeepEqual(plan.order, expectedOrder) {
+		t.Fatalf("Expected order %v, got %v", expectedOrder, plan.order)
+	}
+	if got := plan.jobs["test (1.21, alpine, musl)"].Matrix["libc"]; got != "musl" {
+		t.Errorf("Expected included value 'musl' on the leg, got '%s'", got)
+	}
+}
+
+func TestRunJobs_TracksMatrixLegs(t *testing.T) {
+	exec := &fakeExecutor{fail: map[string]bool{"test (1.21)": true}}
+	srv := newSchedulerTestServer(exec)
+
+	matrixJob := testJob()
+	matrixJob.Strategy = &models.Strategy{Matrix: models.Matrix{
+		Order:      []string{"go"},
+		Dimensions: map[string][]string{"go": {"1.20", "1.21"}},
+	}}
+	// Let the other leg finish instead of being cancelled by fail-fast
+	failFast := false
+	matrixJob.Strategy.FailFast = &failFast
+
+	wf := &models.Workflow{
+		Name: testWorkflowName,
+		Jobs: map[string]models.Job{
+			"test":   matrixJob,
+			"deploy": testJob("test"),
+		},
+		JobOrder: []string{"test", "deploy"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	leg, _ := run.GetJob("test (1.20)")
+	if leg.Status != successStatus || leg.Matrix["go"] != "1.20" {
+		t.Errorf("Expected successful leg with matrix go=1.20, got status '%s' matrix %v", leg.Status, leg.Matrix)
+	}
+
+	deploy, _ := run.GetJob("deploy")
+	if deploy.Status != skippedStatus {
+		t.Errorf("Expected deploy to be skipped after a failed leg, got '%s'", deploy.Status)
+	}
+}
+
+func TestRunJobs_JobConditions(t *testing.T) {
+	exec := &fakeExecutor{fail: map[string]bool{"build": true}}
+	srv := newSchedulerTestServer(exec)
+
+	cleanup := testJob("build")
+	cleanup.If = "always()"
+	notify := testJob("build")
+	notify.If = "${{ failure() && needs.build.result == 'failed' }}"
+	never := testJob()
+	never.If = "gantry.workflow == 'Other'"
+
+	wf := &models.Workflow{
+		Name: testWorkflowName,
+		Jobs: map[string]models.Job{
+			"build":   testJob(),
+			"deploy":  testJob("build"),
+			"cleanup": cleanup,
+			"notify":  notify,
+			"never":   never,
+		},
+		JobOrder: []string{"build", "deploy", "cleanup", "notify", "never"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	expected := map[string]string{
+		"build":   failedStatus,
+		"deploy":  skippedStatus,
+		"cleanup": successStatus,
+		"notify":  successStatus,
+		"never":   skippedStatus,
+	}
+	for name, status := range expected {
+		job, _ := run.GetJob(name)
+		if job.Status != status {
+			t.Errorf("Expected job '%s' status '%s', got '%s'", name, status, job.Status)
+		}
+	}
+}
+
+func TestRunJob_SkipsStepsWithFalseCondition(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+
+	job := testJob()
+	job.Strategy = &models.Strategy{Matrix: models.Matrix{
+		Order:      []string{"os"},
+		Dimensions: map[string][]string{"os": {"ubuntu"}},
+	}}
+	job.Steps = []models.Step{
+		{Name: "Always", Run: "echo always"},
+		{Name: "Alpine only", If: "matrix.os == 'alpine'", Run: "echo alpine"},
+	}
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"test": job},
+		JobOrder: []string{"test"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	leg, _ := run.GetJob("test (ubuntu)")
+	if leg.Status != successStatus {
+		t.Fatalf("Expected job to succeed, got '%s'", leg.Status)
+	}
+	if len(leg.Steps) != 2 || leg.Steps[1].Status != skippedStatus {
+		t.Errorf("Expected second step to be recorded as skipped, got %+v", leg.Steps)
+	}
+}
+
+func TestRunJob_InterpolatesExpressions(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+
+	job := testJob()
+	job.RunsOn = "${{ matrix.os }}"
+	job.Strategy = &models.Strategy{Matrix: models.Matrix{
+		Order:      []string{"os"},
+		Dimensions: map[string][]string{"os": {"alpine"}},
+	}}
+	job.Env = map[string]string{"TARGET": "${{ env.PREFIX }}-${{ matrix.os }}"}
+	job.Steps = []models.Step{{
+		Name: "Build on ${{ matrix.os }}",
+		Env:  map[string]string{"STEP": "${{ env.TARGET }}"},
+		Run:  "echo ${{ env.STEP }} ${{ gantry.workflow }}",
+	}}
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Env:      map[string]string{"PREFIX": "gantry"},
+		Jobs:     map[string]models.Job{"build": job},
+		JobOrder: []string{"build"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	name := "build (alpine)"
+	if recorded, _ := run.GetJob(name); recorded.Status != successStatus {
+		t.Fatalf("Expected job to succeed, got '%s': %s", recorded.Status, recorded.Output)
+	}
+
+	executed := exec.jobs[name]
+	if executed.RunsOn != "alpine" {
+		t.Errorf("Expected runs-on 'alpine', got '%s'", executed.RunsOn)
+	}
+	if executed.Env["PREFIX"] != "gantry" || executed.Env["TARGET"] != "gantry-alpine" {
+		t.Errorf("Expected merged workflow and job env, got %v", executed.Env)
+	}
+
+	step := executed.Steps[0]
+	if step.Name != "Build on alpine" {
+		t.Errorf("Expected interpolated step name, got '%s'", step.Name)
+	}
+	if step.Run != "echo gantry-alpine Test" {
+		t.Errorf("Expected interpolated run command, got '%s'", step.Run)
+	}
+
+	// The recorded job keeps the original templates
+	recorded, _ := run.GetJob(name)
+	if recorded.Steps[0].Run != job.Steps[0].Run {
+		t.Errorf("Expected recorded step to keep its template, got '%s'", recorded.Steps[0].Run)
+	}
+}
+
+func TestRunJob_SetsRunEnv(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+
+	job := testJob()
+	job.Env = map[string]string{"GANTRY_BRANCH": "override"}
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"build": job},
+		JobOrder: []string{"build"},
+	}
+
+	plan := buildJobPlan(wf, nil)
+	run := &models.WorkflowRun{
+		ID:           "run-test",
+		WorkflowName: wf.Name,
+		Status:       runningStatus,
+		Jobs:         make(map[string]models.Job),
+		JobOrder:     plan.order,
+		Inputs:       map[string]interface{}{"dry-run": true, "target": "prod"},
+		Trigger:      &models.TriggerInfo{Event: models.EventWorkflowDispatch, Actor: "alice", Branch: "main"},
+		StartedAt:    time.Now(),
+	}
+	if err := srv.storage.SaveRun(run); err != nil {
+		t.Fatalf("Failed to save run: %v", err)
+	}
+	srv.runJobs(context.Background(), run, plan)
+
+	env := exec.jobs["build"].Env
+	want := map[string]string{
+		"CI":                   "true",
+		"GANTRY_WORKFLOW":      testWorkflowName,
+		"GANTRY_RUN_ID":        run.ID,
+		"GANTRY_JOB":           "build",
+		"GANTRY_EVENT":         models.EventWorkflowDispatch,
+		"GANTRY_ACTOR":         "alice",
+		"GANTRY_BRANCH":        "override",
+		"GANTRY_INPUT_DRY_RUN": "true",
+		"GANTRY_INPUT_TARGET":  "prod",
+	}
+	for k, v := range want {
+		if env[k] != v {
+			t.Errorf("Expected %s=%s, got %q", k, v, env[k])
+		}
+	}
+	if _, ok := env["GANTRY_SHA"]; ok {
+		t.Errorf("Expected no GANTRY_SHA for a trigger without a commit, got %q", env["GANTRY_SHA"])
+	}
+}
+
+func TestRunJob_ResolvesRunnerImage(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+	srv.runners = runners.NewMemoryStore()
+	if err := srv.runners.(*runners.MemoryStore).Set(runners.Runner{Label: "debian", Image: "debian:bookworm"}); err != nil {
+		t.Fatalf("Failed to add runner: %v", err)
+	}
+
+	job := testJob()
+	job.RunsOn = "${{ matrix.os }}"
+	job.Strategy = &models.Strategy{
+		FailFast: new(bool),
+		Matrix: models.Matrix{
+			Order:      []string{"os"},
+			Dimensions: map[string][]string{"os": {"debian", "ubuntu", "node:20-alpine"}},
+		},
+	}
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"build": job},
+		JobOrder: []string{"build"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	if image := exec.images["build (debian)"]; image != "debian:bookworm" {
+		t.Errorf("Expected the debian runner's image, got '%s'", image)
+	}
+	if image := exec.images["build (node:20-alpine)"]; image != "node:20-alpine" {
+		t.Errorf("Expected the image named in runs-on, got '%s'", image)
+	}
+	recorded, _ := run.GetJob("build (ubuntu)")
+	if recorded.Status != failedStatus || !strings.Contains(recorded.Output, "'ubuntu' is not a known runner (expected one of debian, or an image") {
+		t.Errorf("Expected unknown runner to fail the leg, got '%s': %s", recorded.Status, recorded.Output)
+	}
+}
+
+func TestRunJob_FailsOnExpressionError(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+
+	job := testJob()
+	job.Steps = []models.Step{{Name: "Bad", Run: "echo ${{ fromJSON('{') }}"}}
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"build": job},
+		JobOrder: []string{"build"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	recorded, _ := run.GetJob("build")
+	if recorded.Status != failedStatus {
+		t.Errorf("Expected job to fail on expression error, got '%s'", recorded.Status)
+	}
+	if len(exec.order) != 0 {
+		t.Errorf("Expected executor not to be called, got %v", exec.order)
+	}
+}
+
+func TestRunJob_ResolvesContainer(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+
+	job := testJob()
+	job.Strategy = &models.Strategy{Matrix: models.Matrix{
+		Order:      []string{"node"},
+		Dimensions: map[string][]string{"node": {"20"}},
+	}}
+	job.Container = &models.Container{
+		Image: "node:${{ matrix.node }}",
+		User:  "node",
+		Env:   map[string]string{"NODE_ENV": "${{ env.MODE }}"},
+	}
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Env:      map[string]string{"MODE": "test"},
+		Jobs:     map[string]models.Job{"test": job},
+		JobOrder: []string{"test"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	name := "test (20)"
+	if recorded, _ := run.GetJob(name); recorded.Status != successStatus {
+		t.Fatalf("Expected job to succeed, got '%s': %s", recorded.Status, recorded.Output)
+	}
+
+	c := exec.jobs[name].Container
+	if c == nil || c.Image != "node:20" || c.User != "node" || c.Env["NODE_ENV"] != "test" {
+		t.Errorf("Expected resolved container, got %+v", c)
+	}
+}
+
+func TestRunJob_StartsServices(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+
+	job := testJob()
+	job.Services = map[string]models.Service{
+		"postgres": {Image: "postgres:${{ env.PG_VERSION }}"},
+	}
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Env:      map[string]string{"PG_VERSION": "16"},
+		Jobs:     map[string]models.Job{"test": job},
+		JobOrder: []string{"test"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	if image := exec.jobs["test"].Services["postgres"].Image; image != "postgres:16" {
+		t.Errorf("Expected interpolated service image 'postgres:16', got '%s'", image)
+	}
+
+	recorded, _ := run.GetJob("test")
+	if recorded.ServiceLogs["postgres"] != "postgres ready" {
+		t.Errorf("Expected service logs to be recorded, got %v", recorded.ServiceLogs)
+	}
+	if recorded.ServiceStderr["postgres"] != "postgres warning" {
+		t.Errorf("Expected service stderr to be recorded, got %v", recorded.ServiceStderr)
+	}
+	if recorded.Services["postgres"].Image != "postgres:${{ env.PG_VERSION }}" {
+		t.Errorf("Expected recorded service to keep its template, got %+v", recorded.Services)
+	}
+}
+
+func TestRunJob_RecordsTimeout(t *testing.T) {
+	exec := &fakeExecutor{timeout: map[string]bool{"slow": true}}
+	srv := newSchedulerTestServer(exec)
+
+	slow := testJob()
+	slow.Steps = []models.Step{
+		{Name: "Skipped", If: "false", Run: "echo skipped"},
+		{Name: "Sleep", Run: "sleep 600", TimeoutMinutes: 1},
+		{Name: "Never", Run: "echo never"},
+	}
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"slow": slow, "fast": testJob()},
+		JobOrder: []string{"slow", "fast"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	recorded, _ := run.GetJob("slow")
+	if recorded.Status != failedStatus || recorded.FailureReason != models.FailureTimeout {
+		t.Errorf("Expected job to fail with reason timeout, got '%s' (%s)", recorded.Status, recorded.FailureReason)
+	}
+
+	steps := recorded.Steps
+	if steps[0].Status != skippedStatus {
+		t.Errorf("Expected skipped step to stay skipped, got '%s'", steps[0].Status)
+	}
+	if steps[1].Status != failedStatus || steps[1].FailureReason != models.FailureTimeout {
+		t.Errorf("Expected timed out step, got '%s' (%s)", steps[1].Status, steps[1].FailureReason)
+	}
+	if steps[2].Status != "" {
+		t.Errorf("Expected unreached step to have no status, got '%s'", steps[2].Status)
+	}
+
+	if fast, _ := run.GetJob("fast"); fast.Status != skippedStatus {
+		t.Errorf("Expected next job to be skipped, got '%s'", fast.Status)
+	}
+}
+
+func TestRunJob_RecordsOutOfMemory(t *testing.T) {
+	exec := &fakeExecutor{oom: map[string]bool{"build": true}}
+	srv := newSchedulerTestServer(exec)
+
+	build := testJob()
+	build.Resources = &models.Resources{Memory: "64m"}
+	wf := &models.Workflow{Name: testWorkflowName, Jobs: map[string]models.Job{"build": build}}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	recorded, _ := run.GetJob("build")
+	if recorded.Status != failedStatus || recorded.FailureReason != models.FailureOutOfMemory {
+		t.Errorf("Expected job to fail with reason out_of_memory, got '%s' (%s)", recorded.Status, recorded.FailureReason)
+	}
+	if step := recorded.Steps[0]; step.FailureReason != models.FailureOutOfMemory {
+		t.Errorf("Expected step to fail with reason out_of_memory, got %s", step.FailureReason)
+	}
+}
+
+func TestRunJob_CheckoutWithoutRepository(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+
+	job := testJob()
+	job.Checkout = &models.Checkout{}
+	wf := &models.Workflow{Name: testWorkflowName, Jobs: map[string]models.Job{"build": job}}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	recorded, _ := run.GetJob("build")
+	if recorded.Status != failedStatus || !strings.Contains(recorded.Output, "no repository to check out") {
+		t.Errorf("Expected the job to fail without a repository, got '%s': %s", recorded.Status, recorded.Output)
+	}
+}
+
+func TestRunJob_ResolvesJobArtifacts(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+
+	test := matrixJob("1", "2")
+	test.Artifacts = []models.ArtifactStep{{Name: "reports-${{ matrix.n }}", Path: "build/reports"}}
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"test": test},
+		JobOrder: []string{"test"},
+	}
+
+	runWorkflowSync(t, srv, wf)
+
+	for _, n := range []string{"1", "2"} {
+		got := exec.jobs["test ("+n+")"].Artifacts
+		if len(got) != 1 || got[0].Name != "reports-"+n || got[0].Path != "build/reports" {
+			t.Errorf("Expected leg %s to collect reports-%s, got %+v", n, n, got)
+		}
+	}
+}
+
+func TestRunJob_ResolvesCacheVolumes(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+
+	test := matrixJob("1", "2")
+	test.CacheVolumes = []models.CacheVolume{{Key: "deps-${{ matrix.n }}", Path: "/deps"}}
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"test": test},
+		JobOrder: []string{"test"},
+	}
+
+	runWorkflowSync(t, srv, wf)
+
+	for _, n := range []string{"1", "2"} {
+		got := exec.jobs["test ("+n+")"].CacheVolumes
+		if len(got) != 1 || got[0].Key != "deps-"+n || got[0].Path != "/deps" {
+			t.Errorf("Expected leg %s to mount deps-%s, got %+v", n, n, got)
+		}
+	}
+}
+
+func TestRunJob_StepContinueOnError(t *testing.T) {
+	exec := &fakeExecutor{failSteps: map[string]bool{"Flaky": true}}
+	srv := newSchedulerTestServer(exec)
+
+	build := testJob()
+	build.Steps = []models.Step{
+		{Name: "Flaky", Run: "exit 1", ContinueOnError: true},
+		{Name: "After", Run: "echo after"},
+	}
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"build": build, "deploy": testJob()},
+		JobOrder: []string{"build", "deploy"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	if run.Status != successWithFailuresStatus {
+		t.Errorf("Expected run status '%s', got '%s'", successWithFailuresStatus, run.Status)
+	}
+
+	recorded, _ := run.GetJob("build")
+	if recorded.Status != successStatus {
+		t.Errorf("Expected job to succeed, got '%s'", recorded.Status)
+	}
+	if recorded.Steps[0].Status != failedStatus || recorded.Steps[1].Status != successStatus {
+		t.Errorf("Expected failed then successful step, got '%s', '%s'", recorded.Steps[0].Status, recorded.Steps[1].Status)
+	}
+
+	if deploy, _ := run.GetJob("deploy"); deploy.Status != successStatus {
+		t.Errorf("Expected next job to run, got '%s'", deploy.Status)
+	}
+}
+
+func TestRunJob_RecordsStepOutput(t *testing.T) {
+	exec := &fakeExecutor{failSteps: map[string]bool{"Lint": true, "Test": true}}
+	srv := newSchedulerTestServer(exec)
+
+	build := testJob()
+	build.Steps = []models.Step{
+		{Name: "Build", Run: "make"},
+		{Name: "Lint", Run: "make lint", ContinueOnError: true},
+		{Name: "Test", Run: "make test"},
+		{Name: "Report", Run: "echo report", If: "always()"},
+	}
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"build": build},
+		JobOrder: []string{"build"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	recorded, _ := run.GetJob("build")
+	if recorded.Status != failedStatus || recorded.FailedStep != "Test" {
+		t.Errorf("Expected job to fail at 'Test', got '%s' at '%s'", recorded.Status, recorded.FailedStep)
+	}
+	for _, step := range recorded.Steps {
+		if step.Output != step.Name+" output" {
+			t.Errorf("Expected step '%s' to record its own output, got %q", step.Name, step.Output)
+		}
+	}
+}
+
+func TestRunJob_RecordsFailedStepExitCode(t *testing.T) {
+	exec := &fakeExecutor{
+		failSteps: map[string]bool{"Lint": true, "Test": true},
+		exitCodes: map[string]int{"Lint": 2, "Test": 42},
+	}
+	srv := newSchedulerTestServer(exec)
+
+	build := testJob()
+	build.Steps = []models.Step{
+		{Name: "Build", Run: "make"},
+		{Name: "Lint", Run: "make lint", ContinueOnError: true},
+		{Name: "Test", Run: "make test"},
+	}
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"build": build},
+		JobOrder: []string{"build"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	recorded, _ := run.GetJob("build")
+	if recorded.FailedStep != "Test" || recorded.ExitCode != 42 {
+		t.Errorf("Expected job to fail at 'Test' with exit code 42, got '%s' with %d", recorded.FailedStep, recorded.ExitCode)
+	}
+	for i, want := range []int{0, 2, 42} {
+		if got := recorded.Steps[i].ExitCode; got != want {
+			t.Errorf("Expected step '%s' to exit with %d, got %d", recorded.Steps[i].Name, want, got)
+		}
+	}
+}
+
+func TestRunJobs_JobContinueOnError(t *testing.T) {
+	exec := &fakeExecutor{fail: map[string]bool{"lint": true}}
+	srv := newSchedulerTestServer(exec)
+
+	lint := testJob()
+	lint.ContinueOnError = true
+
+	wf := &models.Workflow{
+		Name: testWorkflowName,
+		Jobs: map[string]models.Job{
+			"lint":   lint,
+			"deploy": testJob("lint"),
+			"notify": {RunsOn: "ubuntu", Needs: []string{"lint"}, If: "failure()", Steps: lint.Steps},
+		},
+		JobOrder: []string{"lint", "deploy", "notify"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	if run.Status != successWithFailuresStatus {
+		t.Errorf("Expected run status '%s', got '%s'", successWithFailuresStatus, run.Status)
+	}
+
+	expected := map[string]string{
+		"lint":   failedStatus,
+		"deploy": successStatus,
+		"notify": skippedStatus,
+	}
+	for name, status := range expected {
+		if job, _ := run.GetJob(name); job.Status != status {
+			t.Errorf("Expected job '%s' status '%s', got '%s'", name, status, job.Status)
+		}
+	}
+}
+
+func TestRunJob_RecordsRetryAttempts(t *testing.T) {
+	exec := &fakeExecutor{flaky: map[string]int{"Install": 2}, output: "token s3cret"}
+	srv := newSchedulerTestServer(exec)
+	store := secrets.NewMemoryStore()
+	store.Set("TOKEN", "s3cret")
+	srv.secrets = store
+
+	build := testJob()
+	build.Steps = []models.Step{{Name: "Install", Run: "apk add curl", Retry: &models.RetryPolicy{Attempts: 3}}}
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"build": build},
+		JobOrder: []string{"build"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	if run.Status != successStatus {
+		t.Errorf("Expected run status '%s', got '%s'", successStatus, run.Status)
+	}
+
+	recorded, _ := run.GetJob("build")
+	attempts := recorded.Steps[0].Attempts
+	if len(attempts) != 3 || attempts[0].Success || attempts[1].Success || !attempts[2].Success {
+		t.Fatalf("Expected two failed attempts then a success, got %+v", attempts)
+	}
+	if attempts[0].Output != "token ***" {
+		t.Errorf("Expected attempt output to be masked, got '%s'", attempts[0].Output)
+	}
+}
+
+func TestRunJobs_PassesOutputsToDependents(t *testing.T) {
+	exec := &fakeExecutor{outputs: map[string]map[string]string{
+		"Version": {"version": "1.2.3"},
+		"Token":   {"token": "s3cret"},
+	}}
+	srv := newSchedulerTestServer(exec)
+	store := secrets.NewMemoryStore()
+	store.Set("TOKEN", "s3cret")
+	srv.secrets = store
+
+	build := testJob()
+	build.Steps = []models.Step{
+		{Name: "Version", Run: "echo version=1.2.3 >> $GANTRY_OUTPUT"},
+		{Name: "Token", Run: "echo token=$TOKEN >> $GANTRY_OUTPUT"},
+	}
+	deploy := testJob("build")
+	deploy.Steps = []models.Step{{Name: "Deploy", Run: "deploy ${{ needs.build.outputs.version }}"}}
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"build": build, "deploy": deploy},
+		JobOrder: []string{"build", "deploy"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	recorded, _ := run.GetJob("build")
+	if recorded.Outputs["version"] != "1.2.3" || recorded.Outputs["token"] != "***" {
+		t.Errorf("Expected recorded outputs with secrets masked, got %v", recorded.Outputs)
+	}
+
+	if run := exec.jobs["deploy"].Steps[0].Run; run != "deploy 1.2.3" {
+		t.Errorf("Expected output to be interpolated downstream, got '%s'", run)
+	}
+}
+
+func TestRunJob_ResolvesWorkingDirectory(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+
+	build := testJob()
+	build.Env = map[string]string{"SERVICE": "api"}
+	build.WorkingDirectory = "services/${{ env.SERVICE }}"
+	build.Steps = []models.Step{{Name: "Test", Run: "go test ./...", WorkingDirectory: "${{ env.SERVICE }}/cmd"}}
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"build": build},
+		JobOrder: []string{"build"},
+	}
+
+	runWorkflowSync(t, srv, wf)
+
+	executed := exec.jobs["build"]
+	if executed.WorkingDirectory != "services/api" {
+		t.Errorf("Expected job working-directory 'services/api', got '%s'", executed.WorkingDirectory)
+	}
+	if dir := executed.Steps[0].WorkingDirectory; dir != "api/cmd" {
+		t.Errorf("Expected step working-directory 'api/cmd', got '%s'", dir)
+	}
+}
+
+func TestRunJob_ResolvesActionInputs(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+
+	build := testJob()
+	build.Env = map[string]string{"GO_VERSION": "1.22"}
+	build.Steps = []models.Step{{
+		Name:   "Setup Go: Install",
+		Run:    "install-go ${{ inputs.version }}",
+		Inputs: map[string]string{"version": "${{ env.GO_VERSION }}"},
+	}}
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"build": build},
+		JobOrder: []string{"build"},
+	}
+
+	runWorkflowSync(t, srv, wf)
+
+	if got := exec.jobs["build"].Steps[0].Run; got != "install-go 1.22" {
+		t.Errorf("Expected action inputs to be resolved, got '%s'", got)
+	}
+}
+
+func matrixJob(values ...string) models.Job {
+	job := testJob()
+	job.Strategy = &models.Strategy{Matrix: models.Matrix{
+		Order:      []string{"n"},
+		Dimensions: map[string][]string{"n": values},
+	}}
+	return job
+}
+
+func TestRunJobs_MatrixFailFast(t *testing.T) {
+	exec := &fakeExecutor{fail: map[string]bool{"test (1)": true}}
+	srv := newSchedulerTestServer(exec)
+
+	test := matrixJob("1", "2", "3")
+	test.Strategy.MaxParallel = 1
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"test": test},
+		JobOrder: []string{"test"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	if len(exec.order) != 1 {
+		t.Errorf("Expected only the failing leg to run, got %v", exec.order)
+	}
+	for _, leg := range []string{"test (2)", "test (3)"} {
+		job, _ := run.GetJob(leg)
+		if job.Status != skippedStatus || job.FailureReason != models.FailureCancelled {
+			t.Errorf("Expected leg %s to be cancelled, got '%s' (%s)", leg, job.Status, job.FailureReason)
+		}
+	}
+}
+
+func TestRunJobs_MatrixFailFastCancelsRunningLegs(t *testing.T) {
+	exec := &fakeExecutor{fail: map[string]bool{"test (1)": true}, hang: map[string]bool{"test (2)": true}}
+	srv := newSchedulerTestServer(exec)
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"test": matrixJob("1", "2")},
+		JobOrder: []string{"test"},
+	}
+
+	start := time.Now()
+	run := runWorkflowSync(t, srv, wf)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the running leg to be cancelled, run took %v", elapsed)
+	}
+
+	job, _ := run.GetJob("test (2)")
+	if job.Status != failedStatus || job.FailureReason != models.FailureCancelled {
+		t.Errorf("Expected running leg to be cancelled, got '%s' (%s)", job.Status, job.FailureReason)
+	}
+}
+
+func TestRunJobs_MatrixWithoutFailFast(t *testing.T) {
+	exec := &fakeExecutor{fail: map[string]bool{"test (1)": true}}
+	srv := newSchedulerTestServer(exec)
+
+	failFast := false
+	test := matrixJob("1", "2", "3")
+	test.Strategy.FailFast = &failFast
+	test.Strategy.MaxParallel = 1
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"test": test},
+		JobOrder: []string{"test"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	if len(exec.order) != 3 {
+		t.Errorf("Expected every leg to run, got %v", exec.order)
+	}
+	if job, _ := run.GetJob("test (3)"); job.Status != successStatus {
+		t.Errorf("Expected sibling leg to succeed, got '%s'", job.Status)
+	}
+	if run.Status != failedStatus {
+		t.Errorf("Expected run status '%s', got '%s'", failedStatus, run.Status)
+	}
+}
+
+func TestRunJobs_MatrixMaxParallel(t *testing.T) {
+	exec := &fakeExecutor{delay: 20 * time.Millisecond}
+	srv := newSchedulerTestServer(exec)
+
+	test := matrixJob("1", "2", "3", "4", "5")
+	test.Strategy.MaxParallel = 2
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"test": test},
+		JobOrder: []string{"test"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	if run.Status != successStatus {
+		t.Errorf("Expected run status '%s', got '%s'", successStatus, run.Status)
+	}
+	if len(exec.order) != 5 {
+		t.Errorf("Expected every leg to run, got %v", exec.order)
+	}
+	if exec.peak != 2 {
+		t.Errorf("Expected at most 2 legs at once, peak was %d", exec.peak)
+	}
+}
+
+func TestRunJob_ResolvesArtifactSteps(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+
+	build := testJob()
+	build.Env = map[string]string{"TARGET": "linux"}
+	build.Steps = []models.Step{
+		{Name: "Upload", UploadArtifact: &models.ArtifactStep{Name: "bin-${{ env.TARGET }}", Path: "out/${{ env.TARGET }}"}},
+	}
+	deploy := testJob("build")
+	deploy.Steps = []models.Step{
+		{Name: "Download", DownloadArtifact: &models.ArtifactStep{Name: "bin-linux"}},
+	}
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"build": build, "deploy": deploy},
+		JobOrder: []string{"build", "deploy"},
+	}
+
+	runWorkflowSync(t, srv, wf)
+
+	upload := exec.jobs["build"].Steps[0].UploadArtifact
+	if upload.Name != "bin-linux" || upload.Path != "out/linux" {
+		t.Errorf("Expected upload of 'bin-linux' from 'out/linux', got %+v", upload)
+	}
+	if download := exec.jobs["deploy"].Steps[0].DownloadArtifact; download.Name != "bin-linux" {
+		t.Errorf("Expected download of 'bin-linux', got %+v", download)
+	}
+}
+
+func TestRunJob_ResolvesCacheSteps(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+
+	build := testJob()
+	build.Env = map[string]string{"GO": "1.22"}
+	build.Steps = []models.Step{{
+		Name: "Cache modules",
+		Cache: &models.CacheStep{
+			Path:        "/go/pkg/mod",
+			Key:         "go-${{ env.GO }}-${{ gantry.workflow }}",
+			RestoreKeys: models.StringList{"go-${{ env.GO }}-"},
+		},
+	}}
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"build": build},
+		JobOrder: []string{"build"},
+	}
+
+	runWorkflowSync(t, srv, wf)
+
+	c := exec.jobs["build"].Steps[0].Cache
+	if want := "go-1.22-" + testWorkflowName; c.Key != want {
+		t.Errorf("Expected key '%s', got '%s'", want, c.Key)
+	}
+	if len(c.RestoreKeys) != 1 || c.RestoreKeys[0] != "go-1.22-" {
+		t.Errorf("Expected restore key 'go-1.22-', got %v", c.RestoreKeys)
+	}
+}
+
+func TestRunJob_ResolvesBuildSteps(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+	store := secrets.NewMemoryStore()
+	store.Set("REGISTRY_PASSWORD", "s3cret")
+	srv.secrets = store
+
+	build := testJob()
+	build.Env = map[string]string{"VERSION": "1.2.3"}
+	build.Steps = []models.Step{{
+		Name: "Image",
+		Build: &models.BuildStep{
+			Context:   "app",
+			Tags:      models.StringList{"registry.example.com/app:${{ env.VERSION }}"},
+			BuildArgs: map[string]string{"VERSION": "${{ env.VERSION }}"},
+			Push:      true,
+			Username:  "ci",
+			Password:  "${{ secrets.REGISTRY_PASSWORD }}",
+		},
+	}}
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"build": build},
+		JobOrder: []string{"build"},
+	}
+
+	runWorkflowSync(t, srv, wf)
+
+	b := exec.jobs["build"].Steps[0].Build
+	if len(b.Tags) != 1 || b.Tags[0] != "registry.example.com/app:1.2.3" {
+		t.Errorf("Expected tag 'registry.example.com/app:1.2.3', got %v", b.Tags)
+	}
+	if b.BuildArgs["VERSION"] != "1.2.3" || b.Password != "s3cret" || !b.Push {
+		t.Errorf("Expected build args and password to be resolved, got %+v", b)
+	}
+}
+
+func TestRunJob_ResolvesRegistryCredentials(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+	store := secrets.NewMemoryStore()
+	store.Set("REGISTRY_PASSWORD", "s3cret")
+	srv.secrets = store
+
+	credentials := &models.RegistryCredentials{Username: "acme", Password: "${{ secrets.REGISTRY_PASSWORD }}"}
+	build := testJob()
+	build.Container = &models.Container{Image: "ghcr.io/acme/builder:1", Credentials: credentials}
+	build.Services = map[string]models.Service{"db": {Image: "ghcr.io/acme/db:1", Credentials: credentials}}
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"build": build},
+		JobOrder: []string{"build"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	job := exec.jobs["build"]
+	want := models.RegistryCredentials{Username: "acme", Password: "s3cret"}
+	if c := job.Container.Credentials; c == nil || *c != want {
+		t.Errorf("Expected container credentials %+v, got %+v", want, c)
+	}
+	if c := job.Services["db"].Credentials; c == nil || *c != want {
+		t.Errorf("Expected service credentials %+v, got %+v", want, c)
+	}
+	if recorded, _ := run.GetJob("build"); recorded.Container.Credentials.Password != credentials.Password {
+		t.Errorf("Expected the run to keep the password template, got '%s'", recorded.Container.Credentials.Password)
+	}
+}
+
+func TestRunJob_PassesPullPolicy(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+	srv.pullPolicy = models.PullIfNotPresent
+
+	build := testJob()
+	deploy := testJob()
+	deploy.PullPolicy = models.PullAlways
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"build": build, "deploy": deploy},
+		JobOrder: []string{"build", "deploy"},
+	}
+
+	runWorkflowSync(t, srv, wf)
+
+	if policy := exec.policies["build"]; policy != models.PullIfNotPresent {
+		t.Errorf("Expected build to use the server's pull policy, got '%s'", policy)
+	}
+	if policy := exec.policies["deploy"]; policy != models.PullAlways {
+		t.Errorf("Expected deploy to override the pull policy, got '%s'", policy)
+	}
+}
+
+func TestRunJob_PassesMaxRuntime(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+	srv.maxJobRuntime = 2 * time.Hour
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"build": testJob()},
+		JobOrder: []string{"build"},
+	}
+
+	runWorkflowSync(t, srv, wf)
+
+	if runtime := exec.runtimes["build"]; runtime != 2*time.Hour {
+		t.Errorf("Expected the server's maximum runtime, got %v", runtime)
+	}
+}
+
+func TestRunJob_FailsBuildWithInvalidTag(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+
+	build := testJob()
+	build.Env = map[string]string{"NAME": "My App"}
+	build.Steps = []models.Step{{
+		Name:  "Image",
+		Build: &models.BuildStep{Tags: models.StringList{"${{ env.NAME }}:1"}},
+	}}
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"build": build},
+		JobOrder: []string{"build"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	if run.Status != failedStatus {
+		t.Errorf("Expected run to fail on a tag that isn't an image reference, got '%s'", run.Status)
+	}
+}
+
+func TestRunJob_RunsCleanupStepsAfterFailure(t *testing.T) {
+	exec := &fakeExecutor{failSteps: map[string]bool{"Build": true}}
+	srv := newSchedulerTestServer(exec)
+
+	build := testJob()
+	build.Steps = []models.Step{
+		{Name: "Build", Run: "make"},
+		{Name: "Deploy", Run: "make deploy"},
+		{Name: "Report", Run: "upload-report", If: "always()"},
+		{Name: "Notify", Run: "notify", If: "${{ failure() }}"},
+	}
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"build": build},
+		JobOrder: []string{"build"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	job, _ := run.GetJob("build")
+	if job.Status != failedStatus {
+		t.Errorf("Expected build to fail, got '%s'", job.Status)
+	}
+	want := map[string]string{"Build": failedStatus, "Deploy": skippedStatus, "Report": successStatus, "Notify": successStatus}
+	for _, step := range job.Steps {
+		if step.Status != want[step.Name] {
+			t.Errorf("Expected step '%s' to be '%s', got '%s'", step.Name, want[step.Name], step.Status)
+		}
+	}
+
+	if got := len(exec.jobs["build"].Steps); got != 4 {
+		t.Errorf("Expected every possibly-running step to reach the executor, got %d", got)
+	}
+	if executed := exec.jobs["build"].Steps; executed[2].When != models.StepWhenAlways || executed[3].When != models.StepWhenFailure {
+		t.Errorf("Expected When 'always' and 'failure', got '%s' and '%s'", executed[2].When, executed[3].When)
+	}
+}
+
+func TestRunJob_StepConditionsIgnoreFailedNeeds(t *testing.T) {
+	exec := &fakeExecutor{fail: map[string]bool{"build": true}}
+	srv := newSchedulerTestServer(exec)
+
+	report := testJob("build")
+	report.If = "always()"
+	report.Steps = []models.Step{
+		{Name: "Report", Run: "report"},
+		{Name: "Rollback", Run: "rollback", If: "failure()"},
+	}
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"build": testJob(), "report": report},
+		JobOrder: []string{"build", "report"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	job, _ := run.GetJob("report")
+	if job.Status != successStatus {
+		t.Fatalf("Expected report to run and succeed, got '%s'", job.Status)
+	}
+	if job.Steps[0].Status != successStatus || job.Steps[1].Status != skippedStatus {
+		t.Errorf("Expected step conditions to follow the job's own steps, got '%s' and '%s'",
+			job.Steps[0].Status, job.Steps[1].Status)
+	}
+}
+
+func TestRunJob_ResolvesStepReferences(t *testing.T) {
+	exec := &fakeExecutor{
+		failSteps: map[string]bool{"Lint": true},
+		outputs:   map[string]map[string]string{"Version": {"version": "1.2.3"}},
+	}
+	srv := newSchedulerTestServer(exec)
+
+	build := testJob()
+	build.Steps = []models.Step{
+		{ID: "ver", Name: "Version", Run: "version"},
+		{ID: "lint", Name: "Lint", Run: "lint", ContinueOnError: true},
+		{ID: "skip", Name: "Never", Run: "never", If: "false"},
+		{Name: "Tag ${{ steps.ver.outputs.version }}", Run: "tag ${{ steps.ver.outputs.version }}"},
+		{Name: "Fix", Run: "fix", If: "steps.lint.outcome == 'failed' && steps.lint.conclusion == 'success'"},
+		{Name: "Missing", Run: "missing", If: "steps.skip.outcome != 'skipped'"},
+	}
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"build": build},
+		JobOrder: []string{"build"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	job, _ := run.GetJob("build")
+	if job.Status != successStatus {
+		t.Fatalf("Expected build to succeed, got '%s'", job.Status)
+	}
+
+	executed := exec.jobs["build"].Steps
+	if len(executed) != 5 {
+		t.Fatalf("Expected 5 steps to reach the executor, got %d", len(executed))
+	}
+	if executed[2].Run != "tag 1.2.3" || executed[2].Name != "Tag 1.2.3" {
+		t.Errorf("Expected the tag step to see the version output, got %q (%q)", executed[2].Run, executed[2].Name)
+	}
+	want := map[string]string{"Fix": successStatus, "Missing": skippedStatus, "Never": skippedStatus}
+	for _, step := range job.Steps {
+		if w, ok := want[step.Name]; ok && step.Status != w {
+			t.Errorf("Expected step '%s' to be '%s', got '%s'", step.Name, w, step.Status)
+		}
+	}
+
+	states := job.StepStates
+	if states["ver"].Outcome != successStatus || states["ver"].Outputs["version"] != "1.2.3" {
+		t.Errorf("Expected the version step's state to be recorded, got %+v", states["ver"])
+	}
+	if states["lint"].Outcome != failedStatus || states["lint"].Conclusion != successStatus {
+		t.Errorf("Expected lint to fail but conclude as success, got %+v", states["lint"])
+	}
+	if states["skip"].Outcome != skippedStatus {
+		t.Errorf("Expected the skipped step's state to be recorded, got %+v", states["skip"])
+	}
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"gantry/internal/expr"
+	"gantry/internal/models"
+	"gantry/internal/secrets"
+)
+
+// Errors of managing secrets
+var (
+	ErrSecretNotFound  = errors.New("secret not found")
+	ErrInvalidSecret   = errors.New("invalid secret")
+	ErrSecretsReadOnly = errors.New("secret store is read-only")
+)
+
+// secretNamePattern is what secret names may look like, as environment
+// variable names
+var secretNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// secretReferences returns the sorted, de-duplicated names of the secrets a
+// set of jobs (and the workflow env) reference
+func secretReferences(workflowEnv map[string]string, jobs ...models.Job) ([]string, error) {
+	var templates, conditions []string
+
+	for _, v := range workflowEnv {
+		templates = append(templates, v)
+	}
+	for _, job := range jobs {
+		templates = append(templates, job.Templates()...)
+		conditions = append(conditions, job.Conditions()...)
+	}
+
+	seen := make(map[string]bool)
+	for _, t := range templates {
+		refs, err := expr.TemplateReferences(t, "secrets")
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range refs {
+			seen[ref] = true
+		}
+	}
+	for _, c := range conditions {
+		if c == "" {
+			continue
+		}
+		e, err := expr.ParseCondition(c)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range e.References("secrets") {
+			seen[ref] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// checkSecrets returns an error naming the first referenced secret that is
+// not defined in the secret store
+func (s *Server) checkSecrets(workflowEnv map[string]string, jobs ...models.Job) error {
+	names, err := secretReferences(workflowEnv, jobs...)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if s.secrets == nil {
+			return fmt.Errorf("secret '%s' is not defined", name)
+		}
+		if _, exists := s.secrets.Get(name); !exists {
+			return fmt.Errorf("secret '%s' is not defined", name)
+		}
+	}
+	return nil
+}
+
+// secretsContext exposes the secret store to expressions
+func (s *Server) secretsContext() map[string]interface{} {
+	values := make(map[string]interface{})
+	if s.secrets == nil {
+		return values
+	}
+	for _, name := range s.secrets.Names() {
+		value, _ := s.secrets.Get(name)
+		values[name] = value
+	}
+	return values
+}
+
+// SecretNames returns the sorted names of the secrets, never their values
+func (s *Server) SecretNames() []string {
+	if s.secrets == nil {
+		return []string{}
+	}
+	return s.secrets.Names()
+}
+
+// SetSecret stores a secret, replacing one with the same name. Secrets set
+// this way last until the server restarts; ones that should outlive it
+// belong in SECRETS_FILE or GANTRY_SECRET_* variables.
+func (s *Server) SetSecret(name, value string) error {
+	store, ok := s.secrets.(secrets.MutableStore)
+	if !ok {
+		return ErrSecretsReadOnly
+	}
+	if !secretNamePattern.MatchString(name) {
+		return fmt.Errorf("%w: names are letters, digits and '_', not starting with a digit", ErrInvalidSecret)
+	}
+	if value == "" {
+		return fmt.Errorf("%w: value must not be empty", ErrInvalidSecret)
+	}
+	store.Set(name, value)
+	return nil
+}
+
+// DeleteSecret removes a secret
+func (s *Server) DeleteSecret(name string) error {
+	store, ok := s.secrets.(secrets.MutableStore)
+	if !ok {
+		return ErrSecretsReadOnly
+	}
+	if _, exists := store.Get(name); !exists {
+		return fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	store.Delete(name)
+	return nil
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"gantry/internal/models"
+	"gantry/internal/parser"
+	"gantry/internal/secrets"
+	"gantry/internal/storage"
+)
+
+func TestServer_ParseAndSaveWorkflow_UnknownSecret(t *testing.T) {
+	store := secrets.NewMemoryStore()
+	store.Set("DEPLOY_TOKEN", "s3cr3t")
+
+	srv := &Server{
+		storage: storage.NewMemoryStorage(),
+		parser:  parser.NewParser(),
+		secrets: store,
+	}
+
+	yaml := []byte(`
+name: Deploy
+jobs:
+  deploy:
+    runs-on: ubuntu
+    env:
+      TOKEN: ${{ secrets.DEPLOY_TOKEN }}
+    steps:
+      - name: Push
+        if: secrets['REGISTRY_PASSWORD'] != ''
+        run: echo pushing
+`)
+
+	_, err := srv.ParseAndSaveWorkflow(yaml, "")
+	if err == nil {
+		t.Fatal("Expected error for unknown secret")
+	}
+	if !strings.Contains(err.Error(), "REGISTRY_PASSWORD") {
+		t.Errorf("Expected error to name the missing secret, got: %v", err)
+	}
+
+	store.Set("REGISTRY_PASSWORD", "hunter2")
+	if _, err := srv.ParseAndSaveWorkflow(yaml, ""); err != nil {
+		t.Errorf("Expected workflow to be accepted once secrets exist, got: %v", err)
+	}
+}
+
+func TestRunJob_ResolvesAndMasksSecrets(t *testing.T) {
+	exec := &fakeExecutor{output: "logging in with s3cr3t\ndone"}
+	srv := newSchedulerTestServer(exec)
+	store := secrets.NewMemoryStore()
+	store.Set("DEPLOY_TOKEN", "s3cr3t")
+	srv.secrets = store
+
+	job := testJob()
+	job.Steps = []models.Step{{
+		Name: "Login",
+		Env:  map[string]string{"TOKEN": "${{ secrets.DEPLOY_TOKEN }}"},
+		Run:  "login --token ${{ secrets.DEPLOY_TOKEN }}",
+	}}
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"deploy": job},
+		JobOrder: []string{"deploy"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	step := exec.jobs["deploy"].Steps[0]
+	if step.Run != "login --token s3cr3t" || step.Env["TOKEN"] != "s3cr3t" {
+		t.Errorf("Expected secret to reach the executor, got run %q env %v", step.Run, step.Env)
+	}
+
+	recorded, _ := run.GetJob("deploy")
+	if strings.Contains(recorded.Output, "s3cr3t") {
+		t.Errorf("Expected secret to be masked in output, got %q", recorded.Output)
+	}
+	if recorded.Output != "logging in with ***\ndone" {
+		t.Errorf("Unexpected masked output %q", recorded.Output)
+	}
+	if recorded.Steps[0].Run != job.Steps[0].Run {
+		t.Errorf("Expected recorded step to keep its template, got %q", recorded.Steps[0].Run)
+	}
+}
+
+func TestRunJob_FailsOnRemovedSecret(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+	srv.secrets = secrets.NewMemoryStore()
+
+	job := testJob()
+	job.Steps[0].Run = "echo ${{ secrets.GONE }}"
+
+	wf := &models.Workflow{
+		Name:     testWorkflowName,
+		Jobs:     map[string]models.Job{"build": job},
+		JobOrder: []string{"build"},
+	}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	recorded, _ := run.GetJob("build")
+	if recorded.Status != failedStatus || !strings.Contains(recorded.Output, "GONE") {
+		t.Errorf("Expected job to fail naming the secret, got '%s': %s", recorded.Status, recorded.Output)
+	}
+	if len(exec.order) != 0 {
+		t.Errorf("Expected executor not to run, got %v", exec.order)
+	}
+}
@@ -2,16 +2,31 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/netip"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"gantry/internal/artifacts"
+	"gantry/internal/auth"
+	"gantry/internal/cache"
+	"gantry/internal/environments"
 	"gantry/internal/executor"
+	"gantry/internal/glob"
+	"gantry/internal/importer"
 	"gantry/internal/models"
 	"gantry/internal/parser"
+	"gantry/internal/runners"
+	"gantry/internal/secrets"
 	"gantry/internal/storage"
 
+	"github.com/docker/go-units"
 	"github.com/joho/godotenv"
 )
 
@@ -19,20 +34,166 @@ const (
 	successStatus = "success"
 	failedStatus  = "failed"
 	runningStatus = "running"
+	skippedStatus = "skipped"
+
+	// successWithFailuresStatus marks a run that succeeded only because its
+	// failed steps or jobs had continue-on-error set
+	successWithFailuresStatus = "success_with_failures"
 )
 
 // Config holds server configuration
 type Config struct {
-	StorageType string // "memory" or "mongodb"
-	MongoURI    string
-	MongoDB     string
+	StorageType  string // "memory" or "mongodb"
+	MongoURI     string
+	MongoDB      string
+	SecretsFile  string // optional dotenv file merged with GANTRY_SECRET_* env vars
+	ActionsRoot  string // directory local `uses: ./...` actions are loaded from
+	ArtifactsDir string // directory uploaded artifacts are stored in
+	CacheDir     string // directory cache step entries are stored in
+
+	EnvironmentsFile string // optional YAML file defining deployment environments
+	RunnersFile      string // optional YAML file mapping runs-on labels to images
+
+	// VolumePaths are the host paths, and the paths below them, that jobs
+	// may mount with volumes
+	VolumePaths []string
+	// AllowPrivileged lets jobs set privileged: true
+	AllowPrivileged bool
+	// ImagePolicy restricts the images jobs and services may run in
+	ImagePolicy models.ImagePolicy
+	// JobUser is the user job containers run as unless a job sets one
+	JobUser string
+	// ForbidRoot refuses jobs that would run as root
+	ForbidRoot bool
+	// VMRuntime runs jobs with isolation vm: a Docker or Podman runtime such
+	// as Kata Containers with Firecracker, or on Kubernetes a RuntimeClass
+	VMRuntime string
+
+	// RegistryAuthFile is an optional Docker config.json whose credentials
+	// pull the images of jobs and services from private registries
+	RegistryAuthFile string
+
+	// PullPolicy is the image pull policy of jobs that don't set one; empty
+	// pulls only images tagged latest
+	PullPolicy string
+	// PrepullImages pulls the images of uploaded workflows in the background
+	PrepullImages bool
+
+	// MaxConcurrentJobs bounds how many jobs run at once across all runs;
+	// zero doesn't limit them
+	MaxConcurrentJobs int
+	// MaxJobRuntime caps how long any job may run, whatever its
+	// timeout-minutes; zero leaves jobs to their own timeouts
+	MaxJobRuntime time.Duration
+	// MaxJobOutput is how many bytes of each job's output, and each step's,
+	// are kept; the middle of longer output is left out. Zero keeps it all.
+	MaxJobOutput int64
+	// OrphanCleanupInterval is how often the resources of runs that ended
+	// without cleaning up are removed, besides on startup; zero only
+	// removes them on startup
+	OrphanCleanupInterval time.Duration
+	// CacheVolumeBudget is the total size in bytes the executor keeps cache
+	// volumes within, removing the least recently used; zero keeps them all
+	CacheVolumeBudget int64
+	// Hardening restricts every job container that isn't privileged
+	Hardening executor.Hardening
+	// WarmPoolSize is how many idle containers the executor keeps started
+	// for each container configuration jobs run with; zero keeps none
+	WarmPoolSize int
+	// WarmPoolTTL is how long a warm container may stay idle
+	WarmPoolTTL time.Duration
+
+	ExecutorType string // "docker", "podman", "kubernetes", "shell" or "wasm"
+	PodmanSocket string // Podman API socket; discovered when empty
+	Kubernetes   executor.KubernetesConfig
+
+	// DockerHosts spreads runs across these Docker daemons instead of the
+	// local one, picking a run's host by DockerStrategy
+	DockerHosts    []executor.DockerHost
+	DockerStrategy string // "least-loaded" (default) or "round-robin"
+
+	WasmModulesDir string // directory the wasm executor looks up modules named without a path in
+
+	// JWTSecret signs the tokens of signed-in users; when set, every API
+	// request but signing in must carry one. Empty leaves the API open.
+	JWTSecret       string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// AllowRegistration lets anyone register; otherwise only the first user may
+	AllowRegistration bool
+
+	// RateLimit is how many requests a minute each client may make to the
+	// endpoints that upload workflows or start runs, in bursts of up to
+	// RateLimitBurst (RateLimit when zero); zero doesn't limit them
+	RateLimit      int
+	RateLimitBurst int
+	// TrustProxyHeaders tells clients apart by the X-Real-IP or
+	// X-Forwarded-For header of a reverse proxy in front of the server
+	TrustProxyHeaders bool
+
+	// GitHubWebhookSecret verifies the signatures of GitHub webhook
+	// deliveries, and GitLabWebhookSecret is the secret token of GitLab
+	// ones; empty refuses them
+	GitHubWebhookSecret string
+	GitLabWebhookSecret string
+
+	// HookNetworks are the networks of loopback, private and link-local
+	// addresses outgoing webhooks may be delivered to; others are refused
+	HookNetworks []netip.Prefix
 }
 
 // Server coordinates all components
 type Server struct {
-	storage  storage.Storage
-	executor executor.Executor
-	parser   *parser.Parser
+	storage   storage.Storage
+	executor  executor.Executor
+	parser    *parser.Parser
+	secrets   secrets.Store
+	artifacts artifacts.Store
+	cache     cache.Store
+
+	// workflowMu serializes saving workflows, so that each save is given
+	// its own version
+	workflowMu sync.Mutex
+
+	environments environments.Store
+	approvals    approvalGate
+	active       activeRuns
+	logs         liveLogs
+	runEvents    runEvents
+
+	runners     runners.Store
+	volumePaths []string
+
+	allowPrivileged bool
+	imagePolicy     models.ImagePolicy
+	vmRuntime       string
+	jobUser         string
+	forbidRoot      bool
+
+	registryAuths map[string]models.RegistryCredentials // by registry host
+	pullPolicy    string
+	pulls         *imagePulls // nil unless images are pre-pulled
+
+	pool          *jobPool // nil when jobs aren't limited
+	maxJobRuntime time.Duration
+	maxJobOutput  int64
+
+	cacheVolumeBudget int64
+	hardening         executor.Hardening
+
+	sessions sessions
+
+	limiter           *rateLimiter // nil when requests aren't limited
+	trustProxyHeaders bool
+
+	githubWebhookSecret string
+	gitlabWebhookSecret string
+	hooks               hookSender
+
+	// storageType and executorType name the backends, as reported with the
+	// server's version
+	storageType  string
+	executorType string
 }
 
 // NewServer creates a new server instance
@@ -43,7 +204,9 @@ func NewServer(cfg *Config) (*Server, error) {
 
 	log.Println(cfg.StorageType)
 
+	storageType := "memory"
 	if cfg.StorageType == "mongodb" {
+		storageType = cfg.StorageType
 		log.Printf("Initializing MongoDB storage: %s/%s", cfg.MongoURI, cfg.MongoDB)
 		store, err = storage.NewMongoStorage(cfg.MongoURI, cfg.MongoDB)
 		if err != nil {
@@ -55,30 +218,301 @@ func NewServer(cfg *Config) (*Server, error) {
 		store = storage.NewMemoryStorage()
 	}
 
+	if err := models.ValidatePullPolicy(cfg.PullPolicy); err != nil {
+		return nil, err
+	}
+
 	// Initialize executor
-	exec, err := executor.NewDockerExecutor()
+	exec, err := newExecutor(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create executor: %w", err)
 	}
 
+	if cfg.WarmPoolSize > 0 {
+		if pooler, ok := exec.(executor.WarmPooler); ok {
+			log.Printf("Keeping %d warm containers per job configuration for %s", cfg.WarmPoolSize, cfg.WarmPoolTTL)
+			pooler.EnableWarmPool(cfg.WarmPoolSize, cfg.WarmPoolTTL)
+		} else {
+			log.Printf("WARNING: the %s executor can't keep warm containers", cfg.ExecutorType)
+		}
+	}
+
+	// Load runners
+	runnerStore, err := runners.NewStoreFromEnv(cfg.RunnersFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load runners: %w", err)
+	}
+	for _, label := range runnerStore.Labels() {
+		if r, _ := runnerStore.Get(label); !r.Pinned() {
+			log.Printf("Runner '%s' image %s is not pinned with a digest", label, r.Image)
+		}
+	}
+	log.Printf("Loaded %d runners", len(runnerStore.Labels()))
+
 	// Initialize parser
-	p := parser.NewParser()
+	p := parser.NewParser(
+		parser.WithActionsRoot(cfg.ActionsRoot),
+		parser.WithBaseWorkflows(storedSource(store)),
+		parser.WithRunners(runnerStore),
+	)
+
+	// Load secrets
+	secretStore, err := secrets.NewStoreFromEnv(cfg.SecretsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load secrets: %w", err)
+	}
+	log.Printf("Loaded %d secrets", len(secretStore.Names()))
+
+	// Initialize artifact storage
+	artifactStore, err := artifacts.NewFileStore(cfg.ArtifactsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create artifact store: %w", err)
+	}
+
+	// Initialize cache storage
+	cacheStore, err := cache.NewFileStore(cfg.CacheDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache store: %w", err)
+	}
+
+	// Load deployment environments
+	environmentStore, err := environments.NewStoreFromFile(cfg.EnvironmentsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load environments: %w", err)
+	}
+	log.Printf("Loaded %d environments", len(environmentStore.Names()))
+
+	// Load registry credentials
+	registryAuths, err := loadRegistryAuths(cfg.RegistryAuthFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load registry credentials: %w", err)
+	}
+	if len(registryAuths) > 0 {
+		log.Printf("Loaded credentials for %d registries", len(registryAuths))
+	}
+
+	var tokens *auth.Tokens
+	if cfg.JWTSecret != "" {
+		if tokens, err = auth.NewTokens(cfg.JWTSecret, cfg.AccessTokenTTL, cfg.RefreshTokenTTL); err != nil {
+			return nil, fmt.Errorf("JWT_SECRET: %w", err)
+		}
+		log.Println("Authentication enabled")
+	} else {
+		log.Println("WARNING: JWT_SECRET is not set; the API is open to anyone who can reach it")
+	}
+
+	if cfg.RateLimit > 0 {
+		log.Printf("Limiting uploads and triggers to %d requests a minute per client", cfg.RateLimit)
+	}
+
+	var pulls *imagePulls
+	if cfg.PrepullImages {
+		if _, ok := exec.(executor.ImagePuller); ok {
+			pulls = &imagePulls{pulls: make(map[string]*ImagePull)}
+		} else {
+			log.Printf("WARNING: the %s executor can't pre-pull images", cfg.ExecutorType)
+		}
+	}
+
+	executorType := cfg.ExecutorType
+	if executorType == "" {
+		executorType = "docker"
+	}
 
-	return &Server{
-		storage:  store,
-		executor: exec,
-		parser:   p,
-	}, nil
+	srv := &Server{
+		storage:      store,
+		executor:     exec,
+		parser:       p,
+		secrets:      secretStore,
+		artifacts:    artifactStore,
+		cache:        cacheStore,
+		environments: environmentStore,
+		runners:      runnerStore,
+		volumePaths:  cfg.VolumePaths,
+
+		allowPrivileged: cfg.AllowPrivileged,
+		imagePolicy:     cfg.ImagePolicy,
+		vmRuntime:       cfg.VMRuntime,
+		jobUser:         cfg.JobUser,
+		forbidRoot:      cfg.ForbidRoot,
+
+		registryAuths: registryAuths,
+		pullPolicy:    cfg.PullPolicy,
+		pulls:         pulls,
+
+		pool:          newJobPool(cfg.MaxConcurrentJobs),
+		maxJobRuntime: cfg.MaxJobRuntime,
+		maxJobOutput:  cfg.MaxJobOutput,
+
+		cacheVolumeBudget: cfg.CacheVolumeBudget,
+		hardening:         cfg.Hardening,
+
+		sessions: sessions{tokens: tokens, openRegistration: cfg.AllowRegistration},
+
+		limiter:           newRateLimiter(cfg.RateLimit, cfg.RateLimitBurst),
+		trustProxyHeaders: cfg.TrustProxyHeaders,
+
+		githubWebhookSecret: cfg.GitHubWebhookSecret,
+		gitlabWebhookSecret: cfg.GitLabWebhookSecret,
+		hooks:               hookSender{allowed: cfg.HookNetworks},
+
+		storageType:  storageType,
+		executorType: executorType,
+	}
+
+	// No run is active yet, so whatever runs left behind can go
+	if cleaner, ok := exec.(executor.OrphanCleaner); ok {
+		go srv.cleanupOrphans(cleaner, cfg.OrphanCleanupInterval)
+	}
+
+	return srv, nil
 }
 
 // NewServerFromEnv creates a server from environment variables
 func NewServerFromEnv() (*Server, error) {
 	_ = godotenv.Load() // Loads the .env file automatically
 
+	volumePaths, err := parseVolumePaths(getEnv("ALLOWED_VOLUME_PATHS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("ALLOWED_VOLUME_PATHS: %w", err)
+	}
+
+	maxJobs, err := strconv.Atoi(getEnv("MAX_CONCURRENT_JOBS", "0"))
+	if err != nil || maxJobs < 0 {
+		return nil, fmt.Errorf("MAX_CONCURRENT_JOBS must be a number of jobs, got '%s'", getEnv("MAX_CONCURRENT_JOBS", ""))
+	}
+
+	var maxRuntime time.Duration
+	if value := getEnv("MAX_JOB_RUNTIME", ""); value != "" {
+		if maxRuntime, err = time.ParseDuration(value); err != nil || maxRuntime < 0 {
+			return nil, fmt.Errorf("MAX_JOB_RUNTIME must be a duration such as 2h, got '%s'", value)
+		}
+	}
+
+	maxOutput, err := units.RAMInBytes(getEnv("MAX_JOB_OUTPUT", "10m"))
+	if err != nil || maxOutput < 0 {
+		return nil, fmt.Errorf("MAX_JOB_OUTPUT must be a size such as 10m, got '%s'", getEnv("MAX_JOB_OUTPUT", ""))
+	}
+
+	orphanInterval, err := time.ParseDuration(getEnv("ORPHAN_CLEANUP_INTERVAL", "10m"))
+	if err != nil || orphanInterval < 0 {
+		return nil, fmt.Errorf("ORPHAN_CLEANUP_INTERVAL must be a duration such as 10m, got '%s'", getEnv("ORPHAN_CLEANUP_INTERVAL", ""))
+	}
+
+	var cacheBudget int64
+	if value := getEnv("CACHE_VOLUME_BUDGET", ""); value != "" {
+		if cacheBudget, err = units.RAMInBytes(value); err != nil || cacheBudget < 0 {
+			return nil, fmt.Errorf("CACHE_VOLUME_BUDGET must be a size such as 20g, got '%s'", value)
+		}
+	}
+
+	warmSize, err := strconv.Atoi(getEnv("WARM_POOL_SIZE", "0"))
+	if err != nil || warmSize < 0 {
+		return nil, fmt.Errorf("WARM_POOL_SIZE must be a number of containers, got '%s'", getEnv("WARM_POOL_SIZE", ""))
+	}
+	warmTTL, err := time.ParseDuration(getEnv("WARM_POOL_TTL", "10m"))
+	if err != nil || warmTTL <= 0 {
+		return nil, fmt.Errorf("WARM_POOL_TTL must be a duration such as 10m, got '%s'", getEnv("WARM_POOL_TTL", ""))
+	}
+
+	accessTTL, err := time.ParseDuration(getEnv("JWT_ACCESS_TTL", "15m"))
+	if err != nil || accessTTL <= 0 {
+		return nil, fmt.Errorf("JWT_ACCESS_TTL must be a duration such as 15m, got '%s'", getEnv("JWT_ACCESS_TTL", ""))
+	}
+	refreshTTL, err := time.ParseDuration(getEnv("JWT_REFRESH_TTL", "168h"))
+	if err != nil || refreshTTL <= 0 {
+		return nil, fmt.Errorf("JWT_REFRESH_TTL must be a duration such as 168h, got '%s'", getEnv("JWT_REFRESH_TTL", ""))
+	}
+
+	rateLimit, err := strconv.Atoi(getEnv("RATE_LIMIT", "0"))
+	if err != nil || rateLimit < 0 {
+		return nil, fmt.Errorf("RATE_LIMIT must be a number of requests a minute, got '%s'", getEnv("RATE_LIMIT", ""))
+	}
+	rateBurst, err := strconv.Atoi(getEnv("RATE_LIMIT_BURST", "0"))
+	if err != nil || rateBurst < 0 {
+		return nil, fmt.Errorf("RATE_LIMIT_BURST must be a number of requests, got '%s'", getEnv("RATE_LIMIT_BURST", ""))
+	}
+
+	dockerHosts, err := parseDockerHosts(getEnv("DOCKER_HOSTS", ""), getEnv("DOCKER_CERT_PATH", ""))
+	if err != nil {
+		return nil, err
+	}
+
+	hookNetworks, err := parseHookNetworks(getEnv("HOOK_ALLOWED_NETWORKS", ""))
+	if err != nil {
+		return nil, err
+	}
+
+	hardening, err := parseHardening(getEnv("JOB_CAP_DROP", ""), getEnv("JOB_NO_NEW_PRIVILEGES", "false"),
+		getEnv("JOB_READ_ONLY_ROOTFS", "false"), getEnv("JOB_PIDS_LIMIT", ""))
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := &Config{
-		StorageType: getEnv("STORAGE_TYPE", "memory"), // "memory" or "mongodb"
-		MongoURI:    getEnv("MONGO_URI", "mongodb://localhost:27017"),
-		MongoDB:     getEnv("MONGO_DATABASE", "gantry"),
+		StorageType:  getEnv("STORAGE_TYPE", "memory"), // "memory" or "mongodb"
+		MongoURI:     getEnv("MONGO_URI", "mongodb://localhost:27017"),
+		MongoDB:      getEnv("MONGO_DATABASE", "gantry"),
+		SecretsFile:  getEnv("SECRETS_FILE", ""),
+		ActionsRoot:  getEnv("ACTIONS_ROOT", "."),
+		ArtifactsDir: getEnv("ARTIFACTS_DIR", "artifacts"),
+		CacheDir:     getEnv("CACHE_DIR", "cache"),
+
+		EnvironmentsFile: getEnv("ENVIRONMENTS_FILE", ""),
+		RunnersFile:      getEnv("RUNNERS_FILE", ""),
+		VolumePaths:      volumePaths,
+		AllowPrivileged:  getEnv("ALLOW_PRIVILEGED_JOBS", "false") == "true",
+		ImagePolicy: models.ImagePolicy{
+			Allowed: models.ParseImagePatterns(getEnv("ALLOWED_IMAGES", "")),
+			Denied:  models.ParseImagePatterns(getEnv("DENIED_IMAGES", "")),
+		},
+		VMRuntime:        getEnv("VM_RUNTIME", ""),
+		JobUser:          getEnv("JOB_USER", ""),
+		ForbidRoot:       getEnv("FORBID_ROOT_JOBS", "false") == "true",
+		RegistryAuthFile: getEnv("REGISTRY_AUTH_FILE", ""),
+		PullPolicy:       getEnv("IMAGE_PULL_POLICY", ""),
+		PrepullImages:    getEnv("PREPULL_IMAGES", "false") == "true",
+
+		MaxConcurrentJobs: maxJobs,
+		MaxJobRuntime:     maxRuntime,
+		MaxJobOutput:      maxOutput,
+
+		OrphanCleanupInterval: orphanInterval,
+		CacheVolumeBudget:     cacheBudget,
+		Hardening:             hardening,
+		WarmPoolSize:          warmSize,
+		WarmPoolTTL:           warmTTL,
+
+		ExecutorType: getEnv("EXECUTOR_TYPE", "docker"),
+		PodmanSocket: getEnv("PODMAN_SOCKET", ""),
+		Kubernetes: executor.KubernetesConfig{
+			Kubeconfig:     getEnv("KUBECONFIG", ""),
+			Namespace:      getEnv("K8S_NAMESPACE", "default"),
+			ServiceAccount: getEnv("K8S_SERVICE_ACCOUNT", ""),
+			CPURequest:     getEnv("K8S_CPU_REQUEST", ""),
+			CPULimit:       getEnv("K8S_CPU_LIMIT", ""),
+			MemoryRequest:  getEnv("K8S_MEMORY_REQUEST", ""),
+			MemoryLimit:    getEnv("K8S_MEMORY_LIMIT", ""),
+
+			WorkspaceStorageClass: getEnv("K8S_WORKSPACE_STORAGE_CLASS", ""),
+			WorkspaceSize:         getEnv("K8S_WORKSPACE_SIZE", ""),
+		},
+		WasmModulesDir: getEnv("WASM_MODULES_DIR", ""),
+		DockerHosts:    dockerHosts,
+		DockerStrategy: getEnv("DOCKER_STRATEGY", ""),
+
+		JWTSecret:         getEnv("JWT_SECRET", ""),
+		AccessTokenTTL:    accessTTL,
+		RefreshTokenTTL:   refreshTTL,
+		AllowRegistration: getEnv("ALLOW_REGISTRATION", "false") == "true",
+
+		RateLimit:         rateLimit,
+		RateLimitBurst:    rateBurst,
+		TrustProxyHeaders: getEnv("TRUST_PROXY_HEADERS", "false") == "true",
+
+		GitHubWebhookSecret: getEnv("GITHUB_WEBHOOK_SECRET", ""),
+		GitLabWebhookSecret: getEnv("GITLAB_WEBHOOK_SECRET", ""),
+		HookNetworks:        hookNetworks,
 	}
 
 	log.Println(cfg.StorageType)
@@ -86,6 +520,46 @@ func NewServerFromEnv() (*Server, error) {
 	return NewServer(cfg)
 }
 
+// newExecutor creates the executor jobs run with
+func newExecutor(cfg *Config) (executor.Executor, error) {
+	switch cfg.ExecutorType {
+	case "", "docker":
+		if len(cfg.DockerHosts) > 0 {
+			log.Printf("Using Docker executor on %d hosts", len(cfg.DockerHosts))
+			return executor.NewMultiDockerExecutor(cfg.DockerHosts, cfg.DockerStrategy)
+		}
+		log.Println("Using Docker executor")
+		return executor.NewDockerExecutor()
+	case "podman":
+		log.Println("Using Podman executor")
+		return executor.NewPodmanExecutor(cfg.PodmanSocket)
+	case "kubernetes":
+		log.Printf("Using Kubernetes executor in namespace %s", cfg.Kubernetes.Namespace)
+		return executor.NewKubernetesExecutor(cfg.Kubernetes)
+	case "shell":
+		log.Println("WARNING: Using shell executor; steps run directly on this host without isolation")
+		return executor.NewShellExecutor(), nil
+	case "wasm":
+		log.Println("WARNING: Using experimental wasm executor; steps run as WASI modules on this host")
+		return executor.NewWasmExecutor(cfg.WasmModulesDir)
+	default:
+		return nil, fmt.Errorf("unknown executor type '%s' (expected docker, podman, kubernetes, shell or wasm)", cfg.ExecutorType)
+	}
+}
+
+// loadRegistryAuths reads the registry credentials of a Docker config.json,
+// or none when file is empty
+func loadRegistryAuths(file string) (map[string]models.RegistryCredentials, error) {
+	if file == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registry auth file: %w", err)
+	}
+	return models.ParseDockerConfig(data)
+}
+
 func getEnv(key, defaultValue string) string {
 
 	if value := os.Getenv(key); value != "" {
@@ -94,8 +568,195 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
-// ParseAndSaveWorkflow parses and saves a workflow
-func (s *Server) ParseAndSaveWorkflow(data []byte) (*models.Workflow, error) {
+// ParseAndSaveWorkflow parses and saves a workflow, as author's version of
+// it
+func (s *Server) ParseAndSaveWorkflow(data []byte, author string) (*models.Workflow, error) {
+	wf, err := s.checkWorkflow(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.saveWorkflow(wf, author); err != nil {
+		return nil, err
+	}
+	s.prepullImages(wf)
+
+	return wf, nil
+}
+
+// UpdateWorkflow replaces the workflow ref names with a new version parsed
+// from data. The workflow keeps its ID and runs, and is renamed when data
+// names it differently.
+func (s *Server) UpdateWorkflow(ref string, data []byte, author string) (*models.Workflow, error) {
+	existing, err := findWorkflow(s.storage, ref)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrWorkflowNotFound, ref)
+	}
+
+	wf, err := s.checkWorkflow(data)
+	if err != nil {
+		return nil, err
+	}
+
+	wf.ID = existing.ID
+	if err := s.saveWorkflow(wf, author); err != nil {
+		return nil, err
+	}
+	s.prepullImages(wf)
+
+	return wf, nil
+}
+
+// saveWorkflow saves a workflow as the version after the stored one, and
+// records the version as a revision
+func (s *Server) saveWorkflow(wf *models.Workflow, author string) error {
+	s.workflowMu.Lock()
+	defer s.workflowMu.Unlock()
+
+	if _, err := s.storeWorkflow(wf, author); err != nil {
+		return err
+	}
+	s.recordRevision(wf)
+	return nil
+}
+
+// changeWorkflow saves a copy of the workflow ref names, as changed by
+// change, as author's version of it. The workflow is looked up while
+// workflowMu is held, so no other save lands between the lookup and the save.
+func (s *Server) changeWorkflow(ref, author string, change func(wf *models.Workflow) error) (*models.Workflow, error) {
+	s.workflowMu.Lock()
+	defer s.workflowMu.Unlock()
+
+	wf, err := findWorkflow(s.storage, ref)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrWorkflowNotFound, ref)
+	}
+
+	changed := *wf
+	if err := change(&changed); err != nil {
+		return nil, err
+	}
+	if _, err := s.storeWorkflow(&changed, author); err != nil {
+		return nil, err
+	}
+	s.recordRevision(&changed)
+	return &changed, nil
+}
+
+// storeWorkflow saves a workflow as the version after the stored one,
+// which it returns, or nil for new workflows. Workflows stored before
+// versions were counted are taken to be version 1. Callers hold workflowMu
+// until the version is recorded.
+func (s *Server) storeWorkflow(wf *models.Workflow, author string) (*models.Workflow, error) {
+	var previous *models.Workflow
+	var err error
+	if wf.ID != "" {
+		previous, err = s.storage.GetWorkflow(wf.ID)
+	} else {
+		previous, err = s.storage.GetWorkflowByName(wf.Name)
+	}
+
+	switch {
+	case err == nil:
+		wf.Version = max(previous.Version, 1) + 1
+	case errors.Is(err, storage.ErrWorkflowNotFound):
+		previous = nil
+		wf.Version = 1
+	default:
+		return nil, fmt.Errorf("failed to look up the stored version: %w", err)
+	}
+	wf.UpdatedBy = author
+	wf.UpdatedAt = time.Now()
+	if err := s.storage.SaveWorkflow(wf); err != nil {
+		return nil, err
+	}
+	return previous, nil
+}
+
+// recordRevision keeps the saved version of a workflow
+func (s *Server) recordRevision(wf *models.Workflow) {
+	rev := &models.WorkflowRevision{
+		WorkflowID: wf.ID,
+		Version:    wf.Version,
+		Name:       wf.Name,
+		Source:     wf.Source,
+		UpdatedBy:  wf.UpdatedBy,
+		UpdatedAt:  wf.UpdatedAt,
+	}
+	if err := s.storage.SaveWorkflowRevision(rev); err != nil {
+		log.Printf("WARNING: failed to record version %d of workflow '%s': %v", wf.Version, wf.Name, err)
+	}
+}
+
+// ErrNoSource is returned when exporting a workflow saved before workflows
+// kept the YAML they were uploaded as
+var ErrNoSource = errors.New("workflow has no stored YAML")
+
+// WorkflowSource returns the YAML a workflow was uploaded as, byte for byte,
+// at a saved version, or at its current version when version is 0
+func (s *Server) WorkflowSource(ref string, version int) (string, error) {
+	wf, err := findWorkflow(s.storage, ref)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrWorkflowNotFound, ref)
+	}
+
+	source := wf.Source
+	if version != 0 && version != wf.Version {
+		revisions, err := s.storage.ListWorkflowRevisions(wf.ID)
+		if err != nil {
+			return "", err
+		}
+		i := slices.IndexFunc(revisions, func(rev *models.WorkflowRevision) bool { return rev.Version == version })
+		if i < 0 {
+			return "", fmt.Errorf("%w: %s has no version %d", ErrWorkflowNotFound, ref, version)
+		}
+		source = revisions[i].Source
+	}
+
+	if source == "" {
+		return "", fmt.Errorf("%w: upload '%s' again to keep it", ErrNoSource, wf.Name)
+	}
+	return source, nil
+}
+
+// ListWorkflowRevisions returns the saved versions of a workflow, newest
+// first
+func (s *Server) ListWorkflowRevisions(ref string) ([]*models.WorkflowRevision, error) {
+	wf, err := findWorkflow(s.storage, ref)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrWorkflowNotFound, ref)
+	}
+	return s.storage.ListWorkflowRevisions(wf.ID)
+}
+
+// ValidationResult is the outcome of checking a workflow without saving it
+type ValidationResult struct {
+	Valid    bool                    `json:"valid"`
+	Name     string                  `json:"name,omitempty"`
+	Errors   parser.ValidationErrors `json:"errors"`
+	Warnings parser.ValidationErrors `json:"warnings"`
+}
+
+// ValidateWorkflow runs the checks of an upload without saving the
+// workflow, and lints it once it is valid
+func (s *Server) ValidateWorkflow(data []byte) *ValidationResult {
+	result := &ValidationResult{Errors: parser.ValidationErrors{}, Warnings: parser.ValidationErrors{}}
+
+	wf, err := s.checkWorkflow(data)
+	if err != nil {
+		result.Errors = parser.AsValidationErrors(err)
+		return result
+	}
+
+	result.Valid = true
+	result.Name = wf.Name
+	result.Warnings = append(result.Warnings, s.parser.Lint(wf)...)
+	return result
+}
+
+// checkWorkflow parses and validates a workflow, and checks that the secrets
+// and environments it references exist on this server
+func (s *Server) checkWorkflow(data []byte) (*models.Workflow, error) {
 	wf, err := s.parser.Parse(data)
 	if err != nil {
 		return nil, err
@@ -105,26 +766,216 @@ func (s *Server) ParseAndSaveWorkflow(data []byte) (*models.Workflow, error) {
 		return nil, err
 	}
 
-	if err := s.storage.SaveWorkflow(wf); err != nil {
+	jobs := make([]models.Job, 0, len(wf.Jobs))
+	for _, job := range wf.Jobs {
+		jobs = append(jobs, job)
+	}
+	if err := s.checkSecrets(wf.Env, jobs...); err != nil {
+		return nil, err
+	}
+	if err := s.checkWebhook(wf); err != nil {
+		return nil, err
+	}
+	if err := s.checkEnvironments(jobs...); err != nil {
+		return nil, err
+	}
+	if err := s.checkVolumes(jobs...); err != nil {
+		return nil, err
+	}
+	if err := s.checkPrivileged(jobs...); err != nil {
+		return nil, err
+	}
+	if err := s.checkGPUs(jobs...); err != nil {
+		return nil, err
+	}
+	if err := s.checkIsolation(jobs...); err != nil {
+		return nil, err
+	}
+	if err := s.checkUsers(jobs...); err != nil {
+		return nil, err
+	}
+	if err := s.checkImages(wf); err != nil {
 		return nil, err
 	}
 
 	return wf, nil
 }
 
-// ListWorkflows returns all workflows
-func (s *Server) ListWorkflows() ([]*models.Workflow, error) {
-	return s.storage.ListWorkflows()
+// ImportResult is a workflow translated from another CI system and saved
+type ImportResult struct {
+	Workflow *models.Workflow `json:"workflow"`
+	YAML     string           `json:"yaml"`    // the translated workflow
+	Ignored  []string         `json:"ignored"` // constructs that didn't carry over
 }
 
-// TriggerWorkflow triggers a workflow execution
-func (s *Server) TriggerWorkflow(ctx context.Context, name string) (*models.WorkflowRun, error) {
-	wf, err := s.storage.GetWorkflow(name)
+// ImportWorkflow translates a workflow file of another CI system and saves
+// the result like an upload
+func (s *Server) ImportWorkflow(format string, data []byte, opts importer.Options, author string) (*ImportResult, error) {
+	translated, err := importer.Import(format, data, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.executeWorkflow(ctx, wf)
+	wf, err := s.ParseAndSaveWorkflow(translated.Workflow, author)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ImportResult{Workflow: wf, YAML: string(translated.Workflow), Ignored: translated.Ignored}, nil
+}
+
+// ListWorkflows returns a page of the workflows and how many there are
+func (s *Server) ListWorkflows(opts storage.ListOptions) ([]*models.Workflow, int, error) {
+	return s.storage.ListWorkflows(opts)
+}
+
+// Backends returns the names of the executor jobs run with and the storage
+// workflows and runs are kept in
+func (s *Server) Backends() (executorType, storageType string) {
+	return s.executorType, s.storageType
+}
+
+// GetWorkflow looks up a workflow by ID or name
+func (s *Server) GetWorkflow(ref string) (*models.Workflow, error) {
+	return findWorkflow(s.storage, ref)
+}
+
+// ErrInvalidName is returned when renaming a workflow to an empty name
+var ErrInvalidName = errors.New("invalid workflow name")
+
+// RenameWorkflow renames a workflow, keeping its ID and therefore its runs.
+// The rename is saved as author's version of the workflow, with the name in
+// its YAML changed to match.
+func (s *Server) RenameWorkflow(ref, name, author string) (*models.Workflow, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name must not be empty", ErrInvalidName)
+	}
+
+	return s.changeWorkflow(ref, author, func(wf *models.Workflow) error {
+		wf.Name = name
+		if wf.Source == "" {
+			return nil
+		}
+		source, err := parser.SetName([]byte(wf.Source), name)
+		if err != nil {
+			return fmt.Errorf("failed to rename the workflow's YAML: %w", err)
+		}
+		wf.Source = string(source)
+		return nil
+	})
+}
+
+// findWorkflow looks up a workflow by ID, or by name for references that
+// aren't an ID
+func findWorkflow(store storage.Storage, ref string) (*models.Workflow, error) {
+	if wf, err := store.GetWorkflow(ref); err == nil {
+		return wf, nil
+	}
+	return store.GetWorkflowByName(ref)
+}
+
+// storedSource returns the YAML source of a stored workflow, for workflows
+// that extend it
+func storedSource(store storage.Storage) func(name string) ([]byte, error) {
+	return func(name string) ([]byte, error) {
+		wf, err := findWorkflow(store, name)
+		if err != nil {
+			return nil, err
+		}
+		if wf.Source == "" {
+			return nil, fmt.Errorf("workflow '%s' has no stored source", name)
+		}
+		return []byte(wf.Source), nil
+	}
+}
+
+// TriggerOptions holds the parameters of a manual trigger
+type TriggerOptions struct {
+	// Inputs are checked against the workflow's workflow_dispatch inputs
+	Inputs map[string]interface{}
+
+	// Variables override the workflow's declared variables for this run
+	Variables map[string]string
+
+	// Branch is the branch the run is for. It is checked against the
+	// workflow's push branch filters and recorded on the run.
+	Branch string
+
+	// Env overrides the workflow's env for this run. Values are taken as
+	// they are, without evaluating expressions.
+	Env map[string]string
+
+	// Labels are recorded on the run to tell runs apart
+	Labels map[string]string
+}
+
+// ErrWorkflowNotFound is returned when filtering runs by a workflow that
+// doesn't exist
+var ErrWorkflowNotFound = errors.New("workflow not found")
+
+// ErrSkipped is returned when a manual trigger's branch is filtered out by
+// the workflow's push branches, so no run is started
+var ErrSkipped = errors.New("skipped")
+
+// TriggerWorkflow triggers a workflow execution. ref is the workflow's ID
+// or name.
+func (s *Server) TriggerWorkflow(ctx context.Context, ref string, opts TriggerOptions) (*models.WorkflowRun, error) {
+	wf, err := findWorkflow(s.storage, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Branch != "" && wf.On.Push != nil && !glob.Filter(wf.On.Push.Branches, opts.Branch) {
+		return nil, fmt.Errorf("%w: branch '%s' doesn't match the workflow's branches", ErrSkipped, opts.Branch)
+	}
+
+	inputs, err := resolveInputs(wf, opts.Inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	variables, err := resolveVariables(wf, opts.Variables)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkEnvOverrides(opts.Env); err != nil {
+		return nil, err
+	}
+	if err := checkLabels(opts.Labels); err != nil {
+		return nil, err
+	}
+
+	trigger := &models.TriggerInfo{Event: models.EventWorkflowDispatch, Branch: opts.Branch}
+	params := runParams{inputs: inputs, variables: variables, env: opts.Env, labels: opts.Labels}
+	return s.executeWorkflow(ctx, wf, trigger, params, "")
+}
+
+// RerunRun starts a new run of the workflow a run ran, as it was when that
+// run started, with the same inputs, variables, env, labels and trigger. Runs from
+// before workflows were kept with their runs re-run the workflow as it is
+// now.
+func (s *Server) RerunRun(ctx context.Context, runID string) (*models.WorkflowRun, error) {
+	prev, err := s.storage.GetRun(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	wf := prev.Workflow
+	if wf == nil {
+		if wf, err = s.storage.GetWorkflow(prev.WorkflowID); err != nil {
+			return nil, err
+		}
+	}
+
+	var trigger *models.TriggerInfo
+	if prev.Trigger != nil {
+		copied := *prev.Trigger
+		trigger = &copied
+	}
+	params := runParams{inputs: prev.Inputs, variables: prev.Variables, env: prev.Env, labels: prev.Labels}
+	return s.executeWorkflow(ctx, wf, trigger, params, prev.ID)
 }
 
 // GetRun retrieves a workflow run
@@ -132,26 +983,27 @@ func (s *Server) GetRun(id string) (*models.WorkflowRun, error) {
 	return s.storage.GetRun(id)
 }
 
-// ListRuns returns all workflow runs
-func (s *Server) ListRuns() ([]*models.WorkflowRun, error) {
-	return s.storage.ListRuns()
+// ListRuns returns a page of the workflow runs filter matches, newest
+// first, and how many match. The filter's workflow may be given by ID or
+// name.
+func (s *Server) ListRuns(filter storage.RunFilter, opts storage.ListOptions) ([]*models.WorkflowRun, int, error) {
+	if filter.WorkflowID != "" {
+		wf, err := findWorkflow(s.storage, filter.WorkflowID)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: %s", ErrWorkflowNotFound, filter.WorkflowID)
+		}
+		filter.WorkflowID = wf.ID
+	}
+	return s.storage.ListRuns(filter, opts)
 }
 
 // GetWorkflowStats returns statistics for a workflow
-func (s *Server) GetWorkflowStats(workflowName string) (map[string]interface{}, error) {
-	runs, err := s.storage.ListRuns()
+func (s *Server) GetWorkflowStats(ref string) (map[string]interface{}, error) {
+	workflowRuns, err := s.GetWorkflowRuns(ref)
 	if err != nil {
 		return nil, err
 	}
 
-	// Filter runs for this workflow
-	var workflowRuns []*models.WorkflowRun
-	for _, run := range runs {
-		if run.WorkflowName == workflowName {
-			workflowRuns = append(workflowRuns, run)
-		}
-	}
-
 	// Calculate statistics
 	stats := map[string]interface{}{
 		"total_runs":       len(workflowRuns),
@@ -169,7 +1021,7 @@ func (s *Server) GetWorkflowStats(workflowName string) (map[string]interface{},
 
 	for _, run := range workflowRuns {
 		switch run.Status {
-		case successStatus:
+		case successStatus, successWithFailuresStatus:
 			successCount++
 		case failedStatus:
 			failureCount++
@@ -195,142 +1047,140 @@ func (s *Server) GetWorkflowStats(workflowName string) (map[string]interface{},
 	return stats, nil
 }
 
-// GetWorkflowRuns returns all runs for a specific workflow
-func (s *Server) GetWorkflowRuns(workflowName string) ([]*models.WorkflowRun, error) {
-	runs, err := s.storage.ListRuns()
+// GetWorkflowRuns returns all runs for a specific workflow, including runs
+// from before it was renamed
+func (s *Server) GetWorkflowRuns(ref string) ([]*models.WorkflowRun, error) {
+	wf, err := findWorkflow(s.storage, ref)
 	if err != nil {
 		return nil, err
 	}
 
-	var workflowRuns []*models.WorkflowRun
-	for _, run := range runs {
-		if run.WorkflowName == workflowName {
-			workflowRuns = append(workflowRuns, run)
-		}
-	}
-
-	return workflowRuns, nil
+	runs, _, err := s.storage.ListRuns(storage.RunFilter{WorkflowID: wf.ID}, storage.ListOptions{})
+	return runs, err
 }
 
 // DeleteWorkflow deletes a workflow and all associated runs
-func (s *Server) DeleteWorkflow(name string) error {
+func (s *Server) DeleteWorkflow(ref string) error {
+	wf, err := findWorkflow(s.storage, ref)
+	if err != nil {
+		return err
+	}
+
 	// Delete all runs for this workflow (cascade delete)
-	if err := s.storage.DeleteRunsByWorkflow(name); err != nil {
-		log.Printf("WARNING: failed to delete runs for workflow '%s': %v", name, err)
+	if err := s.storage.DeleteRunsByWorkflow(wf.ID); err != nil {
+		log.Printf("WARNING: failed to delete runs for workflow '%s': %v", wf.Name, err)
+	}
+	if err := s.deleteWorkflowHooks(wf.ID); err != nil {
+		log.Printf("WARNING: failed to delete hooks for workflow '%s': %v", wf.Name, err)
 	}
 
 	// Delete the workflow itself
-	return s.storage.DeleteWorkflow(name)
+	return s.storage.DeleteWorkflow(wf.ID)
 }
 
-// executeWorkflow executes a workflow
-func (s *Server) executeWorkflow(ctx context.Context, wf *models.Workflow) (*models.WorkflowRun, error) {
-	runID := fmt.Sprintf("run-%d", time.Now().Unix())
+// ErrRunActive is returned when deleting a run that is still running
+var ErrRunActive = errors.New("run is running")
 
-	run := &models.WorkflowRun{
-		ID:           runID,
-		WorkflowName: wf.Name,
-		Status:       runningStatus,
-		Jobs:         make(map[string]models.Job),
-		JobOrder:     wf.JobOrder,
-		StartedAt:    time.Now(),
+// DeleteRun deletes a run and its artifacts. Runs still running on this
+// server can't be deleted; cancel them first.
+func (s *Server) DeleteRun(runID string) error {
+	if s.runActive(runID) {
+		return fmt.Errorf("%w: %s", ErrRunActive, runID)
 	}
-
-	if err := s.storage.SaveRun(run); err != nil {
-		return nil, err
+	if err := s.storage.DeleteRun(runID); err != nil {
+		return err
 	}
-
-	// Execute jobs asynchronously
-	go s.runJobs(ctx, run, wf)
-
-	return run, nil
+	if s.artifacts != nil {
+		if err := s.artifacts.Delete(runID); err != nil {
+			log.Printf("WARNING: failed to delete artifacts of run %s: %v", runID, err)
+		}
+	}
+	return nil
 }
 
-// runJobs executes all jobs in a workflow
-func (s *Server) runJobs(_ context.Context, run *models.WorkflowRun, wf *models.Workflow) {
-	defer func() {
-		run.Complete()
-
-		if err := s.storage.UpdateRun(run); err != nil {
-			log.Printf("ERROR: failed to update run status in storage: %v", err)
-		}
-	}()
-
-	// Create a new background context with longer timeout for job execution
-	// Don't use the HTTP request context as it may timeout
-	jobCtx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
-	defer cancel()
-
-	allSuccess := true
-	jobOrder := wf.JobOrder
-	if len(jobOrder) == 0 {
-		for name := range wf.Jobs {
-			jobOrder = append(jobOrder, name)
-		}
+// DeleteRuns deletes the runs filter matches and their artifacts, leaving
+// out runs still running, and returns how many it deleted
+func (s *Server) DeleteRuns(filter storage.RunFilter) (int, error) {
+	runs, _, err := s.ListRuns(filter, storage.ListOptions{})
+	if err != nil {
+		return 0, err
 	}
 
-	for _, jobName := range jobOrder {
-		job := wf.Jobs[jobName]
-		log.Printf("Starting job: %s", jobName)
-
-		// Check if executor is available
-		if s.executor == nil {
-			job.Status = failedStatus
-			job.Output = "ERROR: executor not initialized"
-			allSuccess = false
-			run.UpdateJob(jobName, job)
-			if err := s.storage.UpdateRun(run); err != nil {
-				log.Printf("ERROR: failed to update run status in storage: %v", err)
-			}
-			break
+	deleted := 0
+	for _, run := range runs {
+		err := s.DeleteRun(run.ID)
+		if errors.Is(err, ErrRunActive) {
+			continue
 		}
-
-		jobStartTime := time.Now()
-		job.Status = runningStatus
-		job.StartedAt = jobStartTime
-		run.UpdateJob(jobName, job)
-
-		if err := s.storage.UpdateRun(run); err != nil {
-			log.Printf("ERROR: failed to update run status in storage: %v", err)
+		if err != nil {
+			return deleted, err
 		}
+		deleted++
+	}
+	return deleted, nil
+}
 
-		output, err := s.executor.Execute(jobCtx, jobName, job)
-
-		jobEndTime := time.Now()
-		job.Output = output
-		job.EndedAt = &jobEndTime
+// executeWorkflow executes a workflow, recording the run it re-runs if
+// rerunOf is set
+func (s *Server) executeWorkflow(ctx context.Context, wf *models.Workflow, trigger *models.TriggerInfo,
+	params runParams, rerunOf string) (*models.WorkflowRun, error) {
+	// Nanosecond IDs, since one event can start several runs at once
+	runID := fmt.Sprintf("run-%d", time.Now().UnixNano())
 
-		if err != nil {
-			job.Status = failedStatus
-			allSuccess = false
-			log.Printf("Job %s failed: %v", jobName, err)
-		} else {
-			job.Status = successStatus
-			log.Printf("Job %s completed successfully", jobName)
-		}
+	// Reusable workflows are expanded now, so the run uses the called
+	// workflows as they are when it starts
+	expanded, groups, err := s.expandCalls(wf, params.inputs)
+	if err != nil {
+		return nil, err
+	}
+	plan := buildJobPlan(expanded, groups)
 
-		run.UpdateJob(jobName, job)
-		if err := s.storage.UpdateRun(run); err != nil {
-			log.Printf("ERROR: failed to update run status in storage: %v", err)
-		}
+	run := &models.WorkflowRun{
+		ID:           runID,
+		WorkflowID:   wf.ID,
+		WorkflowName: wf.Name,
+		Status:       runningStatus,
+		Jobs:         make(map[string]models.Job),
+		JobOrder:     plan.order,
+		Inputs:       params.inputs,
+		Variables:    params.variables,
+		Env:          params.env,
+		Labels:       params.labels,
+		Trigger:      trigger,
+		StartedAt:    time.Now(),
+		Workflow:     wf,
+		RerunOf:      rerunOf,
 
-		if err != nil {
-			break // Stop on first failure
-		}
+		WorkflowVersion: wf.Version,
 	}
 
-	if allSuccess {
-		run.SetStatus(successStatus)
-	} else {
-		run.SetStatus(failedStatus)
+	// With a bounded executor the run is queued until one of its jobs starts
+	if s.pool != nil {
+		run.Status = queuedStatus
 	}
 
-	if err := s.storage.UpdateRun(run); err != nil {
-		log.Printf("ERROR: failed to update run status in storage: %v", err)
+	if err := s.storage.SaveRun(run); err != nil {
+		return nil, err
 	}
+	s.observeRun(run)
+
+	// Execute jobs asynchronously
+	go s.runJobs(ctx, run, plan)
+
+	return run, nil
 }
 
 // Cleanup performs cleanup operations
 func (s *Server) Cleanup() error {
 	return s.executor.Cleanup()
 }
+
+// runner resolves the runner runs-on selects, falling back to the built-in
+// runners when none are configured
+func (s *Server) runner(label string) (runners.Runner, error) {
+	store := s.runners
+	if store == nil {
+		store = runners.NewDefaultStore()
+	}
+	return runners.Resolve(store, label)
+}
@@ -2,8 +2,15 @@ package server
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"gantry/internal/executor"
+	"gantry/internal/importer"
 	"gantry/internal/models"
 	"gantry/internal/parser"
 	"gantry/internal/storage"
@@ -30,7 +37,7 @@ jobs:
         run: echo "testing"
 `)
 
-	wf, err := srv.ParseAndSaveWorkflow(yaml)
+	wf, err := srv.ParseAndSaveWorkflow(yaml, "")
 	if err != nil {
 		t.Fatalf("Failed to parse and save workflow: %v", err)
 	}
@@ -40,7 +47,7 @@ jobs:
 	}
 
 	// Verify saved in storage
-	retrieved, err := srv.storage.GetWorkflow("Test Workflow")
+	retrieved, err := srv.storage.GetWorkflow("test-workflow")
 	if err != nil {
 		t.Fatalf("Failed to retrieve workflow: %v", err)
 	}
@@ -50,6 +57,98 @@ jobs:
 	}
 }
 
+func TestServer_ParseAndSaveWorkflow_Extends(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	srv := &Server{
+		storage: store,
+		parser:  parser.NewParser(parser.WithBaseWorkflows(storedSource(store))),
+	}
+
+	base := []byte("name: Base\njobs:\n  test:\n    runs-on: ubuntu\n    steps:\n      - name: Test\n        run: make test\n")
+	if _, err := srv.ParseAndSaveWorkflow(base, ""); err != nil {
+		t.Fatalf("Failed to save base workflow: %v", err)
+	}
+
+	wf, err := srv.ParseAndSaveWorkflow([]byte("name: Service\nextends: Base\nenv:\n  SERVICE: api\n"), "")
+	if err != nil {
+		t.Fatalf("Failed to save extending workflow: %v", err)
+	}
+	if _, ok := wf.Jobs["test"]; !ok || wf.Env["SERVICE"] != "api" {
+		t.Errorf("Expected the base's jobs merged with the override, got %+v", wf)
+	}
+
+	if err := store.SaveWorkflow(&models.Workflow{Name: "Built"}); err != nil {
+		t.Fatalf("Failed to save workflow: %v", err)
+	}
+	if _, err := srv.ParseAndSaveWorkflow([]byte("name: Other\nextends: Built\n"), ""); err == nil {
+		t.Error("Expected error extending a workflow without stored source, got nil")
+	}
+}
+
+func TestServer_ImportWorkflow(t *testing.T) {
+	srv := &Server{
+		storage: storage.NewMemoryStorage(),
+		parser:  parser.NewParser(),
+	}
+
+	source := []byte("name: CI\non: [push]\njobs:\n  test:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: actions/checkout@v4\n      - run: make test\n")
+	result, err := srv.ImportWorkflow(importer.FormatGitHub, source, importer.Options{}, "")
+	if err != nil {
+		t.Fatalf("ImportWorkflow returned error: %v", err)
+	}
+	if len(result.Ignored) != 1 || result.YAML == "" {
+		t.Errorf("Expected the translation and a note on checkout, got %+v", result)
+	}
+	if _, err := srv.storage.GetWorkflowByName("CI"); err != nil {
+		t.Errorf("Expected imported workflow to be saved, got %v", err)
+	}
+
+	if _, err := srv.ImportWorkflow(importer.FormatGitHub, []byte("name: Empty\n"), importer.Options{}, ""); err == nil {
+		t.Error("Expected error importing a workflow without jobs, got nil")
+	}
+
+	gitlab := []byte("stages: [test]\nunit:\n  stage: test\n  script: make test\n")
+	if _, err := srv.ImportWorkflow(importer.FormatGitLab, gitlab, importer.Options{Name: "GitLab CI"}, ""); err != nil {
+		t.Fatalf("ImportWorkflow returned error for GitLab CI: %v", err)
+	}
+	if _, err := srv.storage.GetWorkflowByName("GitLab CI"); err != nil {
+		t.Errorf("Expected imported GitLab workflow to be saved under its given name, got %v", err)
+	}
+}
+
+func TestServer_ValidateWorkflow(t *testing.T) {
+	srv := &Server{
+		storage: storage.NewMemoryStorage(),
+		parser:  parser.NewParser(),
+	}
+
+	valid := []byte(`
+name: Lint Me
+jobs:
+  test:
+    runs-on: ubuntu
+    steps:
+      - name: Push
+        run: docker push app:${{ vars.TAG }}
+`)
+	result := srv.ValidateWorkflow(valid)
+	if !result.Valid || result.Name != "Lint Me" || len(result.Errors) != 0 {
+		t.Fatalf("Expected valid workflow, got %+v", result)
+	}
+	if len(result.Warnings) != 1 || result.Warnings[0].Path != "jobs.test" {
+		t.Errorf("Expected a warning for the undeclared variable, got %+v", result.Warnings)
+	}
+	if workflows, _, _ := srv.ListWorkflows(storage.ListOptions{}); len(workflows) != 0 {
+		t.Errorf("Expected validation not to save the workflow, got %d workflows", len(workflows))
+	}
+
+	invalid := []byte("name: Bad\njobs:\n  test:\n    runs-on: ubuntu\n    steps:\n      - name: Test\n        rn: make\n")
+	result = srv.ValidateWorkflow(invalid)
+	if result.Valid || len(result.Errors) == 0 || result.Errors[0].Line != 7 {
+		t.Errorf("Expected errors with lines for an invalid workflow, got %+v", result)
+	}
+}
+
 func TestServer_ListWorkflows(t *testing.T) {
 	srv := &Server{
 		storage: storage.NewMemoryStorage(),
@@ -67,7 +166,7 @@ func TestServer_ListWorkflows(t *testing.T) {
 		}
 	}
 
-	workflows, err := srv.ListWorkflows()
+	workflows, _, err := srv.ListWorkflows(storage.ListOptions{})
 	if err != nil {
 		t.Fatalf("Failed to list workflows: %v", err)
 	}
@@ -101,7 +200,7 @@ func TestServer_TriggerWorkflow(t *testing.T) {
 	}
 
 	// Note: This will fail without Docker, but we can test the run creation
-	run, err := srv.TriggerWorkflow(context.Background(), testWorkflowName)
+	run, err := srv.TriggerWorkflow(context.Background(), testWorkflowName, TriggerOptions{})
 	if err != nil && err.Error() != "failed to create executor: docker daemon not available" {
 		// Expected error if Docker not available
 		if run == nil {
@@ -115,6 +214,59 @@ func TestServer_TriggerWorkflow(t *testing.T) {
 	}
 }
 
+func TestServer_RerunRun(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+
+	workflow := func(script string) *models.Workflow {
+		job := testJob()
+		job.Steps[0].Run = script
+		return &models.Workflow{
+			Name:      testWorkflowName,
+			Variables: map[string]string{"TAG": "latest"},
+			Jobs:      map[string]models.Job{"build": job},
+			JobOrder:  []string{"build"},
+		}
+	}
+	if err := srv.storage.SaveWorkflow(workflow("echo ${{ vars.TAG }} v1")); err != nil {
+		t.Fatalf("Failed to save workflow: %v", err)
+	}
+	first, err := srv.TriggerWorkflow(context.Background(), testWorkflowName, TriggerOptions{
+		Variables: map[string]string{"TAG": "1.4.0"},
+		Branch:    "main",
+	})
+	if err != nil {
+		t.Fatalf("Failed to trigger workflow: %v", err)
+	}
+	waitForRun(t, srv, first.ID)
+
+	// The re-run runs the workflow as the first run did
+	if err := srv.storage.SaveWorkflow(workflow("echo v2")); err != nil {
+		t.Fatalf("Failed to save workflow: %v", err)
+	}
+	rerun, err := srv.RerunRun(context.Background(), first.ID)
+	if err != nil {
+		t.Fatalf("Failed to re-run: %v", err)
+	}
+	waitForRun(t, srv, rerun.ID)
+
+	if rerun.ID == first.ID || rerun.RerunOf != first.ID || rerun.WorkflowID != first.WorkflowID {
+		t.Errorf("Expected a new run of the same workflow linked to %s, got %s (rerun of %q)", first.ID, rerun.ID, rerun.RerunOf)
+	}
+	if rerun.Trigger == nil || rerun.Trigger.Branch != "main" {
+		t.Errorf("Expected the trigger to be kept, got %+v", rerun.Trigger)
+	}
+	exec.mu.Lock()
+	defer exec.mu.Unlock()
+	if got := exec.jobs["build"].Steps[0].Run; got != "echo 1.4.0 v1" {
+		t.Errorf("Expected the original workflow and variables to run again, got %q", got)
+	}
+
+	if _, err := srv.RerunRun(context.Background(), "run-missing"); err == nil {
+		t.Error("Expected re-running a missing run to fail")
+	}
+}
+
 func TestServer_GetWorkflowStats(t *testing.T) {
 	srv := &Server{
 		storage: storage.NewMemoryStorage(),
@@ -138,6 +290,7 @@ func TestServer_GetWorkflowStats(t *testing.T) {
 		}
 		run := &models.WorkflowRun{
 			ID:           "run-" + string(rune(48+i)),
+			WorkflowID:   wf.ID,
 			WorkflowName: testWorkflowName,
 			Status:       status,
 			Jobs:         make(map[string]models.Job),
@@ -189,6 +342,7 @@ func TestServer_GetWorkflowRuns(t *testing.T) {
 	for i := 0; i < 3; i++ {
 		run := &models.WorkflowRun{
 			ID:           "test-run-" + string(rune(48+i)),
+			WorkflowID:   wf.ID,
 			WorkflowName: testWorkflowName,
 			Status:       successStatus,
 			Jobs:         make(map[string]models.Job),
@@ -201,6 +355,7 @@ func TestServer_GetWorkflowRuns(t *testing.T) {
 	for i := 0; i < 2; i++ {
 		run := &models.WorkflowRun{
 			ID:           "other-run-" + string(rune(48+i)),
+			WorkflowID:   "other",
 			WorkflowName: "Other",
 			Status:       successStatus,
 			Jobs:         make(map[string]models.Job),
@@ -244,6 +399,7 @@ func TestServer_DeleteWorkflow(t *testing.T) {
 	// Save runs for this workflow
 	run := &models.WorkflowRun{
 		ID:           "run-123",
+		WorkflowID:   wf.ID,
 		WorkflowName: testWorkflowName,
 		Status:       successStatus,
 		Jobs:         make(map[string]models.Job),
@@ -259,13 +415,13 @@ func TestServer_DeleteWorkflow(t *testing.T) {
 	}
 
 	// Verify workflow deleted
-	_, err = srv.storage.GetWorkflow(testWorkflowName)
+	_, err = srv.storage.GetWorkflow(wf.ID)
 	if err == nil {
 		t.Error("Workflow should be deleted but still exists")
 	}
 
 	// Verify runs deleted (cascade delete)
-	runs, err := srv.storage.ListRuns()
+	runs, _, err := srv.storage.ListRuns(storage.RunFilter{}, storage.ListOptions{})
 	if err != nil {
 		t.Fatalf("Failed to list runs: %v", err)
 	}
@@ -275,6 +431,302 @@ func TestServer_DeleteWorkflow(t *testing.T) {
 	}
 }
 
+func TestServer_RenameWorkflow(t *testing.T) {
+	srv := &Server{
+		storage: storage.NewMemoryStorage(),
+		parser:  parser.NewParser(),
+	}
+
+	source := "name: %s\njobs:\n  test:\n    runs-on: ubuntu\n    steps:\n      - name: Test\n        run: echo test\n"
+	wf, err := srv.ParseAndSaveWorkflow([]byte(fmt.Sprintf(source, "Build")), "")
+	if err != nil {
+		t.Fatalf("Failed to save workflow: %v", err)
+	}
+	if _, err := srv.ParseAndSaveWorkflow([]byte(fmt.Sprintf(source, "Deploy")), ""); err != nil {
+		t.Fatalf("Failed to save workflow: %v", err)
+	}
+	run := &models.WorkflowRun{ID: "run-1", WorkflowID: wf.ID, WorkflowName: "Build", Jobs: map[string]models.Job{}}
+	if err := srv.storage.SaveRun(run); err != nil {
+		t.Fatalf("Failed to save run: %v", err)
+	}
+
+	renamed, err := srv.RenameWorkflow("Build", " Build and Test ", "alice")
+	if err != nil {
+		t.Fatalf("Failed to rename workflow: %v", err)
+	}
+	if renamed.ID != wf.ID || renamed.Name != "Build and Test" {
+		t.Errorf("Expected rename to keep ID %s, got %+v", wf.ID, renamed)
+	}
+	if renamed.Version != 2 || renamed.UpdatedBy != "alice" {
+		t.Errorf("Expected the rename to be alice's version 2, got %d by %q", renamed.Version, renamed.UpdatedBy)
+	}
+	if revisions, _ := srv.ListWorkflowRevisions(wf.ID); len(revisions) != 2 || revisions[0].Name != "Build and Test" {
+		t.Errorf("Expected the rename to be recorded as a revision, got %+v", revisions)
+	}
+
+	// The exported YAML has the new name, so uploading it again updates
+	// the renamed workflow
+	exported, err := srv.WorkflowSource(wf.ID, 0)
+	if err != nil || exported != fmt.Sprintf(source, "Build and Test") {
+		t.Errorf("Expected the YAML to be renamed too, got %q (%v)", exported, err)
+	}
+	if reuploaded, err := srv.ParseAndSaveWorkflow([]byte(exported), ""); err != nil || reuploaded.ID != wf.ID {
+		t.Errorf("Expected the exported YAML to update %s, got %v (%v)", wf.ID, reuploaded, err)
+	}
+
+	for _, ref := range []string{"Build and Test", wf.ID} {
+		runs, err := srv.GetWorkflowRuns(ref)
+		if err != nil || len(runs) != 1 || runs[0].WorkflowName != "Build" {
+			t.Errorf("Expected the run from before the rename for %q, got %v (%v)", ref, runs, err)
+		}
+	}
+	if _, err := srv.GetWorkflowRuns("Build"); err == nil {
+		t.Error("Expected the old name to no longer resolve")
+	}
+
+	// Uploading under the new name updates the same workflow
+	updated, err := srv.ParseAndSaveWorkflow([]byte(fmt.Sprintf(source, "Build and Test")), "")
+	if err != nil || updated.ID != wf.ID {
+		t.Errorf("Expected upload to keep ID %s, got %v (%v)", wf.ID, updated, err)
+	}
+
+	if _, err := srv.RenameWorkflow(wf.ID, "Deploy", ""); !errors.Is(err, storage.ErrWorkflowExists) {
+		t.Errorf("Expected ErrWorkflowExists, got %v", err)
+	}
+	if _, err := srv.RenameWorkflow(wf.ID, " ", ""); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("Expected ErrInvalidName, got %v", err)
+	}
+}
+
+func TestServer_RenameWorkflow_Concurrent(t *testing.T) {
+	srv := &Server{
+		storage: &slowStorage{MemoryStorage: storage.NewMemoryStorage()},
+		parser:  parser.NewParser(),
+	}
+
+	source := "name: %s\njobs:\n  test:\n    runs-on: ubuntu\n    steps:\n      - name: Test\n        run: echo %s\n"
+	wf, err := srv.ParseAndSaveWorkflow([]byte(fmt.Sprintf(source, "Build", "old")), "")
+	if err != nil {
+		t.Fatalf("Failed to save workflow: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if _, err := srv.UpdateWorkflow(wf.ID, []byte(fmt.Sprintf(source, "Build", "new")), ""); err != nil {
+			t.Errorf("Failed to update workflow: %v", err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if _, err := srv.RenameWorkflow(wf.ID, "Deploy", ""); err != nil {
+			t.Errorf("Failed to rename workflow: %v", err)
+		}
+	}()
+	wg.Wait()
+
+	// Whichever came second kept what the first saved
+	current, err := srv.GetWorkflow(wf.ID)
+	if err != nil {
+		t.Fatalf("Failed to get workflow: %v", err)
+	}
+	if current.Version != 3 {
+		t.Errorf("Expected version 3, got %d", current.Version)
+	}
+	if current.Name == "Deploy" && current.Source != fmt.Sprintf(source, "Deploy", "new") {
+		t.Errorf("Expected the rename to keep the update, got %q", current.Source)
+	}
+
+	if _, err := srv.RenameWorkflow("Missing", "Other", ""); !errors.Is(err, ErrWorkflowNotFound) {
+		t.Errorf("Expected ErrWorkflowNotFound, got %v", err)
+	}
+}
+
+func TestServer_UpdateWorkflow(t *testing.T) {
+	srv := newEventTestServer(t, &fakeExecutor{})
+
+	source := "name: %s\njobs:\n  test:\n    runs-on: ubuntu\n    steps:\n      - name: Test\n        run: echo test\n"
+	wf, err := srv.ParseAndSaveWorkflow([]byte(fmt.Sprintf(source, "Build")), "alice")
+	if err != nil {
+		t.Fatalf("Failed to save workflow: %v", err)
+	}
+	if wf.Version != 1 || wf.UpdatedBy != "alice" {
+		t.Errorf("Expected version 1 by alice, got %d by %q", wf.Version, wf.UpdatedBy)
+	}
+	first, err := srv.TriggerWorkflow(context.Background(), wf.ID, TriggerOptions{})
+	if err != nil {
+		t.Fatalf("Failed to trigger workflow: %v", err)
+	}
+
+	updated, err := srv.UpdateWorkflow("Build", []byte(fmt.Sprintf(source, "Build and Test")), "bob")
+	if err != nil {
+		t.Fatalf("Failed to update workflow: %v", err)
+	}
+	if updated.ID != wf.ID || updated.Name != "Build and Test" || updated.Version != 2 || updated.UpdatedBy != "bob" {
+		t.Errorf("Expected version 2 of %s by bob, got %+v", wf.ID, updated)
+	}
+	second, err := srv.TriggerWorkflow(context.Background(), wf.ID, TriggerOptions{})
+	if err != nil {
+		t.Fatalf("Failed to trigger workflow: %v", err)
+	}
+
+	if run := waitForRun(t, srv, first.ID); run.WorkflowVersion != 1 {
+		t.Errorf("Expected the first run to execute version 1, got %d", run.WorkflowVersion)
+	}
+	if run := waitForRun(t, srv, second.ID); run.WorkflowVersion != 2 {
+		t.Errorf("Expected the second run to execute version 2, got %d", run.WorkflowVersion)
+	}
+
+	revisions, err := srv.ListWorkflowRevisions("Build and Test")
+	if err != nil {
+		t.Fatalf("Failed to list revisions: %v", err)
+	}
+	if len(revisions) != 2 || revisions[0].Version != 2 || revisions[1].Version != 1 {
+		t.Fatalf("Expected versions 2 and 1, got %+v", revisions)
+	}
+	if revisions[1].Name != "Build" || revisions[1].UpdatedBy != "alice" || revisions[1].Source != fmt.Sprintf(source, "Build") {
+		t.Errorf("Expected version 1 to be alice's Build, got %+v", revisions[1])
+	}
+
+	if _, err := srv.ParseAndSaveWorkflow([]byte(fmt.Sprintf(source, "Deploy")), ""); err != nil {
+		t.Fatalf("Failed to save workflow: %v", err)
+	}
+	if _, err := srv.UpdateWorkflow(wf.ID, []byte(fmt.Sprintf(source, "Deploy")), ""); !errors.Is(err, storage.ErrWorkflowExists) {
+		t.Errorf("Expected ErrWorkflowExists, got %v", err)
+	}
+	if _, err := srv.UpdateWorkflow("Missing", []byte(fmt.Sprintf(source, "Missing")), ""); !errors.Is(err, ErrWorkflowNotFound) {
+		t.Errorf("Expected ErrWorkflowNotFound, got %v", err)
+	}
+	if _, err := srv.UpdateWorkflow(wf.ID, []byte("name: Build\n"), ""); err == nil {
+		t.Error("Expected an invalid workflow to be refused")
+	}
+	if current, _ := srv.GetWorkflow(wf.ID); current.Version != 2 {
+		t.Errorf("Expected refused updates to leave version 2, got %d", current.Version)
+	}
+}
+
+// slowStorage returns workflows a while after reading them, as a database
+// does, so that concurrent saves overlap
+type slowStorage struct {
+	*storage.MemoryStorage
+}
+
+func (s *slowStorage) GetWorkflow(id string) (*models.Workflow, error) {
+	wf, err := s.MemoryStorage.GetWorkflow(id)
+	time.Sleep(time.Millisecond)
+	return wf, err
+}
+
+func TestServer_UpdateWorkflow_Concurrent(t *testing.T) {
+	srv := &Server{
+		storage: &slowStorage{MemoryStorage: storage.NewMemoryStorage()},
+		parser:  parser.NewParser(),
+	}
+
+	source := "name: Build\njobs:\n  test:\n    runs-on: ubuntu\n    steps:\n      - name: Test\n        run: echo %d\n"
+	if _, err := srv.ParseAndSaveWorkflow([]byte(fmt.Sprintf(source, 0)), ""); err != nil {
+		t.Fatalf("Failed to save workflow: %v", err)
+	}
+
+	const updates = 20
+	var wg sync.WaitGroup
+	for i := 1; i <= updates; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := srv.UpdateWorkflow("Build", []byte(fmt.Sprintf(source, i)), ""); err != nil {
+				t.Errorf("Failed to update workflow: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	revisions, err := srv.ListWorkflowRevisions("Build")
+	if err != nil {
+		t.Fatalf("Failed to list revisions: %v", err)
+	}
+	if len(revisions) != updates+1 {
+		t.Fatalf("Expected %d revisions, got %d", updates+1, len(revisions))
+	}
+	for i, rev := range revisions {
+		if want := updates + 1 - i; rev.Version != want {
+			t.Errorf("Expected revision %d to be version %d, got %d", i, want, rev.Version)
+		}
+	}
+}
+
+// unreachableStorage fails to look workflows up, as a database that is down
+type unreachableStorage struct {
+	*storage.MemoryStorage
+}
+
+func (s *unreachableStorage) GetWorkflowByName(name string) (*models.Workflow, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestServer_ParseAndSaveWorkflow_LookupFails(t *testing.T) {
+	store := &unreachableStorage{MemoryStorage: storage.NewMemoryStorage()}
+	srv := &Server{
+		storage: store,
+		parser:  parser.NewParser(),
+	}
+
+	source := []byte("name: Build\njobs:\n  test:\n    runs-on: ubuntu\n    steps:\n      - name: Test\n        run: echo test\n")
+	if _, err := srv.ParseAndSaveWorkflow(source, ""); err == nil {
+		t.Fatal("Expected a failed lookup to fail the save")
+	}
+	if _, total, _ := store.ListWorkflows(storage.ListOptions{}); total != 0 {
+		t.Errorf("Expected nothing to be saved as version 1, got %d workflows", total)
+	}
+}
+
+func TestServer_WorkflowSource(t *testing.T) {
+	srv := &Server{
+		storage: storage.NewMemoryStorage(),
+		parser:  parser.NewParser(),
+	}
+
+	first := "# Builds on every push\nname: Build\njobs:\n  test:\n    runs-on: ubuntu\n    steps:\n      - name: Test\n        run: echo test\n"
+	second := strings.Replace(first, "echo test", "echo test  # quietly", 1)
+	wf, err := srv.ParseAndSaveWorkflow([]byte(first), "")
+	if err != nil {
+		t.Fatalf("Failed to save workflow: %v", err)
+	}
+	if _, err := srv.UpdateWorkflow(wf.ID, []byte(second), ""); err != nil {
+		t.Fatalf("Failed to update workflow: %v", err)
+	}
+
+	tests := []struct {
+		version int
+		want    string
+	}{
+		{0, second},
+		{2, second},
+		{1, first},
+	}
+	for _, tt := range tests {
+		got, err := srv.WorkflowSource("Build", tt.version)
+		if err != nil || got != tt.want {
+			t.Errorf("Expected version %d to be %q, got %q (%v)", tt.version, tt.want, got, err)
+		}
+	}
+
+	if _, err := srv.WorkflowSource("Build", 3); !errors.Is(err, ErrWorkflowNotFound) {
+		t.Errorf("Expected ErrWorkflowNotFound for a missing version, got %v", err)
+	}
+	if _, err := srv.WorkflowSource("Missing", 0); !errors.Is(err, ErrWorkflowNotFound) {
+		t.Errorf("Expected ErrWorkflowNotFound for a missing workflow, got %v", err)
+	}
+
+	if err := srv.storage.SaveWorkflow(&models.Workflow{Name: "Legacy"}); err != nil {
+		t.Fatalf("Failed to save workflow: %v", err)
+	}
+	if _, err := srv.WorkflowSource("Legacy", 0); !errors.Is(err, ErrNoSource) {
+		t.Errorf("Expected ErrNoSource for a workflow without YAML, got %v", err)
+	}
+}
+
 func TestServer_GetRun(t *testing.T) {
 	srv := &Server{
 		storage: storage.NewMemoryStorage(),
@@ -340,7 +792,7 @@ func TestServer_ListRuns(t *testing.T) {
 	}
 
 	// List runs
-	runs, err := srv.ListRuns()
+	runs, _, err := srv.ListRuns(storage.RunFilter{}, storage.ListOptions{})
 	if err != nil {
 		t.Fatalf("Failed to list runs: %v", err)
 	}
@@ -349,3 +801,94 @@ func TestServer_ListRuns(t *testing.T) {
 		t.Errorf("Expected 3 runs, got %d", len(runs))
 	}
 }
+
+func TestServer_ListRuns_ByWorkflowName(t *testing.T) {
+	srv := &Server{storage: storage.NewMemoryStorage(), parser: parser.NewParser()}
+	wf := &models.Workflow{Name: "Build and Test"}
+	if err := srv.storage.SaveWorkflow(wf); err != nil {
+		t.Fatalf("Failed to save workflow: %v", err)
+	}
+	for _, workflowID := range []string{wf.ID, "other"} {
+		run := &models.WorkflowRun{ID: "run-" + workflowID, WorkflowID: workflowID, Jobs: map[string]models.Job{}}
+		if err := srv.storage.SaveRun(run); err != nil {
+			t.Fatalf("Failed to save run: %v", err)
+		}
+	}
+
+	runs, total, err := srv.ListRuns(storage.RunFilter{WorkflowID: "Build and Test"}, storage.ListOptions{})
+	if err != nil || len(runs) != 1 || total != 1 || runs[0].WorkflowID != wf.ID {
+		t.Errorf("Expected the one run of the workflow named, got %d (%v)", len(runs), err)
+	}
+	if _, _, err := srv.ListRuns(storage.RunFilter{WorkflowID: "Missing"}, storage.ListOptions{}); !errors.Is(err, ErrWorkflowNotFound) {
+		t.Errorf("Expected ErrWorkflowNotFound, got %v", err)
+	}
+}
+
+func TestServer_DeleteRun(t *testing.T) {
+	srv := &Server{storage: storage.NewMemoryStorage(), parser: parser.NewParser()}
+	for _, id := range []string{"run-done", "run-active"} {
+		if err := srv.storage.SaveRun(&models.WorkflowRun{ID: id, Jobs: map[string]models.Job{}}); err != nil {
+			t.Fatalf("Failed to save run: %v", err)
+		}
+	}
+	active := &models.WorkflowRun{ID: "run-active"}
+	srv.trackRun(active, func(error) {})
+	defer srv.untrackRun(active.ID)
+
+	if err := srv.DeleteRun("run-done"); err != nil {
+		t.Fatalf("Failed to delete run: %v", err)
+	}
+	if _, err := srv.GetRun("run-done"); err == nil {
+		t.Error("Expected the run to be deleted")
+	}
+
+	if err := srv.DeleteRun("run-active"); !errors.Is(err, ErrRunActive) {
+		t.Errorf("Expected ErrRunActive, got %v", err)
+	}
+	if _, err := srv.GetRun("run-active"); err != nil {
+		t.Errorf("Expected the running run to be kept, got %v", err)
+	}
+}
+
+func TestServer_DeleteRuns(t *testing.T) {
+	srv := &Server{storage: storage.NewMemoryStorage(), parser: parser.NewParser()}
+	for id, status := range map[string]string{
+		"run-1": failedStatus,
+		"run-2": failedStatus,
+		"run-3": successStatus,
+		"run-4": failedStatus,
+	} {
+		if err := srv.storage.SaveRun(&models.WorkflowRun{ID: id, Status: status, Jobs: map[string]models.Job{}}); err != nil {
+			t.Fatalf("Failed to save run: %v", err)
+		}
+	}
+	active := &models.WorkflowRun{ID: "run-4"}
+	srv.trackRun(active, func(error) {})
+	defer srv.untrackRun(active.ID)
+
+	deleted, err := srv.DeleteRuns(storage.RunFilter{Statuses: []string{failedStatus}})
+	if err != nil || deleted != 2 {
+		t.Fatalf("Expected the 2 failed runs to be deleted, got %d (%v)", deleted, err)
+	}
+
+	runs, _, err := srv.ListRuns(storage.RunFilter{}, storage.ListOptions{})
+	if err != nil || len(runs) != 2 {
+		t.Errorf("Expected the successful and the running run to be kept, got %d (%v)", len(runs), err)
+	}
+}
+
+func TestNewExecutor_Shell(t *testing.T) {
+	exec, err := newExecutor(&Config{ExecutorType: "shell"})
+	if err != nil {
+		t.Fatalf("Expected the shell executor without Docker, got %v", err)
+	}
+	if _, ok := exec.(*executor.ShellExecutor); !ok {
+		t.Errorf("Expected a shell executor, got %T", exec)
+	}
+}
+
+func TestNewExecutor_UnknownType(t *testing.T) {
+	if _, err := newExecutor(&Config{ExecutorType: "nomad"}); err == nil || !strings.Contains(err.Error(), "unknown executor type 'nomad'") {
+		t.Errorf("Expected unknown executor type error, got %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"fmt"
+
+	"gantry/internal/expr"
+	"gantry/internal/models"
+)
+
+// checkUsers returns an error for the first job that would run as root,
+// when the server forbids it. Jobs without a user run as JOB_USER, or else
+// as their image's user, which can't be told apart from root here. Users
+// set by expressions are checked once resolved, when the job starts.
+func (s *Server) checkUsers(jobs ...models.Job) error {
+	if !s.forbidRoot {
+		return nil
+	}
+	for _, job := range jobs {
+		user := job.User
+		if user == "" && job.Container != nil {
+			user = job.Container.User
+		}
+		if user == "" {
+			user = s.jobUser
+		}
+
+		switch {
+		case user == "":
+			return fmt.Errorf("jobs must set a non-root user on this server (set user, or JOB_USER)")
+		case expr.HasExpressions(user):
+			continue
+		case models.IsRootUser(user):
+			return fmt.Errorf("job runs as root, which this server forbids (FORBID_ROOT_JOBS)")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"gantry/internal/models"
+)
+
+func TestCheckUsers(t *testing.T) {
+	srv := newSchedulerTestServer(&fakeExecutor{})
+
+	root := testJob()
+	root.User = "0:0"
+	if err := srv.checkUsers(root); err != nil {
+		t.Errorf("Expected root to be allowed by default, got %v", err)
+	}
+
+	srv.forbidRoot = true
+	if err := srv.checkUsers(root); err == nil || !strings.Contains(err.Error(), "root") {
+		t.Errorf("Expected a root job to be rejected, got %v", err)
+	}
+	if err := srv.checkUsers(testJob()); err == nil || !strings.Contains(err.Error(), "JOB_USER") {
+		t.Errorf("Expected a job without a user to be rejected, got %v", err)
+	}
+	container := testJob()
+	container.Container = &models.Container{Image: "node:20", User: "root"}
+	if err := srv.checkUsers(container); err == nil {
+		t.Error("Expected a container running as root to be rejected, got nil")
+	}
+	templated := testJob()
+	templated.User = "${{ matrix.user }}"
+	if err := srv.checkUsers(templated); err != nil {
+		t.Errorf("Expected a templated user to wait until the job starts, got %v", err)
+	}
+
+	srv.jobUser = "1000:1000"
+	if err := srv.checkUsers(testJob()); err != nil {
+		t.Errorf("Expected a job to run as the default user, got %v", err)
+	}
+}
+
+func TestRunJob_ResolvesUser(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+	srv.forbidRoot = true
+
+	job := matrixJob("1000", "0")
+	job.User = "${{ matrix.n }}"
+	wf := &models.Workflow{Name: testWorkflowName, Jobs: map[string]models.Job{"test": job}}
+
+	run := runWorkflowSync(t, srv, wf)
+	if recorded, _ := run.GetJob("test (1000)"); exec.jobs["test (1000)"].User != "1000" {
+		t.Errorf("Expected the job to run as 1000, got '%s': %s", exec.jobs["test (1000)"].User, recorded.Output)
+	}
+	if recorded, _ := run.GetJob("test (0)"); recorded.Status != failedStatus || !strings.Contains(recorded.Output, "root") {
+		t.Errorf("Expected the root leg to fail, got '%s': %s", recorded.Status, recorded.Output)
+	}
+}
@@ -0,0 +1,30 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"gantry/internal/models"
+)
+
+// ErrInvalidVariables is returned when trigger variables override a variable
+// the workflow doesn't declare
+var ErrInvalidVariables = errors.New("invalid variables")
+
+// resolveVariables applies trigger-time overrides to the workflow's declared
+// variables
+func resolveVariables(wf *models.Workflow, overrides map[string]string) (map[string]string, error) {
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, exists := wf.Variables[name]; !exists {
+			return nil, fmt.Errorf("%w: workflow has no variable '%s'", ErrInvalidVariables, name)
+		}
+	}
+
+	return mergeEnv(wf.Variables, overrides), nil
+}
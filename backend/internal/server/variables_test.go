@@ -0,0 +1,60 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gantry/internal/models"
+)
+
+func variablesWorkflow() *models.Workflow {
+	job := testJob()
+	job.Steps[0].Run = "docker push ${{ vars.REGISTRY }}/app:${{ vars.IMAGE_TAG }}"
+	return &models.Workflow{
+		Name:      testWorkflowName,
+		Variables: map[string]string{"REGISTRY": "registry.local", "IMAGE_TAG": "latest"},
+		Jobs:      map[string]models.Job{"push": job},
+		JobOrder:  []string{"push"},
+	}
+}
+
+func TestResolveVariables(t *testing.T) {
+	vars, err := resolveVariables(variablesWorkflow(), map[string]string{"IMAGE_TAG": "v2"})
+	if err != nil {
+		t.Fatalf("resolveVariables returned error: %v", err)
+	}
+	if vars["IMAGE_TAG"] != "v2" || vars["REGISTRY"] != "registry.local" {
+		t.Errorf("Expected the override on top of the declared variables, got %v", vars)
+	}
+
+	if _, err := resolveVariables(variablesWorkflow(), map[string]string{"REGION": "eu"}); !errors.Is(err, ErrInvalidVariables) {
+		t.Errorf("Expected ErrInvalidVariables for an undeclared variable, got %v", err)
+	}
+}
+
+func TestServer_TriggerWorkflow_ExposesVariables(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+	if err := srv.storage.SaveWorkflow(variablesWorkflow()); err != nil {
+		t.Fatalf("Failed to save workflow: %v", err)
+	}
+
+	run, err := srv.TriggerWorkflow(context.Background(), testWorkflowName, TriggerOptions{
+		Variables: map[string]string{"IMAGE_TAG": "1.4.0"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to trigger workflow: %v", err)
+	}
+	if run.Variables["IMAGE_TAG"] != "1.4.0" {
+		t.Errorf("Expected variables to be recorded on the run, got %v", run.Variables)
+	}
+
+	waitForRun(t, srv, run.ID)
+
+	exec.mu.Lock()
+	defer exec.mu.Unlock()
+	if got := exec.jobs["push"].Steps[0].Run; got != "docker push registry.local/app:1.4.0" {
+		t.Errorf("Expected variables to be interpolated, got %q", got)
+	}
+}
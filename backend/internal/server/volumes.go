@@ -0,0 +1,61 @@
+package server
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"gantry/internal/models"
+)
+
+// parseVolumePaths splits the comma-separated host paths jobs may mount
+func parseVolumePaths(list string) ([]string, error) {
+	var paths []string
+	for _, p := range strings.Split(list, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if !path.IsAbs(p) {
+			return nil, fmt.Errorf("volume path '%s' must be absolute", p)
+		}
+		paths = append(paths, path.Clean(p))
+	}
+	return paths, nil
+}
+
+// checkVolumes returns an error naming the first host path a job mounts that
+// isn't on or below one of the allowed volume paths. Named volumes are
+// always allowed.
+func (s *Server) checkVolumes(jobs ...models.Job) error {
+	for _, job := range jobs {
+		for _, spec := range job.Volumes {
+			v, err := models.ParseVolume(spec)
+			if err != nil {
+				return err
+			}
+			if v.HostPath() && !s.volumeAllowed(v.Source) {
+				return fmt.Errorf("host path '%s' may not be mounted (allowed: %s)", v.Source, s.allowedVolumePaths())
+			}
+		}
+	}
+	return nil
+}
+
+// volumeAllowed reports whether a host path is on or below an allowed path
+func (s *Server) volumeAllowed(hostPath string) bool {
+	for _, allowed := range s.volumePaths {
+		if hostPath == allowed || allowed == "/" || strings.HasPrefix(hostPath, allowed+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedVolumePaths lists the allowed volume paths for error messages
+func (s *Server) allowedVolumePaths() string {
+	if len(s.volumePaths) == 0 {
+		return "none, set ALLOWED_VOLUME_PATHS"
+	}
+	return strings.Join(s.volumePaths, ", ")
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"gantry/internal/models"
+)
+
+func TestParseVolumePaths(t *testing.T) {
+	paths, err := parseVolumePaths(" /srv/cache, /data/ ,")
+	if err != nil || len(paths) != 2 || paths[0] != "/srv/cache" || paths[1] != "/data" {
+		t.Errorf("parseVolumePaths() = %v, %v; want [/srv/cache /data]", paths, err)
+	}
+	if _, err := parseVolumePaths("/srv,relative"); err == nil {
+		t.Error("Expected error for a relative path, got nil")
+	}
+}
+
+func TestCheckVolumes(t *testing.T) {
+	srv := newSchedulerTestServer(&fakeExecutor{})
+	srv.volumePaths = []string{"/srv/cache"}
+
+	for _, spec := range []string{"/srv/cache:/cache", "/srv/cache/go:/go/pkg:ro", "gomod:/go/pkg/mod"} {
+		if err := srv.checkVolumes(models.Job{Volumes: []string{spec}}); err != nil {
+			t.Errorf("Expected %s to be allowed, got %v", spec, err)
+		}
+	}
+	for _, spec := range []string{"/srv/cachex:/cache", "/etc:/host-etc", "/srv/cache/../../etc:/etc"} {
+		err := srv.checkVolumes(models.Job{Volumes: []string{spec}})
+		if err == nil || !strings.Contains(err.Error(), "may not be mounted") {
+			t.Errorf("Expected %s to be rejected, got %v", spec, err)
+		}
+	}
+
+	srv.volumePaths = nil
+	if err := srv.checkVolumes(models.Job{Volumes: []string{"/srv/cache:/cache"}}); err == nil || !strings.Contains(err.Error(), "ALLOWED_VOLUME_PATHS") {
+		t.Errorf("Expected host paths to be rejected without allowed paths, got %v", err)
+	}
+}
+
+func TestRunJob_RejectsDisallowedVolume(t *testing.T) {
+	exec := &fakeExecutor{}
+	srv := newSchedulerTestServer(exec)
+
+	job := testJob()
+	job.Volumes = []string{"/var/run/docker.sock:/var/run/docker.sock"}
+	wf := &models.Workflow{Name: testWorkflowName, Jobs: map[string]models.Job{"build": job}}
+
+	run := runWorkflowSync(t, srv, wf)
+
+	recorded, _ := run.GetJob("build")
+	if recorded.Status != failedStatus || !strings.Contains(recorded.Output, "may not be mounted") {
+		t.Errorf("Expected the job to fail on its volume, got '%s': %s", recorded.Status, recorded.Output)
+	}
+	if len(exec.order) != 0 {
+		t.Errorf("Expected the job not to run, got %v", exec.order)
+	}
+}
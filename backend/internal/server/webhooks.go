@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"gantry/internal/models"
+)
+
+// Errors of receiving webhooks
+var (
+	ErrWebhookDisabled  = errors.New("webhook is not enabled")
+	ErrInvalidSignature = errors.New("invalid signature")
+
+	// ErrIgnoredEvent is returned for webhook deliveries that are valid but
+	// don't trigger anything, such as a deleted branch
+	ErrIgnoredEvent = errors.New("ignored")
+)
+
+// validSignature reports whether signature is the hex-encoded HMAC-SHA256
+// of payload under secret
+func validSignature(secret string, payload []byte, signature string) bool {
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(got, sign(secret, payload))
+}
+
+// sign returns the HMAC-SHA256 of payload under secret
+func sign(secret string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// checkWebhook checks that the secret a workflow's webhook trigger is
+// signed with exists
+func (s *Server) checkWebhook(wf *models.Workflow) error {
+	hook := wf.On.Webhook
+	if hook == nil {
+		return nil
+	}
+	if s.secrets == nil {
+		return fmt.Errorf("webhook secret '%s' is not defined", hook.Secret)
+	}
+	if _, exists := s.secrets.Get(hook.Secret); !exists {
+		return fmt.Errorf("webhook secret '%s' is not defined", hook.Secret)
+	}
+	return nil
+}
+
+// TriggerWebhook starts a run of the workflow with the given ID for a
+// delivery to its webhook. The signature is "sha256=" and the HMAC of the
+// payload under the secret the workflow's webhook trigger names, and the
+// payload, a JSON object, is recorded on the run for its jobs to read.
+// Workflows without a webhook trigger return ErrWebhookDisabled, as do
+// unknown IDs.
+func (s *Server) TriggerWebhook(ctx context.Context, workflowID string, payload []byte, signature string) (*models.WorkflowRun, error) {
+	wf, err := s.storage.GetWorkflow(workflowID)
+	if err != nil || wf.On.Webhook == nil {
+		return nil, fmt.Errorf("workflow %w", ErrWebhookDisabled)
+	}
+
+	var secret string
+	if s.secrets != nil {
+		secret, _ = s.secrets.Get(wf.On.Webhook.Secret)
+	}
+	if secret == "" {
+		return nil, fmt.Errorf("%w: secret '%s' is not defined", ErrWebhookDisabled, wf.On.Webhook.Secret)
+	}
+	mac, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok || !validSignature(secret, payload, mac) {
+		return nil, ErrInvalidSignature
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, fmt.Errorf("%w: payload must be a JSON object: %v", ErrInvalidEvent, err)
+	}
+
+	log.Printf("Webhook triggered workflow %s", wf.Name)
+	trigger := &models.TriggerInfo{Event: models.EventWebhook, Payload: fields}
+	return s.executeWorkflow(ctx, wf, trigger, runParams{variables: wf.Variables}, "")
+}
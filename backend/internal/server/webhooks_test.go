@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gantry/internal/models"
+	"gantry/internal/secrets"
+)
+
+func TestServer_TriggerWebhook(t *testing.T) {
+	job := testJob()
+	job.Steps[0].Run = "deploy ${{ gantry.payload.release.tag }} for ${{ gantry.event }}"
+	hooked := &models.Workflow{
+		ID:       "wf-hooked",
+		Name:     "release",
+		On:       models.TriggerConfig{Webhook: &models.WebhookConfig{Secret: "RELEASE_HOOK"}},
+		Jobs:     map[string]models.Job{"test": job},
+		JobOrder: []string{"test"},
+	}
+	plain := &models.Workflow{ID: "wf-plain", Name: "plain", Jobs: map[string]models.Job{"test": testJob()}, JobOrder: []string{"test"}}
+
+	exec := &fakeExecutor{}
+	srv := newEventTestServer(t, exec, hooked, plain)
+	store := secrets.NewMemoryStore()
+	store.Set("RELEASE_HOOK", "s3cret")
+	srv.secrets = store
+
+	payload := `{"release": {"tag": "v1.4.0"}}`
+	run, err := srv.TriggerWebhook(context.Background(), "wf-hooked", []byte(payload), githubSignature("s3cret", payload))
+	if err != nil {
+		t.Fatalf("TriggerWebhook returned error: %v", err)
+	}
+	if run.Trigger == nil || run.Trigger.Event != models.EventWebhook {
+		t.Errorf("Unexpected trigger recorded: %+v", run.Trigger)
+	}
+
+	waitForRun(t, srv, run.ID)
+	exec.mu.Lock()
+	got := exec.jobs["test"].Steps[0].Run
+	exec.mu.Unlock()
+	if got != "deploy v1.4.0 for webhook" {
+		t.Errorf("Expected the payload in context, got %q", got)
+	}
+
+	tests := []struct {
+		name      string
+		workflow  string
+		payload   string
+		signature string
+		wantErr   error
+	}{
+		{name: "wrong secret", workflow: "wf-hooked", payload: `{}`, signature: githubSignature("guess", `{}`), wantErr: ErrInvalidSignature},
+		{name: "unsigned", workflow: "wf-hooked", payload: `{}`, wantErr: ErrInvalidSignature},
+		{name: "not an object", workflow: "wf-hooked", payload: `[1]`, signature: githubSignature("s3cret", `[1]`), wantErr: ErrInvalidEvent},
+		{name: "no webhook trigger", workflow: "wf-plain", payload: `{}`, signature: githubSignature("s3cret", `{}`), wantErr: ErrWebhookDisabled},
+		{name: "by name", workflow: "release", payload: `{}`, signature: githubSignature("s3cret", `{}`), wantErr: ErrWebhookDisabled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := srv.TriggerWebhook(context.Background(), tt.workflow, []byte(tt.payload), tt.signature)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	store.Delete("RELEASE_HOOK")
+	if _, err := srv.TriggerWebhook(context.Background(), "wf-hooked", []byte(payload), githubSignature("s3cret", payload)); !errors.Is(err, ErrWebhookDisabled) {
+		t.Errorf("Expected ErrWebhookDisabled once the secret is gone, got %v", err)
+	}
+	if err := srv.checkWebhook(hooked); err == nil {
+		t.Error("Expected an error for a webhook secret that isn't defined")
+	}
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	"gantry/internal/models"
@@ -12,6 +13,14 @@ type MemoryStorage struct {
 	workflows    map[string]*models.Workflow
 	workflowRuns map[string]*models.WorkflowRun
 	mu           sync.RWMutex
+
+	revisions map[string][]*models.WorkflowRevision // by workflow ID, oldest first
+
+	users map[string]*models.User // by username
+	audit []*models.AuditEntry    // oldest first
+
+	hooks        []*models.Hook        // oldest first
+	hookFailures []*models.HookFailure // oldest first
 }
 
 // NewMemoryStorage creates a new in-memory storage
@@ -19,31 +28,69 @@ func NewMemoryStorage() *MemoryStorage {
 	return &MemoryStorage{
 		workflows:    make(map[string]*models.Workflow),
 		workflowRuns: make(map[string]*models.WorkflowRun),
+		revisions:    make(map[string][]*models.WorkflowRevision),
+		users:        make(map[string]*models.User),
 	}
 }
 
-// SaveWorkflow saves a workflow
+// SaveWorkflow saves a workflow, assigning an ID to new workflows
 func (s *MemoryStorage) SaveWorkflow(wf *models.Workflow) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.workflows[wf.Name] = wf
+
+	existing := s.workflowByName(wf.Name)
+	if wf.ID == "" {
+		if existing != nil {
+			wf.ID = existing.ID
+		} else {
+			wf.ID, _ = newWorkflowID(wf.Name, func(id string) (bool, error) {
+				_, taken := s.workflows[id]
+				return taken, nil
+			})
+		}
+	} else if existing != nil && existing.ID != wf.ID {
+		return fmt.Errorf("%w: '%s'", ErrWorkflowExists, wf.Name)
+	}
+
+	s.workflows[wf.ID] = wf
 	return nil
 }
 
-// GetWorkflow retrieves a workflow by name
-func (s *MemoryStorage) GetWorkflow(name string) (*models.Workflow, error) {
+// GetWorkflow retrieves a workflow by ID
+func (s *MemoryStorage) GetWorkflow(id string) (*models.Workflow, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	wf, exists := s.workflows[name]
+	wf, exists := s.workflows[id]
 	if !exists {
-		return nil, fmt.Errorf("workflow '%s' not found", name)
+		return nil, fmt.Errorf("%w: '%s'", ErrWorkflowNotFound, id)
 	}
 	return wf, nil
 }
 
-// ListWorkflows returns all workflows
-func (s *MemoryStorage) ListWorkflows() ([]*models.Workflow, error) {
+// GetWorkflowByName retrieves a workflow by name
+func (s *MemoryStorage) GetWorkflowByName(name string) (*models.Workflow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	wf := s.workflowByName(name)
+	if wf == nil {
+		return nil, fmt.Errorf("%w: '%s'", ErrWorkflowNotFound, name)
+	}
+	return wf, nil
+}
+
+func (s *MemoryStorage) workflowByName(name string) *models.Workflow {
+	for _, wf := range s.workflows {
+		if wf.Name == name {
+			return wf
+		}
+	}
+	return nil
+}
+
+// ListWorkflows returns a page of the workflows, ordered by name
+func (s *MemoryStorage) ListWorkflows(opts ListOptions) ([]*models.Workflow, int, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -51,21 +98,49 @@ func (s *MemoryStorage) ListWorkflows() ([]*models.Workflow, error) {
 	for _, wf := range s.workflows {
 		workflows = append(workflows, wf)
 	}
-	return workflows, nil
+	sort.Slice(workflows, func(i, j int) bool {
+		return workflows[i].Name < workflows[j].Name
+	})
+	start, end := opts.page(len(workflows))
+	return workflows[start:end], len(workflows), nil
 }
 
-// DeleteWorkflow deletes a workflow
-func (s *MemoryStorage) DeleteWorkflow(name string) error {
+// DeleteWorkflow deletes a workflow by ID
+func (s *MemoryStorage) DeleteWorkflow(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, exists := s.workflows[name]; !exists {
-		return fmt.Errorf("workflow '%s' not found", name)
+	if _, exists := s.workflows[id]; !exists {
+		return fmt.Errorf("%w: '%s'", ErrWorkflowNotFound, id)
 	}
-	delete(s.workflows, name)
+	delete(s.workflows, id)
+	delete(s.revisions, id)
+	return nil
+}
+
+// SaveWorkflowRevision records a version of a workflow
+func (s *MemoryStorage) SaveWorkflowRevision(rev *models.WorkflowRevision) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	saved := *rev
+	s.revisions[rev.WorkflowID] = append(s.revisions[rev.WorkflowID], &saved)
 	return nil
 }
 
+// ListWorkflowRevisions returns the versions of a workflow, newest first
+func (s *MemoryStorage) ListWorkflowRevisions(workflowID string) ([]*models.WorkflowRevision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored := s.revisions[workflowID]
+	revisions := make([]*models.WorkflowRevision, len(stored))
+	for i, rev := range stored {
+		found := *rev
+		revisions[len(stored)-1-i] = &found
+	}
+	return revisions, nil
+}
+
 // SaveRun saves a workflow run
 func (s *MemoryStorage) SaveRun(run *models.WorkflowRun) error {
 	s.mu.Lock()
@@ -86,16 +161,31 @@ func (s *MemoryStorage) GetRun(id string) (*models.WorkflowRun, error) {
 	return run.Clone(), nil
 }
 
-// ListRuns returns all runs
-func (s *MemoryStorage) ListRuns() ([]*models.WorkflowRun, error) {
+// ListRuns returns a page of the runs filter matches, newest first
+func (s *MemoryStorage) ListRuns(filter RunFilter, opts ListOptions) ([]*models.WorkflowRun, int, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	runs := make([]*models.WorkflowRun, 0, len(s.workflowRuns))
+	all := make([]*models.WorkflowRun, 0, len(s.workflowRuns))
 	for _, run := range s.workflowRuns {
+		if filter.Matches(run) {
+			all = append(all, run)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if !all[i].StartedAt.Equal(all[j].StartedAt) {
+			return all[i].StartedAt.After(all[j].StartedAt)
+		}
+		return all[i].ID > all[j].ID
+	})
+
+	// Only the page is cloned, so a small page of many runs stays cheap
+	start, end := opts.page(len(all))
+	runs := make([]*models.WorkflowRun, 0, end-start)
+	for _, run := range all[start:end] {
 		runs = append(runs, run.Clone())
 	}
-	return runs, nil
+	return runs, len(all), nil
 }
 
 // UpdateRun updates an existing run
@@ -110,15 +200,170 @@ func (s *MemoryStorage) UpdateRun(run *models.WorkflowRun) error {
 	return nil
 }
 
+// DeleteRun deletes a run by ID
+func (s *MemoryStorage) DeleteRun(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.workflowRuns[id]; !exists {
+		return fmt.Errorf("run '%s' not found", id)
+	}
+	delete(s.workflowRuns, id)
+	return nil
+}
+
 // DeleteRunsByWorkflow deletes all runs for a workflow
-func (s *MemoryStorage) DeleteRunsByWorkflow(workflowName string) error {
+func (s *MemoryStorage) DeleteRunsByWorkflow(workflowID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	for id, run := range s.workflowRuns {
-		if run.WorkflowName == workflowName {
+		if run.WorkflowID == workflowID {
 			delete(s.workflowRuns, id)
 		}
 	}
 	return nil
 }
+
+// SaveUser adds a user
+func (s *MemoryStorage) SaveUser(user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.users[user.Username]; exists {
+		return fmt.Errorf("%w: '%s'", ErrUserExists, user.Username)
+	}
+	saved := *user
+	s.users[user.Username] = &saved
+	return nil
+}
+
+// GetUser retrieves a user by username
+func (s *MemoryStorage) GetUser(username string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, exists := s.users[username]
+	if !exists {
+		return nil, fmt.Errorf("%w: '%s'", ErrUserNotFound, username)
+	}
+	found := *user
+	return &found, nil
+}
+
+// UpdateUser saves changes to a user
+func (s *MemoryStorage) UpdateUser(user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.users[user.Username]; !exists {
+		return fmt.Errorf("%w: '%s'", ErrUserNotFound, user.Username)
+	}
+	saved := *user
+	s.users[user.Username] = &saved
+	return nil
+}
+
+// ListUsers returns every user, ordered by username
+func (s *MemoryStorage) ListUsers() ([]*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]*models.User, 0, len(s.users))
+	for _, user := range s.users {
+		found := *user
+		users = append(users, &found)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+	return users, nil
+}
+
+// CountUsers returns how many users there are
+func (s *MemoryStorage) CountUsers() (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.users), nil
+}
+
+// SaveAuditEntry adds an entry to the audit trail
+func (s *MemoryStorage) SaveAuditEntry(entry *models.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	saved := *entry
+	s.audit = append(s.audit, &saved)
+	return nil
+}
+
+// ListAuditEntries returns a page of the audit trail, newest first
+func (s *MemoryStorage) ListAuditEntries(opts ListOptions) ([]*models.AuditEntry, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	start, end := opts.page(len(s.audit))
+	entries := make([]*models.AuditEntry, 0, end-start)
+	for i := start; i < end; i++ {
+		entries = append(entries, s.audit[len(s.audit)-1-i])
+	}
+	return entries, len(s.audit), nil
+}
+
+// SaveHook adds an outgoing webhook
+func (s *MemoryStorage) SaveHook(hook *models.Hook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	saved := *hook
+	s.hooks = append(s.hooks, &saved)
+	return nil
+}
+
+// ListHooks returns every outgoing webhook, oldest first
+func (s *MemoryStorage) ListHooks() ([]*models.Hook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hooks := make([]*models.Hook, len(s.hooks))
+	for i, hook := range s.hooks {
+		found := *hook
+		hooks[i] = &found
+	}
+	return hooks, nil
+}
+
+// DeleteHook removes an outgoing webhook
+func (s *MemoryStorage) DeleteHook(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, hook := range s.hooks {
+		if hook.ID == id {
+			s.hooks = append(s.hooks[:i], s.hooks[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: '%s'", ErrHookNotFound, id)
+}
+
+// SaveHookFailure records a delivery that failed every attempt
+func (s *MemoryStorage) SaveHookFailure(failure *models.HookFailure) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	saved := *failure
+	s.hookFailures = append(s.hookFailures, &saved)
+	return nil
+}
+
+// ListHookFailures returns a page of the failed deliveries, newest first
+func (s *MemoryStorage) ListHookFailures(opts ListOptions) ([]*models.HookFailure, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	start, end := opts.page(len(s.hookFailures))
+	failures := make([]*models.HookFailure, 0, end-start)
+	for i := start; i < end; i++ {
+		found := *s.hookFailures[len(s.hookFailures)-1-i]
+		failures = append(failures, &found)
+	}
+	return failures, len(s.hookFailures), nil
+}
@@ -1,7 +1,9 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
@@ -24,8 +26,12 @@ func TestMemoryStorage_SaveAndGetWorkflow(t *testing.T) {
 		t.Fatalf("Failed to save workflow: %v", err)
 	}
 
+	if wf.ID != "test-workflow" {
+		t.Errorf("Expected ID 'test-workflow' derived from the name, got '%s'", wf.ID)
+	}
+
 	// Get
-	retrieved, err := store.GetWorkflow("Test Workflow")
+	retrieved, err := store.GetWorkflow("test-workflow")
 	if err != nil {
 		t.Fatalf("Failed to get workflow: %v", err)
 	}
@@ -53,7 +59,7 @@ func TestMemoryStorage_ListWorkflows(t *testing.T) {
 	_ = store.SaveWorkflow(wf1)
 	_ = store.SaveWorkflow(wf2)
 
-	workflows, err := store.ListWorkflows()
+	workflows, _, err := store.ListWorkflows(ListOptions{})
 	if err != nil {
 		t.Fatalf("Failed to list workflows: %v", err)
 	}
@@ -70,13 +76,13 @@ func TestMemoryStorage_DeleteWorkflow(t *testing.T) {
 	_ = store.SaveWorkflow(wf)
 
 	// Delete
-	err := store.DeleteWorkflow("Test")
+	err := store.DeleteWorkflow(wf.ID)
 	if err != nil {
 		t.Fatalf("Failed to delete workflow: %v", err)
 	}
 
 	// Verify deleted
-	_, err = store.GetWorkflow("Test")
+	_, err = store.GetWorkflow(wf.ID)
 	if err == nil {
 		t.Error("Expected error after deletion, got nil")
 	}
@@ -91,6 +97,45 @@ func TestMemoryStorage_DeleteNonExistent(t *testing.T) {
 	}
 }
 
+func TestMemoryStorage_WorkflowIDs(t *testing.T) {
+	store := NewMemoryStorage()
+
+	first := &models.Workflow{Name: "Build & Test", Jobs: map[string]models.Job{}}
+	clash := &models.Workflow{Name: "build-test", Jobs: map[string]models.Job{}}
+	_ = store.SaveWorkflow(first)
+	_ = store.SaveWorkflow(clash)
+	if first.ID != "build-test" || clash.ID != "build-test-2" {
+		t.Errorf("Expected IDs build-test and build-test-2, got %s and %s", first.ID, clash.ID)
+	}
+
+	// Saving under an existing name replaces that workflow
+	update := &models.Workflow{Name: "Build & Test", Jobs: map[string]models.Job{}}
+	if err := store.SaveWorkflow(update); err != nil || update.ID != first.ID {
+		t.Fatalf("Expected update to keep ID %s, got %s (%v)", first.ID, update.ID, err)
+	}
+	if workflows, _, _ := store.ListWorkflows(ListOptions{}); len(workflows) != 2 {
+		t.Errorf("Expected 2 workflows, got %d", len(workflows))
+	}
+
+	renamed := *update
+	renamed.Name = "Build"
+	if err := store.SaveWorkflow(&renamed); err != nil {
+		t.Fatalf("Failed to rename workflow: %v", err)
+	}
+	if wf, err := store.GetWorkflowByName("Build"); err != nil || wf.ID != first.ID {
+		t.Errorf("Expected renamed workflow under its ID, got %v (%v)", wf, err)
+	}
+	if _, err := store.GetWorkflowByName("Build & Test"); err == nil {
+		t.Error("Expected old name to be gone after rename")
+	}
+
+	taken := renamed
+	taken.Name = "build-test"
+	if err := store.SaveWorkflow(&taken); !errors.Is(err, ErrWorkflowExists) {
+		t.Errorf("Expected ErrWorkflowExists for another workflow's name, got %v", err)
+	}
+}
+
 func TestMemoryStorage_SaveAndGetRun(t *testing.T) {
 	store := NewMemoryStorage()
 
@@ -155,7 +200,7 @@ func TestMemoryStorage_ListRuns(t *testing.T) {
 	_ = store.SaveRun(run1)
 	_ = store.SaveRun(run2)
 
-	runs, err := store.ListRuns()
+	runs, _, err := store.ListRuns(RunFilter{}, ListOptions{})
 	if err != nil {
 		t.Fatalf("Failed to list runs: %v", err)
 	}
@@ -165,6 +210,247 @@ func TestMemoryStorage_ListRuns(t *testing.T) {
 	}
 }
 
+func TestMemoryStorage_ListRuns_Pages(t *testing.T) {
+	store := NewMemoryStorage()
+	start := time.Now()
+	for i := 1; i <= 5; i++ {
+		run := &models.WorkflowRun{ID: fmt.Sprintf("run-%d", i), Jobs: map[string]models.Job{}, StartedAt: start.Add(time.Duration(i) * time.Minute)}
+		_ = store.SaveRun(run)
+	}
+
+	tests := []struct {
+		opts ListOptions
+		want []string
+	}{
+		{ListOptions{}, []string{"run-5", "run-4", "run-3", "run-2", "run-1"}},
+		{ListOptions{Limit: 2}, []string{"run-5", "run-4"}},
+		{ListOptions{Offset: 2, Limit: 2}, []string{"run-3", "run-2"}},
+		{ListOptions{Offset: 4, Limit: 2}, []string{"run-1"}},
+		{ListOptions{Offset: 10}, nil},
+	}
+	for _, tt := range tests {
+		runs, total, err := store.ListRuns(RunFilter{}, tt.opts)
+		if err != nil {
+			t.Fatalf("Failed to list runs: %v", err)
+		}
+		var got []string
+		for _, run := range runs {
+			got = append(got, run.ID)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) || total != 5 {
+			t.Errorf("ListRuns(%+v) = %v of %d, want %v of 5", tt.opts, got, total, tt.want)
+		}
+	}
+}
+
+func TestMemoryStorage_ListRuns_Filter(t *testing.T) {
+	store := NewMemoryStorage()
+	now := time.Now()
+	runs := []*models.WorkflowRun{
+		{ID: "run-1", WorkflowID: "build", Status: "failed", StartedAt: now.Add(-48 * time.Hour), Trigger: &models.TriggerInfo{Branch: "main"}},
+		{ID: "run-2", WorkflowID: "build", Status: "failed", StartedAt: now.Add(-time.Hour), Trigger: &models.TriggerInfo{Branch: "main"}},
+		{ID: "run-3", WorkflowID: "build", Status: "success", StartedAt: now.Add(-time.Hour)},
+		{ID: "run-4", WorkflowID: "deploy", Status: "failed", StartedAt: now.Add(-time.Hour)},
+		{ID: "run-5", WorkflowID: "build", Status: "cancelled", StartedAt: now.Add(-time.Minute), Trigger: &models.TriggerInfo{Branch: "dev"}},
+	}
+	for _, run := range runs {
+		run.Jobs = map[string]models.Job{}
+		_ = store.SaveRun(run)
+	}
+
+	tests := []struct {
+		filter RunFilter
+		want   []string
+	}{
+		{RunFilter{WorkflowID: "build", Statuses: []string{"failed"}, Since: now.Add(-24 * time.Hour)}, []string{"run-2"}},
+		{RunFilter{Statuses: []string{"failed", "cancelled"}}, []string{"run-5", "run-4", "run-2", "run-1"}},
+		{RunFilter{Until: now.Add(-time.Hour)}, []string{"run-1"}},
+		{RunFilter{Branch: "main"}, []string{"run-2", "run-1"}},
+	}
+	for _, tt := range tests {
+		got, total, err := store.ListRuns(tt.filter, ListOptions{})
+		if err != nil {
+			t.Fatalf("Failed to list runs: %v", err)
+		}
+		var ids []string
+		for _, run := range got {
+			ids = append(ids, run.ID)
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(tt.want) || total != len(tt.want) {
+			t.Errorf("ListRuns(%+v) = %v of %d, want %v", tt.filter, ids, total, tt.want)
+		}
+	}
+}
+
+func TestMemoryStorage_ListWorkflows_Pages(t *testing.T) {
+	store := NewMemoryStorage()
+	for _, name := range []string{"Deploy", "Build", "Test"} {
+		_ = store.SaveWorkflow(&models.Workflow{Name: name})
+	}
+
+	workflows, total, err := store.ListWorkflows(ListOptions{Offset: 1, Limit: 1})
+	if err != nil {
+		t.Fatalf("Failed to list workflows: %v", err)
+	}
+	if len(workflows) != 1 || workflows[0].Name != "Deploy" || total != 3 {
+		t.Errorf("Expected the second workflow by name of 3, got %v of %d", workflows, total)
+	}
+}
+
+func TestMemoryStorage_DeleteRun(t *testing.T) {
+	store := NewMemoryStorage()
+	_ = store.SaveRun(&models.WorkflowRun{ID: "run-1", Jobs: map[string]models.Job{}})
+
+	if err := store.DeleteRun("run-1"); err != nil {
+		t.Fatalf("Failed to delete run: %v", err)
+	}
+	if _, err := store.GetRun("run-1"); err == nil {
+		t.Error("Expected error after deletion, got nil")
+	}
+	if err := store.DeleteRun("run-1"); err == nil {
+		t.Error("Expected error when deleting non-existent run, got nil")
+	}
+}
+
+func TestMemoryStorage_Users(t *testing.T) {
+	store := NewMemoryStorage()
+
+	if err := store.SaveUser(&models.User{Username: "alice", PasswordHash: "hash"}); err != nil {
+		t.Fatalf("Failed to save user: %v", err)
+	}
+	if err := store.SaveUser(&models.User{Username: "alice"}); !errors.Is(err, ErrUserExists) {
+		t.Errorf("Expected ErrUserExists, got %v", err)
+	}
+
+	user, err := store.GetUser("alice")
+	if err != nil || user.PasswordHash != "hash" {
+		t.Errorf("Expected the saved user, got %+v (%v)", user, err)
+	}
+	if _, err := store.GetUser("bob"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+	if count, err := store.CountUsers(); err != nil || count != 1 {
+		t.Errorf("Expected 1 user, got %d (%v)", count, err)
+	}
+}
+
+func TestMemoryStorage_UpdateAndListUsers(t *testing.T) {
+	store := NewMemoryStorage()
+
+	for _, name := range []string{"carol", "alice", "bob"} {
+		if err := store.SaveUser(&models.User{Username: name, Role: "viewer"}); err != nil {
+			t.Fatalf("Failed to save user: %v", err)
+		}
+	}
+	if err := store.UpdateUser(&models.User{Username: "bob", Role: "admin"}); err != nil {
+		t.Fatalf("Failed to update user: %v", err)
+	}
+	if err := store.UpdateUser(&models.User{Username: "dave"}); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+
+	users, err := store.ListUsers()
+	if err != nil {
+		t.Fatalf("Failed to list users: %v", err)
+	}
+	var names []string
+	for _, u := range users {
+		names = append(names, u.Username+":"+u.Role)
+	}
+	if want := []string{"alice:viewer", "bob:admin", "carol:viewer"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Expected %v, got %v", want, names)
+	}
+}
+
+func TestMemoryStorage_AuditEntries(t *testing.T) {
+	store := NewMemoryStorage()
+
+	start := time.Now()
+	for i, action := range []string{"POST /a", "POST /b", "POST /c"} {
+		entry := &models.AuditEntry{Time: start.Add(time.Duration(i) * time.Second), User: "alice", Action: action}
+		if err := store.SaveAuditEntry(entry); err != nil {
+			t.Fatalf("Failed to save audit entry: %v", err)
+		}
+	}
+
+	entries, total, err := store.ListAuditEntries(ListOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("Failed to list audit entries: %v", err)
+	}
+	if total != 3 || len(entries) != 2 {
+		t.Fatalf("Expected 2 of 3 entries, got %d of %d", len(entries), total)
+	}
+	if entries[0].Action != "POST /c" || entries[1].Action != "POST /b" {
+		t.Errorf("Expected the newest entries first, got %s, %s", entries[0].Action, entries[1].Action)
+	}
+}
+
+func TestMemoryStorage_WorkflowRevisions(t *testing.T) {
+	store := NewMemoryStorage()
+
+	wf := &models.Workflow{Name: "Build"}
+	if err := store.SaveWorkflow(wf); err != nil {
+		t.Fatalf("Failed to save workflow: %v", err)
+	}
+	for version := 1; version <= 3; version++ {
+		if err := store.SaveWorkflowRevision(&models.WorkflowRevision{WorkflowID: wf.ID, Version: version}); err != nil {
+			t.Fatalf("Failed to save revision: %v", err)
+		}
+	}
+
+	revisions, err := store.ListWorkflowRevisions(wf.ID)
+	if err != nil {
+		t.Fatalf("Failed to list revisions: %v", err)
+	}
+	if len(revisions) != 3 || revisions[0].Version != 3 || revisions[2].Version != 1 {
+		t.Fatalf("Expected versions 3 to 1, got %+v", revisions)
+	}
+
+	if err := store.DeleteWorkflow(wf.ID); err != nil {
+		t.Fatalf("Failed to delete workflow: %v", err)
+	}
+	if revisions, _ := store.ListWorkflowRevisions(wf.ID); len(revisions) != 0 {
+		t.Errorf("Expected deleting the workflow to delete its revisions, got %+v", revisions)
+	}
+}
+
+func TestMemoryStorage_Hooks(t *testing.T) {
+	store := NewMemoryStorage()
+
+	for _, id := range []string{"hook-1", "hook-2", "hook-3"} {
+		if err := store.SaveHook(&models.Hook{ID: id, URL: "https://chat.example.com/" + id}); err != nil {
+			t.Fatalf("Failed to save hook: %v", err)
+		}
+	}
+	if err := store.DeleteHook("hook-2"); err != nil {
+		t.Fatalf("Failed to delete hook: %v", err)
+	}
+	if err := store.DeleteHook("hook-2"); !errors.Is(err, ErrHookNotFound) {
+		t.Errorf("Expected ErrHookNotFound deleting it again, got %v", err)
+	}
+
+	hooks, err := store.ListHooks()
+	if err != nil {
+		t.Fatalf("Failed to list hooks: %v", err)
+	}
+	if len(hooks) != 2 || hooks[0].ID != "hook-1" || hooks[1].ID != "hook-3" {
+		t.Fatalf("Expected hook-1 and hook-3 in order, got %+v", hooks)
+	}
+
+	for _, run := range []string{"run-1", "run-2"} {
+		if err := store.SaveHookFailure(&models.HookFailure{HookID: "hook-1", RunID: run}); err != nil {
+			t.Fatalf("Failed to save hook failure: %v", err)
+		}
+	}
+	failures, total, err := store.ListHookFailures(ListOptions{Limit: 1})
+	if err != nil {
+		t.Fatalf("Failed to list hook failures: %v", err)
+	}
+	if total != 2 || len(failures) != 1 || failures[0].RunID != "run-2" {
+		t.Errorf("Expected the newest of 2 failures, got %+v of %d", failures, total)
+	}
+}
+
 func TestMemoryStorage_Concurrency(t *testing.T) {
 	store := NewMemoryStorage()
 
@@ -185,7 +471,7 @@ func TestMemoryStorage_Concurrency(t *testing.T) {
 		<-done
 	}
 
-	workflows, _ := store.ListWorkflows()
+	workflows, _, _ := store.ListWorkflows(ListOptions{})
 	if len(workflows) != 10 {
 		t.Errorf("Expected 10 workflows, got %d", len(workflows))
 	}
@@ -199,28 +485,28 @@ func TestMemoryStorage_DeleteRunsByWorkflow(t *testing.T) {
 	_ = store.SaveWorkflow(wf)
 
 	// Save runs for this workflow
-	run1 := &models.WorkflowRun{ID: "run-1", WorkflowName: "TestWorkflow", Status: "success", Jobs: make(map[string]models.Job)}
-	run2 := &models.WorkflowRun{ID: "run-2", WorkflowName: "TestWorkflow", Status: "success", Jobs: make(map[string]models.Job)}
-	run3 := &models.WorkflowRun{ID: "run-3", WorkflowName: "OtherWorkflow", Status: "success", Jobs: make(map[string]models.Job)}
+	run1 := &models.WorkflowRun{ID: "run-1", WorkflowID: wf.ID, Status: "success", Jobs: make(map[string]models.Job)}
+	run2 := &models.WorkflowRun{ID: "run-2", WorkflowID: wf.ID, Status: "success", Jobs: make(map[string]models.Job)}
+	run3 := &models.WorkflowRun{ID: "run-3", WorkflowID: "otherworkflow", Status: "success", Jobs: make(map[string]models.Job)}
 
 	_ = store.SaveRun(run1)
 	_ = store.SaveRun(run2)
 	_ = store.SaveRun(run3)
 
 	// Verify we have 3 runs
-	runs, _ := store.ListRuns()
+	runs, _, _ := store.ListRuns(RunFilter{}, ListOptions{})
 	if len(runs) != 3 {
 		t.Fatalf("Expected 3 runs initially, got %d", len(runs))
 	}
 
 	// Delete runs for TestWorkflow
-	err := store.DeleteRunsByWorkflow("TestWorkflow")
+	err := store.DeleteRunsByWorkflow(wf.ID)
 	if err != nil {
 		t.Fatalf("Failed to delete runs: %v", err)
 	}
 
 	// Verify only run3 remains
-	runs, _ = store.ListRuns()
+	runs, _, _ = store.ListRuns(RunFilter{}, ListOptions{})
 	if len(runs) != 1 {
 		t.Errorf("Expected 1 run after deletion, got %d", len(runs))
 	}
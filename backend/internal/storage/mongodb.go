@@ -18,6 +18,11 @@ type MongoStorage struct {
 	database     *mongo.Database
 	workflows    *mongo.Collection
 	workflowRuns *mongo.Collection
+	revisions    *mongo.Collection
+	users        *mongo.Collection
+	audit        *mongo.Collection
+	hooks        *mongo.Collection
+	hookFailures *mongo.Collection
 }
 
 // NewMongoStorage creates a new MongoDB storage instance
@@ -38,20 +43,104 @@ func NewMongoStorage(uri, database string) (*MongoStorage, error) {
 
 	db := client.Database(database)
 
-	return &MongoStorage{
+	s := &MongoStorage{
 		client:       client,
 		database:     db,
 		workflows:    db.Collection("workflows"),
 		workflowRuns: db.Collection("workflow_runs"),
-	}, nil
+		revisions:    db.Collection("workflow_revisions"),
+		users:        db.Collection("users"),
+		audit:        db.Collection("audit"),
+		hooks:        db.Collection("hooks"),
+		hookFailures: db.Collection("hook_failures"),
+	}
+	if err := s.migrateWorkflowIDs(ctx); err != nil {
+		return nil, err
+	}
+	index := mongo.IndexModel{Keys: bson.D{{Key: "username", Value: 1}}, Options: options.Index().SetUnique(true)}
+	if _, err := s.users.Indexes().CreateOne(ctx, index); err != nil {
+		return nil, fmt.Errorf("failed to index usernames: %w", err)
+	}
+
+	return s, nil
+}
+
+// migrateWorkflowIDs assigns IDs to workflows saved before workflows had
+// them, links their runs by workflow name, and indexes workflows by ID
+func (s *MongoStorage) migrateWorkflowIDs(ctx context.Context) error {
+	cursor, err := s.workflows.Find(ctx, bson.M{"id": bson.M{"$exists": false}})
+	if err != nil {
+		return fmt.Errorf("failed to find workflows without IDs: %w", err)
+	}
+	var legacy []*models.Workflow
+	if err := cursor.All(ctx, &legacy); err != nil {
+		return fmt.Errorf("failed to decode workflows without IDs: %w", err)
+	}
+
+	for _, wf := range legacy {
+		id, err := newWorkflowID(wf.Name, func(id string) (bool, error) {
+			return s.workflowIDTaken(ctx, id)
+		})
+		if err != nil {
+			return err
+		}
+		filter := bson.M{"name": wf.Name, "id": bson.M{"$exists": false}}
+		if _, err := s.workflows.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"id": id}}); err != nil {
+			return fmt.Errorf("failed to assign workflow ID: %w", err)
+		}
+		runs := bson.M{"workflow_name": wf.Name, "workflow_id": bson.M{"$in": bson.A{nil, ""}}}
+		if _, err := s.workflowRuns.UpdateMany(ctx, runs, bson.M{"$set": bson.M{"workflow_id": id}}); err != nil {
+			return fmt.Errorf("failed to link runs to workflow ID: %w", err)
+		}
+	}
+
+	index := mongo.IndexModel{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)}
+	if _, err := s.workflows.Indexes().CreateOne(ctx, index); err != nil {
+		return fmt.Errorf("failed to index workflow IDs: %w", err)
+	}
+	return nil
 }
 
-// SaveWorkflow saves a workflow to MongoDB
+func (s *MongoStorage) workflowIDTaken(ctx context.Context, id string) (bool, error) {
+	count, err := s.workflows.CountDocuments(ctx, bson.M{"id": id})
+	if err != nil {
+		return false, fmt.Errorf("failed to check workflow ID: %w", err)
+	}
+	return count > 0, nil
+}
+
+// SaveWorkflow saves a workflow to MongoDB, assigning an ID to new workflows
 func (s *MongoStorage) SaveWorkflow(wf *models.Workflow) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	filter := bson.M{"name": wf.Name}
+	if wf.ID == "" {
+		existing, err := s.findWorkflow(ctx, bson.M{"name": wf.Name})
+		switch {
+		case err == nil:
+			wf.ID = existing.ID
+		case err == mongo.ErrNoDocuments:
+			id, err := newWorkflowID(wf.Name, func(id string) (bool, error) {
+				return s.workflowIDTaken(ctx, id)
+			})
+			if err != nil {
+				return err
+			}
+			wf.ID = id
+		default:
+			return fmt.Errorf("failed to save workflow: %w", err)
+		}
+	} else {
+		count, err := s.workflows.CountDocuments(ctx, bson.M{"name": wf.Name, "id": bson.M{"$ne": wf.ID}})
+		if err != nil {
+			return fmt.Errorf("failed to save workflow: %w", err)
+		}
+		if count > 0 {
+			return fmt.Errorf("%w: '%s'", ErrWorkflowExists, wf.Name)
+		}
+	}
+
+	filter := bson.M{"id": wf.ID}
 	update := bson.M{"$set": wf}
 	opts := options.Update().SetUpsert(true)
 
@@ -63,60 +152,123 @@ func (s *MongoStorage) SaveWorkflow(wf *models.Workflow) error {
 	return nil
 }
 
-// GetWorkflow retrieves a workflow by name
-func (s *MongoStorage) GetWorkflow(name string) (*models.Workflow, error) {
+// GetWorkflow retrieves a workflow by ID
+func (s *MongoStorage) GetWorkflow(id string) (*models.Workflow, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	var wf models.Workflow
-	err := s.workflows.FindOne(ctx, bson.M{"name": name}).Decode(&wf)
+	wf, err := s.findWorkflow(ctx, bson.M{"id": id})
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("workflow '%s' not found", name)
+			return nil, fmt.Errorf("%w: '%s'", ErrWorkflowNotFound, id)
 		}
 		return nil, fmt.Errorf("failed to get workflow: %w", err)
 	}
 
+	return wf, nil
+}
+
+// GetWorkflowByName retrieves a workflow by name
+func (s *MongoStorage) GetWorkflowByName(name string) (*models.Workflow, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	wf, err := s.findWorkflow(ctx, bson.M{"name": name})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("%w: '%s'", ErrWorkflowNotFound, name)
+		}
+		return nil, fmt.Errorf("failed to get workflow: %w", err)
+	}
+
+	return wf, nil
+}
+
+func (s *MongoStorage) findWorkflow(ctx context.Context, filter bson.M) (*models.Workflow, error) {
+	var wf models.Workflow
+	if err := s.workflows.FindOne(ctx, filter).Decode(&wf); err != nil {
+		return nil, err
+	}
 	return &wf, nil
 }
 
-// ListWorkflows returns all workflows
-func (s *MongoStorage) ListWorkflows() ([]*models.Workflow, error) {
+// ListWorkflows returns a page of the workflows, ordered by name
+func (s *MongoStorage) ListWorkflows(opts ListOptions) ([]*models.Workflow, int, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	cursor, err := s.workflows.Find(ctx, bson.M{})
+	total, err := s.workflows.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count workflows: %w", err)
+	}
+
+	find := findPage(opts).SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := s.workflows.Find(ctx, bson.M{}, find)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list workflows: %w", err)
+		return nil, 0, fmt.Errorf("failed to list workflows: %w", err)
 	}
 
 	defer func() { _ = cursor.Close(ctx) }()
 
 	var workflows []*models.Workflow
 	if err := cursor.All(ctx, &workflows); err != nil {
-		return nil, fmt.Errorf("failed to decode workflows: %w", err)
+		return nil, 0, fmt.Errorf("failed to decode workflows: %w", err)
 	}
 
-	return workflows, nil
+	return workflows, int(total), nil
 }
 
-// DeleteWorkflow deletes a workflow
-func (s *MongoStorage) DeleteWorkflow(name string) error {
+// DeleteWorkflow deletes a workflow by ID
+func (s *MongoStorage) DeleteWorkflow(id string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	result, err := s.workflows.DeleteOne(ctx, bson.M{"name": name})
+	result, err := s.workflows.DeleteOne(ctx, bson.M{"id": id})
 	if err != nil {
 		return fmt.Errorf("failed to delete workflow: %w", err)
 	}
 
 	if result.DeletedCount == 0 {
-		return fmt.Errorf("workflow '%s' not found", name)
+		return fmt.Errorf("%w: '%s'", ErrWorkflowNotFound, id)
+	}
+
+	if _, err := s.revisions.DeleteMany(ctx, bson.M{"workflow_id": id}); err != nil {
+		return fmt.Errorf("failed to delete workflow revisions: %w", err)
 	}
 
 	return nil
 }
 
+// SaveWorkflowRevision records a version of a workflow
+func (s *MongoStorage) SaveWorkflowRevision(rev *models.WorkflowRevision) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := s.revisions.InsertOne(ctx, rev); err != nil {
+		return fmt.Errorf("failed to save workflow revision: %w", err)
+	}
+	return nil
+}
+
+// ListWorkflowRevisions returns the versions of a workflow, newest first
+func (s *MongoStorage) ListWorkflowRevisions(workflowID string) ([]*models.WorkflowRevision, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	find := options.Find().SetSort(bson.D{{Key: "version", Value: -1}})
+	cursor, err := s.revisions.Find(ctx, bson.M{"workflow_id": workflowID}, find)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflow revisions: %w", err)
+	}
+	defer func() { _ = cursor.Close(ctx) }()
+
+	revisions := []*models.WorkflowRevision{}
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, fmt.Errorf("failed to decode workflow revisions: %w", err)
+	}
+	return revisions, nil
+}
+
 // SaveRun saves a workflow run
 func (s *MongoStorage) SaveRun(run *models.WorkflowRun) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -137,18 +289,35 @@ func (s *MongoStorage) SaveRun(run *models.WorkflowRun) error {
 	return nil
 }
 
+// DeleteRun deletes a run by ID
+func (s *MongoStorage) DeleteRun(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := s.workflowRuns.DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return fmt.Errorf("failed to delete run: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("run '%s' not found", id)
+	}
+
+	return nil
+}
+
 // DeleteRunsByWorkflow deletes all runs for a workflow
-func (s *MongoStorage) DeleteRunsByWorkflow(workflowName string) error {
+func (s *MongoStorage) DeleteRunsByWorkflow(workflowID string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	result, err := s.workflowRuns.DeleteMany(ctx, bson.M{"workflow_name": workflowName})
+	result, err := s.workflowRuns.DeleteMany(ctx, bson.M{"workflow_id": workflowID})
 	if err != nil {
 		return fmt.Errorf("failed to delete runs: %w", err)
 	}
 
 	if result.DeletedCount > 0 {
-		fmt.Printf("Deleted %d runs for workflow '%s'\n", result.DeletedCount, workflowName)
+		fmt.Printf("Deleted %d runs for workflow '%s'\n", result.DeletedCount, workflowID)
 	}
 
 	return nil
@@ -171,24 +340,68 @@ func (s *MongoStorage) GetRun(id string) (*models.WorkflowRun, error) {
 	return &run, nil
 }
 
-// ListRuns returns all runs, sorted by start time (newest first)
-func (s *MongoStorage) ListRuns() ([]*models.WorkflowRun, error) {
+// ListRuns returns a page of the runs filter matches, sorted by start time
+// (newest first)
+func (s *MongoStorage) ListRuns(filter RunFilter, opts ListOptions) ([]*models.WorkflowRun, int, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	opts := options.Find().SetSort(bson.D{{Key: "started_at", Value: -1}})
-	cursor, err := s.workflowRuns.Find(ctx, bson.M{}, opts)
+	query := runQuery(filter)
+	total, err := s.workflowRuns.CountDocuments(ctx, query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list runs: %w", err)
+		return nil, 0, fmt.Errorf("failed to count runs: %w", err)
+	}
+
+	find := findPage(opts).SetSort(bson.D{{Key: "started_at", Value: -1}, {Key: "id", Value: -1}})
+	cursor, err := s.workflowRuns.Find(ctx, query, find)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list runs: %w", err)
 	}
 	defer func() { _ = cursor.Close(ctx) }()
 
 	var runs []*models.WorkflowRun
 	if err := cursor.All(ctx, &runs); err != nil {
-		return nil, fmt.Errorf("failed to decode runs: %w", err)
+		return nil, 0, fmt.Errorf("failed to decode runs: %w", err)
 	}
 
-	return runs, nil
+	return runs, int(total), nil
+}
+
+// runQuery translates a run filter into a MongoDB query
+func runQuery(filter RunFilter) bson.M {
+	query := bson.M{}
+	if filter.WorkflowID != "" {
+		query["workflow_id"] = filter.WorkflowID
+	}
+	if len(filter.Statuses) > 0 {
+		query["status"] = bson.M{"$in": filter.Statuses}
+	}
+	started := bson.M{}
+	if !filter.Since.IsZero() {
+		started["$gte"] = filter.Since
+	}
+	if !filter.Until.IsZero() {
+		started["$lt"] = filter.Until
+	}
+	if len(started) > 0 {
+		query["started_at"] = started
+	}
+	if filter.Branch != "" {
+		query["trigger.branch"] = filter.Branch
+	}
+	return query
+}
+
+// findPage returns find options selecting the page opts selects
+func findPage(opts ListOptions) *options.FindOptions {
+	find := options.Find()
+	if opts.Offset > 0 {
+		find.SetSkip(int64(opts.Offset))
+	}
+	if opts.Limit > 0 {
+		find.SetLimit(int64(opts.Limit))
+	}
+	return find
 }
 
 // UpdateRun updates an existing run
@@ -214,6 +427,195 @@ func (s *MongoStorage) UpdateRun(run *models.WorkflowRun) error {
 	return nil
 }
 
+// SaveUser adds a user
+func (s *MongoStorage) SaveUser(user *models.User) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := s.users.InsertOne(ctx, user); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("%w: '%s'", ErrUserExists, user.Username)
+		}
+		return fmt.Errorf("failed to save user: %w", err)
+	}
+	return nil
+}
+
+// GetUser retrieves a user by username
+func (s *MongoStorage) GetUser(username string) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var user models.User
+	if err := s.users.FindOne(ctx, bson.M{"username": username}).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("%w: '%s'", ErrUserNotFound, username)
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &user, nil
+}
+
+// UpdateUser saves changes to a user
+func (s *MongoStorage) UpdateUser(user *models.User) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := s.users.UpdateOne(ctx, bson.M{"username": user.Username}, bson.M{"$set": user})
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: '%s'", ErrUserNotFound, user.Username)
+	}
+	return nil
+}
+
+// ListUsers returns every user, ordered by username
+func (s *MongoStorage) ListUsers() ([]*models.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := s.users.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "username", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer func() { _ = cursor.Close(ctx) }()
+
+	var users []*models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, fmt.Errorf("failed to decode users: %w", err)
+	}
+	return users, nil
+}
+
+// CountUsers returns how many users there are
+func (s *MongoStorage) CountUsers() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	count, err := s.users.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return int(count), nil
+}
+
+// SaveAuditEntry adds an entry to the audit trail
+func (s *MongoStorage) SaveAuditEntry(entry *models.AuditEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := s.audit.InsertOne(ctx, entry); err != nil {
+		return fmt.Errorf("failed to save audit entry: %w", err)
+	}
+	return nil
+}
+
+// ListAuditEntries returns a page of the audit trail, newest first
+func (s *MongoStorage) ListAuditEntries(opts ListOptions) ([]*models.AuditEntry, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	total, err := s.audit.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+
+	find := findPage(opts).SetSort(bson.D{{Key: "time", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := s.audit.Find(ctx, bson.M{}, find)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	defer func() { _ = cursor.Close(ctx) }()
+
+	var entries []*models.AuditEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode audit entries: %w", err)
+	}
+	return entries, int(total), nil
+}
+
+// SaveHook adds an outgoing webhook
+func (s *MongoStorage) SaveHook(hook *models.Hook) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := s.hooks.InsertOne(ctx, hook); err != nil {
+		return fmt.Errorf("failed to save hook: %w", err)
+	}
+	return nil
+}
+
+// ListHooks returns every outgoing webhook, oldest first
+func (s *MongoStorage) ListHooks() ([]*models.Hook, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	find := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := s.hooks.Find(ctx, bson.M{}, find)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list hooks: %w", err)
+	}
+	defer func() { _ = cursor.Close(ctx) }()
+
+	hooks := []*models.Hook{}
+	if err := cursor.All(ctx, &hooks); err != nil {
+		return nil, fmt.Errorf("failed to decode hooks: %w", err)
+	}
+	return hooks, nil
+}
+
+// DeleteHook removes an outgoing webhook
+func (s *MongoStorage) DeleteHook(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := s.hooks.DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return fmt.Errorf("failed to delete hook: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("%w: '%s'", ErrHookNotFound, id)
+	}
+	return nil
+}
+
+// SaveHookFailure records a delivery that failed every attempt
+func (s *MongoStorage) SaveHookFailure(failure *models.HookFailure) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := s.hookFailures.InsertOne(ctx, failure); err != nil {
+		return fmt.Errorf("failed to save hook failure: %w", err)
+	}
+	return nil
+}
+
+// ListHookFailures returns a page of the failed deliveries, newest first
+func (s *MongoStorage) ListHookFailures(opts ListOptions) ([]*models.HookFailure, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	total, err := s.hookFailures.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count hook failures: %w", err)
+	}
+
+	find := findPage(opts).SetSort(bson.D{{Key: "failed_at", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := s.hookFailures.Find(ctx, bson.M{}, find)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list hook failures: %w", err)
+	}
+	defer func() { _ = cursor.Close(ctx) }()
+
+	var failures []*models.HookFailure
+	if err := cursor.All(ctx, &failures); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode hook failures: %w", err)
+	}
+	return failures, int(total), nil
+}
+
 // Close closes the MongoDB connection
 func (s *MongoStorage) Close() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
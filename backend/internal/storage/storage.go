@@ -1,19 +1,155 @@
 package storage
 
-import "gantry/internal/models"
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
 
-// Storage defines the interface for workflow and run storage
+	"gantry/internal/models"
+)
+
+// ErrWorkflowExists is returned when saving a workflow under a name another
+// workflow already has
+var ErrWorkflowExists = errors.New("workflow already exists")
+
+// ErrWorkflowNotFound is returned for workflows that don't exist
+var ErrWorkflowNotFound = errors.New("workflow not found")
+
+// Errors of user storage
+var (
+	ErrUserExists   = errors.New("user already exists")
+	ErrUserNotFound = errors.New("user not found")
+)
+
+// ErrHookNotFound is returned for outgoing webhooks that don't exist
+var ErrHookNotFound = errors.New("hook not found")
+
+// Storage defines the interface for workflow and run storage. Workflows are
+// keyed by their ID, which SaveWorkflow assigns to new workflows; saving a
+// workflow without an ID under an existing name replaces that workflow.
 type Storage interface {
 	// Workflow operations
 	SaveWorkflow(wf *models.Workflow) error
-	GetWorkflow(name string) (*models.Workflow, error)
-	ListWorkflows() ([]*models.Workflow, error)
-	DeleteWorkflow(name string) error
+	GetWorkflow(id string) (*models.Workflow, error)
+	GetWorkflowByName(name string) (*models.Workflow, error)
+	ListWorkflows(opts ListOptions) ([]*models.Workflow, int, error)
+	DeleteWorkflow(id string) error
+
+	// Workflow revision operations; revisions are listed newest first, and
+	// deleting a workflow deletes its revisions
+	SaveWorkflowRevision(rev *models.WorkflowRevision) error
+	ListWorkflowRevisions(workflowID string) ([]*models.WorkflowRevision, error)
 
 	// Run operations
 	SaveRun(run *models.WorkflowRun) error
 	GetRun(id string) (*models.WorkflowRun, error)
-	ListRuns() ([]*models.WorkflowRun, error)
+	ListRuns(filter RunFilter, opts ListOptions) ([]*models.WorkflowRun, int, error)
 	UpdateRun(run *models.WorkflowRun) error
-	DeleteRunsByWorkflow(workflowName string) error
+	DeleteRun(id string) error
+	DeleteRunsByWorkflow(workflowID string) error
+
+	// User operations. SaveUser only adds users, failing with ErrUserExists
+	// for a taken username.
+	SaveUser(user *models.User) error
+	GetUser(username string) (*models.User, error)
+	UpdateUser(user *models.User) error
+	ListUsers() ([]*models.User, error)
+	CountUsers() (int, error)
+
+	// Audit trail operations; entries are listed newest first
+	SaveAuditEntry(entry *models.AuditEntry) error
+	ListAuditEntries(opts ListOptions) ([]*models.AuditEntry, int, error)
+
+	// Outgoing webhook operations; hooks are listed oldest first and
+	// failures newest first
+	SaveHook(hook *models.Hook) error
+	ListHooks() ([]*models.Hook, error)
+	DeleteHook(id string) error
+	SaveHookFailure(failure *models.HookFailure) error
+	ListHookFailures(opts ListOptions) ([]*models.HookFailure, int, error)
+}
+
+// ListOptions selects a page of a listing. Listings return the page and how
+// many items there are in all; workflows are ordered by name and runs by
+// start time, newest first.
+type ListOptions struct {
+	Offset int // items skipped from the start of the listing
+	Limit  int // most items returned; zero returns every item after Offset
+}
+
+// page returns the items of a listing of n items that opts selects, as the
+// bounds of a slice
+func (opts ListOptions) page(n int) (int, int) {
+	start := min(max(opts.Offset, 0), n)
+	end := n
+	if opts.Limit > 0 {
+		end = min(start+opts.Limit, n)
+	}
+	return start, end
+}
+
+// RunFilter selects the runs a listing includes. Unset fields match every
+// run; the total of a filtered listing counts the runs that match.
+type RunFilter struct {
+	WorkflowID string
+	Statuses   []string  // runs with any of these statuses
+	Since      time.Time // runs started at or after this time
+	Until      time.Time // runs started before this time
+	Branch     string    // runs triggered for this branch
+}
+
+// Matches reports whether the filter includes run. It is safe to use on a
+// run whose jobs are running.
+func (f RunFilter) Matches(run *models.WorkflowRun) bool {
+	switch {
+	case f.WorkflowID != "" && run.WorkflowID != f.WorkflowID:
+		return false
+	case len(f.Statuses) > 0 && !slices.Contains(f.Statuses, run.GetStatus()):
+		return false
+	case !f.Since.IsZero() && run.StartedAt.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !run.StartedAt.Before(f.Until):
+		return false
+	case f.Branch != "" && (run.Trigger == nil || run.Trigger.Branch != f.Branch):
+		return false
+	}
+	return true
+}
+
+// newWorkflowID derives an ID from a workflow's name, e.g. "Build & Test"
+// becomes build-test, appending -2, -3 and so on while taken reports the ID
+// is in use
+func newWorkflowID(name string, taken func(id string) (bool, error)) (string, error) {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z' || r >= '0' && r <= '9':
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		default:
+			hyphen = true
+		}
+	}
+	base := b.String()
+	if base == "" {
+		base = "workflow"
+	}
+
+	id := base
+	for n := 2; ; n++ {
+		inUse, err := taken(id)
+		if err != nil {
+			return "", err
+		}
+		if !inUse {
+			return id, nil
+		}
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
 }
@@ -0,0 +1,50 @@
+// Package version describes the build of the server, as set at link time:
+//
+//	go build -ldflags "-X gantry/internal/version.Version=v1.2.0 \
+//	  -X gantry/internal/version.Commit=$(git rev-parse HEAD) \
+//	  -X gantry/internal/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/server
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Set with -ldflags -X; builds that don't are "dev", with the commit and
+// date the Go toolchain stamped from version control, if any
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+// Info describes a build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build of the running binary
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, BuildDate: BuildDate, GoVersion: runtime.Version()}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		info = fromBuildInfo(info, build.Settings)
+	}
+	return info
+}
+
+// fromBuildInfo fills in the commit and date the linker wasn't given from
+// the version control settings the toolchain stamped
+func fromBuildInfo(info Info, settings []debug.BuildSetting) Info {
+	for _, s := range settings {
+		switch {
+		case s.Key == "vcs.revision" && info.Commit == "":
+			info.Commit = s.Value
+		case s.Key == "vcs.time" && info.BuildDate == "":
+			info.BuildDate = s.Value
+		}
+	}
+	return info
+}
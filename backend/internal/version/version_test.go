@@ -0,0 +1,32 @@
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	info := Get()
+	if info.Version != "dev" || info.GoVersion != runtime.Version() {
+		t.Errorf("Expected a dev build with Go %s, got %+v", runtime.Version(), info)
+	}
+}
+
+func TestFromBuildInfo(t *testing.T) {
+	settings := []debug.BuildSetting{
+		{Key: "vcs", Value: "git"},
+		{Key: "vcs.revision", Value: "b831ee2"},
+		{Key: "vcs.time", Value: "2025-01-15T10:30:00Z"},
+	}
+
+	info := fromBuildInfo(Info{Version: "dev"}, settings)
+	if info.Commit != "b831ee2" || info.BuildDate != "2025-01-15T10:30:00Z" {
+		t.Errorf("Expected the stamped commit and date, got %+v", info)
+	}
+
+	linked := fromBuildInfo(Info{Version: "v1.2.0", Commit: "a36cd1a", BuildDate: "2025-02-01T00:00:00Z"}, settings)
+	if linked.Commit != "a36cd1a" || linked.BuildDate != "2025-02-01T00:00:00Z" {
+		t.Errorf("Expected the linked commit and date to win, got %+v", linked)
+	}
+}
@@ -11,6 +11,7 @@ jest.mock('./services/apiService', () => ({
   getWorkflowStats: jest.fn(),
   getWorkflowRuns: jest.fn(),
   getRun: jest.fn(),
+  getJobLogs: jest.fn(() => Promise.resolve({})),
   uploadWorkflow: jest.fn(),
   triggerWorkflow: jest.fn(),
   deleteWorkflow: jest.fn(),
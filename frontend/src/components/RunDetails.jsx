@@ -1,4 +1,4 @@
-import React, { useState } from "react";
+import React, { useEffect, useState } from "react";
 import {
   CheckCircle2,
   XCircle,
@@ -10,10 +10,12 @@ import {
   GitBranch,
   User,
 } from "lucide-react";
+import apiService from "../services/apiService";
 
 const getStatusIcon = (status) => {
   switch (status) {
     case "success":
+    case "success_with_failures":
       return <CheckCircle2 className="w-4 h-4 text-green-600" />;
     case "failed":
       return <XCircle className="w-4 h-4 text-red-600" />;
@@ -27,6 +29,7 @@ const getStatusIcon = (status) => {
 const getStatusColor = (status) => {
   switch (status) {
     case "success":
+    case "success_with_failures":
       return "bg-green-50 text-green-700 border-green-200";
     case "failed":
       return "bg-red-50 text-red-700 border-red-200";
@@ -37,8 +40,24 @@ const getStatusColor = (status) => {
   }
 };
 
-function JobItem({ name, job }) {
+function JobItem({ runId, name, job }) {
   const [isExpanded, setIsExpanded] = useState(true);
+  const [logs, setLogs] = useState(null);
+
+  // Run responses leave out output, so it is fetched for expanded jobs
+  useEffect(() => {
+    if (!isExpanded || !job.status) return;
+    let cancelled = false;
+    apiService
+      .getJobLogs(runId, name)
+      .then((data) => {
+        if (!cancelled) setLogs(data);
+      })
+      .catch((err) => console.error("Failed to fetch job logs:", err));
+    return () => {
+      cancelled = true;
+    };
+  }, [isExpanded, runId, name, job.status, job.ended_at]);
 
   const duration =
     job.started_at && job.ended_at
@@ -69,7 +88,9 @@ function JobItem({ name, job }) {
             <span
               className={`px-2.5 py-1 text-xs font-medium rounded-full border ${getStatusColor(job.status)}`}
             >
-              {job.status}
+              {job.failure_reason
+                ? `${job.status} (${job.failure_reason})`
+                : job.status}
             </span>
           </div>
         </div>
@@ -119,7 +140,7 @@ function JobItem({ name, job }) {
           )}
 
           {/* Output Logs */}
-          {job.output && (
+          {logs?.output && (
             <div>
               <h4 className="text-sm font-semibold text-gray-900 mb-2">
                 Build logs
@@ -131,11 +152,26 @@ function JobItem({ name, job }) {
                   </span>
                 </div>
                 <pre className="px-4 py-3 text-sm text-gray-300 font-mono overflow-x-auto max-h-96">
-                  {job.output}
+                  {logs.output}
                 </pre>
               </div>
             </div>
           )}
+
+          {/* Service Logs */}
+          {logs?.service_logs &&
+            Object.entries(logs.service_logs).map(([service, output]) => (
+              <div key={service}>
+                <h4 className="text-sm font-semibold text-gray-900 mb-2">
+                  Service: {service}
+                </h4>
+                <div className="bg-gray-900 rounded-lg overflow-hidden">
+                  <pre className="px-4 py-3 text-sm text-gray-300 font-mono overflow-x-auto max-h-96">
+                    {output || "(no output)"}
+                  </pre>
+                </div>
+              </div>
+            ))}
         </div>
       )}
     </div>
@@ -154,7 +190,7 @@ export default function RunDetails({ run }) {
         : Object.entries(run.jobs || {}).map(([name, job]) => ({ name, job }));
 
     return jobsToRender.map(({ name, job }) => (
-      <JobItem key={name} name={name} job={job} />
+      <JobItem key={name} runId={run.id} name={name} job={job} />
     ));
   };
 
@@ -12,6 +12,7 @@ import {
 const getStatusIcon = (status) => {
   switch (status) {
     case "success":
+    case "success_with_failures":
       return <CheckCircle2 className="w-4 h-4 text-green-600" />;
     case "failed":
       return <XCircle className="w-4 h-4 text-red-600" />;
@@ -25,6 +26,7 @@ const getStatusIcon = (status) => {
 const getStatusColor = (status) => {
   switch (status) {
     case "success":
+    case "success_with_failures":
       return "bg-green-50 text-green-700 border-green-200";
     case "failed":
       return "bg-red-50 text-red-700 border-red-200";
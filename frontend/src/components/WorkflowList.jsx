@@ -4,6 +4,7 @@ import { Play, Upload, FileText, AlertCircle, Clock, CheckCircle, AlertTriangle,
 const getStatusColor = (status) => {
   switch (status) {
     case "success":
+    case "success_with_failures":
       return { bg: "bg-green-50", border: "border-green-200", text: "text-green-700", icon: "text-green-600" };
     case "failed":
       return { bg: "bg-red-50", border: "border-red-200", text: "text-red-700", icon: "text-red-600" };
@@ -17,6 +18,7 @@ const getStatusColor = (status) => {
 const StatusIcon = ({ status }) => {
   switch (status) {
     case "success":
+    case "success_with_failures":
       return <CheckCircle className="w-5 h-5 text-green-600" />;
     case "failed":
       return <AlertTriangle className="w-5 h-5 text-red-600" />;
@@ -39,9 +41,13 @@ export default function WorkflowList({
   uploadStatus,
   loading,
 }) {
-  const getLatestRun = (workflowName) => {
+  const getLatestRun = (workflow) => {
     return runs
-      .filter((r) => r.workflow_name === workflowName)
+      .filter((r) =>
+        r.workflow_id
+          ? r.workflow_id === workflow.id
+          : r.workflow_name === workflow.name,
+      )
       .sort((a, b) => new Date(b.started_at) - new Date(a.started_at))[0];
   };
 
@@ -120,7 +126,7 @@ export default function WorkflowList({
         ) : (
           <div className="grid grid-cols-1 md:grid-cols-2 lg:grid-cols-3 gap-4">
             {workflows.map((wf) => {
-              const latestRun = getLatestRun(wf.name);
+              const latestRun = getLatestRun(wf);
               const stats = workflowStats[wf.name] || {};
               const colors = getStatusColor(latestRun?.status);
 
@@ -67,6 +67,12 @@ class ApiService {
     if (!response.ok) throw new Error("Failed to fetch run");
     return response.json();
   }
+
+  async getJobLogs(runId, job) {
+    const response = await fetch(`${API_URL}/runs/${runId}/jobs/${job}/logs`);
+    if (!response.ok) throw new Error("Failed to fetch job logs");
+    return response.json();
+  }
 }
 
 // Create instance and export